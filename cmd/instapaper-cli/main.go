@@ -1,23 +1,53 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
+	"mime"
+	"net"
+	"net/http"
 	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
+	"instapaper-cli/internal/bot"
+	"instapaper-cli/internal/config"
 	"instapaper-cli/internal/db"
 	"instapaper-cli/internal/export"
 	"instapaper-cli/internal/fetcher"
 	"instapaper-cli/internal/importer"
+	"instapaper-cli/internal/ingest"
+	"instapaper-cli/internal/language"
 	"instapaper-cli/internal/mcp"
+	"instapaper-cli/internal/metrics"
 	"instapaper-cli/internal/model"
+	"instapaper-cli/internal/policy"
 	"instapaper-cli/internal/rss"
 	"instapaper-cli/internal/search"
+	"instapaper-cli/internal/similarity"
+	"instapaper-cli/internal/tagging"
+	"instapaper-cli/internal/term"
+	"instapaper-cli/internal/textnorm"
+	"instapaper-cli/internal/titles"
+	"instapaper-cli/internal/util"
 	"instapaper-cli/internal/version"
+	"instapaper-cli/migrations"
 
 	"github.com/spf13/cobra"
 )
@@ -25,7 +55,28 @@ import (
 var (
 	dbPath         string
 	migrationsPath string
+	outputFormat   string
+	plainOutput    bool
+	stripParams    []string
+	maxBandwidth   string
+	maxRequests    int
+	debugSQL       bool
+	attachmentsDir string
+	tagRulesPath   string
+	tagRules       *tagging.Rules
+	lockWait       time.Duration
+	writeLock      *db.WriteLock
 	database       *db.DB
+
+	// obsoletePolicies are applied by doctor, daemon, serve, and obsolete --policies
+	obsoletePoliciesPath string
+	obsoletePolicies     *policy.ObsoletePolicies
+
+	// userConfig is the user's config file, loaded before any command runs
+	userConfig = &config.Config{}
+
+	// schemaErr is set when the database comes from a newer release; only db version runs then
+	schemaErr error
 )
 
 func init() {
@@ -37,19 +88,47 @@ func initDB() {
 		dbPath = "instapaper.sqlite"
 	}
 
-	if migrationsPath == "" {
-		migrationsPath = "migrations"
-	}
-
 	var err error
 	database, err = db.New(dbPath)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
-	if err := database.RunMigrations(migrationsPath); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+	if debugSQL {
+		database.EnableQueryLog(os.Stderr)
+	}
+
+	if attachmentsDir != "" {
+		database.SetAttachmentsDir(attachmentsDir)
+	}
+
+	if err := runMigrations(); err != nil {
+		var tooNew *db.SchemaTooNewError
+		if !errors.As(err, &tooNew) {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+		schemaErr = err
+	}
+}
+
+// runMigrations applies the migrations of the --migrations directory, or without it those built
+// into the binary
+func runMigrations() error {
+	if migrationsPath == "" {
+		return database.RunMigrationsFS(migrations.FS)
+	}
+	return database.RunMigrations(migrationsPath)
+}
+
+// schemaMigrations returns the migrations runMigrations applies
+func schemaMigrations() (fs.FS, error) {
+	if migrationsPath == "" {
+		return migrations.FS, nil
+	}
+	if _, err := os.Stat(migrationsPath); err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory %s: %w", migrationsPath, err)
 	}
+	return os.DirFS(migrationsPath), nil
 }
 
 func main() {
@@ -60,7 +139,56 @@ func main() {
 	}
 
 	rootCmd.PersistentFlags().StringVar(&dbPath, "db", "instapaper.sqlite", "Path to SQLite database file")
-	rootCmd.PersistentFlags().StringVar(&migrationsPath, "migrations", "migrations", "Path to migrations directory")
+	rootCmd.PersistentFlags().StringVar(&migrationsPath, "migrations", "", "Path to a migrations directory (default: the migrations built into the binary)")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", "text", "Output format: 'text' or 'json' ('csv' for search and latest)")
+	rootCmd.PersistentFlags().BoolVar(&plainOutput, "plain", false, "Plain text output without colors, badges, or highlighting (also set by NO_COLOR)")
+	rootCmd.PersistentFlags().BoolVar(&debugSQL, "debug-sql", false, "Log every SQL query with its parameters and timing to stderr")
+	rootCmd.PersistentFlags().StringVar(&attachmentsDir, "attachments-dir", "", "Directory for stored PDFs and other attached files (default: attachments next to the database)")
+	rootCmd.PersistentFlags().StringVar(&maxBandwidth, "max-bandwidth", "", "Cap download bandwidth for article fetches, e.g. 500K or 2MB/s (default unlimited)")
+	rootCmd.PersistentFlags().IntVar(&maxRequests, "max-requests-per-minute", 0, "Cap article fetch requests per minute (default unlimited)")
+	rootCmd.PersistentFlags().StringVar(&obsoletePoliciesPath, "obsolete-policies", "", "YAML file of policies that mark dead articles obsolete, applied by doctor, daemon, serve, and obsolete --policies (min_failures, status_codes, failure_classes, link_status, older_than)")
+	rootCmd.PersistentFlags().StringVar(&tagRulesPath, "tag-rules", "", "YAML file of rules that tag and file articles added by import, add, and RSS sync (url, domain, title_contains, tags, folder)")
+	rootCmd.PersistentFlags().DurationVar(&lockWait, "lock-wait", 0, "How long a command that writes to the database waits for another writer to finish (default: fail at once)")
+	rootCmd.PersistentFlags().StringSliceVar(&stripParams, "strip-params", nil, "Extra query parameters to strip from URLs, in addition to utm_*, fbclid, gclid, ref, ... (use a trailing * for prefixes)")
+
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if outputFormat != "text" && outputFormat != "json" && outputFormat != "csv" {
+			return fmt.Errorf("invalid --output value: %s. Use text, json, or csv", outputFormat)
+		}
+		if outputFormat == "csv" && cmd.Name() != "search" && cmd.Name() != "latest" {
+			return fmt.Errorf("--output csv is only supported by search and latest")
+		}
+		util.AddTrackingParams(stripParams...)
+
+		if schemaErr != nil && commandName(cmd) != "db version" {
+			cmd.SilenceUsage = true
+			return schemaErr
+		}
+
+		var err error
+		if tagRules, err = tagging.LoadRules(tagRulesPath); err != nil {
+			return err
+		}
+		if obsoletePolicies, err = policy.LoadObsoletePolicies(obsoletePoliciesPath); err != nil {
+			return err
+		}
+
+		if needsWriteLock(cmd) {
+			if writeLock, err = database.LockWrites(cmd.Context(), commandName(cmd), lockWait); err != nil {
+				return lockError(err)
+			}
+		}
+
+		bandwidth, err := fetcher.ParseBandwidth(maxBandwidth)
+		if err != nil {
+			return err
+		}
+		if maxRequests < 0 {
+			return fmt.Errorf("invalid --max-requests-per-minute value: %d", maxRequests)
+		}
+		fetcher.SetLimits(bandwidth, maxRequests)
+		return nil
+	}
 
 	var importCmd = &cobra.Command{
 		Use:   "import",
@@ -68,8 +196,16 @@ func main() {
 		RunE:  runImport,
 	}
 
-	var csvPath string
+	var (
+		csvPath      string
+		splitFolders bool
+		importAtomic bool
+	)
 	importCmd.Flags().StringVar(&csvPath, "csv", "", "Path to CSV file (required)")
+	importCmd.Flags().BoolVar(&splitFolders, "split-folders", false, "Treat slashes in folder names as a hierarchy (Tech/Go becomes Go inside Tech)")
+	importCmd.Flags().BoolVar(&importAtomic, "atomic", false, "Import the whole file in one transaction, keeping nothing if it is interrupted or fails")
+	addTaxonomyFlags(importCmd)
+	addFailOnErrorFlags(importCmd)
 	importCmd.MarkFlagRequired("csv")
 
 	var fetchCmd = &cobra.Command{
@@ -85,14 +221,55 @@ func main() {
 		fetchPreferExtracted    bool
 		fetchStoreRaw          bool
 		fetchLogPath           string
+		fetchNoPrettify        bool
+		fetchPriorityConfig    string
+		fetchFolders           []string
+		fetchTags              []string
+		fetchIDs               []int64
+		fetchDomains           []string
+		fetchStatusCodes       []int
+		fetchStatuses          []string
+		fetchUpdateURL         bool
+		fetchTitleConfig       string
+		fetchTitleCase         bool
+		fetchDomainRules       string
+		fetchDryRun            bool
+		fetchMinContentLength  int
+		fetchKeepImages        bool
+		fetchKeepByline        bool
+		fetchLowQuality        bool
+		fetchGarbled           bool
+		fetchNormalizeText     bool
+		fetchNormalizeConfig   string
 	)
 
-	fetchCmd.Flags().StringVar(&fetchOrder, "order", "oldest", "Order articles by 'oldest' or 'newest'")
+	fetchCmd.Flags().StringVar(&fetchOrder, "order", "oldest", "Order articles by 'oldest', 'newest', or 'priority'")
 	fetchCmd.Flags().StringVar(&fetchSearch, "search", "", "Search phrase to filter articles")
 	fetchCmd.Flags().IntVar(&fetchLimit, "limit", 10, "Maximum number of articles to fetch")
 	fetchCmd.Flags().BoolVar(&fetchPreferExtracted, "prefer-extracted-title", false, "Use extracted title instead of CSV title")
 	fetchCmd.Flags().BoolVar(&fetchStoreRaw, "store-raw", false, "Store raw HTML alongside Markdown")
 	fetchCmd.Flags().StringVar(&fetchLogPath, "log", "", "Path to log file")
+	fetchCmd.Flags().BoolVar(&fetchNoPrettify, "no-prettify", false, "Store converted Markdown as-is without blank-line and tracker cleanup")
+	fetchCmd.Flags().StringVar(&fetchPriorityConfig, "priority-config", "", "YAML file with scoring weights for --order priority")
+	fetchCmd.Flags().StringSliceVar(&fetchFolders, "folder", nil, "Only fetch articles in these folders (title or path)")
+	fetchCmd.Flags().StringSliceVar(&fetchTags, "tag", nil, "Only fetch articles with any of these tags")
+	fetchCmd.Flags().Int64SliceVar(&fetchIDs, "ids", nil, "Comma-separated list of article IDs to fetch")
+	fetchCmd.Flags().StringSliceVar(&fetchDomains, "domain", nil, "Only fetch articles from these domains (includes subdomains)")
+	fetchCmd.Flags().IntSliceVar(&fetchStatusCodes, "status-code", nil, "Only retry articles whose last fetch returned these HTTP status codes (e.g., 429,503)")
+	fetchCmd.Flags().StringSliceVar(&fetchStatuses, "status", nil, "Only fetch articles with these reading statuses, e.g. reading")
+	fetchCmd.Flags().BoolVar(&fetchUpdateURL, "update-url", false, "Replace article URLs with the page's canonical URL (skipped when another article already has it)")
+	fetchCmd.Flags().StringVar(&fetchTitleConfig, "title-config", "", "YAML file with title cleanup rules (separators, site_names, max_suffix_words, title_case)")
+	fetchCmd.Flags().BoolVar(&fetchTitleCase, "title-case", false, "Convert cleaned titles to title case")
+	fetchCmd.Flags().StringVar(&fetchDomainRules, "domain-rules", "", "YAML file with per-domain rules (skip, render: js, headers, requests_per_minute, wayback, content_selector)")
+	fetchCmd.Flags().IntVar(&fetchMinContentLength, "min-content-length", 0, "Fewest characters readability accepts as the article before retrying with looser rules (default 500)")
+	fetchCmd.Flags().BoolVar(&fetchKeepImages, "keep-images", false, "Keep image galleries and figures that readability drops as clutter")
+	fetchCmd.Flags().BoolVar(&fetchKeepByline, "keep-byline", false, "Keep the author byline as the first line of the content")
+	fetchCmd.Flags().BoolVar(&fetchLowQuality, "low-quality", false, "Refetch fetched articles whose content is probably not the article, instead of unfetched ones")
+	fetchCmd.Flags().BoolVar(&fetchGarbled, "garbled", false, "Refetch fetched articles whose content was decoded with the wrong charset (mojibake), instead of unfetched ones")
+	fetchCmd.Flags().BoolVar(&fetchNormalizeText, "normalize-text", false, "Replace curly quotes, non-breaking spaces, and HTML entities in titles and content with plain forms")
+	fetchCmd.Flags().StringVar(&fetchNormalizeConfig, "normalize-config", "", "YAML file choosing what --normalize-text replaces (quotes, spaces, entities, dashes, ellipses); implies --normalize-text")
+	fetchCmd.Flags().BoolVar(&fetchDryRun, "dry-run", false, "List the articles that would be fetched, in order, and why others matching the filters are excluded, without fetching")
+	addFailOnErrorFlags(fetchCmd)
 
 	var searchCmd = &cobra.Command{
 		Use:   "search [query]",
@@ -102,20 +279,36 @@ func main() {
 	}
 
 	var (
-		searchField string
-		searchFTS   bool
-		searchLimit int
-		searchJSON  bool
-		searchSince string
-		searchUntil string
+		searchField      string
+		searchFTS        bool
+		searchLimit      int
+		searchJSON       bool
+		searchSince      string
+		searchUntil      string
+		searchExplain    bool
+		searchFuzzy      bool
+		searchFacets     bool
+		searchFacetLimit int
+		searchDedupe     bool
+		searchCase       bool
+		searchWholeWord  bool
 	)
 
-	searchCmd.Flags().StringVar(&searchField, "field", "", "Search specific field: url, title, content, tags, folder")
+	addFetchHealthFlags(searchCmd)
+
+	searchCmd.Flags().StringVar(&searchField, "field", "", "Search specific field: url, title, content, selection, tags, folder")
 	searchCmd.Flags().BoolVar(&searchFTS, "fts", false, "Use full-text search")
 	searchCmd.Flags().IntVar(&searchLimit, "limit", 50, "Maximum number of results")
 	searchCmd.Flags().BoolVar(&searchJSON, "json", false, "Output results as JSON")
 	searchCmd.Flags().StringVar(&searchSince, "since", "", "Filter articles since date (1d, 1w, today, yesterday, 2006-01-02)")
 	searchCmd.Flags().StringVar(&searchUntil, "until", "", "Filter articles until date (1d, 1w, today, yesterday, 2006-01-02)")
+	searchCmd.Flags().BoolVar(&searchExplain, "explain", false, "Print the SQLite query plan instead of running the search")
+	searchCmd.Flags().BoolVar(&searchFuzzy, "fuzzy", false, "With --fts, retry with misspelled terms corrected when nothing matches")
+	searchCmd.Flags().BoolVar(&searchFacets, "facets", false, "Also count all matches per tag, folder, year, and domain")
+	searchCmd.Flags().IntVar(&searchFacetLimit, "facet-limit", 10, "Number of values to show per facet (0 for all)")
+	searchCmd.Flags().BoolVar(&searchDedupe, "dedupe", false, "Collapse results that resolve to the same canonical or final URL, showing how many were collapsed")
+	searchCmd.Flags().BoolVar(&searchCase, "case-sensitive", false, "Match the query's case exactly (\"Go\" does not find \"go\"); not with --fts")
+	searchCmd.Flags().BoolVar(&searchWholeWord, "whole-word", false, "Match the query only as whole words (\"go\" does not find \"google\"); not with --fts")
 
 	var latestCmd = &cobra.Command{
 		Use:   "latest",
@@ -125,33 +318,246 @@ func main() {
 	}
 
 	var (
-		latestLimit int
-		latestJSON  bool
-		latestSince string
-		latestUntil string
+		latestLimit   int
+		latestJSON    bool
+		latestSince   string
+		latestUntil   string
+		latestBy      string
+		latestTags    []string
+		latestFolders []string
 	)
 
+	addFetchHealthFlags(latestCmd)
+
 	latestCmd.Flags().IntVar(&latestLimit, "limit", 20, "Maximum number of articles to show")
 	latestCmd.Flags().BoolVar(&latestJSON, "json", false, "Output results as JSON")
 	latestCmd.Flags().StringVar(&latestSince, "since", "", "Show articles since date (1d, 1w, today, yesterday, 2006-01-02)")
 	latestCmd.Flags().StringVar(&latestUntil, "until", "", "Show articles until date (1d, 1w, today, yesterday, 2006-01-02)")
+	latestCmd.Flags().StringVar(&latestBy, "by", search.LatestByAdded, "Date to order and filter by: added (saved to Instapaper), fetched, or published")
+	latestCmd.Flags().StringSliceVar(&latestTags, "tag", nil, "Only show articles with any of these tags")
+	latestCmd.Flags().StringSliceVar(&latestFolders, "folder", nil, "Only show articles in these folders (title or path)")
+
+	var randomCmd = &cobra.Command{
+		Use:   "random",
+		Short: "Show random fetched articles to rediscover old saves",
+		RunE:  runRandom,
+	}
+
+	var (
+		randomCount   int
+		randomTags    []string
+		randomFolders []string
+		randomUnread   bool
+		randomStatuses []string
+		randomJSON     bool
+	)
+
+	randomCmd.Flags().IntVarP(&randomCount, "count", "n", 1, "Number of random articles to show")
+	randomCmd.Flags().StringSliceVar(&randomTags, "tag", nil, "Only pick articles with any of these tags")
+	randomCmd.Flags().StringSliceVar(&randomFolders, "folder", nil, "Only pick articles in these folders (title or path)")
+	randomCmd.Flags().BoolVar(&randomUnread, "unread", false, "Only pick articles still in the Unread folder")
+	randomCmd.Flags().StringSliceVar(&randomStatuses, "status", nil, "Only pick articles with these reading statuses, e.g. someday")
+	randomCmd.Flags().BoolVar(&randomJSON, "json", false, "Output results as JSON")
+
+	var similarCmd = &cobra.Command{
+		Use:   "similar",
+		Short: "Find articles with content similar to an article",
+		Long:  "Compare SimHash signatures of fetched content to find articles similar to the given one. Use --duplicates to only show near-identical copies.",
+		RunE:  runSimilar,
+	}
+
+	var (
+		similarID          int64
+		similarLimit       int
+		similarMaxDistance int
+		similarDuplicates  bool
+		similarJSON        bool
+	)
+
+	similarCmd.Flags().Int64Var(&similarID, "id", 0, "Article ID to compare against (required)")
+	similarCmd.Flags().IntVar(&similarLimit, "limit", 10, "Maximum number of similar articles to show")
+	similarCmd.Flags().IntVar(&similarMaxDistance, "max-distance", similarity.DefaultMaxDistance, "Largest signature distance (0-64 differing bits) to include")
+	similarCmd.Flags().BoolVar(&similarDuplicates, "duplicates", false, fmt.Sprintf("Only show near-duplicates (distance <= %d)", similarity.NearDuplicateDistance))
+	similarCmd.Flags().BoolVar(&similarJSON, "json", false, "Output results as JSON")
+	similarCmd.MarkFlagRequired("id")
+
+	var suggestTagsCmd = &cobra.Command{
+		Use:   "suggest-tags",
+		Short: "Suggest tags for articles from their content",
+		Long:  "Propose tags using TF-IDF keywords, the existing tag vocabulary, and tags of similar articles (or an external LLM command). Review the suggestions, accept them interactively, or apply them all.",
+		RunE:  runSuggestTags,
+	}
+
+	var (
+		suggestID          int64
+		suggestUntagged    bool
+		suggestLimit       int
+		suggestMax         int
+		suggestMinScore    float64
+		suggestApply       bool
+		suggestInteractive bool
+		suggestLLMCommand  string
+		suggestJSON        bool
+	)
+
+	suggestTagsCmd.Flags().Int64Var(&suggestID, "id", 0, "Article ID to suggest tags for")
+	suggestTagsCmd.Flags().BoolVar(&suggestUntagged, "untagged", false, "Suggest tags for fetched articles that have no tags")
+	suggestTagsCmd.Flags().IntVar(&suggestLimit, "limit", 20, "Maximum number of untagged articles to process")
+	suggestTagsCmd.Flags().IntVar(&suggestMax, "max", 5, "Maximum number of suggestions per article")
+	suggestTagsCmd.Flags().Float64Var(&suggestMinScore, "min-score", 0.2, "Drop suggestions scoring below this (0-1)")
+	suggestTagsCmd.Flags().BoolVar(&suggestApply, "apply", false, "Apply all suggestions without asking")
+	suggestTagsCmd.Flags().BoolVar(&suggestInteractive, "interactive", false, "Accept or reject each suggestion")
+	suggestTagsCmd.Flags().StringVar(&suggestLLMCommand, "llm-command", "", "Shell command that reads a prompt on stdin and prints comma-separated tags (e.g. 'llm -m gpt-4o-mini')")
+	suggestTagsCmd.Flags().BoolVar(&suggestJSON, "json", false, "Output results as JSON")
+
+	var addCmd = &cobra.Command{
+		Use:   "add [url...]",
+		Short: "Queue URLs for fetching, from arguments or the clipboard",
+		Long:  "Add URLs as unfetched articles saved now, skipping URLs already in the database. With --from-clipboard, every http(s) link in the clipboard text is added.",
+		RunE:  runAdd,
+	}
+
+	var (
+		addFromClipboard bool
+		addFolder        string
+		addTags          []string
+		addJSON          bool
+	)
+
+	addCmd.Flags().BoolVar(&addFromClipboard, "from-clipboard", false, "Add the URLs found in the system clipboard")
+	addCmd.Flags().StringVar(&addFolder, "folder", "", "Folder for the new articles (slashes create a hierarchy)")
+	addCmd.Flags().StringSliceVar(&addTags, "tag", nil, "Tags for the new articles (repeatable or comma-separated)")
+	addCmd.Flags().BoolVar(&addJSON, "json", false, "Output results as JSON")
+
+	var showCmd = &cobra.Command{
+		Use:   "show",
+		Short: "Print an article's metadata and content in the terminal",
+		Long:  "Print an article's metadata, content, and highlights. Output to a terminal goes through $PAGER (default less -R).",
+		RunE:  runShow,
+	}
+
+	var (
+		showID      int64
+		showNoPager bool
+		showJSON    bool
+		showOutline bool
+	)
+
+	showCmd.Flags().Int64Var(&showID, "id", 0, "Article ID (required)")
+	showCmd.Flags().BoolVar(&showNoPager, "no-pager", false, "Print directly instead of through the pager")
+	showCmd.Flags().BoolVar(&showJSON, "json", false, "Output the article with its content as JSON")
+	showCmd.Flags().BoolVar(&showOutline, "outline", false, "Print only the article's headings as a table of contents")
+	showCmd.MarkFlagRequired("id")
+
+	var grepCmd = &cobra.Command{
+		Use:   "grep PATTERN",
+		Short: "Find passages in an article's content",
+		Long:  "Print the paragraphs of an article that contain a pattern, with surrounding paragraphs for context, to find a quote without reading the whole article. Patterns are literal text matched ignoring case unless --regex or --case-sensitive is given.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runGrep,
+	}
+
+	var (
+		grepID            int64
+		grepContext       int
+		grepRegex         bool
+		grepCaseSensitive bool
+		grepLimit         int
+	)
+
+	grepCmd.Flags().Int64Var(&grepID, "id", 0, "Article ID (required)")
+	grepCmd.Flags().IntVarP(&grepContext, "context", "C", 1, "Paragraphs to show before and after each match")
+	grepCmd.Flags().BoolVar(&grepRegex, "regex", false, "Treat the pattern as a regular expression")
+	grepCmd.Flags().BoolVar(&grepCaseSensitive, "case-sensitive", false, "Match case exactly")
+	grepCmd.Flags().IntVar(&grepLimit, "limit", 0, "Show at most this many passages (0 for all)")
+	grepCmd.MarkFlagRequired("id")
+
+	var openCmd = &cobra.Command{
+		Use:   "open",
+		Short: "Open an article's URL in the browser, or its exported file in an editor",
+		Long:  "Open the original URL in the default browser. With --export-dir, open the article's markdown file from that export-all directory in $EDITOR, or the default application when $EDITOR is not set.",
+		RunE:  runOpen,
+	}
+
+	var (
+		openID        int64
+		openExportDir string
+	)
+
+	openCmd.Flags().Int64Var(&openID, "id", 0, "Article ID (required)")
+	openCmd.Flags().StringVar(&openExportDir, "export-dir", "", "Open the exported markdown file from this export-all directory instead of the URL")
+	openCmd.MarkFlagRequired("id")
+
+	var attachmentsCmd = &cobra.Command{
+		Use:   "attachments",
+		Short: "List an article's attached files, or attach a local file",
+		Long:  "List the original files (PDFs, slide decks, images) kept for an article. Fetch stores them automatically when a URL serves a file instead of a web page; --add attaches a local file.",
+		RunE:  runAttachments,
+	}
+
+	var (
+		attachmentsID   int64
+		attachmentsAdd  string
+		attachmentsJSON bool
+	)
+
+	attachmentsCmd.Flags().Int64Var(&attachmentsID, "id", 0, "Article ID (required)")
+	attachmentsCmd.Flags().StringVar(&attachmentsAdd, "add", "", "Attach this local file to the article")
+	attachmentsCmd.Flags().BoolVar(&attachmentsJSON, "json", false, "Output as JSON")
+	attachmentsCmd.MarkFlagRequired("id")
 
 	var exportCmd = &cobra.Command{
 		Use:   "export",
-		Short: "Export a single article",
+		Short: "Export a single article, highlights as Anki cards, or articles as an Instapaper CSV",
+		Long:  "Export a single article as markdown, highlights as Anki cards, or a selection of articles as a CSV in Instapaper's export format, which Instapaper and the import command read back.",
 		RunE:  runExport,
 	}
 
 	var (
-		exportID     int64
-		exportOut    string
-		exportStdout bool
+		exportID          int64
+		exportOut         string
+		exportStdout      bool
+		exportIncludeHTML bool
+		exportFormat      string
+		exportTag         string
+		exportDeck        string
+		exportLLM         string
+		exportQuestions   int
+		exportFolder      string
+		exportStatuses    []string
+		exportSince       string
+		exportUntil       string
+		exportFromSearch  string
+		exportSearchFTS   bool
+		exportLimit       int
+		exportStrip       []string
+		exportSanitize    string
+		exportFields      []string
+		exportFieldsConf  string
+		exportHighlights  string
 	)
 
-	exportCmd.Flags().Int64Var(&exportID, "id", 0, "Article ID to export (required)")
+	exportCmd.Flags().Int64Var(&exportID, "id", 0, "Article ID to export (for markdown, required unless --from-search is given; with anki and instapaper-csv, all matching articles if omitted)")
 	exportCmd.Flags().StringVar(&exportOut, "out", "", "Output file path")
 	exportCmd.Flags().BoolVar(&exportStdout, "stdout", false, "Output to stdout")
-	exportCmd.MarkFlagRequired("id")
+	exportCmd.Flags().BoolVar(&exportIncludeHTML, "include-html", false, "Also write stored raw HTML as a sibling .html file (embedded in a details block with --stdout)")
+	exportCmd.Flags().StringVar(&exportFormat, "format", "markdown", "Export format: markdown, anki (tab-separated notes for Anki's File > Import), or instapaper-csv (Instapaper's CSV export format)")
+	exportCmd.Flags().StringVar(&exportTag, "tag", "", "With anki or instapaper-csv, only export articles with this tag")
+	exportCmd.Flags().StringVar(&exportDeck, "deck", "Instapaper", "With anki, the deck to import cards into")
+	exportCmd.Flags().StringVar(&exportLLM, "llm", "", "With anki, also generate Q&A cards from article content by piping it to this command (e.g. \"llm -m gpt-4o-mini\")")
+	exportCmd.Flags().IntVar(&exportQuestions, "questions", 5, "With anki and --llm, maximum Q&A cards per article")
+	exportCmd.Flags().StringVar(&exportFolder, "folder", "", "With instapaper-csv, only export articles in this folder")
+	exportCmd.Flags().StringSliceVar(&exportStatuses, "status", nil, "With instapaper-csv or --from-search, only export articles with these reading statuses")
+	exportCmd.Flags().StringVar(&exportSince, "since", "", "With instapaper-csv, only export articles saved since this date (ISO8601)")
+	exportCmd.Flags().StringVar(&exportUntil, "until", "", "With instapaper-csv, only export articles saved until this date (ISO8601)")
+	exportCmd.Flags().StringVar(&exportFromSearch, "from-search", "", "With markdown, export the fetched articles matching this search as one document; with instapaper-csv, only export articles matching it")
+	exportCmd.Flags().BoolVar(&exportSearchFTS, "fts", false, "With --from-search, use full-text search")
+	exportCmd.Flags().IntVar(&exportLimit, "limit", 0, "With markdown and --from-search, maximum number of articles (default all)")
+	exportCmd.Flags().StringSliceVar(&exportStrip, "strip", nil, "With markdown, remove clutter from the content: data-images, share, related, references, or all")
+	exportCmd.Flags().StringVar(&exportSanitize, "sanitize-config", "", "With markdown, YAML file of content filters with per-domain overrides")
+	exportCmd.Flags().StringSliceVar(&exportFields, "frontmatter-fields", nil, "With markdown, add computed frontmatter fields: domain, word_count, read_time, status, year, aliases, or all")
+	exportCmd.Flags().StringVar(&exportFieldsConf, "frontmatter-config", "", "With markdown, YAML map of computed frontmatter fields to the keys they are written under")
+	exportCmd.Flags().StringVar(&exportHighlights, "highlights", export.HighlightsBottom, "With markdown, where to put the Highlights section (the selection saved with the article): bottom, top, or none")
 
 	var exportAllCmd = &cobra.Command{
 		Use:   "export-all",
@@ -165,25 +571,63 @@ func main() {
 		exportAllIncludeUnsynced bool
 		exportAllFolder        string
 		exportAllTag           string
+		exportAllStatuses      []string
 		exportAllSince         string
 		exportAllUntil         string
 		exportAllFromSearch    string
 		exportAllSearchField   string
 		exportAllSearchFTS     bool
 		exportAllSearchLimit   int
+		exportAllIncludeHTML   bool
+		exportAllNaming        string
+		exportAllFormat        string
+		exportAllNamespaces    bool
+		exportAllUnsyncedMode  string
+		exportAllLinkFormat    string
+		exportAllAttachments   string
+		exportAllChangelog     string
+		exportAllPrune         bool
+		exportAllGitCommit     bool
+		exportAllStrip         []string
+		exportAllSanitize      string
+		exportAllFields        []string
+		exportAllFieldsConf    string
+		exportAllHighlights    string
+		exportAllMaxArticles   int
+		exportAllMaxTotalSize  string
+		exportAllPaginate      bool
 	)
 
 	exportAllCmd.Flags().StringVar(&exportAllDir, "dir", "", "Output directory (required)")
 	exportAllCmd.Flags().BoolVar(&exportAllOnlySynced, "only-synced", true, "Only export synced articles")
-	exportAllCmd.Flags().BoolVar(&exportAllIncludeUnsynced, "include-unsynced", false, "Include unsynced articles as stubs")
+	exportAllCmd.Flags().BoolVar(&exportAllIncludeUnsynced, "include-unsynced", false, "Include unsynced articles as stubs (same as --unsynced-mode stub)")
+	exportAllCmd.Flags().StringVar(&exportAllUnsyncedMode, "unsynced-mode", export.UnsyncedSkip, "How to export unsynced articles: stub (markdown file), linkfile (.url/.webloc shortcut), index (one unfetched.md), or skip")
+	exportAllCmd.Flags().StringVar(&exportAllLinkFormat, "link-format", export.LinkFormatURL, "Link file format for --unsynced-mode linkfile: url or webloc")
+	exportAllCmd.Flags().StringVar(&exportAllAttachments, "attachments", export.AttachmentsReference, "How to include attached files (PDFs, images): reference (link to the stored file), copy (next to the markdown file), or none")
 	exportAllCmd.Flags().StringVar(&exportAllFolder, "folder", "", "Filter by folder path")
 	exportAllCmd.Flags().StringVar(&exportAllTag, "tag", "", "Filter by tag")
+	exportAllCmd.Flags().StringSliceVar(&exportAllStatuses, "status", nil, "Filter by reading status, e.g. read,archived")
 	exportAllCmd.Flags().StringVar(&exportAllSince, "since", "", "Filter articles since date (ISO8601)")
 	exportAllCmd.Flags().StringVar(&exportAllUntil, "until", "", "Filter articles until date (ISO8601)")
 	exportAllCmd.Flags().StringVar(&exportAllFromSearch, "from-search", "", "Export articles from search results")
-	exportAllCmd.Flags().StringVar(&exportAllSearchField, "field", "", "Search specific field: url, title, content, tags, folder")
+	exportAllCmd.Flags().StringVar(&exportAllSearchField, "field", "", "Search specific field: url, title, content, selection, tags, folder")
 	exportAllCmd.Flags().BoolVar(&exportAllSearchFTS, "fts", false, "Use full-text search")
 	exportAllCmd.Flags().IntVar(&exportAllSearchLimit, "limit", 0, "Maximum number of search results to export")
+	exportAllCmd.Flags().BoolVar(&exportAllIncludeHTML, "include-html", false, "Also write stored raw HTML as a sibling .html file")
+	exportAllCmd.Flags().StringVar(&exportAllNaming, "naming", export.NamingCounter, "Filename scheme: counter (slug-ID, -2 on collision) or stable (slug + URL hash, reused across runs)")
+	exportAllCmd.Flags().StringVar(&exportAllFormat, "format", export.FormatMarkdown, "Export format: markdown (frontmatter, Obsidian-style) or logseq (pages/ and journals/ of a Logseq graph)")
+	exportAllCmd.Flags().BoolVar(&exportAllNamespaces, "logseq-namespaces", false, "With logseq, put pages in a namespace per folder (Instapaper/Tech/Go/Title)")
+	exportAllCmd.Flags().StringVar(&exportAllChangelog, "changelog", "", "Write a summary of the new, updated, and removed files of this run to this file (e.g. for a commit message)")
+	exportAllCmd.Flags().BoolVar(&exportAllPrune, "prune", false, "Delete exported files of articles deleted or marked obsolete since they were exported")
+	exportAllCmd.Flags().BoolVar(&exportAllGitCommit, "git-commit", false, "Commit the export directory to git afterwards (runs git init if needed), with the changelog as the message")
+	exportAllCmd.Flags().StringSliceVar(&exportAllStrip, "strip", nil, "Remove clutter from the content: data-images, share, related, references, or all")
+	exportAllCmd.Flags().StringVar(&exportAllSanitize, "sanitize-config", "", "YAML file of content filters with per-domain overrides")
+	exportAllCmd.Flags().StringSliceVar(&exportAllFields, "frontmatter-fields", nil, "Add computed frontmatter fields for Dataview queries: domain, word_count, read_time, status, year, aliases, or all")
+	exportAllCmd.Flags().StringVar(&exportAllFieldsConf, "frontmatter-config", "", "YAML map of computed frontmatter fields to the keys they are written under")
+	exportAllCmd.Flags().StringVar(&exportAllHighlights, "highlights", export.HighlightsBottom, "Where to put the Highlights section (the selection saved with the article): bottom, top, or none")
+	exportAllCmd.Flags().IntVar(&exportAllMaxArticles, "max-articles", 0, "Refuse to export when more articles match than this, e.g. to catch a missing filter (0 for no cap)")
+	exportAllCmd.Flags().StringVar(&exportAllMaxTotalSize, "max-total-size", "", "Stop the export once the written files reach this size, e.g. 50MB or 1G (default no cap)")
+	exportAllCmd.Flags().BoolVar(&exportAllPaginate, "paginate", false, "Instead of stopping at --max-articles or --max-total-size, split the export into part-001, part-002, ... directories of at most that much")
 	exportAllCmd.MarkFlagRequired("dir")
 
 	var foldersCmd = &cobra.Command{
@@ -202,7 +646,24 @@ func main() {
 	foldersCmd.Flags().StringVar(&foldersAction, "action", "list", "Action: list, mv, mkdir")
 	foldersCmd.Flags().StringVar(&foldersSource, "source", "", "Source folder for mv")
 	foldersCmd.Flags().StringVar(&foldersTarget, "target", "", "Target folder for mv")
-	foldersCmd.Flags().StringVar(&foldersName, "name", "", "Folder name for mkdir")
+	foldersCmd.Flags().StringVar(&foldersName, "name", "", "Folder name or path for mkdir (e.g. Tech/Go/Generics)")
+
+	var foldersRenameCmd = &cobra.Command{
+		Use:   "rename",
+		Short: "Rename a folder, updating the paths of its subfolders and the search index",
+		RunE:  runFoldersRename,
+	}
+
+	var (
+		foldersRenameID      int64
+		foldersRenamePath    string
+		foldersRenameNewName string
+	)
+
+	foldersRenameCmd.Flags().Int64Var(&foldersRenameID, "id", 0, "ID of the folder to rename (see folders --action list)")
+	foldersRenameCmd.Flags().StringVar(&foldersRenamePath, "path", "", "Path of the folder to rename (e.g. Tech/Go)")
+	foldersRenameCmd.Flags().StringVar(&foldersRenameNewName, "new-name", "", "New name of the folder; its subfolders keep theirs")
+	foldersRenameCmd.MarkFlagRequired("new-name")
 
 	var tagsCmd = &cobra.Command{
 		Use:   "tags",
@@ -220,20 +681,65 @@ func main() {
 	tagsCmd.Flags().StringVar(&tagsOld, "old", "", "Old tag name for rename")
 	tagsCmd.Flags().StringVar(&tagsNew, "new", "", "New tag name for rename")
 
+	tagsCmd.AddCommand(newAliasCmd(db.AliasTag))
+	foldersCmd.AddCommand(newAliasCmd(db.AliasFolder), foldersRenameCmd)
+
 	var doctorCmd = &cobra.Command{
 		Use:   "doctor",
 		Short: "Database integrity checks and maintenance",
 		RunE:  runDoctor,
 	}
 
+	var (
+		doctorOptimize        bool
+		doctorRecanonicalize  bool
+		doctorCleanTitles     bool
+		doctorTitleConfig     string
+		doctorTitleCase       bool
+		doctorCompress        bool
+		doctorDecompress      bool
+		doctorFailOn          string
+		doctorNormalizeText   bool
+		doctorNormalizeConfig string
+	)
+	doctorCmd.Flags().BoolVar(&doctorOptimize, "optimize", false, "Also optimize the FTS index, reindex, VACUUM, and report size before/after")
+	doctorCmd.Flags().BoolVar(&doctorRecanonicalize, "recanonicalize", false, "Re-apply URL canonicalization (tracking parameter removal) and merge resulting duplicates")
+	doctorCmd.Flags().BoolVar(&doctorCleanTitles, "clean-titles", false, "Re-apply title cleanup (site-name suffixes, HTML entities, whitespace) to every article")
+	doctorCmd.Flags().StringVar(&doctorTitleConfig, "title-config", "", "YAML file with title cleanup rules for --clean-titles")
+	doctorCmd.Flags().BoolVar(&doctorTitleCase, "title-case", false, "Also convert titles to title case with --clean-titles")
+	doctorCmd.Flags().BoolVar(&doctorCompress, "compress-content", false, "Store article content zstd-compressed from now on and compress existing content (combine with --optimize to reclaim space)")
+	doctorCmd.Flags().BoolVar(&doctorDecompress, "decompress-content", false, "Store article content as plain text again and decompress existing content")
+	doctorCmd.Flags().BoolVar(&doctorNormalizeText, "normalize-text", false, "Replace curly quotes, non-breaking spaces, and HTML entities in every article's title and content with plain forms")
+	doctorCmd.Flags().StringVar(&doctorNormalizeConfig, "normalize-config", "", "YAML file choosing what --normalize-text replaces (quotes, spaces, entities, dashes, ellipses); implies --normalize-text")
+	doctorCmd.Flags().StringVar(&doctorFailOn, "fail-on", "", fmt.Sprintf("Exit with code %d when a finding is at least this severe: info, warn, or error", exitPartialFailure))
+
 	var versionCmd = &cobra.Command{
 		Use:   "version",
 		Short: "Show version information",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if wantJSON(cmd) {
+				return writeJSON(map[string]string{
+					"version":      version.GetVersion(),
+					"full_version": version.GetFullVersion(),
+				})
+			}
 			fmt.Printf("instapaper-cli %s\n", version.GetFullVersion())
+			return nil
 		},
 	}
 
+	var dbCmd = &cobra.Command{
+		Use:   "db",
+		Short: "Inspect the database",
+	}
+
+	var dbVersionCmd = &cobra.Command{
+		Use:   "version",
+		Short: "Show the database schema version and whether this release supports it",
+		RunE:  runDBVersion,
+	}
+	dbCmd.AddCommand(dbVersionCmd)
+
 	var mcpCmd = &cobra.Command{
 		Use:   "mcp",
 		Short: "Start MCP (Model Context Protocol) server",
@@ -241,6 +747,22 @@ func main() {
 		RunE:  runMCP,
 	}
 
+	var (
+		mcpReadOnly   bool
+		mcpAllow      []string
+		mcpExportRoot string
+		mcpLog        string
+		mcpLogMaxSize int
+		mcpLogBackups int
+	)
+
+	mcpCmd.Flags().BoolVar(&mcpReadOnly, "read-only", true, "Only expose tools that read the archive (--read-only=false grants every capability)")
+	mcpCmd.Flags().StringSliceVar(&mcpAllow, "allow", nil, "Capabilities to grant MCP clients in addition to read: fetch, tags, status, export, feeds")
+	mcpCmd.Flags().StringVar(&mcpExportRoot, "export-root", "", "Directory the export_to_files tool may write below (required for the export capability)")
+	mcpCmd.Flags().StringVar(&mcpLog, "log", "", "Append a JSON line per tool call (tool, arguments, duration, result size, error) to this file")
+	mcpCmd.Flags().IntVar(&mcpLogMaxSize, "log-max-size", 10, "Rotate the --log file when it reaches this many megabytes (0 never rotates)")
+	mcpCmd.Flags().IntVar(&mcpLogBackups, "log-backups", 3, "Number of rotated --log files to keep (file.1 is the newest)")
+
 	var obsoleteCmd = &cobra.Command{
 		Use:   "obsolete",
 		Short: "Mark articles as obsolete to exclude from searches and exports",
@@ -252,6 +774,9 @@ func main() {
 		obsoleteIDs         []int64
 		obsoleteStatusCodes []int
 		obsoleteFailureMin  int
+		obsoleteLinkStatus  []string
+		obsoletePolicy      bool
+		obsoleteReason      string
 		obsoleteDryRun      bool
 		obsoleteConfirm     bool
 	)
@@ -259,9 +784,79 @@ func main() {
 	obsoleteCmd.Flags().Int64SliceVar(&obsoleteIDs, "ids", nil, "Comma-separated list of article IDs to mark obsolete")
 	obsoleteCmd.Flags().IntSliceVar(&obsoleteStatusCodes, "status-codes", nil, "Mark articles with these HTTP status codes as obsolete (e.g., 404,403)")
 	obsoleteCmd.Flags().IntVar(&obsoleteFailureMin, "min-failures", 0, "Mark articles with at least this many fetch failures as obsolete")
+	obsoleteCmd.Flags().StringSliceVar(&obsoleteLinkStatus, "link-status", nil, "Mark articles whose last check-links result has these statuses as obsolete (e.g., broken,moved)")
+	obsoleteCmd.Flags().BoolVar(&obsoletePolicy, "policies", false, "Mark the articles matching the --obsolete-policies file as obsolete")
+	obsoleteCmd.Flags().StringVar(&obsoleteReason, "reason", "", "Why these articles are obsolete, kept in the audit trail shown by list-obsolete")
 	obsoleteCmd.Flags().BoolVar(&obsoleteDryRun, "dry-run", false, "Show what would be marked obsolete without making changes")
 	obsoleteCmd.Flags().BoolVar(&obsoleteConfirm, "confirm", false, "Confirm the operation (required for non-dry-run)")
 
+	var checkLinksCmd = &cobra.Command{
+		Use:   "check-links",
+		Short: "Check saved URLs for dead links, redirects, and moved domains",
+		Long:  "Send a HEAD request to each saved URL, without fetching content, and record whether it still works, redirects within the site, moved to another domain, or is gone (404/410 or unresolvable domain). Use obsolete --link-status to act on the results.",
+		RunE:  runCheckLinks,
+	}
+
+	var (
+		checkLinksIDs          []int64
+		checkLinksFolders      []string
+		checkLinksTags         []string
+		checkLinksDomains      []string
+		checkLinksStatuses     []string
+		checkLinksLimit        int
+		checkLinksConcurrency  int
+		checkLinksRate         float64
+		checkLinksRecheckAfter time.Duration
+		checkLinksAll          bool
+		checkLinksJSON         bool
+	)
+
+	checkLinksCmd.Flags().Int64SliceVar(&checkLinksIDs, "ids", nil, "Comma-separated list of article IDs to check")
+	checkLinksCmd.Flags().StringSliceVar(&checkLinksFolders, "folder", nil, "Only check articles in these folders (title or path)")
+	checkLinksCmd.Flags().StringSliceVar(&checkLinksTags, "tag", nil, "Only check articles with any of these tags")
+	checkLinksCmd.Flags().StringSliceVar(&checkLinksDomains, "domain", nil, "Only check articles from these domains (includes subdomains)")
+	checkLinksCmd.Flags().StringSliceVar(&checkLinksStatuses, "status", nil, "Only check articles with these reading statuses")
+	checkLinksCmd.Flags().IntVar(&checkLinksLimit, "limit", 0, "Maximum number of links to check, never-checked and least recently checked first (0 for all)")
+	checkLinksCmd.Flags().IntVar(&checkLinksConcurrency, "concurrency", 8, "Number of requests in flight at once")
+	checkLinksCmd.Flags().Float64Var(&checkLinksRate, "rate", 5, "Maximum requests per second (0 for unlimited)")
+	checkLinksCmd.Flags().DurationVar(&checkLinksRecheckAfter, "recheck-after", 0, "Skip links checked more recently than this, e.g. 168h (default checks every link)")
+	checkLinksCmd.Flags().BoolVar(&checkLinksAll, "all", false, "List working links too, not only problems")
+	checkLinksCmd.Flags().BoolVar(&checkLinksJSON, "json", false, "Output as JSON")
+
+	var bulkCmd = &cobra.Command{
+		Use:   "bulk",
+		Short: "Add or remove tags, move folders, and set the status of all articles matching a search",
+		Long:  "Apply tag, folder, and reading status changes to every article matching a search. Like obsolete, run with --dry-run to preview and --confirm to apply.",
+		RunE:  runBulk,
+	}
+
+	var (
+		bulkFromSearch string
+		bulkField      string
+		bulkFTS        bool
+		bulkLimit      int
+		bulkAddTags    []string
+		bulkRemoveTags []string
+		bulkSetFolder  string
+		bulkSetStatus  string
+		bulkStatuses   []string
+		bulkDryRun     bool
+		bulkConfirm    bool
+	)
+
+	bulkCmd.Flags().StringVar(&bulkFromSearch, "from-search", "", "Search query selecting the articles to change (required)")
+	bulkCmd.Flags().StringVar(&bulkField, "field", "", "Search specific field: url, title, content, selection, tags, folder")
+	bulkCmd.Flags().BoolVar(&bulkFTS, "fts", false, "Use full-text search")
+	bulkCmd.Flags().IntVar(&bulkLimit, "limit", 0, "Maximum number of articles to change (0 for all matches)")
+	bulkCmd.Flags().StringSliceVar(&bulkAddTags, "add-tag", nil, "Tags to add (repeatable or comma-separated)")
+	bulkCmd.Flags().StringSliceVar(&bulkRemoveTags, "remove-tag", nil, "Tags to remove (repeatable or comma-separated)")
+	bulkCmd.Flags().StringVar(&bulkSetFolder, "set-folder", "", "Move articles into this folder or path, creating it if needed")
+	bulkCmd.Flags().StringVar(&bulkSetStatus, "set-status", "", "Set the reading status of the articles: "+strings.Join(db.Statuses, ", "))
+	bulkCmd.Flags().StringSliceVar(&bulkStatuses, "status", nil, "Only change articles with these reading statuses")
+	bulkCmd.Flags().BoolVar(&bulkDryRun, "dry-run", false, "Show what would change without making changes")
+	bulkCmd.Flags().BoolVar(&bulkConfirm, "confirm", false, "Confirm the operation (required for non-dry-run)")
+	bulkCmd.MarkFlagRequired("from-search")
+
 	var listObsoleteCmd = &cobra.Command{
 		Use:   "list-obsolete",
 		Short: "List articles marked as obsolete",
@@ -270,12 +865,182 @@ func main() {
 	}
 
 	var (
-		listObsoleteJSON  bool
-		listObsoleteLimit int
+		listObsoleteJSON    bool
+		listObsoleteLimit   int
+		listObsoleteHistory int64
 	)
 
 	listObsoleteCmd.Flags().BoolVar(&listObsoleteJSON, "json", false, "Output results as JSON")
 	listObsoleteCmd.Flags().IntVar(&listObsoleteLimit, "limit", 100, "Maximum number of obsolete articles to show")
+	listObsoleteCmd.Flags().Int64Var(&listObsoleteHistory, "history", 0, "Show every obsolete and restore action recorded for this article ID")
+
+	var restoreCmd = &cobra.Command{
+		Use:   "restore",
+		Short: "Restore obsolete articles",
+		Long:  "Clear the obsolete flag on articles so they are searchable, exportable, and fetchable again",
+		RunE:  runRestore,
+	}
+
+	var (
+		restoreIDs    []int64
+		restoreReason string
+	)
+	restoreCmd.Flags().Int64SliceVar(&restoreIDs, "ids", nil, "Comma-separated list of article IDs to restore (required)")
+	restoreCmd.Flags().StringVar(&restoreReason, "reason", "", "Why these articles are restored, kept in the audit trail")
+
+	var trashCmd = &cobra.Command{
+		Use:   "trash",
+		Short: "Move articles to the trash",
+		Long:  "Move articles to the trash, hiding them from searches, exports, and fetches like obsolete articles. Trashed articles can be restored until the trash is emptied, which deletes them for good.",
+		RunE:  runTrash,
+	}
+
+	var (
+		trashIDs    []int64
+		trashReason string
+	)
+	trashCmd.Flags().Int64SliceVar(&trashIDs, "id", nil, "Comma-separated list of article IDs to trash (required)")
+	trashCmd.Flags().StringVar(&trashReason, "reason", "", "Why these articles are trashed, shown by trash list")
+
+	var trashListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List trashed articles, most recently trashed first",
+		RunE:  runTrashList,
+	}
+
+	var (
+		trashListOlderThan string
+		trashListLimit     int
+	)
+	trashListCmd.Flags().StringVar(&trashListOlderThan, "older-than", "", "Only list articles trashed longer ago than this, e.g. 30d")
+	trashListCmd.Flags().IntVar(&trashListLimit, "limit", 100, "Maximum number of articles to show (0 for all)")
+
+	var trashRestoreCmd = &cobra.Command{
+		Use:   "restore",
+		Short: "Take articles out of the trash",
+		RunE:  runTrashRestore,
+	}
+
+	var trashRestoreIDs []int64
+	trashRestoreCmd.Flags().Int64SliceVar(&trashRestoreIDs, "id", nil, "Comma-separated list of article IDs to restore (required)")
+
+	var trashEmptyCmd = &cobra.Command{
+		Use:   "empty",
+		Short: "Delete trashed articles for good",
+		Long:  "Delete trashed articles for good. Without --confirm this only shows what would be deleted. Deletions are journaled and can be reverted with undo --operation-id, which puts the articles back in the trash.",
+		RunE:  runTrashEmpty,
+	}
+
+	var (
+		trashEmptyOlderThan string
+		trashEmptyDryRun    bool
+		trashEmptyConfirm   bool
+	)
+	trashEmptyCmd.Flags().StringVar(&trashEmptyOlderThan, "older-than", "", "Only delete articles trashed longer ago than this, e.g. 30d (default: the whole trash)")
+	trashEmptyCmd.Flags().BoolVar(&trashEmptyDryRun, "dry-run", false, "Show what would be deleted without deleting it (the default without --confirm)")
+	trashEmptyCmd.Flags().BoolVar(&trashEmptyConfirm, "confirm", false, "Delete the articles; without it nothing is deleted")
+	trashCmd.AddCommand(trashListCmd, trashRestoreCmd, trashEmptyCmd)
+
+	var undoCmd = &cobra.Command{
+		Use:   "undo",
+		Short: "Undo a journaled bulk operation",
+		Long:  "Revert an obsolete, restore, bulk, status, or tag rename/merge operation using the operations journal. Use --list to find operation IDs.",
+		RunE:  runUndo,
+	}
+
+	var (
+		undoOperationID int64
+		undoList        bool
+		undoLimit       int
+		undoDryRun      bool
+		undoJSON        bool
+	)
+	undoCmd.Flags().Int64Var(&undoOperationID, "operation-id", 0, "ID of the operation to undo")
+	undoCmd.Flags().BoolVar(&undoList, "list", false, "List recent operations that can be undone")
+	undoCmd.Flags().IntVar(&undoLimit, "limit", 20, "Maximum number of operations to list")
+	undoCmd.Flags().BoolVar(&undoDryRun, "dry-run", false, "Show the changes that would be reverted without reverting them")
+	undoCmd.Flags().BoolVar(&undoJSON, "json", false, "Output as JSON")
+
+	var rulesCmd = &cobra.Command{
+		Use:   "rules",
+		Short: "Work with the --tag-rules file",
+	}
+
+	var rulesTestCmd = &cobra.Command{
+		Use:   "test [url]",
+		Short: "Show which tag rules match a URL or the saved articles, without changing anything",
+		Long:  "Evaluate the rules from --tag-rules against a URL (and --title), or against every saved article when no URL is given. Nothing is changed.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runRulesTest,
+	}
+
+	var (
+		rulesTestTitle string
+		rulesTestLimit int
+		rulesTestJSON  bool
+	)
+	rulesTestCmd.Flags().StringVar(&rulesTestTitle, "title", "", "Title to test together with the URL")
+	rulesTestCmd.Flags().IntVar(&rulesTestLimit, "limit", 0, "Maximum number of matching articles to list (0 for all)")
+	rulesTestCmd.Flags().BoolVar(&rulesTestJSON, "json", false, "Output as JSON")
+	rulesCmd.AddCommand(rulesTestCmd)
+
+	var statusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Count articles per reading status",
+		Long:  "Articles move through the reading statuses inbox (new, untriaged), reading (reading now), someday (parked for later), read, and archived. Without a subcommand, count the articles in each status. Triage with status set and filter with --status on search, latest, random, fetch, check-links, export-all, and bulk.",
+		RunE:  runStatus,
+	}
+
+	var statusJSON bool
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "Output as JSON")
+
+	var statusSetCmd = &cobra.Command{
+		Use:   "set <status>",
+		Short: "Set the reading status of articles",
+		Long:  "Set the reading status of articles to inbox, reading, someday, read, or archived. Any status can follow any other. Changes are journaled and can be reverted with undo --operation-id.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runStatusSet,
+	}
+
+	var (
+		statusSetIDs  []int64
+		statusSetJSON bool
+	)
+	statusSetCmd.Flags().Int64SliceVar(&statusSetIDs, "ids", nil, "Comma-separated list of article IDs to update (required)")
+	statusSetCmd.Flags().BoolVar(&statusSetJSON, "json", false, "Output as JSON")
+	statusSetCmd.MarkFlagRequired("ids")
+	statusCmd.AddCommand(statusSetCmd)
+
+	var goalsCmd = &cobra.Command{
+		Use:   "goals",
+		Short: "Show progress on reading goals",
+		Long:  "Show this week's and month's progress on reading goals, counted from articles marked read (status set read), with the streak of periods each goal was met.",
+		RunE:  runGoals,
+	}
+
+	var goalsJSON bool
+	goalsCmd.Flags().BoolVar(&goalsJSON, "json", false, "Output as JSON")
+
+	var goalsSetCmd = &cobra.Command{
+		Use:   "set <articles|words> <target>",
+		Short: "Set a reading goal, e.g. goals set articles 5",
+		Args:  cobra.ExactArgs(2),
+		RunE:  runGoalsSet,
+	}
+
+	var goalsSetPer string
+	goalsSetCmd.Flags().StringVar(&goalsSetPer, "per", db.PeriodWeek, "Period the goal starts over: week or month")
+
+	var goalsRemoveCmd = &cobra.Command{
+		Use:   "remove <articles|words>",
+		Short: "Remove a reading goal",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runGoalsRemove,
+	}
+
+	var goalsRemovePer string
+	goalsRemoveCmd.Flags().StringVar(&goalsRemovePer, "per", db.PeriodWeek, "Period of the goal to remove: week or month")
+	goalsCmd.AddCommand(goalsSetCmd, goalsRemoveCmd)
 
 	var statsCmd = &cobra.Command{
 		Use:   "stats",
@@ -284,8 +1049,12 @@ func main() {
 		RunE:  runStats,
 	}
 
-	var statsJSON bool
+	var (
+		statsJSON bool
+		statsBy   string
+	)
 	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "Output statistics as JSON")
+	statsCmd.Flags().StringVar(&statsBy, "by", "", "Break statistics down by 'folder', 'tag', 'year', or 'domain'")
 
 	// RSS commands
 	var rssCmd = &cobra.Command{
@@ -295,6 +1064,9 @@ func main() {
 		RunE:  runRSSSync,
 	}
 
+	var rssNoPrettify bool
+	rssCmd.Flags().BoolVar(&rssNoPrettify, "no-prettify", false, "Store the content of use-content feeds as-is without blank-line and tracker cleanup")
+
 	var rssAddCmd = &cobra.Command{
 		Use:   "rss:add [url]",
 		Short: "Add a new RSS feed",
@@ -303,12 +1075,39 @@ func main() {
 	}
 
 	var (
-		rssAddName string
-		rssAddTags string
+		rssAddName       string
+		rssAddTags       string
+		rssAddUseContent bool
+		rssAddBackfill   string
 	)
 
 	rssAddCmd.Flags().StringVar(&rssAddName, "name", "instapaper", "Feed name")
 	rssAddCmd.Flags().StringVar(&rssAddTags, "tags", "", "Comma-separated tags to apply to all articles from this feed")
+	rssAddCmd.Flags().BoolVar(&rssAddUseContent, "use-content", false, "Store the full content items embed (content:encoded) at sync time instead of fetching them")
+	rssAddCmd.Flags().StringVar(&rssAddBackfill, "backfill", rss.BackfillAll, "History the first sync ingests: all, none (only items published later), or an age like 30d")
+
+	var rssDiscoverCmd = &cobra.Command{
+		Use:   "rss:discover",
+		Short: "Find the RSS feeds of the sites you save articles from",
+		Long:  "Look for the RSS feeds of the domains you have saved the most articles from (or of the site of --url), from the feed links on their home pages or common feed paths, and list them. --subscribe adds them all, --interactive asks for each. Sites with a feed in rss:list are skipped.",
+		RunE:  runRSSDiscover,
+	}
+
+	var (
+		rssDiscoverURL         string
+		rssDiscoverMinArticles int
+		rssDiscoverLimit       int
+		rssDiscoverTags        int
+		rssDiscoverSubscribe   bool
+		rssDiscoverInteractive bool
+	)
+
+	rssDiscoverCmd.Flags().StringVar(&rssDiscoverURL, "url", "", "Look for the feed of this page's site instead of the sites of saved articles")
+	rssDiscoverCmd.Flags().IntVar(&rssDiscoverMinArticles, "min-articles", 3, "Only check domains with at least this many saved articles")
+	rssDiscoverCmd.Flags().IntVar(&rssDiscoverLimit, "limit", 20, "Check at most this many domains, those with the most saved articles first")
+	rssDiscoverCmd.Flags().IntVar(&rssDiscoverTags, "tags", 3, "Tag each feed with up to this many of the tags most used on its domain's saved articles (0 for none)")
+	rssDiscoverCmd.Flags().BoolVar(&rssDiscoverSubscribe, "subscribe", false, "Subscribe to every feed found")
+	rssDiscoverCmd.Flags().BoolVar(&rssDiscoverInteractive, "interactive", false, "Ask whether to subscribe to each feed found")
 
 	var rssListCmd = &cobra.Command{
 		Use:   "rss:list",
@@ -336,505 +1135,3601 @@ func main() {
 	}
 
 	var (
-		rssUpdateID   int64
-		rssUpdateName string
-		rssUpdateTags string
+		rssUpdateID         int64
+		rssUpdateName       string
+		rssUpdateTags       string
+		rssUpdateUseContent bool
+		rssUpdateBackfill   string
 	)
 
 	rssUpdateCmd.Flags().Int64Var(&rssUpdateID, "id", 0, "Feed ID to update (required)")
 	rssUpdateCmd.Flags().StringVar(&rssUpdateName, "name", "", "New feed name")
 	rssUpdateCmd.Flags().StringVar(&rssUpdateTags, "tags", "", "Comma-separated tags (replaces existing tags)")
+	rssUpdateCmd.Flags().BoolVar(&rssUpdateUseContent, "use-content", false, "Store the full content items embed at sync time instead of fetching them (--use-content=false to fetch again)")
+	rssUpdateCmd.Flags().StringVar(&rssUpdateBackfill, "backfill", "", "History the first sync ingests: all, none, or an age like 30d (only before the feed's first sync)")
 	rssUpdateCmd.MarkFlagRequired("id")
 
-	rootCmd.AddCommand(importCmd, fetchCmd, searchCmd, latestCmd, exportCmd, exportAllCmd, foldersCmd, tagsCmd, doctorCmd, versionCmd, mcpCmd, obsoleteCmd, listObsoleteCmd, statsCmd, rssCmd, rssAddCmd, rssListCmd, rssDeleteCmd, rssUpdateCmd)
+	var serveCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Run as a daemon exposing Prometheus metrics",
+		Long:  "Serve a /metrics endpoint for Prometheus and optionally sync RSS feeds and fetch articles on an interval, so archive health can be graphed over time. With a fetch window, from the daemon block of the config file or --fetch-window, articles are fetched only in that daily window, one at a time and spread across it, so a large backlog drains slowly without noticeable load.",
+		RunE:  runServe,
+	}
+
+	var (
+		serveAddr        string
+		serveInterval    time.Duration
+		serveFetchLimit  int
+		serveFetchWindow string
+		serveWindowLimit int
+		serveDomainCap   int
+	)
 
-	if err := rootCmd.Execute(); err != nil {
-		log.Fatal(err)
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":9090", "Address to serve /metrics on")
+	serveCmd.Flags().DurationVar(&serveInterval, "interval", 0, "Sync RSS feeds and fetch articles on this interval (e.g. 30m, 0 disables)")
+	serveCmd.Flags().IntVar(&serveFetchLimit, "fetch-limit", 10, "Maximum number of articles to fetch per interval")
+	serveCmd.Flags().StringVar(&serveFetchWindow, "fetch-window", "", "Fetch only during this daily window, local time (e.g. 01:00-06:00), spreading --window-limit articles across it (overrides daemon.fetch_window in the config file)")
+	serveCmd.Flags().IntVar(&serveWindowLimit, "window-limit", 100, "Maximum number of articles to fetch per --fetch-window (overrides daemon.window_limit)")
+	serveCmd.Flags().IntVar(&serveDomainCap, "domain-daily-cap", 0, "Maximum fetches per domain per day, 0 for no cap (overrides daemon.domain_daily_cap)")
+
+	var daemonCmd = &cobra.Command{
+		Use:   "daemon",
+		Short: "Watch a folder and import CSV exports dropped into it",
+		Long:  "Watch a folder (e.g. ~/Downloads) for Instapaper or Pocket CSV exports and import them automatically. Imported files are moved to a processed subfolder and files that fail to import to a failed subfolder; other CSV files are left alone.",
+		RunE:  runDaemon,
 	}
 
-	if database != nil {
-		database.Close()
+	var (
+		daemonWatchDir       string
+		daemonProcessedDir   string
+		daemonFailedDir      string
+		daemonInterval       time.Duration
+		daemonSplitFolders   bool
+		daemonFetchLimit     int
+		daemonTrashRetention string
+		daemonMetricsAddr    string
+	)
+
+	daemonCmd.Flags().StringVar(&daemonWatchDir, "watch-dir", "", "Folder to watch for CSV exports (required)")
+	daemonCmd.Flags().StringVar(&daemonProcessedDir, "processed-dir", "", "Where imported files are moved (default <watch-dir>/processed)")
+	daemonCmd.Flags().StringVar(&daemonFailedDir, "failed-dir", "", "Where files that fail to import are moved (default <watch-dir>/failed)")
+	daemonCmd.Flags().DurationVar(&daemonInterval, "interval", 10*time.Second, "How often to scan the folder")
+	daemonCmd.Flags().BoolVar(&daemonSplitFolders, "split-folders", false, "Treat slashes in folder names as a hierarchy (Tech/Go becomes Go inside Tech)")
+	addTaxonomyFlags(daemonCmd)
+	daemonCmd.Flags().IntVar(&daemonFetchLimit, "fetch-limit", 0, "Fetch up to this many new articles after each import (0 disables)")
+	daemonCmd.Flags().StringVar(&daemonTrashRetention, "trash-retention", "", "Delete articles trashed longer ago than this, e.g. 30d, checked hourly (default keeps the trash)")
+	daemonCmd.Flags().StringVar(&daemonMetricsAddr, "metrics-addr", "", "Also serve Prometheus /metrics for this daemon's imports and fetches on this address, e.g. :9091 (default off)")
+	daemonCmd.MarkFlagRequired("watch-dir")
+
+	var ingestEmailCmd = &cobra.Command{
+		Use:   "ingest:email [file.eml...]",
+		Short: "Turn newsletter emails into articles",
+		Long:  "Read the unread messages of an IMAP mailbox (or .eml files given as arguments) and save each as an article: tagged newsletter, filed in a folder named after the sender, and linked to the newsletter's web version when it has a \"View in browser\" link. Messages that were saved are marked read, and moved with --move-to. The password is read from the environment variable named by --password-env.",
+		RunE:  runIngestEmail,
 	}
-}
 
-func runImport(cmd *cobra.Command, args []string) error {
-	csvPath, _ := cmd.Flags().GetString("csv")
+	var (
+		ingestEmailServer       string
+		ingestEmailUser         string
+		ingestEmailPasswordEnv  string
+		ingestEmailMailbox      string
+		ingestEmailMoveTo       string
+		ingestEmailAll          bool
+		ingestEmailLimit        int
+		ingestEmailTags         []string
+		ingestEmailFolderPrefix string
+		ingestEmailDryRun       bool
+		ingestEmailNoPrettify   bool
+	)
 
-	if _, err := os.Stat(csvPath); os.IsNotExist(err) {
-		fmt.Printf("CSV file does not exist: %s\n", csvPath)
-		return fmt.Errorf("CSV file does not exist: %s", csvPath)
+	ingestEmailCmd.Flags().StringVar(&ingestEmailServer, "server", "", "IMAP server as host:port, reached over TLS (e.g. imap.fastmail.com:993)")
+	ingestEmailCmd.Flags().StringVar(&ingestEmailUser, "user", "", "IMAP username")
+	ingestEmailCmd.Flags().StringVar(&ingestEmailPasswordEnv, "password-env", "IMAP_PASSWORD", "Environment variable holding the IMAP password")
+	ingestEmailCmd.Flags().StringVar(&ingestEmailMailbox, "mailbox", "INBOX", "Mailbox to read newsletters from")
+	ingestEmailCmd.Flags().StringVar(&ingestEmailMoveTo, "move-to", "", "Move processed messages to this mailbox")
+	ingestEmailCmd.Flags().BoolVar(&ingestEmailAll, "all", false, "Also read messages already marked read")
+	ingestEmailCmd.Flags().IntVar(&ingestEmailLimit, "limit", 0, "Read at most this many messages, oldest first (0 for all)")
+	ingestEmailCmd.Flags().StringSliceVar(&ingestEmailTags, "tag", []string{ingest.DefaultTag}, "Tags for the new articles (repeatable or comma-separated)")
+	ingestEmailCmd.Flags().StringVar(&ingestEmailFolderPrefix, "folder-prefix", "Newsletters", "Parent folder of the per-sender folders (empty files them at the top level)")
+	ingestEmailCmd.Flags().BoolVar(&ingestEmailDryRun, "dry-run", false, "Show what would be saved without changing the database or the mailbox")
+	ingestEmailCmd.Flags().BoolVar(&ingestEmailNoPrettify, "no-prettify", false, "Store converted Markdown as-is without blank-line and tracker cleanup")
+
+	var botCmd = &cobra.Command{
+		Use:   "bot",
+		Short: "Run a Telegram bot that saves links and answers searches",
+		Long:  "Run a Telegram bot configured by a YAML file. Links sent to it are saved as articles (and fetched with fetch: true), and other messages are searched for, with dates like \"last month\" or \"this week\" read as saved-date filters. Only the chats in allowed_chats are answered.",
+		RunE:  runBot,
 	}
 
-	imp := importer.New(database)
-	return imp.ImportCSV(csvPath)
-}
+	var botConfigPath string
+	botCmd.Flags().StringVar(&botConfigPath, "config", "", "Bot config file (YAML, required)")
+	botCmd.MarkFlagRequired("config")
 
-func runFetch(cmd *cobra.Command, args []string) error {
-	order, _ := cmd.Flags().GetString("order")
-	searchPhrase, _ := cmd.Flags().GetString("search")
-	limit, _ := cmd.Flags().GetInt("limit")
-	preferExtracted, _ := cmd.Flags().GetBool("prefer-extracted-title")
-	storeRaw, _ := cmd.Flags().GetBool("store-raw")
-	logPath, _ := cmd.Flags().GetString("log")
+	rootCmd.AddCommand(importCmd, addCmd, fetchCmd, searchCmd, latestCmd, randomCmd, similarCmd, suggestTagsCmd, showCmd, grepCmd, openCmd, attachmentsCmd, exportCmd, exportAllCmd, foldersCmd, tagsCmd, doctorCmd, checkLinksCmd, versionCmd, dbCmd, mcpCmd, obsoleteCmd, listObsoleteCmd, restoreCmd, trashCmd, bulkCmd, undoCmd, rulesCmd, statusCmd, goalsCmd, statsCmd, rssCmd, rssAddCmd, rssDiscoverCmd, rssListCmd, rssDeleteCmd, rssUpdateCmd, serveCmd, daemonCmd, ingestEmailCmd, botCmd)
 
-	opts := fetcher.FetchOptions{
-		Order:            order,
-		SearchPhrase:     searchPhrase,
-		Limit:            limit,
-		PreferExtracted:  preferExtracted,
-		StoreRaw:         storeRaw,
-		LogPath:          logPath,
+	cfg, err := config.Load(config.Path())
+	if err != nil {
+		log.Fatal(err)
+	}
+	userConfig = cfg
+	args, err := expandArgs(rootCmd, os.Args[1:], cfg)
+	if err != nil {
+		log.Fatal(err)
 	}
+	rootCmd.SetArgs(args)
+
+	// Ctrl-C and SIGTERM cancel the command's context so long operations stop early
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		var partial *partialFailureError
+		if errors.As(err, &partial) {
+			writeLock.Release()
+			if database != nil {
+				database.Close()
+			}
+			os.Exit(exitPartialFailure)
+		}
 
-	f := fetcher.New(database)
-	return f.FetchArticles(opts)
+		if outputFormat == "json" {
+			writeJSON(map[string]string{"error": err.Error()})
+		}
+		log.Fatal(err)
+	}
+
+	writeLock.Release()
+	if database != nil {
+		database.Close()
+	}
 }
 
-func runSearch(cmd *cobra.Command, args []string) error {
-	var query string
-	if len(args) > 0 {
-		query = args[0]
+// writeCommands take the single-writer lock before they run. A check, when set, decides from the
+// flags whether this invocation writes; --dry-run never takes the lock.
+var writeCommands = map[string]func(cmd *cobra.Command) bool{
+	"import":               nil,
+	"add":                  nil,
+	"fetch":                nil,
+	"doctor":               nil,
+	"check-links":          nil,
+	"obsolete":             nil,
+	"restore":              nil,
+	"trash":                nil,
+	"trash restore":        nil,
+	"trash empty":          nil,
+	"bulk":                 nil,
+	"status set":           nil,
+	"goals set":            nil,
+	"goals remove":         nil,
+	"undo":                 func(cmd *cobra.Command) bool { return !cmd.Flags().Changed("list") },
+	"rss":                  nil,
+	"rss:add":              nil,
+	"rss:discover":         func(cmd *cobra.Command) bool { return cmd.Flags().Changed("subscribe") || cmd.Flags().Changed("interactive") },
+	"rss:delete":           nil,
+	"rss:update":           nil,
+	"ingest:email":         nil,
+	"tags":                 actionWrites,
+	"folders":              actionWrites,
+	"tags alias add":       nil,
+	"tags alias remove":    nil,
+	"folders alias add":    nil,
+	"folders alias remove": nil,
+	"folders rename":       nil,
+	"suggest-tags":         func(cmd *cobra.Command) bool { return cmd.Flags().Changed("apply") || cmd.Flags().Changed("interactive") },
+	"attachments":          func(cmd *cobra.Command) bool { return cmd.Flags().Changed("add") },
+}
+
+// expandArgs replaces a config alias in the command position with its command line, and runs add
+// when the command position holds a URL. Global flags may come before either.
+func expandArgs(rootCmd *cobra.Command, args []string, cfg *config.Config) ([]string, error) {
+	for name := range cfg.Aliases {
+		if isCommand(rootCmd, name) {
+			return nil, fmt.Errorf("config alias %q has the name of a command", name)
+		}
 	}
 
-	field, _ := cmd.Flags().GetString("field")
-	useFTS, _ := cmd.Flags().GetBool("fts")
-	limit, _ := cmd.Flags().GetInt("limit")
-	jsonOutput, _ := cmd.Flags().GetBool("json")
-	since, _ := cmd.Flags().GetString("since")
-	until, _ := cmd.Flags().GetString("until")
+	i := 0
+	for i < len(args) && strings.HasPrefix(args[i], "-") {
+		if args[i] == "--" {
+			return args, nil
+		}
+		// A global flag given as "--name value" is followed by its value
+		name := strings.TrimLeft(args[i], "-")
+		if flag := rootCmd.PersistentFlags().Lookup(name); flag != nil && flag.NoOptDefVal == "" {
+			i++
+		}
+		i++
+	}
+	if i >= len(args) || isCommand(rootCmd, args[i]) {
+		return args, nil
+	}
 
-	opts := search.SearchOptions{
-		Query:      query,
-		Field:      field,
-		UseFTS:     useFTS,
-		Limit:      limit,
-		JSONOutput: jsonOutput,
-		Since:      since,
-		Until:      until,
+	var replacement []string
+	if words, ok := cfg.Alias(args[i]); ok {
+		replacement = words
+	} else if strings.HasPrefix(args[i], "http://") || strings.HasPrefix(args[i], "https://") {
+		replacement = []string{"add", args[i]}
+	} else {
+		return args, nil
 	}
 
-	s := search.New(database)
-	return s.Search(opts)
+	expanded := append(slices.Clone(args[:i]), replacement...)
+	return append(expanded, args[i+1:]...), nil
 }
 
-func runLatest(cmd *cobra.Command, args []string) error {
-	limit, _ := cmd.Flags().GetInt("limit")
-	jsonOutput, _ := cmd.Flags().GetBool("json")
-	since, _ := cmd.Flags().GetString("since")
-	until, _ := cmd.Flags().GetString("until")
-
-	// Use search functionality with empty query to get all articles
-	opts := search.SearchOptions{
-		Query:      "",
-		Field:      "",
-		UseFTS:     false,
-		Limit:      limit,
-		JSONOutput: jsonOutput,
-		Since:      since,
-		Until:      until,
+// isCommand reports whether name runs one of the root command's subcommands, including the ones
+// cobra adds
+func isCommand(rootCmd *cobra.Command, name string) bool {
+	if name == "help" || name == "completion" || strings.HasPrefix(name, "__") {
+		return true
+	}
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.Name() == name || cmd.HasAlias(name) {
+			return true
+		}
 	}
+	return false
+}
 
-	s := search.New(database)
-	return s.Search(opts)
+// commandName is a command's path below the root command, e.g. "tags alias add"
+func commandName(cmd *cobra.Command) string {
+	return strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
 }
 
-func runExport(cmd *cobra.Command, args []string) error {
-	id, _ := cmd.Flags().GetInt64("id")
-	outPath, _ := cmd.Flags().GetString("out")
-	stdout, _ := cmd.Flags().GetBool("stdout")
+func needsWriteLock(cmd *cobra.Command) bool {
+	check, ok := writeCommands[commandName(cmd)]
+	if !ok {
+		return false
+	}
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		return false
+	}
+	return check == nil || check(cmd)
+}
 
-	if !stdout && outPath == "" {
-		return fmt.Errorf("either --out or --stdout must be specified")
+// actionWrites reports whether a tags or folders --action changes anything
+func actionWrites(cmd *cobra.Command) bool {
+	action, _ := cmd.Flags().GetString("action")
+	return action != "list"
+}
+
+// lockError points a writer that found the database busy at --lock-wait
+func lockError(err error) error {
+	var locked *db.LockedError
+	if errors.As(err, &locked) {
+		return fmt.Errorf("%w; wait for it to finish or queue behind it with --lock-wait (e.g. --lock-wait 10m)", err)
 	}
+	return err
+}
 
-	e := export.New(database)
-	return e.ExportArticle(id, outPath, stdout)
+func runImport(cmd *cobra.Command, args []string) error {
+	csvPath, _ := cmd.Flags().GetString("csv")
+	splitFolders, _ := cmd.Flags().GetBool("split-folders")
+	atomic, _ := cmd.Flags().GetBool("atomic")
+
+	if _, err := os.Stat(csvPath); os.IsNotExist(err) {
+		if !wantJSON(cmd) {
+			fmt.Printf("CSV file does not exist: %s\n", csvPath)
+		}
+		return fmt.Errorf("CSV file does not exist: %s", csvPath)
+	}
+
+	imp := importer.New(database)
+	importOpts := importer.ImportOptions{SplitFolders: splitFolders, Rules: tagRules, Atomic: atomic}
+	if err := applyTaxonomyFlags(cmd, &importOpts); err != nil {
+		return err
+	}
+
+	result, err := imp.ImportCSV(cmd.Context(), csvPath, importOpts)
+	if result == nil {
+		return err
+	}
+
+	if wantJSON(cmd) {
+		if err := writeJSON(result); err != nil {
+			return err
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("import interrupted: %w", err)
+	}
+
+	return checkPartialFailure(cmd, result.Skipped, result.Total)
 }
 
-func runExportAll(cmd *cobra.Command, args []string) error {
-	dir, _ := cmd.Flags().GetString("dir")
-	onlySynced, _ := cmd.Flags().GetBool("only-synced")
-	includeUnsynced, _ := cmd.Flags().GetBool("include-unsynced")
-	folder, _ := cmd.Flags().GetString("folder")
-	tag, _ := cmd.Flags().GetString("tag")
+// DBVersionReport is the output of db version
+type DBVersionReport struct {
+	*db.SchemaInfo
+	AppVersion string `json:"app_version"`
+}
+
+func runDBVersion(cmd *cobra.Command, args []string) error {
+	fsys, err := schemaMigrations()
+	if err != nil {
+		return err
+	}
+	info, err := database.SchemaVersion(fsys)
+	if err != nil {
+		return err
+	}
+
+	if wantJSON(cmd) {
+		return writeJSON(DBVersionReport{SchemaInfo: info, AppVersion: version.GetVersion()})
+	}
+
+	fmt.Printf("Database:  %s\n", dbPath)
+	if info.AppliedAt != "" {
+		fmt.Printf("Schema:    %d (migrated %s)\n", info.Version, info.AppliedAt)
+	} else {
+		fmt.Printf("Schema:    %d\n", info.Version)
+	}
+	fmt.Printf("Supported: %d (instapaper-cli %s)\n", info.Supported, version.GetVersion())
+
+	switch {
+	case !info.Compatible:
+		fmt.Printf("Status:    too new, created by a newer release; upgrade instapaper-cli to use it\n")
+		for _, name := range info.Unknown {
+			fmt.Printf("  unknown migration %s\n", name)
+		}
+	case len(info.Pending) > 0:
+		fmt.Printf("Status:    %d pending migrations\n", len(info.Pending))
+		for _, name := range info.Pending {
+			fmt.Printf("  %s\n", name)
+		}
+	default:
+		fmt.Printf("Status:    up to date\n")
+	}
+	return nil
+}
+
+func runFetch(cmd *cobra.Command, args []string) error {
+	order, _ := cmd.Flags().GetString("order")
+	searchPhrase, _ := cmd.Flags().GetString("search")
+	limit, _ := cmd.Flags().GetInt("limit")
+	preferExtracted, _ := cmd.Flags().GetBool("prefer-extracted-title")
+	storeRaw, _ := cmd.Flags().GetBool("store-raw")
+	logPath, _ := cmd.Flags().GetString("log")
+	noPrettify, _ := cmd.Flags().GetBool("no-prettify")
+	priorityConfig, _ := cmd.Flags().GetString("priority-config")
+	folders, _ := cmd.Flags().GetStringSlice("folder")
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+	ids, _ := cmd.Flags().GetInt64Slice("ids")
+	domains, _ := cmd.Flags().GetStringSlice("domain")
+	statusCodes, _ := cmd.Flags().GetIntSlice("status-code")
+	updateURL, _ := cmd.Flags().GetBool("update-url")
+	statuses, err := statusFilter(cmd)
+	if err != nil {
+		return err
+	}
+	domainRules, _ := cmd.Flags().GetString("domain-rules")
+	minContentLength, _ := cmd.Flags().GetInt("min-content-length")
+	keepImages, _ := cmd.Flags().GetBool("keep-images")
+	keepByline, _ := cmd.Flags().GetBool("keep-byline")
+	lowQuality, _ := cmd.Flags().GetBool("low-quality")
+	garbled, _ := cmd.Flags().GetBool("garbled")
+
+	if minContentLength < 0 {
+		return fmt.Errorf("--min-content-length cannot be negative")
+	}
+	if lowQuality && garbled {
+		return fmt.Errorf("--low-quality and --garbled cannot be combined")
+	}
+
+	cleaner, err := loadTitleCleaner(cmd)
+	if err != nil {
+		return err
+	}
+
+	rules, err := fetcher.LoadDomainRules(domainRules)
+	if err != nil {
+		return err
+	}
+
+	switch order {
+	case "oldest", "newest", "priority":
+	default:
+		return fmt.Errorf("invalid order %q: must be 'oldest', 'newest', or 'priority'", order)
+	}
+
+	weights, err := fetcher.LoadPriorityWeights(priorityConfig)
+	if err != nil {
+		return err
+	}
+
+	normalizer, err := loadNormalizer(cmd)
+	if err != nil {
+		return err
+	}
+
+	opts := fetcher.FetchOptions{
+		Order:            order,
+		SearchPhrase:     searchPhrase,
+		Limit:            limit,
+		PreferExtracted:  preferExtracted,
+		StoreRaw:         storeRaw,
+		LogPath:          logPath,
+		NoPrettify:       noPrettify,
+		Priority:         weights,
+		Selector: search.Selector{
+			IDs:         ids,
+			Folders:     folders,
+			Tags:        tags,
+			Domains:     domains,
+			StatusCodes: statusCodes,
+			Statuses:    statuses,
+		},
+		UpdateURL:        updateURL,
+		Titles:           &cleaner,
+		Rules:            rules,
+		MinContentLength: minContentLength,
+		KeepImages:       keepImages,
+		KeepByline:       keepByline,
+		LowQuality:       lowQuality,
+		Garbled:          garbled,
+		Normalize:        normalizer,
+	}
+	if opts.Selector, err = opts.Selector.WithAliases(database); err != nil {
+		return err
+	}
+
+	f := fetcher.New(database)
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		plan, err := f.PlanFetch(cmd.Context(), opts)
+		if err != nil {
+			return err
+		}
+		if wantJSON(cmd) {
+			return writeJSON(plan)
+		}
+		printFetchPlan(plan)
+		return nil
+	}
+
+	result, err := f.FetchArticles(cmd.Context(), opts)
+	if result == nil {
+		return err
+	}
+
+	if wantJSON(cmd) {
+		if err := writeJSON(result); err != nil {
+			return err
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("fetch interrupted: %w", err)
+	}
+
+	return checkPartialFailure(cmd, result.Failed, result.Candidates)
+}
+
+// printFetchPlan prints the articles a fetch would process and a summary of the excluded ones
+func printFetchPlan(plan *fetcher.FetchPlan) {
+	if len(plan.Planned) == 0 {
+		fmt.Println("No articles would be fetched.")
+	} else {
+		skipped := 0
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "#\tID\tACTION\tTITLE\tURL")
+		for _, planned := range plan.Planned {
+			if planned.Action == "skip" {
+				skipped++
+			}
+			action := planned.Action
+			if planned.Rule != "" && action != "fetch" {
+				action += " (" + planned.Rule + ")"
+			}
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\n", planned.Position, planned.ID, action, truncate(planned.Title, 50), truncate(planned.URL, 60))
+		}
+		w.Flush()
+		fmt.Printf("\n%d articles would be fetched", len(plan.Planned)-skipped)
+		if skipped > 0 {
+			fmt.Printf(" and %d marked obsolete by skip rules", skipped)
+		}
+		fmt.Println(".")
+	}
+
+	fmt.Printf("%d articles match the filters", plan.Matched)
+	reasons := []struct{ key, label string }{
+		{fetcher.ExcludedFetched, "already fetched"},
+		{fetcher.ExcludedObsolete, "obsolete"},
+		{fetcher.ExcludedBackoff, "waiting to retry"},
+		{fetcher.ExcludedGaveUp, "given up after failures"},
+		{fetcher.ExcludedBeyondLimit, "beyond --limit"},
+		{fetcher.ExcludedNotFetched, "not fetched yet"},
+	}
+	var excluded []string
+	for _, reason := range reasons {
+		if count := plan.Excluded[reason.key]; count > 0 {
+			excluded = append(excluded, fmt.Sprintf("%d %s", count, reason.label))
+		}
+	}
+	if len(excluded) > 0 {
+		fmt.Printf("; excluded: %s", strings.Join(excluded, ", "))
+	}
+	fmt.Println(".")
+
+	const maxListed = 20
+	if len(plan.Failed) > 0 {
+		fmt.Println("\nExcluded after failures or marked obsolete:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tREASON\tFAILURES\tCLASS\tNEXT RETRY\tURL")
+		for i, article := range plan.Failed {
+			if i == maxListed {
+				break
+			}
+			class, nextRetry := "", ""
+			if article.Class != nil {
+				class = *article.Class
+			}
+			if article.NextRetryAt != nil && article.Reason == fetcher.ExcludedBackoff {
+				nextRetry = *article.NextRetryAt
+			}
+			fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%s\n", article.ID, article.Reason, article.FailedCount, class, nextRetry, truncate(article.URL, 60))
+		}
+		w.Flush()
+		if len(plan.Failed) > maxListed {
+			fmt.Printf("... and %d more (use --output json for the full list)\n", len(plan.Failed)-maxListed)
+		}
+	}
+}
+
+// exitPartialFailure is the exit code used when --fail-on-error or doctor --fail-on trips; fatal
+// errors exit with 1
+const exitPartialFailure = 2
+
+// partialFailureError reports a batch run that completed but had too many failed items
+type partialFailureError struct {
+	failed int
+	total  int
+}
+
+func (e *partialFailureError) Error() string {
+	return fmt.Sprintf("%d of %d items failed", e.failed, e.total)
+}
+
+// addFailOnErrorFlags registers the partial failure flags shared by import and fetch
+func addFailOnErrorFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("fail-on-error", false, fmt.Sprintf("Exit with code %d when the share of failed items exceeds --error-threshold", exitPartialFailure))
+	cmd.Flags().Float64("error-threshold", 0, "Fraction of failed items (0-1) tolerated before --fail-on-error trips")
+}
+
+// checkPartialFailure returns a partialFailureError when --fail-on-error is set
+// and the failure rate is above --error-threshold
+func checkPartialFailure(cmd *cobra.Command, failed, total int) error {
+	failOnError, _ := cmd.Flags().GetBool("fail-on-error")
+	threshold, _ := cmd.Flags().GetFloat64("error-threshold")
+
+	if !failOnError || failed == 0 || total == 0 {
+		return nil
+	}
+
+	if float64(failed)/float64(total) <= threshold {
+		return nil
+	}
+
+	// The error is reported here; main only translates it into the exit code
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	log.Printf("%d of %d items failed (threshold %.0f%%)", failed, total, threshold*100)
+
+	return &partialFailureError{failed: failed, total: total}
+}
+
+func runSearch(cmd *cobra.Command, args []string) error {
+	var query string
+	if len(args) > 0 {
+		query = args[0]
+	}
+
+	field, _ := cmd.Flags().GetString("field")
+	useFTS, _ := cmd.Flags().GetBool("fts")
+	limit, _ := cmd.Flags().GetInt("limit")
+	jsonOutput := wantJSON(cmd)
 	since, _ := cmd.Flags().GetString("since")
 	until, _ := cmd.Flags().GetString("until")
-	fromSearch, _ := cmd.Flags().GetString("from-search")
-	searchField, _ := cmd.Flags().GetString("field")
-	searchFTS, _ := cmd.Flags().GetBool("fts")
-	searchLimit, _ := cmd.Flags().GetInt("limit")
+	explain, _ := cmd.Flags().GetBool("explain")
+	fuzzy, _ := cmd.Flags().GetBool("fuzzy")
+	facets, _ := cmd.Flags().GetBool("facets")
+	facetLimit, _ := cmd.Flags().GetInt("facet-limit")
+	dedupe, _ := cmd.Flags().GetBool("dedupe")
+	caseSensitive, _ := cmd.Flags().GetBool("case-sensitive")
+	wholeWord, _ := cmd.Flags().GetBool("whole-word")
 
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	opts := search.SearchOptions{
+		Query:         query,
+		Field:         field,
+		UseFTS:        useFTS,
+		Limit:         limit,
+		JSONOutput:    jsonOutput,
+		Since:         since,
+		Until:         until,
+		Explain:       explain,
+		Fuzzy:         fuzzy,
+		Facets:        facets,
+		FacetLimit:    facetLimit,
+		CSVOutput:     outputFormat == "csv",
+		Dedupe:        dedupe,
+		CaseSensitive: caseSensitive,
+		WholeWord:     wholeWord,
+	}
+	if err := applyFetchHealthFlags(cmd, &opts.HealthFilters, query); err != nil {
+		return err
+	}
+
+	s := search.New(database).WithColor(colorOutput())
+	return s.Search(cmd.Context(), opts)
+}
+
+func runLatest(cmd *cobra.Command, args []string) error {
+	limit, _ := cmd.Flags().GetInt("limit")
+	jsonOutput := wantJSON(cmd)
+	since, _ := cmd.Flags().GetString("since")
+	until, _ := cmd.Flags().GetString("until")
+	by, _ := cmd.Flags().GetString("by")
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+	folders, _ := cmd.Flags().GetStringSlice("folder")
+
+	opts := search.LatestOptions{
+		By:         by,
+		Limit:      limit,
+		Since:      since,
+		Until:      until,
+		Folders:    folders,
+		Tags:       tags,
+		JSONOutput: jsonOutput,
+		CSVOutput:  outputFormat == "csv",
+	}
+	if err := applyFetchHealthFlags(cmd, &opts.HealthFilters, ""); err != nil {
+		return err
+	}
+
+	s := search.New(database).WithColor(colorOutput())
+	return s.Latest(cmd.Context(), opts)
+}
+
+func runRandom(cmd *cobra.Command, args []string) error {
+	count, _ := cmd.Flags().GetInt("count")
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+	folders, _ := cmd.Flags().GetStringSlice("folder")
+	unread, _ := cmd.Flags().GetBool("unread")
+	statuses, err := statusFilter(cmd)
+	if err != nil {
+		return err
+	}
+
+	if count < 1 {
+		return fmt.Errorf("--count must be at least 1")
+	}
+
+	s := search.New(database).WithColor(colorOutput())
+	results, err := s.Random(cmd.Context(), search.RandomOptions{
+		Count:    count,
+		Tags:     tags,
+		Folders:  folders,
+		Unread:   unread,
+		Statuses: statuses,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to pick random articles: %w", err)
+	}
+
+	return s.PrintResults(results, wantJSON(cmd))
+}
+
+func runSimilar(cmd *cobra.Command, args []string) error {
+	id, _ := cmd.Flags().GetInt64("id")
+	limit, _ := cmd.Flags().GetInt("limit")
+	maxDistance, _ := cmd.Flags().GetInt("max-distance")
+	duplicates, _ := cmd.Flags().GetBool("duplicates")
+
+	if duplicates {
+		maxDistance = similarity.NearDuplicateDistance
+	}
+
+	matches, err := similarity.New(database).Similar(cmd.Context(), id, limit, maxDistance)
+	if err != nil {
+		return err
+	}
+
+	if wantJSON(cmd) {
+		if matches == nil {
+			matches = []similarity.Match{}
+		}
+		return writeJSON(matches)
+	}
+
+	if len(matches) == 0 {
+		fmt.Println("No similar articles found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "ID\tSIMILARITY\tDISTANCE\tTITLE\tURL")
+	for _, match := range matches {
+		fmt.Fprintf(w, "%d\t%.0f%%\t%d\t%s\t%s\n",
+			match.ID, match.Similarity*100, match.Distance, truncate(match.Title, 50), truncate(match.URL, 60))
+	}
+
+	return nil
+}
+
+// TagSuggestionReport lists the tags suggested for, and applied to, one article
+type TagSuggestionReport struct {
+	ID          int64                `json:"id"`
+	Title       string               `json:"title"`
+	Suggestions []tagging.Suggestion `json:"suggestions"`
+	Applied     []string             `json:"applied,omitempty"`
+	Error       string               `json:"error,omitempty"`
+}
+
+func runSuggestTags(cmd *cobra.Command, args []string) error {
+	id, _ := cmd.Flags().GetInt64("id")
+	untagged, _ := cmd.Flags().GetBool("untagged")
+	limit, _ := cmd.Flags().GetInt("limit")
+	max, _ := cmd.Flags().GetInt("max")
+	minScore, _ := cmd.Flags().GetFloat64("min-score")
+	apply, _ := cmd.Flags().GetBool("apply")
+	interactive, _ := cmd.Flags().GetBool("interactive")
+	llmCommand, _ := cmd.Flags().GetString("llm-command")
+	jsonOutput := wantJSON(cmd)
+
+	if (id == 0) == !untagged {
+		return fmt.Errorf("specify either --id or --untagged")
+	}
+	if apply && interactive {
+		return fmt.Errorf("--apply and --interactive cannot be combined")
+	}
+	if interactive && jsonOutput {
+		return fmt.Errorf("--interactive cannot be combined with JSON output")
+	}
+
+	ctx := cmd.Context()
+	suggester := tagging.New(database)
+
+	ids := []int64{id}
+	if untagged {
+		var err error
+		ids, err = suggester.UntaggedArticleIDs(ctx, limit)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 && !jsonOutput {
+			fmt.Println("No untagged articles with content found.")
+		}
+	}
+
+	stdin := bufio.NewReader(os.Stdin)
+	reports := []TagSuggestionReport{}
+
+	for _, articleID := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		report := TagSuggestionReport{ID: articleID}
+		database.Get(&report.Title, "SELECT title FROM articles WHERE id = ?", articleID)
+
+		var suggestions []tagging.Suggestion
+		var err error
+		if llmCommand != "" {
+			suggestions, err = suggester.LLMSuggest(ctx, llmCommand, articleID, max)
+		} else {
+			suggestions, err = suggester.Suggest(ctx, articleID, max)
+		}
+		if err != nil {
+			if !untagged {
+				return err
+			}
+			report.Error = err.Error()
+			reports = append(reports, report)
+			if !jsonOutput {
+				fmt.Printf("\nArticle %d: %s\n  Error: %v\n", articleID, report.Title, err)
+			}
+			continue
+		}
+
+		report.Suggestions = []tagging.Suggestion{}
+		for _, suggestion := range suggestions {
+			if suggestion.Score >= minScore {
+				report.Suggestions = append(report.Suggestions, suggestion)
+			}
+		}
+
+		if !jsonOutput {
+			fmt.Printf("\nArticle %d: %s\n", articleID, report.Title)
+			if len(report.Suggestions) == 0 {
+				fmt.Println("  No suggestions.")
+			}
+		}
+
+		var accepted []string
+		for _, suggestion := range report.Suggestions {
+			label := suggestion.Source
+			if suggestion.New {
+				label += ", new tag"
+			}
+
+			if !interactive {
+				if !jsonOutput {
+					fmt.Printf("  %-24s %.2f  (%s)\n", suggestion.Tag, suggestion.Score, label)
+				}
+				if apply {
+					accepted = append(accepted, suggestion.Tag)
+				}
+				continue
+			}
+
+			fmt.Printf("  %-24s %.2f  (%s)  apply? [y/N/q] ", suggestion.Tag, suggestion.Score, label)
+			answer, _ := stdin.ReadString('\n')
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "y", "yes":
+				accepted = append(accepted, suggestion.Tag)
+			case "q", "quit":
+				if err := applySuggestedTags(&report, accepted, jsonOutput); err != nil {
+					return err
+				}
+				return nil
+			}
+		}
+
+		if err := applySuggestedTags(&report, accepted, jsonOutput); err != nil {
+			return err
+		}
+		reports = append(reports, report)
+	}
+
+	if jsonOutput {
+		return writeJSON(reports)
+	}
+
+	return nil
+}
+
+// applySuggestedTags adds accepted tags to the report's article
+func applySuggestedTags(report *TagSuggestionReport, tags []string, quiet bool) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	if err := database.AddArticleTags(report.ID, tags); err != nil {
+		return fmt.Errorf("failed to tag article %d: %w", report.ID, err)
+	}
+	report.Applied = tags
+
+	if !quiet {
+		fmt.Printf("  Applied: %s\n", strings.Join(tags, ", "))
+	}
+	return nil
+}
+
+// addTaxonomyFlags registers the folder and tag mapping flags shared by import and daemon
+func addTaxonomyFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("folders-as-tags", false, "Tag articles with their Instapaper folder instead of filing them in folders")
+	cmd.Flags().String("folder-tag-prefix", "", "Prefix for tags made by --folders-as-tags, e.g. folder/")
+	cmd.Flags().String("tags-as-folders", "", "File articles in the folder named by their first tag with this prefix (e.g. folder/ makes folder/Tech the folder Tech)")
+	cmd.Flags().String("mapping", "", "YAML file that renames, merges, and drops folders and tags during import")
+}
+
+// applyTaxonomyFlags copies the folder and tag mapping flags into import options
+func applyTaxonomyFlags(cmd *cobra.Command, opts *importer.ImportOptions) error {
+	opts.FoldersAsTags, _ = cmd.Flags().GetBool("folders-as-tags")
+	opts.FolderTagPrefix, _ = cmd.Flags().GetString("folder-tag-prefix")
+	opts.TagsAsFolders, _ = cmd.Flags().GetString("tags-as-folders")
+
+	mappingPath, _ := cmd.Flags().GetString("mapping")
+	var err error
+	opts.Mapping, err = importer.LoadMapping(mappingPath)
+	return err
+}
+
+// addFetchHealthFlags registers the fetch health, reading status, and language filter flags shared by search and latest
+func addFetchHealthFlags(cmd *cobra.Command) {
+	cmd.Flags().IntSlice("status-code", nil, "Only articles whose last fetch returned these HTTP status codes (e.g., 403,404)")
+	cmd.Flags().Bool("failed-only", false, "Only articles with at least one fetch failure")
+	cmd.Flags().Bool("never-fetched", false, "Only articles that have never had a fetch attempt")
+	cmd.Flags().Bool("fetched-only", false, "Only articles with fetched content")
+	cmd.Flags().Bool("low-quality", false, "Only fetched articles whose content is probably not the article (low extraction quality)")
+	cmd.Flags().StringSlice("lang", nil, "Only articles in these languages, e.g. en,de, or auto for the language of the search query")
+	cmd.Flags().StringSlice("status", nil, "Only articles with these reading statuses: "+strings.Join(db.Statuses, ", "))
+}
+
+// applyFetchHealthFlags copies the fetch health, reading status, and language filter flags into search
+// or latest options; --lang auto takes the language of query
+func applyFetchHealthFlags(cmd *cobra.Command, opts *search.HealthFilters, query string) error {
+	opts.StatusCodes, _ = cmd.Flags().GetIntSlice("status-code")
+	opts.FailedOnly, _ = cmd.Flags().GetBool("failed-only")
+	opts.NeverFetched, _ = cmd.Flags().GetBool("never-fetched")
+	opts.FetchedOnly, _ = cmd.Flags().GetBool("fetched-only")
+	opts.LowQuality, _ = cmd.Flags().GetBool("low-quality")
+
+	var err error
+	if opts.Statuses, err = statusFilter(cmd); err != nil {
+		return err
+	}
+
+	langs, _ := cmd.Flags().GetStringSlice("lang")
+	if err := language.Validate(langs); err != nil {
+		return err
+	}
+	opts.Languages, err = language.Resolve(langs, query)
+	return err
+}
+
+// statusFilter returns the validated reading statuses given with --status
+func statusFilter(cmd *cobra.Command) ([]string, error) {
+	statuses, _ := cmd.Flags().GetStringSlice("status")
+	for i := range statuses {
+		statuses[i] = strings.ToLower(strings.TrimSpace(statuses[i]))
+	}
+	if err := db.ValidateStatuses(statuses...); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+func runAdd(cmd *cobra.Command, args []string) error {
+	fromClipboard, _ := cmd.Flags().GetBool("from-clipboard")
+	folder, _ := cmd.Flags().GetString("folder")
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+
+	urls := args
+	if fromClipboard {
+		text, err := readClipboard(cmd.Context())
+		if err != nil {
+			return err
+		}
+		found := importer.ExtractURLs(text)
+		if len(found) == 0 {
+			return fmt.Errorf("no URLs found in the clipboard")
+		}
+		urls = append(urls, found...)
+	}
+
+	if len(urls) == 0 {
+		return fmt.Errorf("give one or more URLs or use --from-clipboard")
+	}
+
+	result, err := importer.New(database).AddURLs(cmd.Context(), urls, importer.AddOptions{Folder: folder, Tags: tags, Rules: tagRules})
+	if result == nil {
+		return err
+	}
+
+	if wantJSON(cmd) {
+		if err := writeJSON(result); err != nil {
+			return err
+		}
+	} else {
+		for _, added := range result.Added {
+			fmt.Printf("Added %d: %s\n", added.ID, added.URL)
+		}
+		for _, duplicate := range result.Duplicates {
+			fmt.Printf("Already saved: %s\n", duplicate)
+		}
+		for _, invalid := range result.Invalid {
+			fmt.Printf("Not a valid URL: %s\n", invalid)
+		}
+		fmt.Printf("%d added, %d already saved. Run fetch to download their content.\n", len(result.Added), len(result.Duplicates))
+	}
+
+	if err != nil {
+		return fmt.Errorf("add interrupted: %w", err)
+	}
+
+	return nil
+}
+
+// readClipboard returns the system clipboard's text using the platform's clipboard tool
+func readClipboard(ctx context.Context) (string, error) {
+	var candidates [][]string
+	switch runtime.GOOS {
+	case "darwin":
+		candidates = [][]string{{"pbpaste"}}
+	case "windows":
+		candidates = [][]string{{"powershell", "-NoProfile", "-Command", "Get-Clipboard"}}
+	default:
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			candidates = append(candidates, []string{"wl-paste", "--no-newline"})
+		}
+		candidates = append(candidates, []string{"xclip", "-selection", "clipboard", "-o"}, []string{"xsel", "--clipboard", "--output"})
+	}
+
+	for _, candidate := range candidates {
+		if _, err := exec.LookPath(candidate[0]); err != nil {
+			continue
+		}
+		out, err := exec.CommandContext(ctx, candidate[0], candidate[1:]...).Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to read clipboard with %s: %w", candidate[0], err)
+		}
+		return string(out), nil
+	}
+
+	names := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		names[i] = candidate[0]
+	}
+	return "", fmt.Errorf("no clipboard tool found (install %s)", strings.Join(names, " or "))
+}
+
+func runShow(cmd *cobra.Command, args []string) error {
+	id, _ := cmd.Flags().GetInt64("id")
+	noPager, _ := cmd.Flags().GetBool("no-pager")
+	outline, _ := cmd.Flags().GetBool("outline")
+
+	e := export.New(database)
+
+	if outline {
+		return showArticleOutline(cmd, e, id)
+	}
+
+	if wantJSON(cmd) {
+		article, err := e.Article(cmd.Context(), id)
+		if err != nil {
+			return err
+		}
+		return writeJSON(article)
+	}
+
+	terminal := isTerminal(os.Stdout)
+	text, err := e.ArticleTerminal(cmd.Context(), id, colorOutput())
+	if err != nil {
+		return err
+	}
+
+	if !terminal || noPager {
+		fmt.Print(text)
+		return nil
+	}
+	return page(cmd.Context(), text)
+}
+
+func runGrep(cmd *cobra.Command, args []string) error {
+	id, _ := cmd.Flags().GetInt64("id")
+	opts := search.PassageOptions{}
+	opts.Context, _ = cmd.Flags().GetInt("context")
+	opts.Regex, _ = cmd.Flags().GetBool("regex")
+	opts.CaseSensitive, _ = cmd.Flags().GetBool("case-sensitive")
+	opts.Limit, _ = cmd.Flags().GetInt("limit")
+
+	finder, err := search.NewPassageFinder(args[0], opts)
+	if err != nil {
+		return err
+	}
+
+	article, err := export.New(database).Article(cmd.Context(), id)
+	if err != nil {
+		return err
+	}
+	if article.ContentMD == nil || *article.ContentMD == "" {
+		return fmt.Errorf("article %d has no content yet; fetch it first", id)
+	}
+
+	passages, matches := finder.Find(*article.ContentMD)
+
+	if wantJSON(cmd) {
+		return writeJSON(map[string]interface{}{
+			"id":       article.ID,
+			"title":    article.Title,
+			"matches":  matches,
+			"passages": passages,
+		})
+	}
+
+	if len(passages) == 0 {
+		fmt.Printf("No passages in article %d match %q.\n", id, args[0])
+		return nil
+	}
+
+	// Matches are shown in bold red on a terminal, as grep does
+	before, after := "", ""
+	if colorOutput() {
+		before, after = term.Match, term.Reset
+	}
+
+	fmt.Printf("%s\nMatches: %d, passages: %d\n", article.Title, matches, len(passages))
+	for _, passage := range passages {
+		if passage.Start == passage.End {
+			fmt.Printf("\n-- paragraph %d --\n", passage.Start)
+		} else {
+			fmt.Printf("\n-- paragraphs %d-%d --\n", passage.Start, passage.End)
+		}
+		fmt.Println(finder.Highlight(passage.Text, before, after))
+	}
+	return nil
+}
+
+// showArticleOutline prints an article's headings indented by level, with the paragraph each
+// starts at as grep numbers them
+func showArticleOutline(cmd *cobra.Command, e *export.Export, id int64) error {
+	article, err := e.Article(cmd.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	if wantJSON(cmd) {
+		outline := article.Outline
+		if outline == nil {
+			outline = []model.Heading{}
+		}
+		return writeJSON(map[string]interface{}{
+			"id":      article.ID,
+			"title":   article.Title,
+			"outline": outline,
+		})
+	}
+
+	fmt.Println(article.Title)
+	switch {
+	case article.SyncedAt == nil:
+		fmt.Println("Not fetched yet.")
+		return nil
+	case len(article.Outline) == 0:
+		fmt.Println("No headings.")
+		return nil
+	}
+
+	top := 6
+	for _, heading := range article.Outline {
+		top = min(top, heading.Level)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\nHEADING\tPARAGRAPH")
+	for _, heading := range article.Outline {
+		fmt.Fprintf(w, "%s%s\t%d\n", strings.Repeat("  ", heading.Level-top), heading.Title, heading.Paragraph)
+	}
+	return w.Flush()
+}
+
+// page shows text through $PAGER (default less -R), printing it directly if the pager cannot start
+func page(ctx context.Context, text string) error {
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = "less -R"
+	}
+
+	pagerCmd := exec.CommandContext(ctx, "sh", "-c", pager)
+	pagerCmd.Stdin = strings.NewReader(text)
+	pagerCmd.Stdout = os.Stdout
+	pagerCmd.Stderr = os.Stderr
+
+	if err := pagerCmd.Start(); err != nil {
+		fmt.Print(text)
+		return nil
+	}
+	return pagerCmd.Wait()
+}
+
+// colorOutput reports whether text output is styled: it goes to a terminal, NO_COLOR is not set,
+// and --plain is not given
+func colorOutput() bool {
+	return !plainOutput && outputFormat == "text" && term.ColorEnabled(os.Stdout)
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func runOpen(cmd *cobra.Command, args []string) error {
+	id, _ := cmd.Flags().GetInt64("id")
+	exportDir, _ := cmd.Flags().GetString("export-dir")
+
+	article, err := export.New(database).Article(cmd.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	if exportDir == "" {
+		fmt.Printf("Opening %s\n", article.URL)
+		return openWithSystem(article.URL)
+	}
+
+	manifest, err := export.LoadManifest(exportDir)
+	if err != nil {
+		return err
+	}
+	path, ok := manifest.Lookup(exportDir, id)
+	if !ok {
+		return fmt.Errorf("article %d is not in the export manifest of %s; run export-all first", id, exportDir)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("exported file is missing: %w", err)
+	}
+
+	if editor := os.Getenv("EDITOR"); editor != "" {
+		editorCmd := exec.CommandContext(cmd.Context(), "sh", "-c", editor+` "$1"`, "sh", path)
+		editorCmd.Stdin = os.Stdin
+		editorCmd.Stdout = os.Stdout
+		editorCmd.Stderr = os.Stderr
+		return editorCmd.Run()
+	}
+
+	fmt.Printf("Opening %s\n", path)
+	return openWithSystem(path)
+}
+
+// openWithSystem opens a URL or file with the desktop's default application
+func openWithSystem(target string) error {
+	var opener *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		opener = exec.Command("open", target)
+	case "windows":
+		opener = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		opener = exec.Command("xdg-open", target)
+	}
+
+	if err := opener.Start(); err != nil {
+		return fmt.Errorf("failed to open %s: %w", target, err)
+	}
+	// The opener hands off to the application and exits; reap it without waiting on the application
+	go opener.Wait()
+	return nil
+}
+
+func runAttachments(cmd *cobra.Command, args []string) error {
+	id, _ := cmd.Flags().GetInt64("id")
+	addPath, _ := cmd.Flags().GetString("add")
+
+	if _, err := export.New(database).Article(cmd.Context(), id); err != nil {
+		return err
+	}
+
+	if addPath != "" {
+		data, err := os.ReadFile(addPath)
+		if err != nil {
+			return fmt.Errorf("failed to read attachment: %w", err)
+		}
+
+		contentType := mime.TypeByExtension(filepath.Ext(addPath))
+		if contentType == "" {
+			contentType = http.DetectContentType(data)
+		}
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+			contentType = mediaType
+		}
+
+		if _, err := database.StoreAttachment(id, filepath.Base(addPath), contentType, nil, data); err != nil {
+			return err
+		}
+	}
+
+	attachments, err := database.ArticleAttachments(id)
+	if err != nil {
+		return err
+	}
+
+	if wantJSON(cmd) {
+		type attachmentJSON struct {
+			db.Attachment
+			Path string `json:"path"`
+		}
+		out := make([]attachmentJSON, len(attachments))
+		for i, attachment := range attachments {
+			out[i] = attachmentJSON{Attachment: attachment, Path: database.AttachmentPath(attachment)}
+		}
+		return writeJSON(out)
+	}
+
+	if len(attachments) == 0 {
+		fmt.Printf("Article %d has no attachments.\n", id)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tFILENAME\tTYPE\tSIZE\tPATH")
+	for _, attachment := range attachments {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", attachment.ID, attachment.Filename, attachment.ContentType, formatBytes(attachment.Size), database.AttachmentPath(attachment))
+	}
+	return w.Flush()
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	id, _ := cmd.Flags().GetInt64("id")
+	outPath, _ := cmd.Flags().GetString("out")
+	stdout, _ := cmd.Flags().GetBool("stdout")
+	includeHTML, _ := cmd.Flags().GetBool("include-html")
+	format, _ := cmd.Flags().GetString("format")
+
+	if !stdout && outPath == "" {
+		return fmt.Errorf("either --out or --stdout must be specified")
+	}
+
+	e := export.New(database)
+
+	switch format {
+	case "markdown":
+		sanitizer, err := loadSanitizer(cmd)
+		if err != nil {
+			return err
+		}
+		fields, err := loadFrontmatterFields(cmd)
+		if err != nil {
+			return err
+		}
+		highlights, _ := cmd.Flags().GetString("highlights")
+		if err := export.ValidateHighlights(highlights); err != nil {
+			return err
+		}
+		e.WithSanitizer(sanitizer).WithFrontmatterFields(fields).WithHighlights(highlights)
+		if fromSearch, _ := cmd.Flags().GetString("from-search"); fromSearch != "" && id == 0 {
+			return runExportCombined(cmd, e, fromSearch, outPath, stdout)
+		}
+		if id == 0 {
+			return fmt.Errorf("--id or --from-search is required for markdown export")
+		}
+		return e.ExportArticle(cmd.Context(), id, outPath, stdout, includeHTML)
+	case "anki":
+		return runExportAnki(cmd, e, id, outPath, stdout)
+	case "instapaper-csv":
+		return runExportInstapaperCSV(cmd, e, id, outPath, stdout)
+	default:
+		return fmt.Errorf("invalid format %q: use markdown, anki, or instapaper-csv", format)
+	}
+}
+
+func runExportInstapaperCSV(cmd *cobra.Command, e *export.Export, id int64, outPath string, stdout bool) error {
+	tag, _ := cmd.Flags().GetString("tag")
+	folder, _ := cmd.Flags().GetString("folder")
+	since, _ := cmd.Flags().GetString("since")
+	until, _ := cmd.Flags().GetString("until")
+	fromSearch, _ := cmd.Flags().GetString("from-search")
+	searchFTS, _ := cmd.Flags().GetBool("fts")
+	statuses, err := statusFilter(cmd)
+	if err != nil {
+		return err
+	}
+
+	opts := export.InstapaperCSVOptions{
+		ArticleID: id,
+		Filter: export.ExportAllOptions{
+			FolderFilter: folder,
+			TagFilter:    tag,
+			Statuses:     statuses,
+			Since:        since,
+			Until:        until,
+			FromSearch:   fromSearch,
+			SearchFTS:    searchFTS,
+		},
+	}
+
+	var out bytes.Buffer
+	result, err := e.ExportInstapaperCSV(cmd.Context(), &out, opts)
+	if err != nil {
+		return err
+	}
+
+	if stdout {
+		_, err := os.Stdout.Write(out.Bytes())
+		return err
+	}
+
+	if err := os.WriteFile(outPath, out.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if wantJSON(cmd) {
+		return writeJSON(result)
+	}
+
+	fmt.Printf("Exported %d articles to: %s\n", result.Articles, outPath)
+	return nil
+}
+
+func runExportCombined(cmd *cobra.Command, e *export.Export, fromSearch, outPath string, stdout bool) error {
+	searchFTS, _ := cmd.Flags().GetBool("fts")
+	limit, _ := cmd.Flags().GetInt("limit")
+	statuses, err := statusFilter(cmd)
+	if err != nil {
+		return err
+	}
+
+	// The document streams to its destination; a file is removed again when nothing matches
+	out := io.Writer(os.Stdout)
+	if !stdout {
+		file, err := os.Create(outPath)
+		if err != nil {
+			return fmt.Errorf("failed to create file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	result, err := e.ExportCombined(cmd.Context(), out, export.ExportAllOptions{
+		FromSearch:  fromSearch,
+		SearchFTS:   searchFTS,
+		SearchLimit: limit,
+		Statuses:    statuses,
+	})
+	if err == nil && result.Articles == 0 {
+		err = fmt.Errorf("no fetched articles match %q", fromSearch)
+		if !stdout {
+			os.Remove(outPath)
+		}
+	}
+	if err != nil || stdout {
+		return err
+	}
+
+	if wantJSON(cmd) {
+		return writeJSON(result)
+	}
+
+	fmt.Printf("Exported %d articles to: %s\n", result.Articles, outPath)
+	return nil
+}
+
+func runExportAnki(cmd *cobra.Command, e *export.Export, id int64, outPath string, stdout bool) error {
+	tag, _ := cmd.Flags().GetString("tag")
+	deck, _ := cmd.Flags().GetString("deck")
+	llmCommand, _ := cmd.Flags().GetString("llm")
+	questions, _ := cmd.Flags().GetInt("questions")
+
+	opts := export.AnkiOptions{
+		ArticleID:  id,
+		Tag:        tag,
+		Deck:       deck,
+		LLMCommand: llmCommand,
+		Questions:  questions,
+	}
+
+	var out bytes.Buffer
+	result, err := e.ExportAnki(cmd.Context(), &out, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, itemErr := range result.Errors {
+		fmt.Fprintf(os.Stderr, "Warning: article %d: %s\n", itemErr.ID, itemErr.Error)
+	}
+
+	if stdout {
+		_, err := os.Stdout.Write(out.Bytes())
+		return err
+	}
+
+	if err := os.WriteFile(outPath, out.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if wantJSON(cmd) {
+		return writeJSON(result)
+	}
+
+	fmt.Printf("Exported %d cards from %d articles to: %s\n", result.Cards, result.Articles, outPath)
+	return nil
+}
+
+func runExportAll(cmd *cobra.Command, args []string) error {
+	dir, _ := cmd.Flags().GetString("dir")
+	onlySynced, _ := cmd.Flags().GetBool("only-synced")
+	includeUnsynced, _ := cmd.Flags().GetBool("include-unsynced")
+	folder, _ := cmd.Flags().GetString("folder")
+	tag, _ := cmd.Flags().GetString("tag")
+	since, _ := cmd.Flags().GetString("since")
+	until, _ := cmd.Flags().GetString("until")
+	fromSearch, _ := cmd.Flags().GetString("from-search")
+	searchField, _ := cmd.Flags().GetString("field")
+	searchFTS, _ := cmd.Flags().GetBool("fts")
+	searchLimit, _ := cmd.Flags().GetInt("limit")
+	includeHTML, _ := cmd.Flags().GetBool("include-html")
+	naming, _ := cmd.Flags().GetString("naming")
+	format, _ := cmd.Flags().GetString("format")
+	namespaces, _ := cmd.Flags().GetBool("logseq-namespaces")
+	unsyncedMode, _ := cmd.Flags().GetString("unsynced-mode")
+	linkFormat, _ := cmd.Flags().GetString("link-format")
+	attachments, _ := cmd.Flags().GetString("attachments")
+	changelog, _ := cmd.Flags().GetString("changelog")
+	prune, _ := cmd.Flags().GetBool("prune")
+	gitCommit, _ := cmd.Flags().GetBool("git-commit")
+	highlights, _ := cmd.Flags().GetString("highlights")
+	maxArticles, _ := cmd.Flags().GetInt("max-articles")
+	maxTotalSize, _ := cmd.Flags().GetString("max-total-size")
+	paginate, _ := cmd.Flags().GetBool("paginate")
+	statuses, err := statusFilter(cmd)
+	if err != nil {
+		return err
+	}
+	maxBytes, err := export.ParseSize(maxTotalSize)
+	if err != nil {
+		return err
+	}
+
+	if includeUnsynced && !cmd.Flags().Changed("unsynced-mode") {
+		unsyncedMode = export.UnsyncedStub
+	}
+
+	if format == export.FormatLogseq && (changelog != "" || prune) {
+		return fmt.Errorf("--changelog and --prune are only supported with --format %s", export.FormatMarkdown)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	opts := export.ExportAllOptions{
+		Directory:        dir,
+		OnlySynced:       onlySynced && unsyncedMode == export.UnsyncedSkip,
+		UnsyncedMode:     unsyncedMode,
+		LinkFormat:       linkFormat,
+		FolderFilter:     folder,
+		TagFilter:        tag,
+		Statuses:         statuses,
+		Since:            since,
+		Until:            until,
+		FromSearch:       fromSearch,
+		SearchField:      searchField,
+		SearchFTS:        searchFTS,
+		SearchLimit:      searchLimit,
+		IncludeHTML:      includeHTML,
+		Quiet:            wantJSON(cmd),
+		Naming:           naming,
+		Format:           format,
+		LogseqNamespaces: namespaces,
+		Attachments:      attachments,
+		Prune:            prune,
+		MaxArticles:      maxArticles,
+		MaxTotalSize:     maxBytes,
+		Paginate:         paginate,
+	}
+
+	sanitizer, err := loadSanitizer(cmd)
+	if err != nil {
+		return err
+	}
+	fields, err := loadFrontmatterFields(cmd)
+	if err != nil {
+		return err
+	}
+
+	if err := export.ValidateHighlights(highlights); err != nil {
+		return err
+	}
+
+	// Errors from here on, like too many matching articles for --max-articles, are about the
+	// export rather than the command line
+	cmd.SilenceUsage = true
+
+	e := export.New(database).WithSanitizer(sanitizer).WithFrontmatterFields(fields).WithHighlights(highlights)
+	result, err := e.ExportAll(cmd.Context(), opts)
+	if result == nil {
+		return err
+	}
+
+	if changelog != "" && err == nil {
+		changes := result.Changes
+		if changes == nil {
+			// Nothing matched, so nothing was exported
+			changes = &export.Changelog{}
+		}
+		if err := export.WriteChangelog(changelog, changes); err != nil {
+			return err
+		}
+		if !wantJSON(cmd) {
+			fmt.Printf("Changelog written to %s: %d new, %d updated, %d removed\n", changelog, len(changes.New), len(changes.Updated), len(changes.Removed))
+		}
+	}
+
+	if gitCommit && err == nil {
+		message := fmt.Sprintf("Export: %d articles\n", result.Exported)
+		if result.Changes != nil {
+			message = result.Changes.Message()
+		}
+		committed, err := export.GitCommit(cmd.Context(), dir, message)
+		if err != nil {
+			return err
+		}
+		if !wantJSON(cmd) {
+			if committed {
+				fmt.Printf("Committed to git: %s\n", strings.SplitN(message, "\n", 2)[0])
+			} else {
+				fmt.Println("Nothing to commit: the export directory is unchanged.")
+			}
+		}
+	}
+
+	if wantJSON(cmd) {
+		if err := writeJSON(result); err != nil {
+			return err
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("export interrupted: %w", err)
+	}
+
+	return nil
+}
+
+func runFolders(cmd *cobra.Command, args []string) error {
+	action, _ := cmd.Flags().GetString("action")
+
+	switch action {
+	case "list":
+		return listFolders(wantJSON(cmd))
+	case "mv":
+		source, _ := cmd.Flags().GetString("source")
+		target, _ := cmd.Flags().GetString("target")
+		if source == "" || target == "" {
+			return fmt.Errorf("both --source and --target are required for mv action")
+		}
+		return moveFolders(source, target)
+	case "mkdir":
+		name, _ := cmd.Flags().GetString("name")
+		if name == "" {
+			return fmt.Errorf("--name is required for mkdir action")
+		}
+		return createFolder(name, wantJSON(cmd))
+	default:
+		return fmt.Errorf("invalid action: %s. Use list, mv, or mkdir", action)
+	}
+}
+
+func runTags(cmd *cobra.Command, args []string) error {
+	action, _ := cmd.Flags().GetString("action")
+
+	switch action {
+	case "list":
+		return listTags(wantJSON(cmd))
+	case "rename":
+		old, _ := cmd.Flags().GetString("old")
+		new, _ := cmd.Flags().GetString("new")
+		if old == "" || new == "" {
+			return fmt.Errorf("both --old and --new are required for rename action")
+		}
+		return renameTag(old, new, wantJSON(cmd))
+	default:
+		return fmt.Errorf("invalid action: %s. Use list or rename", action)
+	}
+}
+
+// loadTitleCleaner builds the title cleaner from a command's --title-config and --title-case flags
+func loadTitleCleaner(cmd *cobra.Command) (titles.Cleaner, error) {
+	configPath, _ := cmd.Flags().GetString("title-config")
+	titleCase, _ := cmd.Flags().GetBool("title-case")
+
+	cleaner, err := titles.Load(configPath)
+	if err != nil {
+		return cleaner, err
+	}
+	if titleCase {
+		cleaner.TitleCase = true
+	}
+	return cleaner, nil
+}
+
+// loadSanitizer builds the content filters from --sanitize-config and --strip, nil when neither is set
+func loadSanitizer(cmd *cobra.Command) (*export.Sanitizer, error) {
+	configPath, _ := cmd.Flags().GetString("sanitize-config")
+	strip, _ := cmd.Flags().GetStringSlice("strip")
+	return export.LoadSanitizer(configPath, strip)
+}
+
+// loadNormalizer builds the text normalizer from --normalize-text and --normalize-config, nil
+// when neither is set
+func loadNormalizer(cmd *cobra.Command) (*textnorm.Normalizer, error) {
+	enabled, _ := cmd.Flags().GetBool("normalize-text")
+	configPath, _ := cmd.Flags().GetString("normalize-config")
+	if !enabled && configPath == "" {
+		return nil, nil
+	}
+
+	normalizer, err := textnorm.Load(configPath)
+	if err != nil {
+		return nil, err
+	}
+	return &normalizer, nil
+}
+
+// loadFrontmatterFields builds the computed frontmatter fields from --frontmatter-config and
+// --frontmatter-fields, nil when neither is set
+func loadFrontmatterFields(cmd *cobra.Command) (export.FrontmatterFields, error) {
+	configPath, _ := cmd.Flags().GetString("frontmatter-config")
+	fields, _ := cmd.Flags().GetStringSlice("frontmatter-fields")
+	return export.LoadFrontmatterFields(configPath, fields)
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	optimize, _ := cmd.Flags().GetBool("optimize")
+	recanonicalize, _ := cmd.Flags().GetBool("recanonicalize")
+	cleanTitles, _ := cmd.Flags().GetBool("clean-titles")
+	compressContent, _ := cmd.Flags().GetBool("compress-content")
+	decompressContent, _ := cmd.Flags().GetBool("decompress-content")
+	jsonOutput := wantJSON(cmd)
+
+	if compressContent && decompressContent {
+		return fmt.Errorf("--compress-content and --decompress-content cannot be combined")
+	}
+	if failOn, _ := cmd.Flags().GetString("fail-on"); failOn != "" && (failOn == doctorOK || doctorSeverities[failOn] == 0) {
+		return fmt.Errorf("invalid --fail-on %q: use %s, %s, or %s", failOn, severityInfo, severityWarn, severityError)
+	}
+
+	cleaner, err := loadTitleCleaner(cmd)
+	if err != nil {
+		return err
+	}
+	normalizer, err := loadNormalizer(cmd)
+	if err != nil {
+		return err
+	}
+
+	report, err := runDatabaseDoctor(cmd.Context(), jsonOutput)
+	if err != nil {
+		return err
+	}
+
+	if recanonicalize {
+		if !jsonOutput {
+			fmt.Println("\nRecanonicalizing article URLs...")
+		}
+
+		recanonicalized, err := database.RecanonicalizeURLs(cmd.Context(), util.CanonicalizeURL)
+		if err != nil {
+			return fmt.Errorf("recanonicalize failed: %w", err)
+		}
+		report.Recanonicalize = &recanonicalized
+
+		if !jsonOutput {
+			fmt.Printf("  Checked: %d\n", recanonicalized.Checked)
+			fmt.Printf("  Updated: %d\n", recanonicalized.Updated)
+			fmt.Printf("  Merged duplicates: %d\n", recanonicalized.Merged)
+		}
+	}
+
+	if cleanTitles {
+		if !jsonOutput {
+			fmt.Println("\nCleaning article titles...")
+		}
+
+		cleaned, err := database.CleanTitles(cmd.Context(), cleaner.Clean)
+		if err != nil {
+			return fmt.Errorf("title cleanup failed: %w", err)
+		}
+		report.CleanTitles = &cleaned
+
+		if !jsonOutput {
+			fmt.Printf("  Checked: %d\n", cleaned.Checked)
+			fmt.Printf("  Updated: %d\n", cleaned.Updated)
+		}
+	}
+
+	if normalizer != nil {
+		if !jsonOutput {
+			fmt.Println("\nNormalizing article text...")
+		}
+
+		normalized, err := database.NormalizeArticleText(cmd.Context(), normalizer.Text, normalizer.Markdown)
+		if err != nil {
+			return fmt.Errorf("text normalization failed: %w", err)
+		}
+		report.NormalizeText = &normalized
+
+		if !jsonOutput {
+			fmt.Printf("  Checked: %d\n", normalized.Checked)
+			fmt.Printf("  Titles updated: %d\n", normalized.Titles)
+			fmt.Printf("  Content updated: %d\n", normalized.Content)
+		}
+	}
+
+	if compressContent || decompressContent {
+		recode, action := database.CompressContent, "Compressing"
+		if decompressContent {
+			recode, action = database.DecompressContent, "Decompressing"
+		}
+		if !jsonOutput {
+			fmt.Printf("\n%s article content...\n", action)
+		}
+
+		recoded, err := recode(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("content recoding failed: %w", err)
+		}
+		report.Content = &recoded
+
+		if !jsonOutput {
+			fmt.Printf("  Articles: %d\n", recoded.Articles)
+			fmt.Printf("  Content size: %d -> %d bytes\n", recoded.BytesBefore, recoded.BytesAfter)
+		}
+	}
+
+	if obsoletePolicies != nil {
+		if !jsonOutput {
+			fmt.Println("\nApplying obsolete policies...")
+		}
+
+		report.Obsoleted, err = obsoletePolicies.Apply(cmd.Context(), database, false)
+		if err != nil {
+			return fmt.Errorf("obsolete policies failed: %w", err)
+		}
+		if !jsonOutput {
+			printObsoleteReport(report.Obsoleted)
+		}
+	}
+
+	if optimize {
+		report.Optimize, err = runDatabaseOptimize(cmd.Context(), jsonOutput)
+		if err != nil {
+			return err
+		}
+	}
+
+	if jsonOutput {
+		if err := writeJSON(report); err != nil {
+			return err
+		}
+	} else {
+		printDoctorFindings(report)
+	}
+
+	return checkDoctorFindings(cmd, report)
+}
+
+// checkDoctorFindings returns a partialFailureError when --fail-on is set and a finding is at least
+// that serious, so scripts can hold off a backup until the database is healthy
+func checkDoctorFindings(cmd *cobra.Command, report *DoctorReport) error {
+	failOn, _ := cmd.Flags().GetString("fail-on")
+	if failOn == "" {
+		return nil
+	}
+
+	serious := 0
+	for _, finding := range report.Findings {
+		if doctorSeverities[finding.Severity] >= doctorSeverities[failOn] {
+			serious++
+		}
+	}
+	if serious == 0 {
+		return nil
+	}
+
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	log.Printf("%d doctor findings at severity %s or above", serious, failOn)
+	return &partialFailureError{failed: serious, total: len(report.Findings)}
+}
+
+// wantJSON reports whether JSON output was requested via --output json or the command's own --json flag
+func wantJSON(cmd *cobra.Command) bool {
+	if outputFormat == "json" {
+		return true
+	}
+
+	if cmd.Flags().Lookup("json") != nil {
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		return jsonOutput
+	}
+
+	return false
+}
+
+// writeJSON prints v to stdout as indented JSON
+func writeJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func listFolders(jsonOutput bool) error {
+	query := `
+		SELECT id, title, parent_id, path_cache
+		FROM folders
+		ORDER BY path_cache
+	`
+
+	var folders []struct {
+		ID        int64   `db:"id" json:"id"`
+		Title     string  `db:"title" json:"title"`
+		ParentID  *int64  `db:"parent_id" json:"parent_id,omitempty"`
+		PathCache *string `db:"path_cache" json:"path,omitempty"`
+	}
+
+	if err := database.Select(&folders, query); err != nil {
+		return fmt.Errorf("failed to get folders: %w", err)
+	}
+
+	if jsonOutput {
+		return writeJSON(folders)
+	}
+
+	fmt.Printf("%-5s %-30s %-10s %s\n", "ID", "PATH", "PARENT", "TITLE")
+	fmt.Println(strings.Repeat("-", 80))
+
+	for _, folder := range folders {
+		parentStr := ""
+		if folder.ParentID != nil {
+			parentStr = fmt.Sprintf("%d", *folder.ParentID)
+		}
+
+		pathStr := folder.Title
+		if folder.PathCache != nil {
+			pathStr = *folder.PathCache
+		}
+
+		fmt.Printf("%-5d %-30s %-10s %s\n", folder.ID, pathStr, parentStr, folder.Title)
+	}
+
+	return nil
+}
+
+func moveFolders(source, target string) error {
+	return fmt.Errorf("folder move not yet implemented")
+}
+
+// createFolder creates a folder, including any missing parents in a slash-separated path
+func createFolder(name string, jsonOutput bool) error {
+	id, err := database.UpsertFolderPath(name)
+	if err != nil {
+		return fmt.Errorf("failed to create folder: %w", err)
+	}
+
+	if err := database.UpdateFolderPaths(); err != nil {
+		return fmt.Errorf("failed to update folder paths: %w", err)
+	}
+
+	var folder struct {
+		Title string `db:"title" json:"title"`
+		Path  string `db:"path_cache" json:"path"`
+	}
+	if err := database.Get(&folder, "SELECT title, path_cache FROM folders WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to get folder: %w", err)
+	}
+
+	if jsonOutput {
+		return writeJSON(map[string]interface{}{"id": id, "title": folder.Title, "path": folder.Path})
+	}
+
+	fmt.Printf("Created folder: %s\n", folder.Path)
+	return nil
+}
+
+// runFoldersRename renames the folder picked by --id or --path
+func runFoldersRename(cmd *cobra.Command, args []string) error {
+	id, _ := cmd.Flags().GetInt64("id")
+	path, _ := cmd.Flags().GetString("path")
+	newName, _ := cmd.Flags().GetString("new-name")
+
+	if (id == 0) == (path == "") {
+		return fmt.Errorf("exactly one of --id and --path is required")
+	}
+	if path != "" {
+		var err error
+		if id, err = database.FindFolder(path); err != nil {
+			return err
+		}
+	} else {
+		var found bool
+		if err := database.Get(&found, "SELECT EXISTS(SELECT 1 FROM folders WHERE id = ?)", id); err != nil {
+			return fmt.Errorf("failed to look up folder: %w", err)
+		}
+		if !found {
+			return fmt.Errorf("folder %d not found", id)
+		}
+	}
+
+	operationID, err := database.BeginOperation(db.OperationFolderRename, fmt.Sprintf("folders rename --id %d --new-name %s", id, newName))
+	if err != nil {
+		return err
+	}
+
+	oldPath, newPath, articles, err := database.RenameFolder(operationID, id, newName)
+	if err != nil {
+		return err
+	}
+
+	if wantJSON(cmd) {
+		return writeJSON(map[string]interface{}{"id": id, "old": oldPath, "new": newPath, "articles": articles, "operation_id": operationID})
+	}
+
+	if oldPath == newPath {
+		fmt.Printf("Folder '%s' already has that name\n", oldPath)
+		return nil
+	}
+	fmt.Printf("Renamed folder '%s' to '%s' and reindexed %d articles\n", oldPath, newPath, articles)
+	fmt.Printf("Undo with: instapaper-cli undo --operation-id %d\n", operationID)
+	return nil
+}
+
+func listTags(jsonOutput bool) error {
+	query := `
+		SELECT t.id, t.title, COUNT(at.article_id) as article_count
+		FROM tags t
+		LEFT JOIN article_tags at ON t.id = at.tag_id
+		GROUP BY t.id, t.title
+		ORDER BY t.title
+	`
+
+	var tags []struct {
+		ID           int64  `db:"id" json:"id"`
+		Title        string `db:"title" json:"title"`
+		ArticleCount int    `db:"article_count" json:"article_count"`
+	}
+
+	if err := database.Select(&tags, query); err != nil {
+		return fmt.Errorf("failed to get tags: %w", err)
+	}
+
+	if jsonOutput {
+		return writeJSON(tags)
+	}
+
+	fmt.Printf("%-5s %-30s %s\n", "ID", "TAG", "ARTICLES")
+	fmt.Println(strings.Repeat("-", 50))
+
+	for _, tag := range tags {
+		fmt.Printf("%-5d %-30s %d\n", tag.ID, tag.Title, tag.ArticleCount)
+	}
+
+	return nil
+}
+
+func renameTag(old, new string, jsonOutput bool) error {
+	// Renaming onto an alias renames onto the tag it stands for
+	new, _, err := database.ResolveAlias(db.AliasTag, new)
+	if err != nil {
+		return err
+	}
+
+	var found, exists bool
+	if err := database.Get(&found, "SELECT EXISTS(SELECT 1 FROM tags WHERE title = ?)", old); err != nil {
+		return fmt.Errorf("failed to look up tag: %w", err)
+	}
+	if !found {
+		return fmt.Errorf("tag '%s' not found", old)
+	}
+	if err := database.Get(&exists, "SELECT EXISTS(SELECT 1 FROM tags WHERE title = ?)", new); err != nil {
+		return fmt.Errorf("failed to look up tag: %w", err)
+	}
+
+	// Renaming onto an existing tag merges the two
+	kind := db.OperationTagRename
+	if exists {
+		kind = db.OperationTagMerge
+	}
+	operationID, err := database.BeginOperation(kind, fmt.Sprintf("tags --action rename --old %s --new %s", old, new))
+	if err != nil {
+		return err
+	}
+
+	merged, articles, err := database.RenameTag(operationID, old, new)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return writeJSON(map[string]interface{}{"old": old, "new": new, "merged": merged, "articles": articles, "operation_id": operationID})
+	}
+
+	if merged {
+		fmt.Printf("Merged tag '%s' into '%s' on %d articles\n", old, new, articles)
+	} else {
+		fmt.Printf("Renamed tag '%s' to '%s'\n", old, new)
+	}
+	fmt.Printf("Undo with: instapaper-cli undo --operation-id %d\n", operationID)
+	return nil
+}
+
+// newAliasCmd builds the "alias" subcommand of tags or folders for managing alternative names
+func newAliasCmd(kind string) *cobra.Command {
+	targetName := "tag"
+	if kind == db.AliasFolder {
+		targetName = "folder path"
+	}
+
+	aliasCmd := &cobra.Command{
+		Use:   "alias",
+		Short: fmt.Sprintf("Manage %s aliases: alternative names that resolve to the same %s", kind, kind),
+		Long: fmt.Sprintf("An alias makes another name resolve to an existing %s in searches, filters, imports, and MCP tools, "+
+			"so vocabulary from different services (e.g. \"golang\" and \"go\") lands in one place.", kind),
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add <alias> <" + strings.ReplaceAll(targetName, " ", "-") + ">",
+		Short: "Make alias resolve to a " + targetName,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := database.AddAlias(kind, args[0], args[1]); err != nil {
+				return err
+			}
+			target, _, err := database.ResolveAlias(kind, args[0])
+			if err != nil {
+				return err
+			}
+			if wantJSON(cmd) {
+				return writeJSON(map[string]string{"kind": kind, "alias": args[0], "target": target})
+			}
+			fmt.Printf("Added %s alias '%s' -> '%s'\n", kind, args[0], target)
+			return nil
+		},
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List " + kind + " aliases",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			aliases, err := database.Aliases(kind)
+			if err != nil {
+				return err
+			}
+			if wantJSON(cmd) {
+				return writeJSON(aliases)
+			}
+			if len(aliases) == 0 {
+				fmt.Printf("No %s aliases.\n", kind)
+				return nil
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ALIAS\tTARGET")
+			for _, alias := range aliases {
+				fmt.Fprintf(w, "%s\t%s\n", alias.Alias, alias.Target)
+			}
+			return w.Flush()
+		},
+	}
+
+	removeCmd := &cobra.Command{
+		Use:   "remove <alias>",
+		Short: "Remove a " + kind + " alias",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := database.RemoveAlias(kind, args[0]); err != nil {
+				return err
+			}
+			if wantJSON(cmd) {
+				return writeJSON(map[string]string{"kind": kind, "removed": args[0]})
+			}
+			fmt.Printf("Removed %s alias '%s'\n", kind, args[0])
+			return nil
+		},
+	}
+
+	for _, sub := range []*cobra.Command{addCmd, listCmd, removeCmd} {
+		sub.Flags().Bool("json", false, "Output as JSON")
+	}
+	aliasCmd.AddCommand(addCmd, listCmd, removeCmd)
+	return aliasCmd
+}
+
+// DoctorReport collects the results of the database doctor checks
+type DoctorReport struct {
+	// Severity is the most serious finding's, or ok when there are none
+	Severity string          `json:"severity"`
+	Findings []DoctorFinding `json:"findings"`
+	Counts   struct {
+		Articles       int `db:"articles" json:"articles"`
+		Folders        int `db:"folders" json:"folders"`
+		Tags           int `db:"tags" json:"tags"`
+		SyncedArticles int `db:"synced_articles" json:"synced_articles"`
+		FailedArticles int `db:"failed_articles" json:"failed_articles"`
+	} `json:"counts"`
+	FTS            *db.FTSReport                `json:"fts,omitempty"`
+	FTSRebuilt     bool                         `json:"fts_rebuilt"`
+	DuplicateURLs  []DuplicateURL               `json:"duplicate_urls,omitempty"`
+	LowQuality     []db.LowQualityArticle       `json:"low_quality,omitempty"`
+	Garbled        []db.GarbledArticle          `json:"garbled,omitempty"`
+	MissingIndexes []db.IndexSpec               `json:"missing_indexes,omitempty"`
+	CreatedIndexes []string                     `json:"created_indexes,omitempty"`
+	Recanonicalize *db.RecanonicalizeReport     `json:"recanonicalize,omitempty"`
+	CleanTitles    *db.CleanTitlesReport        `json:"clean_titles,omitempty"`
+	NormalizeText  *db.NormalizeTextReport      `json:"normalize_text,omitempty"`
+	Content        *db.ContentCompressionReport `json:"content,omitempty"`
+	Optimize       *OptimizeReport              `json:"optimize,omitempty"`
+	Obsoleted      *policy.ObsoleteReport       `json:"obsoleted,omitempty"`
+	Signed         int                          `json:"signed,omitempty"`
+}
+
+// Doctor finding severities, from least to most serious
+const (
+	severityInfo  = "info"
+	severityWarn  = "warn"
+	severityError = "error"
+)
+
+// doctorOK is the report's severity when doctor has no findings
+const doctorOK = "ok"
+
+// doctorSeverities ranks the severities, for --fail-on and the report's overall severity
+var doctorSeverities = map[string]int{doctorOK: 0, severityInfo: 1, severityWarn: 2, severityError: 3}
+
+// DoctorFinding is a problem doctor found, or a repair it made
+type DoctorFinding struct {
+	ID       string `json:"id"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	// Count is how many rows the finding affects
+	Count int `json:"count"`
+	// Fix is a command that resolves the finding
+	Fix string `json:"fix,omitempty"`
+}
+
+// addFinding records a finding, raising the report's severity to the finding's
+func (r *DoctorReport) addFinding(finding DoctorFinding) {
+	r.Findings = append(r.Findings, finding)
+	if doctorSeverities[finding.Severity] > doctorSeverities[r.Severity] {
+		r.Severity = finding.Severity
+	}
+}
+
+// DuplicateURL is a URL stored on more than one article
+type DuplicateURL struct {
+	URL   string `db:"url" json:"url"`
+	Count int    `db:"count" json:"count"`
+}
+
+// OptimizeReport describes the effect of doctor --optimize
+type OptimizeReport struct {
+	SizeBefore int64 `json:"size_before_bytes"`
+	SizeAfter  int64 `json:"size_after_bytes"`
+	DurationMS int64 `json:"duration_ms"`
+}
+
+// maxDoctorListed bounds the articles doctor prints for a check; --output json lists them all
+const maxDoctorListed = 20
+
+// runDatabaseDoctor runs the integrity checks, printing progress unless quiet. Problems are
+// recorded as findings on the report rather than returned as errors.
+func runDatabaseDoctor(ctx context.Context, quiet bool) (*DoctorReport, error) {
+	printf := func(format string, args ...interface{}) {
+		if !quiet {
+			fmt.Printf(format, args...)
+		}
+	}
+
+	report := &DoctorReport{Severity: doctorOK, Findings: []DoctorFinding{}}
+
+	printf("Running database integrity checks...\n")
+
+	var problems []string
+	if err := database.Select(&problems, "PRAGMA integrity_check"); err != nil {
+		return nil, fmt.Errorf("integrity check failed: %w", err)
+	}
+	if len(problems) != 1 || problems[0] != "ok" {
+		printf("Error: integrity check found %d problems, the first: %s\n", len(problems), problems[0])
+		report.addFinding(DoctorFinding{
+			ID:       "integrity",
+			Severity: severityError,
+			Message:  fmt.Sprintf("integrity check found %d problems, the first: %s", len(problems), problems[0]),
+			Count:    len(problems),
+			Fix:      fmt.Sprintf("sqlite3 %s .recover | sqlite3 recovered.sqlite", dbPath),
+		})
+	}
+
+	var orphans int
+	if err := database.Get(&orphans, "SELECT COUNT(*) FROM pragma_foreign_key_check"); err != nil {
+		return nil, fmt.Errorf("foreign key check failed: %w", err)
+	}
+	if orphans > 0 {
+		printf("Error: %d rows refer to rows that no longer exist\n", orphans)
+		report.addFinding(DoctorFinding{
+			ID:       "foreign-keys",
+			Severity: severityError,
+			Message:  fmt.Sprintf("%d rows refer to rows that no longer exist", orphans),
+			Count:    orphans,
+			Fix:      fmt.Sprintf("sqlite3 %s 'PRAGMA foreign_key_check'", dbPath),
+		})
+	}
+
+	countQuery := `
+		SELECT
+			(SELECT COUNT(*) FROM articles) as articles,
+			(SELECT COUNT(*) FROM folders) as folders,
+			(SELECT COUNT(*) FROM tags) as tags,
+			(SELECT COUNT(*) FROM articles WHERE synced_at IS NOT NULL) as synced_articles,
+			(SELECT COUNT(*) FROM articles WHERE synced_at IS NULL AND sync_failed_at IS NOT NULL AND next_retry_at IS NULL) as failed_articles
+	`
+
+	if err := database.Get(&report.Counts, countQuery); err != nil {
+		return nil, fmt.Errorf("failed to get counts: %w", err)
+	}
+
+	printf("Database Statistics:\n")
+	printf("  Articles: %d\n", report.Counts.Articles)
+	printf("  Folders: %d\n", report.Counts.Folders)
+	printf("  Tags: %d\n", report.Counts.Tags)
+	printf("  Synced Articles: %d\n", report.Counts.SyncedArticles)
+	printf("  Failed Articles (no retries left): %d\n", report.Counts.FailedArticles)
+
+	if report.Counts.FailedArticles > 0 {
+		report.addFinding(DoctorFinding{
+			ID:       "failed-articles",
+			Severity: severityInfo,
+			Message:  fmt.Sprintf("%d articles failed to fetch and have no retries left", report.Counts.FailedArticles),
+			Count:    report.Counts.FailedArticles,
+			Fix:      "instapaper-cli latest --failed-only",
+		})
+	}
+
+	printf("\nChecking indexes...\n")
+	missingIndexes, err := database.MissingIndexes()
+	if err != nil {
+		printf("Warning: index check failed: %v\n", err)
+		report.addFinding(DoctorFinding{ID: "index-check", Severity: severityError, Message: fmt.Sprintf("index check failed: %v", err)})
+	} else if len(missingIndexes) == 0 {
+		printf("All %d required indexes present.\n", len(db.RequiredIndexes))
+	} else {
+		report.MissingIndexes = missingIndexes
+		var failed []string
+		for _, spec := range missingIndexes {
+			printf("  Missing index on %s(%s), creating %s\n", spec.Table, strings.Join(spec.Columns, ", "), spec.Name)
+			if err := database.CreateIndex(spec); err != nil {
+				printf("Warning: %v\n", err)
+				failed = append(failed, err.Error())
+				continue
+			}
+			report.CreatedIndexes = append(report.CreatedIndexes, spec.Name)
+		}
+		if len(report.CreatedIndexes) > 0 {
+			report.addFinding(DoctorFinding{
+				ID:       "missing-indexes",
+				Severity: severityInfo,
+				Message:  fmt.Sprintf("created %d missing indexes: %s", len(report.CreatedIndexes), strings.Join(report.CreatedIndexes, ", ")),
+				Count:    len(report.CreatedIndexes),
+			})
+		}
+		if len(failed) > 0 {
+			report.addFinding(DoctorFinding{
+				ID:       "index-create",
+				Severity: severityError,
+				Message:  fmt.Sprintf("failed to create %d missing indexes: %s", len(failed), strings.Join(failed, "; ")),
+				Count:    len(failed),
+			})
+		}
+	}
+
+	printf("\nUpdating folder paths...\n")
+	if err := database.UpdateFolderPaths(); err != nil {
+		return nil, fmt.Errorf("failed to update folder paths: %w", err)
+	}
+
+	printf("\nComputing similarity signatures...\n")
+	signed, err := similarity.New(database).UpdateSignatures(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute similarity signatures: %w", err)
+	}
+	report.Signed = signed
+	printf("Signed %d articles.\n", signed)
+
+	printf("\nVerifying FTS index...\n")
+	ftsReport, err := database.VerifyFTS()
+	if err != nil {
+		printf("Warning: FTS verification failed: %v\n", err)
+		report.addFinding(DoctorFinding{ID: "fts-check", Severity: severityError, Message: fmt.Sprintf("FTS verification failed: %v", err)})
+	} else {
+		report.FTS = &ftsReport
+		if ftsReport.OK() {
+			printf("FTS index matches %d active articles.\n", ftsReport.Expected)
+		} else {
+			printf("Warning: FTS index has %d rows, expected %d\n", ftsReport.Indexed, ftsReport.Expected)
+			if len(ftsReport.ObsoleteIndexed) > 0 {
+				printf("  %d obsolete articles still indexed\n", len(ftsReport.ObsoleteIndexed))
+			}
+			if len(ftsReport.Missing) > 0 {
+				printf("  %d active articles missing from index\n", len(ftsReport.Missing))
+			}
+		}
+	}
+
+	printf("\nRebuilding FTS index...\n")
+	if err := database.RebuildFTS(ctx); err != nil {
+		printf("Warning: FTS rebuild failed: %v\n", err)
+		report.addFinding(DoctorFinding{ID: "fts-rebuild", Severity: severityError, Message: fmt.Sprintf("FTS rebuild failed: %v", err), Fix: "instapaper-cli doctor"})
+	} else {
+		report.FTSRebuilt = true
+		printf("FTS index rebuilt successfully!\n")
+	}
+
+	// The rebuild repairs a mismatched index, so the mismatch is only news when it did
+	if report.FTS != nil && !report.FTS.OK() {
+		severity, message := severityInfo, "FTS index did not match the active articles and was rebuilt"
+		if !report.FTSRebuilt {
+			severity, message = severityWarn, fmt.Sprintf("FTS index has %d rows, expected %d", report.FTS.Indexed, report.FTS.Expected)
+		}
+		report.addFinding(DoctorFinding{
+			ID:       "fts-mismatch",
+			Severity: severity,
+			Message:  message,
+			Count:    len(report.FTS.ObsoleteIndexed) + len(report.FTS.Missing),
+		})
+	}
+
+	duplicateQuery := `
+		SELECT url, COUNT(*) as count
+		FROM articles
+		GROUP BY url
+		HAVING COUNT(*) > 1
+	`
+
+	if err := database.Select(&report.DuplicateURLs, duplicateQuery); err == nil && len(report.DuplicateURLs) > 0 {
+		printf("\nWarning: Found %d duplicate URLs:\n", len(report.DuplicateURLs))
+		for _, dup := range report.DuplicateURLs {
+			printf("  %s (%d copies)\n", dup.URL, dup.Count)
+		}
+		report.addFinding(DoctorFinding{
+			ID:       "duplicate-urls",
+			Severity: severityWarn,
+			Message:  fmt.Sprintf("found %d URLs saved on more than one article", len(report.DuplicateURLs)),
+			Count:    len(report.DuplicateURLs),
+			Fix:      "instapaper-cli doctor --recanonicalize",
+		})
+	}
+
+	// Fetched counts as synced even when readability kept a cookie wall or a list of links
+	lowQuality, err := database.LowQualityArticles()
+	if err != nil {
+		printf("Warning: %v\n", err)
+		report.addFinding(DoctorFinding{ID: "low-quality-check", Severity: severityError, Message: err.Error()})
+	} else if len(lowQuality) > 0 {
+		report.LowQuality = lowQuality
+		printf("\nWarning: %d synced articles are probably extraction failures (quality below %.2f):\n", len(lowQuality), db.LowQualityThreshold)
+		for i, article := range lowQuality {
+			if i == maxDoctorListed {
+				printf("  ... and %d more (search --low-quality lists them all)\n", len(lowQuality)-i)
+				break
+			}
+			printf("  %d  %.2f  %s\n", article.ID, article.Quality, article.Title)
+		}
+		printf("Refetch them with fetch --low-quality, after adding content_selector domain rules for their sites if needed.\n")
+		report.addFinding(DoctorFinding{
+			ID:       "low-quality",
+			Severity: severityWarn,
+			Message:  fmt.Sprintf("%d fetched articles are probably extraction failures (quality below %.2f)", len(lowQuality), db.LowQualityThreshold),
+			Count:    len(lowQuality),
+			Fix:      "instapaper-cli fetch --low-quality",
+		})
+	}
+
+	// Pages fetched before charsets were detected may have been read as the wrong one
+	garbled, err := database.GarbledArticles(ctx)
+	if err != nil {
+		printf("Warning: %v\n", err)
+		report.addFinding(DoctorFinding{ID: "garbled-check", Severity: severityError, Message: err.Error()})
+	} else if len(garbled) > 0 {
+		report.Garbled = garbled
+		printf("\nWarning: %d synced articles have content decoded with the wrong charset:\n", len(garbled))
+		for i, article := range garbled {
+			if i == maxDoctorListed {
+				printf("  ... and %d more (fetch --garbled --dry-run lists them all)\n", len(garbled)-i)
+				break
+			}
+			printf("  %d  %d garbled  %s\n", article.ID, article.Garbled, article.Title)
+		}
+		printf("Refetch them with fetch --garbled.\n")
+		report.addFinding(DoctorFinding{
+			ID:       "garbled-content",
+			Severity: severityWarn,
+			Message:  fmt.Sprintf("%d fetched articles have at least %d characters decoded with the wrong charset", len(garbled), db.GarbledThreshold),
+			Count:    len(garbled),
+			Fix:      "instapaper-cli fetch --garbled",
+		})
+	}
+
+	printf("\nDatabase doctor completed successfully!\n")
+	return report, nil
+}
+
+// printDoctorFindings lists the findings with their fixes, most serious first
+func printDoctorFindings(report *DoctorReport) {
+	if len(report.Findings) == 0 {
+		fmt.Println("\nNo findings: the database is healthy.")
+		return
+	}
+
+	findings := append([]DoctorFinding{}, report.Findings...)
+	sort.SliceStable(findings, func(i, j int) bool {
+		return doctorSeverities[findings[i].Severity] > doctorSeverities[findings[j].Severity]
+	})
+
+	fmt.Printf("\nFindings (%s):\n", report.Severity)
+	for _, finding := range findings {
+		fmt.Printf("  [%s] %s: %s\n", finding.Severity, finding.ID, finding.Message)
+		if finding.Fix != "" {
+			fmt.Printf("         fix: %s\n", finding.Fix)
+		}
+	}
+}
+
+// runDatabaseOptimize compacts the database, printing sizes unless quiet
+func runDatabaseOptimize(ctx context.Context, quiet bool) (*OptimizeReport, error) {
+	printf := func(format string, args ...interface{}) {
+		if !quiet {
+			fmt.Printf(format, args...)
+		}
+	}
+
+	printf("\nOptimizing database...\n")
+
+	before, err := database.Size()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	if err := database.Optimize(ctx); err != nil {
+		return nil, fmt.Errorf("optimize failed: %w", err)
+	}
+
+	after, err := database.Size()
+	if err != nil {
+		return nil, err
+	}
+
+	elapsed := time.Since(start)
+
+	printf("  Size before: %s\n", formatBytes(before))
+	printf("  Size after:  %s\n", formatBytes(after))
+	if after < before {
+		printf("  Reclaimed:   %s\n", formatBytes(before-after))
+	} else {
+		printf("  Reclaimed:   nothing\n")
+	}
+	printf("  Completed in %s\n", elapsed.Round(time.Millisecond))
+
+	return &OptimizeReport{
+		SizeBefore: before,
+		SizeAfter:  after,
+		DurationMS: elapsed.Milliseconds(),
+	}, nil
+}
+
+// formatBytes renders a byte count using binary units, e.g. 1.5 MiB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	value := float64(n)
+	units := []string{"KiB", "MiB", "GiB", "TiB"}
+	i := -1
+	for value >= unit && i < len(units)-1 {
+		value /= unit
+		i++
+	}
+
+	return fmt.Sprintf("%.1f %s", value, units[i])
+}
+
+func runMCP(cmd *cobra.Command, args []string) error {
+	readOnly, _ := cmd.Flags().GetBool("read-only")
+	allow, _ := cmd.Flags().GetStringSlice("allow")
+	exportRoot, _ := cmd.Flags().GetString("export-root")
+	logPath, _ := cmd.Flags().GetString("log")
+	logMaxSize, _ := cmd.Flags().GetInt("log-max-size")
+	logBackups, _ := cmd.Flags().GetInt("log-backups")
+
+	permissions, err := mcp.ParsePermissions(readOnly, cmd.Flags().Changed("read-only"), allow)
+	if err != nil {
+		return err
+	}
+	if logMaxSize < 0 || logBackups < 0 {
+		return fmt.Errorf("--log-max-size and --log-backups cannot be negative")
+	}
+
+	fmt.Fprintf(os.Stderr, "Starting MCP server for instapaper-cli %s\n", version.GetVersion())
+	fmt.Fprintf(os.Stderr, "Database: %s\n", dbPath)
+	fmt.Fprintf(os.Stderr, "Capabilities: %s\n", permissions)
+	if permissions.Allows(mcp.CapExport) {
+		if exportRoot == "" {
+			fmt.Fprintf(os.Stderr, "Export root: not set, export_to_files is disabled (use --export-root)\n")
+		} else {
+			fmt.Fprintf(os.Stderr, "Export root: %s\n", exportRoot)
+		}
+	}
+
+	// Create the MCP server, logging tool calls when asked
+	server := mcp.NewServer(database, permissions, exportRoot).WithTagRules(tagRules)
+	if logPath != "" {
+		requestLog, err := mcp.OpenRequestLog(logPath, int64(logMaxSize)<<20, logBackups)
+		if err != nil {
+			return err
+		}
+		defer requestLog.Close()
+		server.WithRequestLog(requestLog)
+		fmt.Fprintf(os.Stderr, "Request log: %s\n", logPath)
+	}
+	fmt.Fprintf(os.Stderr, "MCP server listening on stdio...\n")
+
+	return server.Start(cmd.Context())
+}
+
+func runCheckLinks(cmd *cobra.Command, args []string) error {
+	ids, _ := cmd.Flags().GetInt64Slice("ids")
+	folders, _ := cmd.Flags().GetStringSlice("folder")
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+	domains, _ := cmd.Flags().GetStringSlice("domain")
+	limit, _ := cmd.Flags().GetInt("limit")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	rate, _ := cmd.Flags().GetFloat64("rate")
+	recheckAfter, _ := cmd.Flags().GetDuration("recheck-after")
+	all, _ := cmd.Flags().GetBool("all")
+	statuses, err := statusFilter(cmd)
+	if err != nil {
+		return err
+	}
+
+	opts := fetcher.CheckOptions{
+		Selector: search.Selector{
+			IDs:      ids,
+			Folders:  folders,
+			Tags:     tags,
+			Domains:  domains,
+			Statuses: statuses,
+		},
+		Limit:        limit,
+		Concurrency:  concurrency,
+		Rate:         rate,
+		RecheckAfter: recheckAfter,
+	}
+	if opts.Selector, err = opts.Selector.WithAliases(database); err != nil {
+		return err
+	}
+
+	report, err := fetcher.New(database).CheckLinks(cmd.Context(), opts)
+	if report == nil {
+		return err
+	}
+
+	sort.Slice(report.Results, func(i, j int) bool { return report.Results[i].ID < report.Results[j].ID })
+	if !all {
+		var problems []fetcher.LinkCheck
+		for _, check := range report.Results {
+			if check.Status != fetcher.LinkOK {
+				problems = append(problems, check)
+			}
+		}
+		report.Results = problems
+	}
+
+	if wantJSON(cmd) {
+		if err := writeJSON(report); err != nil {
+			return err
+		}
+	} else {
+		if len(report.Results) > 0 {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tSTATUS\tCODE\tURL\tDETAIL")
+			for _, check := range report.Results {
+				code := ""
+				if check.StatusCode != 0 {
+					code = strconv.Itoa(check.StatusCode)
+				}
+				detail := check.Error
+				if check.FinalURL != "" {
+					detail = "-> " + check.FinalURL
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", check.ID, check.Status, code, truncate(check.URL, 60), truncate(detail, 80))
+			}
+			w.Flush()
+			fmt.Println()
+		}
+
+		counts := make([]string, len(fetcher.LinkStatuses))
+		for i, status := range fetcher.LinkStatuses {
+			counts[i] = fmt.Sprintf("%d %s", report.Counts[status], status)
+		}
+		fmt.Printf("Checked %d links: %s\n", report.Checked, strings.Join(counts, ", "))
+		if report.Counts[fetcher.LinkBroken] > 0 {
+			fmt.Println("Mark broken links obsolete with: obsolete --link-status broken --confirm")
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("link check interrupted: %w", err)
+	}
+	return nil
+}
+
+// printObsoleteReport lists the articles obsolete policies matched, by policy
+func printObsoleteReport(report *policy.ObsoleteReport) {
+	matched := 0
+	for _, match := range report.Policies {
+		if len(match.Articles) == 0 {
+			continue
+		}
+		fmt.Printf("  %s (%s): %d articles\n", match.Name, match.Criteria, len(match.Articles))
+		for _, article := range match.Articles {
+			fmt.Printf("    %d  %s\n", article.ID, article.URL)
+		}
+		matched += len(match.Articles)
+	}
+
+	switch {
+	case matched == 0:
+		fmt.Println("  No articles match the obsolete policies.")
+	case report.DryRun:
+		fmt.Printf("  %d articles would be marked obsolete.\n", matched)
+	default:
+		fmt.Printf("  Marked %d articles obsolete. Undo with: instapaper-cli undo --operation-id %d\n", report.Marked, report.OperationID)
+	}
+}
+
+func runObsolete(cmd *cobra.Command, args []string) error {
+	ids, _ := cmd.Flags().GetInt64Slice("ids")
+	statusCodes, _ := cmd.Flags().GetIntSlice("status-codes")
+	minFailures, _ := cmd.Flags().GetInt("min-failures")
+	linkStatuses, _ := cmd.Flags().GetStringSlice("link-status")
+	reason, _ := cmd.Flags().GetString("reason")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	confirm, _ := cmd.Flags().GetBool("confirm")
+	applyPolicies, _ := cmd.Flags().GetBool("policies")
+	jsonOutput := wantJSON(cmd)
+
+	if applyPolicies {
+		if len(ids) > 0 || len(statusCodes) > 0 || minFailures > 0 || len(linkStatuses) > 0 {
+			return fmt.Errorf("--policies cannot be combined with other criteria")
+		}
+		if obsoletePolicies == nil {
+			return fmt.Errorf("--policies needs a policy file given with --obsolete-policies")
+		}
+		if !dryRun && !confirm {
+			return fmt.Errorf("must use --confirm flag for non-dry-run operations")
+		}
+
+		report, err := obsoletePolicies.Apply(cmd.Context(), database, dryRun)
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			return writeJSON(report)
+		}
+		printObsoleteReport(report)
+		return nil
+	}
+
+	// Validate that at least one criteria is provided
+	if len(ids) == 0 && len(statusCodes) == 0 && minFailures == 0 && len(linkStatuses) == 0 {
+		return fmt.Errorf("must specify at least one criteria: --ids, --status-codes, --min-failures, --link-status, or --policies")
+	}
+
+	// Require confirmation for non-dry-run operations
+	if !dryRun && !confirm {
+		return fmt.Errorf("must use --confirm flag for non-dry-run operations")
+	}
+
+	// Build query conditions
+	var conditions []string
+	var queryArgs []interface{}
+
+	if len(ids) > 0 {
+		placeholders := make([]string, len(ids))
+		for i, id := range ids {
+			placeholders[i] = "?"
+			queryArgs = append(queryArgs, id)
+		}
+		conditions = append(conditions, fmt.Sprintf("id IN (%s)", strings.Join(placeholders, ",")))
+	}
+
+	if len(statusCodes) > 0 {
+		placeholders := make([]string, len(statusCodes))
+		for i, code := range statusCodes {
+			placeholders[i] = "?"
+			queryArgs = append(queryArgs, code)
+		}
+		conditions = append(conditions, fmt.Sprintf("status_code IN (%s)", strings.Join(placeholders, ",")))
+	}
+
+	if minFailures > 0 {
+		conditions = append(conditions, "failed_count >= ?")
+		queryArgs = append(queryArgs, minFailures)
+	}
+
+	if len(linkStatuses) > 0 {
+		placeholders := make([]string, len(linkStatuses))
+		for i, status := range linkStatuses {
+			placeholders[i] = "?"
+			queryArgs = append(queryArgs, status)
+		}
+		conditions = append(conditions, fmt.Sprintf("id IN (SELECT article_id FROM link_checks WHERE status IN (%s))", strings.Join(placeholders, ",")))
+	}
+
+	// Add condition to exclude already obsolete articles
+	conditions = append(conditions, "obsolete = FALSE")
+
+	whereClause := strings.Join(conditions, " AND ")
+
+	// First, get the articles that would be affected
+	selectQuery := fmt.Sprintf(`
+		SELECT id, url, title, status_code, failed_count
+		FROM articles
+		WHERE %s
+		ORDER BY id
+	`, whereClause)
+
+	type ObsoleteCandidate struct {
+		ID          int64  `db:"id" json:"id"`
+		URL         string `db:"url" json:"url"`
+		Title       string `db:"title" json:"title"`
+		StatusCode  *int   `db:"status_code" json:"status_code,omitempty"`
+		FailedCount int    `db:"failed_count" json:"failed_count"`
+	}
+
+	var candidates []ObsoleteCandidate
+	if err := database.Select(&candidates, selectQuery, queryArgs...); err != nil {
+		return fmt.Errorf("failed to find articles: %w", err)
+	}
+
+	result := struct {
+		DryRun      bool                `json:"dry_run"`
+		Candidates  []ObsoleteCandidate `json:"candidates"`
+		Marked      int64               `json:"marked"`
+		OperationID int64               `json:"operation_id,omitempty"`
+	}{DryRun: dryRun, Candidates: candidates}
+
+	if len(candidates) == 0 {
+		if jsonOutput {
+			return writeJSON(result)
+		}
+		fmt.Println("No articles found matching the criteria.")
+		return nil
+	}
+
+	if !jsonOutput {
+		// Show articles that would be obsoleted
+		for _, article := range candidates {
+			statusStr := "unknown"
+			if article.StatusCode != nil {
+				statusStr = fmt.Sprintf("%d", *article.StatusCode)
+			}
+			fmt.Printf("  ID: %d | Status: %s | Failures: %d\n", article.ID, statusStr, article.FailedCount)
+			fmt.Printf("  URL: %s\n", article.URL)
+			fmt.Printf("  Title: %s\n\n", article.Title)
+		}
+
+		fmt.Printf("Found %d articles to mark as obsolete.\n", len(candidates))
+	}
+
+	if dryRun {
+		if jsonOutput {
+			return writeJSON(result)
+		}
+		fmt.Println("Dry run completed. Use --confirm to actually mark these articles as obsolete.")
+		return nil
+	}
+
+	// Execute the update, removing each article from the FTS index
+	candidateIDs := make([]int64, len(candidates))
+	for i, article := range candidates {
+		candidateIDs[i] = article.ID
+	}
+
+	criteria := flagCriteria(cmd, "ids", "status-codes", "min-failures", "link-status")
+	operationID, err := database.BeginOperation(db.OperationObsolete, strings.TrimSpace("obsolete "+criteria))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := database.SetArticlesObsolete(candidateIDs, true, db.ObsoleteAudit{
+		Reason:      reason,
+		Criteria:    criteria,
+		OperationID: operationID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark articles as obsolete: %w", err)
+	}
+
+	if jsonOutput {
+		result.Marked = rowsAffected
+		result.OperationID = operationID
+		return writeJSON(result)
+	}
+
+	fmt.Printf("Successfully marked %d articles as obsolete.\n", rowsAffected)
+	fmt.Printf("Undo with: instapaper-cli undo --operation-id %d\n", operationID)
+	return nil
+}
+
+// BulkResult reports the articles matched by bulk and what was changed
+type BulkResult struct {
+	DryRun        bool                 `json:"dry_run"`
+	AddTags       []string             `json:"add_tags,omitempty"`
+	RemoveTags    []string             `json:"remove_tags,omitempty"`
+	SetFolder     string               `json:"set_folder,omitempty"`
+	SetStatus     string               `json:"set_status,omitempty"`
+	Candidates    []model.SearchResult `json:"candidates"`
+	Retagged      int                  `json:"retagged"`
+	Moved         int64                `json:"moved"`
+	StatusChanged int64                `json:"status_changed"`
+	OperationID   int64                `json:"operation_id,omitempty"`
+}
+
+func runBulk(cmd *cobra.Command, args []string) error {
+	query, _ := cmd.Flags().GetString("from-search")
+	field, _ := cmd.Flags().GetString("field")
+	useFTS, _ := cmd.Flags().GetBool("fts")
+	limit, _ := cmd.Flags().GetInt("limit")
+	addTags, _ := cmd.Flags().GetStringSlice("add-tag")
+	removeTags, _ := cmd.Flags().GetStringSlice("remove-tag")
+	setFolder, _ := cmd.Flags().GetString("set-folder")
+	setStatus, _ := cmd.Flags().GetString("set-status")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	confirm, _ := cmd.Flags().GetBool("confirm")
+	jsonOutput := wantJSON(cmd)
+
+	addTags = util.DedupeStrings(addTags)
+	removeTags = util.DedupeStrings(removeTags)
+	setFolder = strings.Join(db.SplitFolderPath(setFolder), "/")
+
+	if len(addTags) == 0 && len(removeTags) == 0 && setFolder == "" && setStatus == "" {
+		return fmt.Errorf("must specify at least one change: --add-tag, --remove-tag, --set-folder, or --set-status")
+	}
+	if setStatus != "" {
+		if err := db.ValidateStatuses(setStatus); err != nil {
+			return err
+		}
+	}
+	statuses, err := statusFilter(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Require confirmation for non-dry-run operations
+	if !dryRun && !confirm {
+		return fmt.Errorf("must use --confirm flag for non-dry-run operations")
+	}
+
+	searchOpts := search.SearchOptions{
+		Query:  query,
+		Field:  field,
+		UseFTS: useFTS,
+		Limit:  limit,
+	}
+	searchOpts.Statuses = statuses
+	candidates, err := search.New(database).Find(cmd.Context(), searchOpts)
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+
+	result := BulkResult{
+		DryRun:     dryRun,
+		AddTags:    addTags,
+		RemoveTags: removeTags,
+		SetFolder:  setFolder,
+		SetStatus:  setStatus,
+		Candidates: candidates,
+	}
+
+	if len(candidates) == 0 {
+		if jsonOutput {
+			return writeJSON(result)
+		}
+		fmt.Println("No articles found matching the search.")
+		return nil
+	}
+
+	if !jsonOutput {
+		for _, article := range candidates {
+			folder, tags := "", ""
+			if article.FolderPath != nil {
+				folder = *article.FolderPath
+			}
+			if article.Tags != nil {
+				tags = *article.Tags
+			}
+			fmt.Printf("  ID: %d | Folder: %s | Tags: %s\n", article.ID, folder, tags)
+			fmt.Printf("  Title: %s\n\n", article.Title)
+		}
+
+		fmt.Printf("Found %d articles to update:", len(candidates))
+		if len(addTags) > 0 {
+			fmt.Printf(" add tags %s;", strings.Join(addTags, ", "))
+		}
+		if len(removeTags) > 0 {
+			fmt.Printf(" remove tags %s;", strings.Join(removeTags, ", "))
+		}
+		if setFolder != "" {
+			fmt.Printf(" move to folder %s;", setFolder)
+		}
+		if setStatus != "" {
+			fmt.Printf(" set status %s;", setStatus)
+		}
+		fmt.Println()
+	}
+
+	if dryRun {
+		if jsonOutput {
+			return writeJSON(result)
+		}
+		fmt.Println("Dry run completed. Use --confirm to apply these changes.")
+		return nil
+	}
+
+	ids := make([]int64, len(candidates))
+	for i, article := range candidates {
+		ids[i] = article.ID
+	}
+
+	criteria := flagCriteria(cmd, "from-search", "field", "fts", "limit", "status", "add-tag", "remove-tag", "set-folder", "set-status")
+	result.OperationID, err = database.BeginOperation(db.OperationBulk, "bulk "+criteria)
+	if err != nil {
+		return err
+	}
+
+	if len(addTags) > 0 || len(removeTags) > 0 {
+		for _, id := range ids {
+			if err := cmd.Context().Err(); err != nil {
+				return fmt.Errorf("bulk update interrupted after %d articles (undo with --operation-id %d): %w", result.Retagged, result.OperationID, err)
+			}
+			changed, err := database.RetagArticle(result.OperationID, id, addTags, removeTags)
+			if err != nil {
+				return fmt.Errorf("failed to update tags on article %d: %w", id, err)
+			}
+			if changed {
+				result.Retagged++
+			}
+		}
+	}
+
+	if setFolder != "" {
+		folderID, err := database.UpsertFolderPath(setFolder)
+		if err != nil {
+			return fmt.Errorf("failed to create folder: %w", err)
+		}
+		if err := database.UpdateFolderPaths(); err != nil {
+			return fmt.Errorf("failed to update folder paths: %w", err)
+		}
+
+		result.Moved, err = database.SetArticlesFolder(ids, &folderID, result.OperationID)
+		if err != nil {
+			return fmt.Errorf("failed to move articles: %w", err)
+		}
+	}
+
+	if setStatus != "" {
+		result.StatusChanged, err = database.SetArticlesStatus(ids, setStatus, result.OperationID)
+		if err != nil {
+			return fmt.Errorf("failed to set status: %w", err)
+		}
 	}
 
-	opts := export.ExportAllOptions{
-		Directory:       dir,
-		OnlySynced:      onlySynced && !includeUnsynced,
-		IncludeUnsynced: includeUnsynced,
-		FolderFilter:    folder,
-		TagFilter:       tag,
-		Since:           since,
-		Until:           until,
-		FromSearch:      fromSearch,
-		SearchField:     searchField,
-		SearchFTS:       searchFTS,
-		SearchLimit:     searchLimit,
+	if jsonOutput {
+		return writeJSON(result)
 	}
 
-	e := export.New(database)
-	return e.ExportAll(opts)
+	if result.Retagged > 0 {
+		fmt.Printf("Updated tags on %d articles.\n", result.Retagged)
+	}
+	if setFolder != "" {
+		fmt.Printf("Moved %d articles to %s.\n", result.Moved, setFolder)
+	}
+	if setStatus != "" {
+		fmt.Printf("Set %d articles to %s.\n", result.StatusChanged, setStatus)
+	}
+	fmt.Printf("Undo with: instapaper-cli undo --operation-id %d\n", result.OperationID)
+	return nil
 }
 
-func runFolders(cmd *cobra.Command, args []string) error {
-	action, _ := cmd.Flags().GetString("action")
-
-	switch action {
-	case "list":
-		return listFolders()
-	case "mv":
-		source, _ := cmd.Flags().GetString("source")
-		target, _ := cmd.Flags().GetString("target")
-		if source == "" || target == "" {
-			return fmt.Errorf("both --source and --target are required for mv action")
-		}
-		return moveFolders(source, target)
-	case "mkdir":
-		name, _ := cmd.Flags().GetString("name")
-		if name == "" {
-			return fmt.Errorf("--name is required for mkdir action")
+// flagCriteria renders the given flags that were set on the command line, e.g. "--status-codes 404 --min-failures 3"
+func flagCriteria(cmd *cobra.Command, names ...string) string {
+	var parts []string
+	for _, name := range names {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || !flag.Changed {
+			continue
 		}
-		return createFolder(name)
-	default:
-		return fmt.Errorf("invalid action: %s. Use list, mv, or mkdir", action)
+		value := strings.Trim(flag.Value.String(), "[]")
+		parts = append(parts, "--"+name+" "+value)
 	}
+	return strings.Join(parts, " ")
 }
 
-func runTags(cmd *cobra.Command, args []string) error {
-	action, _ := cmd.Flags().GetString("action")
+func runRestore(cmd *cobra.Command, args []string) error {
+	ids, _ := cmd.Flags().GetInt64Slice("ids")
+	reason, _ := cmd.Flags().GetString("reason")
 
-	switch action {
-	case "list":
-		return listTags()
-	case "rename":
-		old, _ := cmd.Flags().GetString("old")
-		new, _ := cmd.Flags().GetString("new")
-		if old == "" || new == "" {
-			return fmt.Errorf("both --old and --new are required for rename action")
-		}
-		return renameTag(old, new)
-	default:
-		return fmt.Errorf("invalid action: %s. Use list or rename", action)
+	if len(ids) == 0 {
+		return fmt.Errorf("must specify --ids")
 	}
-}
 
-func runDoctor(cmd *cobra.Command, args []string) error {
-	return runDatabaseDoctor()
+	criteria := flagCriteria(cmd, "ids")
+	operationID, err := database.BeginOperation(db.OperationRestore, "restore "+criteria)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := database.SetArticlesObsolete(ids, false, db.ObsoleteAudit{
+		Reason:      reason,
+		Criteria:    criteria,
+		OperationID: operationID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore articles: %w", err)
+	}
+
+	if wantJSON(cmd) {
+		return writeJSON(map[string]interface{}{"restored": rowsAffected, "operation_id": operationID})
+	}
+
+	fmt.Printf("Restored %d obsolete articles.\n", rowsAffected)
+	fmt.Printf("Undo with: instapaper-cli undo --operation-id %d\n", operationID)
+	return nil
 }
 
-func listFolders() error {
-	query := `
-		SELECT id, title, parent_id, path_cache
-		FROM folders
-		ORDER BY path_cache
-	`
+func runTrash(cmd *cobra.Command, args []string) error {
+	ids, _ := cmd.Flags().GetInt64Slice("id")
+	reason, _ := cmd.Flags().GetString("reason")
 
-	var folders []struct {
-		ID        int64   `db:"id"`
-		Title     string  `db:"title"`
-		ParentID  *int64  `db:"parent_id"`
-		PathCache *string `db:"path_cache"`
+	if len(ids) == 0 {
+		return fmt.Errorf("must specify --id")
 	}
 
-	if err := database.Select(&folders, query); err != nil {
-		return fmt.Errorf("failed to get folders: %w", err)
+	trashed, err := database.TrashArticles(ids, reason)
+	if err != nil {
+		return err
 	}
 
-	fmt.Printf("%-5s %-30s %-10s %s\n", "ID", "PATH", "PARENT", "TITLE")
-	fmt.Println(strings.Repeat("-", 80))
+	if wantJSON(cmd) {
+		return writeJSON(map[string]interface{}{"trashed": trashed})
+	}
 
-	for _, folder := range folders {
-		parentStr := ""
-		if folder.ParentID != nil {
-			parentStr = fmt.Sprintf("%d", *folder.ParentID)
-		}
+	fmt.Printf("Moved %d articles to the trash.\n", trashed)
+	if trashed > 0 {
+		fmt.Printf("Restore with: instapaper-cli trash restore %s\n", flagCriteria(cmd, "id"))
+	}
+	return nil
+}
 
-		pathStr := folder.Title
-		if folder.PathCache != nil {
-			pathStr = *folder.PathCache
-		}
+// trashCutoff parses an --older-than age into the time articles must have been trashed before,
+// the zero time when no age is given
+func trashCutoff(olderThan string) (time.Time, error) {
+	if olderThan == "" {
+		return time.Time{}, nil
+	}
+	cutoff, err := util.ParseRelativeDate(olderThan)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --older-than value %q: use an age like 30d, 12w, or 6m", olderThan)
+	}
+	return cutoff, nil
+}
 
-		fmt.Printf("%-5d %-30s %-10s %s\n", folder.ID, pathStr, parentStr, folder.Title)
+func runTrashList(cmd *cobra.Command, args []string) error {
+	olderThan, _ := cmd.Flags().GetString("older-than")
+	limit, _ := cmd.Flags().GetInt("limit")
+
+	cutoff, err := trashCutoff(olderThan)
+	if err != nil {
+		return err
+	}
+
+	articles, err := database.Trash(cutoff, limit)
+	if err != nil {
+		return err
 	}
 
+	if wantJSON(cmd) {
+		return writeJSON(articles)
+	}
+
+	if len(articles) == 0 {
+		fmt.Println("The trash is empty.")
+		return nil
+	}
+	printTrash(articles)
 	return nil
 }
 
-func moveFolders(source, target string) error {
-	return fmt.Errorf("folder move not yet implemented")
+func printTrash(articles []db.TrashedArticle) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTRASHED\tTITLE\tREASON")
+	for _, article := range articles {
+		reason := ""
+		if article.Reason != nil {
+			reason = *article.Reason
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", article.ID, article.TrashedAt, article.Title, reason)
+	}
+	w.Flush()
 }
 
-func createFolder(name string) error {
-	_, err := database.UpsertFolder(name, nil)
+func runTrashRestore(cmd *cobra.Command, args []string) error {
+	ids, _ := cmd.Flags().GetInt64Slice("id")
+
+	if len(ids) == 0 {
+		return fmt.Errorf("must specify --id")
+	}
+
+	restored, err := database.RestoreTrash(ids)
 	if err != nil {
-		return fmt.Errorf("failed to create folder: %w", err)
+		return err
 	}
 
-	if err := database.UpdateFolderPaths(); err != nil {
-		return fmt.Errorf("failed to update folder paths: %w", err)
+	if wantJSON(cmd) {
+		return writeJSON(map[string]interface{}{"restored": restored})
 	}
 
-	fmt.Printf("Created folder: %s\n", name)
+	fmt.Printf("Restored %d articles from the trash.\n", restored)
 	return nil
 }
 
-func listTags() error {
-	query := `
-		SELECT t.id, t.title, COUNT(at.article_id) as article_count
-		FROM tags t
-		LEFT JOIN article_tags at ON t.id = at.tag_id
-		GROUP BY t.id, t.title
-		ORDER BY t.title
-	`
+func runTrashEmpty(cmd *cobra.Command, args []string) error {
+	olderThan, _ := cmd.Flags().GetString("older-than")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	confirm, _ := cmd.Flags().GetBool("confirm")
+	dryRun = dryRun || !confirm
 
-	var tags []struct {
-		ID           int64  `db:"id"`
-		Title        string `db:"title"`
-		ArticleCount int    `db:"article_count"`
+	cutoff, err := trashCutoff(olderThan)
+	if err != nil {
+		return err
 	}
 
-	if err := database.Select(&tags, query); err != nil {
-		return fmt.Errorf("failed to get tags: %w", err)
+	var articles []db.TrashedArticle
+	var operationID int64
+	if dryRun {
+		articles, err = database.Trash(cutoff, 0)
+	} else {
+		description := "trash empty"
+		if olderThan != "" {
+			description += " --older-than " + olderThan
+		}
+		if operationID, err = database.BeginOperation(db.OperationEmptyTrash, description); err != nil {
+			return err
+		}
+		articles, err = database.EmptyTrash(cmd.Context(), operationID, cutoff)
 	}
 
-	fmt.Printf("%-5s %-30s %s\n", "ID", "TAG", "ARTICLES")
-	fmt.Println(strings.Repeat("-", 50))
+	if wantJSON(cmd) {
+		result := map[string]interface{}{"dry_run": dryRun, "deleted": len(articles), "articles": articles}
+		if operationID != 0 {
+			result["operation_id"] = operationID
+		}
+		if jsonErr := writeJSON(result); jsonErr != nil {
+			return jsonErr
+		}
+		return err
+	}
 
-	for _, tag := range tags {
-		fmt.Printf("%-5d %-30s %d\n", tag.ID, tag.Title, tag.ArticleCount)
+	if dryRun && len(articles) > 0 {
+		printTrash(articles)
+		fmt.Println()
+		fmt.Printf("Would delete %d articles for good.\n", len(articles))
+		if !confirm {
+			fmt.Println("Run again with --confirm to delete them.")
+		}
+	} else if !dryRun {
+		fmt.Printf("Deleted %d articles for good.\n", len(articles))
+	} else {
+		fmt.Println("Nothing to delete.")
 	}
+	return err
+}
 
-	return nil
+// emptyTrash deletes articles trashed longer ago than retention, for daemon
+func emptyTrash(ctx context.Context, retention string) {
+	cutoff, err := trashCutoff(retention)
+	if err != nil || ctx.Err() != nil {
+		return
+	}
+
+	lock, err := database.LockWrites(ctx, "daemon", lockWait)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Leaving the trash for later: %v", err)
+		}
+		return
+	}
+	defer lock.Release()
+
+	operationID, err := database.BeginOperation(db.OperationEmptyTrash, "daemon --trash-retention "+retention)
+	if err != nil {
+		log.Printf("Emptying the trash failed: %v", err)
+		return
+	}
+	articles, err := database.EmptyTrash(ctx, operationID, cutoff)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("Emptying the trash failed: %v", err)
+	}
+	if len(articles) > 0 {
+		log.Printf("Deleted %d articles trashed more than %s ago (operation %d)", len(articles), retention, operationID)
+	}
 }
 
-func renameTag(old, new string) error {
-	result, err := database.Exec("UPDATE tags SET title = ? WHERE title = ?", new, old)
+func runStatus(cmd *cobra.Command, args []string) error {
+	counts, err := database.StatusCounts()
 	if err != nil {
-		return fmt.Errorf("failed to rename tag: %w", err)
+		return err
 	}
 
-	rows, _ := result.RowsAffected()
-	if rows == 0 {
-		return fmt.Errorf("tag '%s' not found", old)
+	if wantJSON(cmd) {
+		return writeJSON(counts)
 	}
 
-	fmt.Printf("Renamed tag '%s' to '%s'\n", old, new)
-	return nil
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tARTICLES")
+	for _, count := range counts {
+		fmt.Fprintf(w, "%s\t%d\n", count.Status, count.Count)
+	}
+	return w.Flush()
 }
 
-func runDatabaseDoctor() error {
-	fmt.Println("Running database integrity checks...")
+func runStatusSet(cmd *cobra.Command, args []string) error {
+	ids, _ := cmd.Flags().GetInt64Slice("ids")
+	status := strings.ToLower(strings.TrimSpace(args[0]))
 
-	if _, err := database.Exec("PRAGMA integrity_check"); err != nil {
-		return fmt.Errorf("integrity check failed: %w", err)
+	if err := db.ValidateStatuses(status); err != nil {
+		return err
 	}
-
-	if _, err := database.Exec("PRAGMA foreign_key_check"); err != nil {
-		return fmt.Errorf("foreign key check failed: %w", err)
+	if len(ids) == 0 {
+		return fmt.Errorf("must specify --ids")
 	}
 
-	var counts struct {
-		Articles       int `db:"articles"`
-		Folders        int `db:"folders"`
-		Tags           int `db:"tags"`
-		SyncedArticles int `db:"synced_articles"`
-		FailedArticles int `db:"failed_articles"`
+	operationID, err := database.BeginOperation(db.OperationStatus, "status set "+status+" "+flagCriteria(cmd, "ids"))
+	if err != nil {
+		return err
 	}
 
-	countQuery := `
-		SELECT
-			(SELECT COUNT(*) FROM articles) as articles,
-			(SELECT COUNT(*) FROM folders) as folders,
-			(SELECT COUNT(*) FROM tags) as tags,
-			(SELECT COUNT(*) FROM articles WHERE synced_at IS NOT NULL) as synced_articles,
-			(SELECT COUNT(*) FROM articles WHERE failed_count >= 5) as failed_articles
-	`
+	updated, err := database.SetArticlesStatus(ids, status, operationID)
+	if err != nil {
+		return fmt.Errorf("failed to set status after %d articles (undo with --operation-id %d): %w", updated, operationID, err)
+	}
 
-	if err := database.Get(&counts, countQuery); err != nil {
-		return fmt.Errorf("failed to get counts: %w", err)
+	if wantJSON(cmd) {
+		return writeJSON(map[string]interface{}{"status": status, "updated": updated, "operation_id": operationID})
 	}
 
-	fmt.Printf("Database Statistics:\n")
-	fmt.Printf("  Articles: %d\n", counts.Articles)
-	fmt.Printf("  Folders: %d\n", counts.Folders)
-	fmt.Printf("  Tags: %d\n", counts.Tags)
-	fmt.Printf("  Synced Articles: %d\n", counts.SyncedArticles)
-	fmt.Printf("  Failed Articles (5+ failures): %d\n", counts.FailedArticles)
+	fmt.Printf("Set %d of %d articles to %s.\n", updated, len(ids), status)
+	fmt.Printf("Undo with: instapaper-cli undo --operation-id %d\n", operationID)
+	return nil
+}
 
-	fmt.Println("\nUpdating folder paths...")
-	if err := database.UpdateFolderPaths(); err != nil {
-		return fmt.Errorf("failed to update folder paths: %w", err)
+func runGoals(cmd *cobra.Command, args []string) error {
+	progress, err := database.GoalProgress(time.Now())
+	if err != nil {
+		return err
 	}
 
-	fmt.Println("\nRebuilding FTS index...")
-	if err := database.RebuildFTS(); err != nil {
-		fmt.Printf("Warning: FTS rebuild failed: %v\n", err)
-	} else {
-		fmt.Println("FTS index rebuilt successfully!")
+	if wantJSON(cmd) {
+		return writeJSON(progress)
 	}
 
-	var duplicateURLs []struct {
-		URL   string `db:"url"`
-		Count int    `db:"count"`
+	if len(progress) == 0 {
+		fmt.Println("No reading goals. Set one with: instapaper-cli goals set articles 5")
+		return nil
 	}
 
-	duplicateQuery := `
-		SELECT url, COUNT(*) as count
-		FROM articles
-		GROUP BY url
-		HAVING COUNT(*) > 1
-	`
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GOAL\tPERIOD\tDONE\tPROGRESS\tSTREAK")
+	for _, p := range progress {
+		fmt.Fprintf(w, "%d %s per %s\t%s to %s\t%d\t%s\t%d\n",
+			p.Target, p.Metric, p.Period, p.PeriodStart, p.PeriodEnd, p.Done, progressBar(p.Percent), p.Streak)
+	}
+	return w.Flush()
+}
 
-	if err := database.Select(&duplicateURLs, duplicateQuery); err == nil && len(duplicateURLs) > 0 {
-		fmt.Printf("\nWarning: Found %d duplicate URLs:\n", len(duplicateURLs))
-		for _, dup := range duplicateURLs {
-			fmt.Printf("  %s (%d copies)\n", dup.URL, dup.Count)
-		}
+// progressBar draws a percentage as a 10-character bar followed by the number, e.g. "██████░░░░ 60%"
+func progressBar(percent float64) string {
+	filled := int(percent / 10)
+	return strings.Repeat("█", filled) + strings.Repeat("░", 10-filled) + fmt.Sprintf(" %.0f%%", percent)
+}
+
+func runGoalsSet(cmd *cobra.Command, args []string) error {
+	per, _ := cmd.Flags().GetString("per")
+
+	target, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid goal target %q: %w", args[1], err)
+	}
+	if err := database.SetGoal(args[0], per, target); err != nil {
+		return err
 	}
 
-	fmt.Println("\nDatabase doctor completed successfully!")
+	if wantJSON(cmd) {
+		return writeJSON(map[string]interface{}{"metric": args[0], "period": per, "target": target})
+	}
+	fmt.Printf("Goal set: %d %s per %s.\n", target, args[0], per)
 	return nil
 }
 
-func runMCP(cmd *cobra.Command, args []string) error {
-	fmt.Fprintf(os.Stderr, "Starting MCP server for instapaper-cli %s\n", version.GetVersion())
-	fmt.Fprintf(os.Stderr, "Database: %s\n", dbPath)
-	fmt.Fprintf(os.Stderr, "MCP server listening on stdio...\n")
+func runGoalsRemove(cmd *cobra.Command, args []string) error {
+	per, _ := cmd.Flags().GetString("per")
+
+	removed, err := database.RemoveGoal(args[0], per)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("no %s per %s goal is set", args[0], per)
+	}
 
-	// Create and start MCP server
-	server := mcp.NewServer(database)
-	return server.Start()
+	if wantJSON(cmd) {
+		return writeJSON(map[string]interface{}{"metric": args[0], "period": per, "removed": true})
+	}
+	fmt.Printf("Removed the %s per %s goal.\n", args[0], per)
+	return nil
 }
 
-func runObsolete(cmd *cobra.Command, args []string) error {
-	ids, _ := cmd.Flags().GetInt64Slice("ids")
-	statusCodes, _ := cmd.Flags().GetIntSlice("status-codes")
-	minFailures, _ := cmd.Flags().GetInt("min-failures")
+func runUndo(cmd *cobra.Command, args []string) error {
+	operationID, _ := cmd.Flags().GetInt64("operation-id")
+	list, _ := cmd.Flags().GetBool("list")
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
-	confirm, _ := cmd.Flags().GetBool("confirm")
+	jsonOutput := wantJSON(cmd)
 
-	// Validate that at least one criteria is provided
-	if len(ids) == 0 && len(statusCodes) == 0 && minFailures == 0 {
-		return fmt.Errorf("must specify at least one criteria: --ids, --status-codes, or --min-failures")
+	if list {
+		limit, _ := cmd.Flags().GetInt("limit")
+		return listOperations(limit, jsonOutput)
 	}
-
-	// Require confirmation for non-dry-run operations
-	if !dryRun && !confirm {
-		return fmt.Errorf("must use --confirm flag for non-dry-run operations")
+	if operationID == 0 {
+		return fmt.Errorf("must specify --operation-id or --list")
 	}
 
-	// Build query conditions
-	var conditions []string
-	var queryArgs []interface{}
+	operation, changes, err := database.GetOperation(operationID)
+	if err != nil {
+		return err
+	}
 
-	if len(ids) > 0 {
-		placeholders := make([]string, len(ids))
-		for i, id := range ids {
-			placeholders[i] = "?"
-			queryArgs = append(queryArgs, id)
+	if dryRun {
+		if jsonOutput {
+			return writeJSON(map[string]interface{}{"operation": operation, "changes": changes})
 		}
-		conditions = append(conditions, fmt.Sprintf("id IN (%s)", strings.Join(placeholders, ",")))
+		printOperation(*operation)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ARTICLE\tFIELD\tBEFORE\tAFTER")
+		for _, change := range changes {
+			article := "-"
+			if change.ArticleID != nil {
+				article = fmt.Sprintf("%d", *change.ArticleID)
+			}
+			before := optionalString(change.Before)
+			// The tombstone of a deleted article is JSON; show which article it was
+			if change.Field == db.ChangeArticleDeleted {
+				if id, url, err := db.DeletedArticle(before); err == nil {
+					article, before = fmt.Sprintf("%d", id), url
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", article, change.Field, before, optionalString(change.After))
+		}
+		w.Flush()
+		if operation.Kind == db.OperationEmptyTrash {
+			fmt.Println("\nThese articles were deleted for good; this operation cannot be undone.")
+			return nil
+		}
+		fmt.Printf("\nDry run completed. %d changes would be reverted.\n", len(changes))
+		return nil
 	}
 
-	if len(statusCodes) > 0 {
-		placeholders := make([]string, len(statusCodes))
-		for i, code := range statusCodes {
-			placeholders[i] = "?"
-			queryArgs = append(queryArgs, code)
-		}
-		conditions = append(conditions, fmt.Sprintf("status_code IN (%s)", strings.Join(placeholders, ",")))
+	reverted, err := database.UndoOperation(operationID)
+	if err != nil {
+		return err
 	}
 
-	if minFailures > 0 {
-		conditions = append(conditions, "failed_count >= ?")
-		queryArgs = append(queryArgs, minFailures)
+	if jsonOutput {
+		return writeJSON(map[string]interface{}{"operation_id": operationID, "reverted": reverted})
 	}
 
-	// Add condition to exclude already obsolete articles
-	conditions = append(conditions, "obsolete = FALSE")
+	fmt.Printf("Undid operation %d (%s): reverted %d changes.\n", operationID, operation.Kind, reverted)
+	return nil
+}
 
-	whereClause := strings.Join(conditions, " AND ")
+// ruleTestResult is an article, or a tested URL, and what the tag rules would apply to it
+type ruleTestResult struct {
+	ID    int64  `db:"id" json:"id,omitempty"`
+	URL   string `db:"url" json:"url"`
+	Title string `db:"title" json:"title"`
+	*tagging.RuleMatch
+}
 
-	// First, get the articles that would be affected
-	selectQuery := fmt.Sprintf(`
-		SELECT id, url, title, status_code, failed_count
-		FROM articles
-		WHERE %s
-		ORDER BY id
-	`, whereClause)
+func runRulesTest(cmd *cobra.Command, args []string) error {
+	title, _ := cmd.Flags().GetString("title")
+	limit, _ := cmd.Flags().GetInt("limit")
+	jsonOutput := wantJSON(cmd)
 
-	type ObsoleteCandidate struct {
-		ID          int64  `db:"id"`
-		URL         string `db:"url"`
-		Title       string `db:"title"`
-		StatusCode  *int   `db:"status_code"`
-		FailedCount int    `db:"failed_count"`
+	if tagRules == nil {
+		return fmt.Errorf("no rules to test: pass --tag-rules FILE")
 	}
 
-	var candidates []ObsoleteCandidate
-	if err := database.Select(&candidates, selectQuery, queryArgs...); err != nil {
-		return fmt.Errorf("failed to find articles: %w", err)
+	var candidates []ruleTestResult
+	if len(args) == 1 {
+		canonicalURL, err := util.CanonicalizeURL(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid URL %q: %w", args[0], err)
+		}
+		if title == "" {
+			title = canonicalURL
+		}
+		candidates = append(candidates, ruleTestResult{URL: canonicalURL, Title: title})
+	} else if err := database.Select(&candidates, "SELECT id, url, title FROM articles WHERE obsolete = FALSE ORDER BY id"); err != nil {
+		return fmt.Errorf("failed to get articles: %w", err)
 	}
 
-	if len(candidates) == 0 {
-		fmt.Println("No articles found matching the criteria.")
+	results := []ruleTestResult{}
+	for _, candidate := range candidates {
+		if candidate.RuleMatch = tagRules.Apply(candidate.URL, candidate.Title); candidate.RuleMatch != nil {
+			results = append(results, candidate)
+		}
+	}
+	matched := len(results)
+	if limit > 0 && matched > limit {
+		results = results[:limit]
+	}
+
+	if jsonOutput {
+		return writeJSON(results)
+	}
+
+	if len(args) == 1 && len(results) == 0 {
+		fmt.Printf("No rules match %s\n", candidates[0].URL)
 		return nil
 	}
 
-	// Show articles that would be obsoleted
-	for _, article := range candidates {
-		statusStr := "unknown"
-		if article.StatusCode != nil {
-			statusStr = fmt.Sprintf("%d", *article.StatusCode)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tRULES\tTAGS\tFOLDER\tTITLE")
+	for _, result := range results {
+		id := "-"
+		if result.ID != 0 {
+			id = fmt.Sprintf("%d", result.ID)
+		}
+		folder := result.Folder
+		if folder == "" {
+			folder = "-"
 		}
-		fmt.Printf("  ID: %d | Status: %s | Failures: %d\n", article.ID, statusStr, article.FailedCount)
-		fmt.Printf("  URL: %s\n", article.URL)
-		fmt.Printf("  Title: %s\n\n", article.Title)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", id, strings.Join(result.Rules, ", "), strings.Join(result.Tags, ", "), folder, truncate(result.Title, 50))
 	}
+	w.Flush()
 
-	fmt.Printf("Found %d articles to mark as obsolete.\n", len(candidates))
+	if len(args) == 0 {
+		fmt.Printf("\nDry run: %d of %d articles match. Rules apply to articles added by import, add, and rss.\n", matched, len(candidates))
+	}
+	return nil
+}
 
-	if dryRun {
-		fmt.Println("Dry run completed. Use --confirm to actually mark these articles as obsolete.")
-		return nil
+func listOperations(limit int, jsonOutput bool) error {
+	operations, err := database.Operations(limit)
+	if err != nil {
+		return err
 	}
 
-	// Execute the update
-	updateQuery := fmt.Sprintf(`
-		UPDATE articles
-		SET obsolete = TRUE
-		WHERE %s
-	`, whereClause)
+	if jsonOutput {
+		return writeJSON(operations)
+	}
 
-	result, err := database.Exec(updateQuery, queryArgs...)
-	if err != nil {
-		return fmt.Errorf("failed to mark articles as obsolete: %w", err)
+	if len(operations) == 0 {
+		fmt.Println("No operations recorded.")
+		return nil
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	fmt.Printf("Successfully marked %d articles as obsolete.\n", rowsAffected)
-	return nil
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tKIND\tCREATED\tARTICLES\tCHANGES\tUNDONE\tDESCRIPTION")
+	for _, operation := range operations {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\t%s\t%s\n", operation.ID, operation.Kind, operation.CreatedAt,
+			operation.Articles, operation.Changes, optionalString(operation.UndoneAt), truncate(optionalString(operation.Description), 60))
+	}
+	return w.Flush()
+}
+
+func printOperation(operation db.Operation) {
+	fmt.Printf("Operation %d: %s at %s\n", operation.ID, operation.Kind, operation.CreatedAt)
+	if operation.Description != nil {
+		fmt.Printf("  %s\n", *operation.Description)
+	}
+	if operation.UndoneAt != nil {
+		fmt.Printf("  Already undone at %s\n", *operation.UndoneAt)
+	}
+	fmt.Println()
 }
 
 func runListObsolete(cmd *cobra.Command, args []string) error {
-	jsonOutput, _ := cmd.Flags().GetBool("json")
+	jsonOutput := wantJSON(cmd)
 	limit, _ := cmd.Flags().GetInt("limit")
 
+	if history, _ := cmd.Flags().GetInt64("history"); history != 0 {
+		return printObsoleteHistory(history, jsonOutput)
+	}
+
+	// The latest obsolete action explains the article's current state
 	query := `
-		SELECT id, url, title, folder_id, instapapered_at, status_code, failed_count
-		FROM articles
-		WHERE obsolete = TRUE
-		ORDER BY instapapered_at DESC
+		SELECT a.id, a.url, a.title, a.folder_id, a.instapapered_at, a.status_code, a.failed_count,
+		       ol.reason, ol.criteria, ol.created_at AS obsoleted_at
+		FROM articles a
+		LEFT JOIN obsolete_log ol ON ol.id = (
+			SELECT MAX(id) FROM obsolete_log WHERE article_id = a.id AND action = 'obsolete'
+		)
+		WHERE a.obsolete = TRUE AND a.id NOT IN (SELECT article_id FROM trash)
+		ORDER BY ol.created_at IS NULL, ol.created_at DESC, a.instapapered_at DESC
 	`
 
 	if limit > 0 {
@@ -842,31 +4737,32 @@ func runListObsolete(cmd *cobra.Command, args []string) error {
 	}
 
 	type ObsoleteArticle struct {
-		ID             int64  `db:"id" json:"id"`
-		URL            string `db:"url" json:"url"`
-		Title          string `db:"title" json:"title"`
-		FolderID       *int64 `db:"folder_id" json:"folder_id,omitempty"`
-		InstapaperedAt string `db:"instapapered_at" json:"instapapered_at"`
-		StatusCode     *int   `db:"status_code" json:"status_code,omitempty"`
-		FailedCount    int    `db:"failed_count" json:"failed_count"`
+		ID             int64   `db:"id" json:"id"`
+		URL            string  `db:"url" json:"url"`
+		Title          string  `db:"title" json:"title"`
+		FolderID       *int64  `db:"folder_id" json:"folder_id,omitempty"`
+		InstapaperedAt string  `db:"instapapered_at" json:"instapapered_at"`
+		StatusCode     *int    `db:"status_code" json:"status_code,omitempty"`
+		FailedCount    int     `db:"failed_count" json:"failed_count"`
+		Reason         *string `db:"reason" json:"reason,omitempty"`
+		Criteria       *string `db:"criteria" json:"criteria,omitempty"`
+		ObsoletedAt    *string `db:"obsoleted_at" json:"obsoleted_at,omitempty"`
 	}
 
-	var articles []ObsoleteArticle
+	articles := []ObsoleteArticle{}
 	if err := database.Select(&articles, query); err != nil {
 		return fmt.Errorf("failed to query obsolete articles: %w", err)
 	}
 
+	if jsonOutput {
+		return writeJSON(articles)
+	}
+
 	if len(articles) == 0 {
 		fmt.Println("No obsolete articles found.")
 		return nil
 	}
 
-	if jsonOutput {
-		encoder := json.NewEncoder(os.Stdout)
-		encoder.SetIndent("", "  ")
-		return encoder.Encode(articles)
-	}
-
 	fmt.Printf("Found %d obsolete articles:\n\n", len(articles))
 	for _, article := range articles {
 		statusStr := "unknown"
@@ -876,6 +4772,15 @@ func runListObsolete(cmd *cobra.Command, args []string) error {
 
 		fmt.Printf("ID: %d | Status: %s | Failures: %d\n", article.ID, statusStr, article.FailedCount)
 		fmt.Printf("Added: %s\n", article.InstapaperedAt)
+		if article.ObsoletedAt != nil {
+			fmt.Printf("Obsoleted: %s\n", *article.ObsoletedAt)
+		}
+		if article.Reason != nil {
+			fmt.Printf("Reason: %s\n", *article.Reason)
+		}
+		if article.Criteria != nil {
+			fmt.Printf("Criteria: %s\n", *article.Criteria)
+		}
 		fmt.Printf("URL: %s\n", article.URL)
 		fmt.Printf("Title: %s\n\n", article.Title)
 	}
@@ -883,6 +4788,44 @@ func runListObsolete(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// printObsoleteHistory prints an article's obsolete and restore actions, oldest first
+func printObsoleteHistory(id int64, jsonOutput bool) error {
+	type ObsoleteLogEntry struct {
+		Action    string  `db:"action" json:"action"`
+		Reason    *string `db:"reason" json:"reason,omitempty"`
+		Criteria  *string `db:"criteria" json:"criteria,omitempty"`
+		CreatedAt string  `db:"created_at" json:"created_at"`
+	}
+
+	entries := []ObsoleteLogEntry{}
+	if err := database.Select(&entries, "SELECT action, reason, criteria, created_at FROM obsolete_log WHERE article_id = ? ORDER BY id", id); err != nil {
+		return fmt.Errorf("failed to query obsolete history: %w", err)
+	}
+
+	if jsonOutput {
+		return writeJSON(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("No obsolete history for article %d.\n", id)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WHEN\tACTION\tREASON\tCRITERIA")
+	for _, entry := range entries {
+		reason, criteria := "", ""
+		if entry.Reason != nil {
+			reason = *entry.Reason
+		}
+		if entry.Criteria != nil {
+			criteria = *entry.Criteria
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.CreatedAt, entry.Action, reason, criteria)
+	}
+	return w.Flush()
+}
+
 func getStatusCodeName(code string) string {
 	switch code {
 	case "200":
@@ -920,8 +4863,21 @@ func getStatusCodeName(code string) string {
 	}
 }
 
+// FailureClassStats counts unfetched articles whose last failure had a given class
+type FailureClassStats struct {
+	Class    string `db:"class" json:"class"`
+	Articles int    `db:"articles" json:"articles"`
+	Retrying int    `db:"retrying" json:"retrying"`
+	GivenUp  int    `db:"given_up" json:"given_up"`
+}
+
 func runStats(cmd *cobra.Command, args []string) error {
-	jsonOutput, _ := cmd.Flags().GetBool("json")
+	jsonOutput := wantJSON(cmd)
+	by, _ := cmd.Flags().GetString("by")
+
+	if by != "" {
+		return runStatsBreakdown(by, jsonOutput)
+	}
 
 	// Define the stats structure
 	type DatabaseStats struct {
@@ -930,7 +4886,9 @@ func runStats(cmd *cobra.Command, args []string) error {
 		Fetched     int                    `json:"fetched"`
 		NotFetched  int                    `json:"not_fetched"`
 		Failures    map[string]int         `json:"failures_by_count"`
+		Classes     []FailureClassStats    `json:"failures_by_class"`
 		StatusCodes map[string]int         `json:"status_codes"`
+		Goals       []db.GoalProgress      `json:"goals,omitempty"`
 		Summary     map[string]interface{} `json:"summary,omitempty"`
 	}
 
@@ -982,6 +4940,19 @@ func runStats(cmd *cobra.Command, args []string) error {
 		stats.Failures[fmt.Sprintf("%d", f.FailedCount)] = f.Count
 	}
 
+	// Get failure statistics by class (unfetched, non-obsolete only)
+	if err := database.Select(&stats.Classes, `
+		SELECT failure_class as class, COUNT(*) as articles,
+			SUM(CASE WHEN next_retry_at IS NOT NULL THEN 1 ELSE 0 END) as retrying,
+			SUM(CASE WHEN next_retry_at IS NULL THEN 1 ELSE 0 END) as given_up
+		FROM articles
+		WHERE failure_class IS NOT NULL AND synced_at IS NULL AND obsolete = FALSE
+		GROUP BY failure_class
+		ORDER BY articles DESC, failure_class
+	`); err != nil {
+		return fmt.Errorf("failed to get failure class statistics: %w", err)
+	}
+
 	// Get status code statistics (failed, non-obsolete only)
 	statusQuery := `
 		SELECT status_code, COUNT(*) as count
@@ -1006,6 +4977,12 @@ func runStats(cmd *cobra.Command, args []string) error {
 		stats.StatusCodes[fmt.Sprintf("%d", s.StatusCode)] = s.Count
 	}
 
+	goals, err := database.GoalProgress(time.Now())
+	if err != nil {
+		return err
+	}
+	stats.Goals = goals
+
 	// Calculate summary percentages for human-readable output
 	if !jsonOutput {
 		stats.Summary = map[string]interface{}{
@@ -1022,24 +4999,25 @@ func runStats(cmd *cobra.Command, args []string) error {
 	}
 
 	// Human-readable output
-	fmt.Printf("Database Statistics\n")
+	p := term.NewPalette(colorOutput())
+	fmt.Printf("%s\n", p.Paint(term.Bold, "Database Statistics"))
 	fmt.Printf("==================\n\n")
 
-	fmt.Printf("Articles Overview:\n")
+	fmt.Printf("%s\n", p.Paint(term.Bold, "Articles Overview:"))
 	fmt.Printf("  Total Articles:     %d\n", stats.Total)
 	fmt.Printf("  Active Articles:    %d (%.1f%%)\n", stats.Total-stats.Obsolete,
 		float64(stats.Total-stats.Obsolete)/float64(stats.Total)*100)
 	fmt.Printf("  Obsolete Articles:  %d (%.1f%%)\n", stats.Obsolete,
 		float64(stats.Obsolete)/float64(stats.Total)*100)
 
-	fmt.Printf("\nFetch Status (Active Articles):\n")
+	fmt.Printf("\n%s\n", p.Paint(term.Bold, "Fetch Status (Active Articles):"))
 	fmt.Printf("  Successfully Fetched: %d (%.1f%%)\n", stats.Fetched,
 		float64(stats.Fetched)/float64(stats.Total-stats.Obsolete)*100)
 	fmt.Printf("  Not Yet Fetched:     %d (%.1f%%)\n", stats.NotFetched,
 		float64(stats.NotFetched)/float64(stats.Total-stats.Obsolete)*100)
 
 	if len(stats.Failures) > 0 {
-		fmt.Printf("\nFetch Failures (Active Articles):\n")
+		fmt.Printf("\n%s\n", p.Paint(term.Bold, "Fetch Failures (Active Articles):"))
 		totalFailed := 0
 		for failCount, count := range stats.Failures {
 			fmt.Printf("  %s failure(s): %d articles\n", failCount, count)
@@ -1048,11 +5026,18 @@ func runStats(cmd *cobra.Command, args []string) error {
 		fmt.Printf("  Total with failures: %d (%.1f%% of active)\n", totalFailed,
 			float64(totalFailed)/float64(stats.Total-stats.Obsolete)*100)
 	} else {
-		fmt.Printf("\nFetch Failures: None\n")
+		fmt.Printf("\n%s %s\n", p.Paint(term.Bold, "Fetch Failures:"), p.Paint(term.Green, "None"))
+	}
+
+	if len(stats.Classes) > 0 {
+		fmt.Printf("\n%s\n", p.Paint(term.Bold, "Failures by Class (Active Articles):"))
+		for _, class := range stats.Classes {
+			fmt.Printf("  %-12s %d articles (%d retrying, %d given up)\n", class.Class+":", class.Articles, class.Retrying, class.GivenUp)
+		}
 	}
 
 	if len(stats.StatusCodes) > 0 {
-		fmt.Printf("\nFailed HTTP Status Codes (Active Articles):\n")
+		fmt.Printf("\n%s\n", p.Paint(term.Bold, "Failed HTTP Status Codes (Active Articles):"))
 
 		// Sort status codes numerically
 		var sortedCodes []string
@@ -1073,24 +5058,125 @@ func runStats(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if len(stats.Goals) > 0 {
+		fmt.Printf("\n%s\n", p.Paint(term.Bold, "Reading Goals:"))
+		for _, goal := range stats.Goals {
+			fmt.Printf("  %s\n", goal.Summary())
+		}
+	}
+
 	// Health recommendations
-	fmt.Printf("\nHealth Summary:\n")
+	fmt.Printf("\n%s\n", p.Paint(term.Bold, "Health Summary:"))
 	if stats.Obsolete > 0 {
-		fmt.Printf("  📁 %d obsolete articles excluded from operations\n", stats.Obsolete)
+		fmt.Printf("  📁 %s\n", p.Paint(term.Dim, fmt.Sprintf("%d obsolete articles excluded from operations", stats.Obsolete)))
 	}
 	if stats.NotFetched > 0 {
-		fmt.Printf("  ⏳ %d articles ready for content fetching\n", stats.NotFetched)
+		fmt.Printf("  ⏳ %s\n", p.Paint(term.Yellow, fmt.Sprintf("%d articles ready for content fetching", stats.NotFetched)))
 	}
 
 	// Check for high failure articles that might need obsoleting
 	for failCount, count := range stats.Failures {
 		if failCount >= "4" {
-			fmt.Printf("  ⚠️  %d articles with %s+ failures (consider marking obsolete)\n", count, failCount)
+			fmt.Printf("  ⚠️  %s\n", p.Paint(term.Yellow, fmt.Sprintf("%d articles with %s+ failures (consider marking obsolete)", count, failCount)))
 		}
 	}
 
 	if len(stats.Failures) == 0 && stats.NotFetched == 0 {
-		fmt.Printf("  ✅ All active articles successfully fetched!\n")
+		fmt.Printf("  ✅ %s\n", p.Paint(term.Green, "All active articles successfully fetched!"))
+	}
+
+	return nil
+}
+
+// StatsGroup holds article counts for one group of a stats breakdown
+type StatsGroup struct {
+	Name           string  `db:"name" json:"name"`
+	Total          int     `db:"total" json:"total"`
+	Fetched        int     `db:"fetched" json:"fetched"`
+	FetchedPercent float64 `db:"-" json:"fetched_percent"`
+	Words          int     `db:"words" json:"words"`
+}
+
+// runStatsBreakdown prints active article counts, fetched percentage and word counts per group
+func runStatsBreakdown(by string, jsonOutput bool) error {
+	// Host part of the URL: everything after "://" up to the first "/"
+	hostExpr := `CASE WHEN instr(a.rest, '/') > 0 THEN substr(a.rest, 1, instr(a.rest, '/') - 1) ELSE a.rest END`
+
+	var groupExpr, joins string
+	switch by {
+	case "folder":
+		groupExpr = "COALESCE(f.path_cache, f.title, '(no folder)')"
+		joins = "LEFT JOIN folders f ON a.folder_id = f.id"
+	case "tag":
+		groupExpr = "COALESCE(t.title, '(untagged)')"
+		joins = "LEFT JOIN article_tags at ON a.id = at.article_id LEFT JOIN tags t ON at.tag_id = t.id"
+	case "year":
+		groupExpr = "COALESCE(substr(a.instapapered_at, 1, 4), '(unknown)')"
+	case "domain":
+		groupExpr = fmt.Sprintf("CASE WHEN %[1]s LIKE 'www.%%' THEN substr(%[1]s, 5) ELSE %[1]s END", hostExpr)
+	default:
+		return fmt.Errorf("invalid --by value: %s. Use folder, tag, year, or domain", by)
+	}
+
+	// Word counts are approximated by counting whitespace-separated runs of text
+	query := fmt.Sprintf(`
+		SELECT
+			%s as name,
+			COUNT(*) as total,
+			COALESCE(SUM(CASE WHEN a.synced_at IS NOT NULL THEN 1 ELSE 0 END), 0) as fetched,
+			COALESCE(SUM(CASE WHEN a.words_text IS NULL OR a.words_text = '' THEN 0
+				ELSE length(a.words_text) - length(replace(a.words_text, ' ', '')) + 1 END), 0) as words
+		FROM (
+			SELECT *,
+				substr(url, instr(url, '://') + 3) as rest,
+				trim(replace(replace(content_text(content_md), char(10), ' '), char(9), ' ')) as words_text
+			FROM articles
+			WHERE obsolete = FALSE
+		) a
+		%s
+		GROUP BY name
+		ORDER BY total DESC, name
+	`, groupExpr, joins)
+
+	var groups []StatsGroup
+	if err := database.Select(&groups, query); err != nil {
+		return fmt.Errorf("failed to get statistics by %s: %w", by, err)
+	}
+
+	for i := range groups {
+		if groups[i].Total > 0 {
+			groups[i].FetchedPercent = float64(groups[i].Fetched) / float64(groups[i].Total) * 100
+		}
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(map[string]interface{}{
+			"by":     by,
+			"groups": groups,
+		})
+	}
+
+	p := term.NewPalette(colorOutput())
+	fmt.Println(p.Paint(term.Bold, fmt.Sprintf("%-40s %8s %8s %8s %12s", strings.ToUpper(by), "TOTAL", "FETCHED", "FETCHED%", "WORDS")))
+	fmt.Println(strings.Repeat("-", 80))
+
+	for _, group := range groups {
+		name := group.Name
+		if len(name) > 40 {
+			name = name[:37] + "..."
+		}
+		// Fetched percentages are green when nearly all are fetched and red when most are not
+		percentStyle := term.Yellow
+		switch {
+		case group.FetchedPercent >= 90:
+			percentStyle = term.Green
+		case group.FetchedPercent < 50:
+			percentStyle = term.Red
+		}
+		percent := p.Paint(percentStyle, fmt.Sprintf("%7.1f%%", group.FetchedPercent))
+		fmt.Printf("%-40s %8d %8d %s %12d\n", name, group.Total, group.Fetched, percent, group.Words)
 	}
 
 	return nil
@@ -1100,6 +5186,12 @@ func runRSSAdd(cmd *cobra.Command, args []string) error {
 	url := args[0]
 	name, _ := cmd.Flags().GetString("name")
 	tagsStr, _ := cmd.Flags().GetString("tags")
+	useContent, _ := cmd.Flags().GetBool("use-content")
+	backfill, _ := cmd.Flags().GetString("backfill")
+
+	if err := rss.ValidateBackfill(backfill); err != nil {
+		return err
+	}
 
 	var tags []string
 	if tagsStr != "" {
@@ -1109,7 +5201,7 @@ func runRSSAdd(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	feedID, err := database.AddRSSFeed(url, name, tags)
+	feedID, err := database.AddRSSFeed(url, name, tags, useContent, backfill)
 	if err != nil {
 		return fmt.Errorf("failed to add RSS feed: %w", err)
 	}
@@ -1118,29 +5210,144 @@ func runRSSAdd(cmd *cobra.Command, args []string) error {
 	if len(tags) > 0 {
 		fmt.Printf("Tags: %s\n", strings.Join(tags, ", "))
 	}
+	if useContent {
+		fmt.Println("Items' embedded content is stored at sync time")
+	}
+	switch backfill {
+	case rss.BackfillAll:
+	case rss.BackfillNone:
+		fmt.Println("The first sync skips the feed's current items")
+	default:
+		fmt.Printf("The first sync skips items older than %s\n", backfill)
+	}
+
+	return nil
+}
+
+func runRSSDiscover(cmd *cobra.Command, args []string) error {
+	pageURL, _ := cmd.Flags().GetString("url")
+	minArticles, _ := cmd.Flags().GetInt("min-articles")
+	limit, _ := cmd.Flags().GetInt("limit")
+	maxTags, _ := cmd.Flags().GetInt("tags")
+	subscribe, _ := cmd.Flags().GetBool("subscribe")
+	interactive, _ := cmd.Flags().GetBool("interactive")
+	jsonOutput := wantJSON(cmd)
+
+	if subscribe && interactive {
+		return fmt.Errorf("use either --subscribe or --interactive")
+	}
+	if interactive && jsonOutput {
+		return fmt.Errorf("--interactive cannot be combined with JSON output")
+	}
+
+	candidates, err := rss.Discover(cmd.Context(), database, rss.DiscoverOptions{
+		URL:         pageURL,
+		MinArticles: minArticles,
+		Limit:       limit,
+		MaxTags:     maxTags,
+	})
+	if err != nil {
+		return err
+	}
+
+	type discoverReport struct {
+		rss.FeedCandidate
+		FeedID int64 `json:"feed_id,omitempty"`
+	}
+	reports := []discoverReport{}
+	stdin := bufio.NewReader(os.Stdin)
+	found := 0
+
+	if len(candidates) == 0 && !jsonOutput {
+		fmt.Printf("No domains with at least %d saved articles.\n", minArticles)
+	}
+
+	for _, candidate := range candidates {
+		report := discoverReport{FeedCandidate: candidate}
+		reports = append(reports, report)
+		r := &reports[len(reports)-1]
+
+		if !jsonOutput {
+			fmt.Printf("%s (%d articles): ", candidate.Domain, candidate.Articles)
+			switch {
+			case candidate.Subscribed:
+				fmt.Printf("already subscribed to %s\n", candidate.FeedURL)
+			case candidate.Error != "":
+				fmt.Println(candidate.Error)
+			default:
+				fmt.Printf("%s", candidate.FeedURL)
+				if candidate.Title != "" {
+					fmt.Printf(" (%s)", candidate.Title)
+				}
+				if len(candidate.Tags) > 0 {
+					fmt.Printf(", tags: %s", strings.Join(candidate.Tags, ", "))
+				}
+				fmt.Println()
+			}
+		}
+		if candidate.Subscribed || candidate.Error != "" {
+			continue
+		}
+		found++
+
+		accept := subscribe
+		if interactive {
+			fmt.Print("  subscribe? [y/N/q] ")
+			answer, _ := stdin.ReadString('\n')
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "y", "yes":
+				accept = true
+			case "q", "quit":
+				return nil
+			}
+		}
+		if !accept {
+			continue
+		}
+
+		name := candidate.Title
+		if name == "" {
+			name = candidate.Domain
+		}
+		feedID, err := database.AddRSSFeed(candidate.FeedURL, name, candidate.Tags, false, rss.BackfillAll)
+		if err != nil {
+			return fmt.Errorf("failed to add RSS feed: %w", err)
+		}
+		r.FeedID = feedID
+		if !jsonOutput {
+			fmt.Printf("  Added RSS feed #%d: %s\n", feedID, name)
+		}
+	}
 
+	if jsonOutput {
+		return writeJSON(reports)
+	}
+	if !subscribe && !interactive && found > 0 {
+		fmt.Println("\nRun with --subscribe to add every feed found, or --interactive to choose.")
+	}
 	return nil
 }
 
 func runRSSList(cmd *cobra.Command, args []string) error {
-	jsonOutput, _ := cmd.Flags().GetBool("json")
+	jsonOutput := wantJSON(cmd)
 
 	feeds, err := database.GetRSSFeeds()
 	if err != nil {
 		return fmt.Errorf("failed to get RSS feeds: %w", err)
 	}
 
+	if jsonOutput {
+		if feeds == nil {
+			feeds = []map[string]interface{}{}
+		}
+		return writeJSON(feeds)
+	}
+
 	if len(feeds) == 0 {
 		fmt.Println("No RSS feeds configured.")
 		return nil
 	}
 
-	if jsonOutput {
-		encoder := json.NewEncoder(os.Stdout)
-		encoder.SetIndent("", "  ")
-		return encoder.Encode(feeds)
-	}
-
 	fmt.Printf("%-5s %-30s %-50s %-20s %s\n", "ID", "NAME", "URL", "LAST SYNCED", "TAGS")
 	fmt.Println(strings.Repeat("-", 130))
 
@@ -1196,7 +5403,29 @@ func runRSSUpdate(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	if err := database.UpdateRSSFeed(id, namePtr, tags); err != nil {
+	var useContent *bool
+	if cmd.Flags().Changed("use-content") {
+		value, _ := cmd.Flags().GetBool("use-content")
+		useContent = &value
+	}
+
+	var backfill *string
+	if cmd.Flags().Changed("backfill") {
+		value, _ := cmd.Flags().GetString("backfill")
+		if err := rss.ValidateBackfill(value); err != nil {
+			return err
+		}
+		feed, _, err := database.GetRSSFeed(id)
+		if err != nil {
+			return err
+		}
+		if _, synced := feed["last_synced_at"]; synced {
+			return fmt.Errorf("feed #%d has been synced already; --backfill only applies to a feed's first sync", id)
+		}
+		backfill = &value
+	}
+
+	if err := database.UpdateRSSFeed(id, namePtr, tags, useContent, backfill); err != nil {
 		return fmt.Errorf("failed to update RSS feed: %w", err)
 	}
 
@@ -1205,14 +5434,22 @@ func runRSSUpdate(cmd *cobra.Command, args []string) error {
 }
 
 func runRSSSync(cmd *cobra.Command, args []string) error {
+	noPrettify, _ := cmd.Flags().GetBool("no-prettify")
+	_, err := syncRSSFeeds(fetcher.FetchOptions{NoPrettify: noPrettify})
+	return err
+}
+
+// syncRSSFeeds syncs all active RSS feeds and returns the number of new articles. storeOpts apply
+// to the content of use-content feeds.
+func syncRSSFeeds(storeOpts fetcher.FetchOptions) (int, error) {
 	feeds, err := database.GetRSSFeeds()
 	if err != nil {
-		return fmt.Errorf("failed to get RSS feeds: %w", err)
+		return 0, fmt.Errorf("failed to get RSS feeds: %w", err)
 	}
 
 	if len(feeds) == 0 {
 		fmt.Println("No RSS feeds configured. Use 'rss:add' to add a feed.")
-		return nil
+		return 0, nil
 	}
 
 	totalNew := 0
@@ -1225,21 +5462,15 @@ func runRSSSync(cmd *cobra.Command, args []string) error {
 
 		// Get feed details with tags
 		feedID := feedData["id"].(int64)
-		_, tags, err := database.GetRSSFeed(feedID)
+		feed, tags, err := rss.LoadFeed(database, feedID)
 		if err != nil {
 			fmt.Printf("Error getting feed #%d: %v\n", feedID, err)
 			continue
 		}
 
-		feed := &model.RSSFeed{
-			ID:   feedID,
-			URL:  feedData["url"].(string),
-			Name: feedData["name"].(string),
-		}
-
 		fmt.Printf("Syncing: %s...\n", feed.Name)
 
-		newArticles, err := rss.SyncFeed(database, feed, tags)
+		newArticles, err := rss.SyncFeed(database, feed, tags, tagRules, storeOpts)
 		if err != nil {
 			fmt.Printf("  Error: %v\n", err)
 			continue
@@ -1250,12 +5481,337 @@ func runRSSSync(cmd *cobra.Command, args []string) error {
 	}
 
 	fmt.Printf("\nSync complete. Total new articles: %d\n", totalNew)
+	return totalNew, nil
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	addr, _ := cmd.Flags().GetString("addr")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	fetchLimit, _ := cmd.Flags().GetInt("fetch-limit")
+
+	ctx := cmd.Context()
+
+	schedule, err := fetchSchedule(cmd)
+	if err != nil {
+		return err
+	}
+
+	fetchOpts := &fetcher.FetchOptions{Order: "newest", Limit: fetchLimit, DomainCap: schedule.DomainCap}
+	if schedule.Limit > 0 {
+		startSchedule(ctx, *schedule)
+		// The window does all fetching; the interval only syncs RSS feeds
+		fetchOpts = nil
+	}
+
+	if interval > 0 {
+		go runServeLoop(ctx, interval, fetchOpts)
+	}
+
+	return serveMetrics(ctx, addr)
+}
+
+// fetchSchedule reads the nightly fetch window from the daemon block of the config file; the
+// command's --fetch-window, --window-limit, and --domain-daily-cap flags override it. Without a
+// window the schedule's Limit is 0 and only its DomainCap applies.
+func fetchSchedule(cmd *cobra.Command) (*fetcher.Schedule, error) {
+	daemon := userConfig.Daemon
+	if cmd.Flags().Changed("fetch-window") {
+		daemon.FetchWindow, _ = cmd.Flags().GetString("fetch-window")
+	}
+	if cmd.Flags().Changed("window-limit") {
+		daemon.WindowLimit, _ = cmd.Flags().GetInt("window-limit")
+		if daemon.WindowLimit <= 0 {
+			return nil, fmt.Errorf("--window-limit must be above 0")
+		}
+	}
+	if cmd.Flags().Changed("domain-daily-cap") {
+		daemon.DomainDailyCap, _ = cmd.Flags().GetInt("domain-daily-cap")
+	}
+
+	schedule := &fetcher.Schedule{DomainCap: daemon.DomainDailyCap, LockWait: lockWait, Fetch: fetcher.FetchOptions{Order: "newest"}}
+	if daemon.FetchWindow == "" {
+		return schedule, nil
+	}
+
+	start, end, err := fetcher.ParseWindow(daemon.FetchWindow)
+	if err != nil {
+		return nil, err
+	}
+	schedule.Start, schedule.End, schedule.Limit = start, end, daemon.WindowLimit
+	if schedule.Limit == 0 {
+		schedule.Limit = 100
+	}
+	return schedule, nil
+}
+
+// startSchedule fetches articles in the schedule's window every night until ctx is cancelled
+func startSchedule(ctx context.Context, schedule fetcher.Schedule) {
+	go func() {
+		if err := fetcher.New(database).Run(ctx, schedule); err != nil {
+			log.Printf("Scheduled fetching stopped: %v", err)
+		}
+	}()
+}
+
+// serveMetrics serves /metrics on addr until ctx is cancelled
+func serveMetrics(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler(database))
+
+	srv := &http.Server{
+		Addr:        addr,
+		Handler:     mux,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Serving metrics on %s/metrics\n", addr)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// applyObsoletePolicies applies the --obsolete-policies file in a long-running command, logging
+// what it marked obsolete. The caller holds the write lock.
+func applyObsoletePolicies(ctx context.Context) {
+	if obsoletePolicies == nil || ctx.Err() != nil {
+		return
+	}
+
+	report, err := obsoletePolicies.Apply(ctx, database, false)
+	if err != nil {
+		log.Printf("Obsolete policies failed: %v", err)
+	}
+	for _, match := range report.Policies {
+		for _, article := range match.Articles {
+			log.Printf("Obsolete policy %s marked article %d obsolete: %s", match.Name, article.ID, article.URL)
+		}
+	}
+	if report.Marked > 0 {
+		log.Printf("Obsolete policies marked %d articles obsolete (undo with: instapaper-cli undo --operation-id %d)", report.Marked, report.OperationID)
+	}
+}
+
+// runServeLoop periodically syncs RSS feeds and fetches new article content until ctx is cancelled.
+// A nil fetchOpts only syncs feeds.
+func runServeLoop(ctx context.Context, interval time.Duration, fetchOpts *fetcher.FetchOptions) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Hold the write lock for one sync and fetch at a time, so other commands can run in between
+		if lock, err := database.LockWrites(ctx, "serve", lockWait); err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Skipping RSS sync and fetch: %v", err)
+			}
+		} else {
+			if _, err := syncRSSFeeds(fetcher.FetchOptions{}); err != nil {
+				log.Printf("RSS sync failed: %v", err)
+			}
+
+			if fetchOpts != nil {
+				f := fetcher.New(database)
+				if _, err := f.FetchArticles(ctx, *fetchOpts); err != nil && ctx.Err() == nil {
+					log.Printf("Fetch failed: %v", err)
+				}
+			}
+			applyObsoletePolicies(ctx)
+			lock.Release()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func runIngestEmail(cmd *cobra.Command, args []string) error {
+	server, _ := cmd.Flags().GetString("server")
+	user, _ := cmd.Flags().GetString("user")
+	passwordEnv, _ := cmd.Flags().GetString("password-env")
+	mailbox, _ := cmd.Flags().GetString("mailbox")
+	moveTo, _ := cmd.Flags().GetString("move-to")
+	all, _ := cmd.Flags().GetBool("all")
+	limit, _ := cmd.Flags().GetInt("limit")
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+	folderPrefix, _ := cmd.Flags().GetString("folder-prefix")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	noPrettify, _ := cmd.Flags().GetBool("no-prettify")
+
+	opts := ingest.EmailOptions{Tags: tags, FolderPrefix: folderPrefix, DryRun: dryRun, NoPrettify: noPrettify}
+
+	var result *ingest.EmailResult
+	var err error
+	switch {
+	case len(args) > 0 && server != "":
+		return fmt.Errorf("give either .eml files or --server")
+	case len(args) > 0:
+		result, err = ingest.IngestFiles(cmd.Context(), database, args, opts)
+	case server == "" || user == "":
+		return fmt.Errorf("--server and --user are required to read from an IMAP mailbox")
+	default:
+		password := os.Getenv(passwordEnv)
+		if password == "" {
+			return fmt.Errorf("set the IMAP password in the %s environment variable", passwordEnv)
+		}
+		result, err = ingest.IngestIMAP(cmd.Context(), database, ingest.IMAPOptions{
+			Server:   server,
+			Username: user,
+			Password: password,
+			Mailbox:  mailbox,
+			MoveTo:   moveTo,
+			All:      all,
+			Limit:    limit,
+		}, opts)
+	}
+	if result == nil {
+		return err
+	}
+
+	if wantJSON(cmd) {
+		if err := writeJSON(result); err != nil {
+			return err
+		}
+	} else {
+		verb := "Saved"
+		if dryRun {
+			verb = "Would save"
+		}
+		for _, item := range result.Ingested {
+			if item.ID != 0 {
+				fmt.Printf("%s %d: %s (%s, in %s)\n", verb, item.ID, item.Title, item.URL, item.Folder)
+			} else {
+				fmt.Printf("%s: %s (%s, in %s)\n", verb, item.Title, item.URL, item.Folder)
+			}
+		}
+		for _, itemErr := range result.Errors {
+			if itemErr.URL != "" {
+				fmt.Printf("Failed %s: %s\n", itemErr.URL, itemErr.Error)
+			} else {
+				fmt.Printf("Failed message %d: %s\n", itemErr.ID, itemErr.Error)
+			}
+		}
+		fmt.Printf("%d messages, %d saved, %d already saved, %d failed\n", result.Messages, len(result.Ingested), result.Duplicates, len(result.Errors))
+	}
+
+	if err != nil {
+		return fmt.Errorf("ingest interrupted: %w", err)
+	}
 	return nil
 }
 
+func runBot(cmd *cobra.Command, args []string) error {
+	configPath, _ := cmd.Flags().GetString("config")
+
+	cfg, err := bot.LoadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	if len(cfg.AllowedChats) == 0 {
+		fmt.Println("No allowed_chats configured: the bot replies to each chat with its ID until you add it")
+	}
+
+	fmt.Println("Bot running, press Ctrl+C to stop")
+	return bot.New(database, bot.Options{Config: cfg, Rules: tagRules, LockWait: lockWait}).Run(cmd.Context())
+}
+
+func runDaemon(cmd *cobra.Command, args []string) error {
+	watchDir, _ := cmd.Flags().GetString("watch-dir")
+	processedDir, _ := cmd.Flags().GetString("processed-dir")
+	failedDir, _ := cmd.Flags().GetString("failed-dir")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	splitFolders, _ := cmd.Flags().GetBool("split-folders")
+	fetchLimit, _ := cmd.Flags().GetInt("fetch-limit")
+	trashRetention, _ := cmd.Flags().GetString("trash-retention")
+	metricsAddr, _ := cmd.Flags().GetString("metrics-addr")
+
+	if _, err := trashCutoff(trashRetention); err != nil {
+		return fmt.Errorf("invalid --trash-retention value %q: use an age like 30d, 12w, or 6m", trashRetention)
+	}
+
+	importOpts := importer.ImportOptions{SplitFolders: splitFolders, Rules: tagRules}
+	if err := applyTaxonomyFlags(cmd, &importOpts); err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+
+	schedule, err := fetchSchedule(cmd)
+	if err != nil {
+		return err
+	}
+	if schedule.Limit > 0 {
+		startSchedule(ctx, *schedule)
+		// The window does all fetching, so imports leave their articles to it
+		fetchLimit = 0
+	}
+
+	opts := importer.WatchOptions{
+		Dir:          watchDir,
+		ProcessedDir: processedDir,
+		FailedDir:    failedDir,
+		Interval:     interval,
+		LockWait:     lockWait,
+		Import:       importOpts,
+		OnImport: func(path string, result *importer.ImportResult, err error) {
+			if err != nil {
+				fmt.Printf("Import failed, moved to %s: %v\n", path, err)
+				return
+			}
+			fmt.Printf("Imported %d of %d records (%s), moved to %s\n", result.Processed, result.Total, result.Format, path)
+
+			if fetchLimit > 0 {
+				f := fetcher.New(database)
+				if _, err := f.FetchArticles(ctx, fetcher.FetchOptions{Order: "newest", Limit: fetchLimit, DomainCap: schedule.DomainCap}); err != nil && ctx.Err() == nil {
+					log.Printf("Fetch failed: %v", err)
+				}
+			}
+			applyObsoletePolicies(ctx)
+		},
+	}
+
+	if trashRetention != "" {
+		var emptiedAt time.Time
+		opts.OnScan = func() {
+			if time.Since(emptiedAt) >= time.Hour {
+				emptiedAt = time.Now()
+				emptyTrash(ctx, trashRetention)
+			}
+		}
+	}
+
+	if metricsAddr != "" {
+		go func() {
+			if err := serveMetrics(ctx, metricsAddr); err != nil {
+				log.Printf("Serving metrics failed: %v", err)
+			}
+		}()
+	}
+
+	fmt.Printf("Watching %s for Instapaper and Pocket CSV exports\n", watchDir)
+	return importer.New(database).Watch(ctx, opts)
+}
+
+// optionalString renders a nullable column, empty for NULL
+func optionalString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
 	}
-	return s[:maxLen-3] + "..."
+	return util.TruncateUTF8(s, maxLen-3) + "..."
 }
\ No newline at end of file
@@ -0,0 +1,220 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"instapaper-cli/internal/db"
+	"instapaper-cli/internal/fetcher"
+	"instapaper-cli/internal/importer"
+	"instapaper-cli/internal/search"
+	"instapaper-cli/internal/tagging"
+)
+
+// retryDelay is how long the bot waits after Telegram could not be reached
+const retryDelay = 10 * time.Second
+
+const helpText = `Send me a link to save it, or ask for articles you saved:
+
+kubernetes
+find my kubernetes articles from last month
+rust posts this week
+go generics in 2024
+
+/latest lists what you saved most recently.`
+
+// Options wires the bot to the database
+type Options struct {
+	Config *Config
+	// Rules tag and file saved articles, as for add
+	Rules *tagging.Rules
+	// LockWait is how long a save waits for another writer to finish
+	LockWait time.Duration
+}
+
+// Bot answers chat messages: links are saved as articles and other text is searched for
+type Bot struct {
+	db       *db.DB
+	opts     Options
+	telegram *telegram
+	logger   *log.Logger
+}
+
+// New returns a bot for the database
+func New(database *db.DB, opts Options) *Bot {
+	return &Bot{
+		db:       database,
+		opts:     opts,
+		telegram: newTelegram(opts.Config.Telegram),
+		logger:   log.New(os.Stderr, "", log.LstdFlags),
+	}
+}
+
+// Run answers messages until ctx is cancelled. Messages that arrived while the bot was not
+// running are answered when it starts.
+func (b *Bot) Run(ctx context.Context) error {
+	var offset int64
+	for {
+		updates, err := b.telegram.updates(ctx, offset)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			b.logger.Printf("Failed to get messages, retrying in %s: %v", retryDelay, err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(retryDelay):
+			}
+			continue
+		}
+
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if u.Message == nil {
+				continue
+			}
+			text := u.Message.Text
+			if text == "" {
+				text = u.Message.Caption
+			}
+
+			reply := b.Reply(ctx, u.Message.Chat.ID, text)
+			if err := b.telegram.send(ctx, u.Message.Chat.ID, reply); err != nil && ctx.Err() == nil {
+				b.logger.Printf("Failed to reply to chat %d: %v", u.Message.Chat.ID, err)
+			}
+		}
+	}
+}
+
+// Reply answers a message from a chat
+func (b *Bot) Reply(ctx context.Context, chatID int64, text string) string {
+	text = strings.TrimSpace(text)
+	if !b.opts.Config.allows(chatID) {
+		b.logger.Printf("Ignoring message from chat %d, which is not in allowed_chats", chatID)
+		return fmt.Sprintf("This chat is not allowed to use the bot. Add its ID, %d, to allowed_chats in the bot config.", chatID)
+	}
+
+	command := strings.ToLower(strings.Fields(text + " ")[0])
+	// In groups, commands carry the bot's name, e.g. /latest@my_bot
+	command, _, _ = strings.Cut(command, "@")
+	switch {
+	case text == "" || command == "/start" || command == "/help":
+		return helpText
+	case command == "/latest":
+		return b.search(ctx, Query{}, "Latest saved")
+	}
+
+	if urls := importer.ExtractURLs(text); len(urls) > 0 {
+		return b.save(ctx, urls)
+	}
+
+	query := ParseQuery(text, time.Now())
+	if query.Terms == "" && query.Since == "" {
+		return helpText
+	}
+	return b.search(ctx, query, "")
+}
+
+// save adds links as articles, fetching them when the config asks to
+func (b *Bot) save(ctx context.Context, urls []string) string {
+	lock, err := b.db.LockWrites(ctx, "bot", b.opts.LockWait)
+	if err != nil {
+		return fmt.Sprintf("Could not save: %v", err)
+	}
+	defer lock.Release()
+
+	result, err := importer.New(b.db).AddURLs(ctx, urls, importer.AddOptions{
+		Folder: b.opts.Config.Folder,
+		Tags:   b.opts.Config.Tags,
+		Rules:  b.opts.Rules,
+	})
+	if result == nil {
+		return fmt.Sprintf("Could not save: %v", err)
+	}
+
+	var ids []int64
+	for _, added := range result.Added {
+		ids = append(ids, added.ID)
+	}
+	if b.opts.Config.Fetch && len(ids) > 0 {
+		f := fetcher.New(b.db)
+		if _, err := f.FetchArticles(ctx, fetcher.FetchOptions{Selector: search.Selector{IDs: ids}}); err != nil {
+			b.logger.Printf("Failed to fetch saved articles: %v", err)
+		}
+	}
+
+	var lines []string
+	for _, added := range result.Added {
+		line := "Saved: " + added.URL
+		// The title is still the URL unless the article was fetched
+		var title string
+		if err := b.db.GetContext(ctx, &title, "SELECT title FROM articles WHERE id = ?", added.ID); err == nil && title != added.URL {
+			line = "Saved: " + title + "\n" + added.URL
+		}
+		lines = append(lines, line)
+	}
+	for _, duplicate := range result.Duplicates {
+		lines = append(lines, "Already saved: "+duplicate)
+	}
+	for _, invalid := range result.Invalid {
+		lines = append(lines, "Not a valid link: "+invalid)
+	}
+	if err != nil {
+		lines = append(lines, fmt.Sprintf("Stopped early: %v", err))
+	}
+	return strings.Join(lines, "\n\n")
+}
+
+// search answers a query with the best matching articles, or the latest ones for an empty query
+func (b *Bot) search(ctx context.Context, query Query, heading string) string {
+	results, err := search.New(b.db).Find(ctx, search.SearchOptions{
+		Query:  query.Terms,
+		UseFTS: true,
+		Fuzzy:  true,
+		Limit:  b.opts.Config.Results,
+		Since:  query.Since,
+		Until:  query.Until,
+	})
+	if err != nil {
+		return fmt.Sprintf("Search failed: %v", err)
+	}
+
+	if heading == "" {
+		heading = describeQuery(query)
+	}
+	if len(results) == 0 {
+		return "No articles found: " + heading
+	}
+
+	lines := []string{heading + ":"}
+	for i, result := range results {
+		if result.Title == "" || result.Title == result.URL {
+			lines = append(lines, fmt.Sprintf("%d. %s", i+1, result.URL))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%d. %s\n%s", i+1, result.Title, result.URL))
+	}
+	return strings.Join(lines, "\n\n")
+}
+
+// describeQuery restates what a query searched for, so a misread date shows in the reply
+func describeQuery(query Query) string {
+	description := "all articles"
+	if query.Terms != "" {
+		description = fmt.Sprintf("%q", query.Terms)
+	}
+	switch {
+	case query.Since != "" && query.Until == query.Since:
+		description += " saved on " + query.Since
+	case query.Since != "" && query.Until != "":
+		description += " saved " + query.Since + " to " + query.Until
+	case query.Since != "":
+		description += " saved since " + query.Since
+	}
+	return description
+}
@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"instapaper-cli/internal/db"
+	"instapaper-cli/migrations"
+)
+
+// TestReplyYesterday searches articles saved yesterday, stored in RFC 3339 as imports store them,
+// through both the LIKE path (no terms) and the FTS path
+func TestReplyYesterday(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "bot.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	if err := database.RunMigrationsFS(migrations.FS); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1).Format("2006-01-02")
+	articles := []struct {
+		id    int64
+		url   string
+		title string
+		saved string
+	}{
+		{1, "https://example.com/yesterday", "Kubernetes operators", yesterday + "T23:30:00Z"},
+		{2, "https://example.com/earlier", "Kubernetes networking", now.AddDate(0, 0, -3).Format("2006-01-02") + "T09:00:00Z"},
+	}
+	for _, article := range articles {
+		if _, err := database.Exec(`
+			INSERT INTO articles (id, url, title, instapapered_at) VALUES (?, ?, ?, ?)
+		`, article.id, article.url, article.title, article.saved); err != nil {
+			t.Fatal(err)
+		}
+		if err := database.UpsertArticleFTS(article.id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b := New(database, Options{Config: &Config{AllowedChats: []int64{1}, Results: defaultResults}})
+	for _, message := range []string{"yesterday", "kubernetes articles from yesterday"} {
+		reply := b.Reply(context.Background(), 1, message)
+		if !strings.Contains(reply, "https://example.com/yesterday") {
+			t.Errorf("Reply(%q) = %q, want the article saved yesterday", message, reply)
+		}
+		if strings.Contains(reply, "https://example.com/earlier") {
+			t.Errorf("Reply(%q) = %q, want only the article saved yesterday", message, reply)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Package bot saves links and answers searches sent to a chat bot, for capture from a phone
+package bot
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Defaults for settings the config file leaves out
+const (
+	defaultAPIURL   = "https://api.telegram.org"
+	defaultTokenEnv = "TELEGRAM_BOT_TOKEN"
+	defaultResults  = 5
+)
+
+// Config is the bot's YAML config file
+type Config struct {
+	Telegram TelegramConfig `yaml:"telegram"`
+	// AllowedChats are the chat IDs the bot answers; messages from other chats only get their ID
+	// back, so it can be added here
+	AllowedChats []int64 `yaml:"allowed_chats"`
+	// Folder and Tags file and tag saved articles, as for add
+	Folder string   `yaml:"folder"`
+	Tags   []string `yaml:"tags"`
+	// Fetch downloads a saved article's content at once, so the reply shows its title
+	Fetch bool `yaml:"fetch"`
+	// Results is the most search results in a reply
+	Results int `yaml:"results"`
+}
+
+// TelegramConfig connects the bot to Telegram
+type TelegramConfig struct {
+	// Token is the bot token from @BotFather; prefer TokenEnv to keep it out of the file
+	Token    string `yaml:"token"`
+	TokenEnv string `yaml:"token_env"`
+	// APIURL is the Bot API server, for a self-hosted one
+	APIURL string `yaml:"api_url"`
+}
+
+// LoadConfig reads and validates the bot's config file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bot config: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse bot config: %w", err)
+	}
+
+	if cfg.Telegram.TokenEnv == "" {
+		cfg.Telegram.TokenEnv = defaultTokenEnv
+	}
+	if cfg.Telegram.Token == "" {
+		cfg.Telegram.Token = os.Getenv(cfg.Telegram.TokenEnv)
+	}
+	if cfg.Telegram.Token == "" {
+		return nil, fmt.Errorf("bot config has no telegram token: set telegram.token or the %s environment variable", cfg.Telegram.TokenEnv)
+	}
+	if cfg.Telegram.APIURL == "" {
+		cfg.Telegram.APIURL = defaultAPIURL
+	}
+	if cfg.Results <= 0 {
+		cfg.Results = defaultResults
+	}
+	return &cfg, nil
+}
+
+// allows reports whether the bot answers a chat
+func (c *Config) allows(chatID int64) bool {
+	for _, id := range c.AllowedChats {
+		if id == chatID {
+			return true
+		}
+	}
+	return false
+}
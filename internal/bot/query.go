@@ -0,0 +1,101 @@
+package bot
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query is a search asked for in plain words, like "find my kubernetes articles from last month"
+type Query struct {
+	Terms string
+	// Since and Until bound the saved date as YYYY-MM-DD, empty when unbounded
+	Since string
+	Until string
+}
+
+var (
+	// lastNPattern matches "last 3 days" or "past 2 weeks"
+	lastNPattern = regexp.MustCompile(`(?i)\b(?:(?:from|in|over|during)\s+)?(?:the\s+)?(?:last|past)\s+(\d+)\s+(day|week|month|year)s?\b`)
+	// periodPattern matches "last month" or "this week"
+	periodPattern = regexp.MustCompile(`(?i)\b(?:(?:from|in|during)\s+)?(last|this)\s+(week|month|year)\b`)
+	// dayPattern matches "today" or "yesterday"
+	dayPattern = regexp.MustCompile(`(?i)\b(?:(?:from|since)\s+)?(today|yesterday)\b`)
+	// yearPattern matches "in 2024" or "from 2023"
+	yearPattern = regexp.MustCompile(`(?i)\b(?:from|in|during)\s+((?:19|20)\d\d)\b`)
+)
+
+// fillerWords are the words of a request that are not what it searches for
+var fillerWords = map[string]bool{
+	"find": true, "search": true, "show": true, "list": true, "get": true, "give": true, "me": true,
+	"my": true, "all": true, "any": true, "some": true, "the": true, "a": true, "an": true,
+	"article": true, "articles": true, "post": true, "posts": true, "link": true, "links": true,
+	"about": true, "on": true, "regarding": true, "saved": true, "i": true, "did": true, "what": true,
+	"for": true, "please": true, "stuff": true, "things": true,
+}
+
+// ParseQuery turns a chat message into search terms and a saved-date range. Dates are read from
+// phrases like "today", "last month", "this week", "last 3 days", and "in 2024".
+func ParseQuery(text string, now time.Time) Query {
+	var q Query
+	day := func(t time.Time) string { return t.Format("2006-01-02") }
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	if m := lastNPattern.FindStringSubmatch(text); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		switch strings.ToLower(m[2]) {
+		case "day":
+			q.Since = day(today.AddDate(0, 0, -n))
+		case "week":
+			q.Since = day(today.AddDate(0, 0, -7*n))
+		case "month":
+			q.Since = day(today.AddDate(0, -n, 0))
+		case "year":
+			q.Since = day(today.AddDate(-n, 0, 0))
+		}
+		text = lastNPattern.ReplaceAllString(text, " ")
+	} else if m := periodPattern.FindStringSubmatch(text); m != nil {
+		// Periods are calendar weeks, starting on Monday, months, and years
+		var start time.Time
+		var years, months, days int
+		switch strings.ToLower(m[2]) {
+		case "week":
+			start = today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+			days = 7
+		case "month":
+			start = time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+			months = 1
+		case "year":
+			start = time.Date(today.Year(), 1, 1, 0, 0, 0, 0, today.Location())
+			years = 1
+		}
+		if strings.EqualFold(m[1], "last") {
+			q.Since, q.Until = day(start.AddDate(-years, -months, -days)), day(start.AddDate(0, 0, -1))
+		} else {
+			q.Since = day(start)
+		}
+		text = periodPattern.ReplaceAllString(text, " ")
+	} else if m := dayPattern.FindStringSubmatch(text); m != nil {
+		if strings.EqualFold(m[1], "yesterday") {
+			q.Since = day(today.AddDate(0, 0, -1))
+			q.Until = q.Since
+		} else {
+			q.Since = day(today)
+		}
+		text = dayPattern.ReplaceAllString(text, " ")
+	} else if m := yearPattern.FindStringSubmatch(text); m != nil {
+		q.Since, q.Until = m[1]+"-01-01", m[1]+"-12-31"
+		text = yearPattern.ReplaceAllString(text, " ")
+	}
+
+	var terms []string
+	for _, word := range strings.Fields(text) {
+		word = strings.Trim(word, ".,;:!?")
+		if word != "" && !fillerWords[strings.ToLower(word)] {
+			terms = append(terms, word)
+		}
+	}
+	q.Terms = strings.Join(terms, " ")
+	return q
+}
@@ -0,0 +1,105 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// pollTimeout is how long a getUpdates call waits for a message before returning none
+const pollTimeout = 50 * time.Second
+
+// telegram calls the Telegram Bot API
+type telegram struct {
+	baseURL string
+	client  *http.Client
+}
+
+// update is an incoming Telegram event; only messages are asked for
+type update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *message `json:"message"`
+}
+
+type message struct {
+	Chat struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	Text string `json:"text"`
+	// Caption is the text of a shared photo or file, which may hold a link
+	Caption string `json:"caption"`
+}
+
+func newTelegram(cfg TelegramConfig) *telegram {
+	return &telegram{
+		baseURL: strings.TrimSuffix(cfg.APIURL, "/") + "/bot" + cfg.Token,
+		client:  &http.Client{Timeout: pollTimeout + 10*time.Second},
+	}
+}
+
+// call posts a Bot API method and decodes its result into out
+func (t *telegram) call(ctx context.Context, method string, params, out interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		// The URL holds the token, so the error must not quote it
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("telegram %s failed: %s", method, strings.ReplaceAll(err.Error(), t.baseURL, "<api>"))
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("failed to decode telegram %s response (status %d): %w", method, resp.StatusCode, err)
+	}
+	if !envelope.OK {
+		return fmt.Errorf("telegram %s failed: %s", method, envelope.Description)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Result, out); err != nil {
+		return fmt.Errorf("failed to decode telegram %s result: %w", method, err)
+	}
+	return nil
+}
+
+// updates waits for messages after offset, the last update ID seen plus one
+func (t *telegram) updates(ctx context.Context, offset int64) ([]update, error) {
+	var updates []update
+	err := t.call(ctx, "getUpdates", map[string]interface{}{
+		"offset":          offset,
+		"timeout":         int(pollTimeout / time.Second),
+		"allowed_updates": []string{"message"},
+	}, &updates)
+	return updates, err
+}
+
+// send posts a plain text message to a chat
+func (t *telegram) send(ctx context.Context, chatID int64, text string) error {
+	return t.call(ctx, "sendMessage", map[string]interface{}{
+		"chat_id":                  chatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	}, nil)
+}
@@ -0,0 +1,130 @@
+// Package config reads the user's config file, which holds settings for every command
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PathEnv is the environment variable that overrides the config file's location
+const PathEnv = "INSTAPAPER_CLI_CONFIG"
+
+// Config is the user's YAML config file
+type Config struct {
+	// Aliases map a name to the command line it runs, e.g. s: search --fts --limit 20
+	Aliases map[string]string `yaml:"aliases"`
+
+	// Daemon throttles the fetching of the daemon and serve commands
+	Daemon Daemon `yaml:"daemon"`
+}
+
+// Daemon is the daemon block: fetching proceeds only in a nightly window, so a large backlog
+// drains slowly without noticeable load
+type Daemon struct {
+	// FetchWindow is the daily window fetches are spread across, local time, e.g. 01:00-06:00
+	FetchWindow string `yaml:"fetch_window"`
+	// WindowLimit is the most articles fetched per window, 0 for the default of 100
+	WindowLimit int `yaml:"window_limit"`
+	// DomainDailyCap is the most fetches per domain per day, 0 for no cap
+	DomainDailyCap int `yaml:"domain_daily_cap"`
+}
+
+// Path returns $INSTAPAPER_CLI_CONFIG, else instapaper-cli/config.yaml in the user config
+// directory, or "" when there is none
+func Path() string {
+	if path := os.Getenv(PathEnv); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "instapaper-cli", "config.yaml")
+}
+
+// Load reads the config file. An empty path or a missing file is an empty config.
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	for name, command := range cfg.Aliases {
+		if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("config %s: invalid alias name %q", path, name)
+		}
+		if words, err := splitWords(command); err != nil || len(words) == 0 {
+			return nil, fmt.Errorf("config %s: alias %q has no valid command line", path, name)
+		}
+	}
+	if cfg.Daemon.WindowLimit < 0 || cfg.Daemon.DomainDailyCap < 0 {
+		return nil, fmt.Errorf("config %s: daemon window_limit and domain_daily_cap cannot be negative", path)
+	}
+	return cfg, nil
+}
+
+// Alias returns the words of an alias's command line, or false when there is no such alias
+func (c *Config) Alias(name string) ([]string, bool) {
+	command, ok := c.Aliases[name]
+	if !ok {
+		return nil, false
+	}
+	words, _ := splitWords(command)
+	return words, true
+}
+
+// splitWords splits a command line at whitespace; single or double quotes keep a word whole
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Alias kinds
+const (
+	AliasTag    = "tag"
+	AliasFolder = "folder"
+)
+
+// Alias is an alternative name that resolves to a tag title or folder path
+type Alias struct {
+	ID        int64  `db:"id" json:"id"`
+	Kind      string `db:"kind" json:"kind"`
+	Alias     string `db:"alias" json:"alias"`
+	Target    string `db:"target" json:"target"`
+	CreatedAt string `db:"created_at" json:"created_at"`
+}
+
+// AddAlias makes alias resolve to target. An existing tag or folder with the alias's name has
+// to be merged into the target first, since it could never be reached again.
+func (db *DB) AddAlias(kind, alias, target string) error {
+	alias, target = strings.TrimSpace(alias), strings.TrimSpace(target)
+	if kind == AliasFolder {
+		target = strings.Join(SplitFolderPath(target), "/")
+	}
+
+	switch {
+	case kind != AliasTag && kind != AliasFolder:
+		return fmt.Errorf("invalid alias kind %q: use %s or %s", kind, AliasTag, AliasFolder)
+	case alias == "" || target == "":
+		return fmt.Errorf("alias and target are required")
+	case strings.EqualFold(alias, target):
+		return fmt.Errorf("alias %q would point at itself", alias)
+	}
+
+	if _, aliased, err := db.ResolveAlias(kind, target); err != nil {
+		return err
+	} else if aliased {
+		return fmt.Errorf("%q is itself an alias; point %q at its target instead", target, alias)
+	}
+
+	var exists bool
+	var err error
+	if kind == AliasTag {
+		err = db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM tags WHERE title = ? COLLATE NOCASE)", alias)
+	} else {
+		err = db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM folders WHERE COALESCE(path_cache, title) = ? COLLATE NOCASE)", alias)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up %s: %w", kind, err)
+	}
+	if exists && kind == AliasTag {
+		return fmt.Errorf("tag %q exists; merge it first with: tags --action rename --old %q --new %q", alias, alias, target)
+	} else if exists {
+		return fmt.Errorf("folder %q exists; move its articles to %q first", alias, target)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO aliases (kind, alias, target, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(kind, alias) DO UPDATE SET target = excluded.target
+	`, kind, alias, target, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to add alias: %w", err)
+	}
+	return nil
+}
+
+// RemoveAlias deletes an alias; the name then refers to a tag or folder of its own again
+func (db *DB) RemoveAlias(kind, alias string) error {
+	result, err := db.Exec("DELETE FROM aliases WHERE kind = ? AND alias = ?", kind, alias)
+	if err != nil {
+		return fmt.Errorf("failed to remove alias: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("%s alias %q not found", kind, alias)
+	}
+	return nil
+}
+
+// Aliases lists the aliases of a kind by target, then alias
+func (db *DB) Aliases(kind string) ([]Alias, error) {
+	aliases := []Alias{}
+	if err := db.Select(&aliases, "SELECT * FROM aliases WHERE kind = ? ORDER BY target, alias", kind); err != nil {
+		return nil, fmt.Errorf("failed to get aliases: %w", err)
+	}
+	return aliases, nil
+}
+
+// retargetAliases points the aliases of a renamed or merged tag or folder at its new name
+func (db *DB) retargetAliases(kind, old, new string) error {
+	if _, err := db.Exec("UPDATE aliases SET target = ? WHERE kind = ? AND target = ?", new, kind, old); err != nil {
+		return fmt.Errorf("failed to update aliases: %w", err)
+	}
+	return nil
+}
+
+// ResolveAlias returns the target of name when it is an alias (matched case-insensitively),
+// or name itself, and whether it was an alias
+func (db *DB) ResolveAlias(kind, name string) (string, bool, error) {
+	var target string
+	err := db.Get(&target, "SELECT target FROM aliases WHERE kind = ? AND alias = ?", kind, strings.TrimSpace(name))
+	if err == sql.ErrNoRows {
+		return name, false, nil
+	} else if err != nil {
+		return name, false, fmt.Errorf("failed to resolve %s alias: %w", kind, err)
+	}
+	return target, true, nil
+}
+
+// ResolveAliases resolves each name with ResolveAlias, dropping duplicates
+func (db *DB) ResolveAliases(kind string, names []string) ([]string, error) {
+	if len(names) == 0 {
+		return names, nil
+	}
+
+	resolved := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		target, _, err := db.ResolveAlias(kind, name)
+		if err != nil {
+			return nil, err
+		}
+		if key := strings.ToLower(target); !seen[key] {
+			seen[key] = true
+			resolved = append(resolved, target)
+		}
+	}
+	return resolved, nil
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Attachment is an original file kept for an article, such as a PDF or slide deck
+type Attachment struct {
+	ID          int64   `db:"id" json:"id"`
+	ArticleID   int64   `db:"article_id" json:"article_id"`
+	Hash        string  `db:"hash" json:"hash"`
+	Filename    string  `db:"filename" json:"filename"`
+	ContentType string  `db:"content_type" json:"content_type"`
+	Size        int64   `db:"size" json:"size"`
+	SourceURL   *string `db:"source_url" json:"source_url,omitempty"`
+	CreatedAt   string  `db:"created_at" json:"created_at"`
+}
+
+// SetAttachmentsDir changes where attachment files are stored (default: "attachments" next to the database file)
+func (db *DB) SetAttachmentsDir(dir string) {
+	db.attachmentsDir = dir
+}
+
+// AttachmentPath returns where an attachment's file is stored: named by its content hash, keeping
+// the extension so the file opens in the right application
+func (db *DB) AttachmentPath(attachment Attachment) string {
+	return db.attachmentPath(attachment.Hash, attachment.Filename)
+}
+
+func (db *DB) attachmentPath(hash, filename string) string {
+	return filepath.Join(db.attachmentsDir, hash[:2], hash+strings.ToLower(filepath.Ext(filename)))
+}
+
+// StoreAttachment writes data to the content-addressed store and records it against the article.
+// Storing the same file for the same article again returns the existing attachment.
+func (db *DB) StoreAttachment(articleID int64, filename, contentType string, sourceURL *string, data []byte) (*Attachment, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	path := db.attachmentPath(hash, filename)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create attachments directory: %w", err)
+		}
+
+		// Write to a temporary file first so an interrupted write never leaves a truncated file under the hash
+		tmp, err := os.CreateTemp(filepath.Dir(path), hash+".*.tmp")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create attachment file: %w", err)
+		}
+		if _, err := tmp.Write(data); err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return nil, fmt.Errorf("failed to write attachment file: %w", err)
+		}
+		if err := tmp.Close(); err != nil {
+			os.Remove(tmp.Name())
+			return nil, fmt.Errorf("failed to write attachment file: %w", err)
+		}
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			os.Remove(tmp.Name())
+			return nil, fmt.Errorf("failed to store attachment file: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to check attachment file: %w", err)
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO attachments (article_id, hash, filename, content_type, size, source_url)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(article_id, hash) DO UPDATE SET filename = excluded.filename, content_type = excluded.content_type
+	`, articleID, hash, filename, contentType, len(data), sourceURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record attachment: %w", err)
+	}
+
+	var attachment Attachment
+	if err := db.Get(&attachment, "SELECT * FROM attachments WHERE article_id = ? AND hash = ?", articleID, hash); err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	return &attachment, nil
+}
+
+// ArticleAttachments returns the attachments of an article in the order they were stored
+func (db *DB) ArticleAttachments(articleID int64) ([]Attachment, error) {
+	var attachments []Attachment
+	if err := db.Select(&attachments, "SELECT * FROM attachments WHERE article_id = ? ORDER BY id", articleID); err != nil {
+		return nil, fmt.Errorf("failed to get attachments: %w", err)
+	}
+	return attachments, nil
+}
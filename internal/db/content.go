@@ -0,0 +1,186 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log"
+
+	"github.com/klauspost/compress/zstd"
+	"modernc.org/sqlite"
+)
+
+// SettingContentCompression is "on" when new article content is stored compressed
+const SettingContentCompression = "content_compression"
+
+// zstdMagic starts every zstd frame. It can never start markdown text, because 0xB5 is not a
+// valid UTF-8 lead byte, so compressed and plain content can share the content_md column.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedBetterCompression))
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+func init() {
+	// content_text(content_md) returns the markdown whether it is stored compressed or not, so
+	// queries read and search content without knowing how it is stored
+	sqlite.MustRegisterDeterministicScalarFunction("content_text", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		switch value := args[0].(type) {
+		case []byte:
+			text, err := DecodeContent(value)
+			if err != nil {
+				return nil, err
+			}
+			return text, nil
+		default:
+			return value, nil
+		}
+	})
+}
+
+// DecodeContent returns stored content as markdown, decompressing it if needed
+func DecodeContent(stored []byte) (string, error) {
+	if !bytes.HasPrefix(stored, zstdMagic) {
+		return string(stored), nil
+	}
+
+	text, err := zstdDecoder.DecodeAll(stored, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress content: %w", err)
+	}
+	return string(text), nil
+}
+
+// EncodeContent returns markdown in the form it should be stored: compressed when content
+// compression is on for this database, and as text otherwise
+func (db *DB) EncodeContent(markdown string) (interface{}, error) {
+	on, err := db.ContentCompression()
+	if err != nil {
+		return nil, err
+	}
+	if !on {
+		return markdown, nil
+	}
+	return zstdEncoder.EncodeAll([]byte(markdown), nil), nil
+}
+
+// ContentCompression reports whether new article content is stored compressed
+func (db *DB) ContentCompression() (bool, error) {
+	var value string
+	err := db.Get(&value, "SELECT value FROM settings WHERE key = ?", SettingContentCompression)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read content compression setting: %w", err)
+	}
+	return value == "on", nil
+}
+
+// ContentCompressionReport summarizes a CompressContent or DecompressContent run
+type ContentCompressionReport struct {
+	Articles    int   `json:"articles"`
+	BytesBefore int64 `json:"bytes_before"`
+	BytesAfter  int64 `json:"bytes_after"`
+}
+
+// CompressContent turns content compression on and compresses the content of every article stored
+// as text. Run Optimize (VACUUM) afterwards to return the freed pages to the file system.
+func (db *DB) CompressContent(ctx context.Context) (ContentCompressionReport, error) {
+	if err := db.setContentCompression(true); err != nil {
+		return ContentCompressionReport{}, err
+	}
+	return db.recodeContent(ctx, "typeof(content_md) = 'text'", func(stored []byte) ([]byte, error) {
+		return zstdEncoder.EncodeAll(stored, nil), nil
+	})
+}
+
+// DecompressContent turns content compression off and stores all compressed content as text again
+func (db *DB) DecompressContent(ctx context.Context) (ContentCompressionReport, error) {
+	if err := db.setContentCompression(false); err != nil {
+		return ContentCompressionReport{}, err
+	}
+	return db.recodeContent(ctx, "typeof(content_md) = 'blob'", func(stored []byte) ([]byte, error) {
+		text, err := DecodeContent(stored)
+		return []byte(text), err
+	})
+}
+
+func (db *DB) setContentCompression(on bool) error {
+	value := "off"
+	if on {
+		value = "on"
+	}
+	_, err := db.Exec(`
+		INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`, SettingContentCompression, value)
+	if err != nil {
+		return fmt.Errorf("failed to save content compression setting: %w", err)
+	}
+	return nil
+}
+
+// recodeContent rewrites the content of matching articles in batches, each batch in its own transaction
+func (db *DB) recodeContent(ctx context.Context, condition string, recode func([]byte) ([]byte, error)) (ContentCompressionReport, error) {
+	const batchSize = 200
+	var report ContentCompressionReport
+	lastID := int64(0)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		var rows []struct {
+			ID      int64  `db:"id"`
+			Content []byte `db:"content_md"`
+		}
+		query := "SELECT id, content_md FROM articles WHERE id > ? AND " + condition + " ORDER BY id LIMIT ?"
+		if err := db.SelectContext(ctx, &rows, query, lastID, batchSize); err != nil {
+			return report, fmt.Errorf("failed to read content: %w", err)
+		}
+		if len(rows) == 0 {
+			return report, nil
+		}
+
+		tx, err := db.BeginTxx(ctx, nil)
+		if err != nil {
+			return report, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+
+		for _, row := range rows {
+			recoded, err := recode(row.Content)
+			if err != nil {
+				tx.Rollback()
+				return report, fmt.Errorf("article %d: %w", row.ID, err)
+			}
+
+			// Compressed content is stored as a BLOB and text as TEXT, so typeof() tells them apart
+			var value interface{} = recoded
+			if !bytes.HasPrefix(recoded, zstdMagic) {
+				value = string(recoded)
+			}
+			if _, err := tx.Exec("UPDATE articles SET content_md = ? WHERE id = ?", value, row.ID); err != nil {
+				tx.Rollback()
+				return report, fmt.Errorf("failed to update article %d: %w", row.ID, err)
+			}
+
+			report.Articles++
+			report.BytesBefore += int64(len(row.Content))
+			report.BytesAfter += int64(len(recoded))
+			lastID = row.ID
+		}
+
+		if err := tx.Commit(); err != nil {
+			return report, fmt.Errorf("failed to commit: %w", err)
+		}
+
+		if report.Articles%1000 < batchSize {
+			log.Printf("Recoded content of %d articles...", report.Articles)
+		}
+	}
+}
@@ -1,14 +1,21 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"io/fs"
+	"log"
 	"os"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"instapaper-cli/internal/language"
+	"instapaper-cli/internal/util"
 
 	"github.com/jmoiron/sqlx"
 	_ "modernc.org/sqlite"
@@ -16,6 +23,17 @@ import (
 
 type DB struct {
 	*sqlx.DB
+	queryLog       *log.Logger
+	attachmentsDir string
+
+	// Set on the DB that InTx passes to its function, see InTx
+	tx *sqlx.Tx
+
+	// The single-writer lock, see LockWrites
+	lockPath  string
+	lockMu    sync.Mutex
+	lockFile  *os.File
+	lockDepth int
 }
 
 func New(dbPath string) (*DB, error) {
@@ -33,19 +51,45 @@ func New(dbPath string) (*DB, error) {
 		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
 	}
 
-	return &DB{DB: db}, nil
+	database := &DB{DB: db, attachmentsDir: filepath.Join(filepath.Dir(dbPath), "attachments")}
+	// In-memory and URI databases have no file to put a lock next to
+	if dbPath != ":memory:" && !strings.HasPrefix(dbPath, "file:") {
+		database.lockPath = dbPath + ".lock"
+	}
+	return database, nil
 }
 
+// RunMigrations applies pending migrations read from the migrationsDir directory
 func (db *DB) RunMigrations(migrationsDir string) error {
+	if info, err := os.Stat(migrationsDir); err != nil {
+		return fmt.Errorf("failed to read migrations directory %s: %w", migrationsDir, err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("migrations directory %s is not a directory", migrationsDir)
+	}
+	return db.RunMigrationsFS(os.DirFS(migrationsDir))
+}
+
+// RunMigrationsFS applies pending migrations read from fsys, e.g. an embedded filesystem. A database
+// migrated by a newer release is refused with a *SchemaTooNewError.
+func (db *DB) RunMigrationsFS(fsys fs.FS) error {
 	if err := db.createMigrationsTable(); err != nil {
 		return fmt.Errorf("failed to create migrations table: %w", err)
 	}
 
-	migrations, err := getMigrationFiles(migrationsDir)
+	migrations, err := getMigrationFiles(fsys)
 	if err != nil {
 		return fmt.Errorf("failed to get migration files: %w", err)
 	}
 
+	// A newer release's schema may be misread or damaged, so it is refused before anything is applied
+	info, err := db.schemaInfo(migrations)
+	if err != nil {
+		return err
+	}
+	if !info.Compatible {
+		return &SchemaTooNewError{Version: info.Version, Supported: info.Supported}
+	}
+
 	appliedMigrations, err := db.getAppliedMigrations()
 	if err != nil {
 		return fmt.Errorf("failed to get applied migrations: %w", err)
@@ -53,7 +97,7 @@ func (db *DB) RunMigrations(migrationsDir string) error {
 
 	for _, migration := range migrations {
 		if _, applied := appliedMigrations[migration.name]; !applied {
-			if err := db.applyMigration(migration); err != nil {
+			if err := db.applyMigration(fsys, migration); err != nil {
 				return fmt.Errorf("failed to apply migration %s: %w", migration.name, err)
 			}
 		}
@@ -68,6 +112,91 @@ type migration struct {
 	path    string
 }
 
+// migrationHooks finish the migrations whose data changes need Go code. A hook runs after the SQL
+// of the migration it is named after, in the same transaction.
+var migrationHooks = map[string]func(tx *sql.Tx) error{
+	"0032_split_highlights":      splitSelectionHighlights,
+	"0033_canonical_url_aliases": canonicalizeURLAliases,
+}
+
+// splitSelectionHighlights gives articles with a selection but no highlights the highlights
+// util.ParseHighlights splits from it
+func splitSelectionHighlights(tx *sql.Tx) error {
+	rows, err := tx.Query(`
+		SELECT id, selection FROM articles a
+		WHERE TRIM(COALESCE(selection, '')) != ''
+		  AND NOT EXISTS (SELECT 1 FROM highlights h WHERE h.article_id = a.id)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to find selections: %w", err)
+	}
+
+	selections := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var selection string
+		if err := rows.Scan(&id, &selection); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read selection: %w", err)
+		}
+		selections[id] = selection
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read selections: %w", err)
+	}
+
+	for id, selection := range selections {
+		for position, text := range util.ParseHighlights(selection) {
+			if _, err := tx.Exec("INSERT INTO highlights (article_id, position, text) VALUES (?, ?, ?)", id, position, text); err != nil {
+				return fmt.Errorf("failed to insert highlight of article %d: %w", id, err)
+			}
+		}
+	}
+	return nil
+}
+
+// canonicalizeURLAliases rewrites each URL alias in the canonical form lookups use. An alias whose
+// canonical URL is an article's URL or another alias is dropped.
+func canonicalizeURLAliases(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT url, article_id, kind, created_at FROM url_aliases")
+	if err != nil {
+		return fmt.Errorf("failed to get URL aliases: %w", err)
+	}
+
+	var aliases []URLAlias
+	for rows.Next() {
+		var alias URLAlias
+		if err := rows.Scan(&alias.URL, &alias.ArticleID, &alias.Kind, &alias.CreatedAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read URL alias: %w", err)
+		}
+		aliases = append(aliases, alias)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read URL aliases: %w", err)
+	}
+
+	for _, alias := range aliases {
+		canonical, err := util.CanonicalizeURL(alias.URL)
+		if err != nil || canonical == alias.URL {
+			continue
+		}
+		if _, err := tx.Exec("DELETE FROM url_aliases WHERE url = ?", alias.URL); err != nil {
+			return fmt.Errorf("failed to replace URL alias %s: %w", alias.URL, err)
+		}
+		if _, err := tx.Exec(`
+			INSERT OR IGNORE INTO url_aliases (url, article_id, kind, created_at)
+			SELECT ?, ?, ?, ?
+			WHERE NOT EXISTS (SELECT 1 FROM articles WHERE url = ?)
+		`, canonical, alias.ArticleID, alias.Kind, alias.CreatedAt, canonical); err != nil {
+			return fmt.Errorf("failed to replace URL alias %s: %w", alias.URL, err)
+		}
+	}
+	return nil
+}
+
 func (db *DB) createMigrationsTable() error {
 	query := `
 		CREATE TABLE IF NOT EXISTS migrations (
@@ -80,10 +209,10 @@ func (db *DB) createMigrationsTable() error {
 	return err
 }
 
-func getMigrationFiles(dir string) ([]migration, error) {
+func getMigrationFiles(fsys fs.FS) ([]migration, error) {
 	var migrations []migration
 
-	err := filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+	err := fs.WalkDir(fsys, ".", func(path string, info fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -142,8 +271,8 @@ func (db *DB) getAppliedMigrations() (map[string]bool, error) {
 	return applied, rows.Err()
 }
 
-func (db *DB) applyMigration(m migration) error {
-	content, err := os.ReadFile(m.path)
+func (db *DB) applyMigration(fsys fs.FS, m migration) error {
+	content, err := fs.ReadFile(fsys, m.path)
 	if err != nil {
 		return fmt.Errorf("failed to read migration file: %w", err)
 	}
@@ -166,6 +295,12 @@ func (db *DB) applyMigration(m migration) error {
 		}
 	}
 
+	if hook := migrationHooks[m.name]; hook != nil {
+		if err := hook(tx); err != nil {
+			return err
+		}
+	}
+
 	if _, err := tx.Exec("INSERT INTO migrations (version, name) VALUES (?, ?)", m.version, m.name); err != nil {
 		return fmt.Errorf("failed to record migration: %w", err)
 	}
@@ -177,10 +312,11 @@ func (db *DB) Close() error {
 	return db.DB.Close()
 }
 
-func (db *DB) UpsertFolder(title string, parentID *int) (int64, error) {
+// UpsertFolder returns the folder with title under parentID (nil for top level), creating it if needed
+func (db *DB) UpsertFolder(title string, parentID *int64) (int64, error) {
 	var folderID int64
 
-	err := db.Get(&folderID, "SELECT id FROM folders WHERE title = ?", title)
+	err := db.Get(&folderID, "SELECT id FROM folders WHERE title = ? AND parent_id IS ?", title, parentID)
 	if err == sql.ErrNoRows {
 		result, err := db.Exec("INSERT INTO folders (title, parent_id) VALUES (?, ?)", title, parentID)
 		if err != nil {
@@ -194,10 +330,68 @@ func (db *DB) UpsertFolder(title string, parentID *int) (int64, error) {
 	return folderID, nil
 }
 
+// UpsertFolderPath creates each folder along a slash-separated path like "Tech/Go/Generics"
+// and returns the innermost folder's ID. Call UpdateFolderPaths afterwards to refresh path_cache.
+func (db *DB) UpsertFolderPath(path string) (int64, error) {
+	path, _, err := db.ResolveAlias(AliasFolder, strings.Join(SplitFolderPath(path), "/"))
+	if err != nil {
+		return 0, err
+	}
+
+	var parentID *int64
+	for _, title := range SplitFolderPath(path) {
+		id, err := db.UpsertFolder(title, parentID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to upsert folder %q: %w", title, err)
+		}
+		parentID = &id
+	}
+
+	if parentID == nil {
+		return 0, fmt.Errorf("empty folder path %q", path)
+	}
+	return *parentID, nil
+}
+
+// FindFolder returns the ID of the folder at a slash-separated path like "Tech/Go" or at the path
+// an alias stands for
+func (db *DB) FindFolder(path string) (int64, error) {
+	path, _, err := db.ResolveAlias(AliasFolder, strings.Join(SplitFolderPath(path), "/"))
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = db.Get(&id, "SELECT id FROM folders WHERE COALESCE(path_cache, title) = ?", path)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("folder '%s' not found", path)
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to find folder: %w", err)
+	}
+	return id, nil
+}
+
+// SplitFolderPath splits a slash-separated folder path into trimmed, non-empty titles
+func SplitFolderPath(path string) []string {
+	var titles []string
+	for _, title := range strings.Split(path, "/") {
+		if title = strings.TrimSpace(title); title != "" {
+			titles = append(titles, title)
+		}
+	}
+	return titles
+}
+
 func (db *DB) UpsertTag(title string) (int64, error) {
 	var tagID int64
 
-	err := db.Get(&tagID, "SELECT id FROM tags WHERE title = ?", title)
+	// An alias links to its target tag instead of creating a tag of its own
+	title, _, err := db.ResolveAlias(AliasTag, title)
+	if err != nil {
+		return 0, err
+	}
+
+	err = db.Get(&tagID, "SELECT id FROM tags WHERE title = ?", title)
 	if err == sql.ErrNoRows {
 		result, err := db.Exec("INSERT INTO tags (title) VALUES (?)", title)
 		if err != nil {
@@ -211,6 +405,36 @@ func (db *DB) UpsertTag(title string) (int64, error) {
 	return tagID, nil
 }
 
+// AddArticleTags links tags to an article, creating missing tags, and refreshes its FTS entry
+func (db *DB) AddArticleTags(articleID int64, tags []string) error {
+	for _, title := range tags {
+		tagID, err := db.UpsertTag(title)
+		if err != nil {
+			return fmt.Errorf("failed to upsert tag %q: %w", title, err)
+		}
+
+		if _, err := db.Exec("INSERT OR IGNORE INTO article_tags (article_id, tag_id) VALUES (?, ?)", articleID, tagID); err != nil {
+			return fmt.Errorf("failed to link article to tag: %w", err)
+		}
+	}
+
+	return db.UpsertArticleFTS(articleID)
+}
+
+// RemoveArticleTags unlinks tags from an article and refreshes its FTS entry
+func (db *DB) RemoveArticleTags(articleID int64, tags []string) error {
+	for _, title := range tags {
+		if _, err := db.Exec(`
+			DELETE FROM article_tags
+			WHERE article_id = ? AND tag_id IN (SELECT id FROM tags WHERE title = ?)
+		`, articleID, title); err != nil {
+			return fmt.Errorf("failed to unlink tag %q: %w", title, err)
+		}
+	}
+
+	return db.UpsertArticleFTS(articleID)
+}
+
 func (db *DB) UpdateFolderPaths() error {
 	folders := []struct {
 		ID       int64  `db:"id"`
@@ -260,7 +484,7 @@ func (db *DB) UpsertArticleFTS(articleID int64) error {
 	// Get article data including tags and folder
 	query := `
 		SELECT
-			a.id, a.url, a.title, a.content_md,
+			a.id, a.url, a.title, content_text(a.content_md) AS content_md, a.selection, a.obsolete,
 			f.path_cache as folder_path,
 			GROUP_CONCAT(t.title, ', ') as tags
 		FROM articles a
@@ -276,6 +500,8 @@ func (db *DB) UpsertArticleFTS(articleID int64) error {
 		URL        string  `db:"url"`
 		Title      string  `db:"title"`
 		ContentMD  *string `db:"content_md"`
+		Selection  *string `db:"selection"`
+		Obsolete   bool    `db:"obsolete"`
 		FolderPath *string `db:"folder_path"`
 		Tags       *string `db:"tags"`
 	}
@@ -284,6 +510,11 @@ func (db *DB) UpsertArticleFTS(articleID int64) error {
 		return fmt.Errorf("failed to get article data: %w", err)
 	}
 
+	// Obsolete articles are never searchable
+	if article.Obsolete {
+		return db.DeleteArticleFTS(articleID)
+	}
+
 	// Prepare FTS values
 	content := ""
 	if article.ContentMD != nil {
@@ -300,11 +531,17 @@ func (db *DB) UpsertArticleFTS(articleID int64) error {
 		tags = *article.Tags
 	}
 
-	// Insert or replace in FTS table
+	selection := ""
+	if article.Selection != nil {
+		selection = *article.Selection
+	}
+
+	// Insert or replace in FTS table, with words of scripts written without spaces split into
+	// terms; FTSQuery splits query words the same way
 	_, err := db.Exec(`
-		INSERT OR REPLACE INTO articles_fts (rowid, url, title, content, folder, tags)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, articleID, article.URL, article.Title, content, folder, tags)
+		INSERT OR REPLACE INTO articles_fts (rowid, url, title, content, folder, tags, selection)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, articleID, article.URL, language.Segment(article.Title), language.Segment(content), language.Segment(folder), language.Segment(tags), language.Segment(selection))
 
 	if err != nil {
 		return fmt.Errorf("failed to update FTS table: %w", err)
@@ -322,8 +559,9 @@ func (db *DB) DeleteArticleFTS(articleID int64) error {
 	return nil
 }
 
-// RebuildFTS rebuilds the entire FTS table from scratch
-func (db *DB) RebuildFTS() error {
+// RebuildFTS rebuilds the entire FTS table from scratch. If ctx is cancelled the
+// index is left partially rebuilt and RebuildFTS can simply be run again.
+func (db *DB) RebuildFTS(ctx context.Context) error {
 	// For contentless FTS tables, we need to drop and recreate instead of DELETE
 	// First, drop the existing FTS table
 	if _, err := db.Exec("DROP TABLE IF EXISTS articles_fts"); err != nil {
@@ -332,42 +570,506 @@ func (db *DB) RebuildFTS() error {
 
 	// Recreate the FTS table
 	if _, err := db.Exec(`CREATE VIRTUAL TABLE articles_fts USING fts5(
-		url, title, content, folder, tags, content=''
+		url, title, content, folder, tags, selection, content='', contentless_delete=1, prefix='2 3 4'
 	)`); err != nil {
 		return fmt.Errorf("failed to recreate FTS table: %w", err)
 	}
 
 	// Get all article IDs
 	var articleIDs []int64
-	if err := db.Select(&articleIDs, "SELECT id FROM articles WHERE obsolete = FALSE ORDER BY id"); err != nil {
+	if err := db.SelectContext(ctx, &articleIDs, "SELECT id FROM articles WHERE obsolete = FALSE ORDER BY id"); err != nil {
 		return fmt.Errorf("failed to get article IDs: %w", err)
 	}
 
-	fmt.Printf("Rebuilding FTS for %d articles...\n", len(articleIDs))
+	log.Printf("Rebuilding FTS for %d articles...", len(articleIDs))
 
 	// Rebuild FTS entries for all articles
 	for i, articleID := range articleIDs {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("FTS rebuild interrupted after %d/%d articles: %w", i, len(articleIDs), err)
+		}
+
 		if err := db.UpsertArticleFTS(articleID); err != nil {
 			return fmt.Errorf("failed to rebuild FTS for article %d: %w", articleID, err)
 		}
 
 		// Print progress every 1000 articles
 		if (i+1)%1000 == 0 {
-			fmt.Printf("Rebuilt FTS for %d/%d articles...\n", i+1, len(articleIDs))
+			log.Printf("Rebuilt FTS for %d/%d articles...", i+1, len(articleIDs))
+		}
+	}
+
+	log.Printf("Successfully rebuilt FTS for %d articles.", len(articleIDs))
+	return nil
+}
+
+// ReplaceHighlights replaces all highlights of an article, keeping the given order
+func (db *DB) ReplaceHighlights(articleID int64, highlights []string) error {
+	if _, err := db.Exec("DELETE FROM highlights WHERE article_id = ?", articleID); err != nil {
+		return fmt.Errorf("failed to delete highlights: %w", err)
+	}
+
+	for position, text := range highlights {
+		_, err := db.Exec(`
+			INSERT INTO highlights (article_id, position, text)
+			VALUES (?, ?, ?)
+		`, articleID, position, text)
+		if err != nil {
+			return fmt.Errorf("failed to insert highlight: %w", err)
 		}
 	}
 
-	fmt.Printf("Successfully rebuilt FTS for %d articles.\n", len(articleIDs))
 	return nil
 }
 
-// AddRSSFeed adds a new RSS feed with optional tags
-func (db *DB) AddRSSFeed(url, name string, tags []string) (int64, error) {
+// GetHighlights returns the highlights of an article in order
+func (db *DB) GetHighlights(articleID int64) ([]string, error) {
+	var highlights []string
+	err := db.Select(&highlights, "SELECT text FROM highlights WHERE article_id = ? ORDER BY position", articleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get highlights: %w", err)
+	}
+
+	return highlights, nil
+}
+
+// ObsoleteAudit explains an obsolete or restore action in the obsolete_log audit trail
+type ObsoleteAudit struct {
+	Reason   string // why, in the user's words
+	Criteria string // what selected the articles, e.g. "--status-codes 404 --min-failures 3"
+	// OperationID journals each change under this operation so it can be undone, 0 for none
+	OperationID int64
+}
+
+// Obsolete log actions
+const (
+	ObsoleteActionObsolete = "obsolete"
+	ObsoleteActionRestore  = "restore"
+)
+
+// SetArticlesObsolete marks articles obsolete (or restores them), keeps the FTS index in sync,
+// and records each changed article in the obsolete_log audit trail
+func (db *DB) SetArticlesObsolete(ids []int64, obsolete bool, audit ObsoleteAudit) (int64, error) {
+	var updated int64
+
+	action := ObsoleteActionObsolete
+	if !obsolete {
+		action = ObsoleteActionRestore
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	for _, id := range ids {
+		// Trashed articles stay hidden until they are restored from the trash
+		result, err := db.Exec(`
+			UPDATE articles SET obsolete = ?
+			WHERE id = ? AND obsolete != ? AND id NOT IN (SELECT article_id FROM trash)
+		`, obsolete, id, obsolete)
+		if err != nil {
+			return updated, fmt.Errorf("failed to update article %d: %w", id, err)
+		}
+
+		rows, _ := result.RowsAffected()
+		if rows == 0 {
+			continue
+		}
+		updated += rows
+
+		_, err = db.Exec(`
+			INSERT INTO obsolete_log (article_id, action, reason, criteria, created_at)
+			VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), ?)
+		`, id, action, audit.Reason, audit.Criteria, now)
+		if err != nil {
+			return updated, fmt.Errorf("failed to log %s of article %d: %w", action, id, err)
+		}
+
+		before, after := "1", "0"
+		if obsolete {
+			before, after = after, before
+		}
+		if err := db.journal(audit.OperationID, &id, ChangeObsolete, &before, &after); err != nil {
+			return updated, err
+		}
+
+		if obsolete {
+			err = db.DeleteArticleFTS(id)
+		} else {
+			err = db.UpsertArticleFTS(id)
+		}
+		if err != nil {
+			return updated, fmt.Errorf("failed to update FTS for article %d: %w", id, err)
+		}
+	}
+
+	return updated, nil
+}
+
+// SetArticlesFolder moves articles into a folder (nil for none) and refreshes their FTS entries,
+// journaling each move under operationID when it is not 0
+func (db *DB) SetArticlesFolder(ids []int64, folderID *int64, operationID int64) (int64, error) {
+	var updated int64
+
+	for _, id := range ids {
+		var previous *int64
+		if err := db.Get(&previous, "SELECT folder_id FROM articles WHERE id = ?", id); err != nil {
+			return updated, fmt.Errorf("failed to get folder of article %d: %w", id, err)
+		}
+
+		result, err := db.Exec("UPDATE articles SET folder_id = ? WHERE id = ? AND folder_id IS NOT ?", folderID, id, folderID)
+		if err != nil {
+			return updated, fmt.Errorf("failed to update article %d: %w", id, err)
+		}
+
+		rows, _ := result.RowsAffected()
+		if rows == 0 {
+			continue
+		}
+		updated += rows
+
+		if err := db.journal(operationID, &id, ChangeFolder, folderIDString(previous), folderIDString(folderID)); err != nil {
+			return updated, err
+		}
+
+		if err := db.UpsertArticleFTS(id); err != nil {
+			return updated, fmt.Errorf("failed to update FTS for article %d: %w", id, err)
+		}
+	}
+
+	return updated, nil
+}
+
+func folderIDString(folderID *int64) *string {
+	if folderID == nil {
+		return nil
+	}
+	s := strconv.FormatInt(*folderID, 10)
+	return &s
+}
+
+// FTSReport describes how the FTS index differs from the articles table
+type FTSReport struct {
+	Indexed         int     `json:"indexed"`
+	Expected        int     `json:"expected"`
+	ObsoleteIndexed []int64 `json:"obsolete_indexed,omitempty"`
+	Missing         []int64 `json:"missing,omitempty"`
+}
+
+// OK reports whether the FTS index matches the active articles exactly
+func (r FTSReport) OK() bool {
+	return len(r.ObsoleteIndexed) == 0 && len(r.Missing) == 0 && r.Indexed == r.Expected
+}
+
+// VerifyFTS compares the FTS index against active (non-obsolete) articles
+func (db *DB) VerifyFTS() (FTSReport, error) {
+	var report FTSReport
+
+	if err := db.Get(&report.Indexed, "SELECT COUNT(*) FROM articles_fts"); err != nil {
+		return report, fmt.Errorf("failed to count FTS rows: %w", err)
+	}
+
+	if err := db.Get(&report.Expected, "SELECT COUNT(*) FROM articles WHERE obsolete = FALSE"); err != nil {
+		return report, fmt.Errorf("failed to count active articles: %w", err)
+	}
+
+	if err := db.Select(&report.ObsoleteIndexed, `
+		SELECT a.id FROM articles a
+		WHERE a.obsolete = TRUE AND a.id IN (SELECT rowid FROM articles_fts)
+		ORDER BY a.id
+	`); err != nil {
+		return report, fmt.Errorf("failed to find obsolete FTS rows: %w", err)
+	}
+
+	if err := db.Select(&report.Missing, `
+		SELECT a.id FROM articles a
+		WHERE a.obsolete = FALSE AND a.id NOT IN (SELECT rowid FROM articles_fts)
+		ORDER BY a.id
+	`); err != nil {
+		return report, fmt.Errorf("failed to find missing FTS rows: %w", err)
+	}
+
+	return report, nil
+}
+
+// CleanTitlesReport summarizes a CleanTitles run
+type CleanTitlesReport struct {
+	Checked int `json:"checked"`
+	Updated int `json:"updated"`
+}
+
+// CleanTitles re-applies title cleaning to every article and refreshes the FTS entry of changed ones
+func (db *DB) CleanTitles(ctx context.Context, clean func(title, url string) string) (CleanTitlesReport, error) {
+	var report CleanTitlesReport
+
+	var articles []struct {
+		ID    int64          `db:"id"`
+		URL   string         `db:"url"`
+		Title sql.NullString `db:"title"`
+	}
+	if err := db.SelectContext(ctx, &articles, "SELECT id, url, title FROM articles WHERE title IS NOT NULL ORDER BY id"); err != nil {
+		return report, fmt.Errorf("failed to get articles: %w", err)
+	}
+
+	for _, article := range articles {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		report.Checked++
+
+		cleaned := clean(article.Title.String, article.URL)
+		if cleaned == "" || cleaned == article.Title.String {
+			continue
+		}
+
+		if _, err := db.Exec("UPDATE articles SET title = ?, title_norm = ? WHERE id = ?", cleaned, util.NormalizeText(cleaned), article.ID); err != nil {
+			return report, fmt.Errorf("failed to update title for article %d: %w", article.ID, err)
+		}
+		if err := db.UpsertArticleFTS(article.ID); err != nil {
+			return report, err
+		}
+		report.Updated++
+	}
+
+	return report, nil
+}
+
+// NormalizeTextReport summarizes a NormalizeArticleText run
+type NormalizeTextReport struct {
+	Checked int `json:"checked"`
+	Titles  int `json:"titles"`
+	Content int `json:"content"`
+}
+
+// NormalizeArticleText re-applies text normalization to every article's title and content, in
+// batches, and refreshes the outline and FTS entry of changed ones
+func (db *DB) NormalizeArticleText(ctx context.Context, title, content func(string) string) (NormalizeTextReport, error) {
+	const batchSize = 200
+	var report NormalizeTextReport
+	lastID := int64(0)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		var articles []struct {
+			ID      int64          `db:"id"`
+			Title   sql.NullString `db:"title"`
+			Content []byte         `db:"content_md"`
+		}
+		if err := db.SelectContext(ctx, &articles, "SELECT id, title, content_md FROM articles WHERE id > ? ORDER BY id LIMIT ?", lastID, batchSize); err != nil {
+			return report, fmt.Errorf("failed to get articles: %w", err)
+		}
+		if len(articles) == 0 {
+			return report, nil
+		}
+
+		for _, article := range articles {
+			lastID = article.ID
+			report.Checked++
+			changed := false
+
+			if normalized := title(article.Title.String); article.Title.Valid && normalized != "" && normalized != article.Title.String {
+				if _, err := db.Exec("UPDATE articles SET title = ?, title_norm = ? WHERE id = ?", normalized, util.NormalizeText(normalized), article.ID); err != nil {
+					return report, fmt.Errorf("failed to update title for article %d: %w", article.ID, err)
+				}
+				report.Titles++
+				changed = true
+			}
+
+			if article.Content != nil {
+				markdown, err := DecodeContent(article.Content)
+				if err != nil {
+					return report, fmt.Errorf("article %d: %w", article.ID, err)
+				}
+				if normalized := content(markdown); normalized != markdown {
+					stored, err := db.EncodeContent(normalized)
+					if err != nil {
+						return report, err
+					}
+					if _, err := db.Exec("UPDATE articles SET content_md = ?, outline = ? WHERE id = ?", stored, OutlineJSON(normalized), article.ID); err != nil {
+						return report, fmt.Errorf("failed to update content for article %d: %w", article.ID, err)
+					}
+					report.Content++
+					changed = true
+				}
+			}
+
+			if changed {
+				if err := db.UpsertArticleFTS(article.ID); err != nil {
+					return report, err
+				}
+			}
+		}
+	}
+}
+
+// RecanonicalizeReport summarizes a RecanonicalizeURLs run
+type RecanonicalizeReport struct {
+	Checked int `json:"checked"`
+	Updated int `json:"updated"`
+	Merged  int `json:"merged"`
+}
+
+// RecanonicalizeURLs re-applies URL canonicalization to every article. Articles
+// whose canonical URL already exists are merged into the existing article:
+// tags are combined, content and highlights are kept if the survivor lacks them,
+// and the earliest save date wins.
+func (db *DB) RecanonicalizeURLs(ctx context.Context, canonicalize func(string) (string, error)) (RecanonicalizeReport, error) {
+	var report RecanonicalizeReport
+
+	var articles []struct {
+		ID  int64  `db:"id"`
+		URL string `db:"url"`
+	}
+	if err := db.SelectContext(ctx, &articles, "SELECT id, url FROM articles ORDER BY id"); err != nil {
+		return report, fmt.Errorf("failed to get articles: %w", err)
+	}
+
+	for _, article := range articles {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		report.Checked++
+
+		canonicalURL, err := canonicalize(article.URL)
+		if err != nil || canonicalURL == article.URL {
+			continue
+		}
+
+		var existingID int64
+		err = db.Get(&existingID, "SELECT id FROM articles WHERE url = ?", canonicalURL)
+		if err == sql.ErrNoRows {
+			err := db.InTx(ctx, func(tx *DB) error {
+				if _, err := tx.Exec("UPDATE articles SET url = ? WHERE id = ?", canonicalURL, article.ID); err != nil {
+					return fmt.Errorf("failed to update URL for article %d: %w", article.ID, err)
+				}
+				if _, err := tx.Exec("DELETE FROM url_aliases WHERE url = ?", canonicalURL); err != nil {
+					return fmt.Errorf("failed to update URL aliases: %w", err)
+				}
+				return tx.UpsertArticleFTS(article.ID)
+			})
+			if err != nil {
+				return report, err
+			}
+			report.Updated++
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("failed to check URL for article %d: %w", article.ID, err)
+		}
+
+		if err := db.mergeArticles(ctx, existingID, article.ID); err != nil {
+			return report, err
+		}
+		report.Merged++
+	}
+
+	return report, nil
+}
+
+// mergeArticles folds the duplicate article into keepID and deletes the duplicate, in one
+// transaction so a failed merge leaves both articles as they were
+func (db *DB) mergeArticles(ctx context.Context, keepID, dropID int64) error {
+	steps := []struct {
+		name  string
+		query string
+		args  []interface{}
+	}{
+		{"merge tags", `
+			INSERT OR IGNORE INTO article_tags (article_id, tag_id)
+			SELECT ?, tag_id FROM article_tags WHERE article_id = ?
+		`, []interface{}{keepID, dropID}},
+		{"merge content", `
+			UPDATE articles
+			SET (synced_at, content_md, raw_html, final_url, canonical_url, simhash, status_code, status_text, failed_count, sync_failed_at, failure_class, next_retry_at) =
+			    (SELECT synced_at, content_md, raw_html, final_url, canonical_url, simhash, status_code, status_text, failed_count, sync_failed_at, failure_class, next_retry_at
+			     FROM articles WHERE id = ?)
+			WHERE id = ? AND synced_at IS NULL
+			AND EXISTS (SELECT 1 FROM articles WHERE id = ? AND synced_at IS NOT NULL)
+		`, []interface{}{dropID, keepID, dropID}},
+		{"merge highlights", `
+			UPDATE highlights SET article_id = ?
+			WHERE article_id = ? AND NOT EXISTS (SELECT 1 FROM highlights WHERE article_id = ?)
+		`, []interface{}{keepID, dropID, keepID}},
+		{"merge attachments", `
+			UPDATE OR IGNORE attachments SET article_id = ? WHERE article_id = ?
+		`, []interface{}{keepID, dropID}},
+		{"merge selection", `
+			UPDATE articles
+			SET selection = (SELECT selection FROM articles WHERE id = ?)
+			WHERE id = ? AND (selection IS NULL OR selection = '')
+		`, []interface{}{dropID, keepID}},
+		{"merge save date", `
+			UPDATE articles
+			SET instapapered_at = MIN(instapapered_at, (SELECT instapapered_at FROM articles WHERE id = ?))
+			WHERE id = ?
+		`, []interface{}{dropID, keepID}},
+		{"merge URL aliases", `
+			UPDATE OR IGNORE url_aliases SET article_id = ? WHERE article_id = ?
+		`, []interface{}{keepID, dropID}},
+		{"keep duplicate URL", `
+			INSERT OR IGNORE INTO url_aliases (url, article_id, kind, created_at)
+			SELECT url, ?, ?, ? FROM articles WHERE id = ?
+		`, []interface{}{keepID, URLAliasPrevious, time.Now().UTC().Format(time.RFC3339), dropID}},
+		{"delete duplicate", "DELETE FROM articles WHERE id = ?", []interface{}{dropID}},
+	}
+
+	return db.InTx(ctx, func(tx *DB) error {
+		if err := tx.DeleteArticleFTS(dropID); err != nil {
+			return err
+		}
+
+		for _, step := range steps {
+			if _, err := tx.Exec(step.query, step.args...); err != nil {
+				return fmt.Errorf("failed to %s of article %d into %d: %w", step.name, dropID, keepID, err)
+			}
+		}
+
+		return tx.UpsertArticleFTS(keepID)
+	})
+}
+
+// Size returns the size of the database file in bytes
+func (db *DB) Size() (int64, error) {
+	var size int64
+	if err := db.Get(&size, "SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()"); err != nil {
+		return 0, fmt.Errorf("failed to get database size: %w", err)
+	}
+	return size, nil
+}
+
+// Optimize merges FTS index segments, rebuilds indexes, reclaims free pages
+// and refreshes query planner statistics
+func (db *DB) Optimize(ctx context.Context) error {
+	steps := []struct {
+		name string
+		sql  string
+	}{
+		{"optimize FTS index", "INSERT INTO articles_fts(articles_fts) VALUES('optimize')"},
+		{"reindex", "REINDEX"},
+		{"vacuum", "VACUUM"},
+		{"optimize", "PRAGMA optimize"},
+	}
+
+	for _, step := range steps {
+		if _, err := db.ExecContext(ctx, step.sql); err != nil {
+			return fmt.Errorf("failed to %s: %w", step.name, err)
+		}
+	}
+
+	return nil
+}
+
+// AddRSSFeed adds a new RSS feed with optional tags. With useContent, items' embedded content is
+// stored at sync time instead of being fetched. Backfill limits the items of the first sync (empty
+// for all).
+func (db *DB) AddRSSFeed(url, name string, tags []string, useContent bool, backfill string) (int64, error) {
 	// Insert the feed
 	result, err := db.Exec(`
-		INSERT INTO rss_feeds (url, name)
-		VALUES (?, ?)
-	`, url, name)
+		INSERT INTO rss_feeds (url, name, use_content, backfill)
+		VALUES (?, ?, ?, COALESCE(NULLIF(?, ''), 'all'))
+	`, url, name, useContent, backfill)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert RSS feed: %w", err)
 	}
@@ -400,7 +1102,7 @@ func (db *DB) AddRSSFeed(url, name string, tags []string) (int64, error) {
 func (db *DB) GetRSSFeeds() ([]map[string]interface{}, error) {
 	query := `
 		SELECT
-			f.id, f.url, f.name, f.created_at, f.last_synced_at, f.active,
+			f.id, f.url, f.name, f.created_at, f.last_synced_at, f.active, f.use_content, f.backfill,
 			GROUP_CONCAT(t.title, ', ') as tags
 		FROM rss_feeds f
 		LEFT JOIN rss_feed_tags rft ON f.id = rft.feed_id
@@ -418,21 +1120,23 @@ func (db *DB) GetRSSFeeds() ([]map[string]interface{}, error) {
 	var feeds []map[string]interface{}
 	for rows.Next() {
 		var id int64
-		var url, name, createdAt string
+		var url, name, createdAt, backfill string
 		var lastSyncedAt, tags *string
-		var active bool
+		var active, useContent bool
 
-		err := rows.Scan(&id, &url, &name, &createdAt, &lastSyncedAt, &active, &tags)
+		err := rows.Scan(&id, &url, &name, &createdAt, &lastSyncedAt, &active, &useContent, &backfill, &tags)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan RSS feed: %w", err)
 		}
 
 		feed := map[string]interface{}{
-			"id":         id,
-			"url":        url,
-			"name":       name,
-			"created_at": createdAt,
-			"active":     active,
+			"id":          id,
+			"url":         url,
+			"name":        name,
+			"created_at":  createdAt,
+			"active":      active,
+			"use_content": useContent,
+			"backfill":    backfill,
 		}
 
 		if lastSyncedAt != nil {
@@ -452,27 +1156,29 @@ func (db *DB) GetRSSFeeds() ([]map[string]interface{}, error) {
 // GetRSSFeed retrieves a single RSS feed by ID with its tags
 func (db *DB) GetRSSFeed(id int64) (map[string]interface{}, []string, error) {
 	query := `
-		SELECT id, url, name, created_at, last_synced_at, active
+		SELECT id, url, name, created_at, last_synced_at, active, use_content, backfill
 		FROM rss_feeds
 		WHERE id = ?
 	`
 
 	var feedID int64
-	var url, name, createdAt string
+	var url, name, createdAt, backfill string
 	var lastSyncedAt *string
-	var active bool
+	var active, useContent bool
 
-	err := db.QueryRow(query, id).Scan(&feedID, &url, &name, &createdAt, &lastSyncedAt, &active)
+	err := db.QueryRow(query, id).Scan(&feedID, &url, &name, &createdAt, &lastSyncedAt, &active, &useContent, &backfill)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get RSS feed: %w", err)
 	}
 
 	feed := map[string]interface{}{
-		"id":         feedID,
-		"url":        url,
-		"name":       name,
-		"created_at": createdAt,
-		"active":     active,
+		"id":          feedID,
+		"url":         url,
+		"name":        name,
+		"created_at":  createdAt,
+		"active":      active,
+		"use_content": useContent,
+		"backfill":    backfill,
 	}
 
 	if lastSyncedAt != nil {
@@ -513,8 +1219,9 @@ func (db *DB) DeleteRSSFeed(id int64) error {
 	return nil
 }
 
-// UpdateRSSFeed updates an RSS feed's name and/or tags
-func (db *DB) UpdateRSSFeed(id int64, name *string, tags []string) error {
+// UpdateRSSFeed updates an RSS feed's name, tags, whether its items' content is used, and/or its
+// first sync's backfill
+func (db *DB) UpdateRSSFeed(id int64, name *string, tags []string, useContent *bool, backfill *string) error {
 	// Update name if provided
 	if name != nil {
 		_, err := db.Exec("UPDATE rss_feeds SET name = ? WHERE id = ?", *name, id)
@@ -523,6 +1230,18 @@ func (db *DB) UpdateRSSFeed(id int64, name *string, tags []string) error {
 		}
 	}
 
+	if useContent != nil {
+		if _, err := db.Exec("UPDATE rss_feeds SET use_content = ? WHERE id = ?", *useContent, id); err != nil {
+			return fmt.Errorf("failed to update RSS feed content setting: %w", err)
+		}
+	}
+
+	if backfill != nil {
+		if _, err := db.Exec("UPDATE rss_feeds SET backfill = ? WHERE id = ?", *backfill, id); err != nil {
+			return fmt.Errorf("failed to update RSS feed backfill: %w", err)
+		}
+	}
+
 	// Update tags if provided
 	if tags != nil {
 		// Remove existing tags
@@ -548,5 +1267,22 @@ func (db *DB) UpdateRSSFeed(id int64, name *string, tags []string) error {
 		}
 	}
 
+	return nil
+}
+
+// IsRSSItemSkipped reports whether a feed's first sync left out the item with this URL
+func (db *DB) IsRSSItemSkipped(feedID int64, url string) (bool, error) {
+	var skipped bool
+	if err := db.Get(&skipped, "SELECT EXISTS(SELECT 1 FROM rss_skipped_items WHERE feed_id = ? AND url = ?)", feedID, url); err != nil {
+		return false, fmt.Errorf("failed to check skipped RSS items: %w", err)
+	}
+	return skipped, nil
+}
+
+// SkipRSSItem remembers that a feed's first sync left out the item with this URL
+func (db *DB) SkipRSSItem(feedID int64, url string) error {
+	if _, err := db.Exec("INSERT OR IGNORE INTO rss_skipped_items (feed_id, url) VALUES (?, ?)", feedID, url); err != nil {
+		return fmt.Errorf("failed to record skipped RSS item: %w", err)
+	}
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+// EnableQueryLog logs every query executed through DB, with its arguments and timing, to w
+func (db *DB) EnableQueryLog(w io.Writer) {
+	db.queryLog = log.New(w, "[sql] ", log.LstdFlags|log.Lmicroseconds)
+}
+
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+func (db *DB) Get(dest interface{}, query string, args ...interface{}) error {
+	return db.GetContext(context.Background(), dest, query, args...)
+}
+
+func (db *DB) Select(dest interface{}, query string, args ...interface{}) error {
+	return db.SelectContext(context.Background(), dest, query, args...)
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer db.logQuery(time.Now(), query, args)
+	if db.tx != nil {
+		return db.tx.ExecContext(ctx, query, args...)
+	}
+	return db.DB.ExecContext(ctx, query, args...)
+}
+
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	defer db.logQuery(time.Now(), query, args)
+	if db.tx != nil {
+		return db.tx.QueryContext(ctx, query, args...)
+	}
+	return db.DB.QueryContext(ctx, query, args...)
+}
+
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	defer db.logQuery(time.Now(), query, args)
+	if db.tx != nil {
+		return db.tx.QueryRowContext(ctx, query, args...)
+	}
+	return db.DB.QueryRowContext(ctx, query, args...)
+}
+
+func (db *DB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	defer db.logQuery(time.Now(), query, args)
+	if db.tx != nil {
+		return db.tx.GetContext(ctx, dest, query, args...)
+	}
+	return db.DB.GetContext(ctx, dest, query, args...)
+}
+
+func (db *DB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	defer db.logQuery(time.Now(), query, args)
+	if db.tx != nil {
+		return db.tx.SelectContext(ctx, dest, query, args...)
+	}
+	return db.DB.SelectContext(ctx, dest, query, args...)
+}
+
+// logQuery writes a single-line query log entry when query logging is enabled
+func (db *DB) logQuery(start time.Time, query string, args []interface{}) {
+	if db.queryLog == nil {
+		return
+	}
+
+	query = strings.Join(strings.Fields(query), " ")
+	db.queryLog.Printf("%s | %s | args=%v", time.Since(start).Round(time.Microsecond), query, args)
+}
+
+// PlanStep is one row of an EXPLAIN QUERY PLAN result
+type PlanStep struct {
+	ID     int    `db:"id" json:"id"`
+	Parent int    `db:"parent" json:"parent"`
+	Unused int    `db:"notused" json:"-"`
+	Detail string `db:"detail" json:"detail"`
+}
+
+// ExplainQueryPlan returns SQLite's query plan for a query without running it
+func (db *DB) ExplainQueryPlan(ctx context.Context, query string, args ...interface{}) ([]PlanStep, error) {
+	var steps []PlanStep
+	if err := db.SelectContext(ctx, &steps, "EXPLAIN QUERY PLAN "+query, args...); err != nil {
+		return nil, fmt.Errorf("failed to explain query: %w", err)
+	}
+	return steps, nil
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// FTS batch defaults: a batch is written once this many articles are queued or the oldest has
+// waited this long, whichever comes first
+const (
+	DefaultFTSBatchSize     = 200
+	DefaultFTSBatchInterval = 10 * time.Second
+)
+
+// How long a busy database is retried, doubling from ftsRetryDelay up to ftsRetries attempts
+const (
+	ftsRetries    = 8
+	ftsRetryDelay = 50 * time.Millisecond
+)
+
+// FTSBatch collects articles whose search index entries are out of date and updates them together
+// in one transaction, so bulk writers such as import and fetch take the write lock once per batch
+// instead of once per article. Call Flush when done; entries left unflushed by a crash are found
+// and rebuilt by doctor.
+type FTSBatch struct {
+	db       *DB
+	size     int
+	interval time.Duration
+	ids      []int64
+	queued   map[int64]bool
+	since    time.Time
+}
+
+// NewFTSBatch returns a batch that is written every size articles or interval, or only on Flush
+// when they are 0
+func (db *DB) NewFTSBatch(size int, interval time.Duration) *FTSBatch {
+	return &FTSBatch{db: db, size: size, interval: interval, queued: make(map[int64]bool)}
+}
+
+// Add queues an article, writing the batch when it is full or due
+func (b *FTSBatch) Add(ctx context.Context, articleID int64) error {
+	if !b.queued[articleID] {
+		if len(b.ids) == 0 {
+			b.since = time.Now()
+		}
+		b.ids = append(b.ids, articleID)
+		b.queued[articleID] = true
+	}
+
+	if (b.size > 0 && len(b.ids) >= b.size) || (b.interval > 0 && time.Since(b.since) >= b.interval) {
+		return b.Flush(ctx)
+	}
+	return nil
+}
+
+// Flush updates the queued articles' entries in one transaction, retrying with backoff while
+// another connection holds SQLite's lock. Articles deleted since they were queued are skipped.
+func (b *FTSBatch) Flush(ctx context.Context) error {
+	if len(b.ids) == 0 {
+		return nil
+	}
+
+	delay := ftsRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := b.db.InTx(ctx, func(tx *DB) error {
+			for _, id := range b.ids {
+				if err := tx.UpsertArticleFTS(id); err != nil && !errors.Is(err, sql.ErrNoRows) {
+					return fmt.Errorf("article %d: %w", id, err)
+				}
+			}
+			return nil
+		})
+		if err == nil {
+			break
+		}
+		if !isBusy(err) || attempt == ftsRetries {
+			return fmt.Errorf("failed to update FTS for %d articles: %w", len(b.ids), err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+
+	b.ids = b.ids[:0]
+	clear(b.queued)
+	return nil
+}
+
+// isBusy reports whether err is SQLite's SQLITE_BUSY or SQLITE_LOCKED, which go away once the
+// other writer is done
+func isBusy(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code() & 0xff
+	return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+
+	"instapaper-cli/internal/util"
+
+	"modernc.org/sqlite"
+)
+
+// GarbledThreshold is how many mis-decoded characters mark fetched content as garbled. A stray
+// replacement character or two is common in otherwise fine pages.
+const GarbledThreshold = 3
+
+func init() {
+	// garbled_chars(text) counts the characters of text that were decoded with the wrong charset
+	sqlite.MustRegisterDeterministicScalarFunction("garbled_chars", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		text, ok := textValue(args[0])
+		if !ok {
+			return int64(0), nil
+		}
+		return int64(util.CountGarbled(text)), nil
+	})
+}
+
+// GarbledArticle is a fetched article whose content was decoded with the wrong charset
+type GarbledArticle struct {
+	ID      int64  `db:"id" json:"id"`
+	Title   string `db:"title" json:"title"`
+	URL     string `db:"url" json:"url"`
+	Garbled int    `db:"garbled" json:"garbled_chars"`
+}
+
+// GarbledArticles lists the active fetched articles with at least GarbledThreshold mis-decoded
+// characters, most garbled first. Every article's content is scanned.
+func (db *DB) GarbledArticles(ctx context.Context) ([]GarbledArticle, error) {
+	articles := []GarbledArticle{}
+	if err := db.SelectContext(ctx, &articles, `
+		SELECT id, title, url, garbled
+		FROM (
+			SELECT id, title, url, garbled_chars(content_text(content_md)) AS garbled
+			FROM articles
+			WHERE obsolete = FALSE AND synced_at IS NOT NULL AND content_md IS NOT NULL
+		)
+		WHERE garbled >= ?
+		ORDER BY garbled DESC, id
+	`, GarbledThreshold); err != nil {
+		return nil, fmt.Errorf("failed to get garbled articles: %w", err)
+	}
+	return articles, nil
+}
@@ -0,0 +1,217 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// What a reading goal counts
+const (
+	GoalArticles = "articles"
+	GoalWords    = "words"
+)
+
+// How often a reading goal starts over. Weeks start on Monday, in local time.
+const (
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+// maxStreakPeriods bounds how far back a goal's streak is counted
+const maxStreakPeriods = 520
+
+// Goal is a target number of articles or words read per week or month
+type Goal struct {
+	ID        int64  `db:"id" json:"id"`
+	Metric    string `db:"metric" json:"metric"`
+	Period    string `db:"period" json:"period"`
+	Target    int    `db:"target" json:"target"`
+	CreatedAt string `db:"created_at" json:"created_at"`
+}
+
+// GoalProgress is how far a goal is in the current period
+type GoalProgress struct {
+	Goal
+	PeriodStart string  `json:"period_start"` // YYYY-MM-DD
+	PeriodEnd   string  `json:"period_end"`   // YYYY-MM-DD, inclusive
+	Done        int     `json:"done"`
+	Remaining   int     `json:"remaining"`
+	Percent     float64 `json:"percent"`
+	Met         bool    `json:"met"`
+	// Streak counts the consecutive periods the goal was met, up to and including this one when met
+	Streak int `json:"streak"`
+}
+
+// readArticle is an article marked read, with an approximate word count
+type readArticle struct {
+	ReadAt string `db:"read_at"`
+	Words  int    `db:"words"`
+}
+
+func validateGoal(metric, period string) error {
+	if metric != GoalArticles && metric != GoalWords {
+		return fmt.Errorf("invalid goal %q: use %s or %s", metric, GoalArticles, GoalWords)
+	}
+	if period != PeriodWeek && period != PeriodMonth {
+		return fmt.Errorf("invalid period %q: use %s or %s", period, PeriodWeek, PeriodMonth)
+	}
+	return nil
+}
+
+// SetGoal sets the target of a reading goal, replacing the target of an existing goal
+func (db *DB) SetGoal(metric, period string, target int) error {
+	if err := validateGoal(metric, period); err != nil {
+		return err
+	}
+	if target < 1 {
+		return fmt.Errorf("goal target must be at least 1")
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO goals (metric, period, target, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(metric, period) DO UPDATE SET target = excluded.target
+	`, metric, period, target, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to set goal: %w", err)
+	}
+	return nil
+}
+
+// RemoveGoal deletes a reading goal and reports whether it existed
+func (db *DB) RemoveGoal(metric, period string) (bool, error) {
+	if err := validateGoal(metric, period); err != nil {
+		return false, err
+	}
+
+	result, err := db.Exec("DELETE FROM goals WHERE metric = ? AND period = ?", metric, period)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove goal: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// Goals lists the reading goals, weekly before monthly
+func (db *DB) Goals() ([]Goal, error) {
+	goals := []Goal{}
+	if err := db.Select(&goals, "SELECT * FROM goals ORDER BY period DESC, metric"); err != nil {
+		return nil, fmt.Errorf("failed to get goals: %w", err)
+	}
+	return goals, nil
+}
+
+// GoalProgress reports every goal's progress in the period containing now, counting the active
+// articles marked read in it and their approximate words
+func (db *DB) GoalProgress(now time.Time) ([]GoalProgress, error) {
+	goals, err := db.Goals()
+	if err != nil || len(goals) == 0 {
+		return []GoalProgress{}, err
+	}
+
+	// Words are approximated by counting whitespace-separated runs of text, as in stats --by
+	var read []readArticle
+	if err := db.Select(&read, `
+		SELECT read_at,
+			CASE WHEN words_text IS NULL OR words_text = '' THEN 0
+				ELSE length(words_text) - length(replace(words_text, ' ', '')) + 1 END AS words
+		FROM (
+			SELECT read_at, trim(replace(replace(content_text(content_md), char(10), ' '), char(9), ' ')) AS words_text
+			FROM articles
+			WHERE read_at IS NOT NULL AND obsolete = FALSE
+		)
+	`); err != nil {
+		return nil, fmt.Errorf("failed to get read articles: %w", err)
+	}
+
+	progress := make([]GoalProgress, len(goals))
+	for i, goal := range goals {
+		start := PeriodStart(goal.Period, now)
+		end := nextPeriod(goal.Period, start)
+
+		p := GoalProgress{
+			Goal:        goal,
+			PeriodStart: start.Format("2006-01-02"),
+			PeriodEnd:   end.AddDate(0, 0, -1).Format("2006-01-02"),
+			Done:        goalDone(goal.Metric, read, start, end),
+		}
+		p.Remaining = max(0, goal.Target-p.Done)
+		p.Percent = min(100, float64(p.Done)/float64(goal.Target)*100)
+		p.Met = p.Done >= goal.Target
+
+		// The streak runs back from this period, or the last one while this one is still open,
+		// and never before the period the goal was set in
+		created, _ := time.Parse(time.RFC3339, goal.CreatedAt)
+		first := PeriodStart(goal.Period, created.Local())
+		if p.Met {
+			p.Streak = 1
+		}
+		for n := 0; n < maxStreakPeriods && start.After(first); n++ {
+			end, start = start, previousPeriod(goal.Period, start)
+			if goalDone(goal.Metric, read, start, end) < goal.Target {
+				break
+			}
+			p.Streak++
+		}
+
+		progress[i] = p
+	}
+	return progress, nil
+}
+
+// Summary describes a goal's progress in one line, e.g. "3/5 articles this week (60%), 2 to go"
+func (p GoalProgress) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d/%d %s this %s (%.0f%%)", p.Done, p.Target, p.Metric, p.Period, p.Percent)
+	if p.Met {
+		b.WriteString(", goal met")
+	} else {
+		fmt.Fprintf(&b, ", %d to go", p.Remaining)
+	}
+	if p.Streak > 0 {
+		fmt.Fprintf(&b, ", %d-%s streak", p.Streak, p.Period)
+	}
+	return b.String()
+}
+
+// PeriodStart returns the local midnight starting the week (Monday) or month containing t
+func PeriodStart(period string, t time.Time) time.Time {
+	t = t.Local()
+	if period == PeriodMonth {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local)
+	}
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, time.Local)
+}
+
+func nextPeriod(period string, start time.Time) time.Time {
+	if period == PeriodMonth {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 7)
+}
+
+func previousPeriod(period string, start time.Time) time.Time {
+	if period == PeriodMonth {
+		return start.AddDate(0, -1, 0)
+	}
+	return start.AddDate(0, 0, -7)
+}
+
+// goalDone counts the articles or words read from start up to end
+func goalDone(metric string, read []readArticle, start, end time.Time) int {
+	done := 0
+	for _, article := range read {
+		readAt, err := time.Parse(time.RFC3339, article.ReadAt)
+		if err != nil || readAt.Before(start) || !readAt.Before(end) {
+			continue
+		}
+		if metric == GoalWords {
+			done += article.Words
+		} else {
+			done++
+		}
+	}
+	return done
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IndexSpec describes an index the application relies on
+type IndexSpec struct {
+	Name    string   `json:"name"`
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique,omitempty"`
+}
+
+// RequiredIndexes lists the indexes created by migrations that queries depend on
+var RequiredIndexes = []IndexSpec{
+	{Name: "idx_articles_url", Table: "articles", Columns: []string{"url"}, Unique: true},
+	{Name: "idx_articles_folder", Table: "articles", Columns: []string{"folder_id"}},
+	{Name: "idx_articles_instapapered_at", Table: "articles", Columns: []string{"instapapered_at"}},
+	{Name: "idx_articles_synced_at", Table: "articles", Columns: []string{"synced_at"}},
+	{Name: "idx_articles_obsolete", Table: "articles", Columns: []string{"obsolete"}},
+	{Name: "idx_articles_obsolete_instapapered_at", Table: "articles", Columns: []string{"obsolete", "instapapered_at"}},
+	{Name: "idx_articles_obsolete_synced_at", Table: "articles", Columns: []string{"obsolete", "synced_at"}},
+	{Name: "idx_articles_status_code", Table: "articles", Columns: []string{"status_code"}},
+	{Name: "idx_articles_next_retry_at", Table: "articles", Columns: []string{"next_retry_at"}},
+	{Name: "idx_article_tags_pk", Table: "article_tags", Columns: []string{"article_id", "tag_id"}, Unique: true},
+	{Name: "idx_article_tags_tag", Table: "article_tags", Columns: []string{"tag_id", "article_id"}},
+	{Name: "idx_highlights_article", Table: "highlights", Columns: []string{"article_id"}},
+}
+
+// MissingIndexes returns the required indexes that have no index with the same
+// columns on their table, whatever the existing index is named
+func (db *DB) MissingIndexes() ([]IndexSpec, error) {
+	existing := make(map[string]map[string]bool)
+
+	var missing []IndexSpec
+	for _, spec := range RequiredIndexes {
+		if existing[spec.Table] == nil {
+			columns, err := db.indexColumns(spec.Table)
+			if err != nil {
+				return nil, err
+			}
+			existing[spec.Table] = columns
+		}
+
+		if !existing[spec.Table][strings.Join(spec.Columns, ",")] {
+			missing = append(missing, spec)
+		}
+	}
+
+	return missing, nil
+}
+
+// indexColumns returns the column lists of every index on a table, e.g. "obsolete,instapapered_at"
+func (db *DB) indexColumns(table string) (map[string]bool, error) {
+	var names []string
+	if err := db.Select(&names, "SELECT name FROM pragma_index_list(?)", table); err != nil {
+		return nil, fmt.Errorf("failed to list indexes for %s: %w", table, err)
+	}
+
+	columns := make(map[string]bool)
+	for _, name := range names {
+		var indexColumns []string
+		if err := db.Select(&indexColumns, "SELECT COALESCE(name, '') FROM pragma_index_info(?) ORDER BY seqno", name); err != nil {
+			return nil, fmt.Errorf("failed to read index %s: %w", name, err)
+		}
+		columns[strings.Join(indexColumns, ",")] = true
+	}
+
+	return columns, nil
+}
+
+// CreateIndex creates a missing index
+func (db *DB) CreateIndex(spec IndexSpec) error {
+	unique := ""
+	if spec.Unique {
+		unique = "UNIQUE "
+	}
+
+	query := fmt.Sprintf("CREATE %sINDEX IF NOT EXISTS %s ON %s(%s)", unique, spec.Name, spec.Table, strings.Join(spec.Columns, ", "))
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create index %s: %w", spec.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"database/sql/driver"
+
+	"instapaper-cli/internal/language"
+
+	"modernc.org/sqlite"
+)
+
+func init() {
+	// detect_language(text) guesses the language of article text, NULL when unsure
+	sqlite.MustRegisterDeterministicScalarFunction("detect_language", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		text, ok := textValue(args[0])
+		if !ok {
+			return nil, nil
+		}
+		if lang := language.Detect(text); lang != "" {
+			return lang, nil
+		}
+		return nil, nil
+	})
+
+	// segment_text(text) splits runs of scripts written without spaces for the FTS index
+	sqlite.MustRegisterDeterministicScalarFunction("segment_text", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		text, ok := textValue(args[0])
+		if !ok {
+			return args[0], nil
+		}
+		return language.Segment(text), nil
+	})
+}
+
+func textValue(value driver.Value) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	}
+	return "", false
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// lockPollInterval is how often a waiting writer retries the lock
+const lockPollInterval = 200 * time.Millisecond
+
+// LockedError reports that another process holds the database's write lock
+type LockedError struct {
+	Path string
+	// Holder describes the process holding the lock, as it recorded itself in the lock file
+	Holder string
+}
+
+func (e *LockedError) Error() string {
+	if e.Holder == "" {
+		return fmt.Sprintf("database is busy: another process holds the write lock %s", e.Path)
+	}
+	return fmt.Sprintf("database is busy: %s holds the write lock %s", e.Holder, e.Path)
+}
+
+// WriteLock is a held single-writer lock; Release lets the next writer in
+type WriteLock struct {
+	db *DB
+}
+
+// LockWrites takes the single-writer lock in a file next to the database, so concurrent invocations
+// (a cron fetch and a manual import) run one at a time instead of interleaving their writes. It waits
+// up to wait for another writer to finish, and fails at once with a *LockedError when wait is 0.
+// The lock belongs to the open file, so the operating system releases it when the process exits or
+// is killed; a crash never leaves a stale lock behind. Nested calls in the same process share the lock.
+func (db *DB) LockWrites(ctx context.Context, command string, wait time.Duration) (*WriteLock, error) {
+	if db.lockPath == "" {
+		return &WriteLock{}, nil
+	}
+
+	db.lockMu.Lock()
+	defer db.lockMu.Unlock()
+
+	if db.lockFile != nil {
+		db.lockDepth++
+		return &WriteLock{db: db}, nil
+	}
+
+	file, err := os.OpenFile(db.lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", db.lockPath, err)
+		}
+		if locked {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			holder, _ := os.ReadFile(db.lockPath)
+			file.Close()
+			return nil, &LockedError{Path: db.lockPath, Holder: strings.TrimSpace(string(holder))}
+		}
+
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	// Record who holds the lock, for the message other writers get
+	holder := fmt.Sprintf("instapaper-cli %s (pid %d, since %s)", command, os.Getpid(), time.Now().UTC().Format(time.RFC3339))
+	if err := file.Truncate(0); err == nil {
+		file.WriteAt([]byte(holder+"\n"), 0)
+	}
+
+	db.lockFile = file
+	db.lockDepth = 1
+	return &WriteLock{db: db}, nil
+}
+
+// Release gives up the lock once every nested holder in this process has released it
+func (l *WriteLock) Release() error {
+	if l == nil || l.db == nil {
+		return nil
+	}
+
+	db := l.db
+	db.lockMu.Lock()
+	defer db.lockMu.Unlock()
+
+	l.db = nil
+	if db.lockDepth--; db.lockDepth > 0 {
+		return nil
+	}
+
+	file := db.lockFile
+	db.lockFile = nil
+	file.Truncate(0)
+	if err := unlockFile(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to unlock %s: %w", db.lockPath, err)
+	}
+	return file.Close()
+}
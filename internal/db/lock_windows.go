@@ -0,0 +1,29 @@
+//go:build windows
+
+package db
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockOffset places the locked byte far past the holder text, since Windows locks are mandatory
+// and would otherwise stop waiting writers from reading who holds the lock
+var lockOffset = windows.Overlapped{OffsetHigh: 1}
+
+// tryLockFile takes an exclusive lock on file, reporting false when another process holds it
+func tryLockFile(file *os.File) (bool, error) {
+	overlapped := lockOffset
+	err := windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &overlapped)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlockFile(file *os.File) error {
+	overlapped := lockOffset
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &overlapped)
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"instapaper-cli/migrations"
+)
+
+// TestRecanonicalizeMergeIsAtomic makes the last step of a duplicate merge fail and checks that
+// the steps before it are rolled back
+func TestRecanonicalizeMergeIsAtomic(t *testing.T) {
+	database, err := New(filepath.Join(t.TempDir(), "merge.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	if err := database.RunMigrationsFS(migrations.FS); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, url := range map[int64]string{1: "https://example.com/a", 2: "https://example.com/a?utm_source=feed"} {
+		if _, err := database.Exec(`
+			INSERT INTO articles (id, url, title, instapapered_at) VALUES (?, ?, 'Article', '2024-01-01T00:00:00Z')
+		`, id, url); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.AddArticleTags(2, []string{"go"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.ReplaceHighlights(2, []string{"A passage."}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec(`
+		CREATE TRIGGER fail_delete BEFORE DELETE ON articles BEGIN SELECT RAISE(ABORT, 'delete failed'); END
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	stripQuery := func(url string) (string, error) {
+		base, _, _ := strings.Cut(url, "?")
+		return base, nil
+	}
+	if _, err := database.RecanonicalizeURLs(context.Background(), stripQuery); err == nil {
+		t.Fatal("RecanonicalizeURLs succeeded, want the merge to fail")
+	}
+
+	if got := tagsOf(t, database, 1); len(got) != 0 {
+		t.Errorf("article 1 tags = %v after a failed merge, want none", got)
+	}
+	highlights, err := database.GetHighlights(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(highlights, []string{"A passage."}) {
+		t.Errorf("article 2 highlights = %q after a failed merge, want them kept", highlights)
+	}
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"instapaper-cli/internal/util"
+	"instapaper-cli/migrations"
+)
+
+// migrationsBefore returns the embedded migrations older than version
+func migrationsBefore(t *testing.T, version string) fs.FS {
+	t.Helper()
+
+	entries, err := fs.ReadDir(migrations.FS, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	older := fstest.MapFS{}
+	for _, entry := range entries {
+		if entry.Name() >= version {
+			continue
+		}
+		data, err := fs.ReadFile(migrations.FS, entry.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		older[entry.Name()] = &fstest.MapFile{Data: data}
+	}
+	return older
+}
+
+// TestMigrationsSplitBackfilledHighlights upgrades a database from before highlights existed and
+// checks that each selection ends up split the way imports split it
+func TestMigrationsSplitBackfilledHighlights(t *testing.T) {
+	database, err := New(filepath.Join(t.TempDir(), "upgrade.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	if err := database.RunMigrationsFS(migrationsBefore(t, "0006")); err != nil {
+		t.Fatal(err)
+	}
+
+	selections := map[int64]string{
+		1: "First passage.\n\nSecond passage.\n---\nThird passage.",
+		2: "A single passage.",
+		3: "   ",
+	}
+	for id, selection := range selections {
+		if _, err := database.Exec(`
+			INSERT INTO articles (id, url, title, selection, instapapered_at)
+			VALUES (?, ?, 'Article', ?, '2024-01-01 00:00:00')
+		`, id, fmt.Sprintf("https://example.com/%d", id), selection); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := database.RunMigrationsFS(migrations.FS); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, selection := range selections {
+		got, err := database.GetHighlights(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := util.ParseHighlights(selection); !reflect.DeepEqual(got, want) {
+			t.Errorf("highlights of article %d = %q, want %q", id, got, want)
+		}
+	}
+}
+
+// TestMigrationsKeepImportedHighlights checks that highlights written by an import, which are
+// already split, are left alone
+func TestMigrationsKeepImportedHighlights(t *testing.T) {
+	database, err := New(filepath.Join(t.TempDir(), "upgrade.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	if err := database.RunMigrationsFS(migrationsBefore(t, "0032")); err != nil {
+		t.Fatal(err)
+	}
+
+	selection := "One.\n\nTwo."
+	if _, err := database.Exec(`
+		INSERT INTO articles (id, url, title, selection, instapapered_at)
+		VALUES (1, 'https://example.com/a', 'Article', ?, '2024-01-01 00:00:00')
+	`, selection); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.ReplaceHighlights(1, []string{"Two."}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.RunMigrationsFS(migrations.FS); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := database.GetHighlights(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Two."}; !reflect.DeepEqual(got, want) {
+		t.Errorf("highlights = %q, want %q", got, want)
+	}
+}
+
+// TestMigrationsCanonicalizeBackfilledURLAliases checks that the final and canonical URLs 0027
+// backfills resolve to their article once looked up in canonical form
+func TestMigrationsCanonicalizeBackfilledURLAliases(t *testing.T) {
+	database, err := New(filepath.Join(t.TempDir(), "upgrade.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	if err := database.RunMigrationsFS(migrationsBefore(t, "0027")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec(`
+		INSERT INTO articles (id, url, title, final_url, canonical_url, instapapered_at)
+		VALUES (1, 'https://example.com/a', 'Article', 'https://example.com/b?utm_source=feed', 'https://example.com/c?fbclid=x', '2024-01-01T00:00:00Z')
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.RunMigrationsFS(migrations.FS); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, raw := range []string{"https://example.com/b?utm_source=feed", "https://example.com/c?fbclid=x"} {
+		canonical, err := util.CanonicalizeURL(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id, err := database.FindArticleByURL(canonical); err != nil || id != 1 {
+			t.Errorf("FindArticleByURL(%s) = %d, %v, want article 1", canonical, id, err)
+		}
+	}
+
+	aliases, err := database.URLAliases(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, alias := range aliases {
+		if _, err := time.Parse(time.RFC3339, alias.CreatedAt); err != nil {
+			t.Errorf("alias %s created_at = %q, want RFC 3339", alias.URL, alias.CreatedAt)
+		}
+	}
+}
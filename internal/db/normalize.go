@@ -0,0 +1,24 @@
+package db
+
+import (
+	"database/sql/driver"
+
+	"instapaper-cli/internal/util"
+
+	"modernc.org/sqlite"
+)
+
+func init() {
+	// normalize_text(text) lowercases text and strips its diacritics, for the title_norm column
+	// and the patterns matched against it
+	sqlite.MustRegisterDeterministicScalarFunction("normalize_text", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		switch value := args[0].(type) {
+		case string:
+			return util.NormalizeText(value), nil
+		case []byte:
+			return util.NormalizeText(string(value)), nil
+		default:
+			return value, nil
+		}
+	})
+}
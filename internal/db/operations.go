@@ -0,0 +1,426 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operation kinds recorded in the operations journal
+const (
+	OperationObsolete  = "obsolete"
+	OperationRestore   = "restore"
+	OperationBulk      = "bulk"
+	OperationTagRename = "tag_rename"
+	OperationTagMerge  = "tag_merge"
+	OperationStatus    = "status"
+
+	OperationFolderRename = "folder_rename"
+	OperationEmptyTrash   = "empty_trash"
+)
+
+// Journaled change fields; before and after hold the value on each side of the change
+const (
+	ChangeObsolete   = "obsolete"    // "0" or "1"
+	ChangeFolder     = "folder_id"   // folder ID, NULL for none
+	ChangeTagAdded   = "tag_added"   // after is the tag title
+	ChangeTagRemoved = "tag_removed" // before is the tag title
+	ChangeTagTitle   = "tag_title"   // a tag's title, not tied to an article
+	ChangeStatus     = "status"      // reading status
+
+	ChangeFolderTitle    = "folder_title"    // a folder's path, not tied to an article
+	ChangeArticleDeleted = "article_deleted" // before is the deleted article's ID, URL, and title
+	ChangeTagAlias       = "tag_alias"       // a tag alias retargeted by a merge: before is its old target, after the alias
+)
+
+// Operation is a journaled bulk operation
+type Operation struct {
+	ID          int64   `db:"id" json:"id"`
+	Kind        string  `db:"kind" json:"kind"`
+	Description *string `db:"description" json:"description,omitempty"`
+	CreatedAt   string  `db:"created_at" json:"created_at"`
+	UndoneAt    *string `db:"undone_at" json:"undone_at,omitempty"`
+	Changes     int     `db:"changes" json:"changes"`
+	Articles    int     `db:"articles" json:"articles"`
+}
+
+// OperationChange is one journaled change, reverted by undo
+type OperationChange struct {
+	ID        int64   `db:"id" json:"id"`
+	ArticleID *int64  `db:"article_id" json:"article_id,omitempty"`
+	Field     string  `db:"field" json:"field"`
+	Before    *string `db:"before_value" json:"before,omitempty"`
+	After     *string `db:"after_value" json:"after,omitempty"`
+}
+
+// BeginOperation starts a journal entry; pass its ID to the methods that record changes under it
+func (db *DB) BeginOperation(kind, description string) (int64, error) {
+	result, err := db.Exec(`
+		INSERT INTO operations (kind, description, created_at)
+		VALUES (?, NULLIF(?, ''), ?)
+	`, kind, description, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, fmt.Errorf("failed to record operation: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+func (db *DB) journal(operationID int64, articleID *int64, field string, before, after *string) error {
+	if operationID == 0 {
+		return nil
+	}
+	_, err := db.Exec(`
+		INSERT INTO operation_changes (operation_id, article_id, field, before_value, after_value)
+		VALUES (?, ?, ?, ?, ?)
+	`, operationID, articleID, field, before, after)
+	if err != nil {
+		return fmt.Errorf("failed to journal %s change: %w", field, err)
+	}
+	return nil
+}
+
+// RetagArticle removes and adds tags on an article, journaling only the tags that actually changed.
+// It reports whether anything changed.
+func (db *DB) RetagArticle(operationID, articleID int64, add, remove []string) (bool, error) {
+	var current []string
+	if err := db.Select(&current, `
+		SELECT t.title FROM tags t
+		JOIN article_tags at ON at.tag_id = t.id
+		WHERE at.article_id = ?
+	`, articleID); err != nil {
+		return false, fmt.Errorf("failed to get tags of article %d: %w", articleID, err)
+	}
+
+	has := make(map[string]bool, len(current))
+	for _, title := range current {
+		has[title] = true
+	}
+
+	var removed, added []string
+	for _, title := range remove {
+		if has[title] {
+			removed = append(removed, title)
+			delete(has, title)
+		}
+	}
+	for _, title := range add {
+		if !has[title] {
+			added = append(added, title)
+			has[title] = true
+		}
+	}
+
+	if len(removed) == 0 && len(added) == 0 {
+		return false, nil
+	}
+
+	if err := db.RemoveArticleTags(articleID, removed); err != nil {
+		return false, err
+	}
+	if err := db.AddArticleTags(articleID, added); err != nil {
+		return false, err
+	}
+
+	for _, title := range removed {
+		if err := db.journal(operationID, &articleID, ChangeTagRemoved, &title, nil); err != nil {
+			return true, err
+		}
+	}
+	for _, title := range added {
+		if err := db.journal(operationID, &articleID, ChangeTagAdded, nil, &title); err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
+
+// RenameTag renames a tag, or merges it into the tag already named new: its articles get the new
+// tag and the old one is deleted. It reports whether the tags were merged and how many articles
+// the tag was on.
+func (db *DB) RenameTag(operationID int64, old, new string) (bool, int, error) {
+	var oldID int64
+	if err := db.Get(&oldID, "SELECT id FROM tags WHERE title = ?", old); err == sql.ErrNoRows {
+		return false, 0, fmt.Errorf("tag '%s' not found", old)
+	} else if err != nil {
+		return false, 0, fmt.Errorf("failed to find tag: %w", err)
+	}
+
+	var articleIDs []int64
+	if err := db.Select(&articleIDs, "SELECT article_id FROM article_tags WHERE tag_id = ? ORDER BY article_id", oldID); err != nil {
+		return false, 0, fmt.Errorf("failed to get tagged articles: %w", err)
+	}
+
+	var newID int64
+	err := db.Get(&newID, "SELECT id FROM tags WHERE title = ?", new)
+	if err == sql.ErrNoRows {
+		if _, err := db.Exec("UPDATE tags SET title = ? WHERE id = ?", new, oldID); err != nil {
+			return false, 0, fmt.Errorf("failed to rename tag: %w", err)
+		}
+		for _, id := range articleIDs {
+			if err := db.UpsertArticleFTS(id); err != nil {
+				return false, 0, fmt.Errorf("failed to update FTS for article %d: %w", id, err)
+			}
+		}
+		if err := db.retargetAliases(AliasTag, old, new); err != nil {
+			return false, 0, err
+		}
+		return false, len(articleIDs), db.journal(operationID, nil, ChangeTagTitle, &old, &new)
+	} else if err != nil {
+		return false, 0, fmt.Errorf("failed to find tag: %w", err)
+	}
+
+	for _, id := range articleIDs {
+		if _, err := db.RetagArticle(operationID, id, []string{new}, []string{old}); err != nil {
+			return true, 0, fmt.Errorf("failed to merge tag on article %d: %w", id, err)
+		}
+	}
+	if _, err := db.Exec("DELETE FROM tags WHERE id = ?", oldID); err != nil {
+		return true, 0, fmt.Errorf("failed to delete merged tag: %w", err)
+	}
+
+	// The merged tag's aliases move to the tag it was merged into; undo moves them back
+	var aliases []string
+	if err := db.Select(&aliases, "SELECT alias FROM aliases WHERE kind = ? AND target = ? ORDER BY alias", AliasTag, old); err != nil {
+		return true, 0, fmt.Errorf("failed to get aliases: %w", err)
+	}
+	if err := db.retargetAliases(AliasTag, old, new); err != nil {
+		return true, 0, err
+	}
+	for _, alias := range aliases {
+		if err := db.journal(operationID, nil, ChangeTagAlias, &old, &alias); err != nil {
+			return true, 0, err
+		}
+	}
+
+	return true, len(articleIDs), nil
+}
+
+// RenameFolder gives a folder a new title, refreshes the paths of it and its subfolders, and
+// reindexes the articles filed in them so FTS matches the new folder name. It returns the old and
+// new path of the folder and how many articles were reindexed.
+func (db *DB) RenameFolder(operationID, id int64, title string) (string, string, int, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return "", "", 0, fmt.Errorf("folder name cannot be empty")
+	}
+	if strings.Contains(title, "/") {
+		return "", "", 0, fmt.Errorf("folder name %q cannot contain a slash", title)
+	}
+
+	var folder struct {
+		Title    string `db:"title"`
+		ParentID *int64 `db:"parent_id"`
+		Path     string `db:"path"`
+	}
+	err := db.Get(&folder, "SELECT title, parent_id, COALESCE(path_cache, title) AS path FROM folders WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return "", "", 0, fmt.Errorf("folder %d not found", id)
+	} else if err != nil {
+		return "", "", 0, fmt.Errorf("failed to find folder: %w", err)
+	}
+	if folder.Title == title {
+		return folder.Path, folder.Path, 0, nil
+	}
+
+	newPath := title
+	if i := strings.LastIndex(folder.Path, "/"); i >= 0 {
+		newPath = folder.Path[:i+1] + title
+	}
+
+	var taken bool
+	if err := db.Get(&taken, "SELECT EXISTS(SELECT 1 FROM folders WHERE title = ? AND parent_id IS ? AND id != ?)", title, folder.ParentID, id); err != nil {
+		return "", "", 0, fmt.Errorf("failed to look up folder: %w", err)
+	}
+	if taken {
+		return "", "", 0, fmt.Errorf("folder '%s' already exists", newPath)
+	}
+	if target, aliased, err := db.ResolveAlias(AliasFolder, newPath); err != nil {
+		return "", "", 0, err
+	} else if aliased {
+		return "", "", 0, fmt.Errorf("'%s' is an alias of folder '%s'; remove the alias first", newPath, target)
+	}
+
+	var articleIDs []int64
+	if err := db.Select(&articleIDs, `
+		WITH RECURSIVE subtree(id) AS (
+			SELECT ?
+			UNION ALL
+			SELECT f.id FROM folders f JOIN subtree s ON f.parent_id = s.id
+		)
+		SELECT id FROM articles WHERE folder_id IN (SELECT id FROM subtree) ORDER BY id
+	`, id); err != nil {
+		return "", "", 0, fmt.Errorf("failed to get articles in folder: %w", err)
+	}
+
+	if _, err := db.Exec("UPDATE folders SET title = ? WHERE id = ?", title, id); err != nil {
+		return "", "", 0, fmt.Errorf("failed to rename folder: %w", err)
+	}
+	if err := db.UpdateFolderPaths(); err != nil {
+		return "", "", 0, fmt.Errorf("failed to update folder paths: %w", err)
+	}
+	for _, articleID := range articleIDs {
+		if err := db.UpsertArticleFTS(articleID); err != nil {
+			return "", "", 0, fmt.Errorf("failed to update FTS for article %d: %w", articleID, err)
+		}
+	}
+
+	// Aliases of the folder and of its subfolders follow the new path
+	if _, err := db.Exec(`
+		UPDATE aliases SET target = ? || substr(target, length(?) + 1)
+		WHERE kind = ? AND (target = ? OR substr(target, 1, length(?)) = ?)
+	`, newPath, folder.Path, AliasFolder, folder.Path, folder.Path+"/", folder.Path+"/"); err != nil {
+		return "", "", 0, fmt.Errorf("failed to update aliases: %w", err)
+	}
+
+	return folder.Path, newPath, len(articleIDs), db.journal(operationID, nil, ChangeFolderTitle, &folder.Path, &newPath)
+}
+
+// Operations returns the most recent journaled operations, newest first
+func (db *DB) Operations(limit int) ([]Operation, error) {
+	operations := []Operation{}
+	err := db.Select(&operations, `
+		SELECT o.id, o.kind, o.description, o.created_at, o.undone_at,
+		       COUNT(c.id) AS changes, COUNT(DISTINCT c.article_id) + COUNT(CASE WHEN c.field = 'article_deleted' THEN 1 END) AS articles
+		FROM operations o
+		LEFT JOIN operation_changes c ON c.operation_id = o.id
+		GROUP BY o.id
+		ORDER BY o.id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get operations: %w", err)
+	}
+	return operations, nil
+}
+
+// GetOperation returns a journaled operation and its changes in the order they were made
+func (db *DB) GetOperation(id int64) (*Operation, []OperationChange, error) {
+	var operation Operation
+	err := db.Get(&operation, `
+		SELECT o.id, o.kind, o.description, o.created_at, o.undone_at,
+		       COUNT(c.id) AS changes, COUNT(DISTINCT c.article_id) + COUNT(CASE WHEN c.field = 'article_deleted' THEN 1 END) AS articles
+		FROM operations o
+		LEFT JOIN operation_changes c ON c.operation_id = o.id
+		WHERE o.id = ?
+		GROUP BY o.id
+	`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil, fmt.Errorf("operation %d not found", id)
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to get operation: %w", err)
+	}
+
+	changes := []OperationChange{}
+	if err := db.Select(&changes, `
+		SELECT id, article_id, field, before_value, after_value
+		FROM operation_changes
+		WHERE operation_id = ?
+		ORDER BY id
+	`, id); err != nil {
+		return nil, nil, fmt.Errorf("failed to get operation changes: %w", err)
+	}
+
+	return &operation, changes, nil
+}
+
+// UndoOperation reverts an operation's changes, newest first, and marks it undone, all in one
+// transaction, so a failed undo changes nothing. Articles deleted since have no changes left to
+// revert, and emptying the trash cannot be undone.
+func (db *DB) UndoOperation(id int64) (int, error) {
+	operation, changes, err := db.GetOperation(id)
+	if err != nil {
+		return 0, err
+	}
+	if operation.UndoneAt != nil {
+		return 0, fmt.Errorf("operation %d was already undone at %s", id, *operation.UndoneAt)
+	}
+	if operation.Kind == OperationEmptyTrash {
+		return 0, fmt.Errorf("operation %d deleted articles for good and cannot be undone", id)
+	}
+
+	audit := ObsoleteAudit{Reason: fmt.Sprintf("undo of operation %d", id), Criteria: fmt.Sprintf("--operation-id %d", id)}
+
+	err = db.InTx(context.Background(), func(tx *DB) error {
+		for i := len(changes) - 1; i >= 0; i-- {
+			if err := tx.revertChange(changes[i], audit); err != nil {
+				return fmt.Errorf("failed to undo change %d: %w", changes[i].ID, err)
+			}
+		}
+
+		if _, err := tx.Exec("UPDATE operations SET undone_at = ? WHERE id = ?", time.Now().UTC().Format(time.RFC3339), id); err != nil {
+			return fmt.Errorf("failed to mark operation undone: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(changes), nil
+}
+
+func (db *DB) revertChange(change OperationChange, audit ObsoleteAudit) error {
+	value := func(v *string) string {
+		if v == nil {
+			return ""
+		}
+		return *v
+	}
+
+	if change.Field == ChangeTagTitle {
+		_, _, err := db.RenameTag(0, value(change.After), value(change.Before))
+		return err
+	}
+	if change.Field == ChangeTagAlias {
+		_, err := db.Exec("UPDATE aliases SET target = ? WHERE kind = ? AND alias = ?", value(change.Before), AliasTag, value(change.After))
+		if err != nil {
+			return fmt.Errorf("failed to restore alias: %w", err)
+		}
+		return nil
+	}
+	if change.Field == ChangeFolderTitle {
+		id, err := db.FindFolder(value(change.After))
+		if err != nil {
+			return err
+		}
+		titles := SplitFolderPath(value(change.Before))
+		if len(titles) == 0 {
+			return fmt.Errorf("%s change has no previous path", change.Field)
+		}
+		_, _, _, err = db.RenameFolder(0, id, titles[len(titles)-1])
+		return err
+	}
+
+	if change.ArticleID == nil {
+		return fmt.Errorf("%s change has no article", change.Field)
+	}
+	articleID := *change.ArticleID
+
+	switch change.Field {
+	case ChangeObsolete:
+		_, err := db.SetArticlesObsolete([]int64{articleID}, value(change.Before) == "1", audit)
+		return err
+	case ChangeFolder:
+		var folderID *int64
+		if change.Before != nil {
+			id, err := strconv.ParseInt(*change.Before, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid folder ID %q: %w", *change.Before, err)
+			}
+			folderID = &id
+		}
+		_, err := db.SetArticlesFolder([]int64{articleID}, folderID, 0)
+		return err
+	case ChangeTagAdded:
+		return db.RemoveArticleTags(articleID, []string{value(change.After)})
+	case ChangeTagRemoved:
+		return db.AddArticleTags(articleID, []string{value(change.Before)})
+	case ChangeStatus:
+		_, err := db.SetArticlesStatus([]int64{articleID}, value(change.Before), 0)
+		return err
+	}
+	return fmt.Errorf("unknown change field %q", change.Field)
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"instapaper-cli/migrations"
+)
+
+// newOperationsTestDB returns a migrated database with article 1 tagged golang, tag go on article
+// 2, and the alias gopher for golang
+func newOperationsTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	database, err := New(filepath.Join(t.TempDir(), "operations.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := database.RunMigrationsFS(migrations.FS); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, tag := range map[int64]string{1: "golang", 2: "go"} {
+		if _, err := database.Exec(`
+			INSERT INTO articles (id, url, title, instapapered_at) VALUES (?, ?, 'Article', '2024-01-01T00:00:00Z')
+		`, id, "https://example.com/"+tag); err != nil {
+			t.Fatal(err)
+		}
+		if err := database.AddArticleTags(id, []string{tag}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.AddAlias(AliasTag, "gopher", "golang"); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+func tagsOf(t *testing.T, database *DB, articleID int64) []string {
+	t.Helper()
+
+	var tags []string
+	if err := database.Select(&tags, `
+		SELECT t.title FROM tags t JOIN article_tags at ON at.tag_id = t.id WHERE at.article_id = ?
+	`, articleID); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// TestUndoTagMergeRestoresAliases merges golang into go and checks that undo moves golang's
+// alias back along with the tag
+func TestUndoTagMergeRestoresAliases(t *testing.T) {
+	database := newOperationsTestDB(t)
+
+	operationID, err := database.BeginOperation(OperationTagMerge, "merge golang into go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged, _, err := database.RenameTag(operationID, "golang", "go"); err != nil || !merged {
+		t.Fatalf("RenameTag = %v, %v, want a merge", merged, err)
+	}
+	if target, _, _ := database.ResolveAlias(AliasTag, "gopher"); target != "go" {
+		t.Fatalf("gopher resolves to %q after the merge, want go", target)
+	}
+
+	if _, err := database.UndoOperation(operationID); err != nil {
+		t.Fatal(err)
+	}
+	if target, _, _ := database.ResolveAlias(AliasTag, "gopher"); target != "golang" {
+		t.Errorf("gopher resolves to %q after undo, want golang", target)
+	}
+	if got := tagsOf(t, database, 1); !reflect.DeepEqual(got, []string{"golang"}) {
+		t.Errorf("article 1 tags = %v after undo, want [golang]", got)
+	}
+}
+
+// TestUndoOperationIsAtomic checks that an undo failing partway through changes nothing
+func TestUndoOperationIsAtomic(t *testing.T) {
+	database := newOperationsTestDB(t)
+
+	operationID, err := database.BeginOperation(OperationBulk, "retag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Reverted last, so the retag below is reverted before the undo fails
+	if err := database.journal(operationID, nil, "unknown_field", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.RetagArticle(operationID, 2, []string{"db"}, []string{"go"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := database.UndoOperation(operationID); err == nil {
+		t.Fatal("UndoOperation succeeded, want an error")
+	}
+	if got := tagsOf(t, database, 2); !reflect.DeepEqual(got, []string{"db"}) {
+		t.Errorf("article 2 tags = %v after a failed undo, want [db]", got)
+	}
+	operation, _, err := database.GetOperation(operationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if operation.UndoneAt != nil {
+		t.Errorf("operation marked undone at %s after a failed undo", *operation.UndoneAt)
+	}
+}
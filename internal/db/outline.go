@@ -0,0 +1,55 @@
+package db
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"instapaper-cli/internal/model"
+	"instapaper-cli/internal/util"
+
+	"modernc.org/sqlite"
+)
+
+func init() {
+	// markdown_outline(markdown) stores the headings of article content as JSON, for the outline column
+	sqlite.MustRegisterDeterministicScalarFunction("markdown_outline", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		text, ok := textValue(args[0])
+		if !ok {
+			return nil, nil
+		}
+		return OutlineJSON(text), nil
+	})
+}
+
+// OutlineJSON encodes the outline of markdown content for the outline column
+func OutlineJSON(markdown string) string {
+	data, err := json.Marshal(util.ParseOutline(markdown))
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// Outline returns the headings of an article's content, or nil when it has not been fetched
+func (db *DB) Outline(articleID int64) ([]model.Heading, error) {
+	var stored sql.NullString
+	err := db.Get(&stored, "SELECT outline FROM articles WHERE id = ?", articleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("article %d not found", articleID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outline: %w", err)
+	}
+	if !stored.Valid {
+		return nil, nil
+	}
+
+	headings := []model.Heading{}
+	if err := json.Unmarshal([]byte(stored.String), &headings); err != nil {
+		return nil, fmt.Errorf("failed to parse outline: %w", err)
+	}
+	return headings, nil
+}
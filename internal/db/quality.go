@@ -0,0 +1,29 @@
+package db
+
+import "fmt"
+
+// LowQualityThreshold is the extraction quality below which fetched content is probably not the
+// article: a cookie wall, an index page, or a fragment
+const LowQualityThreshold = 0.4
+
+// LowQualityArticle is a fetched article whose content is probably an extraction failure
+type LowQualityArticle struct {
+	ID      int64   `db:"id" json:"id"`
+	Title   string  `db:"title" json:"title"`
+	URL     string  `db:"url" json:"url"`
+	Quality float64 `db:"extraction_quality" json:"extraction_quality"`
+}
+
+// LowQualityArticles lists the active fetched articles scored below LowQualityThreshold, worst first
+func (db *DB) LowQualityArticles() ([]LowQualityArticle, error) {
+	articles := []LowQualityArticle{}
+	if err := db.Select(&articles, `
+		SELECT id, title, url, extraction_quality
+		FROM articles
+		WHERE obsolete = FALSE AND synced_at IS NOT NULL AND extraction_quality < ?
+		ORDER BY extraction_quality, id
+	`, LowQualityThreshold); err != nil {
+		return nil, fmt.Errorf("failed to get low-quality articles: %w", err)
+	}
+	return articles, nil
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"modernc.org/sqlite"
+)
+
+// maxCachedPatterns bounds the compiled patterns kept for REGEXP; a query runs the same pattern
+// against every row, so only the latest few are worth keeping
+const maxCachedPatterns = 32
+
+var (
+	patternsMu sync.Mutex
+	patterns   = map[string]*regexp.Regexp{}
+)
+
+func init() {
+	// regexp(pattern, text) backs SQLite's "text REGEXP pattern" operator with Go's RE2 syntax.
+	// A NULL text matches nothing.
+	sqlite.MustRegisterDeterministicScalarFunction("regexp", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		pattern, ok := textValue(args[0])
+		if !ok {
+			return nil, nil
+		}
+		text, ok := textValue(args[1])
+		if !ok {
+			return nil, nil
+		}
+
+		re, err := compilePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(text), nil
+	})
+}
+
+// compilePattern compiles a REGEXP pattern, reusing it for the rest of the query
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	patternsMu.Lock()
+	defer patternsMu.Unlock()
+
+	if re, ok := patterns[pattern]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REGEXP pattern: %w", err)
+	}
+	if len(patterns) >= maxCachedPatterns {
+		clear(patterns)
+	}
+	patterns[pattern] = re
+	return re, nil
+}
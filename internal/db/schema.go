@@ -0,0 +1,85 @@
+package db
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// SchemaInfo compares a database's schema with the migrations this build knows about
+type SchemaInfo struct {
+	// Version is the highest migration applied to the database, 0 for a new database
+	Version int `json:"version"`
+	// Supported is the highest migration this build knows about
+	Supported int    `json:"supported"`
+	AppliedAt string `json:"applied_at,omitempty"`
+	// Pending lists known migrations not applied yet
+	Pending []string `json:"pending,omitempty"`
+	// Unknown lists applied migrations this build does not know, from a newer release
+	Unknown    []string `json:"unknown,omitempty"`
+	Compatible bool     `json:"compatible"`
+}
+
+// SchemaTooNewError means the database was migrated by a newer release than this one, whose schema
+// this release may misread or damage
+type SchemaTooNewError struct {
+	Version   int
+	Supported int
+}
+
+func (e *SchemaTooNewError) Error() string {
+	return fmt.Sprintf("database schema version %d is newer than this release supports (%d): upgrade instapaper-cli, or use a backup made with this release", e.Version, e.Supported)
+}
+
+// SchemaVersion reports the database's schema version against the migrations in fsys
+func (db *DB) SchemaVersion(fsys fs.FS) (*SchemaInfo, error) {
+	if err := db.createMigrationsTable(); err != nil {
+		return nil, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	migrations, err := getMigrationFiles(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration files: %w", err)
+	}
+
+	return db.schemaInfo(migrations)
+}
+
+func (db *DB) schemaInfo(migrations []migration) (*SchemaInfo, error) {
+	var applied []struct {
+		Version   int     `db:"version"`
+		Name      string  `db:"name"`
+		AppliedAt *string `db:"applied_at"`
+	}
+	if err := db.Select(&applied, "SELECT version, name, applied_at FROM migrations ORDER BY version"); err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	info := &SchemaInfo{}
+	known := make(map[string]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.name] = true
+		info.Supported = max(info.Supported, m.version)
+	}
+
+	appliedNames := make(map[string]bool, len(applied))
+	for _, m := range applied {
+		appliedNames[m.Name] = true
+		if !known[m.Name] {
+			info.Unknown = append(info.Unknown, m.Name)
+		}
+		if m.Version >= info.Version {
+			info.Version = m.Version
+			if m.AppliedAt != nil {
+				info.AppliedAt = *m.AppliedAt
+			}
+		}
+	}
+	for _, m := range migrations {
+		if !appliedNames[m.name] {
+			info.Pending = append(info.Pending, m.name)
+		}
+	}
+
+	info.Compatible = info.Version <= info.Supported
+	return info, nil
+}
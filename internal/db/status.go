@@ -0,0 +1,103 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Reading statuses. New articles start in the inbox, move to reading or someday when triaged,
+// and end up read and then archived; any status can be set from any other.
+const (
+	StatusInbox    = "inbox"
+	StatusReading  = "reading"
+	StatusSomeday  = "someday"
+	StatusRead     = "read"
+	StatusArchived = "archived"
+)
+
+// Statuses lists the reading statuses in lifecycle order
+var Statuses = []string{StatusInbox, StatusReading, StatusSomeday, StatusRead, StatusArchived}
+
+// StatusCount is the number of active articles with a reading status
+type StatusCount struct {
+	Status string `db:"status" json:"status"`
+	Count  int    `db:"count" json:"count"`
+}
+
+// ValidateStatuses checks that every status is one of Statuses
+func ValidateStatuses(statuses ...string) error {
+	for _, status := range statuses {
+		valid := false
+		for _, known := range Statuses {
+			if status == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid status %q: use %s", status, strings.Join(Statuses, ", "))
+		}
+	}
+	return nil
+}
+
+// SetArticlesStatus sets the reading status of articles, journaling each change under the
+// operation, and returns how many articles changed. Missing articles are skipped.
+func (db *DB) SetArticlesStatus(ids []int64, status string, operationID int64) (int64, error) {
+	if err := ValidateStatuses(status); err != nil {
+		return 0, err
+	}
+
+	var updated int64
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	for _, id := range ids {
+		var previous string
+		if err := db.Get(&previous, "SELECT status FROM articles WHERE id = ?", id); err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			return updated, fmt.Errorf("failed to get status of article %d: %w", id, err)
+		}
+		if previous == status {
+			continue
+		}
+
+		// read_at keeps when the article was first read, through archiving, for reading goals
+		if _, err := db.Exec(`
+			UPDATE articles SET status = ?, status_changed_at = ?,
+				read_at = CASE ? WHEN 'read' THEN COALESCE(read_at, ?) WHEN 'archived' THEN read_at END
+			WHERE id = ?
+		`, status, now, status, now, id); err != nil {
+			return updated, fmt.Errorf("failed to update article %d: %w", id, err)
+		}
+		updated++
+
+		if err := db.journal(operationID, &id, ChangeStatus, &previous, &status); err != nil {
+			return updated, err
+		}
+	}
+
+	return updated, nil
+}
+
+// StatusCounts counts the active articles in each reading status, in lifecycle order.
+// Statuses without articles are included with a count of zero.
+func (db *DB) StatusCounts() ([]StatusCount, error) {
+	var found []StatusCount
+	if err := db.Select(&found, "SELECT status, COUNT(*) AS count FROM articles WHERE obsolete = FALSE GROUP BY status"); err != nil {
+		return nil, fmt.Errorf("failed to count statuses: %w", err)
+	}
+
+	byStatus := make(map[string]int, len(found))
+	for _, count := range found {
+		byStatus[count.Status] = count.Count
+	}
+
+	counts := make([]StatusCount, len(Statuses))
+	for i, status := range Statuses {
+		counts[i] = StatusCount{Status: status, Count: byStatus[status]}
+	}
+	return counts, nil
+}
@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// TrashedArticle is an article in the trash
+type TrashedArticle struct {
+	ID        int64   `db:"id" json:"id"`
+	URL       string  `db:"url" json:"url"`
+	Title     string  `db:"title" json:"title"`
+	Reason    *string `db:"reason" json:"reason,omitempty"`
+	TrashedAt string  `db:"trashed_at" json:"trashed_at"`
+}
+
+// articleTables are the tables whose rows belong to an article and go when it is deleted. Foreign
+// keys are only enforced on the connection that enabled them, so rows are not left to the cascade.
+var articleTables = []string{
+	"article_tags", "highlights", "attachments", "link_checks", "obsolete_log",
+	"operation_changes", "url_aliases", "trash",
+}
+
+// TrashArticles moves articles to the trash, hiding them everywhere obsolete articles are hidden
+// until they are restored or the trash is emptied. Articles already in the trash are skipped.
+func (db *DB) TrashArticles(ids []int64, reason string) (int64, error) {
+	var trashed int64
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	for _, id := range ids {
+		result, err := db.Exec(`
+			INSERT OR IGNORE INTO trash (article_id, was_obsolete, reason, trashed_at)
+			SELECT id, obsolete, NULLIF(?, ''), ? FROM articles WHERE id = ?
+		`, reason, now, id)
+		if err != nil {
+			return trashed, fmt.Errorf("failed to trash article %d: %w", id, err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			continue
+		}
+		trashed++
+
+		if _, err := db.Exec("UPDATE articles SET obsolete = TRUE WHERE id = ?", id); err != nil {
+			return trashed, fmt.Errorf("failed to hide article %d: %w", id, err)
+		}
+		if err := db.DeleteArticleFTS(id); err != nil {
+			return trashed, fmt.Errorf("failed to update FTS for article %d: %w", id, err)
+		}
+	}
+
+	return trashed, nil
+}
+
+// RestoreTrash takes articles out of the trash, making them obsolete again only if they were before
+// they were trashed. Articles not in the trash are skipped.
+func (db *DB) RestoreTrash(ids []int64) (int64, error) {
+	var restored int64
+
+	for _, id := range ids {
+		var wasObsolete bool
+		err := db.Get(&wasObsolete, "SELECT was_obsolete FROM trash WHERE article_id = ?", id)
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			return restored, fmt.Errorf("failed to find article %d in the trash: %w", id, err)
+		}
+
+		if _, err := db.Exec("DELETE FROM trash WHERE article_id = ?", id); err != nil {
+			return restored, fmt.Errorf("failed to restore article %d: %w", id, err)
+		}
+		if _, err := db.Exec("UPDATE articles SET obsolete = ? WHERE id = ?", wasObsolete, id); err != nil {
+			return restored, fmt.Errorf("failed to restore article %d: %w", id, err)
+		}
+		if !wasObsolete {
+			if err := db.UpsertArticleFTS(id); err != nil {
+				return restored, fmt.Errorf("failed to update FTS for article %d: %w", id, err)
+			}
+		}
+		restored++
+	}
+
+	return restored, nil
+}
+
+// Trash lists trashed articles, most recently trashed first. A zero before lists the whole trash,
+// otherwise only articles trashed before it.
+func (db *DB) Trash(before time.Time, limit int) ([]TrashedArticle, error) {
+	cutoff := "9999"
+	if !before.IsZero() {
+		cutoff = before.UTC().Format(time.RFC3339)
+	}
+	query := `
+		SELECT a.id, a.url, a.title, t.reason, t.trashed_at
+		FROM trash t
+		JOIN articles a ON a.id = t.article_id
+		WHERE t.trashed_at < ?
+		ORDER BY t.trashed_at DESC, a.id DESC
+	`
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	articles := []TrashedArticle{}
+	if err := db.Select(&articles, query, cutoff); err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+	return articles, nil
+}
+
+// EmptyTrash deletes the trashed articles returned by Trash(before, 0) for good, with their tags,
+// highlights, history, and attachment files no other article uses. Each article is deleted in its
+// own transaction, so an interrupted run can simply be repeated. With an operationID, a tombstone of
+// each article (its ID, URL, and title) is journaled; the rows themselves are gone, so the space
+// they took is freed and the operation cannot be undone.
+func (db *DB) EmptyTrash(ctx context.Context, operationID int64, before time.Time) ([]TrashedArticle, error) {
+	articles, err := db.Trash(before, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, article := range articles {
+		if err := ctx.Err(); err != nil {
+			return articles[:i], err
+		}
+
+		var attachments []Attachment
+		err := db.InTx(ctx, func(tx *DB) error {
+			if err := tx.Select(&attachments, "SELECT * FROM attachments WHERE article_id = ?", article.ID); err != nil {
+				return fmt.Errorf("failed to get attachments: %w", err)
+			}
+			if operationID != 0 {
+				data, err := json.Marshal(deletedArticle{ID: article.ID, URL: article.URL, Title: article.Title})
+				if err != nil {
+					return fmt.Errorf("failed to encode article tombstone: %w", err)
+				}
+				tombstone := string(data)
+				if err := tx.journal(operationID, nil, ChangeArticleDeleted, &tombstone, nil); err != nil {
+					return err
+				}
+			}
+			return tx.deleteArticle(article.ID)
+		})
+		if err != nil {
+			return articles[:i], fmt.Errorf("failed to delete article %d: %w", article.ID, err)
+		}
+
+		for _, attachment := range attachments {
+			db.removeUnusedAttachment(attachment)
+		}
+	}
+
+	return articles, nil
+}
+
+// deletedArticle is the journaled tombstone of an article deleted for good
+type deletedArticle struct {
+	ID    int64  `json:"id"`
+	URL   string `json:"url"`
+	Title string `json:"title"`
+}
+
+// DeletedArticle returns the ID and URL of the article in the tombstone of an article_deleted change
+func DeletedArticle(tombstone string) (int64, string, error) {
+	var article deletedArticle
+	if err := json.Unmarshal([]byte(tombstone), &article); err != nil {
+		return 0, "", fmt.Errorf("invalid article tombstone: %w", err)
+	}
+	return article.ID, article.URL, nil
+}
+
+// deleteArticle deletes an article and every row that belongs to it
+func (db *DB) deleteArticle(id int64) error {
+	if err := db.DeleteArticleFTS(id); err != nil {
+		return err
+	}
+	for _, table := range articleTables {
+		if _, err := db.Exec("DELETE FROM "+table+" WHERE article_id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete from %s: %w", table, err)
+		}
+	}
+	if _, err := db.Exec("UPDATE ingested_emails SET article_id = NULL WHERE article_id = ?", id); err != nil {
+		return fmt.Errorf("failed to unlink ingested emails: %w", err)
+	}
+	if _, err := db.Exec("DELETE FROM articles WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete article: %w", err)
+	}
+	return nil
+}
+
+// removeUnusedAttachment deletes an attachment's file once no article refers to its content
+func (db *DB) removeUnusedAttachment(attachment Attachment) {
+	var used bool
+	if err := db.Get(&used, "SELECT EXISTS (SELECT 1 FROM attachments WHERE hash = ?)", attachment.Hash); err != nil || used {
+		return
+	}
+	os.Remove(db.AttachmentPath(attachment))
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"instapaper-cli/migrations"
+)
+
+// TestEmptyTrashFreesArticleRows checks that emptying the trash leaves only a small tombstone per
+// article in the journal, not the article's content
+func TestEmptyTrashFreesArticleRows(t *testing.T) {
+	database, err := New(filepath.Join(t.TempDir(), "trash.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	if err := database.RunMigrationsFS(migrations.FS); err != nil {
+		t.Fatal(err)
+	}
+
+	content := strings.Repeat("A long article body. ", 5000)
+	if _, err := database.Exec(`
+		INSERT INTO articles (id, url, title, content_md, instapapered_at)
+		VALUES (1, 'https://example.com/a', 'Trashed', ?, '2024-01-01T00:00:00Z')
+	`, content); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.AddArticleTags(1, []string{"go"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.ReplaceHighlights(1, []string{"A long article body."}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.TrashArticles([]int64{1}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	operationID, err := database.BeginOperation(OperationEmptyTrash, "trash empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := database.EmptyTrash(context.Background(), operationID, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 {
+		t.Fatalf("EmptyTrash deleted %d articles, want 1", len(deleted))
+	}
+
+	for _, table := range append([]string{"articles"}, articleTables...) {
+		if table == "operation_changes" {
+			continue
+		}
+		var rows int
+		if err := database.Get(&rows, "SELECT COUNT(*) FROM "+table); err != nil {
+			t.Fatal(err)
+		}
+		if rows != 0 {
+			t.Errorf("%s has %d rows after emptying the trash, want 0", table, rows)
+		}
+	}
+
+	var journaled []OperationChange
+	if err := database.Select(&journaled, "SELECT id, article_id, field, before_value, after_value FROM operation_changes"); err != nil {
+		t.Fatal(err)
+	}
+	if len(journaled) != 1 || journaled[0].Before == nil {
+		t.Fatalf("journal = %+v, want one tombstone", journaled)
+	}
+	if size := len(*journaled[0].Before); size > 200 {
+		t.Errorf("tombstone is %d bytes, want the article's ID, URL, and title only", size)
+	}
+	if id, url, err := DeletedArticle(*journaled[0].Before); err != nil || id != 1 || url != "https://example.com/a" {
+		t.Errorf("DeletedArticle = %d, %q, %v, want 1, https://example.com/a", id, url, err)
+	}
+
+	if _, err := database.UndoOperation(operationID); err == nil {
+		t.Error("undoing an emptied trash succeeded, want an error")
+	}
+}
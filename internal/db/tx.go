@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// InTx runs fn in a transaction, committing when fn returns nil and rolling back otherwise. Queries
+// made through the DB passed to fn run in the transaction. Called on that DB, InTx runs fn in a
+// savepoint, so a failure only undoes fn's own changes and the outer transaction carries on.
+func (db *DB) InTx(ctx context.Context, fn func(tx *DB) error) error {
+	if db.tx != nil {
+		return db.inSavepoint(ctx, fn)
+	}
+
+	sqlTx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer sqlTx.Rollback()
+
+	// The write lock stays with db, whose caller holds it for the whole transaction
+	txDB := &DB{DB: db.DB, queryLog: db.queryLog, attachmentsDir: db.attachmentsDir, tx: sqlTx}
+	if err := fn(txDB); err != nil {
+		return err
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// inSavepoint runs fn in a savepoint of the transaction db is in. SQLite matches ROLLBACK TO and
+// RELEASE with the innermost savepoint of a name, so nested savepoints can share one.
+func (db *DB) inSavepoint(ctx context.Context, fn func(tx *DB) error) error {
+	if _, err := db.ExecContext(ctx, "SAVEPOINT nested"); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	if err := fn(db); err != nil {
+		// ctx may be what failed, and the savepoint has to go either way
+		if _, rbErr := db.ExecContext(context.Background(), "ROLLBACK TO nested"); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		db.ExecContext(context.Background(), "RELEASE nested")
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, "RELEASE nested"); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
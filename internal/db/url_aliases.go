@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"instapaper-cli/internal/util"
+)
+
+// URL alias kinds
+const (
+	// URLAliasRedirect is a URL that redirected to the article, such as a shortened or http link
+	URLAliasRedirect = "redirect"
+	// URLAliasCanonical is the canonical URL the article's page declares
+	URLAliasCanonical = "canonical"
+	// URLAliasPrevious is a URL the article was saved under before, or a merged duplicate's URL
+	URLAliasPrevious = "previous"
+)
+
+// URLAlias is another URL of an article
+type URLAlias struct {
+	URL       string `db:"url" json:"url"`
+	ArticleID int64  `db:"article_id" json:"article_id"`
+	Kind      string `db:"kind" json:"kind"`
+	CreatedAt string `db:"created_at" json:"created_at"`
+}
+
+// AddURLAliases makes each URL resolve to the article. URLs are canonicalized as article URLs are.
+// A URL that is already an article's URL or an alias is left as it is.
+func (db *DB) AddURLAliases(articleID int64, kind string, urls ...string) error {
+	for _, rawURL := range urls {
+		if rawURL == "" {
+			continue
+		}
+		aliasURL, err := util.CanonicalizeURL(rawURL)
+		if err != nil {
+			continue
+		}
+
+		if _, err := db.Exec(`
+			INSERT OR IGNORE INTO url_aliases (url, article_id, kind, created_at)
+			SELECT ?, ?, ?, ?
+			WHERE NOT EXISTS (SELECT 1 FROM articles WHERE url = ?)
+		`, aliasURL, articleID, kind, time.Now().UTC().Format(time.RFC3339), aliasURL); err != nil {
+			return fmt.Errorf("failed to add URL alias %s: %w", aliasURL, err)
+		}
+	}
+	return nil
+}
+
+// FindArticleByURL returns the ID of the article saved under the URL or one of its aliases, or
+// sql.ErrNoRows. The URL is expected in canonical form.
+func (db *DB) FindArticleByURL(url string) (int64, error) {
+	var id int64
+	err := db.Get(&id, `
+		SELECT id FROM (
+			SELECT id, 0 AS rank FROM articles WHERE url = ?
+			UNION ALL
+			SELECT article_id, 1 FROM url_aliases WHERE url = ?
+		)
+		ORDER BY rank
+		LIMIT 1
+	`, url, url)
+	return id, err
+}
+
+// URLAliases lists an article's other URLs, oldest first
+func (db *DB) URLAliases(ctx context.Context, articleID int64) ([]URLAlias, error) {
+	var aliases []URLAlias
+	if err := db.SelectContext(ctx, &aliases, `
+		SELECT url, article_id, kind, created_at FROM url_aliases
+		WHERE article_id = ?
+		ORDER BY created_at, url
+	`, articleID); err != nil {
+		return nil, fmt.Errorf("failed to get URL aliases: %w", err)
+	}
+	return aliases, nil
+}
@@ -0,0 +1,267 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"os/exec"
+	"strings"
+
+	"instapaper-cli/internal/metrics"
+	"instapaper-cli/internal/model"
+	"instapaper-cli/internal/util"
+)
+
+// ankiContentLimit caps how much article content is sent to the LLM command
+const ankiContentLimit = 8000
+
+// AnkiOptions selects the articles and cards written by ExportAnki
+type AnkiOptions struct {
+	ArticleID int64  // 0 exports every matching article
+	Tag       string // only articles with this tag
+	Deck      string
+	// LLMCommand, when set, is run with the article content on stdin (e.g. "llm -m gpt-4o-mini")
+	// to generate up to Questions question/answer cards per article
+	LLMCommand string
+	Questions  int
+}
+
+// AnkiResult summarizes an Anki export
+type AnkiResult struct {
+	Articles int               `json:"articles"`
+	Cards    int               `json:"cards"`
+	Errors   []model.ItemError `json:"errors,omitempty"`
+}
+
+type ankiCard struct {
+	guid  string
+	front string
+	back  string
+	tags  []string
+}
+
+// ExportAnki writes highlights (and optionally LLM-generated questions) as an Anki import file:
+// tab-separated Basic notes with a GUID column. GUIDs are derived from the article ID and the card's
+// highlight or question, so importing a re-export updates existing notes instead of adding
+// duplicates, even after highlights are added, removed, or reordered.
+func (e *Export) ExportAnki(ctx context.Context, w io.Writer, opts AnkiOptions) (*AnkiResult, error) {
+	metrics.ExportRuns.Inc("anki")
+
+	if opts.Deck == "" {
+		opts.Deck = "Instapaper"
+	}
+
+	ids, err := e.ankiArticleIDs(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get articles: %w", err)
+	}
+
+	result := &AnkiResult{}
+	var cards []ankiCard
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		article, err := e.getArticleWithDetails(ctx, id)
+		if err != nil {
+			return result, fmt.Errorf("failed to get article %d: %w", id, err)
+		}
+
+		articleCards := highlightCards(*article)
+
+		if opts.LLMCommand != "" && article.ContentMD != nil && *article.ContentMD != "" {
+			questions, err := generateQuestionCards(ctx, opts.LLMCommand, *article, opts.Questions)
+			if err != nil {
+				if ctx.Err() != nil {
+					return result, ctx.Err()
+				}
+				result.Errors = append(result.Errors, model.ItemError{ID: article.ID, URL: article.URL, Error: err.Error()})
+			}
+			articleCards = append(articleCards, questions...)
+		}
+
+		if len(articleCards) == 0 {
+			continue
+		}
+
+		result.Articles++
+		result.Cards += len(articleCards)
+		cards = append(cards, articleCards...)
+	}
+
+	if err := writeAnkiTSV(w, opts.Deck, cards); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// ankiArticleIDs returns the articles to consider: those with highlights, or all fetched
+// articles when questions are generated
+func (e *Export) ankiArticleIDs(ctx context.Context, opts AnkiOptions) ([]int64, error) {
+	query := `
+		SELECT DISTINCT a.id
+		FROM articles a
+		LEFT JOIN article_tags at ON a.id = at.article_id
+		LEFT JOIN tags t ON at.tag_id = t.id
+		WHERE a.obsolete = FALSE
+	`
+	var args []interface{}
+
+	if opts.LLMCommand != "" {
+		query += " AND (a.content_md IS NOT NULL OR EXISTS (SELECT 1 FROM highlights h WHERE h.article_id = a.id))"
+	} else {
+		query += " AND EXISTS (SELECT 1 FROM highlights h WHERE h.article_id = a.id)"
+	}
+
+	if opts.ArticleID != 0 {
+		query += " AND a.id = ?"
+		args = append(args, opts.ArticleID)
+	}
+
+	if opts.Tag != "" {
+		query += " AND t.title = ?"
+		args = append(args, opts.Tag)
+	}
+
+	query += " ORDER BY a.id"
+
+	var ids []int64
+	if err := e.db.SelectContext(ctx, &ids, query, args...); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// highlightCards shows each highlight on the front and its source on the back
+func highlightCards(article model.ArticleWithDetails) []ankiCard {
+	var cards []ankiCard
+	seen := make(map[string]bool)
+	for _, highlight := range article.Highlights {
+		guid := ankiGUID(article.ID, "h", highlight)
+		if seen[guid] {
+			continue
+		}
+		seen[guid] = true
+		cards = append(cards, ankiCard{
+			guid:  guid,
+			front: ankiHTML(highlight),
+			back:  ankiSource(article),
+			tags:  ankiTags(article),
+		})
+	}
+	return cards
+}
+
+// generateQuestionCards asks the LLM command for question/answer pairs about the article
+func generateQuestionCards(ctx context.Context, command string, article model.ArticleWithDetails, max int) ([]ankiCard, error) {
+	if max <= 0 {
+		max = 5
+	}
+
+	content := util.TruncateUTF8(*article.ContentMD, ankiContentLimit)
+
+	var prompt strings.Builder
+	prompt.WriteString(fmt.Sprintf("Write up to %d flashcards that test the key ideas of the article below.\n", max))
+	prompt.WriteString("Reply with only the cards, one per pair of lines, in the form:\nQ: question\nA: answer\n\n")
+	prompt.WriteString("Title: " + article.Title + "\n\n")
+	prompt.WriteString(content)
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = strings.NewReader(prompt.String())
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("LLM command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var cards []ankiCard
+	var question string
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*#0123456789. "))
+		switch {
+		case strings.HasPrefix(line, "Q:"):
+			question = strings.TrimSpace(line[2:])
+		case strings.HasPrefix(line, "A:") && question != "":
+			cards = append(cards, ankiCard{
+				guid:  ankiGUID(article.ID, "q", question),
+				front: ankiHTML(question),
+				back:  ankiHTML(strings.TrimSpace(line[2:])) + "<br><br>" + ankiSource(article),
+				tags:  ankiTags(article),
+			})
+			question = ""
+		}
+
+		if len(cards) >= max {
+			break
+		}
+	}
+
+	if len(cards) == 0 {
+		return nil, fmt.Errorf("LLM command returned no Q:/A: pairs")
+	}
+	return cards, nil
+}
+
+// ankiGUID identifies a card by its article and the text it asks about, so a card keeps its GUID,
+// and its review history, wherever it ends up in a later export. kind is "h" for highlights and
+// "q" for generated questions.
+func ankiGUID(articleID int64, kind, text string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(text), " ")))
+	return fmt.Sprintf("instapaper-%d-%s-%s", articleID, kind, hex.EncodeToString(sum[:8]))
+}
+
+// writeAnkiTSV writes notes with Anki's file headers, so the deck, note type, and columns are set on import
+func writeAnkiTSV(w io.Writer, deck string, cards []ankiCard) error {
+	var b strings.Builder
+	b.WriteString("#separator:tab\n")
+	b.WriteString("#html:true\n")
+	b.WriteString("#notetype:Basic\n")
+	b.WriteString("#deck:" + deck + "\n")
+	b.WriteString("#guid column:1\n")
+	b.WriteString("#tags column:4\n")
+
+	for _, card := range cards {
+		b.WriteString(strings.Join([]string{
+			card.guid, ankiField(card.front), ankiField(card.back), ankiField(strings.Join(card.tags, " ")),
+		}, "\t"))
+		b.WriteString("\n")
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write Anki export: %w", err)
+	}
+	return nil
+}
+
+// ankiHTML escapes plain text for an HTML field, keeping line breaks
+func ankiHTML(text string) string {
+	return strings.ReplaceAll(html.EscapeString(strings.TrimSpace(text)), "\n", "<br>")
+}
+
+// ankiField strips characters that would break the tab-separated layout
+func ankiField(field string) string {
+	return strings.NewReplacer("\t", " ", "\r", "", "\n", "<br>").Replace(field)
+}
+
+func ankiSource(article model.ArticleWithDetails) string {
+	return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(article.URL), html.EscapeString(article.Title))
+}
+
+// ankiTags returns the article's tags in Anki form (no spaces), plus "instapaper"
+func ankiTags(article model.ArticleWithDetails) []string {
+	tags := []string{"instapaper"}
+	for _, tag := range article.Tags {
+		tags = append(tags, strings.ReplaceAll(strings.TrimSpace(tag), " ", "_"))
+	}
+	return tags
+}
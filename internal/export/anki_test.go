@@ -0,0 +1,42 @@
+package export
+
+import (
+	"testing"
+
+	"instapaper-cli/internal/model"
+)
+
+// TestHighlightCardGUIDsFollowText checks that a highlight keeps its card's GUID when other
+// highlights are added or removed before it
+func TestHighlightCardGUIDsFollowText(t *testing.T) {
+	before := model.ArticleWithDetails{Highlights: []string{"First.", "Second.", "Third."}}
+	before.ID = 7
+	after := model.ArticleWithDetails{Highlights: []string{"New.", "Third.", "Second."}}
+	after.ID = 7
+
+	guids := make(map[string]string)
+	for _, card := range highlightCards(before) {
+		guids[card.front] = card.guid
+	}
+	for _, card := range highlightCards(after) {
+		if old, ok := guids[card.front]; ok && old != card.guid {
+			t.Errorf("card %q changed GUID from %s to %s", card.front, old, card.guid)
+		}
+		if card.front == "New." && card.guid == guids["First."] {
+			t.Errorf("new highlight took the GUID %s of a removed one", card.guid)
+		}
+	}
+
+	other := model.ArticleWithDetails{Highlights: []string{"First."}}
+	other.ID = 8
+	if highlightCards(other)[0].guid == guids["First."] {
+		t.Error("the same highlight in another article has the same GUID")
+	}
+}
+
+func TestHighlightCardsSkipDuplicates(t *testing.T) {
+	article := model.ArticleWithDetails{Highlights: []string{"Same.", "Same."}}
+	if cards := highlightCards(article); len(cards) != 1 {
+		t.Errorf("highlightCards made %d cards for a repeated highlight, want 1", len(cards))
+	}
+}
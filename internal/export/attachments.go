@@ -0,0 +1,111 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Attachment modes for export-all: how markdown files point at an article's stored files
+const (
+	AttachmentsReference = "reference" // link to the file in the attachments store (default)
+	AttachmentsCopy      = "copy"      // copy the file next to the markdown file and link it relatively
+	AttachmentsNone      = "none"      // leave attachments out
+)
+
+// attachmentLink is an attachment as a markdown file refers to it
+type attachmentLink struct {
+	Name   string
+	Target string
+}
+
+func (opts ExportAllOptions) attachmentsMode() string {
+	if opts.Attachments == "" {
+		return AttachmentsReference
+	}
+	return opts.Attachments
+}
+
+func (opts ExportAllOptions) validateAttachments() error {
+	switch opts.attachmentsMode() {
+	case AttachmentsReference, AttachmentsCopy, AttachmentsNone:
+		return nil
+	}
+	return fmt.Errorf("invalid attachments mode %q: use %s, %s, or %s", opts.Attachments, AttachmentsReference, AttachmentsCopy, AttachmentsNone)
+}
+
+// referenceLinks links an article's attachments at their absolute path in the attachments store
+func (e *Export) referenceLinks(articleID int64) ([]attachmentLink, error) {
+	attachments, err := e.db.ArticleAttachments(articleID)
+	if err != nil {
+		return nil, err
+	}
+
+	links := make([]attachmentLink, 0, len(attachments))
+	for _, attachment := range attachments {
+		target, err := filepath.Abs(e.db.AttachmentPath(attachment))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve attachment path: %w", err)
+		}
+		links = append(links, attachmentLink{Name: attachment.Filename, Target: filepath.ToSlash(target)})
+	}
+	return links, nil
+}
+
+// copyAttachments copies an article's attachments next to its markdown file, named after it
+// like the raw HTML sibling (Title.pdf, Title-2.png, ...), and links them relatively
+func (e *Export) copyAttachments(articleID int64, markdownPath string) ([]attachmentLink, error) {
+	attachments, err := e.db.ArticleAttachments(articleID)
+	if err != nil {
+		return nil, err
+	}
+
+	base := strings.TrimSuffix(markdownPath, filepath.Ext(markdownPath))
+	links := make([]attachmentLink, 0, len(attachments))
+	for i, attachment := range attachments {
+		target := base
+		if i > 0 {
+			target += fmt.Sprintf("-%d", i+1)
+		}
+		target += strings.ToLower(filepath.Ext(attachment.Filename))
+
+		if err := copyFile(e.db.AttachmentPath(attachment), target); err != nil {
+			return nil, fmt.Errorf("failed to copy attachment %s: %w", attachment.Filename, err)
+		}
+		links = append(links, attachmentLink{Name: attachment.Filename, Target: filepath.Base(target)})
+	}
+	return links, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// buildAttachmentsSection lists attachments as markdown links
+func buildAttachmentsSection(links []attachmentLink) string {
+	var section strings.Builder
+
+	section.WriteString("\n\n## Attachments\n\n")
+	for _, link := range links {
+		// Angle brackets keep targets with spaces intact
+		section.WriteString(fmt.Sprintf("- [%s](<%s>)\n", link.Name, link.Target))
+	}
+
+	return section.String()
+}
@@ -0,0 +1,153 @@
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// How an exported file changed since the previous export-all run
+const (
+	ChangeNew       = "new"
+	ChangeUpdated   = "updated"
+	ChangeUnchanged = "unchanged"
+)
+
+// ChangedFile is a file an export-all run created, updated, or removed
+type ChangedFile struct {
+	Path  string `json:"path"` // relative to the export directory
+	Title string `json:"title"`
+}
+
+// Changelog compares an export-all run with the previous one recorded in the manifest
+type Changelog struct {
+	New       []ChangedFile `json:"new,omitempty"`
+	Updated   []ChangedFile `json:"updated,omitempty"`
+	Removed   []ChangedFile `json:"removed,omitempty"`
+	Unchanged int           `json:"unchanged"`
+	// SavedFrom and SavedUntil are the dates the new and updated articles were saved, YYYY-MM-DD
+	SavedFrom  string `json:"saved_from,omitempty"`
+	SavedUntil string `json:"saved_until,omitempty"`
+}
+
+// exportedAtLine is left out of content hashes, since it changes on every export
+var exportedAtLine = regexp.MustCompile(`(?m)^exported_at: .*\n`)
+
+// contentHash fingerprints an exported markdown file without its exported_at timestamp
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(exportedAtLine.ReplaceAllString(content, "")))
+	return hex.EncodeToString(sum[:])
+}
+
+// add files a change reported by Manifest.Record for an article saved at savedAt
+func (c *Changelog) add(change string, file ChangedFile, savedAt string) {
+	switch change {
+	case ChangeNew:
+		c.New = append(c.New, file)
+	case ChangeUpdated:
+		c.Updated = append(c.Updated, file)
+	default:
+		c.Unchanged++
+		return
+	}
+
+	if len(savedAt) >= 10 {
+		date := savedAt[:10]
+		if c.SavedFrom == "" || date < c.SavedFrom {
+			c.SavedFrom = date
+		}
+		if date > c.SavedUntil {
+			c.SavedUntil = date
+		}
+	}
+}
+
+// Changed reports whether the run created, updated, or removed any file
+func (c *Changelog) Changed() bool {
+	return len(c.New) > 0 || len(c.Updated) > 0 || len(c.Removed) > 0
+}
+
+// pruneRemoved deletes the exported files of articles that were deleted or marked obsolete
+// since they were exported, and drops them from the manifest
+func (e *Export) pruneRemoved(dir string, manifest *Manifest) ([]ChangedFile, error) {
+	var active []int64
+	if err := e.db.Select(&active, "SELECT id FROM articles WHERE obsolete = FALSE"); err != nil {
+		return nil, fmt.Errorf("failed to get articles: %w", err)
+	}
+	keep := make(map[string]bool, len(active))
+	for _, id := range active {
+		keep[strconv.FormatInt(id, 10)] = true
+	}
+
+	var removed []ChangedFile
+	for key, entry := range manifest.Files {
+		if keep[key] {
+			continue
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(entry.Path))
+		if !IsWithin(dir, path) {
+			continue
+		}
+		for _, file := range []string{path, strings.TrimSuffix(path, filepath.Ext(path)) + ".html"} {
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				return removed, fmt.Errorf("failed to remove %s: %w", file, err)
+			}
+		}
+
+		manifest.forget(key)
+		removed = append(removed, ChangedFile{Path: entry.Path, Title: entry.Title})
+	}
+
+	sort.Slice(removed, func(i, j int) bool { return removed[i].Path < removed[j].Path })
+	return removed, nil
+}
+
+// Message summarizes an export run as a commit message: the counts and the date range of the
+// changed articles on one line, then the new, updated, and removed files
+func (c *Changelog) Message() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Export: %d new, %d updated, %d removed", len(c.New), len(c.Updated), len(c.Removed))
+	switch {
+	case c.SavedFrom != "" && c.SavedFrom != c.SavedUntil:
+		fmt.Fprintf(&b, " (saved %s to %s)", c.SavedFrom, c.SavedUntil)
+	case c.SavedFrom != "":
+		fmt.Fprintf(&b, " (saved %s)", c.SavedFrom)
+	}
+	b.WriteString("\n")
+
+	sections := []struct {
+		name  string
+		files []ChangedFile
+	}{
+		{"New", c.New},
+		{"Updated", c.Updated},
+		{"Removed", c.Removed},
+	}
+	for _, section := range sections {
+		if len(section.files) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s:\n", section.name)
+		for _, file := range section.files {
+			fmt.Fprintf(&b, "- %s (%s)\n", file.Title, file.Path)
+		}
+	}
+
+	return b.String()
+}
+
+// WriteChangelog writes the commit-message summary of an export run to path
+func WriteChangelog(path string, changes *Changelog) error {
+	if err := os.WriteFile(path, []byte(changes.Message()), 0644); err != nil {
+		return fmt.Errorf("failed to write changelog: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"instapaper-cli/internal/metrics"
+	"instapaper-cli/internal/model"
+)
+
+// atxHeading matches a markdown heading line up to the # marks
+var atxHeading = regexp.MustCompile(`^ {0,3}#{1,6}(\s|$)`)
+
+// CombinedResult summarizes a combined markdown export
+type CombinedResult struct {
+	Articles int `json:"articles"`
+}
+
+// ExportCombined writes the fetched articles matching opts as one markdown document, for piping
+// into an LLM or pandoc. Nothing is written when no fetched article matches.
+func (e *Export) ExportCombined(ctx context.Context, w io.Writer, opts ExportAllOptions) (*CombinedResult, error) {
+	metrics.ExportRuns.Inc("combined")
+
+	opts.OnlySynced = true
+	ids, err := e.getArticleIDsForExport(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get articles: %w", err)
+	}
+
+	result := &CombinedResult{Articles: len(ids)}
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	if _, err := fmt.Fprintf(w, "# Exported Articles (%d)\n\n", len(ids)); err != nil {
+		return result, fmt.Errorf("failed to write export: %w", err)
+	}
+	err = e.forEachArticle(ctx, ids, false, func(i int, article model.ArticleWithDetails) error {
+		if err := e.WriteCombinedArticle(w, i, article); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// WriteCombinedArticle writes an article as the i-th section of a combined export: its title,
+// source, folder, tags, and save date, followed by its content and highlights
+func (e *Export) WriteCombinedArticle(w io.Writer, i int, article model.ArticleWithDetails) error {
+	var content strings.Builder
+	if i > 0 {
+		content.WriteString("\n---\n\n")
+	}
+
+	content.WriteString(fmt.Sprintf("%s %s\n\n", strings.Repeat("#", combinedTitleLevel), article.Title))
+	content.WriteString(fmt.Sprintf("**Source:** %s\n", article.URL))
+
+	if article.FolderPath != nil && *article.FolderPath != "" {
+		content.WriteString(fmt.Sprintf("**Folder:** %s\n", *article.FolderPath))
+	}
+
+	if len(article.Tags) > 0 {
+		content.WriteString(fmt.Sprintf("**Tags:** %s\n", strings.Join(article.Tags, ", ")))
+	}
+
+	parsedTime, _ := time.Parse(time.RFC3339, article.InstapaperedAt)
+	content.WriteString(fmt.Sprintf("**Added:** %s\n\n", parsedTime.Format("2006-01-02")))
+
+	highlights := articleHighlights(article)
+	if len(highlights) > 0 && e.highlightsAt(HighlightsTop) {
+		content.WriteString(buildHighlightsSection(strings.Repeat("#", combinedTitleLevel+1), highlights) + "\n")
+	}
+
+	if article.ContentMD != nil && *article.ContentMD != "" {
+		content.WriteString(demoteHeadings(e.sanitizer.Apply(*article.ContentMD, article.URL)))
+	} else {
+		content.WriteString("*Content not yet downloaded.*")
+	}
+
+	if len(highlights) > 0 && e.highlightsAt(HighlightsBottom) {
+		content.WriteString("\n\n" + buildHighlightsSection(strings.Repeat("#", combinedTitleLevel+1), highlights))
+	}
+
+	content.WriteString("\n\n")
+	_, err := io.WriteString(w, content.String())
+	return err
+}
+
+// combinedTitleLevel is the heading level of each article's title in a combined export
+const combinedTitleLevel = 2
+
+// demoteHeadings moves the headings of an article's content, outside fenced code, below the
+// article's title, so a # heading becomes ###. Headings never go deeper than level 6.
+func demoteHeadings(markdown string) string {
+	lines := make([]string, 0, strings.Count(markdown, "\n")+1)
+	for _, line := range splitMarkdown(markdown) {
+		text := line.text
+		if !line.code && atxHeading.MatchString(text) {
+			indent := len(text) - len(strings.TrimLeft(text, " "))
+			marks := len(text) - len(strings.TrimLeft(text[indent:], "#")) - indent
+			text = text[:indent] + strings.Repeat("#", min(marks+combinedTitleLevel, 6)) + text[indent+marks:]
+		}
+		lines = append(lines, text)
+	}
+	return strings.Join(lines, "\n")
+}
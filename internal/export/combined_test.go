@@ -0,0 +1,28 @@
+package export
+
+import "testing"
+
+func TestDemoteHeadings(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"levels", "# One\n## Two\ntext", "### One\n#### Two\ntext"},
+		{"indented", "  # One", "  ### One"},
+		{"empty heading", "#", "###"},
+		{"capped at level 6", "##### Five", "###### Five"},
+		{"deepest level stays", "###### Six", "###### Six"},
+		{"not a heading", "#hashtag\n    # indented code", "#hashtag\n    # indented code"},
+		{"fenced code", "```sh\n# comment\n```\n# After", "```sh\n# comment\n```\n### After"},
+		{"windows line endings", "# One\r\ntext", "### One\ntext"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := demoteHeadings(tt.in); got != tt.want {
+				t.Errorf("demoteHeadings(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
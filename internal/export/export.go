@@ -1,54 +1,135 @@
 package export
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"instapaper-cli/internal/db"
+	"instapaper-cli/internal/metrics"
 	"instapaper-cli/internal/model"
+	"instapaper-cli/internal/search"
 	"instapaper-cli/internal/util"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Export struct {
-	db *db.DB
+	db          *db.DB
+	sanitizer   *Sanitizer
+	frontmatter FrontmatterFields
+	highlights  string
 }
 
 type ExportAllOptions struct {
 	Directory       string
 	OnlySynced      bool
-	IncludeUnsynced bool
+	// UnsyncedMode is UnsyncedSkip (default), UnsyncedStub, UnsyncedLinkFile, or UnsyncedIndex
+	UnsyncedMode string
+	LinkFormat   string // LinkFormatURL (default) or LinkFormatWebloc, for UnsyncedLinkFile
 	FolderFilter    string
 	TagFilter       string
+	// Statuses limits the export to articles with these reading statuses
+	Statuses        []string
 	Since           string
 	Until           string
 	FromSearch      string
 	SearchField     string
 	SearchFTS       bool
 	SearchLimit     int
+	IncludeHTML     bool
+	Quiet           bool
+	Naming          string // NamingCounter (default) or NamingStable
+	Format          string // FormatMarkdown (default) or FormatLogseq
+	// LogseqNamespaces places Logseq pages in namespaces per folder (Instapaper/Tech/Go/Title)
+	LogseqNamespaces bool
+	// Attachments is AttachmentsReference (default), AttachmentsCopy, or AttachmentsNone
+	Attachments string
+	// Prune deletes the exported files of articles deleted or marked obsolete since their export
+	Prune bool
+	// MaxArticles refuses to export more articles and MaxTotalSize (bytes) stops the export once
+	// the written files reach it, unless Paginate splits the export into part-001, part-002, ...
+	// directories holding at most that much. 0 means no cap. A run or part is closed once its files
+	// reach MaxTotalSize, so it can exceed the cap by the files of one article.
+	MaxArticles  int
+	MaxTotalSize int64
+	Paginate     bool
+}
+
+// WithAliases returns the options with an aliased folder or tag filter replaced by its target
+func (opts ExportAllOptions) WithAliases(database *db.DB) (ExportAllOptions, error) {
+	var err error
+	if opts.FolderFilter != "" {
+		if opts.FolderFilter, _, err = database.ResolveAlias(db.AliasFolder, opts.FolderFilter); err != nil {
+			return opts, err
+		}
+	}
+	if opts.TagFilter != "" {
+		if opts.TagFilter, _, err = database.ResolveAlias(db.AliasTag, opts.TagFilter); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+// ExportResult summarizes an export-all run
+type ExportResult struct {
+	Directory string            `json:"directory"`
+	Matched   int               `json:"matched"`
+	Exported  int               `json:"exported"`
+	Skipped   int               `json:"skipped"`
+	Failed    int               `json:"failed"`
+	Files     []string          `json:"files,omitempty"`
+	Errors    []model.ItemError `json:"errors,omitempty"`
+	// Changes compares the markdown files with the previous run
+	Changes *Changelog `json:"changes,omitempty"`
+	// Parts is the number of part directories a paginated export wrote to
+	Parts int `json:"parts,omitempty"`
 }
 
 func New(database *db.DB) *Export {
 	return &Export{db: database}
 }
 
-func (e *Export) ExportArticle(id int64, outPath string, stdout bool) error {
-	article, err := e.getArticleWithDetails(id)
+// WithSanitizer cleans exported content with the sanitizer; nil exports content as stored
+func (e *Export) WithSanitizer(sanitizer *Sanitizer) *Export {
+	e.sanitizer = sanitizer
+	return e
+}
+
+// WithFrontmatterFields adds the computed fields to the frontmatter of exported markdown
+func (e *Export) WithFrontmatterFields(fields FrontmatterFields) *Export {
+	e.frontmatter = fields
+	return e
+}
+
+func (e *Export) ExportArticle(ctx context.Context, id int64, outPath string, stdout bool, includeHTML bool) error {
+	metrics.ExportRuns.Inc("single")
+
+	article, err := e.getArticleWithDetails(ctx, id)
 	if err != nil {
 		return fmt.Errorf("failed to get article: %w", err)
 	}
 
-	content, err := e.buildMarkdownContent(*article)
+	links, err := e.referenceLinks(article.ID)
+	if err != nil {
+		return err
+	}
+
+	content, err := e.buildMarkdownContent(*article, links)
 	if err != nil {
 		return fmt.Errorf("failed to build content: %w", err)
 	}
 
 	if stdout {
 		fmt.Print(content)
+		if includeHTML && article.RawHTML != nil && *article.RawHTML != "" {
+			fmt.Print(buildRawHTMLDetails(*article.RawHTML))
+		}
 		return nil
 	}
 
@@ -56,44 +137,221 @@ func (e *Export) ExportArticle(id int64, outPath string, stdout bool) error {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
+	if includeHTML {
+		if err := e.writeRawHTML(*article, outPath); err != nil {
+			return err
+		}
+	}
+
 	fmt.Printf("Exported article to: %s\n", outPath)
 	return nil
 }
 
-func (e *Export) ExportAll(opts ExportAllOptions) error {
-	articles, err := e.getArticlesForExport(opts)
+// ArticleMarkdown returns an article rendered as Markdown with YAML frontmatter
+func (e *Export) ArticleMarkdown(ctx context.Context, id int64) (string, error) {
+	article, err := e.getArticleWithDetails(ctx, id)
 	if err != nil {
-		return fmt.Errorf("failed to get articles: %w", err)
+		return "", fmt.Errorf("failed to get article: %w", err)
 	}
 
-	if len(articles) == 0 {
-		fmt.Println("No articles found matching criteria.")
-		return nil
+	links, err := e.referenceLinks(article.ID)
+	if err != nil {
+		return "", err
+	}
+
+	return e.buildMarkdownContent(*article, links)
+}
+
+// ExportAll writes matching articles to opts.Directory. When ctx is cancelled it stops
+// before the next article and returns the partial result with ctx's error.
+func (e *Export) ExportAll(ctx context.Context, opts ExportAllOptions) (*ExportResult, error) {
+	metrics.ExportRuns.Inc("all")
+
+	ids, err := e.getArticleIDsForExport(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get articles: %w", err)
+	}
+
+	result := &ExportResult{Directory: opts.Directory, Matched: len(ids)}
+
+	switch opts.Naming {
+	case "", NamingCounter, NamingStable:
+	default:
+		return nil, fmt.Errorf("invalid naming mode %q: use %s or %s", opts.Naming, NamingCounter, NamingStable)
 	}
 
-	fmt.Printf("Exporting %d articles...\n", len(articles))
+	switch opts.Format {
+	case "", FormatMarkdown, FormatLogseq:
+	default:
+		return nil, fmt.Errorf("invalid format %q: use %s or %s", opts.Format, FormatMarkdown, FormatLogseq)
+	}
 
-	for i, article := range articles {
-		if err := e.exportSingleArticle(article, opts.Directory, opts.IncludeUnsynced); err != nil {
-			fmt.Printf("Failed to export article %d (%s): %v\n", article.ID, article.Title, err)
-			continue
+	if err := opts.validateUnsynced(); err != nil {
+		return nil, err
+	}
+
+	if err := opts.validateAttachments(); err != nil {
+		return nil, err
+	}
+
+	if err := opts.validateLimits(); err != nil {
+		return nil, err
+	}
+
+	// Progress goes to stdout unless the caller wants only structured output
+	printf := func(format string, args ...interface{}) {
+		if !opts.Quiet {
+			fmt.Printf(format, args...)
 		}
+	}
+
+	if len(ids) == 0 {
+		printf("No articles found matching criteria.\n")
+		return result, nil
+	}
+
+	if opts.MaxArticles > 0 && len(ids) > opts.MaxArticles && !opts.Paginate {
+		return nil, fmt.Errorf("%d articles match, more than the cap of %d: narrow the selection, raise the cap, or paginate", len(ids), opts.MaxArticles)
+	}
+
+	if err := os.MkdirAll(opts.Directory, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	if opts.Format == FormatLogseq {
+		printf("Exporting %d articles to Logseq graph...\n", len(ids))
+		return result, e.exportLogseq(ctx, ids, opts, result, printf)
+	}
+
+	manifest, err := LoadManifest(opts.Directory)
+	if err != nil {
+		return nil, err
+	}
+	part := &exportPart{opts: opts, manifest: manifest}
+	if opts.Paginate {
+		// Parts are filled in the order articles were added, so a newly saved article goes into
+		// the last part and the earlier parts keep their articles from run to run
+		slices.Sort(ids)
+		if part, err = openPart(opts, 1); err != nil {
+			return nil, err
+		}
+		result.Parts = 1
+	}
+
+	printf("Exporting %d articles...\n", len(ids))
+
+	result.Changes = &Changelog{}
+	var unfetched []model.ArticleWithDetails
+	err = e.forEachArticle(ctx, ids, opts.IncludeHTML, func(i int, article model.ArticleWithDetails) error {
+		if err := ctx.Err(); err != nil {
+			printf("Export interrupted after %d/%d articles\n", i, len(ids))
+			return err
+		}
+
+		// Articles that write no file of their own do not count towards the caps
+		writes := article.ContentMD != nil || opts.unsyncedMode() == UnsyncedStub || opts.unsyncedMode() == UnsyncedLinkFile
+		if writes && part.full(opts) {
+			if !opts.Paginate {
+				printf("Export stopped after %d/%d articles\n", i, len(ids))
+				return fmt.Errorf("the exported files reached the size cap of %d bytes after %d of %d articles", opts.MaxTotalSize, i, len(ids))
+			}
+			if err := part.manifest.Save(part.opts.Directory); err != nil {
+				return err
+			}
+			next, err := openPart(opts, part.number+1)
+			if err != nil {
+				return err
+			}
+			part = next
+			result.Parts++
+		}
+
+		var filePath, hash string
+		var err error
+		switch {
+		case article.ContentMD != nil || opts.unsyncedMode() == UnsyncedStub:
+			filePath, hash, err = e.exportSingleArticle(article, part.opts, part.manifest)
+		case opts.unsyncedMode() == UnsyncedLinkFile:
+			filePath, err = e.exportLinkFile(article, part.opts, part.manifest)
+		case opts.unsyncedMode() == UnsyncedIndex:
+			unfetched = append(unfetched, article)
+			return nil
+		default:
+			result.Skipped++
+			return nil
+		}
+		if err != nil {
+			printf("Failed to export article %d (%s): %v\n", article.ID, article.Title, err)
+			result.Failed++
+			result.Errors = append(result.Errors, model.ItemError{ID: article.ID, URL: article.URL, Error: err.Error()})
+			return nil
+		}
+
+		if article.ContentMD != nil || opts.unsyncedMode() == UnsyncedStub {
+			change := part.manifest.Record(part.opts.Directory, article, filePath, hash)
+			rel, _ := filepath.Rel(opts.Directory, filePath)
+			result.Changes.add(change, ChangedFile{Path: filepath.ToSlash(rel), Title: article.Title}, article.InstapaperedAt)
+		}
+		part.add(filePath, opts.IncludeHTML)
+		result.Exported++
+		result.Files = append(result.Files, filePath)
 
 		if (i+1)%10 == 0 {
-			fmt.Printf("Exported %d/%d articles...\n", i+1, len(articles))
+			printf("Exported %d/%d articles...\n", i+1, len(ids))
 		}
+		return nil
+	})
+	if err != nil {
+		// Keep the manifest in step with the files already written
+		if saveErr := part.manifest.Save(part.opts.Directory); saveErr != nil {
+			printf("Failed to save manifest: %v\n", saveErr)
+		}
+		return result, err
 	}
 
-	fmt.Printf("Export completed: %d articles\n", len(articles))
-	return nil
+	if opts.Prune {
+		removed, err := e.pruneRemoved(opts.Directory, manifest)
+		result.Changes.Removed = removed
+		if err != nil {
+			if saveErr := manifest.Save(opts.Directory); saveErr != nil {
+				printf("Failed to save manifest: %v\n", saveErr)
+			}
+			return result, err
+		}
+		if len(removed) > 0 {
+			printf("Removed %d files of deleted or obsolete articles\n", len(removed))
+		}
+	}
+
+	if err := part.manifest.Save(part.opts.Directory); err != nil {
+		return result, err
+	}
+
+	if opts.unsyncedMode() == UnsyncedIndex {
+		indexPath, err := writeUnfetchedIndex(opts.Directory, unfetched)
+		if err != nil {
+			return result, err
+		}
+		if indexPath != "" {
+			result.Exported += len(unfetched)
+			result.Files = append(result.Files, indexPath)
+		}
+	}
+
+	if opts.Paginate {
+		printf("Export completed: %d articles in %d parts\n", len(ids), result.Parts)
+	} else {
+		printf("Export completed: %d articles\n", len(ids))
+	}
+	return result, nil
 }
 
-func (e *Export) getArticleWithDetails(id int64) (*model.ArticleWithDetails, error) {
+func (e *Export) getArticleWithDetails(ctx context.Context, id int64) (*model.ArticleWithDetails, error) {
 	query := `
 		SELECT
 			a.id, a.url, a.title, a.selection, a.folder_id, a.instapapered_at,
-			a.synced_at, a.sync_failed_at, a.failed_count, a.status_code,
-			a.status_text, a.final_url, a.content_md, a.raw_html,
+			a.synced_at, a.sync_failed_at, a.failed_count, a.status_code, a.status,
+			a.status_text, a.final_url, content_text(a.content_md) AS content_md, a.raw_html, a.extraction_quality, a.language,
 			f.path_cache as folder_path
 		FROM articles a
 		LEFT JOIN folders f ON a.folder_id = f.id
@@ -101,7 +359,7 @@ func (e *Export) getArticleWithDetails(id int64) (*model.ArticleWithDetails, err
 	`
 
 	var article model.ArticleWithDetails
-	if err := e.db.Get(&article, query, id); err != nil {
+	if err := e.db.GetContext(ctx, &article, query, id); err != nil {
 		return nil, err
 	}
 
@@ -111,6 +369,18 @@ func (e *Export) getArticleWithDetails(id int64) (*model.ArticleWithDetails, err
 	}
 	article.Tags = tags
 
+	highlights, err := e.db.GetHighlights(id)
+	if err != nil {
+		return nil, err
+	}
+	article.Highlights = highlights
+
+	outline, err := e.db.Outline(id)
+	if err != nil {
+		return nil, err
+	}
+	article.Outline = outline
+
 	return &article, nil
 }
 
@@ -131,17 +401,19 @@ func (e *Export) getArticleTags(articleID int64) ([]string, error) {
 	return tags, nil
 }
 
-func (e *Export) getArticlesForExport(opts ExportAllOptions) ([]model.ArticleWithDetails, error) {
+// getArticleIDsForExport returns the IDs of the articles to export, in export order
+func (e *Export) getArticleIDsForExport(ctx context.Context, opts ExportAllOptions) ([]int64, error) {
 	if opts.FromSearch != "" {
-		return e.getArticlesFromSearch(opts)
+		return e.getArticleIDsFromSearch(ctx, opts)
+	}
+
+	opts, err := opts.WithAliases(e.db)
+	if err != nil {
+		return nil, err
 	}
 
 	query := `
-		SELECT DISTINCT
-			a.id, a.url, a.title, a.selection, a.folder_id, a.instapapered_at,
-			a.synced_at, a.sync_failed_at, a.failed_count, a.status_code,
-			a.status_text, a.final_url, a.content_md, a.raw_html,
-			f.path_cache as folder_path
+		SELECT a.id
 		FROM articles a
 		LEFT JOIN folders f ON a.folder_id = f.id
 		LEFT JOIN article_tags at ON a.id = at.article_id
@@ -175,31 +447,25 @@ func (e *Export) getArticlesForExport(opts ExportAllOptions) ([]model.ArticleWit
 		args = append(args, opts.Until)
 	}
 
-	query += " ORDER BY a.instapapered_at DESC"
-
-	var articles []model.ArticleWithDetails
-	if err := e.db.Select(&articles, query, args...); err != nil {
-		return nil, err
+	statusConditions, statusArgs := search.Selector{Statuses: opts.Statuses}.Conditions()
+	for _, condition := range statusConditions {
+		query += " AND " + condition
 	}
+	args = append(args, statusArgs...)
 
-	for i := range articles {
-		tags, err := e.getArticleTags(articles[i].ID)
-		if err != nil {
-			return nil, err
-		}
-		articles[i].Tags = tags
+	query += " GROUP BY a.id ORDER BY a.instapapered_at DESC"
+
+	var ids []int64
+	if err := e.db.SelectContext(ctx, &ids, query, args...); err != nil {
+		return nil, err
 	}
 
-	return articles, nil
+	return ids, nil
 }
 
-func (e *Export) getArticlesFromSearch(opts ExportAllOptions) ([]model.ArticleWithDetails, error) {
+func (e *Export) getArticleIDsFromSearch(ctx context.Context, opts ExportAllOptions) ([]int64, error) {
 	baseQuery := `
-		SELECT
-			a.id, a.url, a.title, a.selection, a.folder_id, a.instapapered_at,
-			a.synced_at, a.sync_failed_at, a.failed_count, a.status_code,
-			a.status_text, a.final_url, a.content_md, a.raw_html,
-			f.path_cache as folder_path
+		SELECT a.id
 		FROM articles a
 		LEFT JOIN folders f ON a.folder_id = f.id
 		LEFT JOIN article_tags at ON a.id = at.article_id
@@ -212,11 +478,7 @@ func (e *Export) getArticlesFromSearch(opts ExportAllOptions) ([]model.ArticleWi
 
 	if opts.SearchFTS {
 		baseQuery = `
-			SELECT
-				a.id, a.url, a.title, a.selection, a.folder_id, a.instapapered_at,
-				a.synced_at, a.sync_failed_at, a.failed_count, a.status_code,
-				a.status_text, a.final_url, a.content_md, a.raw_html,
-				f.path_cache as folder_path
+			SELECT a.id
 			FROM articles a
 			LEFT JOIN folders f ON a.folder_id = f.id
 			LEFT JOIN article_tags at ON a.id = at.article_id
@@ -225,39 +487,23 @@ func (e *Export) getArticlesFromSearch(opts ExportAllOptions) ([]model.ArticleWi
 			WHERE a.obsolete = FALSE
 		`
 
-		if opts.SearchField != "" {
-			switch opts.SearchField {
-			case "url":
-				whereClause = "AND articles_fts MATCH ?"
-				args = append(args, "url: "+opts.FromSearch)
-			case "title":
-				whereClause = "AND articles_fts MATCH ?"
-				args = append(args, "title: "+opts.FromSearch)
-			case "content":
-				whereClause = "AND articles_fts MATCH ?"
-				args = append(args, "content: "+opts.FromSearch)
-			case "tags":
-				whereClause = "AND articles_fts MATCH ?"
-				args = append(args, "tags: "+opts.FromSearch)
-			case "folder":
-				whereClause = "AND articles_fts MATCH ?"
-				args = append(args, "folder: "+opts.FromSearch)
-			default:
-				return nil, fmt.Errorf("invalid field for FTS: %s", opts.SearchField)
-			}
-		} else {
-			whereClause = "AND articles_fts MATCH ?"
-			args = append(args, opts.FromSearch)
+		ftsQuery, err := search.FTSQuery(opts.FromSearch, opts.SearchField)
+		if err != nil {
+			return nil, err
 		}
+		whereClause = "AND articles_fts MATCH ?"
+		args = append(args, ftsQuery)
 	} else {
 		if opts.SearchField != "" {
 			switch opts.SearchField {
 			case "url":
 				whereClause = "AND a.url LIKE ?"
 			case "title":
-				whereClause = "AND a.title LIKE ?"
+				whereClause = "AND a.title_norm LIKE normalize_text(?)"
 			case "content":
-				whereClause = "AND a.content_md LIKE ?"
+				whereClause = "AND content_text(a.content_md) LIKE ?"
+			case "selection":
+				whereClause = "AND a.selection LIKE ?"
 			case "tags":
 				whereClause = "AND t.title LIKE ?"
 			case "folder":
@@ -269,14 +515,24 @@ func (e *Export) getArticlesFromSearch(opts ExportAllOptions) ([]model.ArticleWi
 			args = append(args, "%"+opts.FromSearch+"%")
 		} else {
 			whereClause = `
-				AND (a.url LIKE ? OR a.title LIKE ? OR a.content_md LIKE ?
-				       OR t.title LIKE ? OR f.path_cache LIKE ?)
+				AND (a.url LIKE ? OR a.title_norm LIKE normalize_text(?) OR content_text(a.content_md) LIKE ?
+				       OR a.selection LIKE ? OR t.title LIKE ? OR f.path_cache LIKE ?)
 			`
 			pattern := "%" + opts.FromSearch + "%"
-			args = append(args, pattern, pattern, pattern, pattern, pattern)
+			args = append(args, pattern, pattern, pattern, pattern, pattern, pattern)
 		}
 	}
 
+	if opts.OnlySynced {
+		whereClause += " AND a.content_md IS NOT NULL"
+	}
+
+	statusConditions, statusArgs := search.Selector{Statuses: opts.Statuses}.Conditions()
+	for _, condition := range statusConditions {
+		whereClause += " AND " + condition
+	}
+	args = append(args, statusArgs...)
+
 	query := baseQuery + " " + whereClause + `
 		GROUP BY a.id
 	`
@@ -292,53 +548,129 @@ func (e *Export) getArticlesFromSearch(opts ExportAllOptions) ([]model.ArticleWi
 		args = append(args, opts.SearchLimit)
 	}
 
-	var articles []model.ArticleWithDetails
-	if err := e.db.Select(&articles, query, args...); err != nil {
+	var ids []int64
+	if err := e.db.SelectContext(ctx, &ids, query, args...); err != nil {
 		return nil, err
 	}
 
-	for i := range articles {
-		tags, err := e.getArticleTags(articles[i].ID)
-		if err != nil {
-			return nil, err
-		}
-		articles[i].Tags = tags
+	return ids, nil
+}
+
+// exportSingleArticle writes an article below opts.Directory, in a subdirectory for its folder, and returns
+// the markdown path and its content hash
+func (e *Export) exportSingleArticle(article model.ArticleWithDetails, opts ExportAllOptions, manifest *Manifest) (string, string, error) {
+	filePath, err := e.articlePath(article, opts, manifest)
+	if err != nil {
+		return "", "", err
 	}
 
-	return articles, nil
-}
+	var links []attachmentLink
+	switch opts.attachmentsMode() {
+	case AttachmentsReference:
+		links, err = e.referenceLinks(article.ID)
+	case AttachmentsCopy:
+		links, err = e.copyAttachments(article.ID, filePath)
+	}
+	if err != nil {
+		return "", "", err
+	}
 
-func (e *Export) exportSingleArticle(article model.ArticleWithDetails, baseDir string, includeUnsynced bool) error {
-	content, err := e.buildMarkdownContent(article)
+	content, err := e.buildMarkdownContent(article, links)
 	if err != nil {
-		return err
+		return "", "", err
 	}
 
-	if article.ContentMD == nil && !includeUnsynced {
-		return nil
+	// Leave an unchanged file alone, so its exported_at (and a git-backed vault) only changes with its content
+	hash := contentHash(content)
+	if !manifest.unchanged(opts.Directory, article.ID, filePath, hash) {
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			return "", "", fmt.Errorf("failed to write file: %w", err)
+		}
+	}
+	removeLinkFiles(filePath)
+
+	if opts.IncludeHTML {
+		if err := e.writeRawHTML(article, filePath); err != nil {
+			return "", "", err
+		}
+	}
+
+	return filePath, hash, nil
+}
+
+// articlePath picks the markdown path for an article. Stable naming reuses the path recorded
+// in the manifest, so re-exports overwrite the same file even after a title change.
+func (e *Export) articlePath(article model.ArticleWithDetails, opts ExportAllOptions, manifest *Manifest) (string, error) {
+	baseDir := opts.Directory
+
+	if opts.Naming == NamingStable {
+		if path, ok := manifest.Lookup(baseDir, article.ID); ok {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return "", fmt.Errorf("failed to create folder: %w", err)
+			}
+			return path, nil
+		}
 	}
 
 	folderPath := baseDir
 	if article.FolderPath != nil && *article.FolderPath != "" {
 		folderPath = filepath.Join(baseDir, *article.FolderPath)
+		if !IsWithin(baseDir, folderPath) {
+			return "", fmt.Errorf("folder path %q escapes the export directory", *article.FolderPath)
+		}
 		if err := os.MkdirAll(folderPath, 0755); err != nil {
-			return fmt.Errorf("failed to create folder: %w", err)
+			return "", fmt.Errorf("failed to create folder: %w", err)
 		}
 	}
 
-	filename := e.generateFilename(article)
-	filePath := filepath.Join(folderPath, filename)
+	if opts.Naming == NamingStable {
+		filePath := filepath.Join(folderPath, stableFilename(article))
+		// Duplicate URLs with the same title would share a name; fall back to the ID for the newcomer
+		if manifest.claimedByOther(baseDir, filePath, article.ID) {
+			filePath = strings.TrimSuffix(filePath, ".md") + fmt.Sprintf("-%d.md", article.ID)
+		}
+		return filePath, nil
+	}
 
-	filePath = e.resolveFilenameCollision(filePath)
+	filePath := filepath.Join(folderPath, e.generateFilename(article))
+	return e.resolveFilenameCollision(filePath), nil
+}
 
-	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+// IsWithin reports whether path is dir itself or lies below it, after cleaning both
+func IsWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// writeRawHTML writes the stored raw HTML as a sibling .html file next to the markdown file
+func (e *Export) writeRawHTML(article model.ArticleWithDetails, markdownPath string) error {
+	if article.RawHTML == nil || *article.RawHTML == "" {
+		return nil
+	}
+
+	htmlPath := strings.TrimSuffix(markdownPath, filepath.Ext(markdownPath)) + ".html"
+	if err := os.WriteFile(htmlPath, []byte(*article.RawHTML), 0644); err != nil {
+		return fmt.Errorf("failed to write HTML file: %w", err)
 	}
 
 	return nil
 }
 
-func (e *Export) buildMarkdownContent(article model.ArticleWithDetails) (string, error) {
+// buildRawHTMLDetails embeds raw HTML in a collapsible details block for single-stream output
+func buildRawHTMLDetails(rawHTML string) string {
+	var content strings.Builder
+
+	content.WriteString("\n\n<details>\n<summary>Raw HTML</summary>\n\n")
+	content.WriteString(rawHTML)
+	content.WriteString("\n\n</details>\n")
+
+	return content.String()
+}
+
+func (e *Export) buildMarkdownContent(article model.ArticleWithDetails, attachments []attachmentLink) (string, error) {
 	tags := append([]string{"instapaper"}, article.Tags...)
 
 	instapaperedAt, err := time.Parse(time.RFC3339, article.InstapaperedAt)
@@ -352,6 +684,10 @@ func (e *Export) buildMarkdownContent(article model.ArticleWithDetails) (string,
 		ExportedAt:     time.Now().UTC(),
 		Source:         article.URL,
 		Tags:           tags,
+		Fields:         e.frontmatter.values(article),
+	}
+	for _, attachment := range attachments {
+		frontMatter.Attachments = append(frontMatter.Attachments, attachment.Target)
 	}
 
 	yamlBytes, err := yaml.Marshal(frontMatter)
@@ -365,12 +701,25 @@ func (e *Export) buildMarkdownContent(article model.ArticleWithDetails) (string,
 	content.Write(yamlBytes)
 	content.WriteString("---\n\n")
 
+	highlights := articleHighlights(article)
+	if len(highlights) > 0 && e.highlightsAt(HighlightsTop) {
+		content.WriteString(buildHighlightsSection("##", highlights) + "\n")
+	}
+
 	if article.ContentMD != nil && *article.ContentMD != "" {
-		content.WriteString(*article.ContentMD)
+		content.WriteString(e.sanitizer.Apply(*article.ContentMD, article.URL))
 	} else {
 		content.WriteString(fmt.Sprintf("*Article content not yet fetched. Source: %s*\n", article.URL))
 	}
 
+	if len(highlights) > 0 && e.highlightsAt(HighlightsBottom) {
+		content.WriteString("\n\n" + buildHighlightsSection("##", highlights))
+	}
+
+	if len(attachments) > 0 {
+		content.WriteString(buildAttachmentsSection(attachments))
+	}
+
 	return content.String(), nil
 }
 
@@ -0,0 +1,132 @@
+package export
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"instapaper-cli/internal/model"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Computed frontmatter fields, for queries over an exported vault with Obsidian Dataview
+const (
+	FieldDomain    = "domain"     // the source's host, without www.
+	FieldWordCount = "word_count" // words in the fetched content
+	FieldReadTime  = "read_time"  // estimated minutes to read the fetched content
+	FieldStatus    = "status"     // reading status
+	FieldYear      = "year"       // year the article was saved
+	FieldAliases   = "aliases"    // the saved URL and, when it redirected, the final one
+)
+
+// FieldAll enables every computed field
+const FieldAll = "all"
+
+var frontmatterFields = []string{FieldDomain, FieldWordCount, FieldReadTime, FieldStatus, FieldYear, FieldAliases}
+
+// Keys written for every article, which computed fields cannot replace
+var builtinFrontmatterKeys = []string{"title", "instapapered_at", "exported_at", "source", "tags", "attachments"}
+
+// FrontmatterFields maps each computed field to add to exported frontmatter onto its key
+type FrontmatterFields map[string]string
+
+// LoadFrontmatterFields reads a map of computed fields to frontmatter keys from a YAML file, where
+// an empty key keeps the field's name, and adds the named fields under their own names. It returns
+// nil when there is neither a file nor a field, meaning only the standard frontmatter is written.
+func LoadFrontmatterFields(path string, fields []string) (FrontmatterFields, error) {
+	if path == "" && len(fields) == 0 {
+		return nil, nil
+	}
+
+	configured := map[string]string{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read frontmatter config: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &configured); err != nil {
+			return nil, fmt.Errorf("failed to parse frontmatter config: %w", err)
+		}
+	}
+	for _, field := range fields {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == FieldAll {
+			for _, name := range frontmatterFields {
+				configured[name] = ""
+			}
+		} else if field != "" {
+			configured[field] = ""
+		}
+	}
+
+	result := FrontmatterFields{}
+	keys := map[string]string{}
+	for field, key := range configured {
+		if !slices.Contains(frontmatterFields, field) {
+			return nil, fmt.Errorf("invalid frontmatter field %q: use %s, or %s", field, strings.Join(frontmatterFields, ", "), FieldAll)
+		}
+		if key = strings.TrimSpace(key); key == "" {
+			key = field
+		}
+		if slices.Contains(builtinFrontmatterKeys, key) {
+			return nil, fmt.Errorf("frontmatter field %s cannot use the key %q, which is always written", field, key)
+		}
+		if other, ok := keys[key]; ok {
+			return nil, fmt.Errorf("frontmatter fields %s and %s both use the key %q", other, field, key)
+		}
+		keys[key] = field
+		result[field] = key
+	}
+
+	return result, nil
+}
+
+// values computes the enabled fields of an article by frontmatter key. Fields without a value,
+// such as the word count of an article not fetched yet, are left out.
+func (f FrontmatterFields) values(article model.ArticleWithDetails) map[string]interface{} {
+	if len(f) == 0 {
+		return nil
+	}
+
+	words := 0
+	if article.ContentMD != nil {
+		words = len(strings.Fields(*article.ContentMD))
+	}
+
+	values := make(map[string]interface{}, len(f))
+	for field, key := range f {
+		switch field {
+		case FieldDomain:
+			if u, err := url.Parse(article.URL); err == nil && u.Hostname() != "" {
+				values[key] = strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+			}
+		case FieldWordCount:
+			if words > 0 {
+				values[key] = words
+			}
+		case FieldReadTime:
+			if words > 0 {
+				values[key] = max(1, words/wordsPerMinute)
+			}
+		case FieldStatus:
+			if article.Status != "" {
+				values[key] = article.Status
+			}
+		case FieldYear:
+			if saved, err := time.Parse(time.RFC3339, article.InstapaperedAt); err == nil {
+				values[key] = saved.Year()
+			}
+		case FieldAliases:
+			aliases := []string{article.URL}
+			if article.FinalURL != nil && *article.FinalURL != "" && *article.FinalURL != article.URL {
+				aliases = append(aliases, *article.FinalURL)
+			}
+			values[key] = aliases
+		}
+	}
+	return values
+}
@@ -0,0 +1,59 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GitCommit commits everything in the export directory with message, first running git init
+// when the directory is not a repository of its own. It reports false when there was nothing to commit.
+func GitCommit(ctx context.Context, dir, message string) (bool, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return false, fmt.Errorf("git not found in PATH: %w", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		if _, err := runGit(ctx, dir, "", "init", "--quiet"); err != nil {
+			return false, err
+		}
+	} else if err != nil {
+		return false, fmt.Errorf("failed to check for a git repository: %w", err)
+	}
+
+	if _, err := runGit(ctx, dir, "", "add", "--all", "."); err != nil {
+		return false, err
+	}
+
+	staged, err := runGit(ctx, dir, "", "diff", "--cached", "--name-only")
+	if err != nil {
+		return false, err
+	}
+	if strings.TrimSpace(staged) == "" {
+		return false, nil
+	}
+
+	if _, err := runGit(ctx, dir, message, "commit", "--quiet", "--file", "-"); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// runGit runs a git command in dir with stdin as its input and returns its output
+func runGit(ctx context.Context, dir, stdin string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdin = strings.NewReader(stdin)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,75 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+
+	"instapaper-cli/internal/model"
+	"instapaper-cli/internal/util"
+)
+
+// Highlights placements: where exported markdown shows the passages highlighted when saving
+const (
+	HighlightsBottom = "bottom" // after the content (default)
+	HighlightsTop    = "top"    // before the content, below the frontmatter
+	HighlightsNone   = "none"   // leave highlights out
+)
+
+// ValidateHighlights checks a highlights placement, where empty means HighlightsBottom
+func ValidateHighlights(placement string) error {
+	switch placement {
+	case "", HighlightsBottom, HighlightsTop, HighlightsNone:
+		return nil
+	}
+	return fmt.Errorf("invalid highlights placement %q: use %s, %s, or %s", placement, HighlightsBottom, HighlightsTop, HighlightsNone)
+}
+
+// WithHighlights places the Highlights section of exported markdown at the top, at the bottom, or
+// nowhere
+func (e *Export) WithHighlights(placement string) *Export {
+	e.highlights = placement
+	return e
+}
+
+// highlightsAt reports whether the Highlights section goes at placement
+func (e *Export) highlightsAt(placement string) bool {
+	if e.highlights == "" {
+		return placement == HighlightsBottom
+	}
+	return e.highlights == placement
+}
+
+// articleHighlights returns an article's highlights, or its selection split into highlights
+// when none are stored, so an imported selection is never dropped
+func articleHighlights(article model.ArticleWithDetails) []string {
+	if len(article.Highlights) > 0 || article.Selection == nil {
+		return article.Highlights
+	}
+	return util.ParseHighlights(*article.Selection)
+}
+
+// buildHighlightsSection renders highlights as a list of blockquotes under a heading of the given level
+func buildHighlightsSection(heading string, highlights []string) string {
+	var section strings.Builder
+
+	section.WriteString(heading + " Highlights\n")
+	for _, highlight := range highlights {
+		section.WriteString("\n> ")
+		section.WriteString(strings.ReplaceAll(highlight, "\n", "\n> "))
+		section.WriteString("\n")
+	}
+
+	return section.String()
+}
+
+// buildLogseqHighlights renders highlights as a Highlights block with a quote block per highlight
+func buildLogseqHighlights(highlights []string) string {
+	var section strings.Builder
+
+	section.WriteString("- ## Highlights\n")
+	for _, highlight := range highlights {
+		writeLogseqBlock(&section, 1, strings.Split("> "+strings.ReplaceAll(highlight, "\n", "\n> "), "\n"))
+	}
+
+	return section.String()
+}
@@ -0,0 +1,118 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"instapaper-cli/internal/db"
+	"instapaper-cli/internal/metrics"
+	"instapaper-cli/internal/model"
+)
+
+// Folders Instapaper uses for articles outside any folder, as in its own CSV export
+const (
+	instapaperUnreadFolder  = "Unread"
+	instapaperArchiveFolder = "Archive"
+)
+
+// InstapaperCSVOptions selects the articles written by ExportInstapaperCSV
+type InstapaperCSVOptions struct {
+	ArticleID int64 // 0 exports every article matching Filter
+	// Filter selects articles by folder, tag, status, date, or search, as for export-all
+	Filter ExportAllOptions
+}
+
+// InstapaperCSVResult summarizes an Instapaper CSV export
+type InstapaperCSVResult struct {
+	Articles int `json:"articles"`
+}
+
+// ExportInstapaperCSV writes articles in the CSV format of Instapaper's export, which Instapaper
+// and the import command read back: URL, Title, Selection, Folder, Timestamp, Tags. Articles
+// without a folder go to Archive when read or archived and to Unread otherwise, and highlights
+// are joined into the selection with blank lines between them.
+func (e *Export) ExportInstapaperCSV(ctx context.Context, w io.Writer, opts InstapaperCSVOptions) (*InstapaperCSVResult, error) {
+	metrics.ExportRuns.Inc("instapaper-csv")
+
+	ids := []int64{opts.ArticleID}
+	if opts.ArticleID == 0 {
+		var err error
+		if ids, err = e.getArticleIDsForExport(ctx, opts.Filter); err != nil {
+			return nil, fmt.Errorf("failed to get articles: %w", err)
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"URL", "Title", "Selection", "Folder", "Timestamp", "Tags"}); err != nil {
+		return nil, fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	result := &InstapaperCSVResult{}
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		article, err := e.getArticleWithDetails(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, fmt.Errorf("article %d not found", id)
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to get article %d: %w", id, err)
+		}
+
+		if err := writer.Write(instapaperCSVRecord(*article)); err != nil {
+			return result, fmt.Errorf("failed to write CSV: %w", err)
+		}
+		result.Articles++
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return result, fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return result, nil
+}
+
+// instapaperCSVRecord renders an article as a row of Instapaper's CSV export
+func instapaperCSVRecord(article model.ArticleWithDetails) []string {
+	selection := strings.Join(article.Highlights, "\n\n")
+	if selection == "" && article.Selection != nil {
+		selection = *article.Selection
+	}
+
+	folder := instapaperUnreadFolder
+	switch {
+	case article.FolderPath != nil && *article.FolderPath != "":
+		folder = *article.FolderPath
+	case article.Status == db.StatusRead || article.Status == db.StatusArchived:
+		folder = instapaperArchiveFolder
+	}
+
+	var timestamp int64
+	if savedAt, err := time.Parse(time.RFC3339, article.InstapaperedAt); err == nil {
+		timestamp = savedAt.Unix()
+	}
+
+	// Tags are a list of double-quoted names, which cannot contain double quotes themselves
+	tags := make([]string, len(article.Tags))
+	for i, tag := range article.Tags {
+		tags[i] = `"` + strings.ReplaceAll(tag, `"`, "'") + `"`
+	}
+
+	return []string{
+		article.URL,
+		article.Title,
+		selection,
+		folder,
+		strconv.FormatInt(timestamp, 10),
+		"[" + strings.Join(tags, ",") + "]",
+	}
+}
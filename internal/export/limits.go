@@ -0,0 +1,98 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ParseSize parses a size like "500K", "50MB", or "1.5G" into bytes. Units are binary (K = 1024);
+// a bare number is bytes and "0" or "" means no cap.
+func ParseSize(value string) (int64, error) {
+	s := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(value)), "B")
+	if s == "" {
+		return 0, nil
+	}
+
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(s, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		s = s[:len(s)-1]
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size %q: use a size like 500K, 50MB, or 1.5G", value)
+	}
+
+	return int64(number * multiplier), nil
+}
+
+func (opts ExportAllOptions) validateLimits() error {
+	if opts.MaxArticles < 0 || opts.MaxTotalSize < 0 {
+		return fmt.Errorf("export caps cannot be negative")
+	}
+	if opts.Paginate && opts.MaxArticles == 0 && opts.MaxTotalSize == 0 {
+		return fmt.Errorf("paginating an export needs a cap on the articles or the total size of a part")
+	}
+	if opts.Format == FormatLogseq && (opts.MaxTotalSize > 0 || opts.Paginate) {
+		return fmt.Errorf("size caps and pagination are not supported by the %s format", FormatLogseq)
+	}
+	if opts.Paginate && opts.Prune {
+		return fmt.Errorf("pruning is not supported when paginating an export")
+	}
+	return nil
+}
+
+// exportPart is where an export-all run writes: the export directory, or when paginating one of
+// its numbered part directories, each with a manifest of its own
+type exportPart struct {
+	opts     ExportAllOptions // Directory is the part's directory
+	manifest *Manifest
+	number   int
+	articles int
+	bytes    int64
+}
+
+// openPart starts the numbered part directory n of a paginated export
+func openPart(opts ExportAllOptions, n int) (*exportPart, error) {
+	opts.Directory = filepath.Join(opts.Directory, fmt.Sprintf("part-%03d", n))
+	if err := os.MkdirAll(opts.Directory, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create part directory: %w", err)
+	}
+
+	manifest, err := LoadManifest(opts.Directory)
+	if err != nil {
+		return nil, err
+	}
+	return &exportPart{opts: opts, manifest: manifest, number: n}, nil
+}
+
+// full reports whether the part holds as many articles or bytes as the caps allow
+func (p *exportPart) full(opts ExportAllOptions) bool {
+	return (opts.MaxArticles > 0 && p.articles >= opts.MaxArticles) || (opts.MaxTotalSize > 0 && p.bytes >= opts.MaxTotalSize)
+}
+
+// add counts an exported file, and the raw HTML written next to it, towards the part's caps
+func (p *exportPart) add(path string, includeHTML bool) {
+	p.articles++
+
+	paths := []string{path}
+	if includeHTML {
+		paths = append(paths, strings.TrimSuffix(path, filepath.Ext(path))+".html")
+	}
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			p.bytes += info.Size()
+		}
+	}
+}
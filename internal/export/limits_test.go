@@ -0,0 +1,87 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"instapaper-cli/internal/db"
+	"instapaper-cli/migrations"
+)
+
+// partFiles lists the markdown files of each part directory of a paginated export
+func partFiles(t *testing.T, dir string) map[string][]string {
+	t.Helper()
+
+	parts := make(map[string][]string)
+	matches, err := filepath.Glob(filepath.Join(dir, "part-*", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, match := range matches {
+		part := filepath.Base(filepath.Dir(match))
+		parts[part] = append(parts[part], filepath.Base(match))
+	}
+	for _, files := range parts {
+		sort.Strings(files)
+	}
+	return parts
+}
+
+// TestPaginatedExportKeepsParts checks that a newly saved article goes into the last part
+// instead of shifting every article after it into the next part
+func TestPaginatedExportKeepsParts(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "export.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	if err := database.RunMigrationsFS(migrations.FS); err != nil {
+		t.Fatal(err)
+	}
+
+	add := func(id int64, saved string) {
+		t.Helper()
+		if _, err := database.Exec(`
+			INSERT INTO articles (id, url, title, content_md, instapapered_at) VALUES (?, ?, ?, 'Text.', ?)
+		`, id, fmt.Sprintf("https://example.com/%d", id), fmt.Sprintf("Article %d", id), saved); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for id := int64(1); id <= 3; id++ {
+		add(id, fmt.Sprintf("2024-01-0%dT09:00:00Z", id))
+	}
+
+	dir := t.TempDir()
+	opts := ExportAllOptions{Directory: dir, OnlySynced: true, Naming: NamingStable, MaxArticles: 2, Paginate: true, Quiet: true}
+	e := New(database)
+	if _, err := e.ExportAll(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+	before := partFiles(t, dir)
+
+	// The newest article sorts first by date, but was added last
+	add(4, "2024-02-01T09:00:00Z")
+	if _, err := e.ExportAll(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+	after := partFiles(t, dir)
+
+	if fmt.Sprint(after["part-001"]) != fmt.Sprint(before["part-001"]) {
+		t.Errorf("part-001 = %v after a new article, want %v", after["part-001"], before["part-001"])
+	}
+	if len(after["part-002"]) != 2 {
+		t.Errorf("part-002 = %v, want the third and the new article", after["part-002"])
+	}
+	for part, files := range after {
+		if len(files) > 2 {
+			t.Errorf("%s holds %d articles, more than the cap of 2", part, len(files))
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "part-003")); err == nil {
+		t.Error("a new article opened part-003, want it in part-002")
+	}
+}
@@ -0,0 +1,322 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"instapaper-cli/internal/model"
+)
+
+// Export formats for ExportAll
+const (
+	FormatMarkdown = "markdown"
+	FormatLogseq   = "logseq"
+)
+
+// logseqJournalBlock heads the block this tool owns in each journal page; it is replaced on re-export
+const logseqJournalBlock = "- [[Instapaper]]"
+
+var (
+	logseqHeading  = regexp.MustCompile(`^#{1,6}\s`)
+	logseqListItem = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+`)
+	// Characters Logseq percent-encodes in page file names
+	logseqUnsafe = strings.NewReplacer(`<`, "%3C", `>`, "%3E", `:`, "%3A", `"`, "%22", `\`, "%5C", `|`, "%7C", `?`, "%3F", `*`, "%2A", `#`, "%23")
+)
+
+// exportLogseq writes articles as a Logseq graph: one page per article under pages/, with
+// page properties and block-indented content, plus a block per save date in journals/
+func (e *Export) exportLogseq(ctx context.Context, ids []int64, opts ExportAllOptions, result *ExportResult, printf func(string, ...interface{})) error {
+	pagesDir := filepath.Join(opts.Directory, "pages")
+	journalsDir := filepath.Join(opts.Directory, "journals")
+	for _, dir := range []string{pagesDir, journalsDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+
+	used := make(map[string]bool)
+	journals := make(map[string][]string) // journal date -> page names
+	folders := make(map[string]bool)
+
+	err := e.forEachArticle(ctx, ids, false, func(i int, article model.ArticleWithDetails) error {
+		if err := ctx.Err(); err != nil {
+			printf("Export interrupted after %d/%d articles\n", i, len(ids))
+			return err
+		}
+
+		if article.ContentMD == nil && opts.unsyncedMode() != UnsyncedStub {
+			result.Skipped++
+			return nil
+		}
+
+		savedAt, err := time.Parse(time.RFC3339, article.InstapaperedAt)
+		if err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, model.ItemError{ID: article.ID, URL: article.URL, Error: fmt.Sprintf("failed to parse instapapered_at: %v", err)})
+			return nil
+		}
+
+		if article.ContentMD != nil && *article.ContentMD != "" {
+			content := e.sanitizer.Apply(*article.ContentMD, article.URL)
+			article.ContentMD = &content
+		}
+
+		name := logseqPageName(article, opts.LogseqNamespaces, used)
+		path := filepath.Join(pagesDir, logseqFilename(name))
+
+		if err := os.WriteFile(path, []byte(e.buildLogseqPage(article, savedAt)), 0644); err != nil {
+			printf("Failed to export article %d (%s): %v\n", article.ID, article.Title, err)
+			result.Failed++
+			result.Errors = append(result.Errors, model.ItemError{ID: article.ID, URL: article.URL, Error: err.Error()})
+			return nil
+		}
+
+		result.Exported++
+		result.Files = append(result.Files, path)
+
+		day := savedAt.Format("2006_01_02")
+		journals[day] = append(journals[day], name)
+
+		if opts.LogseqNamespaces && article.FolderPath != nil && *article.FolderPath != "" {
+			folders[logseqNamespace(*article.FolderPath)] = true
+		}
+
+		if (i+1)%10 == 0 {
+			printf("Exported %d/%d articles...\n", i+1, len(ids))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for day, pages := range journals {
+		path := filepath.Join(journalsDir, day+".md")
+		if err := writeLogseqJournal(path, pages); err != nil {
+			return err
+		}
+		result.Files = append(result.Files, path)
+	}
+
+	for folder := range folders {
+		path := filepath.Join(pagesDir, logseqFilename(folder))
+		if _, err := os.Stat(path); err == nil {
+			continue // keep folder pages the user may have edited
+		}
+		content := fmt.Sprintf("- {{namespace %s}}\n", folder)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write folder page: %w", err)
+		}
+		result.Files = append(result.Files, path)
+	}
+
+	printf("Export completed: %d articles\n", len(ids))
+	return nil
+}
+
+// logseqPageName returns a unique page name, namespaced by folder ("Tech/Go/Title") when requested
+func logseqPageName(article model.ArticleWithDetails, namespaces bool, used map[string]bool) string {
+	// A slash in a page name creates a namespace, so keep titles flat
+	title := strings.TrimSpace(strings.ReplaceAll(article.Title, "/", "-"))
+	if title == "" {
+		title = article.URL
+	}
+
+	name := title
+	if namespaces && article.FolderPath != nil && *article.FolderPath != "" {
+		name = logseqNamespace(*article.FolderPath) + "/" + title
+	}
+
+	if used[strings.ToLower(name)] {
+		name = fmt.Sprintf("%s (%d)", name, article.ID)
+	}
+	used[strings.ToLower(name)] = true
+
+	return name
+}
+
+// logseqNamespace turns a folder path into a namespace under Instapaper, e.g. "Instapaper/Tech/Go"
+func logseqNamespace(folderPath string) string {
+	return "Instapaper/" + strings.Trim(folderPath, "/")
+}
+
+// logseqFilename encodes a page name the way Logseq's triple-lowbar file name format does
+func logseqFilename(name string) string {
+	return logseqUnsafe.Replace(strings.ReplaceAll(name, "/", "___")) + ".md"
+}
+
+// logseqDate formats a date in Logseq's default journal title format, e.g. "Jan 2nd, 2006"
+func logseqDate(t time.Time) string {
+	day := t.Day()
+	suffix := "th"
+	if day < 11 || day > 13 {
+		switch day % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return fmt.Sprintf("%s %d%s, %d", t.Format("Jan"), day, suffix, t.Year())
+}
+
+func (e *Export) buildLogseqPage(article model.ArticleWithDetails, savedAt time.Time) string {
+	var page strings.Builder
+
+	page.WriteString("title:: " + strings.ReplaceAll(article.Title, "\n", " ") + "\n")
+	page.WriteString("source:: " + article.URL + "\n")
+
+	tags := append([]string{"instapaper"}, article.Tags...)
+	page.WriteString("tags:: " + strings.Join(tags, ", ") + "\n")
+	page.WriteString("saved:: [[" + logseqDate(savedAt) + "]]\n")
+	if article.FolderPath != nil && *article.FolderPath != "" {
+		page.WriteString("folder:: " + *article.FolderPath + "\n")
+	}
+	page.WriteString("\n")
+
+	highlights := articleHighlights(article)
+	if len(highlights) > 0 && e.highlightsAt(HighlightsTop) {
+		page.WriteString(buildLogseqHighlights(highlights))
+	}
+
+	if article.ContentMD != nil && *article.ContentMD != "" {
+		page.WriteString(logseqBlocks(*article.ContentMD))
+	} else {
+		page.WriteString(fmt.Sprintf("- *Article content not yet fetched. Source: %s*\n", article.URL))
+	}
+
+	if len(highlights) > 0 && e.highlightsAt(HighlightsBottom) {
+		page.WriteString(buildLogseqHighlights(highlights))
+	}
+
+	return page.String()
+}
+
+// logseqBlocks converts markdown into Logseq outline blocks: each paragraph, list item, and code
+// block becomes a block, and content after a heading is nested under it
+func logseqBlocks(markdown string) string {
+	var out strings.Builder
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+	depth := 0
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence := trimmed[:3]
+			block := []string{trimmed}
+			for i++; i < len(lines); i++ {
+				block = append(block, lines[i])
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+					i++
+					break
+				}
+			}
+			writeLogseqBlock(&out, depth, block)
+
+		case logseqHeading.MatchString(trimmed):
+			writeLogseqBlock(&out, 0, []string{trimmed})
+			depth = 1
+			i++
+
+		case logseqListItem.MatchString(line):
+			match := logseqListItem.FindStringSubmatch(line)
+			indent := len(strings.ReplaceAll(match[1], "\t", "  ")) / 2
+			text := line[len(match[0]):]
+			if marker := match[2]; marker != "-" && marker != "*" && marker != "+" {
+				text = marker + " " + text
+			}
+
+			block := []string{text}
+			for i++; i < len(lines) && isLogseqContinuation(lines[i]); i++ {
+				block = append(block, strings.TrimSpace(lines[i]))
+			}
+			writeLogseqBlock(&out, depth+indent, block)
+
+		default:
+			var block []string
+			for ; i < len(lines); i++ {
+				t := strings.TrimSpace(lines[i])
+				if t == "" || logseqHeading.MatchString(t) || strings.HasPrefix(t, "```") || strings.HasPrefix(t, "~~~") ||
+					(len(block) > 0 && logseqListItem.MatchString(lines[i])) {
+					break
+				}
+				block = append(block, t)
+			}
+			writeLogseqBlock(&out, depth, block)
+		}
+	}
+
+	return out.String()
+}
+
+// isLogseqContinuation reports whether a line continues the previous list item
+func isLogseqContinuation(line string) bool {
+	return strings.TrimSpace(line) != "" && (line[0] == ' ' || line[0] == '\t') && !logseqListItem.MatchString(line)
+}
+
+// writeLogseqBlock writes one block at depth, indenting continuation lines under its bullet
+func writeLogseqBlock(out *strings.Builder, depth int, lines []string) {
+	indent := strings.Repeat("\t", depth)
+	for i, line := range lines {
+		if i == 0 {
+			out.WriteString(indent + "- " + line + "\n")
+		} else {
+			out.WriteString(indent + "  " + line + "\n")
+		}
+	}
+}
+
+// writeLogseqJournal replaces this tool's block in a journal page, keeping anything else on the page
+func writeLogseqJournal(path string, pages []string) error {
+	var kept []string
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	inBlock := false
+	for _, line := range strings.Split(strings.TrimRight(string(existing), "\n"), "\n") {
+		if strings.TrimRight(line, " ") == logseqJournalBlock {
+			inBlock = true
+			continue
+		}
+		if inBlock && (strings.HasPrefix(line, "\t") || strings.HasPrefix(line, " ")) {
+			continue
+		}
+		inBlock = false
+		if line != "" || len(kept) > 0 {
+			kept = append(kept, line)
+		}
+	}
+
+	sort.Strings(pages)
+
+	var journal strings.Builder
+	for _, line := range kept {
+		journal.WriteString(line + "\n")
+	}
+	journal.WriteString(logseqJournalBlock + "\n")
+	for _, page := range pages {
+		journal.WriteString("\t- [[" + page + "]]\n")
+	}
+
+	if err := os.WriteFile(path, []byte(journal.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package export
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"instapaper-cli/internal/model"
+	"instapaper-cli/internal/util"
+)
+
+// Naming modes for exported files
+const (
+	// NamingCounter names files slug-ID.md and appends -2, -3, ... when the file already exists
+	NamingCounter = "counter"
+	// NamingStable names files slug-hash.md from the article URL and overwrites them on re-export.
+	// Articles listed in the manifest keep their recorded filename even if their title changes.
+	NamingStable = "stable"
+)
+
+// ManifestFilename is the file in the export directory that maps article IDs to exported files
+const ManifestFilename = ".instapaper-manifest.json"
+
+// Manifest records where each article was exported, relative to the export directory
+type Manifest struct {
+	Version int                      `json:"version"`
+	Files   map[string]ManifestEntry `json:"files"` // keyed by article ID
+
+	owners map[string]string // relative path -> article ID
+}
+
+// ManifestEntry is one exported article
+type ManifestEntry struct {
+	Path       string `json:"path"`
+	URL        string `json:"url"`
+	Title      string `json:"title"`
+	ExportedAt string `json:"exported_at"`
+	// Hash fingerprints the file's content apart from exported_at
+	Hash string `json:"hash,omitempty"`
+}
+
+// LoadManifest reads the manifest in dir, returning an empty manifest if there is none
+func LoadManifest(dir string) (*Manifest, error) {
+	manifest := &Manifest{Version: 1, Files: make(map[string]ManifestEntry), owners: make(map[string]string)}
+
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFilename))
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if manifest.Files == nil {
+		manifest.Files = make(map[string]ManifestEntry)
+	}
+	for id, entry := range manifest.Files {
+		manifest.owners[entry.Path] = id
+	}
+
+	return manifest, nil
+}
+
+// Save writes the manifest to dir
+func (m *Manifest) Save(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ManifestFilename), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// Lookup returns the recorded absolute path of an article, if any
+func (m *Manifest) Lookup(dir string, id int64) (string, bool) {
+	entry, ok := m.Files[strconv.FormatInt(id, 10)]
+	if !ok || entry.Path == "" {
+		return "", false
+	}
+
+	path := filepath.Join(dir, filepath.FromSlash(entry.Path))
+	if !IsWithin(dir, path) {
+		return "", false
+	}
+	return path, true
+}
+
+// Record stores where an article was exported and the hash of what was written, and reports
+// whether the file is new, updated, or unchanged since the previous export
+func (m *Manifest) Record(dir string, article model.ArticleWithDetails, path, hash string) string {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return ChangeNew
+	}
+
+	change := ChangeNew
+	exportedAt := time.Now().UTC().Format(time.RFC3339)
+	key := strconv.FormatInt(article.ID, 10)
+	if previous, ok := m.Files[key]; ok {
+		delete(m.owners, previous.Path)
+		if previous.Path == filepath.ToSlash(rel) {
+			change = ChangeUpdated
+			if previous.Hash == hash {
+				// The file was left as it was
+				change, exportedAt = ChangeUnchanged, previous.ExportedAt
+			}
+		}
+	}
+	m.owners[filepath.ToSlash(rel)] = key
+
+	m.Files[key] = ManifestEntry{
+		Path:       filepath.ToSlash(rel),
+		URL:        article.URL,
+		Title:      article.Title,
+		ExportedAt: exportedAt,
+		Hash:       hash,
+	}
+	return change
+}
+
+// unchanged reports whether path was recorded for the article with this hash and still exists
+func (m *Manifest) unchanged(dir string, id int64, path, hash string) bool {
+	recorded, ok := m.Lookup(dir, id)
+	if !ok || recorded != path || m.Files[strconv.FormatInt(id, 10)].Hash != hash {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// forget drops an article from the manifest
+func (m *Manifest) forget(key string) {
+	if entry, ok := m.Files[key]; ok {
+		delete(m.owners, entry.Path)
+		delete(m.Files, key)
+	}
+}
+
+// claimedByOther reports whether path is recorded for an article other than id
+func (m *Manifest) claimedByOther(dir, path string, id int64) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	owner, ok := m.owners[filepath.ToSlash(rel)]
+	return ok && owner != strconv.FormatInt(id, 10)
+}
+
+// stableFilename is the title slug followed by a short hash of the article URL
+func stableFilename(article model.ArticleWithDetails) string {
+	sum := sha1.Sum([]byte(article.URL))
+
+	base := util.SlugifyTitle(article.Title, 100)
+	if base == "" {
+		base = "article"
+	}
+
+	return base + "-" + hex.EncodeToString(sum[:])[:8] + ".md"
+}
@@ -0,0 +1,430 @@
+package export
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Sanitize filters, run in this order
+const (
+	// FilterDataImages drops images embedded as base64 data: URIs, which bloat exported files
+	FilterDataImages = "data-images"
+	// FilterShare drops leftover share buttons, such as "Share on Twitter" or a row of network names
+	FilterShare = "share"
+	// FilterRelated drops "Related posts", "You might also like", and similar sections
+	FilterRelated = "related"
+	// FilterReferences drops reference-style link definitions, inlining their links, and footnotes
+	FilterReferences = "references"
+)
+
+// FilterAll enables every filter
+const FilterAll = "all"
+
+var sanitizeFilters = []string{FilterDataImages, FilterShare, FilterRelated, FilterReferences}
+
+// Sanitizer cleans article content on export with a pipeline of filters, configurable per domain
+type Sanitizer struct {
+	// Filters run on every article unless a domain rule replaces them
+	Filters []string `yaml:"filters"`
+	// RemoveLines are regular expressions for lines to drop from every article
+	RemoveLines []string `yaml:"remove_lines"`
+	// RemoveSections are regular expressions for the headings of sections to drop from every article
+	RemoveSections []string       `yaml:"remove_sections"`
+	Domains        []SanitizeRule `yaml:"domains"`
+
+	lines    []*regexp.Regexp
+	sections []*regexp.Regexp
+}
+
+// SanitizeRule adjusts the sanitizer for a host and its subdomains
+type SanitizeRule struct {
+	Match string `yaml:"match"`
+	// Filters replace the default filters for the domain; an empty list turns them off
+	Filters []string `yaml:"filters"`
+	// RemoveLines and RemoveSections add to the defaults
+	RemoveLines    []string `yaml:"remove_lines"`
+	RemoveSections []string `yaml:"remove_sections"`
+
+	lines    []*regexp.Regexp
+	sections []*regexp.Regexp
+}
+
+// LoadSanitizer reads a sanitizer from a YAML file and adds the filters to its defaults. It returns
+// nil when there is neither a file nor a filter, meaning content is exported as stored.
+func LoadSanitizer(path string, filters []string) (*Sanitizer, error) {
+	if path == "" && len(filters) == 0 {
+		return nil, nil
+	}
+
+	s := &Sanitizer{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sanitize config: %w", err)
+		}
+		if err := yaml.Unmarshal(data, s); err != nil {
+			return nil, fmt.Errorf("failed to parse sanitize config: %w", err)
+		}
+	}
+	s.Filters = append(s.Filters, filters...)
+
+	var err error
+	if s.Filters, err = expandFilters(s.Filters); err != nil {
+		return nil, err
+	}
+	if s.lines, err = compilePatterns(s.RemoveLines, "remove_lines"); err != nil {
+		return nil, err
+	}
+	if s.sections, err = compilePatterns(s.RemoveSections, "remove_sections"); err != nil {
+		return nil, err
+	}
+
+	for i := range s.Domains {
+		rule := &s.Domains[i]
+		rule.Match = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(rule.Match)), "www.")
+		if rule.Match == "" {
+			return nil, fmt.Errorf("sanitize rule %d has no match", i+1)
+		}
+		if rule.Filters != nil {
+			if rule.Filters, err = expandFilters(rule.Filters); err != nil {
+				return nil, fmt.Errorf("sanitize rule for %s: %w", rule.Match, err)
+			}
+		}
+		if rule.lines, err = compilePatterns(rule.RemoveLines, "remove_lines"); err != nil {
+			return nil, fmt.Errorf("sanitize rule for %s: %w", rule.Match, err)
+		}
+		if rule.sections, err = compilePatterns(rule.RemoveSections, "remove_sections"); err != nil {
+			return nil, fmt.Errorf("sanitize rule for %s: %w", rule.Match, err)
+		}
+	}
+
+	return s, nil
+}
+
+// expandFilters validates filter names, expanding "all", and returns them without duplicates.
+// The result is never nil, so an empty list stays distinct from an unset one.
+func expandFilters(names []string) ([]string, error) {
+	expanded := []string{}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case name == FilterAll:
+			expanded = append(expanded, sanitizeFilters...)
+		case slices.Contains(sanitizeFilters, name):
+			expanded = append(expanded, name)
+		case name != "":
+			return nil, fmt.Errorf("invalid sanitize filter %q: use %s, or %s", name, strings.Join(sanitizeFilters, ", "), FilterAll)
+		}
+	}
+	slices.Sort(expanded)
+	return slices.Compact(expanded), nil
+}
+
+func compilePatterns(patterns []string, key string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s pattern %q: %w", key, pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// rule returns the most specific domain rule for a URL's host, or nil
+func (s *Sanitizer) rule(rawURL string) *SanitizeRule {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+
+	var best *SanitizeRule
+	for i := range s.Domains {
+		rule := &s.Domains[i]
+		if host != rule.Match && !strings.HasSuffix(host, "."+rule.Match) {
+			continue
+		}
+		if best == nil || len(rule.Match) > len(best.Match) {
+			best = rule
+		}
+	}
+	return best
+}
+
+// Apply runs the filters for the article's URL over its markdown. A nil sanitizer returns the
+// markdown unchanged.
+func (s *Sanitizer) Apply(markdown, rawURL string) string {
+	if s == nil {
+		return markdown
+	}
+
+	filters, lines, sections := s.Filters, s.lines, s.sections
+	if rule := s.rule(rawURL); rule != nil {
+		if rule.Filters != nil {
+			filters = rule.Filters
+		}
+		lines = append(append([]*regexp.Regexp{}, lines...), rule.lines...)
+		sections = append(append([]*regexp.Regexp{}, sections...), rule.sections...)
+	}
+
+	doc := splitMarkdown(markdown)
+	for _, name := range sanitizeFilters {
+		if !slices.Contains(filters, name) {
+			continue
+		}
+		switch name {
+		case FilterDataImages:
+			doc.stripDataImages()
+		case FilterShare:
+			doc.dropLines(isShareLine)
+		case FilterRelated:
+			doc.dropSections([]*regexp.Regexp{relatedHeading})
+		case FilterReferences:
+			doc.inlineReferences()
+		}
+	}
+	if len(sections) > 0 {
+		doc.dropSections(sections)
+	}
+	if len(lines) > 0 {
+		doc.dropLines(func(line string) bool {
+			for _, re := range lines {
+				if re.MatchString(line) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	return doc.String()
+}
+
+// markdownLine is a line of content, marked when it is inside a fenced code block
+type markdownLine struct {
+	text string
+	code bool
+}
+
+// markdownDoc is content split into lines, so filters leave code blocks alone
+type markdownDoc []markdownLine
+
+func splitMarkdown(markdown string) markdownDoc {
+	var doc markdownDoc
+	inCode := false
+	for _, line := range strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		fence := strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+		doc = append(doc, markdownLine{text: line, code: inCode || fence})
+		if fence {
+			inCode = !inCode
+		}
+	}
+	return doc
+}
+
+// String joins the lines, collapsing the runs of blank lines that removed content leaves behind
+func (doc markdownDoc) String() string {
+	var out []string
+	blank := 0
+	for _, line := range doc {
+		if !line.code && strings.TrimSpace(line.text) == "" {
+			blank++
+			if blank > 1 || len(out) == 0 {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		out = append(out, line.text)
+	}
+	return strings.TrimRight(strings.Join(out, "\n"), "\n") + "\n"
+}
+
+// dropLines removes the lines outside code blocks that drop reports
+func (doc *markdownDoc) dropLines(drop func(string) bool) {
+	kept := (*doc)[:0]
+	for _, line := range *doc {
+		if line.code || !drop(line.text) {
+			kept = append(kept, line)
+		}
+	}
+	*doc = kept
+}
+
+var (
+	dataImage = regexp.MustCompile(`!\[[^\]]*\]\(\s*<?data:[^)]*\)|<img[^>]*\ssrc=["']data:[^>]*>`)
+
+	markdownHeading = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	listItem        = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s`)
+	relatedHeading  = regexp.MustCompile(`(?i)^(?:related(?: posts| articles| stories| reading| content| links)?|you (?:may|might) also (?:like|enjoy)|more (?:from|stories|articles|posts|like this)\b.*|read (?:more|next)|recommended(?: for you| reading| articles| posts| stories)?|popular (?:posts|articles|stories)|keep reading|trending(?: now)?|most read)$`)
+
+	inlineLink       = regexp.MustCompile(`!?\[([^\]]*)\]\(([^)\s]*)[^)]*\)`)
+	shareURL         = regexp.MustCompile(`(?i)facebook\.com/shar|twitter\.com/(?:intent|share)|x\.com/intent|linkedin\.com/(?:share|cws/share)|reddit\.com/submit|pinterest\.com/pin/create|wa\.me/|whatsapp\.com/send|t\.me/share|news\.ycombinator\.com/submitlink|^mailto:\?`)
+	nonWord          = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+	referenceDef     = regexp.MustCompile(`^ {0,3}\[([^\]^][^\]]*)\]:\s*<?([^\s>]+)>?(?:\s+(?:"[^"]*"|'[^']*'|\([^)]*\)))?\s*$`)
+	footnoteDef      = regexp.MustCompile(`^ {0,3}\[\^[^\]]+\]:`)
+	footnoteMarker   = regexp.MustCompile(`\[\^[^\]]+\]`)
+	referenceLink    = regexp.MustCompile(`\[([^\]]+)\]\[([^\]]*)\]`)
+	indentedContinue = regexp.MustCompile(`^(?: {4}|\t)\S`)
+)
+
+// shareTriggers are words only share buttons consist of; shareWords may accompany them
+var (
+	shareTriggers = []string{
+		"share", "shares", "sharing", "tweet", "email", "print", "facebook", "twitter", "linkedin", "reddit",
+		"whatsapp", "telegram", "pinterest", "pocket", "flipboard", "mastodon", "bluesky", "tumblr",
+	}
+	shareWords = []string{
+		"this", "on", "via", "it", "pin", "copy", "link", "x", "threads", "hacker", "news", "mail", "e",
+		"save", "article", "post", "story", "page", "the", "to", "with", "or", "and", "by", "friends", "a",
+	}
+)
+
+// isShareLine reports a line made only of share buttons: share words or links to share endpoints
+func isShareLine(line string) bool {
+	sharing := false
+	text := inlineLink.ReplaceAllStringFunc(line, func(link string) string {
+		m := inlineLink.FindStringSubmatch(link)
+		if shareURL.MatchString(m[2]) {
+			sharing = true
+		}
+		if strings.HasPrefix(link, "!") {
+			return " "
+		}
+		return " " + m[1] + " "
+	})
+
+	words := strings.Fields(strings.ToLower(nonWord.ReplaceAllString(text, " ")))
+	if len(words) > 12 {
+		return false
+	}
+	for _, word := range words {
+		switch {
+		case slices.Contains(shareTriggers, word):
+			sharing = true
+		case !slices.Contains(shareWords, word):
+			return false
+		}
+	}
+	return sharing
+}
+
+func (doc *markdownDoc) stripDataImages() {
+	for i, line := range *doc {
+		if line.code || !strings.Contains(line.text, "data:") {
+			continue
+		}
+		stripped := dataImage.ReplaceAllString(line.text, "")
+		if stripped != line.text && strings.TrimSpace(stripped) == "" {
+			stripped = ""
+		}
+		(*doc)[i].text = stripped
+	}
+}
+
+// headingText returns a section heading's level and text, without emphasis or a trailing colon.
+// A paragraph that is only bold text, or ends with a colon, is a heading below all levels.
+func headingText(line string) (int, string, bool) {
+	trimmed := strings.TrimSpace(line)
+	level := 7
+	if m := markdownHeading.FindStringSubmatch(trimmed); m != nil {
+		level, trimmed = len(m[1]), m[2]
+	} else if !(strings.HasPrefix(trimmed, "**") && strings.HasSuffix(trimmed, "**") && len(trimmed) > 4) && !strings.HasSuffix(trimmed, ":") {
+		return 0, "", false
+	}
+	text := strings.Trim(trimmed, "*_ ")
+	text = strings.TrimSpace(strings.TrimRight(text, ":.…"))
+	return level, text, text != ""
+}
+
+// dropSections removes each section whose heading matches a pattern, up to the next heading of
+// the same or a higher level. A bold or colon-ended paragraph only takes the list following it.
+func (doc *markdownDoc) dropSections(patterns []*regexp.Regexp) {
+	lines := *doc
+	kept := lines[:0]
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		level, text, ok := headingText(line.text)
+		matched := false
+		if !line.code && ok {
+			for _, re := range patterns {
+				if re.MatchString(text) {
+					matched = true
+					break
+				}
+			}
+		}
+		if !matched {
+			kept = append(kept, line)
+			continue
+		}
+
+		j := i + 1
+		if level <= 6 {
+			for ; j < len(lines); j++ {
+				if next, _, ok := headingText(lines[j].text); !lines[j].code && ok && next <= level {
+					break
+				}
+			}
+		} else {
+			for ; j < len(lines); j++ {
+				text := strings.TrimSpace(lines[j].text)
+				if text != "" && !listItem.MatchString(lines[j].text) && strings.TrimSpace(inlineLink.ReplaceAllString(text, "")) != "" {
+					break
+				}
+			}
+		}
+		i = j - 1
+	}
+	*doc = kept
+}
+
+// inlineReferences turns reference-style links into inline ones and removes their definitions,
+// along with footnotes and their markers
+func (doc *markdownDoc) inlineReferences() {
+	definitions := make(map[string]string)
+	inFootnote := false
+	doc.dropLines(func(line string) bool {
+		if m := referenceDef.FindStringSubmatch(line); m != nil {
+			definitions[strings.ToLower(m[1])] = m[2]
+			inFootnote = false
+			return true
+		}
+		if footnoteDef.MatchString(line) {
+			inFootnote = true
+			return true
+		}
+		if inFootnote && (strings.TrimSpace(line) == "" || indentedContinue.MatchString(line)) {
+			return true
+		}
+		inFootnote = false
+		return false
+	})
+
+	for i, line := range *doc {
+		if line.code {
+			continue
+		}
+		text := footnoteMarker.ReplaceAllString(line.text, "")
+		text = referenceLink.ReplaceAllStringFunc(text, func(link string) string {
+			m := referenceLink.FindStringSubmatch(link)
+			id := m[2]
+			if id == "" {
+				id = m[1]
+			}
+			if target, ok := definitions[strings.ToLower(id)]; ok {
+				return "[" + m[1] + "](" + target + ")"
+			}
+			return link
+		})
+		(*doc)[i].text = text
+	}
+}
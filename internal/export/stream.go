@@ -0,0 +1,80 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"instapaper-cli/internal/model"
+)
+
+// exportBatchSize is how many articles, with their content, an export holds in memory at once
+const exportBatchSize = 100
+
+// forEachArticle loads the articles in ids batch by batch and calls fn with each one in order,
+// so exporting a large archive only ever holds one batch of content in memory. Raw HTML is
+// only loaded when includeHTML is set. Articles deleted since the IDs were read are skipped.
+func (e *Export) forEachArticle(ctx context.Context, ids []int64, includeHTML bool, fn func(i int, article model.ArticleWithDetails) error) error {
+	rawHTML := "NULL AS raw_html"
+	if includeHTML {
+		rawHTML = "a.raw_html"
+	}
+
+	for start := 0; start < len(ids); start += exportBatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		batch := ids[start:min(start+exportBatchSize, len(ids))]
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+
+		query := fmt.Sprintf(`
+			SELECT
+				a.id, a.url, a.title, a.selection, a.folder_id, a.instapapered_at,
+				a.synced_at, a.sync_failed_at, a.failed_count, a.status_code, a.status,
+				a.status_text, a.final_url, content_text(a.content_md) AS content_md, %s,
+				f.path_cache as folder_path
+			FROM articles a
+			LEFT JOIN folders f ON a.folder_id = f.id
+			WHERE a.id IN (%s)
+		`, rawHTML, strings.TrimSuffix(strings.Repeat("?,", len(batch)), ","))
+
+		var articles []model.ArticleWithDetails
+		if err := e.db.SelectContext(ctx, &articles, query, args...); err != nil {
+			return fmt.Errorf("failed to get articles: %w", err)
+		}
+
+		byID := make(map[int64]*model.ArticleWithDetails, len(articles))
+		for i := range articles {
+			byID[articles[i].ID] = &articles[i]
+		}
+
+		for j, id := range batch {
+			article, ok := byID[id]
+			if !ok {
+				continue
+			}
+
+			tags, err := e.getArticleTags(id)
+			if err != nil {
+				return fmt.Errorf("failed to get tags of article %d: %w", id, err)
+			}
+			article.Tags = tags
+
+			highlights, err := e.db.GetHighlights(id)
+			if err != nil {
+				return err
+			}
+			article.Highlights = highlights
+
+			if err := fn(start+j, *article); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"instapaper-cli/internal/db"
+	"instapaper-cli/internal/model"
+)
+
+// ANSI styles used when the output is a terminal
+const (
+	ansiBold  = "\033[1m"
+	ansiDim   = "\033[2m"
+	ansiReset = "\033[0m"
+)
+
+// wordsPerMinute estimates reading time
+const wordsPerMinute = 230
+
+var (
+	terminalImage = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	terminalLink  = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)[^)]*\)`)
+)
+
+// Article returns an article with its folder, tags, highlights, and URL aliases
+func (e *Export) Article(ctx context.Context, id int64) (*model.ArticleWithDetails, error) {
+	article, err := e.getArticleWithDetails(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("article %d not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get article: %w", err)
+	}
+
+	aliases, err := e.db.URLAliases(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, alias := range aliases {
+		article.Aliases = append(article.Aliases, alias.URL)
+	}
+	return article, nil
+}
+
+// ArticleTerminal renders an article for reading in a terminal: a metadata header, the content with
+// links and images shortened to text, and the highlights. Color adds bold headings and dim URLs.
+func (e *Export) ArticleTerminal(ctx context.Context, id int64, color bool) (string, error) {
+	article, err := e.Article(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	bold, dim, reset := "", "", ""
+	if color {
+		bold, dim, reset = ansiBold, ansiDim, ansiReset
+	}
+
+	var out strings.Builder
+	out.WriteString(bold + article.Title + reset + "\n")
+	out.WriteString(dim + article.URL + reset + "\n\n")
+
+	field := func(name, value string) {
+		if value != "" {
+			out.WriteString(fmt.Sprintf("%-10s %s\n", name+":", value))
+		}
+	}
+
+	field("ID", fmt.Sprintf("%d", article.ID))
+	if article.FolderPath != nil {
+		field("Folder", *article.FolderPath)
+	}
+	field("Tags", strings.Join(article.Tags, ", "))
+	field("Aliases", strings.Join(article.Aliases, "\n"+strings.Repeat(" ", 11)))
+	field("Status", article.Status)
+	if article.Language != nil {
+		field("Language", *article.Language)
+	}
+	field("Saved", terminalDate(article.InstapaperedAt))
+	if article.SyncedAt != nil {
+		field("Fetched", terminalDate(*article.SyncedAt))
+	} else {
+		field("Fetched", "not yet")
+	}
+
+	if article.ContentMD != nil && *article.ContentMD != "" {
+		words := len(strings.Fields(*article.ContentMD))
+		field("Length", fmt.Sprintf("%d words, about %d min", words, max(1, words/wordsPerMinute)))
+	}
+	if q := article.ExtractionQuality; q != nil && *q < db.LowQualityThreshold {
+		field("Quality", fmt.Sprintf("%.2f, probably not the article (refetch with fetch --low-quality --ids %d)", *q, article.ID))
+	} else if q != nil {
+		field("Quality", fmt.Sprintf("%.2f", *q))
+	}
+	out.WriteString("\n")
+
+	if article.ContentMD != nil && *article.ContentMD != "" {
+		out.WriteString(terminalMarkdown(*article.ContentMD, bold, dim, reset))
+	} else {
+		out.WriteString(fmt.Sprintf("Article content not yet fetched. Run fetch --ids %d or open the source URL.\n", article.ID))
+	}
+
+	if len(article.Highlights) > 0 {
+		out.WriteString("\n" + bold + "Highlights" + reset + "\n\n")
+		for _, highlight := range article.Highlights {
+			out.WriteString("  > " + strings.ReplaceAll(strings.TrimSpace(highlight), "\n", "\n  > ") + "\n\n")
+		}
+	}
+
+	return out.String(), nil
+}
+
+// terminalMarkdown shortens images and links to text and styles headings, leaving code blocks as they are
+func terminalMarkdown(markdown, bold, dim, reset string) string {
+	var out strings.Builder
+	inCode := false
+
+	for _, line := range strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCode = !inCode
+			out.WriteString(dim + line + reset + "\n")
+			continue
+		}
+		if inCode {
+			out.WriteString(line + "\n")
+			continue
+		}
+
+		line = terminalImage.ReplaceAllStringFunc(line, func(image string) string {
+			alt := terminalImage.FindStringSubmatch(image)[1]
+			if alt == "" {
+				return "[image]"
+			}
+			return "[image: " + alt + "]"
+		})
+		line = terminalLink.ReplaceAllString(line, "$1 "+dim+"<$2>"+reset)
+
+		if logseqHeading.MatchString(trimmed) {
+			line = bold + strings.TrimLeft(trimmed, "# ") + reset
+		}
+		out.WriteString(line + "\n")
+	}
+
+	return out.String()
+}
+
+func terminalDate(value string) string {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.Local().Format("2006-01-02 15:04")
+	}
+	return value
+}
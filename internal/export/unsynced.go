@@ -0,0 +1,165 @@
+package export
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"instapaper-cli/internal/model"
+)
+
+// How ExportAll writes articles whose content has not been fetched
+const (
+	UnsyncedSkip     = "skip"     // leave them out (default)
+	UnsyncedStub     = "stub"     // a markdown file with frontmatter and a link to the source
+	UnsyncedLinkFile = "linkfile" // a .url or .webloc shortcut that opens the source
+	UnsyncedIndex    = "index"    // one list of links in UnfetchedIndexFilename
+)
+
+// Link file formats for UnsyncedLinkFile
+const (
+	LinkFormatURL    = "url"    // Windows Internet Shortcut, also opened by most Linux and macOS desktops
+	LinkFormatWebloc = "webloc" // macOS Finder
+)
+
+// UnfetchedIndexFilename is written to the export directory in UnsyncedIndex mode
+const UnfetchedIndexFilename = "unfetched.md"
+
+// unsyncedMode returns the mode for unfetched articles, defaulting to skip
+func (opts ExportAllOptions) unsyncedMode() string {
+	if opts.UnsyncedMode == "" {
+		return UnsyncedSkip
+	}
+	return opts.UnsyncedMode
+}
+
+// validateUnsynced checks the unsynced mode and link format against the export format
+func (opts ExportAllOptions) validateUnsynced() error {
+	switch opts.unsyncedMode() {
+	case UnsyncedSkip, UnsyncedStub:
+	case UnsyncedLinkFile, UnsyncedIndex:
+		if opts.Format == FormatLogseq {
+			return fmt.Errorf("unsynced mode %s is not supported by the %s format: use %s or %s", opts.UnsyncedMode, FormatLogseq, UnsyncedStub, UnsyncedSkip)
+		}
+	default:
+		return fmt.Errorf("invalid unsynced mode %q: use %s, %s, %s, or %s", opts.UnsyncedMode, UnsyncedStub, UnsyncedLinkFile, UnsyncedIndex, UnsyncedSkip)
+	}
+
+	switch opts.LinkFormat {
+	case "", LinkFormatURL, LinkFormatWebloc:
+		return nil
+	default:
+		return fmt.Errorf("invalid link format %q: use %s or %s", opts.LinkFormat, LinkFormatURL, LinkFormatWebloc)
+	}
+}
+
+// exportLinkFile writes a shortcut to an unfetched article where its markdown file would go
+func (e *Export) exportLinkFile(article model.ArticleWithDetails, opts ExportAllOptions, manifest *Manifest) (string, error) {
+	markdownPath, err := e.articlePath(article, opts, manifest)
+	if err != nil {
+		return "", err
+	}
+
+	format := opts.LinkFormat
+	if format == "" {
+		format = LinkFormatURL
+	}
+
+	var content string
+	if format == LinkFormatWebloc {
+		content = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>URL</key>
+	<string>` + html.EscapeString(article.URL) + `</string>
+</dict>
+</plist>
+`
+	} else {
+		content = "[InternetShortcut]\r\nURL=" + article.URL + "\r\n"
+	}
+
+	path := strings.TrimSuffix(markdownPath, ".md") + "." + format
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write link file: %w", err)
+	}
+	return path, nil
+}
+
+// removeLinkFiles deletes shortcuts left by earlier exports once the article has a markdown file
+func removeLinkFiles(markdownPath string) {
+	base := strings.TrimSuffix(markdownPath, ".md")
+	for _, format := range []string{LinkFormatURL, LinkFormatWebloc} {
+		os.Remove(base + "." + format)
+	}
+}
+
+// writeUnfetchedIndex lists unfetched articles by folder, newest first, in a single markdown file.
+// With no unfetched articles, an index left by an earlier export is removed.
+func writeUnfetchedIndex(dir string, articles []model.ArticleWithDetails) (string, error) {
+	path := filepath.Join(dir, UnfetchedIndexFilename)
+
+	if len(articles) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to remove stale index: %w", err)
+		}
+		return "", nil
+	}
+
+	byFolder := make(map[string][]model.ArticleWithDetails)
+	for _, article := range articles {
+		folder := ""
+		if article.FolderPath != nil {
+			folder = *article.FolderPath
+		}
+		byFolder[folder] = append(byFolder[folder], article)
+	}
+
+	folders := make([]string, 0, len(byFolder))
+	for folder := range byFolder {
+		folders = append(folders, folder)
+	}
+	sort.Strings(folders)
+
+	var index strings.Builder
+	index.WriteString("# Unfetched articles\n\n")
+	index.WriteString(fmt.Sprintf("%d articles whose content has not been fetched yet.\n", len(articles)))
+
+	for _, folder := range folders {
+		name := folder
+		if name == "" {
+			name = "No folder"
+		}
+		index.WriteString("\n## " + name + "\n\n")
+
+		entries := byFolder[folder]
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].InstapaperedAt > entries[j].InstapaperedAt
+		})
+
+		for _, article := range entries {
+			title := strings.TrimSpace(strings.ReplaceAll(article.Title, "\n", " "))
+			if title == "" {
+				title = article.URL
+			}
+			line := fmt.Sprintf("- [%s](<%s>)", strings.NewReplacer("[", `\[`, "]", `\]`).Replace(title), article.URL)
+			if saved, err := time.Parse(time.RFC3339, article.InstapaperedAt); err == nil {
+				line += " — saved " + saved.Format("2006-01-02")
+			}
+			if len(article.Tags) > 0 {
+				line += " — " + strings.Join(article.Tags, ", ")
+			}
+			index.WriteString(line + "\n")
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(index.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write unfetched index: %w", err)
+	}
+	return path, nil
+}
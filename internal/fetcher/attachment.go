@@ -0,0 +1,103 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"instapaper-cli/internal/db"
+	"instapaper-cli/internal/language"
+	"instapaper-cli/internal/metrics"
+	"instapaper-cli/internal/model"
+	"instapaper-cli/internal/similarity"
+	"instapaper-cli/internal/util"
+)
+
+// isAttachment reports whether a response is a file to keep as it is (PDF, image, slide deck, ...)
+// rather than a page for readability. Rendered and archived pages without a type count as pages.
+func isAttachment(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "text/"), strings.HasSuffix(mediaType, "+xml"), strings.HasSuffix(mediaType, "/xml"):
+		return false
+	case mediaType == "application/xhtml+xml", mediaType == "application/json":
+		return false
+	}
+	return true
+}
+
+// attachmentFilename names a downloaded file after its Content-Disposition, its URL path, or its type
+func attachmentFilename(pg *page) string {
+	if _, params, err := mime.ParseMediaType(pg.disposition); err == nil && params["filename"] != "" {
+		return path.Base(params["filename"])
+	}
+
+	if u, err := url.Parse(pg.finalURL); err == nil {
+		if name := path.Base(u.Path); name != "." && name != "/" && name != "" {
+			if unescaped, err := url.PathUnescape(name); err == nil {
+				return unescaped
+			}
+			return name
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(pg.contentType)
+	if extensions, _ := mime.ExtensionsByType(mediaType); len(extensions) > 0 {
+		return "attachment" + extensions[0]
+	}
+	return "attachment"
+}
+
+// storeAttachment keeps a downloaded file as the article's attachment and marks the article fetched,
+// with a short markdown body linking the file so it still shows up in search and exports
+func (f *Fetcher) storeAttachment(ctx context.Context, article model.Article, pg *page) error {
+	filename := attachmentFilename(pg)
+	mediaType, _, _ := mime.ParseMediaType(pg.contentType)
+
+	attachment, err := f.db.StoreAttachment(article.ID, filename, mediaType, &pg.finalURL, pg.body)
+	if err != nil {
+		return err
+	}
+
+	markdown := fmt.Sprintf("Attached file: [%s](%s) (%s, %d bytes)\n", filename, pg.finalURL, mediaType, attachment.Size)
+
+	title := article.Title
+	if title == "" || title == article.URL {
+		title = strings.TrimSuffix(filename, path.Ext(filename))
+	}
+
+	storedContent, err := f.db.EncodeContent(markdown)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.db.Exec(`
+		UPDATE articles
+		SET synced_at = ?, content_md = ?, title = ?, title_norm = ?, final_url = ?, simhash = ?, extraction_quality = NULL, language = NULLIF(?, ''), outline = ?,
+		    status_code = ?, status_text = ?, failed_count = 0, sync_failed_at = NULL, failure_class = NULL, next_retry_at = NULL
+		WHERE id = ?
+	`, time.Now().UTC().Format(time.RFC3339), storedContent, title, util.NormalizeText(title), pg.finalURL, int64(similarity.Signature(markdown)), language.Detect(title+"\n"+markdown), db.OutlineJSON(markdown), pg.statusCode, "OK", article.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update article: %w", err)
+	}
+
+	f.updateFTS(ctx, article.ID)
+
+	metrics.FetchResults.Inc("success", strconv.Itoa(pg.statusCode))
+
+	f.logger.Printf("Stored attachment for article %d: %s (%s)", article.ID, filename, mediaType)
+	return nil
+}
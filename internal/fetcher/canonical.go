@@ -0,0 +1,87 @@
+package fetcher
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"instapaper-cli/internal/db"
+	"instapaper-cli/internal/model"
+	"instapaper-cli/internal/util"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// extractCanonicalURL returns the page's canonical URL from <link rel="canonical">
+// or og:url, resolved against the final request URL. It returns "" if none is declared.
+func extractCanonicalURL(body []byte, base *url.URL) string {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	candidates := []string{
+		doc.Find(`link[rel~="canonical"]`).First().AttrOr("href", ""),
+		doc.Find(`meta[property="og:url"]`).First().AttrOr("content", ""),
+	}
+
+	for _, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "" {
+			continue
+		}
+
+		ref, err := url.Parse(candidate)
+		if err != nil {
+			continue
+		}
+
+		resolved := base.ResolveReference(ref)
+		if resolved.Scheme != "http" && resolved.Scheme != "https" {
+			continue
+		}
+
+		canonical, err := util.CanonicalizeURL(resolved.String())
+		if err != nil {
+			continue
+		}
+
+		return canonical
+	}
+
+	return ""
+}
+
+// adoptCanonicalURL replaces the article URL with its canonical form unless
+// another article already uses that URL
+func (f *Fetcher) adoptCanonicalURL(article model.Article, canonicalURL string) error {
+	if canonicalURL == "" || canonicalURL == article.URL {
+		return nil
+	}
+
+	var duplicateID int64
+	err := f.db.Get(&duplicateID, "SELECT id FROM articles WHERE url = ? AND id != ?", canonicalURL, article.ID)
+	if err == nil {
+		f.logger.Printf("Article %d canonical URL %s already belongs to article %d; keeping original URL", article.ID, canonicalURL, duplicateID)
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check for duplicate URL: %w", err)
+	}
+
+	if _, err := f.db.Exec("UPDATE articles SET url = ? WHERE id = ?", canonicalURL, article.ID); err != nil {
+		return fmt.Errorf("failed to update article URL: %w", err)
+	}
+	// The canonical URL was an alias until now, and the old URL becomes one
+	if _, err := f.db.Exec("DELETE FROM url_aliases WHERE url = ?", canonicalURL); err != nil {
+		return fmt.Errorf("failed to update URL aliases: %w", err)
+	}
+	if err := f.db.AddURLAliases(article.ID, db.URLAliasPrevious, article.URL); err != nil {
+		return err
+	}
+
+	f.logger.Printf("Updated article %d URL to canonical %s", article.ID, canonicalURL)
+	return nil
+}
@@ -0,0 +1,58 @@
+package fetcher
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"instapaper-cli/internal/db"
+	"instapaper-cli/internal/language"
+	"instapaper-cli/internal/similarity"
+	"instapaper-cli/internal/util"
+)
+
+// StoreDocument stores an HTML document that did not come from the article's URL, such as a
+// newsletter email, as the article's content. Readability picks out the text when it can; layouts
+// it rejects, like the tables of many newsletters, are converted whole. An empty title keeps the
+// article's own. Images are always kept; opts.NoPrettify skips the Markdown cleanup.
+func (f *Fetcher) StoreDocument(articleID int64, title string, body []byte, baseURL *url.URL, opts FetchOptions) error {
+	opts.KeepImages = true
+
+	content := string(body)
+	if extracted, _, err := f.extract(body, baseURL, nil, opts); err == nil && strings.TrimSpace(extracted.Content) != "" {
+		content = extracted.Content
+		if title == "" {
+			title = extracted.Title
+		}
+	}
+
+	markdown, err := newMarkdownConverter().ConvertString(content)
+	if err != nil {
+		return fmt.Errorf("failed to convert document to markdown: %w", err)
+	}
+	if !opts.NoPrettify {
+		markdown = f.prettifyMarkdown(markdown)
+	}
+
+	storedContent, err := f.db.EncodeContent(markdown)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.db.Exec(`
+		UPDATE articles
+		SET synced_at = ?, content_md = ?, title = COALESCE(NULLIF(?, ''), title), title_norm = COALESCE(NULLIF(?, ''), title_norm),
+		    simhash = ?, extraction_quality = NULL, language = NULLIF(?, ''), outline = ?,
+		    status_code = NULL, status_text = ?, failed_count = 0, sync_failed_at = NULL, failure_class = NULL, next_retry_at = NULL
+		WHERE id = ?
+	`, time.Now().UTC().Format(time.RFC3339), storedContent, title, util.NormalizeText(title), int64(similarity.Signature(markdown)), language.Detect(title+"\n"+markdown), db.OutlineJSON(markdown), "OK", articleID)
+	if err != nil {
+		return fmt.Errorf("failed to update article: %w", err)
+	}
+
+	if err := f.db.UpsertArticleFTS(articleID); err != nil {
+		f.logger.Printf("Warning: failed to update FTS for article %d: %v", articleID, err)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// renderTimeout bounds a render_command run, which starts a browser and waits for scripts
+const renderTimeout = 60 * time.Second
+
+// waybackAvailabilityURL finds the closest Wayback Machine snapshot of a URL
+const waybackAvailabilityURL = "https://archive.org/wayback/available?url="
+
+// page is a downloaded document
+type page struct {
+	body       []byte
+	baseURL    *url.URL // resolves relative links; the article's URL for archived copies
+	finalURL   string   // where the content was actually served from
+	redirects  []string // what a live download was redirected through, in order, before finalURL
+	statusCode int
+	// contentType and disposition are the response headers; both are empty for rendered pages
+	contentType string
+	disposition string
+}
+
+// fetchFailure is a failed download, recorded against the article with its class
+type fetchFailure struct {
+	class      FailureClass
+	statusCode int
+	text       string
+}
+
+func (e *fetchFailure) Error() string {
+	return e.text
+}
+
+// download fetches an article's page as its domain rule says: live, rendered, or archived
+func (f *Fetcher) download(ctx context.Context, rawURL string, rule *DomainRule, rules *DomainRules) (*page, error) {
+	if rule != nil && rule.Wayback == WaybackAlways {
+		return f.downloadWayback(ctx, rawURL)
+	}
+
+	if rule != nil && rule.limiter != nil {
+		if err := rule.limiter.wait(ctx, 1); err != nil {
+			return nil, err
+		}
+	}
+
+	if rule != nil && rule.Render == "js" {
+		return f.render(ctx, rawURL, rules.RenderCommand)
+	}
+
+	var headers map[string]string
+	if rule != nil {
+		headers = rule.Headers
+	}
+	return f.get(ctx, rawURL, headers)
+}
+
+// get performs a throttled GET with the per-request timeout and returns the body of a 200 response
+func (f *Fetcher) get(ctx context.Context, rawURL string, headers map[string]string) (*page, error) {
+	requests, bandwidth := currentLimits()
+
+	// Waiting for a request slot happens before the per-request timeout starts
+	if requests != nil {
+		if err := requests.wait(ctx, 1); err != nil {
+			return nil, err
+		}
+	}
+
+	reqCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	timeout := time.AfterFunc(requestTimeout, func() { cancel(errRequestTimeout) })
+	defer timeout.Stop()
+
+	req, err := http.NewRequestWithContext(reqCtx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, &fetchFailure{class: FailureNetwork, text: fmt.Sprintf("RequestError: %v", err)}
+	}
+
+	req.Header.Set("User-Agent", "instapaper-cli/1.0 (+https://github.com/user/instapaper-cli)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		err = requestError(reqCtx, err)
+		return nil, &fetchFailure{class: classifyNetworkError(err), text: fmt.Sprintf("NetworkError: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Reading an error page's body lets its connection be reused
+		io.Copy(io.Discard, io.LimitReader(resp.Body, drainLimit))
+		return nil, &fetchFailure{class: classifyStatus(resp.StatusCode), statusCode: resp.StatusCode, text: resp.Status}
+	}
+
+	var bodyReader io.Reader = resp.Body
+	if bandwidth != nil {
+		bodyReader = &throttledReader{ctx: reqCtx, r: resp.Body, limiter: bandwidth, timeout: timeout}
+	}
+
+	// The bandwidth limit counts the bytes on the wire, so the body is decoded after throttling
+	bodyReader, err = decodeBody(bodyReader, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return nil, &fetchFailure{class: FailureRead, statusCode: resp.StatusCode, text: fmt.Sprintf("ReadError: %v", err)}
+	}
+
+	body, err := io.ReadAll(bodyReader)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		class := FailureRead
+		if err = requestError(reqCtx, err); err == errRequestTimeout {
+			class = FailureTimeout
+		}
+		return nil, &fetchFailure{class: class, statusCode: resp.StatusCode, text: fmt.Sprintf("ReadError: %v", err)}
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if isHTML(contentType) {
+		body = toUTF8(body, contentType)
+	}
+
+	// Each request made for a redirect keeps the response that caused it
+	var redirects []string
+	for r := resp.Request; r.Response != nil; r = r.Response.Request {
+		redirects = append([]string{r.Response.Request.URL.String()}, redirects...)
+	}
+
+	return &page{
+		body:        body,
+		baseURL:     resp.Request.URL,
+		finalURL:    resp.Request.URL.String(),
+		redirects:   redirects,
+		statusCode:  resp.StatusCode,
+		contentType: contentType,
+		disposition: resp.Header.Get("Content-Disposition"),
+	}, nil
+}
+
+// render runs the render command and takes its stdout as the page's HTML
+func (f *Fetcher) render(ctx context.Context, rawURL, command string) (*page, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, &fetchFailure{class: FailureNetwork, text: fmt.Sprintf("RequestError: %v", err)}
+	}
+
+	quoted := "'" + strings.ReplaceAll(rawURL, "'", `'\''`) + "'"
+	if strings.Contains(command, "{url}") {
+		command = strings.ReplaceAll(command, "{url}", quoted)
+	} else {
+		command += " " + quoted
+	}
+
+	renderCtx, cancel := context.WithTimeout(ctx, renderTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(renderCtx, "sh", "-c", command)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if renderCtx.Err() != nil {
+			return nil, &fetchFailure{class: FailureTimeout, text: fmt.Sprintf("RenderError: timed out after %s", renderTimeout)}
+		}
+		return nil, &fetchFailure{class: FailureRead, text: fmt.Sprintf("RenderError: %v: %s", err, strings.TrimSpace(stderr.String()))}
+	}
+
+	if stdout.Len() == 0 {
+		return nil, &fetchFailure{class: FailureRead, text: "RenderError: render command printed nothing"}
+	}
+
+	return &page{body: stdout.Bytes(), baseURL: u, finalURL: rawURL, statusCode: http.StatusOK}, nil
+}
+
+// downloadWayback fetches the most recent Wayback Machine snapshot of a URL, unmodified by the archive
+func (f *Fetcher) downloadWayback(ctx context.Context, rawURL string) (*page, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, &fetchFailure{class: FailureNetwork, text: fmt.Sprintf("RequestError: %v", err)}
+	}
+
+	availability, err := f.get(ctx, waybackAvailabilityURL+url.QueryEscape(rawURL), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		ArchivedSnapshots struct {
+			Closest struct {
+				Available bool   `json:"available"`
+				Timestamp string `json:"timestamp"`
+			} `json:"closest"`
+		} `json:"archived_snapshots"`
+	}
+	if err := json.Unmarshal(availability.body, &response); err != nil {
+		return nil, &fetchFailure{class: FailureRead, text: fmt.Sprintf("WaybackError: invalid availability response: %v", err)}
+	}
+
+	closest := response.ArchivedSnapshots.Closest
+	if !closest.Available || closest.Timestamp == "" {
+		return nil, &fetchFailure{class: FailureClientError, statusCode: http.StatusNotFound, text: "WaybackError: no snapshot available"}
+	}
+
+	// The id_ flag returns the page as captured, without the archive's toolbar and rewritten links
+	snapshot, err := f.get(ctx, fmt.Sprintf("https://web.archive.org/web/%sid_/%s", closest.Timestamp, rawURL), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The snapshot's URLs are the archive's, not other URLs of the article
+	snapshot.baseURL = u
+	snapshot.redirects = nil
+	return snapshot, nil
+}
@@ -0,0 +1,81 @@
+package fetcher
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andybalholm/brotli"
+	"golang.org/x/net/html/charset"
+)
+
+// acceptEncoding lists the content codings decodeBody understands. Setting it turns off the
+// transport's own gzip handling, which does not cover brotli.
+const acceptEncoding = "gzip, deflate, br"
+
+// decodeBody undoes a response's Content-Encoding. Codings are listed in the order they were
+// applied, so they are undone from last to first.
+func decodeBody(r io.Reader, contentEncoding string) (io.Reader, error) {
+	codings := strings.Split(contentEncoding, ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		switch coding := strings.ToLower(strings.TrimSpace(codings[i])); coding {
+		case "", "identity":
+		case "gzip", "x-gzip":
+			gz, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read gzip body: %w", err)
+			}
+			r = gz
+		case "deflate":
+			r = newDeflateReader(r)
+		case "br":
+			r = brotli.NewReader(r)
+		default:
+			return nil, fmt.Errorf("unsupported content encoding %q", coding)
+		}
+	}
+	return r, nil
+}
+
+// newDeflateReader reads a deflate body. HTTP's deflate is zlib-wrapped, but some servers send
+// raw deflate, which is told apart by the zlib header.
+func newDeflateReader(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		if zr, err := zlib.NewReader(br); err == nil {
+			return zr
+		}
+	}
+	return flate.NewReader(br)
+}
+
+// isHTML reports whether a Content-Type is an HTML page
+func isHTML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
+// toUTF8 converts an HTML page to UTF-8, as readability reads it. The charset comes from a byte
+// order mark or the Content-Type, else from a meta tag unless the page is valid UTF-8, else the
+// page is Windows-1252, as browsers assume.
+func toUTF8(body []byte, contentType string) []byte {
+	enc, name, certain := charset.DetermineEncoding(body, contentType)
+	// Only the first 1024 bytes are sniffed, and meta tags are often copied from old templates
+	if name == "utf-8" || (!certain && utf8.Valid(body)) {
+		return bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return body
+	}
+	return decoded
+}
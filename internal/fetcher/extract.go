@@ -0,0 +1,98 @@
+package fetcher
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/go-shiori/go-readability"
+	"golang.org/x/net/html/atom"
+)
+
+// extract finds the article in a page with readability and scores the extraction's quality. A
+// domain rule's content selector picks the content container explicitly; readability still
+// supplies the title, byline, and dates.
+func (f *Fetcher) extract(body []byte, pageURL *url.URL, rule *DomainRule, opts FetchOptions) (readability.Article, float64, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return readability.Article{}, 0, fmt.Errorf("failed to parse page: %w", err)
+	}
+	pageChars := visibleTextLength(doc.Get(0))
+
+	// Find the selected container before protectImages renames elements a selector may name
+	var selected string
+	if rule != nil && rule.ContentSelector != "" {
+		if match := doc.Find(rule.ContentSelector).First(); match.Length() > 0 {
+			absolutizeLinks(match, pageURL)
+			if selected, err = goquery.OuterHtml(match); err != nil {
+				return readability.Article{}, 0, fmt.Errorf("failed to render selected content: %w", err)
+			}
+		} else {
+			f.logger.Printf("Content selector %q matched nothing on %s, using readability", rule.ContentSelector, pageURL)
+		}
+	}
+
+	if opts.KeepImages {
+		protectImages(doc)
+	}
+	// Readability unwraps a box holding a single paragraph, taking its class with it
+	removeSignupBoxes(doc.Selection)
+
+	parser := readability.NewParser()
+	// Keep classes for the markdown rules: code language hints and signup boxes are found by class
+	parser.KeepClasses = true
+	if opts.MinContentLength > 0 {
+		parser.CharThresholds = opts.MinContentLength
+	}
+	article, err := parser.ParseDocument(doc.Get(0), pageURL)
+
+	// The selected container is the content even when readability finds nothing
+	if selected != "" {
+		article.Content = selected
+		err = nil
+	}
+	if err != nil {
+		return article, 0, err
+	}
+	return article, extractionQuality(pageChars, article.Content), nil
+}
+
+// absolutizeLinks resolves relative links and image sources, as readability does for its content
+func absolutizeLinks(content *goquery.Selection, pageURL *url.URL) {
+	content.Find("[href], [src]").AddSelection(content).Each(func(i int, s *goquery.Selection) {
+		for _, attr := range []string{"href", "src"} {
+			value, ok := s.Attr(attr)
+			if !ok || strings.HasPrefix(value, "#") {
+				continue
+			}
+			if ref, err := url.Parse(strings.TrimSpace(value)); err == nil {
+				s.SetAttr(attr, pageURL.ResolveReference(ref).String())
+			}
+		}
+	})
+}
+
+// protectImages turns image containers without paragraphs into figures, which readability's
+// clutter cleanup leaves alone, so galleries and image-heavy blocks survive extraction
+func protectImages(doc *goquery.Document) {
+	doc.Find("div, ul").Each(func(i int, s *goquery.Selection) {
+		if s.Find("img, picture").Length() == 0 || s.Find("p").Length() > 0 {
+			return
+		}
+		node := s.Get(0)
+		node.Data = "figure"
+		node.DataAtom = atom.Figure
+	})
+}
+
+// withByline puts the byline readability lifted out of the content back as the first line,
+// unless the content still has it
+func withByline(markdown, byline string) string {
+	byline = strings.TrimSpace(byline)
+	if byline == "" || strings.Contains(markdown, byline) {
+		return markdown
+	}
+	return "*" + byline + "*\n\n" + markdown
+}
@@ -0,0 +1,66 @@
+package fetcher
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const extractTestPage = `<!DOCTYPE html>
+<html><head><title>Generics in practice</title></head>
+<body>
+<nav><a href="/">Home</a> <a href="/about">About</a></nav>
+<article>
+<h1>Generics in practice</h1>
+<p>Go gained type parameters in version 1.18, and with them a new way to write reusable
+containers and algorithms without giving up static types or reaching for interface{}.</p>
+<p>This article walks through a small generic function, explains how the constraint is
+inferred at the call site, and shows the code the compiler is happy with today.</p>
+<pre><code class="language-go">func Map[T, U any](s []T, f func(T) U) []U {
+	r := make([]U, 0, len(s))
+	for _, v := range s {
+		r = append(r, f(v))
+	}
+	return r
+}</code></pre>
+<p>The function works for any slice, and the compiler checks every call, so a mistake in the
+mapping function is reported where it is made rather than at run time.</p>
+<div class="newsletter-signup"><p>Get posts like this one in your inbox every week, no spam.</p></div>
+<p>Type parameters are not a replacement for interfaces, but for containers and helpers like
+this one they remove a lot of copy and paste from everyday Go code.</p>
+</article>
+</body></html>`
+
+func TestExtractKeepsClassHints(t *testing.T) {
+	pageURL, _ := url.Parse("https://example.com/generics")
+
+	article, _, err := New(nil).extract([]byte(extractTestPage), pageURL, nil, FetchOptions{})
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	markdown, err := newMarkdownConverter().ConvertString(article.Content)
+	if err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+
+	if !strings.Contains(markdown, "```go\nfunc Map") {
+		t.Errorf("code block lost its language hint:\n%s", markdown)
+	}
+	if strings.Contains(markdown, "in your inbox") {
+		t.Errorf("newsletter signup box was not removed:\n%s", markdown)
+	}
+}
+
+func TestExtractKeepsArticleInSignupNamedWrapper(t *testing.T) {
+	pageURL, _ := url.Parse("https://example.com/generics")
+	page := strings.Replace(extractTestPage, "<article>", `<div id="newsletter-app"><article>`, 1)
+	page = strings.Replace(page, "</article>", "</article></div>", 1)
+
+	article, _, err := New(nil).extract([]byte(page), pageURL, nil, FetchOptions{})
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if !strings.Contains(article.Content, "type parameters") {
+		t.Errorf("article inside a wrapper named like a signup box was dropped:\n%s", article.Content)
+	}
+}
@@ -2,24 +2,32 @@ package fetcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"instapaper-cli/internal/db"
+	"instapaper-cli/internal/language"
+	"instapaper-cli/internal/metrics"
 	"instapaper-cli/internal/model"
-
-	md "github.com/JohannesKaufmann/html-to-markdown"
-	"github.com/go-shiori/go-readability"
+	"instapaper-cli/internal/search"
+	"instapaper-cli/internal/similarity"
+	"instapaper-cli/internal/textnorm"
+	"instapaper-cli/internal/titles"
+	"instapaper-cli/internal/util"
 )
 
 type Fetcher struct {
 	db     *db.DB
 	client *http.Client
 	logger *log.Logger
+	// fts batches search index updates while FetchArticles runs, see updateFTS
+	fts *db.FTSBatch
 }
 
 type FetchOptions struct {
@@ -29,15 +37,42 @@ type FetchOptions struct {
 	PreferExtracted bool
 	StoreRaw        bool
 	LogPath         string
+	NoPrettify      bool
+	Priority        PriorityWeights
+	Selector        search.Selector
+	UpdateURL       bool
+	Titles          *titles.Cleaner // nil uses titles.Default()
+	Rules           *DomainRules    // nil fetches every domain the same way
+	// MinContentLength is the fewest characters readability accepts as the article; 0 keeps its default of 500
+	MinContentLength int
+	// KeepImages keeps image galleries and figures that readability would drop as clutter
+	KeepImages bool
+	// KeepByline keeps the author byline readability lifts out of the content, as its first line
+	KeepByline bool
+	// LowQuality refetches fetched articles whose extraction scored below db.LowQualityThreshold,
+	// instead of fetching unfetched ones
+	LowQuality bool
+	// Garbled refetches fetched articles with at least db.GarbledThreshold characters decoded with
+	// the wrong charset, instead of fetching unfetched ones
+	Garbled bool
+	// Normalize replaces typographic quotes, spaces, and entities in titles and content; nil keeps them
+	Normalize *textnorm.Normalizer
+	// DomainCap skips articles of domains with this many fetch attempts since local midnight, 0 for no cap
+	DomainCap int
+}
+
+// FetchResult summarizes a fetch run
+type FetchResult struct {
+	Candidates int               `json:"candidates"`
+	Fetched    int               `json:"fetched"`
+	Skipped    int               `json:"skipped"`
+	Failed     int               `json:"failed"`
+	Errors     []model.ItemError `json:"errors,omitempty"`
 }
 
 func New(database *db.DB) *Fetcher {
-	client := &http.Client{
-		Timeout: 20 * time.Second,
-		Transport: &http.Transport{
-			DisableCompression: false,
-		},
-	}
+	// The per-request timeout lives in fetchSingleArticle so it can pause while the bandwidth limit holds back a body
+	client := &http.Client{Transport: sharedTransport}
 
 	return &Fetcher{
 		db:     database,
@@ -46,172 +81,418 @@ func New(database *db.DB) *Fetcher {
 	}
 }
 
-func (f *Fetcher) FetchArticles(opts FetchOptions) error {
+// FetchArticles fetches candidate articles one at a time. When ctx is cancelled the
+// run stops before the next article and returns the partial result with ctx's error.
+func (f *Fetcher) FetchArticles(ctx context.Context, opts FetchOptions) (*FetchResult, error) {
 	if opts.LogPath != "" {
 		logFile, err := os.OpenFile(opts.LogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
-			return fmt.Errorf("failed to open log file: %w", err)
+			return nil, fmt.Errorf("failed to open log file: %w", err)
 		}
 		defer logFile.Close()
 		f.logger = log.New(logFile, "", log.LstdFlags)
 	}
 
-	articles, err := f.getCandidateArticles(opts)
+	articles, err := f.getCandidateArticles(ctx, opts)
 	if err != nil {
-		return fmt.Errorf("failed to get candidate articles: %w", err)
+		return nil, fmt.Errorf("failed to get candidate articles: %w", err)
 	}
 
 	f.logger.Printf("Found %d articles to fetch", len(articles))
 
+	f.fts = f.db.NewFTSBatch(db.DefaultFTSBatchSize, db.DefaultFTSBatchInterval)
+	defer func() {
+		if err := f.fts.Flush(context.WithoutCancel(ctx)); err != nil {
+			f.logger.Printf("Warning: %v", err)
+		}
+		f.fts = nil
+	}()
+
+	result := &FetchResult{Candidates: len(articles)}
+
 	for i, article := range articles {
+		if err := ctx.Err(); err != nil {
+			f.logger.Printf("Fetch interrupted after %d/%d articles", i, len(articles))
+			return result, err
+		}
+
+		if rule := opts.Rules.Match(article.URL); rule != nil && rule.Skip {
+			if _, err := f.db.SetArticlesObsolete([]int64{article.ID}, true, db.ObsoleteAudit{
+				Reason:   "domain is on the fetch skip list",
+				Criteria: "--domain-rules skip: " + rule.Match,
+			}); err != nil {
+				return result, fmt.Errorf("failed to mark article %d obsolete: %w", article.ID, err)
+			}
+			f.logger.Printf("Skipping article %d: %s is on the skip list, marked obsolete", article.ID, rule.Match)
+			result.Skipped++
+			continue
+		}
+
 		f.logger.Printf("Fetching article %d/%d: %s", i+1, len(articles), article.URL)
 
-		if err := f.fetchSingleArticle(article, opts); err != nil {
+		if err := f.fetchSingleArticle(ctx, article, opts); err != nil {
+			// A cancelled run is not the article's fault, so it is not counted as a failure
+			if ctx.Err() != nil {
+				f.logger.Printf("Fetch interrupted after %d/%d articles", i, len(articles))
+				return result, ctx.Err()
+			}
 			f.logger.Printf("Failed to fetch article %d: %v", article.ID, err)
+			result.Failed++
+			result.Errors = append(result.Errors, model.ItemError{ID: article.ID, URL: article.URL, Error: err.Error()})
 			continue
 		}
 
-		time.Sleep(500 * time.Millisecond)
+		result.Fetched++
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(500 * time.Millisecond):
+		}
 	}
 
 	f.logger.Printf("Fetch completed")
-	return nil
+	return result, nil
 }
 
-func (f *Fetcher) getCandidateArticles(opts FetchOptions) ([]model.Article, error) {
+func (f *Fetcher) getCandidateArticles(ctx context.Context, opts FetchOptions) ([]model.Article, error) {
 	query := `
-		SELECT id, url, title, instapapered_at
-		FROM articles
-		WHERE synced_at IS NULL
-		AND failed_count < 5
-		AND (sync_failed_at IS NULL OR sync_failed_at <= datetime('now', '-1 hour'))
-		AND obsolete = FALSE
+		SELECT a.id, a.url, a.title, a.instapapered_at, a.failed_count
+		FROM articles a
+		WHERE a.synced_at IS NULL
+		AND (a.sync_failed_at IS NULL OR a.next_retry_at <= ?)
+		AND a.obsolete = FALSE
 	`
 
-	args := []interface{}{}
+	// Failed articles wait for next_retry_at; it is NULL once their retry policy gives up
+	args := []interface{}{time.Now().UTC().Format(time.RFC3339)}
+
+	if opts.LowQuality {
+		query = `
+			SELECT a.id, a.url, a.title, a.instapapered_at, a.failed_count
+			FROM articles a
+			WHERE a.synced_at IS NOT NULL AND a.extraction_quality < ?
+			AND a.obsolete = FALSE
+		`
+		args = []interface{}{db.LowQualityThreshold}
+	}
+
+	if opts.Garbled {
+		query = `
+			SELECT a.id, a.url, a.title, a.instapapered_at, a.failed_count
+			FROM articles a
+			WHERE a.synced_at IS NOT NULL AND garbled_chars(content_text(a.content_md)) >= ?
+			AND a.obsolete = FALSE
+		`
+		args = []interface{}{db.GarbledThreshold}
+	}
 
 	if opts.SearchPhrase != "" {
-		query += ` AND (url LIKE ? OR title LIKE ?)`
+		query += ` AND (a.url LIKE ? OR a.title_norm LIKE normalize_text(?))`
 		searchPattern := "%" + opts.SearchPhrase + "%"
 		args = append(args, searchPattern, searchPattern)
 	}
 
+	selectorConditions, selectorArgs := opts.Selector.Conditions()
+	for _, condition := range selectorConditions {
+		query += ` AND ` + condition
+	}
+	args = append(args, selectorArgs...)
+
 	switch opts.Order {
-	case "newest":
-		query += ` ORDER BY instapapered_at DESC`
+	case "newest", "priority":
+		query += ` ORDER BY a.instapapered_at DESC`
 	default:
-		query += ` ORDER BY instapapered_at ASC`
+		query += ` ORDER BY a.instapapered_at ASC`
 	}
 
-	if opts.Limit > 0 {
+	// Priority ordering scores every candidate, and the domain cap drops some, so the limit is applied afterwards
+	if opts.Limit > 0 && opts.Order != "priority" && opts.DomainCap <= 0 {
 		query += ` LIMIT ?`
 		args = append(args, opts.Limit)
 	}
 
 	var articles []model.Article
-	if err := f.db.Select(&articles, query, args...); err != nil {
+	if err := f.db.SelectContext(ctx, &articles, query, args...); err != nil {
 		return nil, err
 	}
 
+	if opts.Order == "priority" {
+		prioritized, err := f.prioritizeArticles(articles, opts.Priority)
+		if err != nil {
+			return nil, err
+		}
+		articles = prioritized
+	}
+
+	if opts.DomainCap > 0 {
+		capped, err := f.capDomains(ctx, articles, opts.DomainCap)
+		if err != nil {
+			return nil, err
+		}
+		articles = capped
+	}
+
+	if opts.Limit > 0 && len(articles) > opts.Limit {
+		articles = articles[:opts.Limit]
+	}
+
 	return articles, nil
 }
 
-func (f *Fetcher) fetchSingleArticle(article model.Article, opts FetchOptions) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
-	defer cancel()
+// capDomains drops the articles of domains that already had perDomain fetch attempts today, counting
+// the candidates kept towards their domain's cap
+func (f *Fetcher) capDomains(ctx context.Context, articles []model.Article, perDomain int) ([]model.Article, error) {
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).UTC().Format(time.RFC3339)
+
+	var attemptedURLs []string
+	if err := f.db.SelectContext(ctx, &attemptedURLs, `
+		SELECT url FROM articles
+		WHERE synced_at >= ? OR sync_failed_at >= ?
+	`, midnight, midnight); err != nil {
+		return nil, fmt.Errorf("failed to count today's fetches: %w", err)
+	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", article.URL, nil)
-	if err != nil {
-		return f.recordFailure(article.ID, 0, fmt.Sprintf("RequestError: %v", err))
+	attempts := make(map[string]int)
+	for _, attemptedURL := range attemptedURLs {
+		attempts[hostOf(attemptedURL)]++
 	}
 
-	req.Header.Set("User-Agent", "instapaper-cli/1.0 (+https://github.com/user/instapaper-cli)")
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
+	var capped []model.Article
+	for _, article := range articles {
+		host := hostOf(article.URL)
+		if attempts[host] >= perDomain {
+			continue
+		}
+		attempts[host]++
+		capped = append(capped, article)
+	}
+	return capped, nil
+}
 
-	resp, err := f.client.Do(req)
+// requestTimeout bounds each article request, excluding time spent waiting on the bandwidth limit
+const requestTimeout = 20 * time.Second
+
+var errRequestTimeout = fmt.Errorf("request timed out after %s", requestTimeout)
+
+// requestError reports a request timeout instead of the generic "context canceled"
+func requestError(reqCtx context.Context, err error) error {
+	if cause := context.Cause(reqCtx); cause == errRequestTimeout {
+		return cause
+	}
+	return err
+}
+
+func (f *Fetcher) fetchSingleArticle(ctx context.Context, article model.Article, opts FetchOptions) error {
+	rule := opts.Rules.Match(article.URL)
+
+	pg, err := f.download(ctx, article.URL, rule, opts.Rules)
+	if err != nil && ctx.Err() == nil && rule != nil && rule.Wayback == WaybackFallback {
+		f.logger.Printf("Live fetch of article %d failed (%v), trying the Wayback Machine", article.ID, err)
+		if archived, waybackErr := f.downloadWayback(ctx, article.URL); waybackErr == nil {
+			pg, err = archived, nil
+		} else {
+			f.logger.Printf("Wayback fallback for article %d failed: %v", article.ID, waybackErr)
+		}
+	}
 	if err != nil {
-		return f.recordFailure(article.ID, 0, fmt.Sprintf("NetworkError: %v", err))
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var failure *fetchFailure
+		if errors.As(err, &failure) {
+			return f.recordFailure(article, failure.class, failure.statusCode, failure.text)
+		}
+		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return f.recordFailure(article.ID, resp.StatusCode, resp.Status)
+	// Shortened, http, and other links that lead to the article resolve to it from now on
+	if len(pg.redirects) > 0 {
+		if err := f.db.AddURLAliases(article.ID, db.URLAliasRedirect, append(pg.redirects, pg.finalURL)...); err != nil {
+			f.logger.Printf("Warning: failed to record redirects for article %d: %v", article.ID, err)
+		}
 	}
 
-	readabilityResult, err := readability.FromReader(resp.Body, resp.Request.URL)
+	if isAttachment(pg.contentType) {
+		return f.storeAttachment(ctx, article, pg)
+	}
+
+	body := pg.body
+
+	canonicalURL := extractCanonicalURL(body, pg.baseURL)
+
+	readabilityResult, quality, err := f.extract(body, pg.baseURL, rule, opts)
 	if err != nil {
-		return f.recordFailure(article.ID, resp.StatusCode, fmt.Sprintf("ReadabilityError: %v", err))
+		return f.recordFailure(article, FailureReadability, pg.statusCode, fmt.Sprintf("ReadabilityError: %v", err))
 	}
 
-	converter := md.NewConverter("", true, nil)
+	converter := newMarkdownConverter()
 	markdown, err := converter.ConvertString(readabilityResult.Content)
 	if err != nil {
-		return f.recordFailure(article.ID, resp.StatusCode, fmt.Sprintf("MarkdownError: %v", err))
+		return f.recordFailure(article, FailureMarkdown, pg.statusCode, fmt.Sprintf("MarkdownError: %v", err))
 	}
 
-	markdown = f.prettifyMarkdown(markdown)
+	if opts.KeepByline {
+		markdown = withByline(markdown, readabilityResult.Byline)
+	}
+
+	if !opts.NoPrettify {
+		markdown = f.prettifyMarkdown(markdown)
+	}
 
 	title := article.Title
-	if opts.PreferExtracted && readabilityResult.Title != "" {
+	// Articles added by URL (or imported without a title) carry the URL until the page is fetched
+	placeholder := title == "" || title == article.URL
+	if (opts.PreferExtracted || placeholder) && readabilityResult.Title != "" {
 		title = readabilityResult.Title
 	}
 
+	cleaner := titles.Default()
+	if opts.Titles != nil {
+		cleaner = *opts.Titles
+	}
+	if cleaned := cleaner.Clean(title, article.URL); cleaned != "" {
+		title = cleaned
+	}
+
+	if opts.Normalize != nil {
+		title = opts.Normalize.Text(title)
+		markdown = opts.Normalize.Markdown(markdown)
+	}
+
 	var rawHTML *string
 	if opts.StoreRaw {
 		rawHTML = &readabilityResult.Content
 	}
 
 	now := time.Now().UTC().Format(time.RFC3339)
-	finalURL := resp.Request.URL.String()
+	finalURL := pg.finalURL
+
+	var canonical *string
+	if canonicalURL != "" {
+		canonical = &canonicalURL
+	}
+
+	var publishedAt *string
+	if readabilityResult.PublishedTime != nil && !readabilityResult.PublishedTime.IsZero() {
+		published := readabilityResult.PublishedTime.UTC().Format(time.RFC3339)
+		publishedAt = &published
+	}
+
+	// Templates often declare the site's language whatever an article is written in, so the
+	// declared language only counts when the text is inconclusive
+	lang := language.Detect(title + "\n" + markdown)
+	if lang == "" {
+		lang = language.Normalize(readabilityResult.Language)
+	}
+
+	storedContent, err := f.db.EncodeContent(markdown)
+	if err != nil {
+		return err
+	}
 
 	_, err = f.db.Exec(`
 		UPDATE articles
-		SET synced_at = ?, content_md = ?, raw_html = ?, title = ?, final_url = ?,
-		    status_code = ?, status_text = ?, failed_count = 0, sync_failed_at = NULL
+		SET synced_at = ?, content_md = ?, raw_html = ?, title = ?, title_norm = ?, final_url = ?, canonical_url = ?, simhash = ?,
+		    published_at = COALESCE(?, published_at), extraction_quality = ?, language = NULLIF(?, ''), outline = ?,
+		    status_code = ?, status_text = ?, failed_count = 0, sync_failed_at = NULL, failure_class = NULL, next_retry_at = NULL
 		WHERE id = ?
-	`, now, markdown, rawHTML, title, finalURL, resp.StatusCode, "OK", article.ID)
+	`, now, storedContent, rawHTML, title, util.NormalizeText(title), finalURL, canonical, int64(similarity.Signature(markdown)), publishedAt, quality, lang, db.OutlineJSON(markdown), pg.statusCode, "OK", article.ID)
 
 	if err != nil {
 		return fmt.Errorf("failed to update article: %w", err)
 	}
 
-	// Update FTS table
-	if err := f.db.UpsertArticleFTS(article.ID); err != nil {
-		f.logger.Printf("Warning: failed to update FTS for article %d: %v", article.ID, err)
+	if err := f.db.AddURLAliases(article.ID, db.URLAliasCanonical, canonicalURL); err != nil {
+		f.logger.Printf("Warning: failed to record canonical URL for article %d: %v", article.ID, err)
+	}
+
+	if opts.UpdateURL {
+		if err := f.adoptCanonicalURL(article, canonicalURL); err != nil {
+			f.logger.Printf("Warning: failed to adopt canonical URL for article %d: %v", article.ID, err)
+		}
 	}
 
+	f.updateFTS(ctx, article.ID)
+
+	metrics.FetchResults.Inc("success", strconv.Itoa(pg.statusCode))
+
 	f.logger.Printf("Successfully fetched article %d: %s", article.ID, article.Title)
 	return nil
 }
 
-func (f *Fetcher) recordFailure(articleID int64, statusCode int, statusText string) error {
-	now := time.Now().UTC().Format(time.RFC3339)
+// updateFTS updates an article's search index entry, queued in the batch of a running FetchArticles
+// so fetches hold the write lock for one transaction per batch rather than per article
+func (f *Fetcher) updateFTS(ctx context.Context, articleID int64) {
+	var err error
+	if f.fts != nil {
+		err = f.fts.Add(ctx, articleID)
+	} else {
+		err = f.db.UpsertArticleFTS(articleID)
+	}
+	if err != nil {
+		f.logger.Printf("Warning: failed to update FTS for article %d: %v", articleID, err)
+	}
+}
+
+// recordFailure stores a failed attempt and schedules the next retry according to the class's policy
+func (f *Fetcher) recordFailure(article model.Article, class FailureClass, statusCode int, statusText string) error {
+	metrics.FetchResults.Inc("failure", strconv.Itoa(statusCode))
+	metrics.FetchFailures.Inc(string(class))
+
+	now := time.Now().UTC()
+	attempts := article.FailedCount + 1
+
+	var nextRetryAt *string
+	if next, ok := PolicyFor(class, statusCode).NextRetry(attempts, now); ok {
+		formatted := next.Format(time.RFC3339)
+		nextRetryAt = &formatted
+	}
 
 	_, err := f.db.Exec(`
 		UPDATE articles
-		SET sync_failed_at = ?, failed_count = failed_count + 1,
+		SET sync_failed_at = ?, failed_count = ?, failure_class = ?, next_retry_at = ?,
 		    status_code = ?, status_text = ?
 		WHERE id = ?
-	`, now, statusCode, statusText, articleID)
+	`, now.Format(time.RFC3339), attempts, class, nextRetryAt, statusCode, statusText, article.ID)
 
 	if err != nil {
-		f.logger.Printf("Failed to record failure for article %d: %v", articleID, err)
+		f.logger.Printf("Failed to record failure for article %d: %v", article.ID, err)
+	} else if nextRetryAt == nil {
+		f.logger.Printf("Recorded %s failure for article %d: %s (no retries left)", class, article.ID, statusText)
 	} else {
-		f.logger.Printf("Recorded failure for article %d: %s", articleID, statusText)
+		f.logger.Printf("Recorded %s failure for article %d: %s (retry after %s)", class, article.ID, statusText, *nextRetryAt)
 	}
 
 	return fmt.Errorf("fetch failed: %s", statusText)
 }
 
+// prettifyMarkdown collapses blank lines and strips tracking lines outside code fences,
+// leaving fenced code and leading indentation (indented code, poetry) untouched
 func (f *Fetcher) prettifyMarkdown(markdown string) string {
 	lines := strings.Split(markdown, "\n")
 	var cleaned []string
 
+	fence := ""
+
 	for _, line := range lines {
-		line = strings.TrimSpace(line)
+		trimmed := strings.TrimSpace(line)
+
+		if fence != "" {
+			cleaned = append(cleaned, line)
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		}
+
+		if marker := codeFenceMarker(trimmed); marker != "" {
+			fence = marker
+			cleaned = append(cleaned, strings.TrimRight(line, " \t"))
+			continue
+		}
 
-		if line == "" {
+		if trimmed == "" {
 			if len(cleaned) > 0 && cleaned[len(cleaned)-1] != "" {
 				cleaned = append(cleaned, "")
 			}
@@ -225,12 +506,24 @@ func (f *Fetcher) prettifyMarkdown(markdown string) string {
 			continue
 		}
 
-		cleaned = append(cleaned, line)
+		cleaned = append(cleaned, strings.TrimRight(line, " \t"))
 	}
 
 	result := strings.Join(cleaned, "\n")
 
-	result = strings.ReplaceAll(result, "\n\n\n", "\n\n")
+	return strings.Trim(result, "\n")
+}
+
+// codeFenceMarker returns the opening fence (``` or ~~~, possibly longer) of a line, or ""
+func codeFenceMarker(trimmed string) string {
+	for _, char := range []string{"`", "~"} {
+		if !strings.HasPrefix(trimmed, strings.Repeat(char, 3)) {
+			continue
+		}
+
+		length := len(trimmed) - len(strings.TrimLeft(trimmed, char))
+		return strings.Repeat(char, length)
+	}
 
-	return strings.TrimSpace(result)
+	return ""
 }
\ No newline at end of file
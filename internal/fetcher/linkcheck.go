@@ -0,0 +1,274 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"instapaper-cli/internal/search"
+)
+
+// Link check statuses, stored in link_checks.status
+const (
+	LinkOK         = "ok"         // the URL answers with a success, possibly after trivial redirects (https, www, trailing slash)
+	LinkRedirected = "redirected" // the URL redirects to another page on the same site
+	LinkMoved      = "moved"      // the URL redirects to another domain
+	LinkBroken     = "broken"     // 404 or 410, or the domain no longer resolves
+	LinkError      = "error"      // any other failure, which may be temporary (403, 429, 5xx, timeouts)
+)
+
+// LinkStatuses lists the link check statuses in report order
+var LinkStatuses = []string{LinkOK, LinkRedirected, LinkMoved, LinkBroken, LinkError}
+
+// CheckOptions selects the articles to check and how hard to hit the network
+type CheckOptions struct {
+	Selector     search.Selector
+	Limit        int
+	Concurrency  int           // parallel requests, default 8
+	Rate         float64       // requests per second across all workers, 0 for unlimited
+	RecheckAfter time.Duration // skip articles checked more recently than this, 0 checks everything
+}
+
+// LinkCheck is the result of checking one article's URL
+type LinkCheck struct {
+	ID         int64  `json:"id"`
+	URL        string `json:"url"`
+	Title      string `json:"title"`
+	Status     string `json:"status"`
+	StatusCode int    `json:"status_code,omitempty"`
+	FinalURL   string `json:"final_url,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// CheckReport summarizes a check-links run
+type CheckReport struct {
+	Checked int            `json:"checked"`
+	Counts  map[string]int `json:"counts"`
+	Results []LinkCheck    `json:"results"`
+}
+
+// CheckLinks sends a HEAD request to each selected article's URL and records whether it still works,
+// redirects, or is gone. When ctx is cancelled the checks finished so far are kept and returned
+// with ctx's error.
+func (f *Fetcher) CheckLinks(ctx context.Context, opts CheckOptions) (*CheckReport, error) {
+	articles, err := f.linkCheckCandidates(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get articles to check: %w", err)
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 8
+	}
+
+	var rate *limiter
+	if opts.Rate > 0 {
+		rate = newLimiter(opts.Rate, 1)
+	}
+
+	report := &CheckReport{Counts: make(map[string]int)}
+	jobs := make(chan LinkCheck)
+	results := make(chan LinkCheck)
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for check := range jobs {
+				if rate != nil && rate.wait(ctx, 1) != nil {
+					return
+				}
+				results <- f.checkLink(ctx, check)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, article := range articles {
+			select {
+			case jobs <- article:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var saveErr error
+	for check := range results {
+		// A check cut short by cancellation says nothing about the link
+		if saveErr != nil || (ctx.Err() != nil && check.Status == LinkError) {
+			continue
+		}
+		if saveErr = f.saveLinkCheck(check); saveErr != nil {
+			// Keep draining so the workers can finish
+			continue
+		}
+		report.Checked++
+		report.Counts[check.Status]++
+		report.Results = append(report.Results, check)
+
+		if report.Checked%100 == 0 {
+			f.logger.Printf("Checked %d/%d links...", report.Checked, len(articles))
+		}
+	}
+
+	if saveErr != nil {
+		return report, saveErr
+	}
+	return report, ctx.Err()
+}
+
+func (f *Fetcher) linkCheckCandidates(ctx context.Context, opts CheckOptions) ([]LinkCheck, error) {
+	query := `
+		SELECT a.id, a.url, a.title
+		FROM articles a
+		LEFT JOIN link_checks lc ON lc.article_id = a.id
+		WHERE a.obsolete = FALSE
+	`
+	var args []interface{}
+
+	if opts.RecheckAfter > 0 {
+		query += ` AND (lc.checked_at IS NULL OR lc.checked_at < ?)`
+		args = append(args, time.Now().UTC().Add(-opts.RecheckAfter).Format(time.RFC3339))
+	}
+
+	conditions, selectorArgs := opts.Selector.Conditions()
+	for _, condition := range conditions {
+		query += ` AND ` + condition
+	}
+	args = append(args, selectorArgs...)
+
+	// Never-checked links first, then the longest unchecked
+	query += ` ORDER BY lc.checked_at IS NOT NULL, lc.checked_at, a.id`
+
+	if opts.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, opts.Limit)
+	}
+
+	var rows []struct {
+		ID    int64  `db:"id"`
+		URL   string `db:"url"`
+		Title string `db:"title"`
+	}
+	if err := f.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+
+	checks := make([]LinkCheck, len(rows))
+	for i, row := range rows {
+		checks[i] = LinkCheck{ID: row.ID, URL: row.URL, Title: row.Title}
+	}
+	return checks, nil
+}
+
+// checkLink requests the URL with HEAD, falling back to GET for servers that refuse HEAD
+func (f *Fetcher) checkLink(ctx context.Context, check LinkCheck) LinkCheck {
+	if requests, _ := currentLimits(); requests != nil {
+		if err := requests.wait(ctx, 1); err != nil {
+			check.Status, check.Error = LinkError, err.Error()
+			return check
+		}
+	}
+
+	resp, err := f.probe(ctx, http.MethodHead, check.URL)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented || resp.StatusCode == http.StatusForbidden) {
+		resp, err = f.probe(ctx, http.MethodGet, check.URL)
+	}
+
+	if err != nil {
+		check.Status, check.Error = LinkError, err.Error()
+		if classifyNetworkError(err) == FailureDNS {
+			check.Status = LinkBroken
+		}
+		return check
+	}
+
+	check.StatusCode = resp.StatusCode
+	finalURL := resp.Request.URL.String()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		check.Status = LinkBroken
+	case resp.StatusCode >= 400:
+		check.Status, check.Error = LinkError, resp.Status
+	case sameDocument(check.URL, finalURL):
+		check.Status = LinkOK
+	case hostOf(check.URL) == hostOf(finalURL):
+		check.Status, check.FinalURL = LinkRedirected, finalURL
+	default:
+		check.Status, check.FinalURL = LinkMoved, finalURL
+	}
+	return check
+}
+
+// probe sends a bodiless request with the per-request timeout and closes the response
+func (f *Fetcher) probe(ctx context.Context, method, rawURL string) (*http.Response, error) {
+	reqCtx, cancel := context.WithTimeoutCause(ctx, requestTimeout, errRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "instapaper-cli/1.0 (+https://github.com/user/instapaper-cli)")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, requestError(reqCtx, err)
+	}
+	// Only the status matters; a GET fallback must not download the page
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	return resp, nil
+}
+
+// sameDocument reports whether a redirect only changed the scheme, a www. prefix, or a trailing slash
+func sameDocument(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return hostOf(a) == hostOf(b) &&
+		strings.TrimSuffix(ua.EscapedPath(), "/") == strings.TrimSuffix(ub.EscapedPath(), "/") &&
+		ua.RawQuery == ub.RawQuery
+}
+
+func (f *Fetcher) saveLinkCheck(check LinkCheck) error {
+	var statusCode *int
+	if check.StatusCode != 0 {
+		statusCode = &check.StatusCode
+	}
+	var finalURL, errorText *string
+	if check.FinalURL != "" {
+		finalURL = &check.FinalURL
+	}
+	if check.Error != "" {
+		errorText = &check.Error
+	}
+
+	_, err := f.db.Exec(`
+		INSERT INTO link_checks (article_id, checked_at, status, status_code, final_url, error)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(article_id) DO UPDATE SET
+			checked_at = excluded.checked_at, status = excluded.status, status_code = excluded.status_code,
+			final_url = excluded.final_url, error = excluded.error
+	`, check.ID, time.Now().UTC().Format(time.RFC3339), check.Status, statusCode, finalURL, errorText)
+	if err != nil {
+		return fmt.Errorf("failed to save link check for article %d: %w", check.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package fetcher
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/JohannesKaufmann/html-to-markdown/plugin"
+	"github.com/PuerkitoBio/goquery"
+)
+
+var (
+	// codeLanguageClass matches the language hint in common highlighter class names
+	codeLanguageClass = regexp.MustCompile(`^(?:language|lang|highlight-source|brush:?)-?(.+)$`)
+
+	// footnoteID matches ids used for footnote definitions, e.g. fn1, fn:1, footnote-1
+	footnoteID = regexp.MustCompile(`^(?:fn|footnote)[-:_]?(\w+)$`)
+
+	// footnoteBackref matches markdown links pointing back to a footnote reference
+	footnoteBackref = regexp.MustCompile(`\[[^\]]*\]\(#(?:fnref|footnote-ref)[^)]*\)`)
+
+	// signupPattern matches class or id values of newsletter signup boxes
+	signupPattern = regexp.MustCompile(`(?i)newsletter|subscribe|signup|sign-up|mailing-?list|email-capture`)
+)
+
+// maxSignupBoxChars bounds the text of a signup box, so a page wrapper whose class or id happens
+// to match signupPattern is not taken for one
+const maxSignupBoxChars = 1000
+
+// newMarkdownConverter creates an HTML-to-Markdown converter with GFM tables,
+// language-hinted code fences, footnotes, and newsletter box removal
+func newMarkdownConverter() *md.Converter {
+	converter := md.NewConverter("", true, &md.Options{CodeBlockStyle: "fenced"})
+	converter.Use(plugin.GitHubFlavored())
+	converter.Before(removeSignupBoxes)
+	converter.AddRules(codeBlockRule(), footnoteReferenceRule(), footnoteDefinitionRule())
+	return converter
+}
+
+// removeSignupBoxes drops newsletter signup forms and boxes before conversion
+func removeSignupBoxes(selec *goquery.Selection) {
+	selec.Find("form").Each(func(i int, s *goquery.Selection) {
+		if s.Find(`input[type="email"]`).Length() > 0 {
+			s.Remove()
+		}
+	})
+
+	selec.Find("div, aside, section").Each(func(i int, s *goquery.Selection) {
+		if !signupPattern.MatchString(s.AttrOr("class", "") + " " + s.AttrOr("id", "")) {
+			return
+		}
+		if len(s.Text()) <= maxSignupBoxChars && s.Find("article, main").Length() == 0 {
+			s.Remove()
+		}
+	})
+}
+
+// codeBlockRule renders <pre> blocks as fenced code with the detected language
+func codeBlockRule() md.Rule {
+	return md.Rule{
+		Filter: []string{"pre"},
+		Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+			language := detectCodeLanguage(selec)
+
+			code := strings.TrimSuffix(selec.Text(), "\n")
+
+			fenceChar, _ := utf8.DecodeRuneInString(opt.Fence)
+			fence := md.CalculateCodeFence(fenceChar, code)
+
+			text := "\n\n" + fence + language + "\n" + code + "\n" + fence + "\n\n"
+			return &text
+		},
+	}
+}
+
+// detectCodeLanguage finds a language hint on a <pre> block or its <code> child
+func detectCodeLanguage(selec *goquery.Selection) string {
+	candidates := []*goquery.Selection{selec.Find("code").First(), selec}
+
+	for _, s := range candidates {
+		if lang := s.AttrOr("data-lang", ""); lang != "" {
+			return lang
+		}
+		if lang := s.AttrOr("data-language", ""); lang != "" {
+			return lang
+		}
+
+		for _, class := range strings.Fields(s.AttrOr("class", "")) {
+			if matches := codeLanguageClass.FindStringSubmatch(class); matches != nil {
+				return matches[1]
+			}
+		}
+	}
+
+	return ""
+}
+
+// footnoteReferenceRule renders <sup><a href="#fn1">1</a></sup> as [^1]
+func footnoteReferenceRule() md.Rule {
+	return md.Rule{
+		Filter: []string{"sup"},
+		Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+			link := selec.Find("a").First()
+			href := strings.TrimPrefix(link.AttrOr("href", ""), "#")
+
+			matches := footnoteID.FindStringSubmatch(href)
+			if matches == nil {
+				return nil
+			}
+
+			text := "[^" + matches[1] + "]"
+			return &text
+		},
+	}
+}
+
+// footnoteDefinitionRule renders footnote list items as [^1]: text definitions
+func footnoteDefinitionRule() md.Rule {
+	return md.Rule{
+		Filter: []string{"li"},
+		Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+			matches := footnoteID.FindStringSubmatch(selec.AttrOr("id", ""))
+			if matches == nil {
+				return nil
+			}
+
+			// Drop back-reference arrows such as [↩](#fnref1)
+			body := footnoteBackref.ReplaceAllString(content, "")
+			body = strings.Join(strings.Fields(body), " ")
+
+			text := "[^" + matches[1] + "]: " + body + "\n"
+			return &text
+		},
+	}
+}
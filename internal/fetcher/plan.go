@@ -0,0 +1,160 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"instapaper-cli/internal/db"
+)
+
+// Reasons an article matching the filters is left out of a fetch run
+const (
+	ExcludedObsolete    = "obsolete"        // marked obsolete
+	ExcludedFetched     = "already_fetched" // content already stored
+	ExcludedBackoff     = "backoff"         // failed, waiting for next_retry_at
+	ExcludedGaveUp      = "gave_up"         // failed, its retry policy has given up
+	ExcludedBeyondLimit = "beyond_limit"    // eligible, but past --limit
+	ExcludedNotFetched  = "not_fetched"     // never fetched, when refetching low-quality or garbled articles
+)
+
+// PlannedFetch is an article a fetch run would process, in order
+type PlannedFetch struct {
+	Position int    `json:"position"`
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	// Action is "fetch", or what a domain rule does instead: "skip", "render", or "wayback"
+	Action string `json:"action"`
+	Rule   string `json:"rule,omitempty"`
+}
+
+// ExcludedFetch is an article that matched the filters but would not be fetched
+type ExcludedFetch struct {
+	ID          int64   `db:"id" json:"id"`
+	Title       string  `db:"title" json:"title"`
+	URL         string  `db:"url" json:"url"`
+	Reason      string  `db:"-" json:"reason"`
+	FailedCount int     `db:"failed_count" json:"failed_count,omitempty"`
+	Class       *string `db:"failure_class" json:"failure_class,omitempty"`
+	NextRetryAt *string `db:"next_retry_at" json:"next_retry_at,omitempty"`
+
+	Obsolete     bool     `db:"obsolete" json:"-"`
+	SyncedAt     *string  `db:"synced_at" json:"-"`
+	SyncFailedAt *string  `db:"sync_failed_at" json:"-"`
+	Quality      *float64 `db:"extraction_quality" json:"-"`
+	Garbled      int      `db:"garbled" json:"-"`
+}
+
+// FetchPlan is what FetchArticles would do with the same options, without any requests
+type FetchPlan struct {
+	Matched  int            `json:"matched"`
+	Planned  []PlannedFetch `json:"planned"`
+	Excluded map[string]int `json:"excluded"`
+	// Failed lists the obsolete, backing-off, and given-up articles; already fetched ones are only counted
+	Failed []ExcludedFetch `json:"failed,omitempty"`
+}
+
+// PlanFetch lists the articles FetchArticles would process under opts, in order, and counts why
+// the other articles matching the search and selector filters would be left out
+func (f *Fetcher) PlanFetch(ctx context.Context, opts FetchOptions) (*FetchPlan, error) {
+	candidates, err := f.getCandidateArticles(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candidate articles: %w", err)
+	}
+
+	plan := &FetchPlan{Excluded: make(map[string]int)}
+	for i, article := range candidates {
+		planned := PlannedFetch{Position: i + 1, ID: article.ID, Title: article.Title, URL: article.URL, Action: "fetch"}
+		if rule := opts.Rules.Match(article.URL); rule != nil {
+			switch {
+			case rule.Skip:
+				planned.Action = "skip"
+			case rule.Wayback == WaybackAlways:
+				planned.Action = "wayback"
+			case rule.Render == "js":
+				planned.Action = "render"
+			}
+			planned.Rule = rule.Match
+		}
+		plan.Planned = append(plan.Planned, planned)
+	}
+
+	// Same filters as getCandidateArticles, without the conditions that exclude articles
+	query := `
+		SELECT a.id, a.title, a.url, a.failed_count, a.failure_class, a.next_retry_at,
+		       a.obsolete, a.synced_at, a.sync_failed_at, a.extraction_quality,
+		       CASE WHEN ? THEN garbled_chars(content_text(a.content_md)) ELSE 0 END AS garbled
+		FROM articles a
+		WHERE 1 = 1
+	`
+	// Content is only scanned for garbled characters when refetching garbled articles
+	args := []interface{}{opts.Garbled}
+
+	if opts.SearchPhrase != "" {
+		query += ` AND (a.url LIKE ? OR a.title_norm LIKE normalize_text(?))`
+		searchPattern := "%" + opts.SearchPhrase + "%"
+		args = append(args, searchPattern, searchPattern)
+	}
+
+	selectorConditions, selectorArgs := opts.Selector.Conditions()
+	for _, condition := range selectorConditions {
+		query += ` AND ` + condition
+	}
+	args = append(args, selectorArgs...)
+	query += ` ORDER BY a.id`
+
+	var matched []ExcludedFetch
+	if err := f.db.SelectContext(ctx, &matched, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get matching articles: %w", err)
+	}
+	plan.Matched = len(matched)
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	eligible := 0
+	for _, article := range matched {
+		switch {
+		case article.Obsolete:
+			article.Reason = ExcludedObsolete
+		case opts.LowQuality && article.SyncedAt == nil:
+			plan.Excluded[ExcludedNotFetched]++
+			continue
+		case opts.LowQuality && (article.Quality == nil || *article.Quality >= db.LowQualityThreshold):
+			plan.Excluded[ExcludedFetched]++
+			continue
+		case opts.LowQuality:
+			eligible++
+			continue
+		case opts.Garbled && article.SyncedAt == nil:
+			plan.Excluded[ExcludedNotFetched]++
+			continue
+		case opts.Garbled && article.Garbled < db.GarbledThreshold:
+			plan.Excluded[ExcludedFetched]++
+			continue
+		case opts.Garbled:
+			eligible++
+			continue
+		case article.SyncedAt != nil:
+			plan.Excluded[ExcludedFetched]++
+			continue
+		case article.SyncFailedAt == nil:
+			eligible++
+			continue
+		case article.NextRetryAt == nil:
+			article.Reason = ExcludedGaveUp
+		case *article.NextRetryAt > now:
+			article.Reason = ExcludedBackoff
+		default:
+			eligible++
+			continue
+		}
+		plan.Excluded[article.Reason]++
+		plan.Failed = append(plan.Failed, article)
+	}
+
+	if beyond := eligible - len(candidates); beyond > 0 {
+		plan.Excluded[ExcludedBeyondLimit] = beyond
+	}
+
+	return plan, nil
+}
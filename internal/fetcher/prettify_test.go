@@ -0,0 +1,68 @@
+package fetcher
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestPrettifyMarkdownGolden runs each testdata/prettify/*.input.md through the prettifier and
+// compares the result with the .golden.md next to it. Run with -update to rewrite the golden files.
+func TestPrettifyMarkdownGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "prettify", "*.input.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no prettify fixtures found")
+	}
+
+	f := New(nil)
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".input.md")
+		t.Run(name, func(t *testing.T) {
+			markdown, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := f.prettifyMarkdown(string(markdown)) + "\n"
+
+			golden := strings.TrimSuffix(input, ".input.md") + ".golden.md"
+			if *updateGolden {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("prettifyMarkdown(%s) =\n%s\nwant\n%s", input, got, want)
+			}
+		})
+	}
+}
+
+func TestPrettifyMarkdownIsIdempotent(t *testing.T) {
+	goldens, err := filepath.Glob(filepath.Join("testdata", "prettify", "*.golden.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := New(nil)
+	for _, golden := range goldens {
+		markdown, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.prettifyMarkdown(string(markdown)) + "\n"; got != string(markdown) {
+			t.Errorf("prettifying %s again changed it:\n%s", golden, got)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package fetcher
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"instapaper-cli/internal/model"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PriorityWeights controls how candidates are scored for --order priority
+type PriorityWeights struct {
+	Favorite        float64  `yaml:"favorite"`
+	Recency         float64  `yaml:"recency"`
+	DomainSuccess   float64  `yaml:"domain_success"`
+	DomainFailure   float64  `yaml:"domain_failure"`
+	ArticleFailure  float64  `yaml:"article_failure"`
+	FavoriteFolders []string `yaml:"favorite_folders"`
+}
+
+// DefaultPriorityWeights favors starred and recently saved articles from domains that usually succeed
+func DefaultPriorityWeights() PriorityWeights {
+	return PriorityWeights{
+		Favorite:        3,
+		Recency:         2,
+		DomainSuccess:   1,
+		DomainFailure:   2,
+		ArticleFailure:  0.5,
+		FavoriteFolders: []string{"Starred"},
+	}
+}
+
+// LoadPriorityWeights reads weights from a YAML file, falling back to defaults for unset keys
+func LoadPriorityWeights(path string) (PriorityWeights, error) {
+	weights := DefaultPriorityWeights()
+	if path == "" {
+		return weights, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return weights, fmt.Errorf("failed to read priority config: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, &weights); err != nil {
+		return weights, fmt.Errorf("failed to parse priority config: %w", err)
+	}
+
+	return weights, nil
+}
+
+// domainStats tracks fetch outcomes for a single host
+type domainStats struct {
+	succeeded int
+	failed    int
+}
+
+func (d domainStats) successRate() float64 {
+	if d.succeeded+d.failed == 0 {
+		return 0
+	}
+	return float64(d.succeeded) / float64(d.succeeded+d.failed)
+}
+
+func (d domainStats) failureRate() float64 {
+	if d.succeeded+d.failed == 0 {
+		return 0
+	}
+	return float64(d.failed) / float64(d.succeeded+d.failed)
+}
+
+// prioritizeArticles sorts candidates by descending priority score
+func (f *Fetcher) prioritizeArticles(articles []model.Article, weights PriorityWeights) ([]model.Article, error) {
+	favorites, err := f.getFavoriteArticleIDs(weights.FavoriteFolders)
+	if err != nil {
+		return nil, err
+	}
+
+	domains, err := f.getDomainStats()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	scores := make(map[int64]float64, len(articles))
+
+	for _, article := range articles {
+		score := 0.0
+
+		if favorites[article.ID] {
+			score += weights.Favorite
+		}
+
+		// Recency decays from 1 (saved today) towards 0 over months
+		if savedAt, err := time.Parse(time.RFC3339, article.InstapaperedAt); err == nil {
+			ageDays := now.Sub(savedAt).Hours() / 24
+			if ageDays < 0 {
+				ageDays = 0
+			}
+			score += weights.Recency / (1 + ageDays/30)
+		}
+
+		stats := domains[hostOf(article.URL)]
+		score += weights.DomainSuccess * stats.successRate()
+		score -= weights.DomainFailure * stats.failureRate()
+		score -= weights.ArticleFailure * float64(article.FailedCount)
+
+		scores[article.ID] = score
+	}
+
+	sort.SliceStable(articles, func(i, j int) bool {
+		return scores[articles[i].ID] > scores[articles[j].ID]
+	})
+
+	return articles, nil
+}
+
+// getFavoriteArticleIDs returns IDs of articles in any of the favorite folders
+func (f *Fetcher) getFavoriteArticleIDs(folders []string) (map[int64]bool, error) {
+	favorites := make(map[int64]bool)
+	if len(folders) == 0 {
+		return favorites, nil
+	}
+
+	placeholders := make([]string, len(folders))
+	args := make([]interface{}, len(folders))
+	for i, folder := range folders {
+		placeholders[i] = "?"
+		args[i] = folder
+	}
+
+	query := fmt.Sprintf(`
+		SELECT a.id
+		FROM articles a
+		JOIN folders f ON a.folder_id = f.id
+		WHERE f.title COLLATE NOCASE IN (%s)
+	`, strings.Join(placeholders, ","))
+
+	var ids []int64
+	if err := f.db.Select(&ids, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get favorite articles: %w", err)
+	}
+
+	for _, id := range ids {
+		favorites[id] = true
+	}
+
+	return favorites, nil
+}
+
+// getDomainStats aggregates fetch successes and failures per host
+func (f *Fetcher) getDomainStats() (map[string]domainStats, error) {
+	var rows []struct {
+		URL         string  `db:"url"`
+		SyncedAt    *string `db:"synced_at"`
+		FailedCount int     `db:"failed_count"`
+	}
+
+	if err := f.db.Select(&rows, "SELECT url, synced_at, failed_count FROM articles WHERE synced_at IS NOT NULL OR failed_count > 0"); err != nil {
+		return nil, fmt.Errorf("failed to get domain statistics: %w", err)
+	}
+
+	stats := make(map[string]domainStats)
+	for _, row := range rows {
+		host := hostOf(row.URL)
+		s := stats[host]
+		if row.SyncedAt != nil {
+			s.succeeded++
+		}
+		s.failed += row.FailedCount
+		stats[host] = s
+	}
+
+	return stats, nil
+}
+
+// hostOf returns the lowercased host of a URL without a www. prefix
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
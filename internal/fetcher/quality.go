@@ -0,0 +1,70 @@
+package fetcher
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+const (
+	// goodArticleWords is the length at which extracted content stops counting as suspiciously short
+	goodArticleWords = 200
+	// goodCoverage is the share of the page's text an extraction is expected to keep
+	goodCoverage = 0.3
+	// longArticleWords is the length at which low coverage stops counting against an extraction,
+	// since long articles on pages with long comment threads are common
+	longArticleWords = 1000
+)
+
+// extractionQuality scores extracted content from 0 (junk) to 1 (looks like the article). Short
+// content, content holding little of the page's text, and content made mostly of links score low.
+func extractionQuality(pageChars int, content string) float64 {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return 0
+	}
+
+	text := strings.Join(strings.Fields(doc.Text()), " ")
+	chars := utf8.RuneCountInString(text)
+	if chars == 0 {
+		return 0
+	}
+	words := len(strings.Fields(text))
+
+	linkChars := 0
+	doc.Find("a").Each(func(i int, s *goquery.Selection) {
+		linkChars += utf8.RuneCountInString(strings.Join(strings.Fields(s.Text()), " "))
+	})
+	linkDensity := min(1, float64(linkChars)/float64(chars))
+
+	coverage := 1.0
+	if pageChars > 0 {
+		coverage = float64(chars) / float64(pageChars)
+	}
+
+	lengthScore := min(1, float64(words)/goodArticleWords)
+	coverageScore := max(min(1, coverage/goodCoverage), min(1, float64(words)/longArticleWords))
+
+	score := lengthScore * coverageScore * (1 - linkDensity)
+	return math.Round(score*100) / 100
+}
+
+// visibleTextLength counts the characters of text a reader would see on a page, outside
+// scripts, styles, and templates, with whitespace runs counted once
+func visibleTextLength(node *html.Node) int {
+	switch {
+	case node.Type == html.ElementNode && (node.Data == "script" || node.Data == "style" || node.Data == "noscript" || node.Data == "template"):
+		return 0
+	case node.Type == html.TextNode:
+		return utf8.RuneCountInString(strings.Join(strings.Fields(node.Data), " "))
+	}
+
+	total := 0
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		total += visibleTextLength(child)
+	}
+	return total
+}
@@ -0,0 +1,130 @@
+package fetcher
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"os"
+	"time"
+)
+
+// FailureClass groups fetch failures that share a cause and a retry policy
+type FailureClass string
+
+const (
+	FailureDNS         FailureClass = "dns"
+	FailureTLS         FailureClass = "tls"
+	FailureTimeout     FailureClass = "timeout"
+	FailureNetwork     FailureClass = "network"
+	FailureClientError FailureClass = "http_4xx"
+	FailureServerError FailureClass = "http_5xx"
+	FailureRead        FailureClass = "read"
+	FailureReadability FailureClass = "readability"
+	FailureMarkdown    FailureClass = "markdown"
+)
+
+// FailureClasses lists every failure class in display order
+var FailureClasses = []FailureClass{
+	FailureDNS, FailureTLS, FailureTimeout, FailureNetwork, FailureClientError,
+	FailureServerError, FailureRead, FailureReadability, FailureMarkdown,
+}
+
+// RetryPolicy is how often a failed article is retried and how long to wait first.
+// The wait doubles with every further failure, up to maxBackoff.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+}
+
+// maxBackoff caps the exponential backoff between retries
+const maxBackoff = 7 * 24 * time.Hour
+
+// retryPolicies are the defaults for each failure class
+var retryPolicies = map[FailureClass]RetryPolicy{
+	FailureDNS:         {MaxAttempts: 3, Backoff: 6 * time.Hour},
+	FailureTLS:         {MaxAttempts: 2, Backoff: 24 * time.Hour},
+	FailureTimeout:     {MaxAttempts: 5, Backoff: 30 * time.Minute},
+	FailureNetwork:     {MaxAttempts: 5, Backoff: time.Hour},
+	FailureClientError: {MaxAttempts: 3, Backoff: 24 * time.Hour},
+	FailureServerError: {MaxAttempts: 5, Backoff: time.Hour},
+	FailureRead:        {MaxAttempts: 5, Backoff: 30 * time.Minute},
+	// Extraction failures are deterministic, so one late retry covers a changed page
+	FailureReadability: {MaxAttempts: 2, Backoff: 7 * 24 * time.Hour},
+	FailureMarkdown:    {MaxAttempts: 2, Backoff: 7 * 24 * time.Hour},
+}
+
+// statusPolicies override the class policy for specific HTTP status codes
+var statusPolicies = map[int]RetryPolicy{
+	404: {MaxAttempts: 2, Backoff: 24 * time.Hour},
+	410: {MaxAttempts: 1},                            // Gone: never retry
+	429: {MaxAttempts: 8, Backoff: 15 * time.Minute}, // rate limited: back off and try again
+	502: {MaxAttempts: 8, Backoff: 10 * time.Minute},
+	503: {MaxAttempts: 8, Backoff: 10 * time.Minute},
+	504: {MaxAttempts: 8, Backoff: 10 * time.Minute},
+}
+
+// PolicyFor returns the retry policy for a failure class and HTTP status code (0 if none)
+func PolicyFor(class FailureClass, statusCode int) RetryPolicy {
+	if policy, ok := statusPolicies[statusCode]; ok {
+		return policy
+	}
+	if policy, ok := retryPolicies[class]; ok {
+		return policy
+	}
+	return RetryPolicy{MaxAttempts: 5, Backoff: time.Hour}
+}
+
+// NextRetry returns when an article that has now failed attempts times may be retried,
+// or false once the policy's attempts are used up
+func (p RetryPolicy) NextRetry(attempts int, now time.Time) (time.Time, bool) {
+	if attempts >= p.MaxAttempts {
+		return time.Time{}, false
+	}
+
+	backoff := p.Backoff
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+
+	return now.Add(backoff), true
+}
+
+// classifyStatus maps a non-200 HTTP status to a failure class
+func classifyStatus(statusCode int) FailureClass {
+	if statusCode >= 500 {
+		return FailureServerError
+	}
+	return FailureClientError
+}
+
+// classifyNetworkError maps an error from sending a request to a failure class
+func classifyNetworkError(err error) FailureClass {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return FailureDNS
+	}
+
+	var (
+		recordErr  tls.RecordHeaderError
+		verifyErr  *tls.CertificateVerificationError
+		unknownErr x509.UnknownAuthorityError
+		hostErr    x509.HostnameError
+		certErr    x509.CertificateInvalidError
+	)
+	if errors.As(err, &recordErr) || errors.As(err, &verifyErr) || errors.As(err, &unknownErr) ||
+		errors.As(err, &hostErr) || errors.As(err, &certErr) {
+		return FailureTLS
+	}
+
+	var netErr net.Error
+	if errors.Is(err, errRequestTimeout) || errors.Is(err, os.ErrDeadlineExceeded) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) {
+		return FailureTimeout
+	}
+
+	return FailureNetwork
+}
@@ -0,0 +1,121 @@
+package fetcher
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"gopkg.in/yaml.v3"
+)
+
+// Wayback modes for a domain rule
+const (
+	// WaybackAlways fetches the latest Wayback Machine snapshot instead of the live page
+	WaybackAlways = "always"
+	// WaybackFallback tries the latest snapshot when the live fetch fails
+	WaybackFallback = "fallback"
+)
+
+// DomainRules collects per-domain fetch workarounds from a YAML file
+type DomainRules struct {
+	// RenderCommand prints the rendered HTML of a page for rules with render: js. "{url}" is
+	// replaced with the quoted URL, or the URL is appended when there is no placeholder.
+	RenderCommand string       `yaml:"render_command"`
+	Domains       []DomainRule `yaml:"domains"`
+}
+
+// DomainRule applies to a host and its subdomains
+type DomainRule struct {
+	Match string `yaml:"match"`
+	// Skip never fetches the domain's articles and marks them obsolete instead
+	Skip bool `yaml:"skip"`
+	// Render "js" fetches the page through RenderCommand, for sites that need JavaScript
+	Render string `yaml:"render"`
+	// Headers are sent with live requests, replacing the defaults of the same name
+	Headers map[string]string `yaml:"headers"`
+	// RequestsPerMinute caps requests to this domain, on top of --max-requests-per-minute
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	// Wayback is WaybackAlways or WaybackFallback
+	Wayback string `yaml:"wayback"`
+	// ContentSelector is a CSS selector for the element holding the article, e.g. article.post-body,
+	// for sites where readability picks the wrong container
+	ContentSelector string `yaml:"content_selector"`
+
+	limiter *limiter
+}
+
+// LoadDomainRules reads and validates domain rules from a YAML file. An empty path means no rules.
+func LoadDomainRules(path string) (*DomainRules, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read domain rules: %w", err)
+	}
+
+	var rules DomainRules
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse domain rules: %w", err)
+	}
+
+	for i := range rules.Domains {
+		rule := &rules.Domains[i]
+		rule.Match = normalizeHost(rule.Match)
+
+		if rule.Match == "" {
+			return nil, fmt.Errorf("domain rule %d has no match", i+1)
+		}
+		if rule.Render != "" && rule.Render != "js" {
+			return nil, fmt.Errorf("domain rule for %s: invalid render %q (use js)", rule.Match, rule.Render)
+		}
+		if rule.Render == "js" && rules.RenderCommand == "" {
+			return nil, fmt.Errorf("domain rule for %s uses render: js but no render_command is set", rule.Match)
+		}
+		if rule.Wayback != "" && rule.Wayback != WaybackAlways && rule.Wayback != WaybackFallback {
+			return nil, fmt.Errorf("domain rule for %s: invalid wayback %q (use %s or %s)", rule.Match, rule.Wayback, WaybackAlways, WaybackFallback)
+		}
+		if rule.ContentSelector != "" {
+			if _, err := cascadia.Compile(rule.ContentSelector); err != nil {
+				return nil, fmt.Errorf("domain rule for %s: invalid content_selector %q: %w", rule.Match, rule.ContentSelector, err)
+			}
+		}
+		if rule.RequestsPerMinute > 0 {
+			rule.limiter = newLimiter(float64(rule.RequestsPerMinute)/60, 1)
+		}
+	}
+
+	return &rules, nil
+}
+
+// Match returns the most specific rule for a URL's host, or nil
+func (r *DomainRules) Match(rawURL string) *DomainRule {
+	if r == nil {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	host := normalizeHost(u.Hostname())
+
+	var best *DomainRule
+	for i := range r.Domains {
+		rule := &r.Domains[i]
+		if host != rule.Match && !strings.HasSuffix(host, "."+rule.Match) {
+			continue
+		}
+		if best == nil || len(rule.Match) > len(best.Match) {
+			best = rule
+		}
+	}
+	return best
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(host)), "www.")
+}
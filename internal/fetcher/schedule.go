@@ -0,0 +1,156 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule spreads unattended fetching over a daily window, such as the night, so a large backlog
+// drains a little at a time without noticeable load
+type Schedule struct {
+	// Start and End are times of day as offsets from local midnight. An End before Start makes
+	// the window run past midnight.
+	Start time.Duration
+	End   time.Duration
+	// Limit is the most articles fetched per window, spaced evenly across it
+	Limit int
+	// DomainCap is the most fetch attempts per domain per day, 0 for no cap
+	DomainCap int
+	// LockWait is how long each fetch waits for another writer to finish
+	LockWait time.Duration
+	// Fetch selects and fetches the articles, as for fetch; its Limit and DomainCap are set per run
+	Fetch FetchOptions
+}
+
+// ParseWindow parses a daily window like "01:00-06:00" into its start and end times of day
+func ParseWindow(window string) (time.Duration, time.Duration, error) {
+	startStr, endStr, ok := strings.Cut(window, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid window %q: use HH:MM-HH:MM, e.g. 01:00-06:00", window)
+	}
+	start, err := parseTimeOfDay(startStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid window %q: %w", window, err)
+	}
+	end, err := parseTimeOfDay(endStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid window %q: %w", window, err)
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("invalid window %q: start and end are the same", window)
+	}
+	return start, end, nil
+}
+
+// parseTimeOfDay parses "HH:MM" as an offset from midnight
+func parseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day like 01:30", strings.TrimSpace(value))
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// window returns the window holding now, or the next one when now is outside every window
+func (s Schedule) window(now time.Time) (time.Time, time.Time) {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	length := s.End - s.Start
+	if length < 0 {
+		length += 24 * time.Hour
+	}
+
+	// A window past midnight may have started yesterday
+	for _, day := range []int{-1, 0, 1} {
+		start := midnight.AddDate(0, 0, day).Add(s.Start)
+		end := start.Add(length)
+		if now.Before(end) {
+			return start, end
+		}
+	}
+	return midnight.AddDate(0, 0, 2).Add(s.Start), midnight.AddDate(0, 0, 2).Add(s.Start + length)
+}
+
+// slots returns when the fetches of the window holding now, or of the next one, begin and end, and
+// how far apart they are. A run started partway through a window spreads its fetches over what is
+// left of it, rather than catching up on the slots already past.
+func (s Schedule) slots(now time.Time) (begin, end time.Time, spacing time.Duration) {
+	begin, end = s.window(now)
+	if now.After(begin) {
+		begin = now
+	}
+	return begin, end, end.Sub(begin) / time.Duration(s.Limit)
+}
+
+// Run fetches articles one at a time inside each window until ctx is cancelled, at most Limit
+// per window and evenly spaced, so the window's fetches spread over all of it. A window ends
+// early once nothing is left to fetch.
+func (f *Fetcher) Run(ctx context.Context, s Schedule) error {
+	if s.Limit <= 0 {
+		return fmt.Errorf("a fetch schedule needs a limit above 0")
+	}
+
+	for {
+		start, end, spacing := s.slots(time.Now())
+		f.logger.Printf("Next fetch window %s to %s, up to %d articles", start.Format("2006-01-02 15:04"), end.Format("15:04"), s.Limit)
+		if !sleepUntil(ctx, start) {
+			return nil
+		}
+
+		for attempted := 0; attempted < s.Limit && time.Now().Before(end); {
+			candidates, err := f.fetchScheduled(ctx, s)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err != nil {
+				f.logger.Printf("Scheduled fetch failed: %v", err)
+			}
+			if err == nil && candidates == 0 {
+				f.logger.Printf("Nothing left to fetch in this window")
+				break
+			}
+			// A failed attempt still uses its slot, rather than retrying at once
+			attempted += max(candidates, 1)
+
+			// Fetches keep to their slot, so a slow or late one does not bunch up the rest
+			if !sleepUntil(ctx, start.Add(time.Duration(attempted)*spacing)) {
+				return nil
+			}
+		}
+
+		if !sleepUntil(ctx, end) {
+			return nil
+		}
+	}
+}
+
+// fetchScheduled fetches the next article under the write lock, returning how many were tried
+func (f *Fetcher) fetchScheduled(ctx context.Context, s Schedule) (int, error) {
+	lock, err := f.db.LockWrites(ctx, "serve", s.LockWait)
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Release()
+
+	opts := s.Fetch
+	opts.Limit = 1
+	opts.DomainCap = s.DomainCap
+	result, err := f.FetchArticles(ctx, opts)
+	if err != nil {
+		return 0, err
+	}
+	return result.Candidates, nil
+}
+
+// sleepUntil waits until t, reporting false when ctx is cancelled first
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
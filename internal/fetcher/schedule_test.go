@@ -0,0 +1,48 @@
+package fetcher
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleSlots(t *testing.T) {
+	s := Schedule{Start: time.Hour, End: 6 * time.Hour, Limit: 100}
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 3, 5, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name        string
+		now         time.Time
+		begin, end  time.Time
+		wantSpacing time.Duration
+	}{
+		{"before the window", at(0, 30), at(1, 0), at(6, 0), 3 * time.Minute},
+		{"at the start", at(1, 0), at(1, 0), at(6, 0), 3 * time.Minute},
+		// A restart an hour before the end spreads the fetches over that hour, not the past slots
+		{"mid-window", at(5, 0), at(5, 0), at(6, 0), 36 * time.Second},
+		{"after the window", at(7, 0), at(1, 0).AddDate(0, 0, 1), at(6, 0).AddDate(0, 0, 1), 3 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			begin, end, spacing := s.slots(tt.now)
+			if !begin.Equal(tt.begin) || !end.Equal(tt.end) || spacing != tt.wantSpacing {
+				t.Errorf("slots(%s) = %s, %s, %s, want %s, %s, %s", tt.now.Format("15:04"), begin, end, spacing, tt.begin, tt.end, tt.wantSpacing)
+			}
+			if begin.Before(tt.now) {
+				t.Errorf("slots(%s) begins in the past at %s", tt.now.Format("15:04"), begin)
+			}
+		})
+	}
+}
+
+func TestScheduleSlotsPastMidnight(t *testing.T) {
+	s := Schedule{Start: 22 * time.Hour, End: 4 * time.Hour, Limit: 60}
+	now := time.Date(2024, 3, 5, 3, 0, 0, 0, time.UTC)
+
+	begin, end, spacing := s.slots(now)
+	if !begin.Equal(now) || !end.Equal(time.Date(2024, 3, 5, 4, 0, 0, 0, time.UTC)) || spacing != time.Minute {
+		t.Errorf("slots(03:00) = %s, %s, %s, want 03:00 to 04:00 a minute apart", begin, end, spacing)
+	}
+}
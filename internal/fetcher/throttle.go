@@ -0,0 +1,150 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// throttleChunk caps how many bytes a throttled read consumes at once so waits stay short
+const throttleChunk = 16 * 1024
+
+// limiter is a token bucket that lets callers go into debt and then sleeps until the debt is repaid
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(rate, burst float64) *limiter {
+	return &limiter{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// wait takes n tokens, blocking until they are available or ctx is cancelled
+func (l *limiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens -= float64(n)
+
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+var (
+	limitsMu         sync.Mutex
+	requestLimiter   *limiter
+	bandwidthLimiter *limiter
+)
+
+// SetLimits throttles every fetcher in the process to at most maxRequestsPerMinute article
+// requests and maxBytesPerSecond of downloaded body data. Zero disables a limit.
+func SetLimits(maxBytesPerSecond int64, maxRequestsPerMinute int) {
+	limitsMu.Lock()
+	defer limitsMu.Unlock()
+
+	requestLimiter = nil
+	if maxRequestsPerMinute > 0 {
+		requestLimiter = newLimiter(float64(maxRequestsPerMinute)/60, 1)
+	}
+
+	bandwidthLimiter = nil
+	if maxBytesPerSecond > 0 {
+		bandwidthLimiter = newLimiter(float64(maxBytesPerSecond), float64(maxBytesPerSecond))
+	}
+}
+
+func currentLimits() (*limiter, *limiter) {
+	limitsMu.Lock()
+	defer limitsMu.Unlock()
+	return requestLimiter, bandwidthLimiter
+}
+
+// throttledReader delays reads so the shared bandwidth limit is respected. The request
+// timeout is paused while waiting, so a low cap does not make large pages time out.
+type throttledReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *limiter
+	timeout *time.Timer
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > throttleChunk {
+		p = p[:throttleChunk]
+	}
+
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if t.timeout != nil && !t.timeout.Stop() {
+			// The timeout already fired and cancelled the request
+			return n, err
+		}
+		waitErr := t.limiter.wait(t.ctx, n)
+		if t.timeout != nil {
+			t.timeout.Reset(requestTimeout)
+		}
+		if waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// ParseBandwidth parses a rate like "500K", "1.5MB", or "2m/s" into bytes per second.
+// Units are binary (K = 1024); a bare number is bytes per second and "0" or "" disables the limit.
+func ParseBandwidth(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	s = strings.TrimSuffix(s, "/S")
+	if s == "" {
+		return 0, nil
+	}
+
+	multiplier := 1.0
+	s = strings.TrimSuffix(s, "B")
+	switch {
+	case strings.HasSuffix(s, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		s = s[:len(s)-1]
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid bandwidth %q: use a rate like 500K, 1.5M, or 2MB/s", value)
+	}
+
+	return int64(number * multiplier), nil
+}
@@ -0,0 +1,129 @@
+package fetcher
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Connection pool settings of the fetch transport. Link checks run up to 8 workers, which may all
+// be on one host.
+const (
+	maxIdleConns        = 100
+	maxIdleConnsPerHost = 8
+	idleConnTimeout     = 90 * time.Second
+	// dnsCacheTTL is how long a host's addresses are reused before they are looked up again
+	dnsCacheTTL = 5 * time.Minute
+	// drainLimit is how much of an unwanted response body is read so its connection can be reused
+	drainLimit = 64 * 1024
+)
+
+// sharedTransport is used by every Fetcher, so that open connections and cached DNS lookups carry
+// over from one fetch run to the next, e.g. in the serve loop
+var sharedTransport = newTransport()
+
+// newTransport returns a transport that keeps connections alive and pooled per host, speaks
+// HTTP/2 where the server does, honors proxy environment variables, and caches DNS lookups
+func newTransport() *http.Transport {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	cache := &dnsCache{ttl: dnsCacheTTL, entries: make(map[string]dnsEntry), lookup: net.DefaultResolver.LookupHost}
+
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           cache.dialContext(dialer),
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		IdleConnTimeout:       idleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// dnsCache remembers the addresses of hosts for a while. Go's resolver does not cache, so without
+// it every new connection waits on a DNS round trip.
+type dnsCache struct {
+	ttl     time.Duration
+	lookup  func(ctx context.Context, host string) ([]string, error)
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+}
+
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+// resolve returns the cached addresses of host, looking them up when missing or expired.
+// IPv4 addresses come first, as addresses are dialed one after another.
+func (c *dnsCache) resolve(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	addrs, err := c.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(addrs, func(i, j int) bool {
+		return isIPv4(addrs[i]) && !isIPv4(addrs[j])
+	})
+
+	c.mu.Lock()
+	c.entries[host] = dnsEntry{addrs: addrs, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return addrs, nil
+}
+
+// forget drops a host's addresses, so the next dial looks them up again
+func (c *dnsCache) forget(host string) {
+	c.mu.Lock()
+	delete(c.entries, host)
+	c.mu.Unlock()
+}
+
+// dialContext dials a host's cached addresses in turn. When none answers, the host is forgotten
+// in case its addresses changed.
+func (c *dnsCache) dialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil || net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, address)
+		}
+
+		addrs, err := c.resolve(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var firstErr error
+		for _, addr := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr, port))
+			if err == nil {
+				return conn, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		c.forget(host)
+		if firstErr == nil {
+			firstErr = &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+		}
+		return nil, firstErr
+	}
+}
+
+func isIPv4(addr string) bool {
+	ip := net.ParseIP(addr)
+	return ip != nil && ip.To4() != nil
+}
@@ -0,0 +1,171 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"instapaper-cli/internal/db"
+	"instapaper-cli/internal/tagging"
+	"instapaper-cli/internal/util"
+)
+
+// urlPattern finds http(s) links in free text such as a clipboard or a pasted list
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'\x60]+`)
+
+// AddOptions files and tags articles added by URL
+type AddOptions struct {
+	Folder string
+	Tags   []string
+	// Rules add tags to the new articles, and file them when Folder is empty. Title conditions
+	// see the URL, which stands in for the title until fetch.
+	Rules *tagging.Rules
+}
+
+// AddResult reports which URLs were queued
+type AddResult struct {
+	Added      []AddedArticle `json:"added"`
+	Duplicates []string       `json:"duplicates,omitempty"`
+	Invalid    []string       `json:"invalid,omitempty"`
+}
+
+// AddedArticle is a newly queued article
+type AddedArticle struct {
+	ID  int64  `json:"id"`
+	URL string `json:"url"`
+}
+
+// ExtractURLs returns the http(s) URLs in text, in order, without trailing punctuation
+func ExtractURLs(text string) []string {
+	var urls []string
+	for _, match := range urlPattern.FindAllString(text, -1) {
+		match = strings.TrimRight(match, ".,;:!?")
+		// Keep a closing parenthesis only when the URL opened one, as in Wikipedia links
+		for strings.HasSuffix(match, ")") && strings.Count(match, "(") < strings.Count(match, ")") {
+			match = strings.TrimSuffix(match, ")")
+		}
+		urls = append(urls, match)
+	}
+	return urls
+}
+
+// AddURLs queues URLs as unfetched articles saved now. URLs already in the database, including
+// obsolete ones, or repeated in the list are reported as duplicates.
+func (i *Importer) AddURLs(ctx context.Context, urls []string, opts AddOptions) (*AddResult, error) {
+	result := &AddResult{}
+	seen := make(map[string]bool)
+	filed := false
+
+	var folderID *int64
+	if opts.Folder != "" {
+		var id int64
+		_, aliased, err := i.db.ResolveAlias(db.AliasFolder, opts.Folder)
+		if err != nil {
+			return nil, err
+		}
+		if aliased || len(db.SplitFolderPath(opts.Folder)) > 1 {
+			id, err = i.db.UpsertFolderPath(opts.Folder)
+		} else {
+			id, err = i.db.UpsertFolder(opts.Folder, nil)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to upsert folder %q: %w", opts.Folder, err)
+		}
+		folderID = &id
+	}
+
+	for _, rawURL := range urls {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		canonicalURL, ok := canonicalAddURL(rawURL)
+		if !ok {
+			result.Invalid = append(result.Invalid, rawURL)
+			continue
+		}
+
+		if seen[canonicalURL] {
+			result.Duplicates = append(result.Duplicates, canonicalURL)
+			continue
+		}
+		seen[canonicalURL] = true
+
+		_, err := i.db.FindArticleByURL(canonicalURL)
+		if err == nil {
+			result.Duplicates = append(result.Duplicates, canonicalURL)
+			continue
+		}
+		if err != sql.ErrNoRows {
+			return result, fmt.Errorf("failed to check existing article: %w", err)
+		}
+
+		articleFolderID, tags := folderID, opts.Tags
+		if match := opts.Rules.Apply(canonicalURL, canonicalURL); match != nil {
+			tags = append(append([]string{}, opts.Tags...), match.Tags...)
+			if folderID == nil && match.Folder != "" {
+				id, err := i.db.UpsertFolderPath(match.Folder)
+				if err != nil {
+					return result, fmt.Errorf("failed to upsert folder %q: %w", match.Folder, err)
+				}
+				articleFolderID = &id
+				filed = true
+			}
+		}
+
+		// The URL stands in for the title until fetch extracts the real one
+		res, err := i.db.ExecContext(ctx, `
+			INSERT INTO articles (url, title, title_norm, folder_id, instapapered_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, canonicalURL, canonicalURL, util.NormalizeText(canonicalURL), articleFolderID, time.Now().UTC().Format(time.RFC3339))
+		if err != nil {
+			return result, fmt.Errorf("failed to insert article: %w", err)
+		}
+
+		articleID, err := res.LastInsertId()
+		if err != nil {
+			return result, fmt.Errorf("failed to get article ID: %w", err)
+		}
+
+		if err := i.db.AddArticleTags(articleID, util.DedupeStrings(tags)); err != nil {
+			return result, err
+		}
+
+		if err := i.db.UpsertArticleFTS(articleID); err != nil {
+			return result, fmt.Errorf("failed to update FTS: %w", err)
+		}
+
+		result.Added = append(result.Added, AddedArticle{ID: articleID, URL: canonicalURL})
+	}
+
+	if folderID != nil || filed {
+		if err := i.db.UpdateFolderPaths(); err != nil {
+			return result, fmt.Errorf("failed to update folder paths: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// canonicalAddURL canonicalizes a URL given on the command line, assuming https when the scheme is missing
+func canonicalAddURL(rawURL string) (string, bool) {
+	rawURL = strings.TrimSpace(rawURL)
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	canonicalURL, err := util.CanonicalizeURL(rawURL)
+	if err != nil {
+		return "", false
+	}
+
+	u, err := url.Parse(canonicalURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !strings.Contains(u.Hostname(), ".") {
+		return "", false
+	}
+	return canonicalURL, true
+}
@@ -0,0 +1,144 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"instapaper-cli/internal/model"
+)
+
+// Format is the service a CSV export came from
+type Format string
+
+const (
+	FormatUnknown    Format = ""
+	FormatInstapaper Format = "instapaper"
+	FormatPocket     Format = "pocket"
+)
+
+var instapaperHeaders = []string{"URL", "Title", "Selection", "Folder", "Timestamp", "Tags"}
+
+// unreadFolder is where Instapaper files articles that were never moved or archived
+const unreadFolder = "Unread"
+
+// archiveFolder is where Instapaper files archived articles; new ones start out with the archived status
+const archiveFolder = "Archive"
+
+// pocketRequiredHeaders are the Pocket export columns needed to import an article
+var pocketRequiredHeaders = []string{"title", "url", "time_added"}
+
+// DetectFormat reads the header row of a CSV file and reports which export it is
+func DetectFormat(csvPath string) (Format, error) {
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return FormatUnknown, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+
+	headers, err := reader.Read()
+	if err != nil {
+		return FormatUnknown, nil
+	}
+
+	return formatOf(headers), nil
+}
+
+func formatOf(headers []string) Format {
+	index := headerIndex(headers)
+
+	instapaper := len(headers) == len(instapaperHeaders)
+	for _, header := range instapaperHeaders {
+		if _, ok := index[header]; !ok {
+			instapaper = false
+		}
+	}
+	if instapaper {
+		return FormatInstapaper
+	}
+
+	for _, header := range pocketRequiredHeaders {
+		if _, ok := index[header]; !ok {
+			return FormatUnknown
+		}
+	}
+	return FormatPocket
+}
+
+// headerIndex maps header names to column positions, ignoring a UTF-8 byte order mark
+func headerIndex(headers []string) map[string]int {
+	index := make(map[string]int, len(headers))
+	for i, header := range headers {
+		index[strings.TrimSpace(strings.TrimPrefix(header, "\ufeff"))] = i
+	}
+	return index
+}
+
+func parseInstapaperRecord(record []string) (model.CSVRecord, error) {
+	if len(record) != len(instapaperHeaders) {
+		return model.CSVRecord{}, fmt.Errorf("expected %d fields, got %d", len(instapaperHeaders), len(record))
+	}
+
+	csvRecord := model.CSVRecord{
+		URL:       record[0],
+		Title:     record[1],
+		Selection: record[2],
+		Folder:    record[3],
+		Tags:      record[5],
+	}
+
+	timestamp, err := strconv.ParseInt(record[4], 10, 64)
+	if err != nil {
+		return csvRecord, fmt.Errorf("invalid timestamp: %v", err)
+	}
+	csvRecord.Timestamp = timestamp
+
+	return csvRecord, nil
+}
+
+// pocketParser maps Pocket export rows (title,url,time_added,tags,status) onto Instapaper records.
+// Archived items go to the Archive folder and everything else to Unread, as in Instapaper exports.
+func pocketParser(headers []string) func(record []string) (model.CSVRecord, error) {
+	index := headerIndex(headers)
+
+	field := func(record []string, name string) string {
+		i, ok := index[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	return func(record []string) (model.CSVRecord, error) {
+		csvRecord := model.CSVRecord{
+			URL:    field(record, "url"),
+			Title:  field(record, "title"),
+			Folder: unreadFolder,
+			// Pocket separates tags with "|"
+			Tags: strings.ReplaceAll(field(record, "tags"), "|", ","),
+		}
+
+		if csvRecord.URL == "" {
+			return csvRecord, fmt.Errorf("missing url")
+		}
+		if csvRecord.Title == "" {
+			csvRecord.Title = csvRecord.URL
+		}
+		if field(record, "status") == "archive" {
+			csvRecord.Folder = archiveFolder
+		}
+
+		timestamp, err := strconv.ParseInt(field(record, "time_added"), 10, 64)
+		if err != nil {
+			return csvRecord, fmt.Errorf("invalid timestamp: %v", err)
+		}
+		csvRecord.Timestamp = timestamp
+
+		return csvRecord, nil
+	}
+}
@@ -1,16 +1,18 @@
 package importer
 
 import (
+	"context"
 	"database/sql"
 	"encoding/csv"
 	"fmt"
 	"io"
 	"log"
 	"os"
-	"strconv"
+	"strings"
 
 	"instapaper-cli/internal/db"
 	"instapaper-cli/internal/model"
+	"instapaper-cli/internal/tagging"
 	"instapaper-cli/internal/util"
 )
 
@@ -18,14 +20,54 @@ type Importer struct {
 	db *db.DB
 }
 
+// ImportResult summarizes an import run
+type ImportResult struct {
+	Format    string            `json:"format"`
+	Total     int               `json:"total"`
+	Processed int               `json:"processed"`
+	Skipped   int               `json:"skipped"`
+	Mapped    int               `json:"mapped,omitempty"`
+	Warnings  []string          `json:"warnings,omitempty"`
+	Errors    []model.ItemError `json:"errors,omitempty"`
+	// RolledBack is set when an atomic import failed, so none of its records were kept
+	RolledBack bool `json:"rolled_back,omitempty"`
+}
+
+// ImportOptions controls how CSV records are mapped onto the database
+type ImportOptions struct {
+	// SplitFolders treats slashes in Instapaper folder names as a hierarchy, so
+	// "Tech/Go" becomes a "Go" folder inside "Tech" rather than one flat folder
+	SplitFolders bool
+	// FoldersAsTags tags each article with its folder name (after FolderTagPrefix) instead of filing it in a folder
+	FoldersAsTags   bool
+	FolderTagPrefix string
+	// TagsAsFolders files each article in the folder named by its first tag with this prefix,
+	// e.g. "folder/" turns the tag "folder/Tech" into the folder "Tech"
+	TagsAsFolders string
+	// Rules tag and file records by URL, domain, and title
+	Rules *tagging.Rules
+	// Mapping renames, merges, and drops folders and tags before the other options apply
+	Mapping *Mapping
+	// Atomic imports the whole file in one transaction, so an interrupted or failed import keeps nothing
+	Atomic bool
+}
+
 func New(database *db.DB) *Importer {
 	return &Importer{db: database}
 }
 
-func (i *Importer) ImportCSV(csvPath string) error {
+// ImportCSV imports an Instapaper or Pocket CSV export, detected from the header row. Each record is
+// written in its own transaction, and search index entries in batches. When ctx is cancelled the
+// records read so far are kept, folder paths are still updated, and the partial result is returned
+// with ctx's error. With opts.Atomic they are rolled back instead.
+func (i *Importer) ImportCSV(ctx context.Context, csvPath string, opts ImportOptions) (*ImportResult, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
 	file, err := os.Open(csvPath)
 	if err != nil {
-		return fmt.Errorf("failed to open CSV file: %w", err)
+		return nil, fmt.Errorf("failed to open CSV file: %w", err)
 	}
 	defer file.Close()
 
@@ -33,62 +75,106 @@ func (i *Importer) ImportCSV(csvPath string) error {
 
 	headers, err := reader.Read()
 	if err != nil {
-		return fmt.Errorf("failed to read CSV headers: %w", err)
+		return nil, fmt.Errorf("failed to read CSV headers: %w", err)
 	}
 
-	expectedHeaders := []string{"URL", "Title", "Selection", "Folder", "Timestamp", "Tags"}
-	if len(headers) != len(expectedHeaders) {
-		return fmt.Errorf("unexpected number of CSV columns: got %d, expected %d", len(headers), len(expectedHeaders))
-	}
+	result := &ImportResult{}
+
+	var parse func(record []string) (model.CSVRecord, error)
+	switch formatOf(headers) {
+	case FormatPocket:
+		result.Format = string(FormatPocket)
+		parse = pocketParser(headers)
+	default:
+		result.Format = string(FormatInstapaper)
+		if len(headers) != len(instapaperHeaders) {
+			return nil, fmt.Errorf("unexpected number of CSV columns: got %d, expected %d", len(headers), len(instapaperHeaders))
+		}
 
-	for idx, header := range headers {
-		if header != expectedHeaders[idx] {
-			log.Printf("Warning: unexpected header at position %d: got %q, expected %q", idx, header, expectedHeaders[idx])
+		for idx, header := range headers {
+			if header != instapaperHeaders[idx] {
+				warning := fmt.Sprintf("unexpected header at position %d: got %q, expected %q", idx, header, instapaperHeaders[idx])
+				log.Printf("Warning: %s", warning)
+				result.Warnings = append(result.Warnings, warning)
+			}
 		}
+		parse = parseInstapaperRecord
+	}
+
+	if !opts.Atomic {
+		err := i.importRecords(ctx, reader, parse, opts, result)
+		return result, err
+	}
+
+	// Cancellation is checked between records, and rolls back here rather than mid-record
+	err = i.db.InTx(context.WithoutCancel(ctx), func(tx *db.DB) error {
+		return (&Importer{db: tx}).importRecords(ctx, reader, parse, opts, result)
+	})
+	if err != nil {
+		log.Printf("Import rolled back: %v", err)
+		result.RolledBack = true
+		result.Processed = 0
+		return result, fmt.Errorf("import rolled back: %w", err)
 	}
+	return result, nil
+}
 
+// importRecords imports the records left in reader into result, returning ctx's error when it is
+// cancelled
+func (i *Importer) importRecords(ctx context.Context, reader *csv.Reader, parse func(record []string) (model.CSVRecord, error), opts ImportOptions, result *ImportResult) error {
 	var recordCount, skipCount, processedCount int
+	var interrupted error
+
+	// Search index entries are written in batches, which keeps the write lock free between them
+	fts := i.db.NewFTSBatch(db.DefaultFTSBatchSize, db.DefaultFTSBatchInterval)
+	ftsWarned := false
 
 	for {
+		if err := ctx.Err(); err != nil {
+			log.Printf("Import interrupted after %d records", recordCount)
+			interrupted = err
+			break
+		}
+
 		record, err := reader.Read()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
 			log.Printf("Error reading CSV record at line %d: %v", recordCount+2, err)
+			result.Errors = append(result.Errors, model.ItemError{Line: recordCount + 2, Error: err.Error()})
 			skipCount++
 			continue
 		}
 
 		recordCount++
 
-		if len(record) != 6 {
-			log.Printf("Skipping malformed record at line %d: expected 6 fields, got %d", recordCount+1, len(record))
+		csvRecord, err := parse(record)
+		if err != nil {
+			log.Printf("Skipping record at line %d: %v", recordCount+1, err)
+			result.Errors = append(result.Errors, model.ItemError{Line: recordCount + 1, URL: csvRecord.URL, Error: err.Error()})
 			skipCount++
 			continue
 		}
 
-		csvRecord := model.CSVRecord{
-			URL:       record[0],
-			Title:     record[1],
-			Selection: record[2],
-			Folder:    record[3],
-			Tags:      record[5],
+		csvRecord, mapped := opts.Mapping.Apply(csvRecord)
+		if mapped {
+			result.Mapped++
 		}
+		csvRecord = opts.mapTaxonomy(csvRecord)
 
-		timestamp, err := strconv.ParseInt(record[4], 10, 64)
+		articleID, err := i.processRecordTx(ctx, csvRecord, opts)
 		if err != nil {
-			log.Printf("Skipping record with invalid timestamp at line %d: %v", recordCount+1, err)
-			skipCount++
-			continue
-		}
-		csvRecord.Timestamp = timestamp
-
-		if err := i.processRecord(csvRecord); err != nil {
 			log.Printf("Error processing record at line %d: %v", recordCount+1, err)
+			result.Errors = append(result.Errors, model.ItemError{Line: recordCount + 1, URL: csvRecord.URL, Error: err.Error()})
 			skipCount++
 			continue
 		}
+		// A failed batch stays queued and is retried with the next one
+		if err := fts.Add(ctx, articleID); err != nil && !ftsWarned {
+			log.Printf("Warning: %v, retrying with the next batch", err)
+			ftsWarned = true
+		}
 
 		processedCount++
 
@@ -99,31 +185,79 @@ func (i *Importer) ImportCSV(csvPath string) error {
 
 	if err := i.db.UpdateFolderPaths(); err != nil {
 		log.Printf("Warning: failed to update folder paths: %v", err)
+		result.Warnings = append(result.Warnings, fmt.Sprintf("failed to update folder paths: %v", err))
+	}
+
+	// Entries include folder paths, so they are written once the paths are up to date
+	if err := fts.Flush(context.WithoutCancel(ctx)); err != nil {
+		log.Printf("Warning: %v", err)
+		result.Warnings = append(result.Warnings, fmt.Sprintf("search index not fully updated, run doctor to rebuild it: %v", err))
 	}
 
 	log.Printf("Import completed: %d total records, %d processed, %d skipped", recordCount, processedCount, skipCount)
-	return nil
+	if result.Mapped > 0 {
+		log.Printf("Mapped the folder or tags of %d records", result.Mapped)
+	}
+
+	result.Total = recordCount
+	result.Processed = processedCount
+	result.Skipped = skipCount
+	return interrupted
+}
+
+// processRecordTx processes a record in a transaction, so a failure leaves none of its article,
+// tags, or highlights behind. A started record is finished even if ctx is cancelled.
+func (i *Importer) processRecordTx(ctx context.Context, record model.CSVRecord, opts ImportOptions) (int64, error) {
+	var articleID int64
+	err := i.db.InTx(context.WithoutCancel(ctx), func(tx *db.DB) error {
+		var err error
+		articleID, err = (&Importer{db: tx}).processRecord(record, opts)
+		return err
+	})
+	return articleID, err
 }
 
-func (i *Importer) processRecord(record model.CSVRecord) error {
+// processRecord saves a record's article with its folder, tags, and highlights, returning its ID.
+// The caller updates its search index entry.
+func (i *Importer) processRecord(record model.CSVRecord, opts ImportOptions) (int64, error) {
 	canonicalURL, err := util.CanonicalizeURL(record.URL)
 	if err != nil {
-		return fmt.Errorf("failed to canonicalize URL %q: %w", record.URL, err)
+		return 0, fmt.Errorf("failed to canonicalize URL %q: %w", record.URL, err)
+	}
+
+	// Rules add tags, and file articles that would land in no folder or Unread
+	match := opts.Rules.Apply(canonicalURL, record.Title)
+	if match != nil && len(match.Tags) > 0 {
+		record.Tags = formatTags(append(util.ParseTags(record.Tags), match.Tags...))
 	}
 
 	var folderID *int64
-	if record.Folder != "" {
-		id, err := i.db.UpsertFolder(record.Folder, nil)
+	if match != nil && match.Folder != "" && (record.Folder == "" || record.Folder == unreadFolder) {
+		id, err := i.db.UpsertFolderPath(match.Folder)
 		if err != nil {
-			return fmt.Errorf("failed to upsert folder %q: %w", record.Folder, err)
+			return 0, fmt.Errorf("failed to upsert folder %q: %w", match.Folder, err)
+		}
+		folderID = &id
+	} else if record.Folder != "" {
+		var id int64
+		_, aliased, err := i.db.ResolveAlias(db.AliasFolder, record.Folder)
+		if err != nil {
+			return 0, err
+		}
+		if aliased || (opts.SplitFolders && strings.Contains(record.Folder, "/") && len(db.SplitFolderPath(record.Folder)) > 0) {
+			id, err = i.db.UpsertFolderPath(record.Folder)
+		} else {
+			id, err = i.db.UpsertFolder(record.Folder, nil)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to upsert folder %q: %w", record.Folder, err)
 		}
 		folderID = &id
 	}
 
 	instapaperedAt := util.UnixToISO8601(record.Timestamp)
 
-	var existingID int64
-	err = i.db.Get(&existingID, "SELECT id FROM articles WHERE url = ?", canonicalURL)
+	existingID, err := i.db.FindArticleByURL(canonicalURL)
 
 	var selection *string
 	if record.Selection != "" {
@@ -131,54 +265,57 @@ func (i *Importer) processRecord(record model.CSVRecord) error {
 	}
 
 	if err == sql.ErrNoRows {
+		status := db.StatusInbox
+		if record.Folder == archiveFolder {
+			status = db.StatusArchived
+		}
+
 		result, err := i.db.Exec(`
-			INSERT INTO articles (url, title, selection, folder_id, instapapered_at)
-			VALUES (?, ?, ?, ?, ?)
-		`, canonicalURL, record.Title, selection, folderID, instapaperedAt)
+			INSERT INTO articles (url, title, title_norm, selection, folder_id, instapapered_at, status)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, canonicalURL, record.Title, util.NormalizeText(record.Title), selection, folderID, instapaperedAt, status)
 		if err != nil {
-			return fmt.Errorf("failed to insert article: %w", err)
+			return 0, fmt.Errorf("failed to insert article: %w", err)
 		}
 
 		articleID, err := result.LastInsertId()
 		if err != nil {
-			return fmt.Errorf("failed to get article ID: %w", err)
+			return 0, fmt.Errorf("failed to get article ID: %w", err)
 		}
 
 		if err := i.processTags(articleID, record.Tags); err != nil {
-			return fmt.Errorf("failed to process tags: %w", err)
+			return 0, fmt.Errorf("failed to process tags: %w", err)
 		}
 
-		// Update FTS table for new article
-		if err := i.db.UpsertArticleFTS(articleID); err != nil {
-			log.Printf("Warning: failed to update FTS for new article %d: %v", articleID, err)
+		if err := i.db.ReplaceHighlights(articleID, util.ParseHighlights(record.Selection)); err != nil {
+			return 0, fmt.Errorf("failed to process highlights: %w", err)
 		}
+		return articleID, nil
 	} else if err != nil {
-		return fmt.Errorf("failed to check existing article: %w", err)
+		return 0, fmt.Errorf("failed to check existing article: %w", err)
 	} else {
 		_, err := i.db.Exec(`
 			UPDATE articles
-			SET title = ?, selection = ?, folder_id = ?, instapapered_at = ?
+			SET title = ?, title_norm = ?, selection = ?, folder_id = ?, instapapered_at = ?
 			WHERE id = ?
-		`, record.Title, selection, folderID, instapaperedAt, existingID)
+		`, record.Title, util.NormalizeText(record.Title), selection, folderID, instapaperedAt, existingID)
 		if err != nil {
-			return fmt.Errorf("failed to update article: %w", err)
+			return 0, fmt.Errorf("failed to update article: %w", err)
 		}
 
 		if _, err := i.db.Exec("DELETE FROM article_tags WHERE article_id = ?", existingID); err != nil {
-			return fmt.Errorf("failed to delete existing tags: %w", err)
+			return 0, fmt.Errorf("failed to delete existing tags: %w", err)
 		}
 
 		if err := i.processTags(existingID, record.Tags); err != nil {
-			return fmt.Errorf("failed to process tags: %w", err)
+			return 0, fmt.Errorf("failed to process tags: %w", err)
 		}
 
-		// Update FTS table for updated article
-		if err := i.db.UpsertArticleFTS(existingID); err != nil {
-			log.Printf("Warning: failed to update FTS for updated article %d: %v", existingID, err)
+		if err := i.db.ReplaceHighlights(existingID, util.ParseHighlights(record.Selection)); err != nil {
+			return 0, fmt.Errorf("failed to process highlights: %w", err)
 		}
+		return existingID, nil
 	}
-
-	return nil
 }
 
 func (i *Importer) processTags(articleID int64, tagsStr string) error {
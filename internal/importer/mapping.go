@@ -0,0 +1,134 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"instapaper-cli/internal/model"
+	"instapaper-cli/internal/util"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Mapping renames, merges, and drops folders and tags as records are imported, from a YAML file:
+//
+//	folders:
+//	  Read Later: {tag: inbox}
+//	  Unread: {drop: true}
+//	  Tech/Golang: {to: Programming/Go}
+//	tags:
+//	  ml: {to: machine-learning}
+//	  todo: {drop: true}
+//
+// Names match ignoring case. Mapping several names to the same one merges them.
+type Mapping struct {
+	Folders map[string]MappingRule `yaml:"folders"`
+	Tags    map[string]MappingRule `yaml:"tags"`
+
+	folders map[string]MappingRule
+	tags    map[string]MappingRule
+}
+
+// MappingRule says what a folder or tag becomes
+type MappingRule struct {
+	// To renames the folder or tag. With split folders, slashes in a folder make a hierarchy.
+	To string `yaml:"to"`
+	// Tag tags the folder's articles instead of filing them in it, or as well when To is set
+	Tag string `yaml:"tag"`
+	// Drop leaves the folder's articles unfiled, or removes the tag
+	Drop bool `yaml:"drop"`
+}
+
+// LoadMapping reads and validates an import mapping from a YAML file. An empty path means no mapping.
+func LoadMapping(path string) (*Mapping, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import mapping: %w", err)
+	}
+
+	var mapping Mapping
+	if err := yaml.Unmarshal(data, &mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse import mapping: %w", err)
+	}
+
+	if mapping.folders, err = mappingIndex("folder", mapping.Folders); err != nil {
+		return nil, err
+	}
+	if mapping.tags, err = mappingIndex("tag", mapping.Tags); err != nil {
+		return nil, err
+	}
+	for name, rule := range mapping.Tags {
+		if rule.Tag != "" {
+			return nil, fmt.Errorf("tag %q: use to, not tag, to rename a tag", name)
+		}
+	}
+
+	return &mapping, nil
+}
+
+// mappingIndex checks a section's rules and keys them by lowercased name
+func mappingIndex(kind string, rules map[string]MappingRule) (map[string]MappingRule, error) {
+	index := make(map[string]MappingRule, len(rules))
+	for name, rule := range rules {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if key == "" {
+			return nil, fmt.Errorf("import mapping has an empty %s name", kind)
+		}
+		if _, ok := index[key]; ok {
+			return nil, fmt.Errorf("%s %q is mapped more than once", kind, name)
+		}
+
+		rule.To = strings.TrimSpace(rule.To)
+		rule.Tag = strings.TrimSpace(rule.Tag)
+		if rule.Drop && (rule.To != "" || rule.Tag != "") {
+			return nil, fmt.Errorf("%s %q: drop cannot be combined with to or tag", kind, name)
+		}
+		if !rule.Drop && rule.To == "" && rule.Tag == "" {
+			return nil, fmt.Errorf("%s %q has no to, tag, or drop", kind, name)
+		}
+		index[key] = rule
+	}
+	return index, nil
+}
+
+// Apply maps a record's folder and tags, and reports whether anything changed
+func (m *Mapping) Apply(record model.CSVRecord) (model.CSVRecord, bool) {
+	if m == nil {
+		return record, false
+	}
+
+	changed := false
+	tags := util.ParseTags(record.Tags)
+
+	if rule, ok := m.folders[strings.ToLower(strings.TrimSpace(record.Folder))]; ok && record.Folder != "" {
+		record.Folder = rule.To
+		if rule.Tag != "" {
+			tags = append(tags, rule.Tag)
+		}
+		changed = true
+	}
+
+	var kept []string
+	for _, tag := range tags {
+		rule, ok := m.tags[strings.ToLower(tag)]
+		switch {
+		case !ok:
+			kept = append(kept, tag)
+		case rule.Drop:
+			changed = true
+		default:
+			kept = append(kept, rule.To)
+			changed = true
+		}
+	}
+
+	if changed {
+		record.Tags = formatTags(util.DedupeStrings(kept))
+	}
+	return record, changed
+}
@@ -0,0 +1,55 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+
+	"instapaper-cli/internal/model"
+	"instapaper-cli/internal/util"
+)
+
+// validate rejects option combinations that would undo each other
+func (opts ImportOptions) validate() error {
+	if opts.FoldersAsTags && opts.TagsAsFolders != "" {
+		return fmt.Errorf("folders as tags and tags as folders cannot be combined")
+	}
+	return nil
+}
+
+// mapTaxonomy moves a record's folder into its tags, or its first prefixed tag into its folder
+func (opts ImportOptions) mapTaxonomy(record model.CSVRecord) model.CSVRecord {
+	switch {
+	case opts.FoldersAsTags && record.Folder != "":
+		tags := append(util.ParseTags(record.Tags), opts.FolderTagPrefix+record.Folder)
+		record.Tags = formatTags(tags)
+		record.Folder = ""
+
+	case opts.TagsAsFolders != "":
+		var kept []string
+		folder := ""
+		for _, tag := range util.ParseTags(record.Tags) {
+			name := strings.TrimSpace(strings.TrimPrefix(tag, opts.TagsAsFolders))
+			// An article has one folder; further prefixed tags stay tags
+			if folder == "" && strings.HasPrefix(tag, opts.TagsAsFolders) && name != "" {
+				folder = name
+				continue
+			}
+			kept = append(kept, tag)
+		}
+		if folder != "" {
+			record.Folder = folder
+			record.Tags = formatTags(kept)
+		}
+	}
+
+	return record
+}
+
+// formatTags writes tags in the Instapaper export's list form, which keeps commas inside tag names
+func formatTags(tags []string) string {
+	quoted := make([]string, len(tags))
+	for i, tag := range tags {
+		quoted[i] = `"` + strings.ReplaceAll(tag, `"`, "'") + `"`
+	}
+	return "[" + strings.Join(quoted, ",") + "]"
+}
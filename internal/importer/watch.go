@@ -0,0 +1,198 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// WatchOptions controls how a directory is watched for CSV exports
+type WatchOptions struct {
+	Dir string
+	// ProcessedDir receives imported files (default Dir/processed)
+	ProcessedDir string
+	// FailedDir receives files that could not be imported (default Dir/failed)
+	FailedDir string
+	// Interval between directory scans. A file is imported once its size and modification
+	// time are unchanged across two scans, so half-written downloads are left alone.
+	Interval time.Duration
+	// LockWait is how long an import waits for another writer; a busy database leaves the file for the next scan
+	LockWait time.Duration
+	Import   ImportOptions
+	// OnImport is called after each import attempt, with the path the file was moved to
+	OnImport func(path string, result *ImportResult, err error)
+	// OnScan is called after each scan of the directory, e.g. for periodic maintenance
+	OnScan func()
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// Watch scans opts.Dir until ctx is cancelled, importing Instapaper and Pocket CSV exports that
+// appear in it. Other CSV files are ignored and left in place.
+func (i *Importer) Watch(ctx context.Context, opts WatchOptions) error {
+	if opts.ProcessedDir == "" {
+		opts.ProcessedDir = filepath.Join(opts.Dir, "processed")
+	}
+	if opts.FailedDir == "" {
+		opts.FailedDir = filepath.Join(opts.Dir, "failed")
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 10 * time.Second
+	}
+	if err := opts.Import.validate(); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(opts.Dir); err != nil {
+		return fmt.Errorf("failed to access watch directory: %w", err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("watch directory is not a directory: %s", opts.Dir)
+	}
+
+	pending := make(map[string]fileState)
+	ignored := make(map[string]fileState)
+
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := i.scan(ctx, opts, pending, ignored); err != nil {
+			log.Printf("Watch: %v", err)
+		}
+		if opts.OnScan != nil && ctx.Err() == nil {
+			opts.OnScan()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan imports every CSV export in opts.Dir that has stopped changing since the previous scan
+func (i *Importer) scan(ctx context.Context, opts WatchOptions, pending, ignored map[string]fileState) error {
+	entries, err := os.ReadDir(opts.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to read watch directory: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".csv") || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		path := filepath.Join(opts.Dir, entry.Name())
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+		seen[path] = true
+
+		if ignored[path] == state {
+			continue
+		}
+		delete(ignored, path)
+
+		if previous, ok := pending[path]; !ok || previous != state {
+			pending[path] = state
+			continue
+		}
+		delete(pending, path)
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		format, err := DetectFormat(path)
+		if err != nil {
+			log.Printf("Watch: %v", err)
+			continue
+		}
+		if format == FormatUnknown {
+			ignored[path] = state
+			continue
+		}
+
+		log.Printf("Watch: importing %s export %s", format, path)
+		i.importWatched(ctx, opts, path)
+	}
+
+	// Forget files that were removed or renamed
+	for path := range pending {
+		if !seen[path] {
+			delete(pending, path)
+		}
+	}
+	for path := range ignored {
+		if !seen[path] {
+			delete(ignored, path)
+		}
+	}
+
+	return nil
+}
+
+// importWatched imports one file and moves it to the processed or failed directory.
+// An interrupted import leaves the file in place so it is imported again on the next run.
+func (i *Importer) importWatched(ctx context.Context, opts WatchOptions, path string) {
+	lock, err := i.db.LockWrites(ctx, "daemon", opts.LockWait)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Watch: leaving %s for a later scan: %v", path, err)
+		}
+		return
+	}
+	defer lock.Release()
+
+	result, err := i.ImportCSV(ctx, path, opts.Import)
+	if ctx.Err() != nil {
+		return
+	}
+
+	target := opts.ProcessedDir
+	if err != nil {
+		target = opts.FailedDir
+		log.Printf("Watch: failed to import %s: %v", path, err)
+	}
+
+	moved, moveErr := moveInto(path, target)
+	if moveErr != nil {
+		log.Printf("Watch: %v", moveErr)
+		moved = path
+	}
+
+	if opts.OnImport != nil {
+		opts.OnImport(moved, result, err)
+	}
+}
+
+// moveInto moves a file into dir, adding a timestamp to the name if it is already taken
+func moveInto(path, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	name := filepath.Base(path)
+	target := filepath.Join(dir, name)
+	if _, err := os.Stat(target); err == nil {
+		ext := filepath.Ext(name)
+		target = filepath.Join(dir, fmt.Sprintf("%s-%s%s", strings.TrimSuffix(name, ext), time.Now().Format("20060102-150405"), ext))
+	}
+
+	if err := os.Rename(path, target); err != nil {
+		return "", fmt.Errorf("failed to move %s: %w", path, err)
+	}
+	return target, nil
+}
@@ -0,0 +1,333 @@
+// Package ingest turns content that arrives outside the browser, such as newsletter emails, into
+// articles
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"instapaper-cli/internal/db"
+	"instapaper-cli/internal/fetcher"
+	"instapaper-cli/internal/model"
+	"instapaper-cli/internal/util"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/emersion/go-message"
+	_ "github.com/emersion/go-message/charset" // Decode newsletters sent in legacy charsets
+	"github.com/emersion/go-message/mail"
+)
+
+// DefaultTag is the tag given to ingested newsletters when no other is asked for
+const DefaultTag = "newsletter"
+
+// webVersionText matches the text of a newsletter's link to its copy on the web
+var webVersionText = regexp.MustCompile(`(?i)\b(view|read|open)\b.{0,20}\b(browser|online|web)\b|\bweb version\b`)
+
+// EmailOptions files and tags the articles made from emails
+type EmailOptions struct {
+	// Tags are given to every article; nil gives DefaultTag
+	Tags []string
+	// FolderPrefix files articles in FolderPrefix/<sender>; empty files them under the sender alone
+	FolderPrefix string
+	// DryRun parses messages and reports what would be ingested without changing anything
+	DryRun bool
+	// NoPrettify stores the converted Markdown as-is, without blank-line and tracker cleanup
+	NoPrettify bool
+}
+
+// EmailResult summarizes an email ingestion run
+type EmailResult struct {
+	Messages   int               `json:"messages"`
+	Ingested   []IngestedEmail   `json:"ingested"`
+	Duplicates int               `json:"duplicates"`
+	Errors     []model.ItemError `json:"errors,omitempty"`
+}
+
+// IngestedEmail is an article made from an email, or one that would be in a dry run
+type IngestedEmail struct {
+	ID     int64  `json:"id,omitempty"`
+	URL    string `json:"url"`
+	Title  string `json:"title"`
+	Sender string `json:"sender"`
+	Folder string `json:"folder"`
+}
+
+// Email is the part of a message an article is made from
+type Email struct {
+	MessageID string
+	Sender    string // Display name of the sender, or their address when they have none
+	Subject   string
+	Date      time.Time
+	HTML      []byte // The text/plain body as HTML paragraphs when the message has no HTML
+}
+
+// ParseEmail reads a message's sender, subject, and body, preferring the HTML body
+func ParseEmail(r io.Reader) (*Email, error) {
+	mr, err := mail.CreateReader(r)
+	if err != nil && !message.IsUnknownCharset(err) {
+		return nil, fmt.Errorf("failed to parse email: %w", err)
+	}
+	defer mr.Close()
+
+	email := &Email{}
+	email.Subject, _ = mr.Header.Subject()
+	email.Date, _ = mr.Header.Date()
+	email.MessageID, _ = mr.Header.MessageID()
+	if from, err := mr.Header.AddressList("From"); err == nil && len(from) > 0 {
+		email.Sender = strings.TrimSpace(from[0].Name)
+		if email.Sender == "" {
+			email.Sender = from[0].Address
+		}
+	}
+	if email.MessageID == "" {
+		// Without a Message-ID the headers identify the message across runs
+		sum := sha256.Sum256([]byte(email.Sender + "\n" + email.Subject + "\n" + email.Date.UTC().Format(time.RFC3339)))
+		email.MessageID = fmt.Sprintf("%x@instapaper-cli", sum[:12])
+	}
+
+	var text []byte
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil && !message.IsUnknownCharset(err) {
+			return nil, fmt.Errorf("failed to read email body: %w", err)
+		}
+		header, ok := part.Header.(*mail.InlineHeader)
+		if !ok {
+			continue
+		}
+
+		mediaType, _, _ := header.ContentType()
+		switch {
+		case mediaType == "text/html" && email.HTML == nil:
+			if email.HTML, err = io.ReadAll(part.Body); err != nil {
+				return nil, fmt.Errorf("failed to read email body: %w", err)
+			}
+		case (mediaType == "text/plain" || mediaType == "") && text == nil:
+			if text, err = io.ReadAll(part.Body); err != nil {
+				return nil, fmt.Errorf("failed to read email body: %w", err)
+			}
+		}
+	}
+
+	if email.HTML == nil && text != nil {
+		email.HTML = textToHTML(string(text))
+	}
+	if email.HTML == nil {
+		return nil, fmt.Errorf("email has no HTML or text body")
+	}
+	return email, nil
+}
+
+// textToHTML turns a plain text body into paragraphs
+func textToHTML(text string) []byte {
+	var b bytes.Buffer
+	b.WriteString("<html><body>")
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			b.WriteString("<p>" + strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>") + "</p>")
+		}
+	}
+	b.WriteString("</body></html>")
+	return b.Bytes()
+}
+
+// WebURL returns the link to the newsletter's copy on the web, like "View in browser", so the
+// article points somewhere a browser can open. Emails without one get a mid: URL naming the
+// message (RFC 2392).
+func (e *Email) WebURL() string {
+	if doc, err := goquery.NewDocumentFromReader(bytes.NewReader(e.HTML)); err == nil {
+		var found string
+		doc.Find("a[href]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+			if !webVersionText.MatchString(strings.Join(strings.Fields(s.Text()), " ")) {
+				return true
+			}
+			href, _ := s.Attr("href")
+			if u, err := url.Parse(strings.TrimSpace(href)); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+				if canonical, err := util.CanonicalizeURL(u.String()); err == nil {
+					found = canonical
+					return false
+				}
+			}
+			return true
+		})
+		if found != "" {
+			return found
+		}
+	}
+	return "mid:" + url.PathEscape(e.MessageID)
+}
+
+// Folder returns the folder path an email's article is filed in
+func (e *Email) Folder(prefix string) string {
+	// A slash in the sender's name would nest folders
+	sender := strings.TrimSpace(strings.ReplaceAll(e.Sender, "/", "-"))
+	if sender == "" {
+		sender = "Unknown sender"
+	}
+	if prefix = strings.Trim(strings.TrimSpace(prefix), "/"); prefix != "" {
+		return prefix + "/" + sender
+	}
+	return sender
+}
+
+// Ingester stores emails as articles
+type Ingester struct {
+	db      *db.DB
+	fetcher *fetcher.Fetcher
+	opts    EmailOptions
+	filed   bool
+}
+
+// NewIngester returns an Ingester filing and tagging articles as opts say
+func NewIngester(database *db.DB, opts EmailOptions) *Ingester {
+	if opts.Tags == nil {
+		opts.Tags = []string{DefaultTag}
+	}
+	return &Ingester{db: database, fetcher: fetcher.New(database), opts: opts}
+}
+
+// Ingest makes an article from an email, filed under its sender and tagged. It returns false
+// without an error when the message, or the web page it links, was ingested before.
+func (in *Ingester) Ingest(ctx context.Context, email *Email, mailbox string) (IngestedEmail, bool, error) {
+	item := IngestedEmail{URL: email.WebURL(), Title: email.Subject, Sender: email.Sender, Folder: email.Folder(in.opts.FolderPrefix)}
+	if item.Title == "" {
+		item.Title = "Email from " + email.Sender
+	}
+
+	var exists bool
+	if err := in.db.GetContext(ctx, &exists, "SELECT EXISTS(SELECT 1 FROM ingested_emails WHERE message_id = ?)", email.MessageID); err != nil {
+		return item, false, fmt.Errorf("failed to check ingested emails: %w", err)
+	}
+	if exists {
+		return item, false, nil
+	}
+
+	existingID, err := in.db.FindArticleByURL(item.URL)
+	if err == nil {
+		// The same issue arrived twice, or its web version was saved already
+		if !in.opts.DryRun {
+			if err := in.recordMessage(ctx, email, mailbox, existingID); err != nil {
+				return item, false, err
+			}
+		}
+		return item, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return item, false, fmt.Errorf("failed to check existing article: %w", err)
+	}
+
+	if in.opts.DryRun {
+		return item, true, nil
+	}
+
+	folderID, err := in.db.UpsertFolderPath(item.Folder)
+	if err != nil {
+		return item, false, fmt.Errorf("failed to upsert folder %q: %w", item.Folder, err)
+	}
+	in.filed = true
+
+	var publishedAt *string
+	if !email.Date.IsZero() {
+		published := email.Date.UTC().Format(time.RFC3339)
+		publishedAt = &published
+	}
+
+	res, err := in.db.ExecContext(ctx, `
+		INSERT INTO articles (url, title, title_norm, folder_id, instapapered_at, published_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, item.URL, item.Title, util.NormalizeText(item.Title), folderID, time.Now().UTC().Format(time.RFC3339), publishedAt)
+	if err != nil {
+		return item, false, fmt.Errorf("failed to insert article: %w", err)
+	}
+	if item.ID, err = res.LastInsertId(); err != nil {
+		return item, false, fmt.Errorf("failed to get article ID: %w", err)
+	}
+
+	if err := in.db.AddArticleTags(item.ID, util.DedupeStrings(in.opts.Tags)); err != nil {
+		return item, false, err
+	}
+
+	baseURL, _ := url.Parse(item.URL)
+	if err := in.fetcher.StoreDocument(item.ID, item.Title, email.HTML, baseURL, fetcher.FetchOptions{NoPrettify: in.opts.NoPrettify}); err != nil {
+		return item, false, err
+	}
+
+	if err := in.recordMessage(ctx, email, mailbox, item.ID); err != nil {
+		return item, false, err
+	}
+	return item, true, nil
+}
+
+// recordMessage remembers that a message was ingested as an article
+func (in *Ingester) recordMessage(ctx context.Context, email *Email, mailbox string, articleID int64) error {
+	if _, err := in.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO ingested_emails (message_id, article_id, mailbox, sender)
+		VALUES (?, ?, ?, ?)
+	`, email.MessageID, articleID, mailbox, email.Sender); err != nil {
+		return fmt.Errorf("failed to record ingested email: %w", err)
+	}
+	return nil
+}
+
+// Finish refreshes the folder paths of the folders articles were filed in
+func (in *Ingester) Finish() error {
+	if !in.filed {
+		return nil
+	}
+	if err := in.db.UpdateFolderPaths(); err != nil {
+		return fmt.Errorf("failed to update folder paths: %w", err)
+	}
+	return nil
+}
+
+// IngestFiles makes articles from messages saved as .eml files
+func IngestFiles(ctx context.Context, database *db.DB, paths []string, opts EmailOptions) (*EmailResult, error) {
+	in := NewIngester(database, opts)
+	result := &EmailResult{Ingested: []IngestedEmail{}}
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		result.Messages++
+
+		item, added, err := ingestFile(ctx, in, path)
+		if err != nil {
+			result.Errors = append(result.Errors, model.ItemError{URL: path, Error: err.Error()})
+			continue
+		}
+		if !added {
+			result.Duplicates++
+			continue
+		}
+		result.Ingested = append(result.Ingested, item)
+	}
+	return result, in.Finish()
+}
+
+// ingestFile parses and ingests one .eml file
+func ingestFile(ctx context.Context, in *Ingester, path string) (IngestedEmail, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return IngestedEmail{}, false, fmt.Errorf("failed to open email: %w", err)
+	}
+	defer f.Close()
+
+	email, err := ParseEmail(f)
+	if err != nil {
+		return IngestedEmail{}, false, err
+	}
+	return in.Ingest(ctx, email, "file:"+path)
+}
@@ -0,0 +1,154 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"instapaper-cli/internal/db"
+	"instapaper-cli/internal/model"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// IMAPOptions selects the mailbox newsletters are read from
+type IMAPOptions struct {
+	Server   string // host:port of the server, reached over TLS
+	Username string
+	Password string
+	Mailbox  string // Defaults to INBOX
+	// MoveTo moves processed messages to this mailbox; empty leaves them in place, marked read
+	MoveTo string
+	// All also reads messages already marked read, not only unread ones
+	All   bool
+	Limit int // Most messages to read, oldest first; 0 for all
+}
+
+// IngestIMAP makes articles from the newsletters in an IMAP mailbox. Messages that became
+// articles, or were ingested before, are marked read and moved when opts.MoveTo is set; messages
+// that failed are left as they were, to be retried by the next run. A dry run opens the mailbox
+// read-only.
+func IngestIMAP(ctx context.Context, database *db.DB, server IMAPOptions, opts EmailOptions) (*EmailResult, error) {
+	if server.Mailbox == "" {
+		server.Mailbox = "INBOX"
+	}
+
+	c, err := client.DialTLS(server.Server, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", server.Server, err)
+	}
+	defer c.Logout()
+
+	if err := c.Login(server.Username, server.Password); err != nil {
+		return nil, fmt.Errorf("failed to log in to %s: %w", server.Server, err)
+	}
+	if _, err := c.Select(server.Mailbox, opts.DryRun); err != nil {
+		return nil, fmt.Errorf("failed to open mailbox %s: %w", server.Mailbox, err)
+	}
+
+	criteria := imap.NewSearchCriteria()
+	if !server.All {
+		criteria.WithoutFlags = []string{imap.SeenFlag}
+	}
+	uids, err := c.UidSearch(criteria)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search mailbox %s: %w", server.Mailbox, err)
+	}
+	if server.Limit > 0 && len(uids) > server.Limit {
+		uids = uids[:server.Limit]
+	}
+
+	result := &EmailResult{Ingested: []IngestedEmail{}}
+	if len(uids) == 0 {
+		return result, nil
+	}
+
+	bodies, err := fetchMessages(c, uids)
+	if err != nil {
+		return nil, err
+	}
+
+	in := NewIngester(database, opts)
+	processed := new(imap.SeqSet)
+	for _, uid := range uids {
+		if err := ctx.Err(); err != nil {
+			break
+		}
+		body, ok := bodies[uid]
+		if !ok {
+			continue
+		}
+		result.Messages++
+
+		email, err := ParseEmail(bytes.NewReader(body))
+		if err != nil {
+			result.Errors = append(result.Errors, model.ItemError{ID: int64(uid), Error: err.Error()})
+			continue
+		}
+		item, added, err := in.Ingest(ctx, email, server.Mailbox)
+		if err != nil {
+			result.Errors = append(result.Errors, model.ItemError{ID: int64(uid), URL: item.URL, Error: err.Error()})
+			continue
+		}
+		if added {
+			result.Ingested = append(result.Ingested, item)
+		} else {
+			result.Duplicates++
+		}
+		processed.AddNum(uid)
+	}
+
+	if err := in.Finish(); err != nil {
+		return result, err
+	}
+	if opts.DryRun || processed.Empty() {
+		return result, ctx.Err()
+	}
+
+	flags := []interface{}{imap.SeenFlag}
+	if err := c.UidStore(processed, imap.FormatFlagsOp(imap.AddFlags, true), flags, nil); err != nil {
+		return result, fmt.Errorf("failed to mark messages read: %w", err)
+	}
+	if server.MoveTo != "" {
+		if err := c.UidMove(processed, server.MoveTo); err != nil {
+			return result, fmt.Errorf("failed to move messages to %s: %w", server.MoveTo, err)
+		}
+	}
+	return result, ctx.Err()
+}
+
+// fetchMessages downloads the full messages with the given UIDs without marking them read
+func fetchMessages(c *client.Client, uids []uint32) (map[uint32][]byte, error) {
+	seqset := new(imap.SeqSet)
+	seqset.AddNum(uids...)
+	section := &imap.BodySectionName{Peek: true}
+
+	messages := make(chan *imap.Message, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.UidFetch(seqset, []imap.FetchItem{imap.FetchUid, section.FetchItem()}, messages)
+	}()
+
+	bodies := make(map[uint32][]byte)
+	var readErr error
+	for msg := range messages {
+		literal := msg.GetBody(section)
+		if literal == nil {
+			continue
+		}
+		body, err := io.ReadAll(literal)
+		if err != nil && readErr == nil {
+			readErr = fmt.Errorf("failed to read message %d: %w", msg.Uid, err)
+		}
+		bodies[msg.Uid] = body
+	}
+	if err := <-done; err != nil {
+		return nil, fmt.Errorf("failed to fetch messages: %w", err)
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	return bodies, nil
+}
@@ -0,0 +1,235 @@
+// Package language detects the language of article text and segments scripts written without
+// spaces, so the full-text index can find words in them
+package language
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Auto asks for the language of the search query instead of a fixed one
+const Auto = "auto"
+
+// minStopwords is the fewest stopword hits before text in a Latin script is assigned a language
+const minStopwords = 3
+
+// maxDetectRunes bounds how much text Detect reads
+const maxDetectRunes = 20000
+
+// stopwords are frequent short words that tell languages in the Latin script apart
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "for", "with", "was", "this", "are", "you", "not", "be", "have", "from"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "den", "mit", "sich", "auf", "für", "auch", "dem", "von", "wir"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "un", "du", "que", "pas", "pour", "dans", "qui", "sur", "avec", "nous", "ce"},
+	"es": {"el", "los", "las", "que", "y", "es", "una", "por", "con", "para", "del", "como", "pero", "más", "su", "al", "se", "lo"},
+	"it": {"il", "che", "di", "è", "non", "una", "per", "gli", "della", "sono", "con", "del", "anche", "questo", "come", "ma", "lo", "nel"},
+	"pt": {"o", "os", "que", "não", "uma", "é", "com", "para", "do", "da", "em", "mais", "por", "como", "dos", "mas", "ao", "também"},
+	"nl": {"de", "het", "een", "en", "van", "is", "niet", "dat", "op", "te", "zijn", "voor", "met", "ook", "maar", "wij", "er", "dit"},
+	"sv": {"och", "att", "det", "som", "är", "en", "på", "för", "med", "inte", "av", "till", "den", "har", "jag", "om", "ett", "vi"},
+	"da": {"og", "at", "det", "er", "en", "til", "på", "som", "med", "af", "ikke", "for", "den", "har", "jeg", "de", "et", "vi"},
+	"nb": {"og", "å", "det", "er", "en", "til", "på", "som", "med", "av", "ikke", "for", "den", "har", "jeg", "de", "et", "vi"},
+	"fi": {"ja", "on", "ei", "että", "se", "oli", "mutta", "kun", "niin", "myös", "tai", "ovat", "joka", "hän", "tämä", "kuin", "jos", "vain"},
+	"pl": {"i", "w", "nie", "na", "się", "z", "że", "do", "jest", "to", "jak", "ale", "o", "co", "po", "tak", "od", "dla"},
+}
+
+// stopwordLanguages indexes stopwords by word
+var stopwordLanguages = func() map[string][]string {
+	index := make(map[string][]string)
+	for lang, words := range stopwords {
+		for _, word := range words {
+			index[word] = append(index[word], lang)
+		}
+	}
+	return index
+}()
+
+// Normalize turns a declared language such as "en-US" or "pt_BR" into its two- or three-letter
+// code, or "" when it is not a language code
+func Normalize(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	if len(lang) < 2 || len(lang) > 3 {
+		return ""
+	}
+	for _, r := range lang {
+		if r < 'a' || r > 'z' {
+			return ""
+		}
+	}
+	// Norwegian is declared as no, nb, or nn; the index does not tell the written forms apart
+	if lang == "no" || lang == "nn" {
+		return "nb"
+	}
+	return lang
+}
+
+// Validate checks that every language is a language code or Auto
+func Validate(langs []string) error {
+	for _, lang := range langs {
+		if lang != Auto && Normalize(lang) != lang {
+			return fmt.Errorf("invalid language %q: use a code like en, de, or ja, or %s", lang, Auto)
+		}
+	}
+	return nil
+}
+
+// Resolve replaces Auto with the detected language of the query. An undetectable query drops the
+// language restriction rather than matching nothing.
+func Resolve(langs []string, query string) ([]string, error) {
+	var resolved []string
+	for _, lang := range langs {
+		if lang != Auto {
+			resolved = append(resolved, lang)
+			continue
+		}
+		if strings.TrimSpace(query) == "" {
+			return nil, fmt.Errorf("--lang %s needs a search query to detect the language of", Auto)
+		}
+		switch detected := Detect(query); detected {
+		case "zh":
+			// Japanese text uses the same Han characters, so a query without kana may be either
+			resolved = append(resolved, "zh", "ja")
+		case "":
+			if len(langs) == 1 {
+				return nil, nil
+			}
+		default:
+			resolved = append(resolved, detected)
+		}
+	}
+	return resolved, nil
+}
+
+// Detect guesses the language of text: from its script for scripts used by few languages, and
+// from common short words for the Latin script. It returns "" when unsure.
+func Detect(text string) string {
+	scripts := make(map[string]int)
+	letters := 0
+	n := 0
+	for _, r := range text {
+		if n++; n > maxDetectRunes {
+			break
+		}
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			scripts["ja"]++
+		case unicode.Is(unicode.Han, r):
+			scripts["han"]++
+		case unicode.Is(unicode.Hangul, r):
+			scripts["ko"]++
+		case unicode.Is(unicode.Thai, r):
+			scripts["th"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			scripts["ru"]++
+		case unicode.Is(unicode.Greek, r):
+			scripts["el"]++
+		case unicode.Is(unicode.Arabic, r):
+			scripts["ar"]++
+		case unicode.Is(unicode.Hebrew, r):
+			scripts["he"]++
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+
+	// Japanese mixes kana with Han characters; Han alone is Chinese
+	if scripts["ja"] > 0 && scripts["ja"]+scripts["han"] > letters/3 {
+		return "ja"
+	}
+	if scripts["han"] > letters/3 {
+		return "zh"
+	}
+	for _, lang := range []string{"ko", "th", "ru", "el", "ar", "he"} {
+		if scripts[lang] > letters/3 {
+			return lang
+		}
+	}
+
+	return detectLatin(text)
+}
+
+// detectLatin picks the language whose stopwords occur most often, when it clearly leads
+func detectLatin(text string) string {
+	if len(text) > maxDetectRunes*4 {
+		text = text[:maxDetectRunes*4]
+	}
+
+	hits := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		for _, lang := range stopwordLanguages[word] {
+			hits[lang]++
+		}
+	}
+
+	best, bestHits, secondHits := "", 0, 0
+	for lang, count := range hits {
+		switch {
+		case count > bestHits || (count == bestHits && lang < best):
+			best, bestHits, secondHits = lang, count, max(secondHits, bestHits)
+		case count > secondHits:
+			secondHits = count
+		}
+	}
+	// Short text needs several hits, and closely related languages a clear lead
+	if bestHits < minStopwords || float64(bestHits) < float64(secondHits)*1.2 {
+		return ""
+	}
+	return best
+}
+
+// segmented reports whether r belongs to a script written without spaces between words
+func segmented(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul, unicode.Thai) || r == 'ー'
+}
+
+// HasSegmented reports whether text contains characters of a script written without spaces
+func HasSegmented(text string) bool {
+	return strings.IndexFunc(text, segmented) >= 0
+}
+
+// Segment splits runs of Chinese, Japanese, Korean, and Thai characters into overlapping pairs
+// ("東京都" becomes "東京 京都"), so the full-text index holds words of those scripts as terms a
+// query can match, instead of whole sentences. Other text is left as is.
+func Segment(text string) string {
+	if !HasSegmented(text) {
+		return text
+	}
+
+	var b strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !segmented(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+
+		end := i
+		for end < len(runes) && segmented(runes[end]) {
+			end++
+		}
+		run := runes[i:end]
+
+		b.WriteByte(' ')
+		if len(run) == 1 {
+			b.WriteRune(run[0])
+		}
+		for j := 0; j+1 < len(run); j++ {
+			if j > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(string(run[j : j+2]))
+		}
+		b.WriteByte(' ')
+		i = end
+	}
+	return b.String()
+}
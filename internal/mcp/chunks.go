@@ -0,0 +1,143 @@
+package mcp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultPartWords is the size of an article part when get_article is asked for a summary
+	// without max_words
+	defaultPartWords = 2000
+	// minPartWords keeps parts large enough to read as text
+	minPartWords = 200
+	// leadWords bounds the lead paragraph and the part openings of a summary
+	leadWords = 60
+)
+
+// articlePart is a run of whole paragraphs of an article
+type articlePart struct {
+	Content  string
+	Words    int
+	Headings []string
+}
+
+// splitArticle splits markdown into parts of about maxWords words, breaking between paragraphs
+// and never inside a fenced code block. A paragraph longer than maxWords gets a part of its own.
+func splitArticle(markdown string, maxWords int) []articlePart {
+	var parts []articlePart
+	var current []string
+	words := 0
+	inFence := false
+
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		content := strings.Join(current, "\n\n")
+		parts = append(parts, articlePart{Content: content, Words: words, Headings: markdownHeadings(content)})
+		current, words = nil, 0
+	}
+
+	for _, paragraph := range strings.Split(markdown, "\n\n") {
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+		n := len(strings.Fields(paragraph))
+		if !inFence && words > 0 && words+n > maxWords {
+			// A heading goes with the text it introduces
+			var carried []string
+			for len(current) > 1 && strings.HasPrefix(current[len(current)-1], "#") {
+				last := current[len(current)-1]
+				carried = append([]string{last}, carried...)
+				current = current[:len(current)-1]
+				words -= len(strings.Fields(last))
+			}
+			flush()
+			current = carried
+			for _, heading := range carried {
+				words += len(strings.Fields(heading))
+			}
+		}
+		current = append(current, paragraph)
+		words += n
+		if strings.Count(paragraph, "```")%2 == 1 {
+			inFence = !inFence
+		}
+	}
+	flush()
+
+	if len(parts) == 0 {
+		parts = append(parts, articlePart{})
+	}
+	return parts
+}
+
+// markdownHeadings lists the headings of markdown, without their # markers
+func markdownHeadings(markdown string) []string {
+	var headings []string
+	for _, line := range strings.Split(markdown, "\n") {
+		if !strings.HasPrefix(line, "#") {
+			continue
+		}
+		if heading := strings.TrimSpace(strings.TrimLeft(line, "#")); heading != "" {
+			headings = append(headings, heading)
+		}
+	}
+	return headings
+}
+
+// partOpening returns the start of a part's first paragraph of text, for a summary
+func partOpening(content string) string {
+	for _, paragraph := range strings.Split(content, "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" || strings.HasPrefix(paragraph, "#") || strings.HasPrefix(paragraph, "```") || strings.HasPrefix(paragraph, "![") {
+			continue
+		}
+		fields := strings.Fields(paragraph)
+		if len(fields) > leadWords {
+			return strings.Join(fields[:leadWords], " ") + " …"
+		}
+		return strings.Join(fields, " ")
+	}
+	return ""
+}
+
+// contentHash fingerprints article content, so a continuation token stops working once the
+// article is refetched and its parts move
+func contentHash(content string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(content))
+	return h.Sum32()
+}
+
+// continuationToken encodes where to resume reading an article
+func continuationToken(id int64, part, maxWords int, content string) string {
+	raw := fmt.Sprintf("%d:%d:%d:%x", id, part, maxWords, contentHash(content))
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// parseContinuationToken decodes a continuation token into the article ID, the 1-based part,
+// the part size, and the content hash it was issued for
+func parseContinuationToken(token string) (id int64, part, maxWords int, hash uint32, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(token))
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("invalid continuation token")
+	}
+	fields := strings.Split(string(raw), ":")
+	if len(fields) != 4 {
+		return 0, 0, 0, 0, fmt.Errorf("invalid continuation token")
+	}
+
+	id, err1 := strconv.ParseInt(fields[0], 10, 64)
+	part, err2 := strconv.Atoi(fields[1])
+	maxWords, err3 := strconv.Atoi(fields[2])
+	h, err4 := strconv.ParseUint(fields[3], 16, 32)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil || part < 1 || maxWords < minPartWords {
+		return 0, 0, 0, 0, fmt.Errorf("invalid continuation token")
+	}
+	return id, part, maxWords, uint32(h), nil
+}
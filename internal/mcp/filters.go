@@ -1,28 +1,31 @@
 package mcp
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
+	"instapaper-cli/internal/db"
 	"instapaper-cli/internal/model"
 	"instapaper-cli/internal/search"
+	"instapaper-cli/internal/similarity"
 	"instapaper-cli/internal/export"
 	"gopkg.in/yaml.v3"
 )
 
 // searchWithFilters performs a search with additional filtering beyond the basic search
-func (s *Server) searchWithFilters(opts search.SearchOptions, req SearchRequest) ([]model.SearchResult, error) {
+func (s *Server) searchWithFilters(ctx context.Context, opts search.SearchOptions, req SearchRequest) ([]model.SearchResult, error) {
 	// Start with basic search
-	results, err := s.performBasicSearch(opts)
+	results, err := s.performBasicSearch(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	// Apply additional filters
 	if len(req.Tags) > 0 || len(req.Folders) > 0 || req.DateAfter != "" || req.DateBefore != "" {
-		results, err = s.applyAdditionalFilters(results, req)
+		results, err = s.applyAdditionalFilters(ctx, results, req)
 		if err != nil {
 			return nil, err
 		}
@@ -32,15 +35,15 @@ func (s *Server) searchWithFilters(opts search.SearchOptions, req SearchRequest)
 }
 
 // performBasicSearch performs the basic search using the existing search functionality
-func (s *Server) performBasicSearch(opts search.SearchOptions) ([]model.SearchResult, error) {
+func (s *Server) performBasicSearch(ctx context.Context, opts search.SearchOptions) ([]model.SearchResult, error) {
 	if opts.UseFTS {
-		return s.searchFTS(opts)
+		return s.searchFTS(ctx, opts)
 	}
-	return s.searchLike(opts)
+	return s.searchLike(ctx, opts)
 }
 
 // searchFTS performs FTS search
-func (s *Server) searchFTS(opts search.SearchOptions) ([]model.SearchResult, error) {
+func (s *Server) searchFTS(ctx context.Context, opts search.SearchOptions) ([]model.SearchResult, error) {
 	if opts.Query == "" {
 		return nil, fmt.Errorf("FTS search requires a query")
 	}
@@ -55,6 +58,7 @@ func (s *Server) searchFTS(opts search.SearchOptions) ([]model.SearchResult, err
 			a.synced_at,
 			a.failed_count,
 			a.status_code,
+			a.status,
 			a.instapapered_at
 		FROM articles a
 		LEFT JOIN folders f ON a.folder_id = f.id
@@ -67,39 +71,18 @@ func (s *Server) searchFTS(opts search.SearchOptions) ([]model.SearchResult, err
 	var whereClause string
 	var args []interface{}
 
-	if opts.Field != "" {
-		switch opts.Field {
-		case "url":
-			whereClause = "AND articles_fts MATCH ?"
-			args = append(args, "url: "+opts.Query)
-		case "title":
-			whereClause = "AND articles_fts MATCH ?"
-			args = append(args, "title: "+opts.Query)
-		case "content":
-			whereClause = "AND articles_fts MATCH ?"
-			args = append(args, "content: "+opts.Query)
-		case "tags":
-			whereClause = "AND articles_fts MATCH ?"
-			args = append(args, "tags: "+opts.Query)
-		case "folder":
-			whereClause = "AND articles_fts MATCH ?"
-			args = append(args, "folder: "+opts.Query)
-		default:
-			return nil, fmt.Errorf("invalid field for FTS: %s", opts.Field)
-		}
-	} else {
-		// For multiple keywords, create an AND query for intersection search
-		keywords := strings.Fields(strings.TrimSpace(opts.Query))
-		if len(keywords) > 1 {
-			// Build FTS query with AND operators for intersection
-			ftsQuery := strings.Join(keywords, " AND ")
-			whereClause = "AND articles_fts MATCH ?"
-			args = append(args, ftsQuery)
-		} else {
-			whereClause = "AND articles_fts MATCH ?"
-			args = append(args, opts.Query)
-		}
+	ftsQuery, err := search.FTSQuery(opts.Query, opts.Field)
+	if err != nil {
+		return nil, err
+	}
+	whereClause = "AND articles_fts MATCH ?"
+	args = append(args, ftsQuery)
+
+	statusConditions, statusArgs := search.Selector{Statuses: opts.Statuses, Languages: opts.Languages}.Conditions()
+	for _, condition := range statusConditions {
+		whereClause += " AND " + condition
 	}
+	args = append(args, statusArgs...)
 
 	query := baseQuery + " " + whereClause + `
 		GROUP BY a.id
@@ -112,7 +95,7 @@ func (s *Server) searchFTS(opts search.SearchOptions) ([]model.SearchResult, err
 	}
 
 	var results []model.SearchResult
-	if err := s.db.Select(&results, query, args...); err != nil {
+	if err := s.db.SelectContext(ctx, &results, query, args...); err != nil {
 		return nil, err
 	}
 
@@ -120,7 +103,7 @@ func (s *Server) searchFTS(opts search.SearchOptions) ([]model.SearchResult, err
 }
 
 // searchLike performs LIKE search
-func (s *Server) searchLike(opts search.SearchOptions) ([]model.SearchResult, error) {
+func (s *Server) searchLike(ctx context.Context, opts search.SearchOptions) ([]model.SearchResult, error) {
 	baseQuery := `
 		SELECT
 			a.id,
@@ -131,6 +114,7 @@ func (s *Server) searchLike(opts search.SearchOptions) ([]model.SearchResult, er
 			a.synced_at,
 			a.failed_count,
 			a.status_code,
+			a.status,
 			a.instapapered_at
 		FROM articles a
 		LEFT JOIN folders f ON a.folder_id = f.id
@@ -147,9 +131,11 @@ func (s *Server) searchLike(opts search.SearchOptions) ([]model.SearchResult, er
 		case "url":
 			whereClause = "AND a.url LIKE ? COLLATE NOCASE"
 		case "title":
-			whereClause = "AND a.title LIKE ? COLLATE NOCASE"
+			whereClause = "AND a.title_norm LIKE normalize_text(?)"
 		case "content":
-			whereClause = "AND a.content_md LIKE ? COLLATE NOCASE"
+			whereClause = "AND content_text(a.content_md) LIKE ? COLLATE NOCASE"
+		case "selection":
+			whereClause = "AND a.selection LIKE ? COLLATE NOCASE"
 		case "tags":
 			whereClause = "AND t.title LIKE ? COLLATE NOCASE"
 		case "folder":
@@ -161,12 +147,18 @@ func (s *Server) searchLike(opts search.SearchOptions) ([]model.SearchResult, er
 		args = append(args, "%"+opts.Query+"%")
 	} else if opts.Query != "" {
 		whereClause = `
-			AND (a.url LIKE ? COLLATE NOCASE OR a.title LIKE ? COLLATE NOCASE OR a.content_md LIKE ? COLLATE NOCASE
-			       OR t.title LIKE ? COLLATE NOCASE OR f.path_cache LIKE ? COLLATE NOCASE)
+			AND (a.url LIKE ? COLLATE NOCASE OR a.title_norm LIKE normalize_text(?) OR content_text(a.content_md) LIKE ? COLLATE NOCASE
+			       OR a.selection LIKE ? COLLATE NOCASE OR t.title LIKE ? COLLATE NOCASE OR f.path_cache LIKE ? COLLATE NOCASE)
 		`
 		pattern := "%" + opts.Query + "%"
-		args = append(args, pattern, pattern, pattern, pattern, pattern)
+		args = append(args, pattern, pattern, pattern, pattern, pattern, pattern)
+	}
+
+	statusConditions, statusArgs := search.Selector{Statuses: opts.Statuses, Languages: opts.Languages}.Conditions()
+	for _, condition := range statusConditions {
+		whereClause += " AND " + condition
 	}
+	args = append(args, statusArgs...)
 
 	query := baseQuery + " " + whereClause + `
 		GROUP BY a.id
@@ -179,7 +171,7 @@ func (s *Server) searchLike(opts search.SearchOptions) ([]model.SearchResult, er
 	}
 
 	var results []model.SearchResult
-	if err := s.db.Select(&results, query, args...); err != nil {
+	if err := s.db.SelectContext(ctx, &results, query, args...); err != nil {
 		return nil, err
 	}
 
@@ -187,11 +179,19 @@ func (s *Server) searchLike(opts search.SearchOptions) ([]model.SearchResult, er
 }
 
 // applyAdditionalFilters applies date, tag, and folder filters to search results
-func (s *Server) applyAdditionalFilters(results []model.SearchResult, req SearchRequest) ([]model.SearchResult, error) {
+func (s *Server) applyAdditionalFilters(ctx context.Context, results []model.SearchResult, req SearchRequest) ([]model.SearchResult, error) {
 	if len(results) == 0 {
 		return results, nil
 	}
 
+	var err error
+	if req.Tags, err = s.db.ResolveAliases(db.AliasTag, req.Tags); err != nil {
+		return nil, err
+	}
+	if req.Folders, err = s.db.ResolveAliases(db.AliasFolder, req.Folders); err != nil {
+		return nil, err
+	}
+
 	// Extract article IDs for filtering
 	articleIDs := make([]string, len(results))
 	for i, result := range results {
@@ -255,7 +255,7 @@ func (s *Server) applyAdditionalFilters(results []model.SearchResult, req Search
 	}
 
 	// Execute filter query
-	rows, err := s.db.Query(query, args...)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to apply filters: %w", err)
 	}
@@ -283,17 +283,18 @@ func (s *Server) applyAdditionalFilters(results []model.SearchResult, req Search
 }
 
 // performAdvancedSearch performs complex search with multiple conditions
-func (s *Server) performAdvancedSearch(req AdvancedSearchRequest) ([]model.SearchResult, error) {
+func (s *Server) performAdvancedSearch(ctx context.Context, req AdvancedSearchRequest) ([]model.SearchResult, error) {
 	baseQuery := `
 		SELECT DISTINCT
 			a.id,
 			a.url,
 			a.title,
 			f.path_cache as folder_path,
-			GROUP_CONCAT(DISTINCT t.title, ', ') as tags,
+			GROUP_CONCAT(t.title, ', ') as tags,
 			a.synced_at,
 			a.failed_count,
 			a.status_code,
+			a.status,
 			a.instapapered_at
 		FROM articles a
 		LEFT JOIN folders f ON a.folder_id = f.id
@@ -302,9 +303,11 @@ func (s *Server) performAdvancedSearch(req AdvancedSearchRequest) ([]model.Searc
 	`
 
 	var joins []string
-	var conditions []string
 	var args []interface{}
 
+	// Always exclude obsolete articles
+	conditions := []string{"a.obsolete = FALSE"}
+
 	// Add FTS join if needed
 	if req.UseFTS && req.Query != "" {
 		joins = append(joins, "INNER JOIN articles_fts fts ON a.id = fts.rowid")
@@ -313,31 +316,26 @@ func (s *Server) performAdvancedSearch(req AdvancedSearchRequest) ([]model.Searc
 	// Build conditions
 	if req.Query != "" {
 		if req.UseFTS {
-			// For multiple keywords, create an AND query for intersection search
-			keywords := strings.Fields(strings.TrimSpace(req.Query))
-			if len(keywords) > 1 {
-				// Build FTS query with AND operators for intersection
-				ftsQuery := strings.Join(keywords, " AND ")
-				conditions = append(conditions, "articles_fts MATCH ?")
-				args = append(args, ftsQuery)
-			} else {
-				conditions = append(conditions, "articles_fts MATCH ?")
-				args = append(args, req.Query)
+			ftsQuery, err := search.FTSQuery(req.Query, "")
+			if err != nil {
+				return nil, err
 			}
+			conditions = append(conditions, "articles_fts MATCH ?")
+			args = append(args, ftsQuery)
 		} else {
-			conditions = append(conditions, "(a.url LIKE ? COLLATE NOCASE OR a.title LIKE ? COLLATE NOCASE OR a.content_md LIKE ? COLLATE NOCASE OR t.title LIKE ? COLLATE NOCASE OR f.path_cache LIKE ? COLLATE NOCASE)")
+			conditions = append(conditions, "(a.url LIKE ? COLLATE NOCASE OR a.title_norm LIKE normalize_text(?) OR content_text(a.content_md) LIKE ? COLLATE NOCASE OR a.selection LIKE ? COLLATE NOCASE OR t.title LIKE ? COLLATE NOCASE OR f.path_cache LIKE ? COLLATE NOCASE)")
 			pattern := "%" + req.Query + "%"
-			args = append(args, pattern, pattern, pattern, pattern, pattern)
+			args = append(args, pattern, pattern, pattern, pattern, pattern, pattern)
 		}
 	}
 
 	if req.TitleContains != "" {
-		conditions = append(conditions, "a.title LIKE ? COLLATE NOCASE")
+		conditions = append(conditions, "a.title_norm LIKE normalize_text(?)")
 		args = append(args, "%"+req.TitleContains+"%")
 	}
 
 	if req.ContentContains != "" {
-		conditions = append(conditions, "a.content_md LIKE ? COLLATE NOCASE")
+		conditions = append(conditions, "content_text(a.content_md) LIKE ? COLLATE NOCASE")
 		args = append(args, "%"+req.ContentContains+"%")
 	}
 
@@ -360,6 +358,10 @@ func (s *Server) performAdvancedSearch(req AdvancedSearchRequest) ([]model.Searc
 		conditions = append(conditions, "a.content_md IS NOT NULL")
 	}
 
+	statusConditions, statusArgs := search.Selector{Statuses: req.Statuses}.Conditions()
+	conditions = append(conditions, statusConditions...)
+	args = append(args, statusArgs...)
+
 	// Handle tag filters
 	if len(req.Tags) > 0 {
 		// Must have ALL these tags
@@ -446,7 +448,7 @@ func (s *Server) performAdvancedSearch(req AdvancedSearchRequest) ([]model.Searc
 
 	// Execute query
 	var results []model.SearchResult
-	if err := s.db.Select(&results, query, args...); err != nil {
+	if err := s.db.SelectContext(ctx, &results, query, args...); err != nil {
 		return nil, fmt.Errorf("advanced search failed: %w", err)
 	}
 
@@ -454,7 +456,7 @@ func (s *Server) performAdvancedSearch(req AdvancedSearchRequest) ([]model.Searc
 }
 
 // findRelatedArticles finds articles related to the given article based on relationship type
-func (s *Server) findRelatedArticles(article model.ArticleWithDetails, relationshipType string, maxRelated int) ([]model.ArticleWithDetails, error) {
+func (s *Server) findRelatedArticles(ctx context.Context, article model.ArticleWithDetails, relationshipType string, maxRelated int) ([]model.ArticleWithDetails, error) {
 	var query string
 	var args []interface{}
 
@@ -467,11 +469,11 @@ func (s *Server) findRelatedArticles(article model.ArticleWithDetails, relations
 			SELECT DISTINCT
 				a.id, a.url, a.title, a.selection, a.folder_id, a.instapapered_at,
 				a.synced_at, a.sync_failed_at, a.failed_count, a.status_code,
-				a.status_text, a.final_url, a.content_md, a.raw_html,
+				a.status_text, a.final_url, content_text(a.content_md) AS content_md, a.raw_html,
 				f.path_cache as folder_path
 			FROM articles a
 			LEFT JOIN folders f ON a.folder_id = f.id
-			WHERE a.folder_id = ? AND a.id != ?
+			WHERE a.folder_id = ? AND a.id != ? AND a.obsolete = FALSE
 			ORDER BY a.instapapered_at DESC
 			LIMIT ?
 		`
@@ -482,7 +484,7 @@ func (s *Server) findRelatedArticles(article model.ArticleWithDetails, relations
 			SELECT DISTINCT
 				a.id, a.url, a.title, a.selection, a.folder_id, a.instapapered_at,
 				a.synced_at, a.sync_failed_at, a.failed_count, a.status_code,
-				a.status_text, a.final_url, a.content_md, a.raw_html,
+				a.status_text, a.final_url, content_text(a.content_md) AS content_md, a.raw_html,
 				f.path_cache as folder_path
 			FROM articles a
 			LEFT JOIN folders f ON a.folder_id = f.id
@@ -494,57 +496,45 @@ func (s *Server) findRelatedArticles(article model.ArticleWithDetails, relations
 				JOIN tags t2 ON at2.tag_id = t2.id
 				WHERE at2.article_id = ?
 			)
-			AND a.id != ?
+			AND a.id != ? AND a.obsolete = FALSE
 			ORDER BY a.instapapered_at DESC
 			LIMIT ?
 		`
 		args = []interface{}{article.ID, article.ID, maxRelated}
 
 	case "content_similarity":
-		// Simple content similarity based on common words (basic implementation)
 		if article.ContentMD == nil || *article.ContentMD == "" {
 			return []model.ArticleWithDetails{}, nil
 		}
 
-		// Extract key words from the content (very basic implementation)
-		words := s.extractKeyWords(*article.ContentMD)
-		if len(words) == 0 {
-			return []model.ArticleWithDetails{}, nil
+		matches, err := similarity.New(s.db).Similar(ctx, article.ID, maxRelated, similarity.DefaultMaxDistance)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find similar articles: %w", err)
 		}
 
-		// Build LIKE conditions for content similarity
-		conditions := make([]string, len(words))
-		for i, word := range words {
-			conditions[i] = "a.content_md LIKE ? COLLATE NOCASE"
-			args = append(args, "%"+word+"%")
+		// Keep the closest-first order of the matches
+		results := []model.ArticleWithDetails{}
+		for _, match := range matches {
+			related, err := s.getArticleWithDetails(ctx, match.ID)
+			if err != nil {
+				continue
+			}
+			results = append(results, *related)
 		}
-
-		query = fmt.Sprintf(`
-			SELECT DISTINCT
-				a.id, a.url, a.title, a.selection, a.folder_id, a.instapapered_at,
-				a.synced_at, a.sync_failed_at, a.failed_count, a.status_code,
-				a.status_text, a.final_url, a.content_md, a.raw_html,
-				f.path_cache as folder_path
-			FROM articles a
-			LEFT JOIN folders f ON a.folder_id = f.id
-			WHERE (%s) AND a.id != ? AND a.content_md IS NOT NULL
-			ORDER BY a.instapapered_at DESC
-			LIMIT ?
-		`, strings.Join(conditions, " OR "))
-		args = append(args, article.ID, maxRelated)
+		return results, nil
 
 	default:
 		return []model.ArticleWithDetails{}, fmt.Errorf("unknown relationship type: %s", relationshipType)
 	}
 
 	var results []model.ArticleWithDetails
-	if err := s.db.Select(&results, query, args...); err != nil {
+	if err := s.db.SelectContext(ctx, &results, query, args...); err != nil {
 		return nil, fmt.Errorf("failed to find related articles: %w", err)
 	}
 
 	// Get tags for each article
 	for i := range results {
-		tags, err := s.getArticleTags(results[i].ID)
+		tags, err := s.getArticleTags(ctx, results[i].ID)
 		if err != nil {
 			continue
 		}
@@ -554,48 +544,8 @@ func (s *Server) findRelatedArticles(article model.ArticleWithDetails, relations
 	return results, nil
 }
 
-// extractKeyWords extracts key words from content for similarity matching
-func (s *Server) extractKeyWords(content string) []string {
-	// Very basic implementation - extract words longer than 4 characters
-	words := strings.Fields(strings.ToLower(content))
-	var keyWords []string
-	seen := make(map[string]bool)
-
-	for _, word := range words {
-		// Remove common punctuation
-		word = strings.Trim(word, ".,!?;:()[]{}\"'")
-
-		// Skip short words, common words, and duplicates
-		if len(word) > 4 && !s.isCommonWord(word) && !seen[word] {
-			keyWords = append(keyWords, word)
-			seen[word] = true
-
-			// Limit to avoid too many conditions
-			if len(keyWords) >= 10 {
-				break
-			}
-		}
-	}
-
-	return keyWords
-}
-
-// isCommonWord checks if a word is too common to be useful for similarity
-func (s *Server) isCommonWord(word string) bool {
-	commonWords := map[string]bool{
-		"that": true, "this": true, "with": true, "from": true, "they": true,
-		"have": true, "been": true, "their": true, "said": true, "each": true,
-		"which": true, "there": true, "what": true, "would": true, "about": true,
-		"could": true, "other": true, "after": true, "first": true, "never": true,
-		"these": true, "think": true, "where": true, "being": true, "every": true,
-		"great": true, "might": true, "shall": true, "still": true, "those": true,
-		"while": true, "should": true, "through": true, "before": true, "around": true,
-	}
-	return commonWords[word]
-}
-
 // getArticleTags gets tags for an article
-func (s *Server) getArticleTags(articleID int64) ([]string, error) {
+func (s *Server) getArticleTags(ctx context.Context, articleID int64) ([]string, error) {
 	query := `
 		SELECT t.title
 		FROM tags t
@@ -605,20 +555,59 @@ func (s *Server) getArticleTags(articleID int64) ([]string, error) {
 	`
 
 	var tags []string
-	if err := s.db.Select(&tags, query, articleID); err != nil {
+	if err := s.db.SelectContext(ctx, &tags, query, articleID); err != nil {
 		return nil, err
 	}
 
 	return tags, nil
 }
 
-// buildAdvancedSearchDescription builds a human-readable description of the search
-func (s *Server) buildAdvancedSearchDescription(req AdvancedSearchRequest) string {
+// resolveAdvancedSearchAliases replaces tag and folder aliases in an advanced search with the
+// names they stand for, so the response shows the filters as applied
+func (s *Server) resolveAdvancedSearchAliases(req *AdvancedSearchRequest) error {
+	var err error
+	if req.Tags, err = s.db.ResolveAliases(db.AliasTag, req.Tags); err != nil {
+		return err
+	}
+	if req.AnyTags, err = s.db.ResolveAliases(db.AliasTag, req.AnyTags); err != nil {
+		return err
+	}
+	if req.Folders, err = s.db.ResolveAliases(db.AliasFolder, req.Folders); err != nil {
+		return err
+	}
+	return nil
+}
+
+// searchArticlesFilters describes the conditions search_articles applied besides the query
+func searchArticlesFilters(opts search.SearchOptions, onlySynced bool) []string {
 	var parts []string
 
-	if req.Query != "" {
-		parts = append(parts, fmt.Sprintf("general query: '%s'", req.Query))
+	if opts.Field != "" && opts.Query != "" {
+		parts = append(parts, fmt.Sprintf("field: %s", opts.Field))
+	}
+	if opts.Since != "" {
+		parts = append(parts, fmt.Sprintf("since: %s", opts.Since))
+	}
+	if opts.Until != "" {
+		parts = append(parts, fmt.Sprintf("until: %s", opts.Until))
+	}
+	if len(opts.Statuses) > 0 {
+		parts = append(parts, fmt.Sprintf("with status: [%s]", strings.Join(opts.Statuses, ", ")))
+	}
+	if len(opts.Languages) > 0 {
+		parts = append(parts, fmt.Sprintf("in languages: [%s]", strings.Join(opts.Languages, ", ")))
 	}
+	if onlySynced {
+		parts = append(parts, "only synced")
+	}
+
+	return parts
+}
+
+// advancedSearchFilters describes the conditions of an advanced search besides the general query
+func advancedSearchFilters(req AdvancedSearchRequest) []string {
+	var parts []string
+
 	if req.TitleContains != "" {
 		parts = append(parts, fmt.Sprintf("title contains: '%s'", req.TitleContains))
 	}
@@ -637,64 +626,77 @@ func (s *Server) buildAdvancedSearchDescription(req AdvancedSearchRequest) strin
 	if len(req.Folders) > 0 {
 		parts = append(parts, fmt.Sprintf("in folders: [%s]", strings.Join(req.Folders, ", ")))
 	}
+	if len(req.Statuses) > 0 {
+		parts = append(parts, fmt.Sprintf("with status: [%s]", strings.Join(req.Statuses, ", ")))
+	}
 	if req.DateAfter != "" {
 		parts = append(parts, fmt.Sprintf("after: %s", req.DateAfter))
 	}
 	if req.DateBefore != "" {
 		parts = append(parts, fmt.Sprintf("before: %s", req.DateBefore))
 	}
-
-	if len(parts) == 0 {
-		return "all articles"
+	if req.OnlySynced {
+		parts = append(parts, "only synced")
 	}
 
-	return strings.Join(parts, ", ")
+	return parts
 }
 
 // getArticleWithDetails gets an article with full details including tags
-func (s *Server) getArticleWithDetails(id int64) (*model.ArticleWithDetails, error) {
+func (s *Server) getArticleWithDetails(ctx context.Context, id int64) (*model.ArticleWithDetails, error) {
 	query := `
 		SELECT
 			a.id, a.url, a.title, a.selection, a.folder_id, a.instapapered_at,
-			a.synced_at, a.sync_failed_at, a.failed_count, a.status_code,
-			a.status_text, a.final_url, a.content_md, a.raw_html,
+			a.synced_at, a.sync_failed_at, a.failed_count, a.status_code, a.status,
+			a.status_text, a.final_url, content_text(a.content_md) AS content_md, a.raw_html,
 			f.path_cache as folder_path
 		FROM articles a
 		LEFT JOIN folders f ON a.folder_id = f.id
-		WHERE a.id = ?
+		WHERE a.id = ? AND a.obsolete = FALSE
 	`
 
 	var article model.ArticleWithDetails
-	if err := s.db.Get(&article, query, id); err != nil {
+	if err := s.db.GetContext(ctx, &article, query, id); err != nil {
 		return nil, err
 	}
 
-	tags, err := s.getArticleTags(id)
+	tags, err := s.getArticleTags(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 	article.Tags = tags
 
+	outline, err := s.db.Outline(id)
+	if err != nil {
+		return nil, err
+	}
+	article.Outline = outline
+
 	return &article, nil
 }
 
 // getArticlesForExport gets articles for export based on options
-func (s *Server) getArticlesForExport(opts export.ExportAllOptions) ([]model.ArticleWithDetails, error) {
+func (s *Server) getArticlesForExport(ctx context.Context, opts export.ExportAllOptions) ([]model.ArticleWithDetails, error) {
 	if opts.FromSearch != "" {
-		return s.getArticlesFromSearch(opts)
+		return s.getArticlesFromSearch(ctx, opts)
+	}
+
+	opts, err := opts.WithAliases(s.db)
+	if err != nil {
+		return nil, err
 	}
 
 	query := `
 		SELECT DISTINCT
 			a.id, a.url, a.title, a.selection, a.folder_id, a.instapapered_at,
 			a.synced_at, a.sync_failed_at, a.failed_count, a.status_code,
-			a.status_text, a.final_url, a.content_md, a.raw_html,
+			a.status_text, a.final_url, content_text(a.content_md) AS content_md, a.raw_html,
 			f.path_cache as folder_path
 		FROM articles a
 		LEFT JOIN folders f ON a.folder_id = f.id
 		LEFT JOIN article_tags at ON a.id = at.article_id
 		LEFT JOIN tags t ON at.tag_id = t.id
-		WHERE 1=1
+		WHERE a.obsolete = FALSE
 	`
 
 	var args []interface{}
@@ -726,12 +728,12 @@ func (s *Server) getArticlesForExport(opts export.ExportAllOptions) ([]model.Art
 	query += " ORDER BY a.instapapered_at DESC"
 
 	var articles []model.ArticleWithDetails
-	if err := s.db.Select(&articles, query, args...); err != nil {
+	if err := s.db.SelectContext(ctx, &articles, query, args...); err != nil {
 		return nil, err
 	}
 
 	for i := range articles {
-		tags, err := s.getArticleTags(articles[i].ID)
+		tags, err := s.getArticleTags(ctx, articles[i].ID)
 		if err != nil {
 			return nil, err
 		}
@@ -742,12 +744,12 @@ func (s *Server) getArticlesForExport(opts export.ExportAllOptions) ([]model.Art
 }
 
 // getArticlesFromSearch gets articles based on search criteria
-func (s *Server) getArticlesFromSearch(opts export.ExportAllOptions) ([]model.ArticleWithDetails, error) {
+func (s *Server) getArticlesFromSearch(ctx context.Context, opts export.ExportAllOptions) ([]model.ArticleWithDetails, error) {
 	baseQuery := `
 		SELECT
 			a.id, a.url, a.title, a.selection, a.folder_id, a.instapapered_at,
 			a.synced_at, a.sync_failed_at, a.failed_count, a.status_code,
-			a.status_text, a.final_url, a.content_md, a.raw_html,
+			a.status_text, a.final_url, content_text(a.content_md) AS content_md, a.raw_html,
 			f.path_cache as folder_path
 		FROM articles a
 		LEFT JOIN folders f ON a.folder_id = f.id
@@ -763,7 +765,7 @@ func (s *Server) getArticlesFromSearch(opts export.ExportAllOptions) ([]model.Ar
 			SELECT
 				a.id, a.url, a.title, a.selection, a.folder_id, a.instapapered_at,
 				a.synced_at, a.sync_failed_at, a.failed_count, a.status_code,
-				a.status_text, a.final_url, a.content_md, a.raw_html,
+				a.status_text, a.final_url, content_text(a.content_md) AS content_md, a.raw_html,
 				f.path_cache as folder_path
 			FROM articles a
 			LEFT JOIN folders f ON a.folder_id = f.id
@@ -772,52 +774,27 @@ func (s *Server) getArticlesFromSearch(opts export.ExportAllOptions) ([]model.Ar
 			INNER JOIN articles_fts fts ON a.id = fts.rowid
 		`
 
-		if opts.SearchField != "" {
-			switch opts.SearchField {
-			case "url":
-				whereClause = "WHERE articles_fts MATCH ?"
-				args = append(args, "url: "+opts.FromSearch)
-			case "title":
-				whereClause = "WHERE articles_fts MATCH ?"
-				args = append(args, "title: "+opts.FromSearch)
-			case "content":
-				whereClause = "WHERE articles_fts MATCH ?"
-				args = append(args, "content: "+opts.FromSearch)
-			case "tags":
-				whereClause = "WHERE articles_fts MATCH ?"
-				args = append(args, "tags: "+opts.FromSearch)
-			case "folder":
-				whereClause = "WHERE articles_fts MATCH ?"
-				args = append(args, "folder: "+opts.FromSearch)
-			default:
-				return nil, fmt.Errorf("invalid field for FTS: %s", opts.SearchField)
-			}
-		} else {
-			// For multiple keywords, create an AND query for intersection search
-			keywords := strings.Fields(strings.TrimSpace(opts.FromSearch))
-			if len(keywords) > 1 {
-				// Build FTS query with AND operators for intersection
-				ftsQuery := strings.Join(keywords, " AND ")
-				whereClause = "WHERE articles_fts MATCH ?"
-				args = append(args, ftsQuery)
-			} else {
-				whereClause = "WHERE articles_fts MATCH ?"
-				args = append(args, opts.FromSearch)
-			}
+		ftsQuery, err := search.FTSQuery(opts.FromSearch, opts.SearchField)
+		if err != nil {
+			return nil, err
 		}
+		whereClause = "WHERE a.obsolete = FALSE AND articles_fts MATCH ?"
+		args = append(args, ftsQuery)
 	} else {
 		if opts.SearchField != "" {
 			switch opts.SearchField {
 			case "url":
-				whereClause = "WHERE a.url LIKE ? COLLATE NOCASE"
+				whereClause = "WHERE a.obsolete = FALSE AND a.url LIKE ? COLLATE NOCASE"
 			case "title":
-				whereClause = "WHERE a.title LIKE ? COLLATE NOCASE"
+				whereClause = "WHERE a.obsolete = FALSE AND a.title_norm LIKE normalize_text(?)"
 			case "content":
-				whereClause = "WHERE a.content_md LIKE ? COLLATE NOCASE"
+				whereClause = "WHERE a.obsolete = FALSE AND content_text(a.content_md) LIKE ? COLLATE NOCASE"
+			case "selection":
+				whereClause = "WHERE a.obsolete = FALSE AND a.selection LIKE ? COLLATE NOCASE"
 			case "tags":
-				whereClause = "WHERE t.title LIKE ? COLLATE NOCASE"
+				whereClause = "WHERE a.obsolete = FALSE AND t.title LIKE ? COLLATE NOCASE"
 			case "folder":
-				whereClause = "WHERE f.path_cache LIKE ? COLLATE NOCASE OR f.title LIKE ? COLLATE NOCASE"
+				whereClause = "WHERE a.obsolete = FALSE AND (f.path_cache LIKE ? COLLATE NOCASE OR f.title LIKE ? COLLATE NOCASE)"
 				args = append(args, "%"+opts.FromSearch+"%")
 			default:
 				return nil, fmt.Errorf("invalid field: %s", opts.SearchField)
@@ -825,11 +802,11 @@ func (s *Server) getArticlesFromSearch(opts export.ExportAllOptions) ([]model.Ar
 			args = append(args, "%"+opts.FromSearch+"%")
 		} else {
 			whereClause = `
-				WHERE (a.url LIKE ? COLLATE NOCASE OR a.title LIKE ? COLLATE NOCASE OR a.content_md LIKE ? COLLATE NOCASE
-				       OR t.title LIKE ? COLLATE NOCASE OR f.path_cache LIKE ? COLLATE NOCASE)
+				WHERE a.obsolete = FALSE AND (a.url LIKE ? COLLATE NOCASE OR a.title_norm LIKE normalize_text(?) OR content_text(a.content_md) LIKE ? COLLATE NOCASE
+				       OR a.selection LIKE ? COLLATE NOCASE OR t.title LIKE ? COLLATE NOCASE OR f.path_cache LIKE ? COLLATE NOCASE)
 			`
 			pattern := "%" + opts.FromSearch + "%"
-			args = append(args, pattern, pattern, pattern, pattern, pattern)
+			args = append(args, pattern, pattern, pattern, pattern, pattern, pattern)
 		}
 	}
 
@@ -849,12 +826,12 @@ func (s *Server) getArticlesFromSearch(opts export.ExportAllOptions) ([]model.Ar
 	}
 
 	var articles []model.ArticleWithDetails
-	if err := s.db.Select(&articles, query, args...); err != nil {
+	if err := s.db.SelectContext(ctx, &articles, query, args...); err != nil {
 		return nil, err
 	}
 
 	for i := range articles {
-		tags, err := s.getArticleTags(articles[i].ID)
+		tags, err := s.getArticleTags(ctx, articles[i].ID)
 		if err != nil {
 			return nil, err
 		}
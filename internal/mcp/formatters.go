@@ -6,15 +6,44 @@ import (
 	"strings"
 	"time"
 
+	"github.com/mark3labs/mcp-go/mcp"
 	"instapaper-cli/internal/model"
 )
 
+// Result formats of the tools that take a format argument
+const (
+	formatMarkdown = "markdown"
+	formatJSON     = "json"
+)
+
+// wantJSON reports whether a tool call asked for structured JSON instead of markdown
+func wantJSON(arguments map[string]interface{}) (bool, error) {
+	format, _ := arguments["format"].(string)
+	switch format {
+	case "", formatMarkdown:
+		return false, nil
+	case formatJSON:
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid format %q: use %s or %s", format, formatMarkdown, formatJSON)
+}
+
+// jsonResult returns value as an indented JSON tool result
+func jsonResult(value interface{}) *mcp.CallToolResult {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to encode result: %v", err))
+	}
+	return mcp.NewToolResultText(string(data))
+}
+
 // convertSearchResultToResponse converts a SearchResult to ArticleResponse
 func (s *Server) convertSearchResultToResponse(result model.SearchResult) ArticleResponse {
 	response := ArticleResponse{
 		ID:          result.ID,
 		URL:         result.URL,
 		Title:       result.Title,
+		Status:      result.Status,
 		FailedCount: result.FailedCount,
 		StatusCode:  result.StatusCode,
 	}
@@ -24,6 +53,12 @@ func (s *Server) convertSearchResultToResponse(result model.SearchResult) Articl
 		response.InstapaperedAt = parsedTime
 	}
 
+	if result.PublishedAt != nil {
+		if parsedTime, err := time.Parse(time.RFC3339, *result.PublishedAt); err == nil {
+			response.PublishedAt = &parsedTime
+		}
+	}
+
 	// Handle optional fields
 	if result.FolderPath != nil {
 		response.FolderPath = result.FolderPath
@@ -43,12 +78,22 @@ func (s *Server) convertSearchResultToResponse(result model.SearchResult) Articl
 	return response
 }
 
+// articlesResponse lists search results as a SearchResponse without a query
+func (s *Server) articlesResponse(results []model.SearchResult) SearchResponse {
+	response := SearchResponse{Articles: make([]ArticleResponse, 0, len(results)), TotalCount: len(results), TotalMatches: len(results)}
+	for _, result := range results {
+		response.Articles = append(response.Articles, s.convertSearchResultToResponse(result))
+	}
+	return response
+}
+
 // convertArticleWithDetailsToResponse converts ArticleWithDetails to ArticleResponse
 func (s *Server) convertArticleWithDetailsToResponse(article model.ArticleWithDetails, includeContent, includeHTML, includeTags bool) ArticleResponse {
 	response := ArticleResponse{
 		ID:          article.ID,
 		URL:         article.URL,
 		Title:       article.Title,
+		Status:      article.Status,
 		Selection:   article.Selection,
 		FailedCount: article.FailedCount,
 		StatusCode:  article.StatusCode,
@@ -90,6 +135,10 @@ func (s *Server) convertArticleWithDetailsToResponse(article model.ArticleWithDe
 		response.FolderPath = article.FolderPath
 	}
 
+	if len(article.Outline) > 0 {
+		response.Outline = article.Outline
+	}
+
 	return response
 }
 
@@ -98,8 +147,17 @@ func (s *Server) formatSearchResponse(response SearchResponse) string {
 	var output strings.Builder
 
 	output.WriteString(fmt.Sprintf("# Search Results\n\n"))
-	output.WriteString(fmt.Sprintf("**Query:** %s\n", response.SearchQuery))
-	output.WriteString(fmt.Sprintf("**Results:** %d articles\n", response.TotalCount))
+	if response.SearchQuery != "" {
+		output.WriteString(fmt.Sprintf("**Query:** %s\n", response.SearchQuery))
+	}
+	if len(response.Filters) > 0 {
+		output.WriteString(fmt.Sprintf("**Filters:** %s\n", strings.Join(response.Filters, ", ")))
+	}
+	if response.TotalMatches > response.TotalCount {
+		output.WriteString(fmt.Sprintf("**Results:** %d of %d articles\n", response.TotalCount, response.TotalMatches))
+	} else {
+		output.WriteString(fmt.Sprintf("**Results:** %d articles\n", response.TotalCount))
+	}
 	output.WriteString(fmt.Sprintf("**Search Time:** %s\n\n", response.SearchTime))
 
 	if len(response.Articles) == 0 {
@@ -170,6 +228,12 @@ func (s *Server) formatArticleResponse(article ArticleResponse) string {
 
 	output.WriteString("\n")
 
+	if len(article.Outline) > 0 {
+		output.WriteString("## Outline\n\n")
+		writeOutline(&output, article.Outline)
+		output.WriteString("\n")
+	}
+
 	if article.ContentMD != nil && *article.ContentMD != "" {
 		output.WriteString("## Content\n\n")
 		output.WriteString(*article.ContentMD)
@@ -264,23 +328,7 @@ func (s *Server) formatTagsResponse(tags []TagInfo) string {
 }
 
 // formatArticleContextResponse formats an article with its context
-func (s *Server) formatArticleContextResponse(response interface{}) string {
-	// Marshal to JSON and then back to get the structure we need
-	jsonData, err := json.Marshal(response)
-	if err != nil {
-		return "Error formatting response"
-	}
-
-	var contextResp struct {
-		MainArticle      ArticleResponse   `json:"main_article"`
-		RelatedArticles  []ArticleResponse `json:"related_articles"`
-		RelationshipType string            `json:"relationship_type"`
-	}
-
-	if err := json.Unmarshal(jsonData, &contextResp); err != nil {
-		return "Error parsing response"
-	}
-
+func (s *Server) formatArticleContextResponse(contextResp ArticleContextResponse) string {
 	var output strings.Builder
 
 	// Format main article
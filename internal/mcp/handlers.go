@@ -1,17 +1,28 @@
 package mcp
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"instapaper-cli/internal/db"
+	"instapaper-cli/internal/export"
+	"instapaper-cli/internal/fetcher"
+	"instapaper-cli/internal/language"
 	"instapaper-cli/internal/model"
+	"instapaper-cli/internal/rss"
 	"instapaper-cli/internal/search"
+	"instapaper-cli/internal/util"
 )
 
 // handleSearchArticles handles the search_articles tool
-func (s *Server) handleSearchArticles(arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+func (s *Server) handleSearchArticles(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
 	// Extract parameters with defaults
 	query, _ := arguments["query"].(string)
 	field, _ := arguments["field"].(string)
@@ -31,29 +42,64 @@ func (s *Server) handleSearchArticles(arguments map[string]interface{}) (*mcp.Ca
 		limit = int(l)
 	}
 	onlySynced, _ := arguments["only_synced"].(bool)
+	withFacets, _ := arguments["facets"].(bool)
+	asJSON, err := wantJSON(arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	statuses := stringSliceArgument(arguments, "statuses")
+	if err := db.ValidateStatuses(statuses...); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	langs := stringSliceArgument(arguments, "languages")
+	if err := language.Validate(langs); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	langs, err = language.Resolve(langs, query)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
-	// Build search options
+	start := time.Now()
+
+	// Build search options. The total and the facets count every match, so the limit is
+	// applied after searching.
 	searchOpts := search.SearchOptions{
 		Query:      query,
 		Field:      field,
 		UseFTS:     useFTS,
-		Limit:      limit,
 		JSONOutput: false,
 		Since:      since,
 		Until:      until,
 	}
+	searchOpts.Statuses = statuses
+	searchOpts.Languages = langs
+	filters := searchArticlesFilters(searchOpts, onlySynced)
 
 	// Perform basic search using existing functionality
 	var results []model.SearchResult
-	var err error
 
+	strategy := search.Strategy{Name: search.StrategyLike, Query: query}
 	if useFTS && query != "" {
-		results, err = s.searchFTS(searchOpts)
+		strategy.Name = search.StrategyFTS
+		results, err = s.searchFTS(ctx, searchOpts)
+
+		// Retry with misspelled terms corrected ("kuberentes") when nothing matched
+		if fuzzy, ok := arguments["fuzzy"].(bool); err == nil && len(results) == 0 && (!ok || fuzzy) {
+			corrected, corrections, correctErr := search.CorrectQuery(ctx, s.db, query)
+			if correctErr == nil && len(corrections) > 0 {
+				searchOpts.Query = corrected
+				if results, err = s.searchFTS(ctx, searchOpts); err == nil && len(results) > 0 {
+					strategy = search.Strategy{Name: search.StrategyFuzzy, Query: corrected, Corrections: corrections}
+					query = corrected
+				}
+			}
+		}
 	} else if query != "" {
-		results, err = s.searchLike(searchOpts)
-	} else if since != "" || until != "" {
-		// Handle date-only filtering (like latest command)
-		results, err = s.searchLike(searchOpts)
+		results, err = s.searchLike(ctx, searchOpts)
+	} else if since != "" || until != "" || len(statuses) > 0 || len(langs) > 0 {
+		// Handle date-only and status-only filtering (like latest command)
+		results, err = s.searchLike(ctx, searchOpts)
 	} else {
 		// Return empty results if no query or date filter
 		results = []model.SearchResult{}
@@ -75,12 +121,50 @@ func (s *Server) handleSearchArticles(arguments map[string]interface{}) (*mcp.Ca
 	}
 
 	// Format results
-	if len(results) == 0 {
+	if len(results) == 0 && !asJSON {
+		if len(filters) > 0 {
+			return mcp.NewToolResultText(fmt.Sprintf("No articles found matching the search criteria (filters: %s).", strings.Join(filters, ", "))), nil
+		}
 		return mcp.NewToolResultText("No articles found matching the search criteria."), nil
 	}
 
+	var facets *search.Facets
+	if withFacets {
+		if facets, err = search.ComputeFacets(ctx, s.db, results, 10); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to compute facets: %v", err)), nil
+		}
+	}
+	total := len(results)
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	elapsed := time.Since(start)
+
+	if asJSON {
+		response := s.articlesResponse(results)
+		response.TotalMatches = total
+		response.SearchTime = elapsed.String()
+		response.SearchQuery = query
+		response.Filters = filters
+		response.Strategy = &strategy
+		response.Facets = facets
+		return jsonResult(response), nil
+	}
+
 	var output strings.Builder
-	output.WriteString(fmt.Sprintf("Found %d articles:\n\n", len(results)))
+	if facets != nil {
+		output.WriteString(fmt.Sprintf("Facets over all %d matches:\n%s\n", facets.Total, facets.Summary()))
+	}
+
+	output.WriteString(fmt.Sprintf("Found %d articles (strategy: %s, query: %q, time: %s)", total, strategy.Name, query, elapsed))
+	if total > len(results) {
+		output.WriteString(fmt.Sprintf(", showing the first %d", len(results)))
+	}
+	output.WriteString(":\n")
+	if len(filters) > 0 {
+		output.WriteString(fmt.Sprintf("Filters: %s\n", strings.Join(filters, ", ")))
+	}
+	output.WriteString("\n")
 
 	for i, result := range results {
 		output.WriteString(fmt.Sprintf("**%d. %s**\n", i+1, result.Title))
@@ -95,6 +179,8 @@ func (s *Server) handleSearchArticles(arguments map[string]interface{}) (*mcp.Ca
 			output.WriteString(fmt.Sprintf("Tags: %s\n", *result.Tags))
 		}
 
+		output.WriteString(fmt.Sprintf("Status: %s\n", result.Status))
+
 		if result.SyncedAt != nil {
 			output.WriteString("Content: Available\n")
 		} else {
@@ -108,7 +194,7 @@ func (s *Server) handleSearchArticles(arguments map[string]interface{}) (*mcp.Ca
 }
 
 // handleGetArticle handles the get_article tool
-func (s *Server) handleGetArticle(arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+func (s *Server) handleGetArticle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
 	// Extract article ID
 	idFloat, ok := arguments["id"].(float64)
 	if !ok {
@@ -126,12 +212,70 @@ func (s *Server) handleGetArticle(arguments map[string]interface{}) (*mcp.CallTo
 		includeTags = it
 	}
 
+	// Long articles can be read in parts of max_words words, resuming from a continuation token
+	maxWords := 0
+	if mw, ok := arguments["max_words"].(float64); ok && mw > 0 {
+		if int(mw) < minPartWords {
+			return mcp.NewToolResultError(fmt.Sprintf("max_words must be at least %d", minPartWords)), nil
+		}
+		maxWords = int(mw)
+	}
+	part := 1
+	var tokenHash uint32
+	continuation, _ := arguments["continuation"].(string)
+	if continuation != "" {
+		tokenID, tokenPart, tokenWords, hash, err := parseContinuationToken(continuation)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if tokenID != id {
+			return mcp.NewToolResultError(fmt.Sprintf("Continuation token is for article %d, not %d", tokenID, id)), nil
+		}
+		part, maxWords, tokenHash = tokenPart, tokenWords, hash
+	}
+	summary, _ := arguments["summary"].(bool)
+	includeOutline, _ := arguments["include_outline"].(bool)
+	asJSON, err := wantJSON(arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if asJSON && summary {
+		return mcp.NewToolResultError("summary is only available as markdown; use max_words to read the article in parts as JSON"), nil
+	}
+
 	// Get article with details
-	article, err := s.getArticleWithDetails(id)
+	article, err := s.getArticleWithDetails(ctx, id)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to get article: %v", err)), nil
 	}
 
+	// A long article read in parts returns only the requested part
+	var parts []articlePart
+	if includeContent && !summary && maxWords > 0 && article.ContentMD != nil && *article.ContentMD != "" {
+		if continuation != "" && tokenHash != contentHash(*article.ContentMD) {
+			return mcp.NewToolResultError("The article was refetched since the continuation token was issued; read it again from the start"), nil
+		}
+		parts = splitArticle(*article.ContentMD, maxWords)
+		if part > len(parts) {
+			return mcp.NewToolResultError(fmt.Sprintf("The article has only %d parts", len(parts))), nil
+		}
+	}
+
+	if asJSON {
+		response := s.convertArticleWithDetailsToResponse(*article, includeContent, false, includeTags)
+		if !includeOutline {
+			response.Outline = nil
+		}
+		if len(parts) > 0 {
+			response.ContentMD = &parts[part-1].Content
+			response.Part = &ArticlePart{Number: part, Total: len(parts), Words: parts[part-1].Words}
+			if part < len(parts) {
+				response.Part.Continuation = continuationToken(id, part+1, maxWords, *article.ContentMD)
+			}
+		}
+		return jsonResult(response), nil
+	}
+
 	// Format article
 	var output strings.Builder
 	output.WriteString(fmt.Sprintf("# %s\n\n", article.Title))
@@ -146,6 +290,8 @@ func (s *Server) handleGetArticle(arguments map[string]interface{}) (*mcp.CallTo
 		output.WriteString(fmt.Sprintf("**Tags:** %s\n", strings.Join(article.Tags, ", ")))
 	}
 
+	output.WriteString(fmt.Sprintf("**Status:** %s\n", article.Status))
+
 	if article.Selection != nil && *article.Selection != "" {
 		output.WriteString(fmt.Sprintf("**Selected Text:** %s\n", *article.Selection))
 	}
@@ -160,28 +306,171 @@ func (s *Server) handleGetArticle(arguments map[string]interface{}) (*mcp.CallTo
 
 	output.WriteString("\n")
 
-	if includeContent && article.ContentMD != nil && *article.ContentMD != "" {
-		output.WriteString("## Content\n\n")
-		output.WriteString(*article.ContentMD)
-	} else {
+	if includeOutline && len(article.Outline) > 0 {
+		output.WriteString("## Outline\n\n")
+		writeOutline(&output, article.Outline)
+		output.WriteString("\n")
+	}
+
+	if !includeContent || article.ContentMD == nil || *article.ContentMD == "" {
 		output.WriteString("*Article content not yet downloaded.*")
+		return mcp.NewToolResultText(output.String()), nil
+	}
+	content := *article.ContentMD
+
+	if summary {
+		if maxWords == 0 {
+			maxWords = defaultPartWords
+		}
+		writeArticleSummary(&output, id, content, maxWords)
+		return mcp.NewToolResultText(output.String()), nil
+	}
+
+	if maxWords == 0 {
+		output.WriteString("## Content\n\n")
+		output.WriteString(content)
+		return mcp.NewToolResultText(output.String()), nil
+	}
+
+	output.WriteString(fmt.Sprintf("## Content (part %d of %d, %d words)\n\n", part, len(parts), parts[part-1].Words))
+	output.WriteString(parts[part-1].Content)
+	if part < len(parts) {
+		output.WriteString(fmt.Sprintf("\n\n---\n*Continued in part %d. Call get_article with id %d and continuation \"%s\" to read on.*\n",
+			part+1, id, continuationToken(id, part+1, maxWords, content)))
+	} else if len(parts) > 1 {
+		output.WriteString("\n\n---\n*End of article.*\n")
+	}
+
+	return mcp.NewToolResultText(output.String()), nil
+}
+
+// handleSearchWithinArticle handles the search_within_article tool
+func (s *Server) handleSearchWithinArticle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	idFloat, ok := arguments["id"].(float64)
+	if !ok {
+		return mcp.NewToolResultError("Article ID is required and must be a number"), nil
+	}
+	id := int64(idFloat)
+
+	pattern, _ := arguments["pattern"].(string)
+	opts := search.PassageOptions{Context: 1, Limit: 10}
+	opts.Regex, _ = arguments["regex"].(bool)
+	opts.CaseSensitive, _ = arguments["case_sensitive"].(bool)
+	if c, ok := arguments["context"].(float64); ok {
+		opts.Context = int(c)
+	}
+	if mp, ok := arguments["max_passages"].(float64); ok && mp > 0 {
+		opts.Limit = int(mp)
+	}
+
+	asJSON, err := wantJSON(arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	finder, err := search.NewPassageFinder(pattern, opts)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	article, err := s.getArticleWithDetails(ctx, id)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get article: %v", err)), nil
+	}
+	if (article.ContentMD == nil || *article.ContentMD == "") && !asJSON {
+		return mcp.NewToolResultText(fmt.Sprintf("Article %d has no downloaded content to search.", id)), nil
+	}
+
+	var passages []search.Passage
+	var matches int
+	if article.ContentMD != nil {
+		passages, matches = finder.Find(*article.ContentMD)
+	}
+	if asJSON {
+		if passages == nil {
+			passages = []search.Passage{}
+		}
+		return jsonResult(PassagesResponse{ID: id, Title: article.Title, Pattern: pattern, Matches: matches, Passages: passages}), nil
+	}
+	if len(passages) == 0 {
+		return mcp.NewToolResultText(fmt.Sprintf("No passages in article %d (%s) match %q.", id, article.Title, pattern)), nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("# %s\n\n", article.Title))
+	output.WriteString(fmt.Sprintf("**Pattern:** %q\n**Matches:** %d in the article, shown in bold\n**Passages:** %d (at most %d)\n", pattern, matches, len(passages), opts.Limit))
+	for _, passage := range passages {
+		if passage.Start == passage.End {
+			output.WriteString(fmt.Sprintf("\n## Paragraph %d\n\n", passage.Start))
+		} else {
+			output.WriteString(fmt.Sprintf("\n## Paragraphs %d-%d\n\n", passage.Start, passage.End))
+		}
+		output.WriteString(finder.Highlight(passage.Text, "**", "**"))
+		output.WriteString("\n")
 	}
 
 	return mcp.NewToolResultText(output.String()), nil
 }
 
+// writeOutline lists headings as a nested markdown list with the paragraph each starts at, the
+// number search_within_article reports
+func writeOutline(output *strings.Builder, outline []model.Heading) {
+	top := 6
+	for _, heading := range outline {
+		top = min(top, heading.Level)
+	}
+	for _, heading := range outline {
+		output.WriteString(fmt.Sprintf("%s- %s (paragraph %d)\n", strings.Repeat("  ", heading.Level-top), heading.Title, heading.Paragraph))
+	}
+}
+
+// writeArticleSummary describes a long article without its full text: its length, lead, and an
+// outline of its parts with the headings and opening of each and a token to read it
+func writeArticleSummary(output *strings.Builder, id int64, content string, maxWords int) {
+	parts := splitArticle(content, maxWords)
+	words := 0
+	for _, p := range parts {
+		words += p.Words
+	}
+
+	output.WriteString("## Summary\n\n")
+	output.WriteString(fmt.Sprintf("**Length:** %d words in %d parts of up to about %d words\n\n", words, len(parts), maxWords))
+	if lead := partOpening(content); lead != "" {
+		output.WriteString(fmt.Sprintf("**Lead:** %s\n\n", lead))
+	}
+
+	output.WriteString("## Outline\n\n")
+	for i, p := range parts {
+		output.WriteString(fmt.Sprintf("### Part %d (%d words)\n", i+1, p.Words))
+		if len(p.Headings) > 0 {
+			output.WriteString(fmt.Sprintf("Sections: %s\n", strings.Join(p.Headings, "; ")))
+		}
+		if opening := partOpening(p.Content); opening != "" {
+			output.WriteString(fmt.Sprintf("Opens: %s\n", opening))
+		}
+		output.WriteString(fmt.Sprintf("Continuation: %s\n\n", continuationToken(id, i+1, maxWords, content)))
+	}
+
+	output.WriteString(fmt.Sprintf("Call get_article with id %d and a part's continuation token to read that part.\n", id))
+}
+
 // handleListFolders handles the list_folders tool
-func (s *Server) handleListFolders(arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+func (s *Server) handleListFolders(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	asJSON, err := wantJSON(arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
 	query := `
 		SELECT f.id, f.title, f.path_cache, COUNT(a.id) as article_count
 		FROM folders f
-		LEFT JOIN articles a ON f.id = a.folder_id
+		LEFT JOIN articles a ON f.id = a.folder_id AND a.obsolete = FALSE
 		GROUP BY f.id, f.title, f.path_cache
 		ORDER BY f.path_cache, f.title
 	`
 
-	var folders []FolderInfo
-	rows, err := s.db.Query(query)
+	folders := []FolderInfo{}
+	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to query folders: %v", err)), nil
 	}
@@ -201,6 +490,9 @@ func (s *Server) handleListFolders(arguments map[string]interface{}) (*mcp.CallT
 		folders = append(folders, folder)
 	}
 
+	if asJSON {
+		return jsonResult(folders), nil
+	}
 	if len(folders) == 0 {
 		return mcp.NewToolResultText("No folders found."), nil
 	}
@@ -216,29 +508,34 @@ func (s *Server) handleListFolders(arguments map[string]interface{}) (*mcp.CallT
 }
 
 // handleListTags handles the list_tags tool
-func (s *Server) handleListTags(arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+func (s *Server) handleListTags(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
 	minCount := 0
 	if mc, ok := arguments["min_count"].(float64); ok {
 		minCount = int(mc)
 	}
+	asJSON, err := wantJSON(arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
 	query := `
-		SELECT t.id, t.title, COUNT(at.article_id) as article_count
+		SELECT t.id, t.title, COUNT(a.id) as article_count
 		FROM tags t
 		LEFT JOIN article_tags at ON t.id = at.tag_id
+		LEFT JOIN articles a ON at.article_id = a.id AND a.obsolete = FALSE
 		GROUP BY t.id, t.title
 	`
 
 	var args []interface{}
 	if minCount > 0 {
-		query += " HAVING COUNT(at.article_id) >= ?"
+		query += " HAVING COUNT(a.id) >= ?"
 		args = append(args, minCount)
 	}
 
 	query += " ORDER BY article_count DESC, t.title"
 
-	var tags []TagInfo
-	rows, err := s.db.Query(query, args...)
+	tags := []TagInfo{}
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to query tags: %v", err)), nil
 	}
@@ -252,6 +549,9 @@ func (s *Server) handleListTags(arguments map[string]interface{}) (*mcp.CallTool
 		tags = append(tags, tag)
 	}
 
+	if asJSON {
+		return jsonResult(tags), nil
+	}
 	if len(tags) == 0 {
 		return mcp.NewToolResultText("No tags found."), nil
 	}
@@ -267,7 +567,7 @@ func (s *Server) handleListTags(arguments map[string]interface{}) (*mcp.CallTool
 }
 
 // handleExportArticles handles the export_articles tool
-func (s *Server) handleExportArticles(arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+func (s *Server) handleExportArticles(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
 	query, _ := arguments["query"].(string)
 	limit := 10 // Default limit for exports
 	if l, ok := arguments["limit"].(float64); ok {
@@ -291,14 +591,14 @@ func (s *Server) handleExportArticles(arguments map[string]interface{}) (*mcp.Ca
 			JSONOutput: false,
 		}
 
-		results, searchErr := s.searchFTS(searchOpts)
+		results, searchErr := s.searchFTS(ctx, searchOpts)
 		if searchErr != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Search failed: %v", searchErr)), nil
 		}
 
 		// Get full details for each result
 		for _, result := range results {
-			article, detailErr := s.getArticleWithDetails(result.ID)
+			article, detailErr := s.getArticleWithDetails(ctx, result.ID)
 			if detailErr != nil {
 				continue
 			}
@@ -313,12 +613,12 @@ func (s *Server) handleExportArticles(arguments map[string]interface{}) (*mcp.Ca
 		// Get recent articles
 		articlesQuery := `
 			SELECT a.id, a.url, a.title, a.selection, a.folder_id, a.instapapered_at,
-				   a.synced_at, a.sync_failed_at, a.failed_count, a.status_code,
-				   a.status_text, a.final_url, a.content_md, a.raw_html,
+				   a.synced_at, a.sync_failed_at, a.failed_count, a.status_code, a.status,
+				   a.status_text, a.final_url, content_text(a.content_md) AS content_md, a.raw_html,
 				   f.path_cache as folder_path
 			FROM articles a
 			LEFT JOIN folders f ON a.folder_id = f.id
-			WHERE 1=1
+			WHERE a.obsolete = FALSE
 		`
 
 		if onlySynced {
@@ -327,13 +627,13 @@ func (s *Server) handleExportArticles(arguments map[string]interface{}) (*mcp.Ca
 
 		articlesQuery += " ORDER BY a.instapapered_at DESC LIMIT ?"
 
-		if err := s.db.Select(&articles, articlesQuery, limit); err != nil {
+		if err := s.db.SelectContext(ctx, &articles, articlesQuery, limit); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to get articles: %v", err)), nil
 		}
 
 		// Get tags for each article
 		for i := range articles {
-			tags, _ := s.getArticleTags(articles[i].ID)
+			tags, _ := s.getArticleTags(ctx, articles[i].ID)
 			articles[i].Tags = tags
 		}
 	}
@@ -347,38 +647,15 @@ func (s *Server) handleExportArticles(arguments map[string]interface{}) (*mcp.Ca
 	content.WriteString(fmt.Sprintf("# Exported Articles (%d)\n\n", len(articles)))
 
 	for i, article := range articles {
-		if i > 0 {
-			content.WriteString("\n---\n\n")
-		}
-
-		content.WriteString(fmt.Sprintf("## %s\n\n", article.Title))
-		content.WriteString(fmt.Sprintf("**Source:** %s\n", article.URL))
-
-		if article.FolderPath != nil && *article.FolderPath != "" {
-			content.WriteString(fmt.Sprintf("**Folder:** %s\n", *article.FolderPath))
-		}
-
-		if len(article.Tags) > 0 {
-			content.WriteString(fmt.Sprintf("**Tags:** %s\n", strings.Join(article.Tags, ", ")))
-		}
-
-		parsedTime, _ := time.Parse(time.RFC3339, article.InstapaperedAt)
-		content.WriteString(fmt.Sprintf("**Added:** %s\n\n", parsedTime.Format("2006-01-02")))
-
-		if article.ContentMD != nil && *article.ContentMD != "" {
-			content.WriteString(*article.ContentMD)
-		} else {
-			content.WriteString("*Content not yet downloaded.*")
-		}
-
-		content.WriteString("\n\n")
+		// Writing to a strings.Builder cannot fail
+		s.export.WriteCombinedArticle(&content, i, article)
 	}
 
 	return mcp.NewToolResultText(content.String()), nil
 }
 
 // handleGetLatestArticles handles the get_latest_articles tool
-func (s *Server) handleGetLatestArticles(arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+func (s *Server) handleGetLatestArticles(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
 	limit := 20
 	if l, ok := arguments["limit"].(float64); ok {
 		limit = int(l)
@@ -387,33 +664,25 @@ func (s *Server) handleGetLatestArticles(arguments map[string]interface{}) (*mcp
 	since, _ := arguments["since"].(string)
 	until, _ := arguments["until"].(string)
 	onlySynced, _ := arguments["only_synced"].(bool)
-
-	// Use search functionality with empty query to get latest articles
-	searchOpts := search.SearchOptions{
-		Query:      "",
-		Field:      "",
-		UseFTS:     false,
-		Limit:      limit,
-		JSONOutput: false,
-		Since:      since,
-		Until:      until,
+	by, _ := arguments["by"].(string)
+	asJSON, err := wantJSON(arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	// Get results using search
-	results, err := s.searchLike(searchOpts)
+	results, err := s.search.FindLatest(ctx, search.LatestOptions{
+		By:            by,
+		Limit:         limit,
+		Since:         since,
+		Until:         until,
+		HealthFilters: search.HealthFilters{FetchedOnly: onlySynced},
+	})
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to get latest articles: %v", err)), nil
 	}
 
-	// Filter by synced status if requested
-	if onlySynced {
-		var filteredResults []model.SearchResult
-		for _, result := range results {
-			if result.SyncedAt != nil {
-				filteredResults = append(filteredResults, result)
-			}
-		}
-		results = filteredResults
+	if asJSON {
+		return jsonResult(s.articlesResponse(results)), nil
 	}
 
 	// Format results
@@ -447,6 +716,11 @@ func (s *Server) handleGetLatestArticles(arguments map[string]interface{}) (*mcp
 		if parsedTime, err := time.Parse(time.RFC3339, result.InstapaperedAt); err == nil {
 			output.WriteString(fmt.Sprintf("Added: %s\n", parsedTime.Format("2006-01-02 15:04:05")))
 		}
+		if result.PublishedAt != nil {
+			if parsedTime, err := time.Parse(time.RFC3339, *result.PublishedAt); err == nil {
+				output.WriteString(fmt.Sprintf("Published: %s\n", parsedTime.Format("2006-01-02")))
+			}
+		}
 
 		if result.FolderPath != nil && *result.FolderPath != "" {
 			output.WriteString(fmt.Sprintf("Folder: %s\n", *result.FolderPath))
@@ -468,8 +742,365 @@ func (s *Server) handleGetLatestArticles(arguments map[string]interface{}) (*mcp
 	return mcp.NewToolResultText(output.String()), nil
 }
 
+// handleAdvancedSearch handles the advanced_search tool
+func (s *Server) handleAdvancedSearch(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	req := AdvancedSearchRequest{
+		Limit:  50,
+		UseFTS: true,
+	}
+
+	req.Query, _ = arguments["query"].(string)
+	req.TitleContains, _ = arguments["title_contains"].(string)
+	req.ContentContains, _ = arguments["content_contains"].(string)
+	req.URLContains, _ = arguments["url_contains"].(string)
+	req.DateAfter, _ = arguments["date_after"].(string)
+	req.DateBefore, _ = arguments["date_before"].(string)
+	req.SortBy, _ = arguments["sort_by"].(string)
+	req.SortOrder, _ = arguments["sort_order"].(string)
+	req.OnlySynced, _ = arguments["only_synced"].(bool)
+	req.Tags = stringSliceArgument(arguments, "tags")
+	req.AnyTags = stringSliceArgument(arguments, "any_tags")
+	req.Folders = stringSliceArgument(arguments, "folders")
+	req.Statuses = stringSliceArgument(arguments, "statuses")
+	if err := db.ValidateStatuses(req.Statuses...); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	asJSON, err := wantJSON(arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if useFTS, ok := arguments["use_fts"].(bool); ok {
+		req.UseFTS = useFTS
+	}
+
+	if l, ok := arguments["limit"].(float64); ok {
+		req.Limit = int(l)
+	}
+
+	start := time.Now()
+	if err := s.resolveAdvancedSearchAliases(&req); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Advanced search failed: %v", err)), nil
+	}
+
+	// Search without the limit to count every match, then cut the list down
+	unlimited := req
+	unlimited.Limit = 0
+	results, err := s.performAdvancedSearch(ctx, unlimited)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Advanced search failed: %v", err)), nil
+	}
+	total := len(results)
+	if req.Limit > 0 && len(results) > req.Limit {
+		results = results[:req.Limit]
+	}
+
+	response := s.articlesResponse(results)
+	response.TotalMatches = total
+	response.SearchTime = time.Since(start).String()
+	response.SearchQuery = req.Query
+	response.Filters = advancedSearchFilters(req)
+
+	if asJSON {
+		return jsonResult(response), nil
+	}
+	return mcp.NewToolResultText(s.formatSearchResponse(response)), nil
+}
+
+// handleGetArticleContext handles the get_article_context tool
+func (s *Server) handleGetArticleContext(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	idFloat, ok := arguments["id"].(float64)
+	if !ok {
+		return mcp.NewToolResultError("Article ID is required and must be a number"), nil
+	}
+
+	req := GetArticleContextRequest{
+		ID:               int64(idFloat),
+		IncludeRelated:   true,
+		MaxRelated:       5,
+		RelationshipType: "tags",
+		IncludeContent:   true,
+	}
+
+	if ir, ok := arguments["include_related"].(bool); ok {
+		req.IncludeRelated = ir
+	}
+	if mr, ok := arguments["max_related"].(float64); ok {
+		req.MaxRelated = int(mr)
+	}
+	if rt, ok := arguments["relationship_type"].(string); ok && rt != "" {
+		req.RelationshipType = rt
+	}
+	if ic, ok := arguments["include_content"].(bool); ok {
+		req.IncludeContent = ic
+	}
+	asJSON, err := wantJSON(arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	article, err := s.getArticleWithDetails(ctx, req.ID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get article: %v", err)), nil
+	}
+
+	related := []ArticleResponse{}
+	if req.IncludeRelated && req.MaxRelated > 0 {
+		relatedArticles, err := s.findRelatedArticles(ctx, *article, req.RelationshipType, req.MaxRelated)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to find related articles: %v", err)), nil
+		}
+
+		for _, relatedArticle := range relatedArticles {
+			related = append(related, s.convertArticleWithDetailsToResponse(relatedArticle, true, false, true))
+		}
+	}
+
+	response := ArticleContextResponse{
+		MainArticle:      s.convertArticleWithDetailsToResponse(*article, req.IncludeContent, false, true),
+		RelatedArticles:  related,
+		RelationshipType: req.RelationshipType,
+	}
+
+	if asJSON {
+		return jsonResult(response), nil
+	}
+	return mcp.NewToolResultText(s.formatArticleContextResponse(response)), nil
+}
+
+// stringSliceArgument extracts a string array argument, ignoring non-string items
+func stringSliceArgument(arguments map[string]interface{}, key string) []string {
+	items, ok := arguments[key].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var values []string
+	for _, item := range items {
+		if str, ok := item.(string); ok && str != "" {
+			values = append(values, str)
+		}
+	}
+
+	return values
+}
+
+// handleGetRandomArticles handles the get_random_articles tool
+func (s *Server) handleGetRandomArticles(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	count := 5
+	if c, ok := arguments["count"].(float64); ok {
+		count = int(c)
+	}
+	if count < 1 || count > 50 {
+		return mcp.NewToolResultError("count must be between 1 and 50"), nil
+	}
+
+	unread, _ := arguments["unread"].(bool)
+	statuses := stringSliceArgument(arguments, "statuses")
+	if err := db.ValidateStatuses(statuses...); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	asJSON, err := wantJSON(arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	results, err := s.search.Random(ctx, search.RandomOptions{
+		Count:    count,
+		Tags:     stringSliceArgument(arguments, "tags"),
+		Folders:  stringSliceArgument(arguments, "folders"),
+		Unread:   unread,
+		Statuses: statuses,
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get random articles: %v", err)), nil
+	}
+
+	if asJSON {
+		return jsonResult(s.articlesResponse(results)), nil
+	}
+
+	if len(results) == 0 {
+		return mcp.NewToolResultText("No fetched articles found matching the criteria."), nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("%d random articles from the archive:\n\n", len(results)))
+
+	for i, result := range results {
+		output.WriteString(fmt.Sprintf("**%d. %s**\n", i+1, result.Title))
+		output.WriteString(fmt.Sprintf("ID: %d\n", result.ID))
+		output.WriteString(fmt.Sprintf("URL: %s\n", result.URL))
+
+		if parsedTime, err := time.Parse(time.RFC3339, result.InstapaperedAt); err == nil {
+			output.WriteString(fmt.Sprintf("Saved: %s\n", parsedTime.Format("2006-01-02")))
+		}
+
+		if result.FolderPath != nil && *result.FolderPath != "" {
+			output.WriteString(fmt.Sprintf("Folder: %s\n", *result.FolderPath))
+		}
+
+		if result.Tags != nil && *result.Tags != "" {
+			output.WriteString(fmt.Sprintf("Tags: %s\n", *result.Tags))
+		}
+
+		output.WriteString("\n")
+	}
+
+	output.WriteString("Use get_article with an ID to read one in full.\n")
+
+	return mcp.NewToolResultText(output.String()), nil
+}
+
+// int64SliceArgument extracts an array of integers (sent as JSON numbers) from tool arguments
+func int64SliceArgument(arguments map[string]interface{}, key string) []int64 {
+	items, ok := arguments[key].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var values []int64
+	for _, item := range items {
+		if num, ok := item.(float64); ok {
+			values = append(values, int64(num))
+		}
+	}
+
+	return values
+}
+
+// maxFetchIDs caps how many articles a single fetch_articles call may download
+const maxFetchIDs = 20
+
+// handleFetchArticles handles the fetch_articles tool
+func (s *Server) handleFetchArticles(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	ids := int64SliceArgument(arguments, "ids")
+	if len(ids) == 0 {
+		return mcp.NewToolResultError("ids is required and must contain at least one article ID"), nil
+	}
+	if len(ids) > maxFetchIDs {
+		return mcp.NewToolResultError(fmt.Sprintf("At most %d articles can be fetched per call", maxFetchIDs)), nil
+	}
+
+	f := fetcher.New(s.db)
+	result, err := f.FetchArticles(ctx, fetcher.FetchOptions{
+		Order:    "oldest",
+		Limit:    len(ids),
+		Selector: search.Selector{IDs: ids},
+	})
+	if result == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Fetch failed: %v", err)), nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Fetched %d of %d requested articles", result.Fetched, len(ids)))
+	if skipped := len(ids) - result.Candidates; skipped > 0 {
+		output.WriteString(fmt.Sprintf(" (%d already fetched, obsolete, or waiting for retry)", skipped))
+	}
+	output.WriteString(".\n")
+
+	for _, itemErr := range result.Errors {
+		output.WriteString(fmt.Sprintf("- Article %d failed: %s\n", itemErr.ID, itemErr.Error))
+	}
+
+	if err != nil {
+		output.WriteString(fmt.Sprintf("Fetch interrupted: %v\n", err))
+	}
+
+	return mcp.NewToolResultText(output.String()), nil
+}
+
+// handleTagArticles handles the tag_articles tool
+func (s *Server) handleTagArticles(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	ids := int64SliceArgument(arguments, "ids")
+	add := util.DedupeStrings(stringSliceArgument(arguments, "add"))
+	remove := util.DedupeStrings(stringSliceArgument(arguments, "remove"))
+
+	if len(ids) == 0 {
+		return mcp.NewToolResultError("ids is required and must contain at least one article ID"), nil
+	}
+	if len(add) == 0 && len(remove) == 0 {
+		return mcp.NewToolResultError("At least one tag to add or remove is required"), nil
+	}
+
+	var updated int
+	var output strings.Builder
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Tagging interrupted after %d articles: %v", updated, err)), nil
+		}
+
+		var exists bool
+		if err := s.db.GetContext(ctx, &exists, "SELECT EXISTS(SELECT 1 FROM articles WHERE id = ? AND obsolete = FALSE)", id); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to look up article %d: %v", id, err)), nil
+		}
+		if !exists {
+			output.WriteString(fmt.Sprintf("- Article %d not found, skipped\n", id))
+			continue
+		}
+
+		if err := s.db.AddArticleTags(id, add); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to tag article %d: %v", id, err)), nil
+		}
+		if err := s.db.RemoveArticleTags(id, remove); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to untag article %d: %v", id, err)), nil
+		}
+		updated++
+	}
+
+	summary := fmt.Sprintf("Updated tags on %d of %d articles", updated, len(ids))
+	if len(add) > 0 {
+		summary += fmt.Sprintf("; added: %s", strings.Join(add, ", "))
+	}
+	if len(remove) > 0 {
+		summary += fmt.Sprintf("; removed: %s", strings.Join(remove, ", "))
+	}
+
+	return mcp.NewToolResultText(summary + ".\n" + output.String()), nil
+}
+
+// handleSetStatus handles the set_status tool
+func (s *Server) handleSetStatus(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	ids := int64SliceArgument(arguments, "ids")
+	status, _ := arguments["status"].(string)
+
+	if len(ids) == 0 {
+		return mcp.NewToolResultError("ids is required and must contain at least one article ID"), nil
+	}
+	if err := db.ValidateStatuses(status); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var found []int64
+	var output strings.Builder
+	for _, id := range ids {
+		var exists bool
+		if err := s.db.GetContext(ctx, &exists, "SELECT EXISTS(SELECT 1 FROM articles WHERE id = ? AND obsolete = FALSE)", id); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to look up article %d: %v", id, err)), nil
+		}
+		if !exists {
+			output.WriteString(fmt.Sprintf("- Article %d not found, skipped\n", id))
+			continue
+		}
+		found = append(found, id)
+	}
+
+	operationID, err := s.db.BeginOperation(db.OperationStatus, fmt.Sprintf("set_status %s (MCP)", status))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	updated, err := s.db.SetArticlesStatus(found, status, operationID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to set status after %d articles: %v", updated, err)), nil
+	}
+
+	summary := fmt.Sprintf("Set %d of %d articles to %s; undo with operation %d", updated, len(ids), status, operationID)
+	return mcp.NewToolResultText(summary + ".\n" + output.String()), nil
+}
+
 // handleGetUsageExamples provides examples of how to handle common requests
-func (s *Server) handleGetUsageExamples(arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+func (s *Server) handleGetUsageExamples(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
 	examples := `# Common Request Patterns and Tool Usage
 
 ## Search with Time Filters
@@ -537,10 +1168,48 @@ Common date filters to use:
 - Use **search_articles** when user mentions specific topics/keywords + time
 - Use **get_latest_articles** when user just wants recent articles by time without topics
 
+## Complex Queries and Related Articles
+
+**User Request: "Articles tagged both golang and performance in my tech folder, oldest first"**
+Tool: advanced_search
+Parameters:
+- tags: ["golang", "performance"]
+- folders: ["tech"]
+- sort_by: "instapapered_at"
+- sort_order: "asc"
+
+**User Request: "What else have I saved that's like article 123?"**
+Tool: get_article_context
+Parameters:
+- id: 123
+- relationship_type: "tags"
+
+## Reading Trends
+
+**User Request: "How has my reading changed over the last year?"**
+Tool: get_timeline
+Parameters:
+- since: "1y"
+
+**User Request: "Which topics did I save most each year?"**
+Tool: get_timeline
+Parameters:
+- granularity: "year"
+- top_tags: 3
+
+## RSS Subscriptions
+
+**User Request: "Subscribe me to that blog and pull in its posts"**
+1. list_feeds to check it is not subscribed yet
+2. add_feed with the blog's feed URL (not its home page), e.g. url: "https://example.com/feed.xml"
+3. sync_feeds with ids: [the new feed's ID]
+add_feed and sync_feeds are only available when the user grants the feeds capability.
+
 ## Content vs Metadata
 
 - Most searches return metadata (title, URL, date, tags)
 - Use **get_article** with specific ID to get full article content
+- For long articles, call **get_article** with summary=true for an outline, then read parts with max_words and continuation
 - Set only_synced=true to only return articles with downloaded content
 
 ## Examples in Context
@@ -552,4 +1221,442 @@ Common date filters to use:
 "Show me Node.js articles from this year" → search_articles(query="node.js", since="1y")`
 
 	return mcp.NewToolResultText(examples), nil
-}
\ No newline at end of file
+}
+
+// defaultExportFilesLimit caps how many search results export_to_files writes when no limit is given
+const defaultExportFilesLimit = 100
+
+// handleExportToFiles handles the export_to_files tool
+func (s *Server) handleExportToFiles(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	directory, _ := arguments["directory"].(string)
+	query, _ := arguments["query"].(string)
+	folder, _ := arguments["folder"].(string)
+	tag, _ := arguments["tag"].(string)
+	since, _ := arguments["since"].(string)
+	until, _ := arguments["until"].(string)
+
+	limit := defaultExportFilesLimit
+	if l, ok := arguments["limit"].(float64); ok && l > 0 {
+		limit = int(l)
+	}
+
+	if query != "" && (folder != "" || tag != "" || since != "" || until != "") {
+		return mcp.NewToolResultError("query cannot be combined with folder, tag, since, or until"), nil
+	}
+
+	target, err := s.exportDirectory(directory)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	opts := export.ExportAllOptions{
+		Directory:   target,
+		OnlySynced:  true,
+		FromSearch:  query,
+		SearchFTS:   true,
+		SearchLimit: limit,
+		Quiet:       true,
+	}
+
+	if query == "" {
+		sinceTime, untilTime, err := util.FormatDateRange(since, until)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if sinceTime != nil {
+			opts.Since = sinceTime.Format(time.RFC3339)
+		}
+		if untilTime != nil {
+			opts.Until = untilTime.Format(time.RFC3339)
+		}
+		opts.FolderFilter = folder
+		opts.TagFilter = tag
+	}
+
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to create export directory: %v", err)), nil
+	}
+
+	result, err := s.export.ExportAll(ctx, opts)
+	if result == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Export failed: %v", err)), nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Exported %d of %d matching articles to %s", result.Exported, result.Matched, result.Directory))
+	if result.Skipped > 0 {
+		output.WriteString(fmt.Sprintf(" (%d skipped without content)", result.Skipped))
+	}
+	output.WriteString(".\n")
+
+	for _, file := range result.Files {
+		output.WriteString("- " + file + "\n")
+	}
+	for _, itemErr := range result.Errors {
+		output.WriteString(fmt.Sprintf("- Article %d failed: %s\n", itemErr.ID, itemErr.Error))
+	}
+
+	if err != nil {
+		output.WriteString(fmt.Sprintf("Export interrupted: %v\n", err))
+	}
+
+	return mcp.NewToolResultText(output.String()), nil
+}
+
+// exportDirectory resolves a client-supplied subdirectory against the export root,
+// rejecting absolute paths and anything that would escape the root
+func (s *Server) exportDirectory(directory string) (string, error) {
+	root, err := filepath.Abs(s.exportRoot)
+	if err != nil {
+		return "", fmt.Errorf("invalid export root: %v", err)
+	}
+
+	if directory == "" {
+		return root, nil
+	}
+	if filepath.IsAbs(directory) {
+		return "", fmt.Errorf("directory must be relative to the export root")
+	}
+
+	target := filepath.Join(root, directory)
+	if !export.IsWithin(root, target) {
+		return "", fmt.Errorf("directory %q is outside the export root", directory)
+	}
+	return target, nil
+}
+
+// handleGetTimeline handles the get_timeline tool
+func (s *Server) handleGetTimeline(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	granularity, _ := arguments["granularity"].(string)
+	since, _ := arguments["since"].(string)
+	until, _ := arguments["until"].(string)
+
+	// Period keys are prefixes of the RFC 3339 timestamps: 2006-01 or 2006
+	var keyLength int
+	switch granularity {
+	case "", "month":
+		granularity, keyLength = "month", 7
+	case "year":
+		keyLength = 4
+	default:
+		return mcp.NewToolResultError("granularity must be month or year"), nil
+	}
+
+	topTags := 5
+	if t, ok := arguments["top_tags"].(float64); ok {
+		topTags = int(t)
+	}
+	if topTags < 0 || topTags > 50 {
+		return mcp.NewToolResultError("top_tags must be between 0 and 50"), nil
+	}
+
+	sinceTime, untilTime, err := util.FormatDateRange(since, until)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	response := TimelineResponse{Granularity: granularity, Periods: []TimelinePeriod{}}
+	rangeCondition := func(column string) (string, []interface{}) {
+		var condition string
+		var args []interface{}
+		if sinceTime != nil {
+			response.Since = sinceTime.Format(time.RFC3339)
+			condition += fmt.Sprintf(" AND %s >= ?", column)
+			args = append(args, response.Since)
+		}
+		if untilTime != nil {
+			response.Until = untilTime.Format(time.RFC3339)
+			condition += fmt.Sprintf(" AND %s <= ?", column)
+			args = append(args, response.Until)
+		}
+		return condition, args
+	}
+
+	type periodCount struct {
+		Period string `db:"period"`
+		Count  int    `db:"count"`
+	}
+
+	periods := make(map[string]*TimelinePeriod)
+	period := func(key string) *TimelinePeriod {
+		if periods[key] == nil {
+			periods[key] = &TimelinePeriod{Period: key, TopTags: []TagCount{}}
+		}
+		return periods[key]
+	}
+
+	condition, args := rangeCondition("instapapered_at")
+	var saved []periodCount
+	if err := s.db.SelectContext(ctx, &saved, fmt.Sprintf(`
+		SELECT substr(instapapered_at, 1, %d) AS period, COUNT(*) AS count
+		FROM articles
+		WHERE obsolete = FALSE AND instapapered_at IS NOT NULL%s
+		GROUP BY period
+	`, keyLength, condition), args...); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to count saved articles: %v", err)), nil
+	}
+	for _, row := range saved {
+		period(row.Period).Saved = row.Count
+		response.TotalSaved += row.Count
+	}
+
+	condition, args = rangeCondition("synced_at")
+	var fetched []periodCount
+	if err := s.db.SelectContext(ctx, &fetched, fmt.Sprintf(`
+		SELECT substr(synced_at, 1, %d) AS period, COUNT(*) AS count
+		FROM articles
+		WHERE obsolete = FALSE AND synced_at IS NOT NULL%s
+		GROUP BY period
+	`, keyLength, condition), args...); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to count fetched articles: %v", err)), nil
+	}
+	for _, row := range fetched {
+		period(row.Period).Fetched = row.Count
+		response.TotalFetched += row.Count
+	}
+
+	if topTags > 0 {
+		condition, args = rangeCondition("a.instapapered_at")
+		var tagged []struct {
+			periodCount
+			Tag string `db:"tag"`
+		}
+		if err := s.db.SelectContext(ctx, &tagged, fmt.Sprintf(`
+			SELECT substr(a.instapapered_at, 1, %d) AS period, t.title AS tag, COUNT(*) AS count
+			FROM articles a
+			JOIN article_tags at ON at.article_id = a.id
+			JOIN tags t ON t.id = at.tag_id
+			WHERE a.obsolete = FALSE AND a.instapapered_at IS NOT NULL%s
+			GROUP BY period, t.title
+			ORDER BY period, count DESC, t.title
+		`, keyLength, condition), args...); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to count tags: %v", err)), nil
+		}
+		for _, row := range tagged {
+			if p := period(row.Period); len(p.TopTags) < topTags {
+				p.TopTags = append(p.TopTags, TagCount{Tag: row.Tag, Count: row.Count})
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(periods))
+	for key := range periods {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Quiet periods are listed with zero counts so the series can be charted as is
+	if len(keys) > 0 {
+		layout := map[string]string{"month": "2006-01", "year": "2006"}[granularity]
+		first, errFirst := time.Parse(layout, keys[0])
+		last, errLast := time.Parse(layout, keys[len(keys)-1])
+		if errFirst == nil && errLast == nil {
+			keys = keys[:0]
+			for t := first; !t.After(last); {
+				keys = append(keys, t.Format(layout))
+				if granularity == "year" {
+					t = t.AddDate(1, 0, 0)
+				} else {
+					t = t.AddDate(0, 1, 0)
+				}
+			}
+		}
+	}
+
+	for _, key := range keys {
+		response.Periods = append(response.Periods, *period(key))
+	}
+
+	data, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to encode timeline: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// handleGetReadingGoals handles the get_reading_goals tool
+func (s *Server) handleGetReadingGoals(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	progress, err := s.db.GoalProgress(time.Now())
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get reading goals: %v", err)), nil
+	}
+	if len(progress) == 0 {
+		return mcp.NewToolResultText("No reading goals are set. The user can set one with: instapaper-cli goals set articles 5 --per week"), nil
+	}
+
+	var output strings.Builder
+	output.WriteString("Reading goals (counted from articles marked read):\n\n")
+	for _, p := range progress {
+		output.WriteString(fmt.Sprintf("- %s (%s to %s)\n", p.Summary(), p.PeriodStart, p.PeriodEnd))
+	}
+
+	counts, err := s.db.StatusCounts()
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	parts := make([]string, len(counts))
+	for i, count := range counts {
+		parts[i] = fmt.Sprintf("%s %d", count.Status, count.Count)
+	}
+	output.WriteString("\nArticles by status: " + strings.Join(parts, ", ") + "\n")
+	output.WriteString("Use search_articles with statuses ['reading'] or ['inbox'] to suggest what to read next.\n")
+
+	return mcp.NewToolResultText(output.String()), nil
+}
+
+// handleListFeeds handles the list_feeds tool
+func (s *Server) handleListFeeds(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	asJSON, err := wantJSON(arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	feeds, err := s.db.GetRSSFeeds()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get RSS feeds: %v", err)), nil
+	}
+
+	if asJSON {
+		if feeds == nil {
+			feeds = []map[string]interface{}{}
+		}
+		return jsonResult(feeds), nil
+	}
+	if len(feeds) == 0 {
+		return mcp.NewToolResultText("No RSS feeds are subscribed."), nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Found %d feeds:\n\n", len(feeds)))
+
+	for _, feed := range feeds {
+		output.WriteString(fmt.Sprintf("**%s** (ID: %d)\n", feed["name"], feed["id"]))
+		output.WriteString(fmt.Sprintf("  URL: %s\n", feed["url"]))
+		if tags, ok := feed["tags"].(string); ok {
+			output.WriteString(fmt.Sprintf("  Tags: %s\n", tags))
+		}
+		if syncedAt, ok := feed["last_synced_at"].(string); ok {
+			output.WriteString(fmt.Sprintf("  Last synced: %s\n", syncedAt))
+		} else {
+			output.WriteString("  Last synced: never\n")
+		}
+		if active, ok := feed["active"].(bool); ok && !active {
+			output.WriteString("  Inactive: not synced unless requested by ID\n")
+		}
+	}
+
+	return mcp.NewToolResultText(output.String()), nil
+}
+
+// handleAddFeed handles the add_feed tool
+func (s *Server) handleAddFeed(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	feedURL, _ := arguments["url"].(string)
+	feedURL = strings.TrimSpace(feedURL)
+	name, _ := arguments["name"].(string)
+	tags := util.DedupeStrings(stringSliceArgument(arguments, "tags"))
+	useContent, _ := arguments["use_content"].(bool)
+	backfill, _ := arguments["backfill"].(string)
+
+	if feedURL == "" {
+		return mcp.NewToolResultError("url is required"), nil
+	}
+	if backfill == "" {
+		backfill = rss.BackfillAll
+	}
+	if err := rss.ValidateBackfill(backfill); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	feeds, err := s.db.GetRSSFeeds()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get RSS feeds: %v", err)), nil
+	}
+	for _, feed := range feeds {
+		if feed["url"] == feedURL {
+			return mcp.NewToolResultText(fmt.Sprintf("Already subscribed to %s as %q (ID: %d).", feedURL, feed["name"], feed["id"])), nil
+		}
+	}
+
+	// Reading the feed checks that the URL is one, and gives it a default name
+	parsed, err := rss.ParseRSSFeed(feedURL)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Not a readable RSS feed: %v. Pass the URL of the feed itself, not of the blog's home page.", err)), nil
+	}
+	if name = strings.TrimSpace(name); name == "" {
+		name = strings.TrimSpace(parsed.Channel.Title)
+	}
+	if name == "" {
+		name = feedURL
+	}
+
+	feedID, err := s.db.AddRSSFeed(feedURL, name, tags, useContent, backfill)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to add RSS feed: %v", err)), nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Subscribed to %q (ID: %d), which has %d items.\n", name, feedID, len(parsed.Channel.Items)))
+	if len(tags) > 0 {
+		output.WriteString(fmt.Sprintf("Its articles are tagged: %s\n", strings.Join(tags, ", ")))
+	}
+	switch backfill {
+	case rss.BackfillAll:
+	case rss.BackfillNone:
+		output.WriteString("The first sync skips the feed's current items.\n")
+	default:
+		output.WriteString(fmt.Sprintf("The first sync skips items older than %s.\n", backfill))
+	}
+	output.WriteString(fmt.Sprintf("Call sync_feeds with ids [%d] to save its posts.\n", feedID))
+
+	return mcp.NewToolResultText(output.String()), nil
+}
+
+// handleSyncFeeds handles the sync_feeds tool
+func (s *Server) handleSyncFeeds(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	ids := int64SliceArgument(arguments, "ids")
+
+	if len(ids) == 0 {
+		feeds, err := s.db.GetRSSFeeds()
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get RSS feeds: %v", err)), nil
+		}
+		for _, feed := range feeds {
+			if active, ok := feed["active"].(bool); ok && active {
+				ids = append(ids, feed["id"].(int64))
+			}
+		}
+		if len(ids) == 0 {
+			return mcp.NewToolResultText("No active RSS feeds to sync. Subscribe to one with add_feed."), nil
+		}
+	}
+
+	var synced, total int
+	var output strings.Builder
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			output.WriteString(fmt.Sprintf("Sync interrupted: %v\n", err))
+			break
+		}
+
+		feed, tags, err := rss.LoadFeed(s.db, id)
+		if err != nil {
+			output.WriteString(fmt.Sprintf("- Feed %d skipped: %v\n", id, err))
+			continue
+		}
+
+		added, err := rss.SyncFeed(s.db, feed, tags, s.rules, fetcher.FetchOptions{})
+		total += added
+		if err != nil {
+			output.WriteString(fmt.Sprintf("- %s (ID: %d) failed after %d new articles: %v\n", feed.Name, id, added, err))
+			continue
+		}
+		synced++
+		output.WriteString(fmt.Sprintf("- %s (ID: %d): %d new articles\n", feed.Name, id, added))
+	}
+
+	summary := fmt.Sprintf("Synced %d of %d feeds; %d new articles.\n", synced, len(ids), total)
+	if total > 0 {
+		summary += "New articles are listed by get_latest_articles; their content is downloaded when they are fetched.\n"
+	}
+	return mcp.NewToolResultText(summary + output.String()), nil
+}
@@ -0,0 +1,145 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Capability is a permission a tool needs before the server exposes it
+type Capability string
+
+const (
+	// CapRead covers tools that only query the archive; it is always granted
+	CapRead Capability = "read"
+	// CapFetch allows downloading article content from the web
+	CapFetch Capability = "fetch"
+	// CapTags allows adding and removing article tags
+	CapTags Capability = "tags"
+	// CapStatus allows changing the reading status of articles
+	CapStatus Capability = "status"
+	// CapExport allows writing markdown files below the configured export root
+	CapExport Capability = "export"
+	// CapFeeds allows subscribing to RSS feeds and syncing them
+	CapFeeds Capability = "feeds"
+)
+
+// writeLockWait is how long a tool that changes the database waits for another writer
+const writeLockWait = 30 * time.Second
+
+// writeCapabilities lists the capabilities that can be granted with --allow
+var writeCapabilities = []Capability{CapFetch, CapTags, CapStatus, CapExport, CapFeeds}
+
+// Permissions is the set of capabilities granted to MCP clients
+type Permissions struct {
+	allowed map[Capability]bool
+}
+
+// ReadOnly returns permissions that only allow read tools
+func ReadOnly() Permissions {
+	return Permissions{allowed: map[Capability]bool{CapRead: true}}
+}
+
+// ParsePermissions builds permissions from the --read-only and --allow flags.
+// Without --allow the server is read-only unless readOnly is false, which grants every capability.
+func ParsePermissions(readOnly bool, readOnlySet bool, allow []string) (Permissions, error) {
+	perms := ReadOnly()
+
+	if len(allow) > 0 && readOnlySet && readOnly {
+		return perms, fmt.Errorf("--read-only cannot be combined with --allow")
+	}
+
+	if len(allow) == 0 {
+		if !readOnly {
+			for _, capability := range writeCapabilities {
+				perms.allowed[capability] = true
+			}
+		}
+		return perms, nil
+	}
+
+	for _, name := range allow {
+		capability := Capability(strings.ToLower(strings.TrimSpace(name)))
+		if capability == CapRead {
+			continue
+		}
+		if !isWriteCapability(capability) {
+			return perms, fmt.Errorf("unknown capability %q: must be one of %s", name, joinCapabilities(writeCapabilities))
+		}
+		perms.allowed[capability] = true
+	}
+
+	return perms, nil
+}
+
+// Allows reports whether a capability is granted
+func (p Permissions) Allows(capability Capability) bool {
+	return p.allowed[capability]
+}
+
+// String lists the granted capabilities, e.g. "fetch, read"
+func (p Permissions) String() string {
+	var granted []Capability
+	for capability, ok := range p.allowed {
+		if ok {
+			granted = append(granted, capability)
+		}
+	}
+	sort.Slice(granted, func(i, j int) bool { return granted[i] < granted[j] })
+	return joinCapabilities(granted)
+}
+
+func isWriteCapability(capability Capability) bool {
+	for _, c := range writeCapabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+func joinCapabilities(capabilities []Capability) string {
+	names := make([]string, len(capabilities))
+	for i, c := range capabilities {
+		names[i] = string(c)
+	}
+	return strings.Join(names, ", ")
+}
+
+// toolHandler is a context-aware tool handler
+type toolHandler func(context.Context, map[string]interface{}) (*mcp.CallToolResult, error)
+
+// addTool registers a tool that declares the capability it needs. Tools whose
+// capability is not granted are not listed to clients at all.
+func (s *Server) addTool(capability Capability, tool mcp.Tool, handler toolHandler) {
+	if !s.permissions.Allows(capability) {
+		return
+	}
+
+	s.mcpServer.AddTool(tool, s.traced(tool.Name, s.withContext(capability, handler)))
+}
+
+// withContext adapts a context-aware tool handler to the mcp-go handler signature,
+// re-checking the tool's capability on every call. Tools that change the database
+// take the single-writer lock for the duration of the call.
+func (s *Server) withContext(capability Capability, handler toolHandler) server.ToolHandlerFunc {
+	return func(arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+		if !s.permissions.Allows(capability) {
+			return mcp.NewToolResultError(fmt.Sprintf("Permission denied: this tool requires the %q capability", capability)), nil
+		}
+
+		if capability == CapFetch || capability == CapTags || capability == CapStatus || capability == CapFeeds {
+			lock, err := s.db.LockWrites(s.ctx, "mcp", writeLockWait)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			defer lock.Release()
+		}
+		return handler(s.ctx, arguments)
+	}
+}
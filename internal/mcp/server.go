@@ -1,11 +1,16 @@
 package mcp
 
 import (
+	"context"
+	"log"
+	"os"
+
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"instapaper-cli/internal/db"
 	"instapaper-cli/internal/export"
 	"instapaper-cli/internal/search"
+	"instapaper-cli/internal/tagging"
 	"instapaper-cli/internal/version"
 )
 
@@ -15,14 +20,27 @@ type Server struct {
 	search   *search.Search
 	export   *export.Export
 	mcpServer *server.MCPServer
+	permissions Permissions
+	// exportRoot is the only directory export_to_files may write below; empty disables the tool
+	exportRoot string
+	// rules tag and file the articles sync_feeds adds
+	rules *tagging.Rules
+	// requestLog records tool calls for debugging; nil logs nothing
+	requestLog *RequestLog
+	// ctx is the serving context; mcp-go tool handlers do not receive one
+	ctx context.Context
 }
 
-// NewServer creates a new MCP server instance
-func NewServer(database *db.DB) *Server {
+// NewServer creates a new MCP server instance exposing the tools allowed by permissions.
+// exportRoot is the directory export_to_files writes below; leave it empty to disable file exports.
+func NewServer(database *db.DB, permissions Permissions, exportRoot string) *Server {
 	s := &Server{
-		db:     database,
-		search: search.New(database),
-		export: export.New(database),
+		db:          database,
+		search:      search.New(database),
+		export:      export.New(database),
+		permissions: permissions,
+		exportRoot:  exportRoot,
+		ctx:         context.Background(),
 	}
 
 	// Create MCP server
@@ -35,37 +53,68 @@ func NewServer(database *db.DB) *Server {
 	return s
 }
 
-// Start starts the MCP server using stdio
-func (s *Server) Start() error {
-	return server.ServeStdio(s.mcpServer)
+// WithTagRules applies tag rules to the articles that feed syncs add
+func (s *Server) WithTagRules(rules *tagging.Rules) *Server {
+	s.rules = rules
+	return s
+}
+
+// Start serves MCP over stdio until ctx is cancelled or stdin is closed.
+// Tool calls in flight when ctx is cancelled are aborted.
+func (s *Server) Start(ctx context.Context) error {
+	s.ctx = ctx
+
+	stdio := server.NewStdioServer(s.mcpServer)
+	stdio.SetErrorLogger(log.New(os.Stderr, "", log.LstdFlags))
+
+	if err := stdio.Listen(ctx, os.Stdin, os.Stdout); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// formatProperty is the format argument of the tools that can return structured JSON
+var formatProperty = map[string]interface{}{
+	"type":        "string",
+	"description": "Result format (default: markdown). Use json for structured results with the same fields as the markdown, for programmatic clients.",
+	"enum":        []string{formatMarkdown, formatJSON},
 }
 
 // registerTools registers all available MCP tools
 func (s *Server) registerTools() {
 	// Search articles tool
-	s.mcpServer.AddTool(mcp.Tool{
+	s.addTool(CapRead, mcp.Tool{
 		Name:        "search_articles",
 		Description: "Search articles with various filters including full-text search (default), date ranges, tags, and folders. Multiple keywords in query are treated as intersection (AND). For requests like 'kubernetes articles from last week' use query='kubernetes' and since='1w'. For 'AI articles from today' use query='AI' and since='today'.",
 		InputSchema: mcp.ToolInputSchema{
 			Type: "object",
 			Properties: map[string]interface{}{
+				"format": formatProperty,
 				"query": map[string]interface{}{
 					"type":        "string",
 					"description": "Search query text. Multiple keywords will be treated as AND (intersection). Use full-text search for better results. Examples: 'kubernetes', 'machine learning', 'docker containers'.",
 				},
 				"field": map[string]interface{}{
 					"type":        "string",
-					"description": "Specific field to search: url, title, content, tags, folder",
-					"enum":        []string{"url", "title", "content", "tags", "folder"},
+					"description": "Specific field to search: url, title, content, selection, tags, folder",
+					"enum":        []string{"url", "title", "content", "selection", "tags", "folder"},
 				},
 				"use_fts": map[string]interface{}{
 					"type":        "boolean",
 					"description": "Use full-text search (default: true). FTS is faster, more accurate, and supports intersection queries. Set to false to use LIKE search instead.",
 				},
+				"fuzzy": map[string]interface{}{
+					"type":        "boolean",
+					"description": "When full-text search finds nothing, retry with misspelled terms corrected to the closest indexed words (default: true). The result header reports the strategy and query used.",
+				},
 				"limit": map[string]interface{}{
 					"type":        "integer",
 					"description": "Maximum number of results to return (default: 50)",
 				},
+				"facets": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Also summarize all matches (not only the returned ones) by tag, folder, year, and domain, showing the top 10 values of each. Useful for drill-down and for describing where matches concentrate.",
+				},
 				"tags": map[string]interface{}{
 					"type":        "array",
 					"description": "Filter by specific tags",
@@ -80,6 +129,21 @@ func (s *Server) registerTools() {
 						"type": "string",
 					},
 				},
+				"statuses": map[string]interface{}{
+					"type":        "array",
+					"description": "Only articles with any of these reading statuses, e.g. ['reading'] for what the user is reading now. Works without a query.",
+					"items": map[string]interface{}{
+						"type": "string",
+						"enum": db.Statuses,
+					},
+				},
+				"languages": map[string]interface{}{
+					"type":        "array",
+					"description": "Only articles in these languages, as codes like 'en', 'de', or 'ja'. 'auto' restricts to the language of the query, which helps recall in mixed-language libraries.",
+					"items": map[string]interface{}{
+						"type": "string",
+					},
+				},
 				"since": map[string]interface{}{
 					"type":        "string",
 					"description": "Filter articles since date. Common values: '1d' (last day), '1w' (last week), '1m' (last month), 'today', 'yesterday'. Also supports absolute dates like '2024-01-15' or ISO 8601 format.",
@@ -105,12 +169,13 @@ func (s *Server) registerTools() {
 	}, s.handleSearchArticles)
 
 	// Get single article tool
-	s.mcpServer.AddTool(mcp.Tool{
+	s.addTool(CapRead, mcp.Tool{
 		Name:        "get_article",
-		Description: "Get a single article by ID with full content and metadata",
+		Description: "Get a single article by ID with full content and metadata, or a long article part by part or as an outline",
 		InputSchema: mcp.ToolInputSchema{
 			Type: "object",
 			Properties: map[string]interface{}{
+				"format": formatProperty,
 				"id": map[string]interface{}{
 					"type":        "integer",
 					"description": "Article ID",
@@ -127,28 +192,84 @@ func (s *Server) registerTools() {
 					"type":        "boolean",
 					"description": "Include tags array (default: true)",
 				},
+				"max_words": map[string]interface{}{
+					"type":        "integer",
+					"description": "Return long content in parts of about this many words (at least 200), split between paragraphs. Each part ends with a continuation token for the next one.",
+				},
+				"continuation": map[string]interface{}{
+					"type":        "string",
+					"description": "Continuation token from a previous get_article call, to read the next part (or, from a summary, any part) of the article",
+				},
+				"include_outline": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Include the article's table of contents: its headings, nested by level, with the paragraph each starts at",
+				},
+				"summary": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Instead of the content, return its length, lead paragraph, and an outline of its parts with their section headings, openings, and continuation tokens. Useful to decide what to read of a long article.",
+				},
 			},
 			Required: []string{"id"},
 		},
 	}, s.handleGetArticle)
 
+	// Search within article tool
+	s.addTool(CapRead, mcp.Tool{
+		Name:        "search_within_article",
+		Description: "Find the passages of one article that contain some text, with surrounding paragraphs for context. Use it to locate a quote or claim without reading the whole article.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"format": formatProperty,
+				"id": map[string]interface{}{
+					"type":        "integer",
+					"description": "Article ID",
+				},
+				"pattern": map[string]interface{}{
+					"type":        "string",
+					"description": "Text to find, matched ignoring case unless case_sensitive is set",
+				},
+				"regex": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Treat the pattern as a regular expression (Go RE2 syntax)",
+				},
+				"case_sensitive": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Match case exactly (default: false)",
+				},
+				"context": map[string]interface{}{
+					"type":        "integer",
+					"description": "Paragraphs to include before and after each matching one (default: 1)",
+				},
+				"max_passages": map[string]interface{}{
+					"type":        "integer",
+					"description": "Most passages to return (default: 10)",
+				},
+			},
+			Required: []string{"id", "pattern"},
+		},
+	}, s.handleSearchWithinArticle)
+
 	// List folders tool
-	s.mcpServer.AddTool(mcp.Tool{
+	s.addTool(CapRead, mcp.Tool{
 		Name:        "list_folders",
 		Description: "Get all available folders with article counts",
 		InputSchema: mcp.ToolInputSchema{
-			Type:       "object",
-			Properties: map[string]interface{}{},
+			Type: "object",
+			Properties: map[string]interface{}{
+				"format": formatProperty,
+			},
 		},
 	}, s.handleListFolders)
 
 	// List tags tool
-	s.mcpServer.AddTool(mcp.Tool{
+	s.addTool(CapRead, mcp.Tool{
 		Name:        "list_tags",
 		Description: "Get all available tags with article counts",
 		InputSchema: mcp.ToolInputSchema{
 			Type: "object",
 			Properties: map[string]interface{}{
+				"format": formatProperty,
 				"min_count": map[string]interface{}{
 					"type":        "integer",
 					"description": "Only include tags with at least this many articles",
@@ -158,7 +279,7 @@ func (s *Server) registerTools() {
 	}, s.handleListTags)
 
 	// Export articles tool
-	s.mcpServer.AddTool(mcp.Tool{
+	s.addTool(CapRead, mcp.Tool{
 		Name:        "export_articles",
 		Description: "Export articles to markdown format with filtering options. Returns content directly for AI consumption.",
 		InputSchema: mcp.ToolInputSchema{
@@ -188,12 +309,13 @@ func (s *Server) registerTools() {
 	}, s.handleExportArticles)
 
 	// Get latest articles tool
-	s.mcpServer.AddTool(mcp.Tool{
+	s.addTool(CapRead, mcp.Tool{
 		Name:        "get_latest_articles",
 		Description: "Get the most recent articles with optional date filtering. Perfect for requests like 'show me recent articles', 'what did I save last week', or 'articles from today'. Use this when no search query is needed, just recent articles by date.",
 		InputSchema: mcp.ToolInputSchema{
 			Type: "object",
 			Properties: map[string]interface{}{
+				"format": formatProperty,
 				"limit": map[string]interface{}{
 					"type":        "integer",
 					"description": "Maximum number of articles to return (default: 20)",
@@ -210,12 +332,400 @@ func (s *Server) registerTools() {
 					"type":        "boolean",
 					"description": "Only return articles that have content downloaded (default: false)",
 				},
+				"by": map[string]interface{}{
+					"type":        "string",
+					"description": "Date to order and filter by (default: added). 'added' is when the article was saved, 'fetched' when its content was downloaded, 'published' the publication date found on the page.",
+					"enum":        []string{"added", "fetched", "published"},
+				},
 			},
 		},
 	}, s.handleGetLatestArticles)
 
+	// Advanced search tool
+	s.addTool(CapRead, mcp.Tool{
+		Name:        "advanced_search",
+		Description: "Search articles with multiple combined conditions: per-field text matching, tags that must ALL match, tags where ANY may match, folders, date range, and custom sorting. Use this when search_articles is not expressive enough, e.g. 'articles tagged both golang and performance in the tech folder, oldest first'.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"format": formatProperty,
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "General search query across url, title, content, selection, tags, and folder",
+				},
+				"title_contains": map[string]interface{}{
+					"type":        "string",
+					"description": "Only include articles whose title contains this text",
+				},
+				"content_contains": map[string]interface{}{
+					"type":        "string",
+					"description": "Only include articles whose content contains this text",
+				},
+				"url_contains": map[string]interface{}{
+					"type":        "string",
+					"description": "Only include articles whose URL contains this text",
+				},
+				"tags": map[string]interface{}{
+					"type":        "array",
+					"description": "Articles must have ALL of these tags",
+					"items": map[string]interface{}{
+						"type": "string",
+					},
+				},
+				"any_tags": map[string]interface{}{
+					"type":        "array",
+					"description": "Articles must have ANY of these tags",
+					"items": map[string]interface{}{
+						"type": "string",
+					},
+				},
+				"folders": map[string]interface{}{
+					"type":        "array",
+					"description": "Articles must be in ANY of these folders",
+					"items": map[string]interface{}{
+						"type": "string",
+					},
+				},
+				"statuses": map[string]interface{}{
+					"type":        "array",
+					"description": "Articles must have ANY of these reading statuses",
+					"items": map[string]interface{}{
+						"type": "string",
+						"enum": db.Statuses,
+					},
+				},
+				"date_after": map[string]interface{}{
+					"type":        "string",
+					"description": "Only include articles added after this date (ISO 8601 format)",
+				},
+				"date_before": map[string]interface{}{
+					"type":        "string",
+					"description": "Only include articles added before this date (ISO 8601 format)",
+				},
+				"only_synced": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Only return articles that have content downloaded",
+				},
+				"use_fts": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Use full-text search for the general query (default: true)",
+				},
+				"sort_by": map[string]interface{}{
+					"type":        "string",
+					"description": "Field to sort by (default: relevance for FTS, otherwise instapapered_at)",
+					"enum":        []string{"instapapered_at", "title", "url"},
+				},
+				"sort_order": map[string]interface{}{
+					"type":        "string",
+					"description": "Sort direction (default: desc)",
+					"enum":        []string{"asc", "desc"},
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of results to return (default: 50)",
+				},
+			},
+		},
+	}, s.handleAdvancedSearch)
+
+	// Article context tool
+	s.addTool(CapRead, mcp.Tool{
+		Name:        "get_article_context",
+		Description: "Get a single article together with related articles, found by shared folder, shared tags, or content similarity. Useful for 'what else have I saved about this?' questions.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"format": formatProperty,
+				"id": map[string]interface{}{
+					"type":        "integer",
+					"description": "Article ID",
+				},
+				"include_related": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Include related articles (default: true)",
+				},
+				"max_related": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of related articles (default: 5)",
+				},
+				"relationship_type": map[string]interface{}{
+					"type":        "string",
+					"description": "How to find related articles (default: tags)",
+					"enum":        []string{"folder", "tags", "content_similarity"},
+				},
+				"include_content": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Include full markdown content of the main article (default: true)",
+				},
+			},
+			Required: []string{"id"},
+		},
+	}, s.handleGetArticleContext)
+
+	// Random articles tool
+	s.addTool(CapRead, mcp.Tool{
+		Name:        "get_random_articles",
+		Description: "Get random fetched articles from the archive, optionally filtered by tags, folders, or unread status. Use for 'surprise me' or 'what did I save and forget about?' requests.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"format": formatProperty,
+				"count": map[string]interface{}{
+					"type":        "integer",
+					"description": "Number of random articles to return (default: 5, max: 50)",
+				},
+				"tags": map[string]interface{}{
+					"type":        "array",
+					"description": "Only pick articles with any of these tags",
+					"items": map[string]interface{}{
+						"type": "string",
+					},
+				},
+				"folders": map[string]interface{}{
+					"type":        "array",
+					"description": "Only pick articles in these folders",
+					"items": map[string]interface{}{
+						"type": "string",
+					},
+				},
+				"unread": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Only pick articles still in the Unread folder",
+				},
+				"statuses": map[string]interface{}{
+					"type":        "array",
+					"description": "Only pick articles with any of these reading statuses, e.g. ['someday']",
+					"items": map[string]interface{}{
+						"type": "string",
+						"enum": db.Statuses,
+					},
+				},
+			},
+		},
+	}, s.handleGetRandomArticles)
+
+	// Fetch article content tool (requires the fetch capability)
+	s.addTool(CapFetch, mcp.Tool{
+		Name:        "fetch_articles",
+		Description: "Download and store the content of specific articles that have not been fetched yet. Articles already fetched, obsolete, or waiting for a retry are skipped.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"ids": map[string]interface{}{
+					"type":        "array",
+					"description": "Article IDs to fetch (at most 20)",
+					"items": map[string]interface{}{
+						"type": "integer",
+					},
+				},
+			},
+			Required: []string{"ids"},
+		},
+	}, s.handleFetchArticles)
+
+	// Tag articles tool (requires the tags capability)
+	s.addTool(CapTags, mcp.Tool{
+		Name:        "tag_articles",
+		Description: "Add and/or remove tags on one or more articles. Missing tags are created.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"ids": map[string]interface{}{
+					"type":        "array",
+					"description": "Article IDs to update",
+					"items": map[string]interface{}{
+						"type": "integer",
+					},
+				},
+				"add": map[string]interface{}{
+					"type":        "array",
+					"description": "Tags to add",
+					"items": map[string]interface{}{
+						"type": "string",
+					},
+				},
+				"remove": map[string]interface{}{
+					"type":        "array",
+					"description": "Tags to remove",
+					"items": map[string]interface{}{
+						"type": "string",
+					},
+				},
+			},
+			Required: []string{"ids"},
+		},
+	}, s.handleTagArticles)
+
+	// Set reading status tool (requires the status capability)
+	s.addTool(CapStatus, mcp.Tool{
+		Name:        "set_status",
+		Description: "Set the reading status of one or more articles: inbox (new, untriaged), reading (reading now), someday (parked for later), read, or archived. Each call can be undone with the CLI's undo command.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"ids": map[string]interface{}{
+					"type":        "array",
+					"description": "Article IDs to update",
+					"items": map[string]interface{}{
+						"type": "integer",
+					},
+				},
+				"status": map[string]interface{}{
+					"type":        "string",
+					"description": "The new reading status",
+					"enum":        db.Statuses,
+				},
+			},
+			Required: []string{"ids", "status"},
+		},
+	}, s.handleSetStatus)
+
+	// Subscribe to feed tool (requires the feeds capability)
+	s.addTool(CapFeeds, mcp.Tool{
+		Name:        "add_feed",
+		Description: "Subscribe to an RSS feed so its new posts are saved as articles on every sync. The URL must be the feed itself, not the blog's home page. Call sync_feeds afterwards to pull in its posts.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"url": map[string]interface{}{
+					"type":        "string",
+					"description": "URL of the RSS feed",
+				},
+				"name": map[string]interface{}{
+					"type":        "string",
+					"description": "Name of the feed (default: the feed's title)",
+				},
+				"tags": map[string]interface{}{
+					"type":        "array",
+					"description": "Tags to add to every article from the feed",
+					"items": map[string]interface{}{
+						"type": "string",
+					},
+				},
+				"use_content": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Store the content embedded in feed items instead of fetching each article (default: false)",
+				},
+				"backfill": map[string]interface{}{
+					"type":        "string",
+					"description": "How much of the feed's history the first sync saves: 'all' (default), 'none', or an age like '30d'",
+				},
+			},
+			Required: []string{"url"},
+		},
+	}, s.handleAddFeed)
+
+	// Sync feeds tool (requires the feeds capability)
+	s.addTool(CapFeeds, mcp.Tool{
+		Name:        "sync_feeds",
+		Description: "Sync RSS feeds, saving their new posts as articles, and report how many were added per feed. Syncs every active feed unless ids are given. New articles have no content until fetched.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"ids": map[string]interface{}{
+					"type":        "array",
+					"description": "Feed IDs to sync (default: all active feeds)",
+					"items": map[string]interface{}{
+						"type": "integer",
+					},
+				},
+			},
+		},
+	}, s.handleSyncFeeds)
+
+	// Export to files tool (requires the export capability and an export root)
+	if s.exportRoot != "" {
+		s.addTool(CapExport, mcp.Tool{
+			Name:        "export_to_files",
+			Description: "Write matching articles as markdown files to a directory on disk and return the file paths instead of the content. Use this for large exports that would not fit in the conversation. Files are grouped in subdirectories by folder.",
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"directory": map[string]interface{}{
+						"type":        "string",
+						"description": "Subdirectory of the export root to write to (default: the export root itself)",
+					},
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "Full-text search query selecting the articles to export",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of search results to export (only with query, default: 100)",
+					},
+					"folder": map[string]interface{}{
+						"type":        "string",
+						"description": "Only export articles in this folder (title or path)",
+					},
+					"tag": map[string]interface{}{
+						"type":        "string",
+						"description": "Only export articles with this tag",
+					},
+					"since": map[string]interface{}{
+						"type":        "string",
+						"description": "Only export articles saved since this date (1d, 1w, today, 2006-01-02)",
+					},
+					"until": map[string]interface{}{
+						"type":        "string",
+						"description": "Only export articles saved until this date (1d, 1w, today, 2006-01-02)",
+					},
+				},
+			},
+		}, s.handleExportToFiles)
+	}
+
+	// Timeline tool
+	s.addTool(CapRead, mcp.Tool{
+		Name:        "get_timeline",
+		Description: "Get reading trends over time as JSON: per month (or year), how many articles were saved and fetched, and the top tags among the articles saved in that period. Periods without activity are included with zero counts, so the series can be charted directly. Use for 'how has my reading changed this year?' or 'what was I into last spring?'.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"granularity": map[string]interface{}{
+					"type":        "string",
+					"description": "Period length (default: month)",
+					"enum":        []string{"month", "year"},
+				},
+				"since": map[string]interface{}{
+					"type":        "string",
+					"description": "Only count activity since this date. Examples: '1y', '6m', '2024-01-01'.",
+				},
+				"until": map[string]interface{}{
+					"type":        "string",
+					"description": "Only count activity until this date. Examples: 'today', '2024-12-31'.",
+				},
+				"top_tags": map[string]interface{}{
+					"type":        "integer",
+					"description": "Number of top tags per period (default: 5, 0 to leave tags out)",
+				},
+			},
+		},
+	}, s.handleGetTimeline)
+
+	// Reading goals tool
+	s.addTool(CapRead, mcp.Tool{
+		Name:        "get_reading_goals",
+		Description: "Get progress on the user's reading goals (articles or words read per week or month), with streaks and how many articles are in each reading status. Use for 'am I on track with my reading?' or 'how much do I have left this week?'.",
+		InputSchema: mcp.ToolInputSchema{
+			Type:       "object",
+			Properties: map[string]interface{}{},
+		},
+	}, s.handleGetReadingGoals)
+
+	// List feeds tool
+	s.addTool(CapRead, mcp.Tool{
+		Name:        "list_feeds",
+		Description: "List the user's RSS subscriptions with their IDs, URLs, tags, and when they were last synced. Use to check whether a blog is already subscribed.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"format": formatProperty,
+			},
+		},
+	}, s.handleListFeeds)
+
 	// Usage examples tool
-	s.mcpServer.AddTool(mcp.Tool{
+	s.addTool(CapRead, mcp.Tool{
 		Name:        "get_usage_examples",
 		Description: "Get examples of how to handle common user requests using the available tools. Use this to understand how to translate natural language requests into proper tool calls.",
 		InputSchema: mcp.ToolInputSchema{
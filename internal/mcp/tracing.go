@@ -0,0 +1,159 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// RequestLog appends a JSON line per tool call to a file, rotating it once it grows past a size.
+// Rotated files are kept as path.1 (newest) to path.N.
+type RequestLog struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	backups  int
+	file     *os.File
+	size     int64
+}
+
+// requestLogEntry is one line of the request log
+type requestLogEntry struct {
+	Time        time.Time              `json:"time"`
+	Tool        string                 `json:"tool"`
+	Arguments   map[string]interface{} `json:"arguments,omitempty"`
+	DurationMS  float64                `json:"duration_ms"`
+	ResultBytes int                    `json:"result_bytes"`
+	Error       string                 `json:"error,omitempty"`
+}
+
+// OpenRequestLog opens the request log at path for appending. It is rotated when a write would
+// take it past maxBytes (0 never rotates), keeping the given number of rotated files.
+func OpenRequestLog(path string, maxBytes int64, backups int) (*RequestLog, error) {
+	l := &RequestLog{path: path, maxBytes: maxBytes, backups: backups}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *RequestLog) open() error {
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open request log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat request log: %w", err)
+	}
+	l.file, l.size = file, info.Size()
+	return nil
+}
+
+// rotate shifts path.1..path.N-1 up by one, moves the current file to path.1, and starts a new one.
+// With no backups the current file is truncated instead.
+func (l *RequestLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close request log: %w", err)
+	}
+
+	if l.backups > 0 {
+		for i := l.backups - 1; i >= 1; i-- {
+			older := fmt.Sprintf("%s.%d", l.path, i)
+			if err := os.Rename(older, fmt.Sprintf("%s.%d", l.path, i+1)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to rotate request log: %w", err)
+			}
+		}
+		if err := os.Rename(l.path, l.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate request log: %w", err)
+		}
+	} else if err := os.Truncate(l.path, 0); err != nil {
+		return fmt.Errorf("failed to truncate request log: %w", err)
+	}
+
+	return l.open()
+}
+
+// Record writes a tool call to the log. A call that fails is logged with the error text of its
+// result, or the error the handler returned.
+func (l *RequestLog) Record(tool string, arguments map[string]interface{}, duration time.Duration, result *mcp.CallToolResult, callErr error) error {
+	entry := requestLogEntry{
+		Time:       time.Now().UTC(),
+		Tool:       tool,
+		Arguments:  arguments,
+		DurationMS: float64(duration.Microseconds()) / 1000,
+	}
+
+	if result != nil {
+		for _, content := range result.Content {
+			if text, ok := content.(mcp.TextContent); ok {
+				entry.ResultBytes += len(text.Text)
+			}
+		}
+		if result.IsError && len(result.Content) > 0 {
+			if text, ok := result.Content[0].(mcp.TextContent); ok {
+				entry.Error = text.Text
+			}
+		}
+	}
+	if callErr != nil {
+		entry.Error = callErr.Error()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode request log entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxBytes > 0 && l.size > 0 && l.size+int64(len(line)) > l.maxBytes {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write request log: %w", err)
+	}
+	return nil
+}
+
+// Close closes the log file
+func (l *RequestLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// WithRequestLog logs every tool call, with its arguments, duration, result size, and error
+func (s *Server) WithRequestLog(requestLog *RequestLog) *Server {
+	s.requestLog = requestLog
+	return s
+}
+
+// traced logs calls of a tool to the request log, when there is one
+func (s *Server) traced(tool string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+		if s.requestLog == nil {
+			return handler(arguments)
+		}
+
+		start := time.Now()
+		result, err := handler(arguments)
+		if logErr := s.requestLog.Record(tool, arguments, time.Since(start), result, err); logErr != nil {
+			log.Printf("Warning: %v", logErr)
+		}
+		return result, err
+	}
+}
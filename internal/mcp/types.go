@@ -2,12 +2,15 @@ package mcp
 
 import (
 	"time"
+
+	"instapaper-cli/internal/model"
+	"instapaper-cli/internal/search"
 )
 
 // SearchRequest represents parameters for searching articles
 type SearchRequest struct {
 	Query         string   `json:"query,omitempty"`
-	Field         string   `json:"field,omitempty"`         // url, title, content, tags, folder
+	Field         string   `json:"field,omitempty"`         // url, title, content, selection, tags, folder
 	UseFTS        bool     `json:"use_fts,omitempty"`       // Use full-text search
 	Limit         int      `json:"limit,omitempty"`
 	Tags          []string `json:"tags,omitempty"`          // Filter by tags
@@ -52,6 +55,7 @@ type AdvancedSearchRequest struct {
 	Tags              []string          `json:"tags,omitempty"`              // Must have ALL these tags
 	AnyTags           []string          `json:"any_tags,omitempty"`          // Must have ANY of these tags
 	Folders           []string          `json:"folders,omitempty"`           // Must be in ANY of these folders
+	Statuses          []string          `json:"statuses,omitempty"`          // Must have ANY of these reading statuses
 	DateAfter         string            `json:"date_after,omitempty"`        // ISO 8601 date
 	DateBefore        string            `json:"date_before,omitempty"`       // ISO 8601 date
 	OnlySynced        bool              `json:"only_synced,omitempty"`
@@ -76,10 +80,12 @@ type ArticleResponse struct {
 	ID             int64     `json:"id"`
 	URL            string    `json:"url"`
 	Title          string    `json:"title"`
+	Status         string    `json:"status,omitempty"`
 	Selection      *string   `json:"selection,omitempty"`
 	FolderPath     *string   `json:"folder_path,omitempty"`
 	Tags           []string  `json:"tags,omitempty"`
 	InstapaperedAt time.Time `json:"instapapered_at"`
+	PublishedAt    *time.Time `json:"published_at,omitempty"`
 	SyncedAt       *time.Time `json:"synced_at,omitempty"`
 	SyncFailedAt   *time.Time `json:"sync_failed_at,omitempty"`
 	FailedCount    int       `json:"failed_count"`
@@ -88,14 +94,48 @@ type ArticleResponse struct {
 	FinalURL       *string   `json:"final_url,omitempty"`
 	ContentMD      *string   `json:"content_md,omitempty"`
 	RawHTML        *string   `json:"raw_html,omitempty"`
+	Outline        []model.Heading `json:"outline,omitempty"`
+	// Part is set when ContentMD holds one part of a long article read with max_words
+	Part *ArticlePart `json:"part,omitempty"`
+}
+
+// ArticlePart locates the part of a long article returned by get_article
+type ArticlePart struct {
+	Number       int    `json:"number"`
+	Total        int    `json:"total"`
+	Words        int    `json:"words"`
+	Continuation string `json:"continuation,omitempty"` // token to read the next part, empty for the last
 }
 
 // SearchResponse represents the result of a search operation
 type SearchResponse struct {
 	Articles    []ArticleResponse `json:"articles"`
 	TotalCount  int               `json:"total_count"`
-	SearchTime  string            `json:"search_time"`
-	SearchQuery string            `json:"search_query"`
+	SearchTime  string            `json:"search_time,omitempty"`
+	SearchQuery string            `json:"search_query,omitempty"`
+	// TotalMatches counts every match, before the limit cut the list down to TotalCount
+	TotalMatches int `json:"total_matches"`
+	// Filters lists the conditions applied besides the query, e.g. "with status: [unread]"
+	Filters []string `json:"filters,omitempty"`
+	// Strategy tells how search_articles matched: fts, fuzzy with corrected terms, or like
+	Strategy *search.Strategy `json:"strategy,omitempty"`
+	Facets   *search.Facets   `json:"facets,omitempty"`
+}
+
+// ArticleContextResponse is an article with the articles related to it
+type ArticleContextResponse struct {
+	MainArticle      ArticleResponse   `json:"main_article"`
+	RelatedArticles  []ArticleResponse `json:"related_articles"`
+	RelationshipType string            `json:"relationship_type"`
+}
+
+// PassagesResponse is the search_within_article result: the passages of an article matching a pattern
+type PassagesResponse struct {
+	ID       int64            `json:"id"`
+	Title    string           `json:"title"`
+	Pattern  string           `json:"pattern"`
+	Matches  int              `json:"matches"`
+	Passages []search.Passage `json:"passages"`
 }
 
 // ExportResponse represents the result of an export operation
@@ -120,4 +160,27 @@ type TagInfo struct {
 	ID           int64  `json:"id"`
 	Title        string `json:"title"`
 	ArticleCount int    `json:"article_count"`
-}
\ No newline at end of file
+}
+// TimelineResponse is the get_timeline result: archive activity per period, oldest first
+type TimelineResponse struct {
+	Granularity  string           `json:"granularity"` // month or year
+	Since        string           `json:"since,omitempty"`
+	Until        string           `json:"until,omitempty"`
+	TotalSaved   int              `json:"total_saved"`
+	TotalFetched int              `json:"total_fetched"`
+	Periods      []TimelinePeriod `json:"periods"`
+}
+
+// TimelinePeriod counts the articles saved and fetched in one period
+type TimelinePeriod struct {
+	Period  string     `json:"period"`   // 2006-01 or 2006
+	Saved   int        `json:"saved"`    // articles added in the period
+	Fetched int        `json:"fetched"`  // articles whose content was downloaded in the period
+	TopTags []TagCount `json:"top_tags"` // most used tags among the articles saved in the period
+}
+
+// TagCount is a tag and how many articles in a period carry it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
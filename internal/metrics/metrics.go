@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"instapaper-cli/internal/db"
+)
+
+// Counter is a monotonically increasing value with optional labels,
+// rendered in the Prometheus text exposition format
+type Counter struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+var (
+	// FetchResults counts article fetch attempts by result and HTTP status code
+	FetchResults = newCounter("instapaper_fetch_total", "Article fetch attempts by this process by result and HTTP status code", "result", "status_code")
+
+	// FetchFailures counts failed article fetches by failure class
+	FetchFailures = newCounter("instapaper_fetch_failures_total", "Failed article fetches by this process by failure class", "class")
+
+	// RSSItemsIngested counts new articles added from RSS feeds
+	RSSItemsIngested = newCounter("instapaper_rss_items_ingested_total", "New articles ingested from RSS feeds by this process", "feed")
+
+	// ExportRuns counts export runs by kind
+	ExportRuns = newCounter("instapaper_export_runs_total", "Export runs by this process by kind", "kind")
+
+	registry = []*Counter{FetchResults, FetchFailures, RSSItemsIngested, ExportRuns}
+)
+
+func newCounter(name, help string, labels ...string) *Counter {
+	return &Counter{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]float64),
+	}
+}
+
+// Inc increments the counter for the given label values, in declaration order
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds delta to the counter for the given label values
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	key := c.labelString(labelValues)
+
+	c.mu.Lock()
+	c.values[key] += delta
+	c.mu.Unlock()
+}
+
+// labelString renders label values as a Prometheus label set, e.g. {result="success"}
+func (c *Counter) labelString(values []string) string {
+	if len(c.labels) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(c.labels))
+	for i, label := range c.labels {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs[i] = fmt.Sprintf("%s=%q", label, value)
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// write renders the counter in Prometheus text format
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", c.name, c.help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", c.name)
+
+	keys := make([]string, 0, len(c.values))
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s%s %g\n", c.name, key, c.values[key])
+	}
+}
+
+// Handler returns an HTTP handler serving all counters plus database gauges. Counters only count
+// work done in this process, so a serve or daemon process reports its own fetches, syncs, and
+// exports; the gauges are read from the database and also reflect other commands.
+func Handler(database *db.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		for _, counter := range registry {
+			counter.write(w)
+		}
+
+		writeDatabaseGauges(r.Context(), w, database)
+	})
+}
+
+// writeDatabaseGauges renders database size and article counts, computed at scrape time
+func writeDatabaseGauges(ctx context.Context, w io.Writer, database *db.DB) {
+	if size, err := database.Size(); err == nil {
+		fmt.Fprintf(w, "# HELP instapaper_db_size_bytes Size of the SQLite database in bytes\n")
+		fmt.Fprintf(w, "# TYPE instapaper_db_size_bytes gauge\n")
+		fmt.Fprintf(w, "instapaper_db_size_bytes %d\n", size)
+	}
+
+	var counts struct {
+		Total    int `db:"total"`
+		Fetched  int `db:"fetched"`
+		Obsolete int `db:"obsolete"`
+	}
+
+	query := `
+		SELECT
+			COUNT(*) as total,
+			COALESCE(SUM(CASE WHEN synced_at IS NOT NULL AND obsolete = FALSE THEN 1 ELSE 0 END), 0) as fetched,
+			COALESCE(SUM(CASE WHEN obsolete = TRUE THEN 1 ELSE 0 END), 0) as obsolete
+		FROM articles
+	`
+
+	if err := database.GetContext(ctx, &counts, query); err == nil {
+		fmt.Fprintf(w, "# HELP instapaper_articles Articles in the archive by state\n")
+		fmt.Fprintf(w, "# TYPE instapaper_articles gauge\n")
+		fmt.Fprintf(w, "instapaper_articles{state=\"total\"} %d\n", counts.Total)
+		fmt.Fprintf(w, "instapaper_articles{state=\"fetched\"} %d\n", counts.Fetched)
+		fmt.Fprintf(w, "instapaper_articles{state=\"obsolete\"} %d\n", counts.Obsolete)
+	}
+
+	// Failures are stored with the article, so fetches by any process show up here
+	var failures []struct {
+		Class string `db:"class"`
+		Count int    `db:"count"`
+	}
+	if err := database.SelectContext(ctx, &failures, `
+		SELECT COALESCE(failure_class, '') AS class, COUNT(*) AS count
+		FROM articles
+		WHERE synced_at IS NULL AND sync_failed_at IS NOT NULL AND obsolete = FALSE
+		GROUP BY class
+		ORDER BY class
+	`); err == nil {
+		fmt.Fprintf(w, "# HELP instapaper_failed_articles Unfetched articles whose last fetch failed, by failure class\n")
+		fmt.Fprintf(w, "# TYPE instapaper_failed_articles gauge\n")
+		for _, failure := range failures {
+			fmt.Fprintf(w, "instapaper_failed_articles{class=%q} %d\n", failure.Class, failure.Count)
+		}
+	}
+}
@@ -16,9 +16,15 @@ type Article struct {
 	FailedCount    int     `db:"failed_count" json:"failed_count"`
 	StatusCode     *int    `db:"status_code" json:"status_code,omitempty"`
 	StatusText     *string `db:"status_text" json:"status_text,omitempty"`
+	Status         string  `db:"status" json:"status,omitempty"`
 	FinalURL       *string `db:"final_url" json:"final_url,omitempty"`
+	CanonicalURL   *string `db:"canonical_url" json:"canonical_url,omitempty"`
 	ContentMD      *string `db:"content_md" json:"content_md,omitempty"`
 	RawHTML        *string `db:"raw_html" json:"raw_html,omitempty"`
+	// ExtractionQuality scores fetched content from 0 (junk) to 1 (looks like the article)
+	ExtractionQuality *float64 `db:"extraction_quality" json:"extraction_quality,omitempty"`
+	// Language is the detected language code of the content, e.g. en or ja
+	Language *string `db:"language" json:"language,omitempty"`
 }
 
 type Folder struct {
@@ -42,6 +48,27 @@ type ArticleWithDetails struct {
 	Article
 	FolderPath *string  `db:"folder_path" json:"folder_path,omitempty"`
 	Tags       []string `json:"tags,omitempty"`
+	Highlights []string `json:"highlights,omitempty"`
+	Outline    []Heading `json:"outline,omitempty"`
+	// Aliases are other URLs that lead to the article, only filled in for a single article
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// Heading is an entry of an article's outline
+type Heading struct {
+	Level  int    `json:"level"` // 1 for #, up to 6
+	Title  string `json:"title"`
+	Anchor string `json:"anchor"` // Fragment identifier of the heading in exported markdown
+	// Paragraph is the heading's paragraph in the content, counted from 1 as grep counts them
+	Paragraph int `json:"paragraph"`
+}
+
+type Highlight struct {
+	ID        int64  `db:"id" json:"id"`
+	ArticleID int64  `db:"article_id" json:"article_id"`
+	Position  int    `db:"position" json:"position"`
+	Text      string `db:"text" json:"text"`
+	CreatedAt string `db:"created_at" json:"created_at"`
 }
 
 type CSVRecord struct {
@@ -59,6 +86,9 @@ type FrontMatter struct {
 	ExportedAt     time.Time `yaml:"exported_at"`
 	Source         string    `yaml:"source"`
 	Tags           []string  `yaml:"tags"`
+	Attachments    []string  `yaml:"attachments,omitempty"`
+	// Fields are extra computed fields, written after the others in key order
+	Fields map[string]interface{} `yaml:",inline"`
 }
 
 type SearchResult struct {
@@ -70,7 +100,11 @@ type SearchResult struct {
 	SyncedAt       *string `db:"synced_at" json:"synced_at,omitempty"`
 	FailedCount    int     `db:"failed_count" json:"failed_count"`
 	StatusCode     *int    `db:"status_code" json:"status_code,omitempty"`
+	Status         string  `db:"status" json:"status,omitempty"`
 	InstapaperedAt string  `db:"instapapered_at" json:"instapapered_at"`
+	PublishedAt    *string `db:"published_at" json:"published_at,omitempty"`
+	// DuplicateIDs are the results collapsed into this one by a deduplicated search
+	DuplicateIDs []int64 `db:"-" json:"duplicate_ids,omitempty"`
 }
 
 type RSSFeed struct {
@@ -80,6 +114,10 @@ type RSSFeed struct {
 	CreatedAt    string  `db:"created_at" json:"created_at"`
 	LastSyncedAt *string `db:"last_synced_at" json:"last_synced_at,omitempty"`
 	Active       bool    `db:"active" json:"active"`
+	// UseContent stores items' embedded content at sync time instead of fetching them
+	UseContent bool `db:"use_content" json:"use_content"`
+	// Backfill is how much history the first sync ingests: all, none, or an age like 30d
+	Backfill string `db:"backfill" json:"backfill"`
 }
 
 type RSSFeedWithTags struct {
@@ -90,4 +128,11 @@ type RSSFeedWithTags struct {
 type RSSFeedTag struct {
 	FeedID int64 `db:"feed_id" json:"feed_id"`
 	TagID  int64 `db:"tag_id" json:"tag_id"`
-}
\ No newline at end of file
+}
+// ItemError describes a failure of a single record or article within a batch operation
+type ItemError struct {
+	ID    int64  `json:"id,omitempty"`
+	Line  int    `json:"line,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Error string `json:"error"`
+}
@@ -0,0 +1,230 @@
+// Package policy applies standing rules to the archive, such as marking dead articles obsolete,
+// so unattended runs keep it tidy without manual commands
+package policy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"instapaper-cli/internal/db"
+	"instapaper-cli/internal/util"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ObsoletePolicies mark articles obsolete when they match, from a YAML file
+type ObsoletePolicies struct {
+	Policies []ObsoletePolicy `yaml:"obsolete"`
+}
+
+// ObsoletePolicy matches articles meeting all of its conditions
+type ObsoletePolicy struct {
+	Name string `yaml:"name"`
+	// MinFailures matches articles whose fetch failed at least this many times
+	MinFailures int `yaml:"min_failures"`
+	// StatusCodes match the HTTP status of the last fetch, e.g. 404 or 410
+	StatusCodes []int `yaml:"status_codes"`
+	// FailureClasses match the class of the last fetch failure, e.g. dns or http_4xx
+	FailureClasses []string `yaml:"failure_classes"`
+	// LinkStatuses match the last check-links result, e.g. broken
+	LinkStatuses []string `yaml:"link_status"`
+	// OlderThan only matches articles saved longer ago than this, e.g. 90d or 6m
+	OlderThan string `yaml:"older_than"`
+}
+
+// ObsoleteReport lists the articles each policy matched and how many were marked obsolete
+type ObsoleteReport struct {
+	DryRun      bool                  `json:"dry_run"`
+	Policies    []ObsoletePolicyMatch `json:"policies"`
+	Marked      int64                 `json:"marked"`
+	OperationID int64                 `json:"operation_id,omitempty"`
+}
+
+// ObsoletePolicyMatch is what one policy matched
+type ObsoletePolicyMatch struct {
+	Name     string            `json:"name"`
+	Criteria string            `json:"criteria"`
+	Articles []ObsoleteArticle `json:"articles"`
+}
+
+// ObsoleteArticle is an article a policy marks obsolete
+type ObsoleteArticle struct {
+	ID          int64  `db:"id" json:"id"`
+	URL         string `db:"url" json:"url"`
+	Title       string `db:"title" json:"title"`
+	StatusCode  *int   `db:"status_code" json:"status_code,omitempty"`
+	FailedCount int    `db:"failed_count" json:"failed_count"`
+}
+
+// LoadObsoletePolicies reads and validates obsolete policies from a YAML file. An empty path means
+// no policies.
+func LoadObsoletePolicies(path string) (*ObsoletePolicies, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read obsolete policies: %w", err)
+	}
+
+	var policies ObsoletePolicies
+	if err := yaml.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("failed to parse obsolete policies: %w", err)
+	}
+
+	for i := range policies.Policies {
+		p := &policies.Policies[i]
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("policy %d", i+1)
+		}
+		// Age alone would retire every old article, so a policy needs a sign the article is dead
+		if p.MinFailures <= 0 && len(p.StatusCodes) == 0 && len(p.FailureClasses) == 0 && len(p.LinkStatuses) == 0 {
+			return nil, fmt.Errorf("%s has no min_failures, status_codes, failure_classes, or link_status condition", p.Name)
+		}
+		if p.OlderThan != "" {
+			if _, err := util.ParseRelativeDate(p.OlderThan); err != nil {
+				return nil, fmt.Errorf("%s: invalid older_than %q: use an age like 90d, 12w, or 6m", p.Name, p.OlderThan)
+			}
+		}
+	}
+
+	return &policies, nil
+}
+
+// Criteria describes a policy's conditions, for the obsolete log
+func (p ObsoletePolicy) Criteria() string {
+	var parts []string
+	if p.MinFailures > 0 {
+		parts = append(parts, fmt.Sprintf("min_failures: %d", p.MinFailures))
+	}
+	if len(p.StatusCodes) > 0 {
+		codes := make([]string, len(p.StatusCodes))
+		for i, code := range p.StatusCodes {
+			codes[i] = strconv.Itoa(code)
+		}
+		parts = append(parts, "status_codes: "+strings.Join(codes, ","))
+	}
+	if len(p.FailureClasses) > 0 {
+		parts = append(parts, "failure_classes: "+strings.Join(p.FailureClasses, ","))
+	}
+	if len(p.LinkStatuses) > 0 {
+		parts = append(parts, "link_status: "+strings.Join(p.LinkStatuses, ","))
+	}
+	if p.OlderThan != "" {
+		parts = append(parts, "older_than: "+p.OlderThan)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// conditions builds the WHERE conditions selecting the active articles a policy matches
+func (p ObsoletePolicy) conditions() ([]string, []interface{}, error) {
+	conditions := []string{"obsolete = FALSE"}
+	var args []interface{}
+
+	if p.MinFailures > 0 {
+		conditions = append(conditions, "failed_count >= ?")
+		args = append(args, p.MinFailures)
+	}
+	if len(p.StatusCodes) > 0 {
+		for _, code := range p.StatusCodes {
+			args = append(args, code)
+		}
+		conditions = append(conditions, fmt.Sprintf("status_code IN (%s)", placeholders(len(p.StatusCodes))))
+	}
+	if len(p.FailureClasses) > 0 {
+		for _, class := range p.FailureClasses {
+			args = append(args, class)
+		}
+		conditions = append(conditions, fmt.Sprintf("failure_class IN (%s)", placeholders(len(p.FailureClasses))))
+	}
+	if len(p.LinkStatuses) > 0 {
+		for _, status := range p.LinkStatuses {
+			args = append(args, status)
+		}
+		conditions = append(conditions, fmt.Sprintf("id IN (SELECT article_id FROM link_checks WHERE status IN (%s))", placeholders(len(p.LinkStatuses))))
+	}
+	if p.OlderThan != "" {
+		cutoff, err := util.ParseRelativeDate(p.OlderThan)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: invalid older_than %q", p.Name, p.OlderThan)
+		}
+		conditions = append(conditions, "instapapered_at < ?")
+		args = append(args, cutoff.Format(time.RFC3339))
+	}
+	return conditions, args, nil
+}
+
+// placeholders returns n comma-separated "?" placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// Apply marks the articles matching each policy obsolete, logging the policy as the reason, all
+// under one operation so undo can restore them. A dry run only reports the matches. Nil policies
+// match nothing.
+func (ps *ObsoletePolicies) Apply(ctx context.Context, database *db.DB, dryRun bool) (*ObsoleteReport, error) {
+	report := &ObsoleteReport{DryRun: dryRun, Policies: []ObsoletePolicyMatch{}}
+	if ps == nil {
+		return report, nil
+	}
+
+	// An article matched by several policies is reported under the first
+	seen := make(map[int64]bool)
+	for _, p := range ps.Policies {
+		conditions, args, err := p.conditions()
+		if err != nil {
+			return report, err
+		}
+
+		var articles []ObsoleteArticle
+		if err := database.SelectContext(ctx, &articles, fmt.Sprintf(`
+			SELECT id, url, title, status_code, failed_count
+			FROM articles
+			WHERE %s
+			ORDER BY id
+		`, strings.Join(conditions, " AND ")), args...); err != nil {
+			return report, fmt.Errorf("failed to find articles for %s: %w", p.Name, err)
+		}
+
+		match := ObsoletePolicyMatch{Name: p.Name, Criteria: p.Criteria(), Articles: []ObsoleteArticle{}}
+		for _, article := range articles {
+			if !seen[article.ID] {
+				seen[article.ID] = true
+				match.Articles = append(match.Articles, article)
+			}
+		}
+		report.Policies = append(report.Policies, match)
+	}
+
+	if dryRun || len(seen) == 0 {
+		return report, nil
+	}
+
+	operationID, err := database.BeginOperation(db.OperationObsolete, "obsolete policies")
+	if err != nil {
+		return report, err
+	}
+	report.OperationID = operationID
+
+	for _, match := range report.Policies {
+		ids := make([]int64, len(match.Articles))
+		for i, article := range match.Articles {
+			ids[i] = article.ID
+		}
+		marked, err := database.SetArticlesObsolete(ids, true, db.ObsoleteAudit{
+			Reason:      "obsolete policy " + match.Name,
+			Criteria:    match.Criteria,
+			OperationID: operationID,
+		})
+		report.Marked += marked
+		if err != nil {
+			return report, fmt.Errorf("failed to mark articles obsolete for %s: %w", match.Name, err)
+		}
+	}
+	return report, nil
+}
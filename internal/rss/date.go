@@ -0,0 +1,120 @@
+package rss
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// pubDateLayouts are tried in order on a date normalized by normalizePubDate: RFC 822 dates
+// without their day of week, with two- or four-digit years and optional seconds, then RFC 3339
+// and other ISO 8601 forms. Dates without a zone are read as UTC.
+var pubDateLayouts = []string{
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 -07:00",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04",
+	"2 Jan 2006",
+	"Jan 2 15:04:05 -0700 2006",
+	"Jan 2, 2006 15:04:05 -0700",
+	"Jan 2, 2006",
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02 15:04:05.999999999 -07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// zoneOffsets are the zone names of RFC 822 and a few other common ones. Go reads an unknown
+// zone name as UTC, which would shift the date.
+var zoneOffsets = map[string]string{
+	"UT": "+0000", "UTC": "+0000", "GMT": "+0000", "Z": "+0000",
+	"EST": "-0500", "EDT": "-0400", "CST": "-0600", "CDT": "-0500",
+	"MST": "-0700", "MDT": "-0600", "PST": "-0800", "PDT": "-0700",
+	"BST": "+0100", "CET": "+0100", "CEST": "+0200", "EET": "+0200", "EEST": "+0300", "JST": "+0900",
+}
+
+// monthNames maps English, German, French, Spanish, and Dutch month names and abbreviations,
+// lowercased, to the English abbreviations the layouts use
+var monthNames = map[string]string{
+	"jan": "Jan", "january": "Jan", "januar": "Jan", "janv": "Jan", "janvier": "Jan", "ene": "Jan", "enero": "Jan", "januari": "Jan",
+	"feb": "Feb", "february": "Feb", "februar": "Feb", "févr": "Feb", "février": "Feb", "febrero": "Feb", "februari": "Feb",
+	"mar": "Mar", "march": "Mar", "mär": "Mar", "mrz": "Mar", "märz": "Mar", "mars": "Mar", "marzo": "Mar", "mrt": "Mar", "maart": "Mar",
+	"apr": "Apr", "april": "Apr", "avr": "Apr", "avril": "Apr", "abr": "Apr", "abril": "Apr",
+	"may": "May", "mai": "May", "mayo": "May", "mei": "May",
+	"jun": "Jun", "june": "Jun", "juni": "Jun", "juin": "Jun", "junio": "Jun",
+	"jul": "Jul", "july": "Jul", "juli": "Jul", "juil": "Jul", "juillet": "Jul", "julio": "Jul",
+	"aug": "Aug", "august": "Aug", "août": "Aug", "ago": "Aug", "agosto": "Aug", "augustus": "Aug",
+	"sep": "Sep", "sept": "Sep", "september": "Sep", "septembre": "Sep", "septiembre": "Sep",
+	"oct": "Oct", "october": "Oct", "okt": "Oct", "oktober": "Oct", "octobre": "Oct", "octubre": "Oct",
+	"nov": "Nov", "november": "Nov", "novembre": "Nov", "noviembre": "Nov",
+	"dec": "Dec", "december": "Dec", "dez": "Dec", "dezember": "Dec", "déc": "Dec", "décembre": "Dec", "dic": "Dec", "diciembre": "Dec",
+}
+
+// weekdayNames are English day names and abbreviations, which may start a date without a comma
+var weekdayNames = map[string]bool{
+	"mon": true, "tue": true, "wed": true, "thu": true, "fri": true, "sat": true, "sun": true,
+	"monday": true, "tuesday": true, "wednesday": true, "thursday": true, "friday": true, "saturday": true, "sunday": true,
+}
+
+var (
+	// leadingWeekday is a day name in any language followed by a comma
+	leadingWeekday = regexp.MustCompile(`^[^\d\s,]+\.?,\s*`)
+	// trailingComment is a parenthesized zone name after a numeric offset, e.g. "+0000 (UTC)"
+	trailingComment = regexp.MustCompile(`\s*\([^)]*\)$`)
+)
+
+// parsePubDate parses a feed item's date. RSS asks for RFC 822 dates, but feeds also use RFC 3339
+// and other ISO 8601 forms, two-digit years, named zones, localized month names, and wrong or
+// missing days of week, so layouts are tried in turn on the normalized date.
+func parsePubDate(dateStr string) (time.Time, error) {
+	normalized := normalizePubDate(dateStr)
+	if normalized == "" {
+		return time.Time{}, fmt.Errorf("failed to parse date: empty")
+	}
+
+	for _, layout := range pubDateLayouts {
+		if t, err := time.Parse(layout, normalized); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("failed to parse date: %s", dateStr)
+}
+
+// normalizePubDate collapses whitespace, drops the day of week and any trailing zone comment,
+// translates month names to English, and replaces a named zone with its offset
+func normalizePubDate(dateStr string) string {
+	fields := strings.Fields(dateStr)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	// The day of week is redundant and often wrong or localized
+	if weekdayNames[strings.ToLower(strings.TrimRight(fields[0], ".,"))] {
+		fields = fields[1:]
+	}
+	s := leadingWeekday.ReplaceAllString(strings.Join(fields, " "), "")
+	s = trailingComment.ReplaceAllString(s, "")
+
+	fields = strings.Fields(s)
+	for i, field := range fields {
+		word := strings.TrimRight(field, ".,")
+		if month, ok := monthNames[strings.ToLower(word)]; ok {
+			fields[i] = month + strings.TrimPrefix(strings.TrimPrefix(field, word), ".")
+		}
+	}
+	for i := 1; i < len(fields); i++ {
+		if offset, ok := zoneOffsets[strings.ToUpper(fields[i])]; ok {
+			fields[i] = offset
+		}
+	}
+	return strings.Join(fields, " ")
+}
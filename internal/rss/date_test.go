@@ -0,0 +1,61 @@
+package rss
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParsePubDate covers dates as real RSS and Atom feeds write them
+func TestParsePubDate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string // RFC 3339 in the date's own offset
+	}{
+		{"RFC 822 with offset", "Tue, 05 Mar 2024 10:00:00 +0000", "2024-03-05T10:00:00Z"},
+		{"RFC 822 with GMT", "Tue, 05 Mar 2024 10:00:00 GMT", "2024-03-05T10:00:00Z"},
+		{"RFC 822 with US zone", "Tue, 05 Mar 2024 10:00:00 EST", "2024-03-05T10:00:00-05:00"},
+		{"RFC 822 single-digit day", "Tue, 5 Mar 2024 10:00:00 +0100", "2024-03-05T10:00:00+01:00"},
+		{"RFC 822 without seconds", "Tue, 05 Mar 2024 10:00 +0000", "2024-03-05T10:00:00Z"},
+		{"RFC 822 two-digit year", "Tue, 05 Mar 24 10:00:00 +0000", "2024-03-05T10:00:00Z"},
+		{"RFC 822 wrong weekday", "Mon, 05 Mar 2024 10:00:00 +0000", "2024-03-05T10:00:00Z"},
+		{"RFC 822 without weekday", "05 Mar 2024 10:00:00 +0000", "2024-03-05T10:00:00Z"},
+		{"RFC 822 zone comment", "Tue, 05 Mar 2024 10:00:00 +0000 (UTC)", "2024-03-05T10:00:00Z"},
+		{"RFC 822 colon offset", "Tue, 05 Mar 2024 10:00:00 +02:00", "2024-03-05T10:00:00+02:00"},
+		{"RFC 822 full month name", "Tuesday, 05 March 2024 10:00:00 +0000", "2024-03-05T10:00:00Z"},
+		{"German", "Di, 05 Mär 2024 10:00:00 +0100", "2024-03-05T10:00:00+01:00"},
+		{"French", "mar., 05 mars 2024 10:00:00 +0100", "2024-03-05T10:00:00+01:00"},
+		{"padded whitespace", "  Tue,  05 Mar 2024\n 10:00:00 +0000 ", "2024-03-05T10:00:00Z"},
+		{"Atom UTC", "2024-03-05T10:00:00Z", "2024-03-05T10:00:00Z"},
+		{"Atom offset", "2024-03-05T10:00:00+02:00", "2024-03-05T10:00:00+02:00"},
+		{"Atom fractional seconds", "2024-03-05T10:00:00.123456Z", "2024-03-05T10:00:00.123456Z"},
+		{"Atom without seconds", "2024-03-05T10:00+02:00", "2024-03-05T10:00:00+02:00"},
+		{"ISO without zone", "2024-03-05T10:00:00", "2024-03-05T10:00:00Z"},
+		{"SQL style with colon offset", "2024-03-05 10:00:00 +02:00", "2024-03-05T10:00:00+02:00"},
+		{"SQL style with offset", "2024-03-05 10:00:00 +0200", "2024-03-05T10:00:00+02:00"},
+		{"SQL style without zone", "2024-03-05 10:00:00", "2024-03-05T10:00:00Z"},
+		{"date only", "2024-03-05", "2024-03-05T00:00:00Z"},
+		{"US style", "Mar 5, 2024", "2024-03-05T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePubDate(tt.in)
+			if err != nil {
+				t.Fatalf("parsePubDate(%q) failed: %v", tt.in, err)
+			}
+			// Comparing the formatted dates checks the offset as well as the instant
+			if formatted := got.Format(time.RFC3339Nano); formatted != tt.want {
+				t.Errorf("parsePubDate(%q) = %s, want %s", tt.in, formatted, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePubDateRejectsGarbage(t *testing.T) {
+	for _, in := range []string{"", "   ", "yesterday", "32 Mar 2024", "2024-13-05"} {
+		if got, err := parsePubDate(in); err == nil {
+			t.Errorf("parsePubDate(%q) = %s, want an error", in, got)
+		}
+	}
+}
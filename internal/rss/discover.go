@@ -0,0 +1,331 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"instapaper-cli/internal/db"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	// discoverTimeout bounds each request made while looking for a site's feed
+	discoverTimeout = 15 * time.Second
+	// maxDiscoverBody bounds how much of a page or feed is read
+	maxDiscoverBody = 5 << 20
+	// dominantTagShare is the share of a domain's articles a tag needs to be suggested for its feed
+	dominantTagShare = 0.3
+)
+
+// commonFeedPaths are tried when a site's home page links no feed
+var commonFeedPaths = []string{"/feed", "/rss", "/feed.xml", "/rss.xml", "/index.xml", "/atom.xml"}
+
+// DiscoverOptions selects the sites to look for feeds on
+type DiscoverOptions struct {
+	// URL looks for the feed of this page's site instead of the sites of saved articles
+	URL string
+	// MinArticles skips domains with fewer saved articles
+	MinArticles int
+	// Limit is the most domains to check, those with the most saved articles first
+	Limit int
+	// MaxTags is the most dominant tags suggested per feed, 0 for none
+	MaxTags int
+}
+
+// FeedCandidate is a feed found, or looked for, on the site of saved articles
+type FeedCandidate struct {
+	Domain   string   `json:"domain"`
+	Articles int      `json:"articles"`
+	FeedURL  string   `json:"feed_url,omitempty"`
+	Title    string   `json:"title,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	// Subscribed is set when a feed of the domain is already in rss:list
+	Subscribed bool   `json:"subscribed,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// domainInfo counts a domain's saved articles and their tags
+type domainInfo struct {
+	name     string
+	home     string // Home page of the site, on the scheme and host of a saved article
+	articles int
+	tags     map[string]int
+}
+
+// Discover looks for the RSS feeds of the sites articles were saved from, most-saved first, or of
+// the site of opts.URL. Sites are checked for feed links on their home page and then for feeds at
+// common paths. A site whose feed cannot be found gets a candidate with an Error.
+func Discover(ctx context.Context, database *db.DB, opts DiscoverOptions) ([]FeedCandidate, error) {
+	domains, err := savedDomains(database)
+	if err != nil {
+		return nil, err
+	}
+
+	subscribed, err := subscribedHosts(database)
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []string
+	var selected []*domainInfo
+	if opts.URL != "" {
+		u, err := url.Parse(opts.URL)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid URL: %s", opts.URL)
+		}
+		host := hostName(u)
+		info := domains[host]
+		if info == nil {
+			info = &domainInfo{name: host}
+		}
+		selected = append(selected, info)
+		pages = append(pages, opts.URL)
+	} else {
+		for _, info := range domains {
+			if info.articles >= opts.MinArticles {
+				selected = append(selected, info)
+			}
+		}
+		sort.Slice(selected, func(i, j int) bool {
+			if selected[i].articles != selected[j].articles {
+				return selected[i].articles > selected[j].articles
+			}
+			return selected[i].name < selected[j].name
+		})
+		if opts.Limit > 0 && len(selected) > opts.Limit {
+			selected = selected[:opts.Limit]
+		}
+		for _, info := range selected {
+			pages = append(pages, info.home)
+		}
+	}
+
+	client := &http.Client{Timeout: discoverTimeout}
+	candidates := []FeedCandidate{}
+	for i, info := range selected {
+		if err := ctx.Err(); err != nil {
+			return candidates, err
+		}
+
+		candidate := FeedCandidate{Domain: info.name, Articles: info.articles, Tags: dominantTags(info, opts.MaxTags)}
+		if feedURL, ok := subscribed[info.name]; ok {
+			candidate.FeedURL = feedURL
+			candidate.Subscribed = true
+			candidates = append(candidates, candidate)
+			continue
+		}
+
+		feedURL, title, err := findFeed(ctx, client, pages[i])
+		if err != nil {
+			candidate.Error = err.Error()
+		} else {
+			candidate.FeedURL, candidate.Title = feedURL, title
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, nil
+}
+
+// savedDomains counts the active articles and their tags per domain
+func savedDomains(database *db.DB) (map[string]*domainInfo, error) {
+	var rows []struct {
+		URL  string  `db:"url"`
+		Tags *string `db:"tags"`
+	}
+	if err := database.Select(&rows, `
+		SELECT a.url, GROUP_CONCAT(t.title, char(31)) AS tags
+		FROM articles a
+		LEFT JOIN article_tags at ON a.id = at.article_id
+		LEFT JOIN tags t ON at.tag_id = t.id
+		WHERE a.obsolete = FALSE
+		GROUP BY a.id
+	`); err != nil {
+		return nil, fmt.Errorf("failed to get saved domains: %w", err)
+	}
+
+	domains := make(map[string]*domainInfo)
+	for _, row := range rows {
+		u, err := url.Parse(row.URL)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		host := hostName(u)
+		info := domains[host]
+		if info == nil {
+			home := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}
+			info = &domainInfo{name: host, home: home.String(), tags: make(map[string]int)}
+			domains[host] = info
+		}
+		info.articles++
+		if row.Tags != nil {
+			for _, tag := range strings.Split(*row.Tags, "\x1f") {
+				info.tags[tag]++
+			}
+		}
+	}
+	return domains, nil
+}
+
+// subscribedHosts maps the domains of the configured feeds to their feed URLs
+func subscribedHosts(database *db.DB) (map[string]string, error) {
+	var feedURLs []string
+	if err := database.Select(&feedURLs, "SELECT url FROM rss_feeds"); err != nil {
+		return nil, fmt.Errorf("failed to get RSS feeds: %w", err)
+	}
+
+	hosts := make(map[string]string)
+	for _, feedURL := range feedURLs {
+		if u, err := url.Parse(feedURL); err == nil && u.Host != "" {
+			hosts[hostName(u)] = feedURL
+		}
+	}
+	return hosts, nil
+}
+
+// hostName returns a URL's lowercased host without a www. prefix
+func hostName(u *url.URL) string {
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// dominantTags returns up to max of the tags on a large share of a domain's articles, most used first
+func dominantTags(info *domainInfo, max int) []string {
+	if max <= 0 || info.articles == 0 {
+		return nil
+	}
+
+	var tags []string
+	for tag, count := range info.tags {
+		if count >= 2 && float64(count) >= dominantTagShare*float64(info.articles) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if info.tags[tags[i]] != info.tags[tags[j]] {
+			return info.tags[tags[i]] > info.tags[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+	if len(tags) > max {
+		tags = tags[:max]
+	}
+	return tags
+}
+
+// findFeed returns the URL and title of the RSS feed of a page's site: the page itself when it
+// is a feed, a feed it links, or one at a common path
+func findFeed(ctx context.Context, client *http.Client, pageURL string) (string, string, error) {
+	body, finalURL, err := discoverGet(ctx, client, pageURL)
+	if err != nil {
+		return "", "", err
+	}
+	if title, ok := rssTitle(body); ok {
+		return finalURL.String(), title, nil
+	}
+
+	candidates, err := linkedFeeds(body, finalURL)
+	if err != nil {
+		return "", "", err
+	}
+	for _, path := range commonFeedPaths {
+		candidates = append(candidates, finalURL.ResolveReference(&url.URL{Path: path}).String())
+	}
+
+	atomOnly := false
+	for _, candidate := range candidates {
+		if err := ctx.Err(); err != nil {
+			return "", "", err
+		}
+		feedBody, feedURL, err := discoverGet(ctx, client, candidate)
+		if err != nil {
+			continue
+		}
+		if title, ok := rssTitle(feedBody); ok {
+			return feedURL.String(), title, nil
+		}
+		if isAtom(feedBody) {
+			atomOnly = true
+		}
+	}
+
+	if atomOnly {
+		return "", "", fmt.Errorf("only an Atom feed was found, but rss only syncs RSS feeds")
+	}
+	return "", "", fmt.Errorf("no RSS feed found")
+}
+
+// linkedFeeds lists the feeds a page declares with <link rel="alternate">, RSS before Atom
+func linkedFeeds(body []byte, pageURL *url.URL) ([]string, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse page: %w", err)
+	}
+
+	var rss, atom []string
+	doc.Find(`link[rel~="alternate"][href]`).Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		ref, err := url.Parse(strings.TrimSpace(href))
+		if err != nil {
+			return
+		}
+		feedURL := pageURL.ResolveReference(ref).String()
+
+		switch linkType := strings.ToLower(s.AttrOr("type", "")); {
+		case strings.Contains(linkType, "rss"):
+			rss = append(rss, feedURL)
+		case strings.Contains(linkType, "atom"):
+			atom = append(atom, feedURL)
+		}
+	})
+	return append(rss, atom...), nil
+}
+
+// discoverGet downloads a page or feed, returning its body and the URL it was served from
+func discoverGet(ctx context.Context, client *http.Client, rawURL string) ([]byte, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	req.Header.Set("User-Agent", "instapaper-cli/1.0 (+https://github.com/user/instapaper-cli)")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("%s returned status %d", rawURL, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDiscoverBody))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", rawURL, err)
+	}
+	return body, resp.Request.URL, nil
+}
+
+// rssTitle reports whether body is an RSS feed, and its channel title
+func rssTitle(body []byte) (string, bool) {
+	var feed RSS
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(feed.Channel.Title), true
+}
+
+// isAtom reports whether body is an Atom feed
+func isAtom(body []byte) bool {
+	var feed struct {
+		XMLName xml.Name
+	}
+	return xml.Unmarshal(body, &feed) == nil && feed.XMLName.Local == "feed"
+}
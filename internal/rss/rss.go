@@ -5,11 +5,16 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"instapaper-cli/internal/db"
+	"instapaper-cli/internal/fetcher"
+	"instapaper-cli/internal/metrics"
 	"instapaper-cli/internal/model"
+	"instapaper-cli/internal/tagging"
+	"instapaper-cli/internal/util"
 )
 
 type RSS struct {
@@ -30,6 +35,10 @@ type Item struct {
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
+	// DCDate is the Dublin Core date some feeds give instead of pubDate, in ISO 8601
+	DCDate string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	// ContentEncoded is the item's full HTML, which some feeds embed
+	ContentEncoded string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
 }
 
 // ParseRSSFeed fetches and parses an RSS feed from a URL
@@ -57,8 +66,63 @@ func ParseRSSFeed(url string) (*RSS, error) {
 	return &rss, nil
 }
 
-// SyncFeed synchronizes articles from an RSS feed, applying feed tags to new articles
-func SyncFeed(database *db.DB, feed *model.RSSFeed, feedTags []string) (int, error) {
+// Backfill values besides an age like 30d
+const (
+	BackfillAll  = "all"
+	BackfillNone = "none"
+)
+
+// ValidateBackfill checks a feed's backfill: all, none, or an age or date as --since takes
+func ValidateBackfill(backfill string) error {
+	if _, err := backfillIncludes(backfill, time.Now(), true); err != nil {
+		return fmt.Errorf("invalid backfill %q: use all, none, or an age like 30d", backfill)
+	}
+	return nil
+}
+
+// backfillIncludes reports whether a feed's first sync ingests an item published at pubDate.
+// With an age, items without a date are left out.
+func backfillIncludes(backfill string, pubDate time.Time, dated bool) (bool, error) {
+	switch backfill {
+	case "", BackfillAll:
+		return true, nil
+	case BackfillNone:
+		return false, nil
+	}
+	cutoff, err := util.ParseRelativeDate(backfill)
+	if err != nil {
+		return false, err
+	}
+	return dated && !pubDate.Before(cutoff), nil
+}
+
+// LoadFeed reads a subscribed feed and its tags, as SyncFeed takes them
+func LoadFeed(database *db.DB, id int64) (*model.RSSFeed, []string, error) {
+	feedData, tags, err := database.GetRSSFeed(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	feed := &model.RSSFeed{
+		ID:         id,
+		URL:        feedData["url"].(string),
+		Name:       feedData["name"].(string),
+		CreatedAt:  feedData["created_at"].(string),
+		Active:     feedData["active"].(bool),
+		UseContent: feedData["use_content"].(bool),
+		Backfill:   feedData["backfill"].(string),
+	}
+	if syncedAt, ok := feedData["last_synced_at"].(string); ok {
+		feed.LastSyncedAt = &syncedAt
+	}
+	return feed, tags, nil
+}
+
+// SyncFeed synchronizes articles from an RSS feed, applying feed tags and matching rules to new
+// articles. The first sync leaves out items older than the feed's backfill and remembers them, so
+// later syncs leave them out too. storeOpts are passed to StoreDocument for feeds whose content is
+// stored.
+func SyncFeed(database *db.DB, feed *model.RSSFeed, feedTags []string, rules *tagging.Rules, storeOpts fetcher.FetchOptions) (int, error) {
 	// Parse the RSS feed
 	rss, err := ParseRSSFeed(feed.URL)
 	if err != nil {
@@ -66,6 +130,8 @@ func SyncFeed(database *db.DB, feed *model.RSSFeed, feedTags []string) (int, err
 	}
 
 	newArticles := 0
+	filed := false
+	firstSync := feed.LastSyncedAt == nil
 
 	// Process each item in the feed
 	for _, item := range rss.Channel.Items {
@@ -73,25 +139,65 @@ func SyncFeed(database *db.DB, feed *model.RSSFeed, feedTags []string) (int, err
 		normalizedURL := normalizeURL(item.Link)
 
 		// Check if article already exists (with normalized URL)
-		var existingID int64
-		err := database.Get(&existingID, "SELECT id FROM articles WHERE url = ?", normalizedURL)
+		_, err := database.FindArticleByURL(normalizedURL)
 		if err == nil {
 			// Article already exists, skip
 			continue
 		}
 
+		skipped, err := database.IsRSSItemSkipped(feed.ID, normalizedURL)
+		if err != nil {
+			return newArticles, err
+		}
+		if skipped {
+			continue
+		}
+
 		// Parse publish date
 		pubDate, err := parsePubDate(item.PubDate)
-		if err != nil {
+		if err != nil && item.DCDate != "" {
+			pubDate, err = parsePubDate(item.DCDate)
+		}
+		dated := err == nil
+
+		if firstSync {
+			include, err := backfillIncludes(feed.Backfill, pubDate, dated)
+			if err != nil {
+				return newArticles, fmt.Errorf("invalid backfill %q: %w", feed.Backfill, err)
+			}
+			if !include {
+				if err := database.SkipRSSItem(feed.ID, normalizedURL); err != nil {
+					return newArticles, err
+				}
+				continue
+			}
+		}
+
+		if !dated {
 			// If parsing fails, use current time
 			pubDate = time.Now()
 		}
 
+		// Apply tag rules on top of the feed's tags
+		tags := feedTags
+		var folderID *int64
+		if match := rules.Apply(normalizedURL, item.Title); match != nil {
+			tags = append(append([]string{}, feedTags...), match.Tags...)
+			if match.Folder != "" {
+				id, err := database.UpsertFolderPath(match.Folder)
+				if err != nil {
+					return newArticles, fmt.Errorf("failed to upsert folder: %w", err)
+				}
+				folderID = &id
+				filed = true
+			}
+		}
+
 		// Insert new article with normalized URL
 		result, err := database.Exec(`
-			INSERT INTO articles (url, title, instapapered_at)
-			VALUES (?, ?, ?)
-		`, normalizedURL, item.Title, pubDate.Format(time.RFC3339))
+			INSERT INTO articles (url, title, title_norm, folder_id, instapapered_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, normalizedURL, item.Title, util.NormalizeText(item.Title), folderID, pubDate.Format(time.RFC3339))
 		if err != nil {
 			return newArticles, fmt.Errorf("failed to insert article: %w", err)
 		}
@@ -101,8 +207,8 @@ func SyncFeed(database *db.DB, feed *model.RSSFeed, feedTags []string) (int, err
 			return newArticles, fmt.Errorf("failed to get article ID: %w", err)
 		}
 
-		// Add feed tags to the article
-		for _, tagTitle := range feedTags {
+		// Add feed and rule tags to the article
+		for _, tagTitle := range util.DedupeStrings(tags) {
 			tagID, err := database.UpsertTag(tagTitle)
 			if err != nil {
 				return newArticles, fmt.Errorf("failed to upsert tag: %w", err)
@@ -117,12 +223,25 @@ func SyncFeed(database *db.DB, feed *model.RSSFeed, feedTags []string) (int, err
 			}
 		}
 
-		// Update FTS index for the new article
-		if err := database.UpsertArticleFTS(articleID); err != nil {
+		if feed.UseContent && strings.TrimSpace(item.ContentEncoded) != "" {
+			// The feed carries the article, so store it as if fetched; this also indexes it
+			baseURL, _ := url.Parse(normalizedURL)
+			if err := fetcher.New(database).StoreDocument(articleID, item.Title, []byte(item.ContentEncoded), baseURL, storeOpts); err != nil {
+				return newArticles, fmt.Errorf("failed to store item content: %w", err)
+			}
+		} else if err := database.UpsertArticleFTS(articleID); err != nil {
+			// Update FTS index for the new article
 			return newArticles, fmt.Errorf("failed to update FTS: %w", err)
 		}
 
 		newArticles++
+		metrics.RSSItemsIngested.Inc(feed.Name)
+	}
+
+	if filed {
+		if err := database.UpdateFolderPaths(); err != nil {
+			return newArticles, fmt.Errorf("failed to update folder paths: %w", err)
+		}
 	}
 
 	// Update last synced timestamp
@@ -136,25 +255,12 @@ func SyncFeed(database *db.DB, feed *model.RSSFeed, feedTags []string) (int, err
 	return newArticles, nil
 }
 
-// parsePubDate attempts to parse RSS pubDate in RFC1123 format
-func parsePubDate(dateStr string) (time.Time, error) {
-	// Try RFC1123 format (common in RSS)
-	t, err := time.Parse(time.RFC1123, dateStr)
-	if err == nil {
-		return t, nil
-	}
-
-	// Try RFC1123Z format (with timezone)
-	t, err = time.Parse(time.RFC1123Z, dateStr)
-	if err == nil {
-		return t, nil
-	}
-
-	return time.Time{}, fmt.Errorf("failed to parse date: %s", dateStr)
-}
-
-// normalizeURL converts http:// URLs to https:// for consistency
+// normalizeURL canonicalizes item links the same way as CSV imports, falling
+// back to upgrading http:// to https:// if the link cannot be parsed
 func normalizeURL(url string) string {
+	if canonical, err := util.CanonicalizeURL(url); err == nil {
+		return canonical
+	}
 	if strings.HasPrefix(url, "http://") {
 		return strings.Replace(url, "http://", "https://", 1)
 	}
@@ -0,0 +1,142 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"instapaper-cli/internal/db"
+	"instapaper-cli/internal/model"
+	"instapaper-cli/internal/util"
+)
+
+// Dedupe collapses results that resolve to the same page, keeping the first of each in result
+// order and recording the IDs of the rest on it. Articles resolve to their canonical URL, else
+// their final URL, else their URL, and a URL that is another article's URL or alias resolves to
+// that article.
+func Dedupe(ctx context.Context, database *db.DB, results []model.SearchResult) ([]model.SearchResult, error) {
+	if len(results) < 2 {
+		return results, nil
+	}
+
+	ids := make([]int64, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+
+	targets, err := resolvedURLs(ctx, database, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve article URLs: %w", err)
+	}
+
+	var urls []string
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		if !seen[target] {
+			seen[target] = true
+			urls = append(urls, target)
+		}
+	}
+	owners, err := urlOwners(ctx, database, urls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve article URLs: %w", err)
+	}
+
+	kept := make(map[string]int)
+	var deduped []model.SearchResult
+	for _, result := range results {
+		key := "url:" + targets[result.ID]
+		if owner, ok := owners[targets[result.ID]]; ok {
+			key = "id:" + strconv.FormatInt(owner, 10)
+		}
+
+		if i, ok := kept[key]; ok {
+			deduped[i].DuplicateIDs = append(deduped[i].DuplicateIDs, result.ID)
+			continue
+		}
+		kept[key] = len(deduped)
+		deduped = append(deduped, result)
+	}
+	return deduped, nil
+}
+
+// resolvedURLs maps article IDs to their canonical, final, or own URL, in canonical form.
+// Wayback Machine snapshots are not the article's final URL.
+func resolvedURLs(ctx context.Context, database *db.DB, ids []int64) (map[int64]string, error) {
+	targets := make(map[int64]string, len(ids))
+
+	for start := 0; start < len(ids); start += facetBatchSize {
+		batch := ids[start:min(start+facetBatchSize, len(ids))]
+
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+
+		var rows []struct {
+			ID     int64  `db:"id"`
+			Target string `db:"target"`
+		}
+		query := fmt.Sprintf(`
+			SELECT id, COALESCE(
+				NULLIF(canonical_url, ''),
+				CASE WHEN final_url NOT LIKE 'https://web.archive.org/%%' THEN NULLIF(final_url, '') END,
+				url
+			) AS target
+			FROM articles
+			WHERE id IN (%s)
+		`, placeholders(len(batch)))
+		if err := database.SelectContext(ctx, &rows, query, args...); err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			target, err := util.CanonicalizeURL(row.Target)
+			if err != nil {
+				target = row.Target
+			}
+			targets[row.ID] = target
+		}
+	}
+
+	return targets, nil
+}
+
+// urlOwners maps URLs to the article saved under them or holding them as an alias
+func urlOwners(ctx context.Context, database *db.DB, urls []string) (map[string]int64, error) {
+	owners := make(map[string]int64, len(urls))
+
+	for start := 0; start < len(urls); start += facetBatchSize {
+		batch := urls[start:min(start+facetBatchSize, len(urls))]
+
+		args := make([]interface{}, 0, 2*len(batch))
+		for _, u := range batch {
+			args = append(args, u)
+		}
+		for _, u := range batch {
+			args = append(args, u)
+		}
+
+		var rows []struct {
+			URL  string `db:"url"`
+			ID   int64  `db:"id"`
+			Rank int    `db:"rank"`
+		}
+		query := fmt.Sprintf(`
+			SELECT url, id, 0 AS rank FROM articles WHERE url IN (%s)
+			UNION ALL
+			SELECT url, article_id, 1 FROM url_aliases WHERE url IN (%s)
+			ORDER BY rank DESC
+		`, placeholders(len(batch)), placeholders(len(batch)))
+		if err := database.SelectContext(ctx, &rows, query, args...); err != nil {
+			return nil, err
+		}
+
+		// Aliases come first so that an article's own URL overrides them
+		for _, row := range rows {
+			owners[row.URL] = row.ID
+		}
+	}
+
+	return owners, nil
+}
@@ -0,0 +1,146 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"instapaper-cli/internal/db"
+	"instapaper-cli/internal/model"
+)
+
+// facetBatchSize bounds the number of article IDs bound into one tag count query
+const facetBatchSize = 500
+
+// Facets summarizes where search matches concentrate
+type Facets struct {
+	Total   int          `json:"total"`
+	Tags    []FacetCount `json:"tags"`
+	Folders []FacetCount `json:"folders"`
+	Years   []FacetCount `json:"years"`
+	Domains []FacetCount `json:"domains"`
+}
+
+// FacetCount is the number of matches with one facet value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// ComputeFacets counts results per tag, folder, year saved, and domain, keeping the top values of
+// each facet (all values when top is 0)
+func ComputeFacets(ctx context.Context, database *db.DB, results []model.SearchResult, top int) (*Facets, error) {
+	folders := make(map[string]int)
+	years := make(map[string]int)
+	domains := make(map[string]int)
+	ids := make([]int64, 0, len(results))
+
+	for _, result := range results {
+		ids = append(ids, result.ID)
+
+		if result.FolderPath != nil && *result.FolderPath != "" {
+			folders[*result.FolderPath]++
+		}
+		if len(result.InstapaperedAt) >= 4 {
+			years[result.InstapaperedAt[:4]]++
+		}
+		if u, err := url.Parse(result.URL); err == nil && u.Hostname() != "" {
+			domains[strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")]++
+		}
+	}
+
+	tags, err := countTags(ctx, database, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tags: %w", err)
+	}
+
+	return &Facets{
+		Total:   len(results),
+		Tags:    topFacets(tags, top),
+		Folders: topFacets(folders, top),
+		Years:   topFacets(years, top),
+		Domains: topFacets(domains, top),
+	}, nil
+}
+
+// countTags counts articles per tag; tags are queried rather than split from the
+// result's concatenated tag list, since tag names may contain commas
+func countTags(ctx context.Context, database *db.DB, ids []int64) (map[string]int, error) {
+	counts := make(map[string]int)
+
+	for start := 0; start < len(ids); start += facetBatchSize {
+		batch := ids[start:min(start+facetBatchSize, len(ids))]
+
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+
+		var rows []struct {
+			Title string `db:"title"`
+			Count int    `db:"count"`
+		}
+		query := fmt.Sprintf(`
+			SELECT t.title, COUNT(DISTINCT at.article_id) as count
+			FROM article_tags at
+			JOIN tags t ON at.tag_id = t.id
+			WHERE at.article_id IN (%s)
+			GROUP BY t.title
+		`, placeholders(len(batch)))
+		if err := database.SelectContext(ctx, &rows, query, args...); err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			counts[row.Title] += row.Count
+		}
+	}
+
+	return counts, nil
+}
+
+// topFacets sorts facet values by count, then value, and keeps the first top
+func topFacets(counts map[string]int, top int) []FacetCount {
+	facets := make([]FacetCount, 0, len(counts))
+	for value, count := range counts {
+		facets = append(facets, FacetCount{Value: value, Count: count})
+	}
+
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Value < facets[j].Value
+	})
+
+	if top > 0 && len(facets) > top {
+		facets = facets[:top]
+	}
+	return facets
+}
+
+// Summary renders facets as one line per facet, e.g. "Tags: golang (12), rust (4)"
+func (f *Facets) Summary() string {
+	var b strings.Builder
+	for _, facet := range []struct {
+		name   string
+		counts []FacetCount
+	}{
+		{"Tags", f.Tags},
+		{"Folders", f.Folders},
+		{"Years", f.Years},
+		{"Domains", f.Domains},
+	} {
+		if len(facet.counts) == 0 {
+			continue
+		}
+		values := make([]string, len(facet.counts))
+		for i, count := range facet.counts {
+			values[i] = fmt.Sprintf("%s (%d)", count.Value, count.Count)
+		}
+		b.WriteString(facet.name + ": " + strings.Join(values, ", ") + "\n")
+	}
+	return b.String()
+}
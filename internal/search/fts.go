@@ -0,0 +1,356 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"instapaper-cli/internal/db"
+	"instapaper-cli/internal/language"
+)
+
+// Strategies that can produce search results
+const (
+	StrategyFTS   = "fts"
+	StrategyFuzzy = "fuzzy"
+	StrategyLike  = "like"
+)
+
+// Strategy reports how search results were found
+type Strategy struct {
+	Name  string `json:"strategy"`
+	Query string `json:"query"`
+	// Corrections maps misspelled query terms to the indexed terms used instead
+	Corrections map[string]string `json:"corrections,omitempty"`
+}
+
+// ftsColumns are the columns of articles_fts, usable as --field values and "column:term" filters
+var ftsColumns = map[string]bool{"url": true, "title": true, "content": true, "folder": true, "tags": true, "selection": true}
+
+type ftsTokenKind int
+
+const (
+	ftsTerm ftsTokenKind = iota
+	ftsPhrase
+	ftsOperator
+	ftsOpen
+	ftsClose
+)
+
+type ftsToken struct {
+	kind   ftsTokenKind
+	text   string
+	column string // optional column filter for terms and phrases
+	prefix bool   // trailing * for prefix matching
+}
+
+// FTSQuery turns user input into a safe FTS5 MATCH expression. Words are matched as terms (so
+// punctuation like "c++" or "go-1.22" cannot break the query), a trailing * makes a prefix query
+// ("gener*"), "quoted phrases", AND/OR/NOT, parentheses, and column:term filters are kept.
+// A non-empty field restricts the whole expression to that column.
+func FTSQuery(raw, field string) (string, error) {
+	if field != "" && !ftsColumns[field] {
+		return "", fmt.Errorf("invalid field for FTS: %s", field)
+	}
+
+	expression := renderFTS(tokenizeFTS(raw))
+	if expression == "" {
+		return "", fmt.Errorf("search query has no searchable terms")
+	}
+
+	if field != "" {
+		return fmt.Sprintf("%s : (%s)", field, expression), nil
+	}
+	return expression, nil
+}
+
+func tokenizeFTS(raw string) []ftsToken {
+	var tokens []ftsToken
+	var pendingColumn string
+	runes := []rune(raw)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(' || r == ')':
+			kind := ftsOpen
+			if r == ')' {
+				kind = ftsClose
+			}
+			tokens = append(tokens, ftsToken{kind: kind})
+			i++
+
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			token := ftsToken{kind: ftsPhrase, text: string(runes[i+1 : min(end, len(runes))]), column: pendingColumn}
+			pendingColumn = ""
+			i = end + 1
+			if i < len(runes) && runes[i] == '*' {
+				token.prefix = true
+				i++
+			}
+			tokens = append(tokens, token)
+
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '(' && runes[end] != ')' && runes[end] != '"' {
+				end++
+			}
+			word := string(runes[i:end])
+			i = end
+
+			if word == "AND" || word == "OR" || word == "NOT" {
+				tokens = append(tokens, ftsToken{kind: ftsOperator, text: word})
+				continue
+			}
+
+			token := ftsToken{kind: ftsTerm}
+			if column, rest, ok := strings.Cut(word, ":"); ok && ftsColumns[strings.ToLower(column)] {
+				token.column, word = strings.ToLower(column), rest
+				// "title:" followed by a quoted phrase
+				if word == "" && i < len(runes) && runes[i] == '"' {
+					pendingColumn = token.column
+					continue
+				}
+			}
+			if strings.HasSuffix(word, "*") {
+				token.prefix = true
+				word = strings.TrimRight(word, "*")
+			}
+			token.text = word
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
+
+// renderFTS quotes terms and phrases and drops operators and parentheses that would be a syntax error
+func renderFTS(tokens []ftsToken) string {
+	depth, balanced := 0, true
+	for _, token := range tokens {
+		switch token.kind {
+		case ftsOpen:
+			depth++
+		case ftsClose:
+			depth--
+			if depth < 0 {
+				balanced = false
+			}
+		}
+	}
+	balanced = balanced && depth == 0
+
+	var parts []string
+	// needOperand is true at the start, after an operator, and after "(": a place where an
+	// operator or ")" would be invalid
+	needOperand := true
+	for _, token := range tokens {
+		switch token.kind {
+		case ftsOperator:
+			if needOperand {
+				continue
+			}
+			parts = append(parts, token.text)
+			needOperand = true
+
+		case ftsOpen:
+			if !balanced {
+				continue
+			}
+			parts = append(parts, "(")
+			needOperand = true
+
+		case ftsClose:
+			if !balanced {
+				continue
+			}
+			for len(parts) > 0 && isFTSOperator(parts[len(parts)-1]) {
+				parts = parts[:len(parts)-1] // dangling operator before ")"
+			}
+			if len(parts) > 0 && parts[len(parts)-1] == "(" {
+				parts = parts[:len(parts)-1] // empty group
+				needOperand = len(parts) == 0 || isFTSOperator(parts[len(parts)-1]) || parts[len(parts)-1] == "("
+				continue
+			}
+			parts = append(parts, ")")
+			needOperand = false
+
+		default:
+			if !hasSearchableRune(token.text) {
+				continue
+			}
+			text, prefix := token.text, token.prefix
+			// Words of scripts written without spaces are indexed as character pairs, and a
+			// single character only as the start of a pair
+			if language.HasSegmented(text) {
+				text = strings.Join(strings.Fields(language.Segment(text)), " ")
+				prefix = prefix || utf8.RuneCountInString(text) == 1
+			}
+			phrase := `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+			if prefix {
+				phrase += "*"
+			}
+			if token.column != "" {
+				phrase = token.column + " : " + phrase
+			}
+			parts = append(parts, phrase)
+			needOperand = false
+		}
+	}
+
+	for len(parts) > 0 && (isFTSOperator(parts[len(parts)-1]) || parts[len(parts)-1] == "(") {
+		parts = parts[:len(parts)-1]
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func isFTSOperator(part string) bool {
+	return part == "AND" || part == "OR" || part == "NOT"
+}
+
+func hasSearchableRune(text string) bool {
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// CorrectQuery replaces query terms that are not in the FTS index with the closest indexed term.
+// It returns the corrected query and the corrections made; no corrections means nothing better was found.
+func CorrectQuery(ctx context.Context, database *db.DB, raw string) (string, map[string]string, error) {
+	tokens := tokenizeFTS(raw)
+	corrections := make(map[string]string)
+
+	var vocabulary []vocabularyTerm
+	for i, token := range tokens {
+		// Prefix terms and phrases are left alone, and short words have too many close neighbors
+		if token.kind != ftsTerm || token.prefix || len([]rune(token.text)) < 4 || language.HasSegmented(token.text) {
+			continue
+		}
+		term := strings.ToLower(token.text)
+
+		var known int
+		if err := database.GetContext(ctx, &known, "SELECT COUNT(*) FROM articles_fts_vocab WHERE term = ?", term); err != nil {
+			return "", nil, fmt.Errorf("failed to look up term: %w", err)
+		}
+		if known > 0 {
+			continue
+		}
+
+		if vocabulary == nil {
+			if err := database.SelectContext(ctx, &vocabulary, "SELECT term, doc FROM articles_fts_vocab"); err != nil {
+				return "", nil, fmt.Errorf("failed to read search vocabulary: %w", err)
+			}
+		}
+
+		if best, ok := closestTerm(term, vocabulary); ok {
+			corrections[token.text] = best
+			tokens[i].text = best
+		}
+	}
+
+	if len(corrections) == 0 {
+		return raw, nil, nil
+	}
+
+	return renderRaw(tokens), corrections, nil
+}
+
+type vocabularyTerm struct {
+	Term string `db:"term"`
+	Docs int    `db:"doc"`
+}
+
+// closestTerm finds the indexed term with the smallest edit distance, preferring common terms on ties.
+// Up to one edit is allowed for words of up to five letters and two for longer words.
+func closestTerm(term string, vocabulary []vocabularyTerm) (string, bool) {
+	maxDistance := 1
+	if len([]rune(term)) > 5 {
+		maxDistance = 2
+	}
+
+	best, bestDistance, bestDocs := "", maxDistance+1, 0
+	length := len([]rune(term))
+	for _, candidate := range vocabulary {
+		candidateLength := len([]rune(candidate.Term))
+		if candidateLength < length-maxDistance || candidateLength > length+maxDistance {
+			continue
+		}
+
+		distance := editDistance(term, candidate.Term)
+		if distance < bestDistance || (distance == bestDistance && candidate.Docs > bestDocs) {
+			best, bestDistance, bestDocs = candidate.Term, distance, candidate.Docs
+		}
+	}
+
+	return best, best != "" && bestDistance <= maxDistance
+}
+
+// editDistance is the optimal string alignment distance: insertions, deletions, substitutions,
+// and swaps of adjacent letters ("kuberentes" is one edit from "kubernetes")
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(s)][len(t)]
+}
+
+// renderRaw turns tokens back into user-style query text
+func renderRaw(tokens []ftsToken) string {
+	var parts []string
+	for _, token := range tokens {
+		var part string
+		switch token.kind {
+		case ftsOpen:
+			part = "("
+		case ftsClose:
+			part = ")"
+		case ftsOperator:
+			part = token.text
+		case ftsPhrase:
+			part = `"` + token.text + `"`
+		default:
+			part = token.text
+		}
+		if token.prefix {
+			part += "*"
+		}
+		if token.column != "" {
+			part = token.column + ":" + part
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
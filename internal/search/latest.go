@@ -0,0 +1,139 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"instapaper-cli/internal/model"
+	"instapaper-cli/internal/util"
+)
+
+// Dates that latest can order by
+const (
+	LatestByAdded     = "added"
+	LatestByFetched   = "fetched"
+	LatestByPublished = "published"
+)
+
+// latestColumns maps each --by value to the article column it orders and filters on
+var latestColumns = map[string]string{
+	LatestByAdded:     "a.instapapered_at",
+	LatestByFetched:   "a.synced_at",
+	LatestByPublished: "a.published_at",
+}
+
+// LatestOptions selects the most recent articles by one of their dates
+type LatestOptions struct {
+	// By is LatestByAdded (default), LatestByFetched, or LatestByPublished. Articles without
+	// that date (never fetched, or no publication date found) are left out.
+	By    string
+	Limit int
+	// Since and Until filter on the By date
+	Since string
+	Until string
+	// Folders (title or path) and Tags leave out articles in none of them; aliases are resolved
+	Folders    []string
+	Tags       []string
+	JSONOutput bool
+	CSVOutput  bool
+	HealthFilters
+}
+
+// Latest prints the most recent articles
+func (s *Search) Latest(ctx context.Context, opts LatestOptions) error {
+	results, err := s.FindLatest(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to get latest articles: %w", err)
+	}
+
+	switch {
+	case opts.CSVOutput:
+		return s.outputCSV(results, false)
+	case opts.JSONOutput:
+		if results == nil {
+			results = []model.SearchResult{}
+		}
+		return s.outputJSON(results)
+	}
+	return s.outputTable(results, nil)
+}
+
+// FindLatest returns non-obsolete articles newest first by the chosen date, with ties broken by
+// ID so the order is stable across runs
+func (s *Search) FindLatest(ctx context.Context, opts LatestOptions) ([]model.SearchResult, error) {
+	if opts.By == "" {
+		opts.By = LatestByAdded
+	}
+	column, ok := latestColumns[opts.By]
+	if !ok {
+		return nil, fmt.Errorf("invalid --by value: %s. Use %s, %s, or %s", opts.By, LatestByAdded, LatestByFetched, LatestByPublished)
+	}
+
+	conditions := []string{"a.obsolete = FALSE", column + " IS NOT NULL"}
+	var args []interface{}
+
+	if opts.Since != "" || opts.Until != "" {
+		sinceTime, untilTime, err := util.FormatDateRange(opts.Since, opts.Until)
+		if err != nil {
+			return nil, err
+		}
+
+		// Dates are stored in RFC 3339, which does not compare as text with a space-separated bound
+		if sinceTime != nil {
+			conditions = append(conditions, "datetime("+column+") >= datetime(?)")
+			args = append(args, sinceTime.Format("2006-01-02 15:04:05"))
+		}
+
+		if untilTime != nil {
+			conditions = append(conditions, "datetime("+column+") <= datetime(?)")
+			args = append(args, untilTime.Format("2006-01-02 15:04:05"))
+		}
+	}
+
+	selector, err := Selector{Folders: opts.Folders, Tags: opts.Tags}.WithAliases(s.db)
+	if err != nil {
+		return nil, err
+	}
+	selectorConditions, selectorArgs := selector.Conditions()
+	conditions = append(conditions, selectorConditions...)
+	args = append(args, selectorArgs...)
+
+	healthConditions, healthArgs := opts.healthConditions()
+	conditions = append(conditions, healthConditions...)
+	args = append(args, healthArgs...)
+
+	// Tags are aggregated in a subquery so the outer query orders articles alone and can use the date index
+	query := fmt.Sprintf(`
+		SELECT
+			a.id,
+			a.url,
+			a.title,
+			f.path_cache as folder_path,
+			(SELECT GROUP_CONCAT(t.title, ', ')
+			 FROM article_tags at JOIN tags t ON at.tag_id = t.id
+			 WHERE at.article_id = a.id) as tags,
+			a.synced_at,
+			a.failed_count,
+			a.status_code,
+			a.status,
+			a.instapapered_at,
+			a.published_at
+		FROM articles a
+		LEFT JOIN folders f ON a.folder_id = f.id
+		WHERE %s
+		ORDER BY %s DESC, a.id DESC
+	`, strings.Join(conditions, " AND "), column)
+
+	if opts.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, opts.Limit)
+	}
+
+	var results []model.SearchResult
+	if err := s.db.SelectContext(ctx, &results, query, args...); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
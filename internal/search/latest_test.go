@@ -0,0 +1,133 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"instapaper-cli/internal/db"
+	"instapaper-cli/migrations"
+)
+
+// newLatestTestDB returns a migrated temporary database holding:
+//
+//	id  added       fetched     published   folder         tags
+//	1   2024-01-01  2024-02-04  -           Work           go
+//	2   2024-01-03  -           2023-12-01  Work/Archive   go, db
+//	3   2024-01-03  2024-02-01  2023-11-01  Reading        -
+//	4   2024-01-02  2024-02-03  2023-12-05  Reading        db
+//	5   2024-01-05  2024-02-05  2024-01-01  Work           go (obsolete)
+func newLatestTestDB(t *testing.T) *db.DB {
+	t.Helper()
+
+	database, err := db.New(filepath.Join(t.TempDir(), "latest.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := database.RunMigrationsFS(migrations.FS); err != nil {
+		t.Fatal(err)
+	}
+
+	folders := []struct {
+		id     int64
+		title  string
+		parent interface{}
+	}{
+		{1, "Work", nil},
+		{2, "Archive", 1},
+		{3, "Reading", nil},
+	}
+	for _, folder := range folders {
+		if _, err := database.Exec("INSERT INTO folders (id, title, parent_id) VALUES (?, ?, ?)", folder.id, folder.title, folder.parent); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.UpdateFolderPaths(); err != nil {
+		t.Fatal(err)
+	}
+
+	articles := []struct {
+		id        int64
+		added     string
+		fetched   interface{}
+		published interface{}
+		folder    int64
+		tags      []string
+		obsolete  bool
+	}{
+		{1, "2024-01-01T09:00:00Z", "2024-02-04T09:00:00Z", nil, 1, []string{"go"}, false},
+		{2, "2024-01-03T09:00:00Z", nil, "2023-12-01T09:00:00Z", 2, []string{"go", "db"}, false},
+		{3, "2024-01-03T09:00:00Z", "2024-02-01T09:00:00Z", "2023-11-01T09:00:00Z", 3, nil, false},
+		{4, "2024-01-02T09:00:00Z", "2024-02-03T09:00:00Z", "2023-12-05T09:00:00Z", 3, []string{"db"}, false},
+		{5, "2024-01-05T09:00:00Z", "2024-02-05T09:00:00Z", "2024-01-01T09:00:00Z", 1, []string{"go"}, true},
+	}
+	for _, article := range articles {
+		if _, err := database.Exec(`
+			INSERT INTO articles (id, url, title, instapapered_at, synced_at, published_at, folder_id, obsolete)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, article.id, fmt.Sprintf("https://example.com/%d", article.id), "Article", article.added, article.fetched, article.published, article.folder, article.obsolete); err != nil {
+			t.Fatal(err)
+		}
+		if err := database.AddArticleTags(article.id, article.tags); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return database
+}
+
+func TestFindLatest(t *testing.T) {
+	s := New(newLatestTestDB(t))
+
+	tests := []struct {
+		name string
+		opts LatestOptions
+		want []int64
+	}{
+		{"newest added first, ties by ID", LatestOptions{}, []int64{3, 2, 4, 1}},
+		{"limit", LatestOptions{Limit: 2}, []int64{3, 2}},
+		{"by fetched leaves out unfetched", LatestOptions{By: LatestByFetched}, []int64{1, 4, 3}},
+		{"by published", LatestOptions{By: LatestByPublished, Limit: 1}, []int64{4}},
+		{"since and until", LatestOptions{Since: "2024-01-02", Until: "2024-01-02"}, []int64{4}},
+		{"until includes its day", LatestOptions{By: LatestByFetched, Until: "2024-02-03"}, []int64{4, 3}},
+		{"until by published", LatestOptions{By: LatestByPublished, Until: "2023-12-01"}, []int64{2, 3}},
+		{"folder by title", LatestOptions{Folders: []string{"reading"}}, []int64{3, 4}},
+		{"folder by path", LatestOptions{Folders: []string{"Work/Archive"}}, []int64{2}},
+		{"folder title is not a prefix", LatestOptions{Folders: []string{"Work"}}, []int64{1}},
+		{"tag", LatestOptions{Tags: []string{"go"}}, []int64{2, 1}},
+		{"any of several tags", LatestOptions{Tags: []string{"go", "db"}, Limit: 2}, []int64{2, 4}},
+		{"folder and tag", LatestOptions{Folders: []string{"Reading"}, Tags: []string{"db"}}, []int64{4}},
+		{"no match", LatestOptions{Tags: []string{"missing"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := s.FindLatest(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []int64
+			for _, result := range results {
+				got = append(got, result.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("FindLatest(%+v) = %v, want %v", tt.opts, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("FindLatest(%+v) = %v, want %v", tt.opts, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFindLatestRejectsUnknownDate(t *testing.T) {
+	s := New(newLatestTestDB(t))
+	if _, err := s.FindLatest(context.Background(), LatestOptions{By: "modified"}); err == nil {
+		t.Fatal("FindLatest with --by modified succeeded, want an error")
+	}
+}
@@ -0,0 +1,100 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"instapaper-cli/internal/util"
+)
+
+// PassageOptions controls how passages of an article are matched
+type PassageOptions struct {
+	Regex         bool // Pattern is a regular expression instead of literal text
+	CaseSensitive bool
+	Context       int // Paragraphs to include before and after each matching one
+	Limit         int // Most passages to return, 0 for all
+}
+
+// Passage is a run of consecutive paragraphs of an article holding one or more matches
+type Passage struct {
+	Start   int    `json:"start"` // First paragraph, counted from 1
+	End     int    `json:"end"`   // Last paragraph, inclusive
+	Matches int    `json:"matches"`
+	Text    string `json:"text"`
+}
+
+// PassageFinder finds the paragraphs of markdown matching a pattern
+type PassageFinder struct {
+	re   *regexp.Regexp
+	opts PassageOptions
+}
+
+// NewPassageFinder compiles a pattern. Patterns are literal text matched ignoring case unless the
+// options say otherwise.
+func NewPassageFinder(pattern string, opts PassageOptions) (*PassageFinder, error) {
+	if strings.TrimSpace(pattern) == "" {
+		return nil, fmt.Errorf("pattern is required")
+	}
+	if opts.Context < 0 {
+		return nil, fmt.Errorf("context must not be negative")
+	}
+
+	expr := pattern
+	if opts.Regex {
+		// Check the pattern as given, so errors quote it rather than the expression built from it
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+	} else {
+		expr = regexp.QuoteMeta(pattern)
+	}
+	if !opts.CaseSensitive {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return &PassageFinder{re: re, opts: opts}, nil
+}
+
+// Find returns the passages of markdown around matching paragraphs, with passages whose context
+// overlaps or touches merged into one, and the total number of matches
+func (f *PassageFinder) Find(markdown string) ([]Passage, int) {
+	paragraphs := util.Paragraphs(markdown)
+
+	var passages []Passage
+	total := 0
+	for i, paragraph := range paragraphs {
+		matches := len(f.re.FindAllStringIndex(paragraph, -1))
+		if matches == 0 {
+			continue
+		}
+		total += matches
+
+		start := max(0, i-f.opts.Context)
+		end := min(len(paragraphs)-1, i+f.opts.Context)
+		if n := len(passages); n > 0 && start <= passages[n-1].End {
+			passages[n-1].End = end + 1
+			passages[n-1].Matches += matches
+			continue
+		}
+		if f.opts.Limit > 0 && len(passages) == f.opts.Limit {
+			continue
+		}
+		passages = append(passages, Passage{Start: start + 1, End: end + 1, Matches: matches})
+	}
+
+	for i := range passages {
+		passages[i].Text = strings.Join(paragraphs[passages[i].Start-1:passages[i].End], "\n\n")
+	}
+	return passages, total
+}
+
+// Highlight wraps every match in text with before and after, e.g. "**" and "**"
+func (f *PassageFinder) Highlight(text, before, after string) string {
+	return f.re.ReplaceAllStringFunc(text, func(match string) string {
+		return before + match + after
+	})
+}
@@ -0,0 +1,72 @@
+package search
+
+import (
+	"context"
+	"strings"
+
+	"instapaper-cli/internal/model"
+)
+
+// unreadFolder is the Instapaper folder holding saves that were never archived
+const unreadFolder = "Unread"
+
+// RandomOptions filters the pool of articles Random draws from
+type RandomOptions struct {
+	Count    int
+	Tags     []string
+	Folders  []string
+	Unread   bool
+	Statuses []string
+}
+
+// Random returns up to opts.Count randomly chosen fetched, non-obsolete articles
+func (s *Search) Random(ctx context.Context, opts RandomOptions) ([]model.SearchResult, error) {
+	count := opts.Count
+	if count <= 0 {
+		count = 1
+	}
+
+	conditions := []string{"a.obsolete = FALSE", "a.synced_at IS NOT NULL"}
+
+	selector, err := Selector{Tags: opts.Tags, Folders: opts.Folders, Statuses: opts.Statuses}.WithAliases(s.db)
+	if err != nil {
+		return nil, err
+	}
+	selectorConditions, args := selector.Conditions()
+	conditions = append(conditions, selectorConditions...)
+
+	if opts.Unread {
+		conditions = append(conditions, "a.folder_id IN (SELECT id FROM folders WHERE title = ? COLLATE NOCASE)")
+		args = append(args, unreadFolder)
+	}
+
+	query := `
+		SELECT
+			a.id,
+			a.url,
+			a.title,
+			f.path_cache as folder_path,
+			GROUP_CONCAT(t.title, ', ') as tags,
+			a.synced_at,
+			a.failed_count,
+			a.status_code,
+			a.status,
+			a.instapapered_at
+		FROM articles a
+		LEFT JOIN folders f ON a.folder_id = f.id
+		LEFT JOIN article_tags at ON a.id = at.article_id
+		LEFT JOIN tags t ON at.tag_id = t.id
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		GROUP BY a.id
+		ORDER BY RANDOM()
+		LIMIT ?
+	`
+	args = append(args, count)
+
+	var results []model.SearchResult
+	if err := s.db.SelectContext(ctx, &results, query, args...); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
@@ -1,65 +1,193 @@
 package search
 
 import (
+	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
-	"text/tabwriter"
 
 	"instapaper-cli/internal/db"
 	"instapaper-cli/internal/model"
+	"instapaper-cli/internal/term"
 	"instapaper-cli/internal/util"
 )
 
 type Search struct {
-	db *db.DB
+	db      *db.DB
+	palette term.Palette
 }
 
 type SearchOptions struct {
-	Query      string
-	Field      string
-	UseFTS     bool
-	Limit      int
-	JSONOutput bool
-	Since      string
-	Until      string
+	Query        string
+	Field        string
+	UseFTS       bool
+	Limit        int
+	JSONOutput   bool
+	CSVOutput    bool
+	Since        string
+	Until        string
+	Explain      bool
+	HealthFilters
+	// Fuzzy retries an FTS search that found nothing with misspelled terms corrected
+	Fuzzy bool
+	// Facets adds counts per tag, folder, year, and domain over all matches, not only the first Limit
+	Facets bool
+	// FacetLimit keeps the top values of each facet (0 keeps all)
+	FacetLimit int
+	// Dedupe collapses results that resolve to the same page into the first of them
+	Dedupe bool
+	// CaseSensitive makes a LIKE search match the query's case exactly
+	CaseSensitive bool
+	// WholeWord makes a LIKE search match the query only as whole words, not inside longer ones
+	WholeWord bool
 }
 
+// FacetedResults is the JSON output of a search with facets
+type FacetedResults struct {
+	Total   int                  `json:"total"`
+	Results []model.SearchResult `json:"results"`
+	Facets  *Facets              `json:"facets"`
+}
+
+// fieldAliasKinds maps the search fields that hold tag and folder names to their alias kind
+var fieldAliasKinds = map[string]string{"tags": db.AliasTag, "folder": db.AliasFolder}
+
 func New(database *db.DB) *Search {
 	return &Search{db: database}
 }
 
-func (s *Search) Search(opts SearchOptions) error {
+// WithColor styles result tables for a terminal: status badges, highlighted query matches, and
+// colored columns
+func (s *Search) WithColor(color bool) *Search {
+	s.palette = term.NewPalette(color)
+	return s
+}
+
+func (s *Search) Search(ctx context.Context, opts SearchOptions) error {
 	// Allow empty query for latest articles functionality
-	if opts.Query == "" && opts.Field == "" && opts.Since == "" && opts.Until == "" {
-		return fmt.Errorf("search query or date filter is required")
+	if opts.Query == "" && opts.Field == "" && opts.Since == "" && opts.Until == "" && !opts.hasHealthFilters() {
+		return fmt.Errorf("search query, date filter, fetch health filter, or status filter is required")
+	}
+	if opts.UseFTS && (opts.CaseSensitive || opts.WholeWord) {
+		return fmt.Errorf("--case-sensitive and --whole-word apply to LIKE search, not --fts (full-text search already matches whole words, ignoring case)")
 	}
 
-	var results []model.SearchResult
-	var err error
-
-	if opts.UseFTS && opts.Query != "" {
-		results, err = s.searchFTS(opts)
-	} else if opts.Query != "" {
-		results, err = s.searchLike(opts)
-	} else {
-		// Handle case where we only have date filters (for latest command)
-		results, err = s.searchLike(opts)
+	// Facets count every match, and deduplication may collapse some, so fetch them all and
+	// apply the limit afterwards
+	limit := opts.Limit
+	if (opts.Facets && !opts.CSVOutput || opts.Dedupe) && !opts.Explain {
+		opts.Limit = 0
 	}
 
+	results, strategy, err := s.FindWithStrategy(ctx, opts)
 	if err != nil {
 		return fmt.Errorf("search failed: %w", err)
 	}
 
+	if opts.Explain {
+		return nil
+	}
+
+	if strategy.Name == StrategyFuzzy {
+		fmt.Fprintf(os.Stderr, "No exact matches for %q, showing fuzzy matches for %q\n", opts.Query, strategy.Query)
+	}
+
+	if opts.Dedupe {
+		if results, err = Dedupe(ctx, s.db, results); err != nil {
+			return fmt.Errorf("search failed: %w", err)
+		}
+	}
+
+	if !opts.Facets || opts.CSVOutput {
+		// CSV holds only the result rows; facets would not fit its columns
+		if limit > 0 && len(results) > limit {
+			results = results[:limit]
+		}
+		switch {
+		case opts.CSVOutput:
+			return s.outputCSV(results, opts.Dedupe)
+		case opts.JSONOutput:
+			return s.outputJSON(results)
+		}
+		return s.outputTable(results, opts.highlight())
+	}
+
+	facets, err := ComputeFacets(ctx, s.db, results, opts.FacetLimit)
+	if err != nil {
+		return err
+	}
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
 	if opts.JSONOutput {
-		return s.outputJSON(results)
+		if results == nil {
+			results = []model.SearchResult{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(FacetedResults{Total: facets.Total, Results: results, Facets: facets})
+	}
+
+	if err := s.outputTable(results, opts.highlight()); err != nil {
+		return err
+	}
+	if facets.Total > 0 {
+		fmt.Printf("\nShowing %d of %d matches\n%s", len(results), facets.Total, facets.Summary())
+	}
+	return nil
+}
+
+// Find runs a search and returns the results instead of printing them
+func (s *Search) Find(ctx context.Context, opts SearchOptions) ([]model.SearchResult, error) {
+	results, _, err := s.FindWithStrategy(ctx, opts)
+	return results, err
+}
+
+// FindWithStrategy runs a search and reports which strategy produced the results. With
+// opts.Fuzzy, an FTS search without hits is retried with misspelled terms corrected.
+func (s *Search) FindWithStrategy(ctx context.Context, opts SearchOptions) ([]model.SearchResult, Strategy, error) {
+	// Searching tags or folders for an alias searches its target
+	if kind := fieldAliasKinds[opts.Field]; kind != "" && opts.Query != "" {
+		target, _, err := s.db.ResolveAlias(kind, opts.Query)
+		if err != nil {
+			return nil, Strategy{}, err
+		}
+		opts.Query = target
+	}
+
+	if !opts.UseFTS || opts.Query == "" {
+		// An empty query with only date or health filters lists the latest articles
+		results, err := s.searchLike(ctx, opts)
+		return results, Strategy{Name: StrategyLike, Query: opts.Query}, err
+	}
+
+	strategy := Strategy{Name: StrategyFTS, Query: opts.Query}
+	results, err := s.searchFTS(ctx, opts)
+	if err != nil || len(results) > 0 || !opts.Fuzzy || opts.Explain {
+		return results, strategy, err
+	}
+
+	corrected, corrections, err := CorrectQuery(ctx, s.db, opts.Query)
+	if err != nil || len(corrections) == 0 {
+		return results, strategy, err
 	}
 
-	return s.outputTable(results)
+	opts.Query = corrected
+	results, err = s.searchFTS(ctx, opts)
+	if err != nil {
+		return nil, strategy, err
+	}
+
+	return results, Strategy{Name: StrategyFuzzy, Query: corrected, Corrections: corrections}, nil
 }
 
-func (s *Search) searchLike(opts SearchOptions) ([]model.SearchResult, error) {
+func (s *Search) searchLike(ctx context.Context, opts SearchOptions) ([]model.SearchResult, error) {
 	baseQuery := `
 		SELECT
 			a.id,
@@ -70,7 +198,9 @@ func (s *Search) searchLike(opts SearchOptions) ([]model.SearchResult, error) {
 			a.synced_at,
 			a.failed_count,
 			a.status_code,
-			a.instapapered_at
+			a.status,
+			a.instapapered_at,
+			a.published_at
 		FROM articles a
 		LEFT JOIN folders f ON a.folder_id = f.id
 		LEFT JOIN article_tags at ON a.id = at.article_id
@@ -91,39 +221,57 @@ func (s *Search) searchLike(opts SearchOptions) ([]model.SearchResult, error) {
 			return nil, err
 		}
 
+		// datetime() compares instants, whether a date is stored as RFC 3339 or with a space
 		if sinceTime != nil {
-			conditions = append(conditions, "a.instapapered_at >= ?")
+			conditions = append(conditions, "datetime(a.instapapered_at) >= datetime(?)")
 			args = append(args, sinceTime.Format("2006-01-02 15:04:05"))
 		}
 
 		if untilTime != nil {
-			conditions = append(conditions, "a.instapapered_at <= ?")
+			conditions = append(conditions, "datetime(a.instapapered_at) <= datetime(?)")
 			args = append(args, untilTime.Format("2006-01-02 15:04:05"))
 		}
 	}
 
+	// Add fetch health filtering
+	healthConditions, healthArgs := opts.healthConditions()
+	conditions = append(conditions, healthConditions...)
+	args = append(args, healthArgs...)
+
+	// Columns match the query with LIKE, ignoring case and, for titles, accents. Exact matches
+	// use REGEXP with the query's case or word boundaries instead.
+	pattern := "%" + opts.Query + "%"
+	match := func(column string) string { return column + " LIKE ? COLLATE NOCASE" }
+	titleMatch := "a.title_norm LIKE normalize_text(?)"
+	if opts.exact() {
+		pattern = opts.queryPattern()
+		match = func(column string) string { return column + " REGEXP ?" }
+		titleMatch = match("a.title")
+	}
+
 	if opts.Field != "" && opts.Query != "" {
 		switch opts.Field {
 		case "url":
-			conditions = append(conditions, "a.url LIKE ? COLLATE NOCASE")
+			conditions = append(conditions, match("a.url"))
 		case "title":
-			conditions = append(conditions, "a.title LIKE ? COLLATE NOCASE")
+			conditions = append(conditions, titleMatch)
 		case "content":
-			conditions = append(conditions, "a.content_md LIKE ? COLLATE NOCASE")
+			conditions = append(conditions, match("content_text(a.content_md)"))
+		case "selection":
+			conditions = append(conditions, match("a.selection"))
 		case "tags":
-			conditions = append(conditions, "t.title LIKE ? COLLATE NOCASE")
+			conditions = append(conditions, match("t.title"))
 		case "folder":
-			conditions = append(conditions, "(f.path_cache LIKE ? COLLATE NOCASE OR f.title LIKE ? COLLATE NOCASE)")
-			args = append(args, "%"+opts.Query+"%")
+			conditions = append(conditions, "("+match("f.path_cache")+" OR "+match("f.title")+")")
+			args = append(args, pattern)
 		default:
 			return nil, fmt.Errorf("invalid field: %s", opts.Field)
 		}
-		args = append(args, "%"+opts.Query+"%")
+		args = append(args, pattern)
 	} else if opts.Query != "" {
-		conditions = append(conditions, `(a.url LIKE ? COLLATE NOCASE OR a.title LIKE ? COLLATE NOCASE OR a.content_md LIKE ? COLLATE NOCASE
-		       OR t.title LIKE ? COLLATE NOCASE OR f.path_cache LIKE ? COLLATE NOCASE)`)
-		pattern := "%" + opts.Query + "%"
-		args = append(args, pattern, pattern, pattern, pattern, pattern)
+		conditions = append(conditions, `(`+match("a.url")+` OR `+titleMatch+` OR `+match("content_text(a.content_md)")+`
+		       OR `+match("a.selection")+` OR `+match("t.title")+` OR `+match("f.path_cache")+`)`)
+		args = append(args, pattern, pattern, pattern, pattern, pattern, pattern)
 	}
 
 	whereClause = "WHERE " + strings.Join(conditions, " AND ")
@@ -138,15 +286,19 @@ func (s *Search) searchLike(opts SearchOptions) ([]model.SearchResult, error) {
 		args = append(args, opts.Limit)
 	}
 
+	if opts.Explain {
+		return nil, s.explain(ctx, query, args, opts.JSONOutput)
+	}
+
 	var results []model.SearchResult
-	if err := s.db.Select(&results, query, args...); err != nil {
+	if err := s.db.SelectContext(ctx, &results, query, args...); err != nil {
 		return nil, err
 	}
 
 	return results, nil
 }
 
-func (s *Search) searchFTS(opts SearchOptions) ([]model.SearchResult, error) {
+func (s *Search) searchFTS(ctx context.Context, opts SearchOptions) ([]model.SearchResult, error) {
 	if opts.Query == "" {
 		return nil, fmt.Errorf("FTS search requires a query")
 	}
@@ -161,7 +313,9 @@ func (s *Search) searchFTS(opts SearchOptions) ([]model.SearchResult, error) {
 			a.synced_at,
 			a.failed_count,
 			a.status_code,
-			a.instapapered_at
+			a.status,
+			a.instapapered_at,
+			a.published_at
 		FROM articles a
 		LEFT JOIN folders f ON a.folder_id = f.id
 		LEFT JOIN article_tags at ON a.id = at.article_id
@@ -183,41 +337,29 @@ func (s *Search) searchFTS(opts SearchOptions) ([]model.SearchResult, error) {
 			return nil, err
 		}
 
+		// datetime() compares instants, whether a date is stored as RFC 3339 or with a space
 		if sinceTime != nil {
-			conditions = append(conditions, "a.instapapered_at >= ?")
+			conditions = append(conditions, "datetime(a.instapapered_at) >= datetime(?)")
 			args = append(args, sinceTime.Format("2006-01-02 15:04:05"))
 		}
 
 		if untilTime != nil {
-			conditions = append(conditions, "a.instapapered_at <= ?")
+			conditions = append(conditions, "datetime(a.instapapered_at) <= datetime(?)")
 			args = append(args, untilTime.Format("2006-01-02 15:04:05"))
 		}
 	}
 
-	if opts.Field != "" {
-		switch opts.Field {
-		case "url":
-			conditions = append(conditions, "articles_fts MATCH ?")
-			args = append(args, "url: "+opts.Query)
-		case "title":
-			conditions = append(conditions, "articles_fts MATCH ?")
-			args = append(args, "title: "+opts.Query)
-		case "content":
-			conditions = append(conditions, "articles_fts MATCH ?")
-			args = append(args, "content: "+opts.Query)
-		case "tags":
-			conditions = append(conditions, "articles_fts MATCH ?")
-			args = append(args, "tags: "+opts.Query)
-		case "folder":
-			conditions = append(conditions, "articles_fts MATCH ?")
-			args = append(args, "folder: "+opts.Query)
-		default:
-			return nil, fmt.Errorf("invalid field for FTS: %s", opts.Field)
-		}
-	} else {
-		conditions = append(conditions, "articles_fts MATCH ?")
-		args = append(args, opts.Query)
+	// Add fetch health filtering
+	healthConditions, healthArgs := opts.healthConditions()
+	conditions = append(conditions, healthConditions...)
+	args = append(args, healthArgs...)
+
+	ftsQuery, err := FTSQuery(opts.Query, opts.Field)
+	if err != nil {
+		return nil, err
 	}
+	conditions = append(conditions, "articles_fts MATCH ?")
+	args = append(args, ftsQuery)
 
 	whereClause = "WHERE " + strings.Join(conditions, " AND ")
 
@@ -231,30 +373,164 @@ func (s *Search) searchFTS(opts SearchOptions) ([]model.SearchResult, error) {
 		args = append(args, opts.Limit)
 	}
 
+	if opts.Explain {
+		return nil, s.explain(ctx, query, args, opts.JSONOutput)
+	}
+
 	var results []model.SearchResult
-	if err := s.db.Select(&results, query, args...); err != nil {
+	if err := s.db.SelectContext(ctx, &results, query, args...); err != nil {
 		return nil, err
 	}
 
 	return results, nil
 }
 
+// HealthFilters select articles by the state of their last fetch and their reading status
+type HealthFilters struct {
+	StatusCodes  []int
+	FailedOnly   bool
+	NeverFetched bool
+	FetchedOnly  bool
+	Statuses     []string
+	// LowQuality selects fetched articles whose extraction scored below db.LowQualityThreshold
+	LowQuality bool
+	// Languages are article languages; language.Auto must be resolved against the query first
+	Languages []string
+}
+
+// hasHealthFilters reports whether any fetch health or reading status filter is set
+func (h HealthFilters) hasHealthFilters() bool {
+	return len(h.StatusCodes) > 0 || h.FailedOnly || h.NeverFetched || h.FetchedOnly || len(h.Statuses) > 0 || h.LowQuality || len(h.Languages) > 0
+}
+
+// healthConditions builds WHERE conditions for the fetch health and reading status filters
+func (h HealthFilters) healthConditions() ([]string, []interface{}) {
+	conditions, args := Selector{StatusCodes: h.StatusCodes, Statuses: h.Statuses, Languages: h.Languages}.Conditions()
+
+	if h.FailedOnly {
+		conditions = append(conditions, "a.failed_count > 0")
+	}
+
+	if h.NeverFetched {
+		conditions = append(conditions, "a.synced_at IS NULL AND a.sync_failed_at IS NULL")
+	}
+
+	if h.FetchedOnly {
+		conditions = append(conditions, "a.synced_at IS NOT NULL")
+	}
+
+	if h.LowQuality {
+		conditions = append(conditions, "a.synced_at IS NOT NULL AND a.extraction_quality < ?")
+		args = append(args, db.LowQualityThreshold)
+	}
+
+	return conditions, args
+}
+
+// explain prints the SQLite query plan for a search query instead of running it
+func (s *Search) explain(ctx context.Context, query string, args []interface{}, jsonOutput bool) error {
+	steps, err := s.db.ExplainQueryPlan(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(steps)
+	}
+
+	fmt.Println("QUERY PLAN")
+
+	// Steps reference their parent by id; indent each step below its parent
+	depth := map[int]int{0: 0}
+	for _, step := range steps {
+		level := depth[step.Parent] + 1
+		depth[step.ID] = level
+		fmt.Printf("%s%s\n", strings.Repeat("  ", level-1)+"|--", step.Detail)
+	}
+
+	return nil
+}
+
+// PrintResults prints results as a table or JSON, the same way Search does
+func (s *Search) PrintResults(results []model.SearchResult, jsonOutput bool) error {
+	if jsonOutput {
+		return s.outputJSON(results)
+	}
+	return s.outputTable(results, nil)
+}
+
 func (s *Search) outputJSON(results []model.SearchResult) error {
 	encoder := json.NewEncoder(os.Stdout)
 	encoder.SetIndent("", "  ")
 	return encoder.Encode(results)
 }
 
-func (s *Search) outputTable(results []model.SearchResult) error {
+// outputCSV writes one row per result with a header, for spreadsheets and other tools. Deduplicated
+// results also list the IDs collapsed into each row.
+func (s *Search) outputCSV(results []model.SearchResult, deduped bool) error {
+	w := csv.NewWriter(os.Stdout)
+	header := []string{"id", "title", "url", "folder", "tags", "instapapered_at", "synced_at", "status_code", "failed_count"}
+	if deduped {
+		header = append(header, "duplicate_ids")
+	}
+	w.Write(header)
+
+	for _, result := range results {
+		var folder, tags, synced, status string
+		if result.FolderPath != nil {
+			folder = *result.FolderPath
+		}
+		if result.Tags != nil {
+			tags = *result.Tags
+		}
+		if result.SyncedAt != nil {
+			synced = *result.SyncedAt
+		}
+		if result.StatusCode != nil {
+			status = strconv.Itoa(*result.StatusCode)
+		}
+
+		row := []string{
+			strconv.FormatInt(result.ID, 10), result.Title, result.URL, folder, tags,
+			result.InstapaperedAt, synced, status, strconv.Itoa(result.FailedCount),
+		}
+		if deduped {
+			duplicates := make([]string, len(result.DuplicateIDs))
+			for i, id := range result.DuplicateIDs {
+				duplicates[i] = strconv.FormatInt(id, 10)
+			}
+			row = append(row, strings.Join(duplicates, " "))
+		}
+		w.Write(row)
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// outputTable prints results in aligned columns. With color, each row also has a status badge,
+// and matches of highlight are painted in the title, URL, folder, and tags.
+func (s *Search) outputTable(results []model.SearchResult, highlight *regexp.Regexp) error {
 	if len(results) == 0 {
 		fmt.Println("No results found.")
 		return nil
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	defer w.Flush()
-
-	fmt.Fprintln(w, "ID\tTITLE\tURL\tFOLDER\tTAGS\tSYNCED\tFAILED")
+	p := s.palette
+	header := []string{"ID", "TITLE", "URL", "FOLDER", "TAGS", "SYNCED", "FAILED"}
+	if p.Color() {
+		header = slices.Insert(header, 1, "STATUS")
+		for i, name := range header {
+			header[i] = p.Paint(term.Bold, name)
+		}
+	}
+	var table term.Table
+	table.Row(header...)
 
 	for _, result := range results {
 		id := fmt.Sprintf("%d", result.ID)
@@ -263,6 +539,10 @@ func (s *Search) outputTable(results []model.SearchResult) error {
 		if len(title) > 50 {
 			title = title[:47] + "..."
 		}
+		title = p.Highlight(title, highlight, term.Bold)
+		if len(result.DuplicateIDs) > 0 {
+			title += p.Paint(term.Dim, fmt.Sprintf(" (+%d)", len(result.DuplicateIDs)))
+		}
 
 		url := result.URL
 		if len(url) > 60 {
@@ -285,19 +565,79 @@ func (s *Search) outputTable(results []model.SearchResult) error {
 			}
 		}
 
-		synced := "No"
+		synced := p.Paint(term.Yellow, "No")
 		if result.SyncedAt != nil {
-			synced = "Yes"
+			synced = p.Paint(term.Green, "Yes")
 		}
 
 		failed := ""
 		if result.FailedCount > 0 {
-			failed = fmt.Sprintf("%d", result.FailedCount)
+			failed = p.Paint(term.Red, fmt.Sprintf("%d", result.FailedCount))
 		}
 
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-			id, title, url, folder, tags, synced, failed)
+		if !p.Color() {
+			table.Row(id, title, url, folder, tags, synced, failed)
+			continue
+		}
+		table.Row(p.Paint(term.Dim, id), p.Badge(result.Status), title, p.Highlight(url, highlight, term.Dim),
+			p.Highlight(folder, highlight, term.Blue), p.Highlight(tags, highlight, term.Cyan), synced, failed)
 	}
 
-	return nil
-}
\ No newline at end of file
+	return table.Write(os.Stdout)
+}
+
+// exact reports whether a LIKE search matches case or whole words, which LIKE cannot do
+func (opts SearchOptions) exact() bool {
+	return !opts.UseFTS && (opts.CaseSensitive || opts.WholeWord)
+}
+
+// queryPattern is the REGEXP pattern of an exact search: the query as literal text, ignoring case
+// unless CaseSensitive, and between non-word characters or the ends of the text if WholeWord.
+// RE2's \b only knows ASCII words, so it would not end a word like "café".
+func (opts SearchOptions) queryPattern() string {
+	pattern := regexp.QuoteMeta(opts.Query)
+	if opts.WholeWord {
+		pattern = `(?:^|[^\pL\pN_])` + pattern + `(?:[^\pL\pN_]|$)`
+	}
+	if !opts.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	return pattern
+}
+
+// highlight matches the query's terms in result columns, ignoring case; nil when there are none.
+// An FTS query's terms and phrases are matched apart, leaving out operators and NOT terms.
+func (opts SearchOptions) highlight() *regexp.Regexp {
+	if opts.exact() && opts.Query != "" {
+		return regexp.MustCompile(opts.queryPattern())
+	}
+
+	var terms []string
+	if !opts.UseFTS {
+		terms = append(terms, opts.Query)
+	} else {
+		negated := false
+		for _, token := range tokenizeFTS(opts.Query) {
+			switch token.kind {
+			case ftsOperator:
+				negated = token.text == "NOT"
+			case ftsTerm, ftsPhrase:
+				if !negated {
+					terms = append(terms, token.text)
+				}
+				negated = false
+			}
+		}
+	}
+
+	var patterns []string
+	for _, t := range terms {
+		if strings.TrimSpace(t) != "" {
+			patterns = append(patterns, regexp.QuoteMeta(t))
+		}
+	}
+	if len(patterns) == 0 {
+		return nil
+	}
+	return regexp.MustCompile("(?i)" + strings.Join(patterns, "|"))
+}
@@ -0,0 +1,118 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"instapaper-cli/internal/db"
+)
+
+// Selector narrows a query to specific articles. Conditions reference the
+// articles table through the alias "a" so they can be added to any query.
+type Selector struct {
+	IDs         []int64
+	Folders     []string
+	Tags        []string
+	Domains     []string
+	StatusCodes []int
+	// Statuses are reading statuses, e.g. inbox or reading
+	Statuses []string
+	// Languages are detected article languages, e.g. en or ja
+	Languages []string
+}
+
+// IsEmpty reports whether no selector is set
+func (sel Selector) IsEmpty() bool {
+	return len(sel.IDs) == 0 && len(sel.Folders) == 0 && len(sel.Tags) == 0 &&
+		len(sel.Domains) == 0 && len(sel.StatusCodes) == 0 && len(sel.Statuses) == 0 && len(sel.Languages) == 0
+}
+
+// WithAliases returns the selector with tag and folder aliases replaced by their targets
+func (sel Selector) WithAliases(database *db.DB) (Selector, error) {
+	var err error
+	if sel.Tags, err = database.ResolveAliases(db.AliasTag, sel.Tags); err != nil {
+		return sel, err
+	}
+	if sel.Folders, err = database.ResolveAliases(db.AliasFolder, sel.Folders); err != nil {
+		return sel, err
+	}
+	return sel, nil
+}
+
+// Conditions builds WHERE conditions and arguments for the selector
+func (sel Selector) Conditions() ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if len(sel.IDs) > 0 {
+		for _, id := range sel.IDs {
+			args = append(args, id)
+		}
+		conditions = append(conditions, fmt.Sprintf("a.id IN (%s)", placeholders(len(sel.IDs))))
+	}
+
+	// Folders match by title or full path, e.g. "Work" or "Projects/Work"
+	if len(sel.Folders) > 0 {
+		for _, folder := range sel.Folders {
+			args = append(args, folder)
+		}
+		for _, folder := range sel.Folders {
+			args = append(args, folder)
+		}
+		conditions = append(conditions, fmt.Sprintf(`a.folder_id IN (
+			SELECT id FROM folders
+			WHERE title COLLATE NOCASE IN (%s) OR path_cache COLLATE NOCASE IN (%s)
+		)`, placeholders(len(sel.Folders)), placeholders(len(sel.Folders))))
+	}
+
+	if len(sel.Tags) > 0 {
+		for _, tag := range sel.Tags {
+			args = append(args, tag)
+		}
+		conditions = append(conditions, fmt.Sprintf(`a.id IN (
+			SELECT sat.article_id FROM article_tags sat
+			JOIN tags st ON sat.tag_id = st.id
+			WHERE st.title COLLATE NOCASE IN (%s)
+		)`, placeholders(len(sel.Tags))))
+	}
+
+	// Domains also match their subdomains, so example.com selects blog.example.com
+	if len(sel.Domains) > 0 {
+		var domainConditions []string
+		for _, domain := range sel.Domains {
+			domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "www.")
+			domainConditions = append(domainConditions,
+				"a.url LIKE ? OR a.url LIKE ? OR a.url LIKE ? OR a.url LIKE ?")
+			args = append(args, "%://"+domain, "%://"+domain+"/%", "%://%."+domain, "%://%."+domain+"/%")
+		}
+		conditions = append(conditions, "("+strings.Join(domainConditions, " OR ")+")")
+	}
+
+	if len(sel.StatusCodes) > 0 {
+		for _, code := range sel.StatusCodes {
+			args = append(args, code)
+		}
+		conditions = append(conditions, fmt.Sprintf("a.status_code IN (%s)", placeholders(len(sel.StatusCodes))))
+	}
+
+	if len(sel.Statuses) > 0 {
+		for _, status := range sel.Statuses {
+			args = append(args, status)
+		}
+		conditions = append(conditions, fmt.Sprintf("a.status IN (%s)", placeholders(len(sel.Statuses))))
+	}
+
+	if len(sel.Languages) > 0 {
+		for _, lang := range sel.Languages {
+			args = append(args, lang)
+		}
+		conditions = append(conditions, fmt.Sprintf("a.language IN (%s)", placeholders(len(sel.Languages))))
+	}
+
+	return conditions, args
+}
+
+// placeholders returns n comma-separated "?" placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
@@ -0,0 +1,106 @@
+package similarity
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+// NearDuplicateDistance is the largest Hamming distance at which two signatures
+// are considered the same document (e.g. a re-saved or syndicated copy); unrelated
+// articles land this close by chance far less than once in a billion comparisons
+const NearDuplicateDistance = 6
+
+// DefaultMaxDistance keeps articles that share clearly more vocabulary than chance;
+// unrelated articles differ in about 32 bits
+const DefaultMaxDistance = 24
+
+// stopWords are frequent words that carry no topical signal
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "but": true, "not": true,
+	"you": true, "all": true, "any": true, "can": true, "had": true, "her": true,
+	"was": true, "one": true, "our": true, "out": true, "has": true, "his": true,
+	"how": true, "its": true, "who": true, "did": true, "get": true, "may": true,
+	"him": true, "use": true, "she": true, "too": true, "way": true,
+	"that": true, "this": true, "with": true, "from": true, "they": true,
+	"have": true, "been": true, "their": true, "said": true, "each": true,
+	"which": true, "there": true, "what": true, "would": true, "about": true,
+	"could": true, "other": true, "after": true, "first": true, "never": true,
+	"these": true, "think": true, "where": true, "being": true, "every": true,
+	"great": true, "might": true, "shall": true, "still": true, "those": true,
+	"while": true, "should": true, "through": true, "before": true, "around": true,
+	"also": true, "into": true, "just": true, "like": true, "more": true,
+	"most": true, "only": true, "some": true, "than": true, "then": true,
+	"them": true, "very": true, "when": true, "will": true, "your": true,
+	"were": true, "here": true, "http": true, "https": true, "www": true,
+}
+
+// Words splits text into lowercase runs of letters and digits
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// IsStopWord reports whether a lowercase word is too common to carry topical signal
+func IsStopWord(word string) bool {
+	return stopWords[word]
+}
+
+// Tokens splits text into lowercase words of at least three letters, skipping stop words
+func Tokens(text string) []string {
+	words := Words(text)
+
+	tokens := words[:0]
+	for _, word := range words {
+		if len([]rune(word)) >= 3 && !stopWords[word] {
+			tokens = append(tokens, word)
+		}
+	}
+
+	return tokens
+}
+
+// Signature computes a 64-bit SimHash of text, weighting each word by its frequency.
+// Texts with similar vocabularies get signatures with a small Hamming distance.
+func Signature(text string) uint64 {
+	counts := make(map[string]int)
+	for _, token := range Tokens(text) {
+		counts[token]++
+	}
+
+	var weights [64]int
+	for token, count := range counts {
+		h := fnv.New64a()
+		h.Write([]byte(token))
+		hash := h.Sum64()
+
+		for bit := 0; bit < 64; bit++ {
+			if hash&(1<<uint(bit)) != 0 {
+				weights[bit] += count
+			} else {
+				weights[bit] -= count
+			}
+		}
+	}
+
+	var signature uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			signature |= 1 << uint(bit)
+		}
+	}
+
+	return signature
+}
+
+// Distance returns the number of differing bits between two signatures (0-64)
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Score converts a Hamming distance into a similarity between 0 and 1
+func Score(distance int) float64 {
+	return 1 - float64(distance)/64
+}
@@ -0,0 +1,131 @@
+package similarity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"instapaper-cli/internal/db"
+)
+
+// Match is an article similar to a reference article
+type Match struct {
+	ID         int64   `json:"id"`
+	Title      string  `json:"title"`
+	URL        string  `json:"url"`
+	Distance   int     `json:"distance"`
+	Similarity float64 `json:"similarity"`
+}
+
+// Finder looks up similar articles by their stored SimHash signatures
+type Finder struct {
+	db *db.DB
+}
+
+func New(database *db.DB) *Finder {
+	return &Finder{db: database}
+}
+
+// UpdateSignatures computes signatures for fetched articles that do not have one yet
+func (f *Finder) UpdateSignatures(ctx context.Context) (int, error) {
+	var ids []int64
+	if err := f.db.SelectContext(ctx, &ids, `
+		SELECT id FROM articles
+		WHERE content_md IS NOT NULL AND simhash IS NULL
+	`); err != nil {
+		return 0, fmt.Errorf("failed to find unsigned articles: %w", err)
+	}
+
+	for i, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return i, err
+		}
+
+		var content string
+		if err := f.db.GetContext(ctx, &content, "SELECT content_text(content_md) FROM articles WHERE id = ?", id); err != nil {
+			return i, fmt.Errorf("failed to read content of article %d: %w", id, err)
+		}
+
+		if _, err := f.db.ExecContext(ctx, "UPDATE articles SET simhash = ? WHERE id = ?", int64(Signature(content)), id); err != nil {
+			return i, fmt.Errorf("failed to store signature of article %d: %w", id, err)
+		}
+	}
+
+	return len(ids), nil
+}
+
+// Similar returns up to limit non-obsolete articles within maxDistance of the
+// article's signature, closest first. Signatures missing from older articles are computed in
+// memory, so lookups never write; UpdateSignatures (run by doctor) stores them.
+func (f *Finder) Similar(ctx context.Context, id int64, limit, maxDistance int) ([]Match, error) {
+	var reference struct {
+		SimHash sql.NullInt64  `db:"simhash"`
+		Content sql.NullString `db:"content"`
+	}
+	if err := f.db.GetContext(ctx, &reference, `
+		SELECT simhash, CASE WHEN simhash IS NULL THEN content_text(content_md) END AS content
+		FROM articles WHERE id = ?
+	`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("article %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to get signature of article %d: %w", id, err)
+	}
+	if !reference.SimHash.Valid && !reference.Content.Valid {
+		return nil, fmt.Errorf("article %d has no fetched content to compare", id)
+	}
+	signature := uint64(reference.SimHash.Int64)
+	if !reference.SimHash.Valid {
+		signature = Signature(reference.Content.String)
+	}
+
+	var candidates []struct {
+		ID      int64          `db:"id"`
+		Title   string         `db:"title"`
+		URL     string         `db:"url"`
+		SimHash sql.NullInt64  `db:"simhash"`
+		Content sql.NullString `db:"content"`
+	}
+	if err := f.db.SelectContext(ctx, &candidates, `
+		SELECT id, title, url, simhash, CASE WHEN simhash IS NULL THEN content_text(content_md) END AS content
+		FROM articles
+		WHERE (simhash IS NOT NULL OR content_md IS NOT NULL) AND obsolete = FALSE AND id != ?
+	`, id); err != nil {
+		return nil, fmt.Errorf("failed to get candidate articles: %w", err)
+	}
+
+	var matches []Match
+	for _, candidate := range candidates {
+		candidateSignature := uint64(candidate.SimHash.Int64)
+		if !candidate.SimHash.Valid {
+			candidateSignature = Signature(candidate.Content.String)
+		}
+
+		distance := Distance(signature, candidateSignature)
+		if distance > maxDistance {
+			continue
+		}
+
+		matches = append(matches, Match{
+			ID:         candidate.ID,
+			Title:      candidate.Title,
+			URL:        candidate.URL,
+			Distance:   distance,
+			Similarity: Score(distance),
+		})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].ID < matches[j].ID
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,98 @@
+package tagging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"instapaper-cli/internal/util"
+)
+
+// llmContentLimit caps how much article content is sent to the LLM command
+const llmContentLimit = 6000
+
+// llmVocabularyLimit caps how many existing tags are listed in the prompt
+const llmVocabularyLimit = 200
+
+// LLMSuggest pipes a tagging prompt to an external command (e.g. "llm -m gpt-4o-mini")
+// and parses the comma- or newline-separated tags it prints. Existing vocabulary is
+// included in the prompt so the model prefers tags that are already in use.
+func (s *Suggester) LLMSuggest(ctx context.Context, command string, articleID int64, max int) ([]Suggestion, error) {
+	var article struct {
+		Title   string  `db:"title"`
+		Content *string `db:"content_md"`
+	}
+	if err := s.db.GetContext(ctx, &article, "SELECT title, content_text(content_md) AS content_md FROM articles WHERE id = ?", articleID); err != nil {
+		return nil, fmt.Errorf("failed to get article %d: %w", articleID, err)
+	}
+	if article.Content == nil || *article.Content == "" {
+		return nil, fmt.Errorf("article %d has no fetched content", articleID)
+	}
+
+	vocabulary, err := s.Vocabulary(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]string, len(vocabulary))
+	for _, tag := range vocabulary {
+		known[strings.ToLower(tag)] = tag
+	}
+
+	if len(vocabulary) > llmVocabularyLimit {
+		vocabulary = vocabulary[:llmVocabularyLimit]
+	}
+
+	current, err := s.articleTags(ctx, articleID)
+	if err != nil {
+		return nil, err
+	}
+
+	title, content := article.Title, util.TruncateUTF8(*article.Content, llmContentLimit)
+
+	var prompt strings.Builder
+	prompt.WriteString(fmt.Sprintf("Suggest up to %d short, lowercase tags for the article below.\n", max))
+	prompt.WriteString("Prefer tags from the existing list when they fit. Reply with only the tags, separated by commas.\n\n")
+	if len(vocabulary) > 0 {
+		prompt.WriteString("Existing tags: " + strings.Join(vocabulary, ", ") + "\n\n")
+	}
+	prompt.WriteString("Title: " + title + "\n\n")
+	prompt.WriteString(content)
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = strings.NewReader(prompt.String())
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("LLM command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var suggestions []Suggestion
+	seen := make(map[string]bool)
+	fields := strings.FieldsFunc(stdout.String(), func(r rune) bool { return r == ',' || r == '\n' })
+	for _, tag := range fields {
+		tag = strings.ToLower(strings.Trim(tag, " \t\r-*#\"'`."))
+		if tag == "" || seen[tag] || current[tag] {
+			continue
+		}
+		seen[tag] = true
+
+		suggestion := Suggestion{Tag: tag, Score: 1, Source: SourceLLM, New: true}
+		if existing, ok := known[tag]; ok {
+			suggestion.Tag = existing
+			suggestion.New = false
+		}
+		suggestions = append(suggestions, suggestion)
+
+		if max > 0 && len(suggestions) >= max {
+			break
+		}
+	}
+
+	return suggestions, nil
+}
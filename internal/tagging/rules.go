@@ -0,0 +1,132 @@
+package tagging
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rules tags and files new articles by URL, domain, and title, from a YAML file
+type Rules struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule applies its tags and folder to articles matching all of its conditions
+type Rule struct {
+	Name string `yaml:"name"`
+	// URL is a regular expression matched against the article URL
+	URL string `yaml:"url"`
+	// Domain matches a host and its subdomains
+	Domain string `yaml:"domain"`
+	// TitleContains matches titles containing the text, ignoring case
+	TitleContains string `yaml:"title_contains"`
+
+	Tags []string `yaml:"tags"`
+	// Folder is a folder path; slashes create a hierarchy
+	Folder string `yaml:"folder"`
+
+	urlPattern *regexp.Regexp
+}
+
+// RuleMatch is what the rules matching an article apply to it
+type RuleMatch struct {
+	Rules []string `json:"rules"`
+	Tags  []string `json:"tags,omitempty"`
+	// Folder comes from the first matching rule with a folder
+	Folder string `json:"folder,omitempty"`
+}
+
+// LoadRules reads and validates tagging rules from a YAML file. An empty path means no rules.
+func LoadRules(path string) (*Rules, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tag rules: %w", err)
+	}
+
+	var rules Rules
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse tag rules: %w", err)
+	}
+
+	for i := range rules.Rules {
+		rule := &rules.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		rule.Domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(rule.Domain)), "www.")
+		rule.TitleContains = strings.ToLower(rule.TitleContains)
+
+		if rule.URL == "" && rule.Domain == "" && rule.TitleContains == "" {
+			return nil, fmt.Errorf("%s has no url, domain, or title_contains condition", rule.Name)
+		}
+		if len(rule.Tags) == 0 && strings.TrimSpace(rule.Folder) == "" {
+			return nil, fmt.Errorf("%s has no tags or folder to apply", rule.Name)
+		}
+		if rule.URL != "" {
+			if rule.urlPattern, err = regexp.Compile(rule.URL); err != nil {
+				return nil, fmt.Errorf("%s: invalid url pattern: %w", rule.Name, err)
+			}
+		}
+	}
+
+	return &rules, nil
+}
+
+// Matches reports whether an article with this URL and title meets all of the rule's conditions
+func (r *Rule) Matches(rawURL, title string) bool {
+	if r.urlPattern != nil && !r.urlPattern.MatchString(rawURL) {
+		return false
+	}
+	if r.Domain != "" {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return false
+		}
+		host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+		if host != r.Domain && !strings.HasSuffix(host, "."+r.Domain) {
+			return false
+		}
+	}
+	if r.TitleContains != "" && !strings.Contains(strings.ToLower(title), r.TitleContains) {
+		return false
+	}
+	return true
+}
+
+// Apply collects the tags and folder of every rule matching an article, or returns nil when none match
+func (r *Rules) Apply(rawURL, title string) *RuleMatch {
+	if r == nil {
+		return nil
+	}
+
+	var match *RuleMatch
+	seen := make(map[string]bool)
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+		if !rule.Matches(rawURL, title) {
+			continue
+		}
+		if match == nil {
+			match = &RuleMatch{}
+		}
+		match.Rules = append(match.Rules, rule.Name)
+		for _, tag := range rule.Tags {
+			if tag = strings.TrimSpace(tag); tag != "" && !seen[tag] {
+				seen[tag] = true
+				match.Tags = append(match.Tags, tag)
+			}
+		}
+		if match.Folder == "" {
+			match.Folder = strings.TrimSpace(rule.Folder)
+		}
+	}
+	return match
+}
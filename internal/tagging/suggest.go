@@ -0,0 +1,362 @@
+package tagging
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"instapaper-cli/internal/db"
+	"instapaper-cli/internal/similarity"
+)
+
+// Suggestion sources
+const (
+	SourceVocabulary = "vocabulary" // an existing tag whose words appear in the content
+	SourceSimilar    = "similar"    // an existing tag carried by similar articles
+	SourceKeyword    = "keyword"    // a distinctive TF-IDF keyword that is not a tag yet
+	SourceLLM        = "llm"        // proposed by an external LLM command
+)
+
+// similarNeighbors is how many similar tagged articles vote for their tags
+const similarNeighbors = 10
+
+// minKeywordCount is how often a word must occur before it is proposed as a new tag
+const minKeywordCount = 2
+
+// Suggestion is a proposed tag for an article
+type Suggestion struct {
+	Tag    string  `json:"tag"`
+	Score  float64 `json:"score"`
+	Source string  `json:"source"`
+	New    bool    `json:"new"`
+}
+
+// Suggester proposes tags from article content, the existing tag vocabulary, and similar articles
+type Suggester struct {
+	db     *db.DB
+	finder *similarity.Finder
+
+	// Corpus statistics, loaded on first use
+	documents int
+	docFreq   map[string]int
+	tags      map[string]string // lowercased title -> title
+}
+
+func New(database *db.DB) *Suggester {
+	return &Suggester{db: database, finder: similarity.New(database)}
+}
+
+// UntaggedArticleIDs returns fetched, non-obsolete articles without tags, newest first
+func (s *Suggester) UntaggedArticleIDs(ctx context.Context, limit int) ([]int64, error) {
+	query := `
+		SELECT a.id FROM articles a
+		WHERE a.obsolete = FALSE AND a.content_md IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM article_tags at WHERE at.article_id = a.id)
+		ORDER BY a.instapapered_at DESC
+	`
+	var args []interface{}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	var ids []int64
+	if err := s.db.SelectContext(ctx, &ids, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to find untagged articles: %w", err)
+	}
+	return ids, nil
+}
+
+// Vocabulary returns existing tag titles, most used first
+func (s *Suggester) Vocabulary(ctx context.Context, limit int) ([]string, error) {
+	query := `
+		SELECT t.title FROM tags t
+		LEFT JOIN article_tags at ON t.id = at.tag_id
+		GROUP BY t.id
+		ORDER BY COUNT(at.article_id) DESC, t.title
+	`
+	var args []interface{}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	var titles []string
+	if err := s.db.SelectContext(ctx, &titles, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get tag vocabulary: %w", err)
+	}
+	return titles, nil
+}
+
+// Suggest proposes up to max tags for an article that it does not already carry.
+// Existing tags are ranked first so the vocabulary stays consistent; new keywords fill remaining slots.
+func (s *Suggester) Suggest(ctx context.Context, articleID int64, max int) ([]Suggestion, error) {
+	if err := s.loadCorpus(ctx); err != nil {
+		return nil, err
+	}
+
+	var content sql.NullString
+	if err := s.db.GetContext(ctx, &content, "SELECT content_text(content_md) FROM articles WHERE id = ?", articleID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("article %d not found", articleID)
+		}
+		return nil, fmt.Errorf("failed to get article %d: %w", articleID, err)
+	}
+	if !content.Valid || content.String == "" {
+		return nil, fmt.Errorf("article %d has no fetched content", articleID)
+	}
+
+	current, err := s.articleTags(ctx, articleID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, word := range similarity.Words(content.String) {
+		counts[word]++
+	}
+
+	scores := make(map[string]float64) // existing tag title -> score
+	sources := make(map[string]string)
+
+	// Existing tags whose words all appear in the content, normalized against the strongest keyword
+	keywords := s.keywords(counts)
+	best := 1.0
+	if len(keywords) > 0 {
+		best = keywords[0].score
+	}
+	for lower, title := range s.tags {
+		if current[lower] {
+			continue
+		}
+		if score := s.tagScore(lower, counts); score > 0 {
+			scores[title] = math.Min(score/best, 1)
+			sources[title] = SourceVocabulary
+		}
+	}
+
+	// Tags of similar articles, weighted by how similar they are
+	votes, err := s.neighborVotes(ctx, articleID)
+	if err != nil {
+		return nil, err
+	}
+	for lower, vote := range votes {
+		if current[lower] {
+			continue
+		}
+		title, ok := s.tags[lower]
+		if !ok {
+			title = lower
+		}
+		if _, ok := sources[title]; !ok || vote > scores[title] {
+			sources[title] = SourceSimilar
+		}
+		scores[title] += vote
+	}
+
+	var suggestions []Suggestion
+	for title, score := range scores {
+		suggestions = append(suggestions, Suggestion{Tag: title, Score: math.Min(score, 1), Source: sources[title]})
+	}
+	sortSuggestions(suggestions)
+
+	// Fill remaining slots with distinctive keywords that are not tags yet
+	for _, keyword := range keywords {
+		if max > 0 && len(suggestions) >= max {
+			break
+		}
+		if _, isTag := s.tags[keyword.word]; isTag {
+			continue
+		}
+		suggestions = append(suggestions, Suggestion{Tag: keyword.word, Score: keyword.score / best, Source: SourceKeyword, New: true})
+	}
+
+	if max > 0 && len(suggestions) > max {
+		suggestions = suggestions[:max]
+	}
+
+	return suggestions, nil
+}
+
+// loadCorpus computes document frequencies over all fetched articles and loads the tag vocabulary
+func (s *Suggester) loadCorpus(ctx context.Context) error {
+	if s.docFreq != nil {
+		return nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT content_text(content_md) FROM articles WHERE content_md IS NOT NULL AND obsolete = FALSE")
+	if err != nil {
+		return fmt.Errorf("failed to read articles: %w", err)
+	}
+	defer rows.Close()
+
+	docFreq := make(map[string]int)
+	documents := 0
+	for rows.Next() {
+		var content string
+		if err := rows.Scan(&content); err != nil {
+			return fmt.Errorf("failed to read article content: %w", err)
+		}
+
+		seen := make(map[string]bool)
+		for _, word := range similarity.Words(content) {
+			if !seen[word] {
+				seen[word] = true
+				docFreq[word]++
+			}
+		}
+		documents++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read articles: %w", err)
+	}
+
+	titles, err := s.Vocabulary(ctx, 0)
+	if err != nil {
+		return err
+	}
+
+	s.tags = make(map[string]string, len(titles))
+	for _, title := range titles {
+		s.tags[strings.ToLower(title)] = title
+	}
+	s.docFreq = docFreq
+	s.documents = documents
+
+	return nil
+}
+
+// tfidf scores a word in an article with sublinear term frequency
+func (s *Suggester) tfidf(word string, count int) float64 {
+	if count == 0 {
+		return 0
+	}
+	idf := math.Log(float64(s.documents+1) / float64(s.docFreq[word]+1))
+	return (1 + math.Log(float64(count))) * idf
+}
+
+type keyword struct {
+	word  string
+	score float64
+}
+
+// keywords ranks the article's repeated topical words by TF-IDF, best first
+func (s *Suggester) keywords(counts map[string]int) []keyword {
+	var keywords []keyword
+	for word, count := range counts {
+		if count < minKeywordCount || len([]rune(word)) < 3 || similarity.IsStopWord(word) || isNumber(word) {
+			continue
+		}
+		if score := s.tfidf(word, count); score > 0 {
+			keywords = append(keywords, keyword{word: word, score: score})
+		}
+	}
+
+	sort.Slice(keywords, func(i, j int) bool {
+		if keywords[i].score != keywords[j].score {
+			return keywords[i].score > keywords[j].score
+		}
+		return keywords[i].word < keywords[j].word
+	})
+
+	return keywords
+}
+
+// tagScore is the weakest TF-IDF among a tag's words, or 0 unless every word
+// appears and the tag is mentioned more than once in passing
+func (s *Suggester) tagScore(tag string, counts map[string]int) float64 {
+	words := similarity.Words(tag)
+	if len(words) == 0 {
+		return 0
+	}
+
+	score := math.Inf(1)
+	occurrences := 0
+	for _, word := range words {
+		count := counts[word]
+		if count == 0 {
+			return 0
+		}
+		occurrences += count
+		score = math.Min(score, s.tfidf(word, count))
+	}
+
+	if occurrences < 2*len(words) {
+		return 0
+	}
+	return score
+}
+
+// neighborVotes sums the similarity of similar tagged articles carrying each tag (keyed by
+// lowercased title), divided by the number of voting articles
+func (s *Suggester) neighborVotes(ctx context.Context, articleID int64) (map[string]float64, error) {
+	matches, err := s.finder.Similar(ctx, articleID, 0, similarity.DefaultMaxDistance)
+	if err != nil {
+		return nil, err
+	}
+
+	votes := make(map[string]float64)
+	voters := 0
+	for _, match := range matches {
+		if voters >= similarNeighbors {
+			break
+		}
+
+		tags, err := s.articleTags(ctx, match.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(tags) == 0 {
+			continue
+		}
+
+		voters++
+		for lower := range tags {
+			votes[lower] += match.Similarity
+		}
+	}
+
+	for lower := range votes {
+		votes[lower] /= float64(voters)
+	}
+	return votes, nil
+}
+
+// articleTags returns the set of an article's lowercased tag titles
+func (s *Suggester) articleTags(ctx context.Context, articleID int64) (map[string]bool, error) {
+	var titles []string
+	if err := s.db.SelectContext(ctx, &titles, `
+		SELECT t.title FROM tags t
+		JOIN article_tags at ON t.id = at.tag_id
+		WHERE at.article_id = ?
+	`, articleID); err != nil {
+		return nil, fmt.Errorf("failed to get tags of article %d: %w", articleID, err)
+	}
+
+	tags := make(map[string]bool, len(titles))
+	for _, title := range titles {
+		tags[strings.ToLower(title)] = true
+	}
+	return tags, nil
+}
+
+func sortSuggestions(suggestions []Suggestion) {
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Tag < suggestions[j].Tag
+	})
+}
+
+func isNumber(word string) bool {
+	for _, r := range word {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
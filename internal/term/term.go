@@ -0,0 +1,141 @@
+// Package term styles text output for a terminal: colors, status badges, highlighted matches, and
+// tables whose columns stay aligned when their cells are colored
+package term
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI styles
+const (
+	Reset   = "\033[0m"
+	Bold    = "\033[1m"
+	Dim     = "\033[2m"
+	Red     = "\033[31m"
+	Green   = "\033[32m"
+	Yellow  = "\033[33m"
+	Blue    = "\033[34m"
+	Magenta = "\033[35m"
+	Cyan    = "\033[36m"
+	// Match is the style of highlighted matches, bold red as grep uses
+	Match = "\033[1;31m"
+)
+
+// statusBadges are the styles of reading status badges, dark text on a colored background
+var statusBadges = map[string]string{
+	"inbox":    "\033[30;44m",
+	"reading":  "\033[30;43m",
+	"someday":  "\033[30;45m",
+	"read":     "\033[30;42m",
+	"archived": "\033[30;47m",
+}
+
+var ansiEscape = regexp.MustCompile(`\033\[[0-9;]*m`)
+
+// ColorEnabled reports whether output to f may be styled: f is a terminal, NO_COLOR is unset or
+// empty (https://no-color.org), and TERM is not "dumb"
+func ColorEnabled(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Palette applies styles to text, or leaves it as it is when color is off
+type Palette struct {
+	color bool
+}
+
+// NewPalette returns a palette that styles text when color is true
+func NewPalette(color bool) Palette {
+	return Palette{color: color}
+}
+
+// Color reports whether the palette styles text
+func (p Palette) Color() bool {
+	return p.color
+}
+
+// Paint wraps text in a style; empty text stays empty
+func (p Palette) Paint(style, text string) string {
+	if !p.color || text == "" {
+		return text
+	}
+	return style + text + Reset
+}
+
+// Badge renders a reading status as a colored badge, or as plain text without color
+func (p Palette) Badge(status string) string {
+	style, ok := statusBadges[status]
+	if !p.color || !ok {
+		return status
+	}
+	return style + " " + status + " " + Reset
+}
+
+// Highlight paints every match of re in text, which is otherwise painted in base (may be empty)
+func (p Palette) Highlight(text string, re *regexp.Regexp, base string) string {
+	if !p.color || re == nil {
+		return p.Paint(base, text)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, match := range re.FindAllStringIndex(text, -1) {
+		if match[0] == match[1] {
+			continue
+		}
+		b.WriteString(p.Paint(base, text[last:match[0]]))
+		b.WriteString(p.Paint(Match, text[match[0]:match[1]]))
+		last = match[1]
+	}
+	b.WriteString(p.Paint(base, text[last:]))
+	return b.String()
+}
+
+// Width is the number of characters text takes on screen, not counting ANSI styles
+func Width(text string) int {
+	return utf8.RuneCountInString(ansiEscape.ReplaceAllString(text, ""))
+}
+
+// Table lays out rows in columns two spaces apart, each as wide as its widest cell, as
+// text/tabwriter does. Cells are measured without their styles, so colored columns line up.
+type Table struct {
+	rows [][]string
+}
+
+// Row adds a row of cells
+func (t *Table) Row(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// Write writes the table. The last cell of a row is not padded.
+func (t *Table) Write(w io.Writer) error {
+	var widths []int
+	for _, row := range t.rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], Width(cell))
+		}
+	}
+
+	var b strings.Builder
+	for _, row := range t.rows {
+		for i, cell := range row {
+			b.WriteString(cell)
+			if i < len(row)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-Width(cell)+2))
+			}
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,136 @@
+// Package textnorm replaces typographic characters in titles and content with the plain forms
+// typed on a keyboard, so searches match them and exports look the same everywhere
+package textnorm
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Normalizer chooses which kinds of characters are replaced
+type Normalizer struct {
+	// Quotes replaces curly quotes, low quotes, and primes with ' and "
+	Quotes bool `yaml:"quotes"`
+	// Spaces replaces non-breaking, thin, and other Unicode spaces with a space and removes
+	// zero-width characters and soft hyphens
+	Spaces bool `yaml:"spaces"`
+	// Entities decodes HTML entities left in the text, such as &amp; or &#8217;
+	Entities bool `yaml:"entities"`
+	// Dashes replaces en dashes with - and em dashes with --
+	Dashes bool `yaml:"dashes"`
+	// Ellipses replaces … with ...
+	Ellipses bool `yaml:"ellipses"`
+}
+
+// Default returns the normalizer used when no config is given: quotes, spaces, and entities.
+// Dashes and ellipses are left alone, since search already matches them as punctuation.
+func Default() Normalizer {
+	return Normalizer{Quotes: true, Spaces: true, Entities: true}
+}
+
+// Load reads a normalizer from a YAML file, falling back to defaults for unset keys
+func Load(path string) (Normalizer, error) {
+	normalizer := Default()
+	if path == "" {
+		return normalizer, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return normalizer, fmt.Errorf("failed to read normalize config: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, &normalizer); err != nil {
+		return normalizer, fmt.Errorf("failed to parse normalize config: %w", err)
+	}
+
+	return normalizer, nil
+}
+
+var (
+	quotes = strings.NewReplacer(
+		"\u2018", "'", "\u2019", "'", "\u201a", "'", "\u201b", "'", "\u2032", "'",
+		"\u201c", `"`, "\u201d", `"`, "\u201e", `"`, "\u201f", `"`, "\u2033", `"`,
+	)
+	spaces = strings.NewReplacer(
+		"\u00a0", " ", "\u2000", " ", "\u2001", " ", "\u2002", " ", "\u2003", " ", "\u2004", " ",
+		"\u2005", " ", "\u2006", " ", "\u2007", " ", "\u2008", " ", "\u2009", " ", "\u200a", " ",
+		"\u202f", " ", "\u205f", " ", "\u3000", " ",
+		"\u200b", "", "\u200c", "", "\u200d", "", "\u2060", "", "\ufeff", "", "\u00ad", "",
+	)
+	dashes   = strings.NewReplacer("\u2013", "-", "\u2014", "--", "\u2212", "-")
+	ellipses = strings.NewReplacer("\u2026", "...")
+
+	// entity matches a named, decimal, or hexadecimal HTML entity
+	entity = regexp.MustCompile(`&(?:[A-Za-z][A-Za-z0-9]{1,31}|#[0-9]{1,7}|#[xX][0-9A-Fa-f]{1,6});`)
+)
+
+// Text normalizes plain text such as a title
+func (n Normalizer) Text(text string) string {
+	if n.Entities && strings.Contains(text, "&") {
+		text = decodeEntities(text)
+	}
+	if n.Quotes {
+		text = quotes.Replace(text)
+	}
+	if n.Spaces {
+		text = spaces.Replace(text)
+	}
+	if n.Dashes {
+		text = dashes.Replace(text)
+	}
+	if n.Ellipses {
+		text = ellipses.Replace(text)
+	}
+	return text
+}
+
+// Markdown normalizes markdown content, leaving fenced code blocks and inline code as they are
+func (n Normalizer) Markdown(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	fence := ""
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+			continue
+		}
+
+		// Odd parts of a line split at backticks are inline code
+		parts := strings.Split(line, "`")
+		for j := 0; j < len(parts); j += 2 {
+			parts[j] = n.Text(parts[j])
+		}
+		lines[i] = strings.Join(parts, "`")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// decodeEntities decodes HTML entities, twice for double-encoded ones like "&amp;#8217;". Entities
+// for < and > stay encoded, as decoding them would turn text into markup.
+func decodeEntities(text string) string {
+	for i := 0; i < 2; i++ {
+		text = entity.ReplaceAllStringFunc(text, func(match string) string {
+			decoded := html.UnescapeString(match)
+			if decoded == "<" || decoded == ">" {
+				return match
+			}
+			return decoded
+		})
+	}
+	return text
+}
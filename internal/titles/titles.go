@@ -0,0 +1,210 @@
+package titles
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Cleaner normalizes article titles: it decodes HTML entities, collapses whitespace,
+// strips a trailing " | Site Name" suffix, and optionally applies title case
+type Cleaner struct {
+	// Separators that may precede a trailing site name
+	Separators []string `yaml:"separators"`
+	// StrongSeparators always mark a site suffix (if short enough); other separators
+	// only do when the suffix matches the article's host or a known site name
+	StrongSeparators []string `yaml:"strong_separators"`
+	// SiteNames are suffixes stripped after any separator, in addition to the host name
+	SiteNames []string `yaml:"site_names"`
+	// MaxSuffixWords is the longest trailing segment treated as a site name
+	MaxSuffixWords int `yaml:"max_suffix_words"`
+	// TitleCase capitalizes words, leaving short connecting words and mixed-case words alone
+	TitleCase bool `yaml:"title_case"`
+}
+
+// Default returns the cleaner used when no title config is given
+func Default() Cleaner {
+	return Cleaner{
+		Separators:       []string{" | ", " - ", " – ", " — ", " · ", " :: ", " » "},
+		StrongSeparators: []string{" | ", " · ", " :: ", " » "},
+		SiteNames: []string{
+			"Medium", "Substack", "YouTube", "Hacker News", "DEV Community", "Stack Overflow",
+			"GitHub", "Reddit", "The New York Times", "The Guardian", "BBC News",
+		},
+		MaxSuffixWords: 5,
+	}
+}
+
+// Load reads a cleaner from a YAML file, falling back to defaults for unset keys
+func Load(path string) (Cleaner, error) {
+	cleaner := Default()
+	if path == "" {
+		return cleaner, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cleaner, fmt.Errorf("failed to read title config: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, &cleaner); err != nil {
+		return cleaner, fmt.Errorf("failed to parse title config: %w", err)
+	}
+
+	return cleaner, nil
+}
+
+// Clean returns the cleaned title. rawURL is the article's URL, used to recognize site-name suffixes.
+func (c Cleaner) Clean(title, rawURL string) string {
+	// Titles are sometimes double-encoded ("&amp;amp;")
+	for i := 0; i < 2 && strings.Contains(title, "&"); i++ {
+		title = html.UnescapeString(title)
+	}
+	title = strings.Join(strings.Fields(title), " ")
+
+	title = c.stripSiteSuffix(title, rawURL)
+
+	if c.TitleCase {
+		title = toTitleCase(title)
+	}
+
+	return title
+}
+
+// stripSiteSuffix removes trailing site names, repeatedly for titles like "Post | Blog | Site"
+func (c Cleaner) stripSiteSuffix(title, rawURL string) string {
+	host := hostKey(rawURL)
+
+	for {
+		cut := -1
+		var separator string
+		for _, sep := range c.Separators {
+			if i := strings.LastIndex(title, sep); i > cut {
+				cut, separator = i, sep
+			}
+		}
+		if cut <= 0 {
+			return title
+		}
+
+		rest, suffix := strings.TrimSpace(title[:cut]), strings.TrimSpace(title[cut+len(separator):])
+		if rest == "" || suffix == "" || len(strings.Fields(suffix)) > c.MaxSuffixWords {
+			return title
+		}
+
+		if !c.isStrong(separator) && !c.isSiteName(suffix, host) {
+			return title
+		}
+
+		title = rest
+	}
+}
+
+func (c Cleaner) isStrong(separator string) bool {
+	for _, sep := range c.StrongSeparators {
+		if sep == separator {
+			return true
+		}
+	}
+	return false
+}
+
+// isSiteName reports whether suffix names the article's site or a configured site
+func (c Cleaner) isSiteName(suffix, host string) bool {
+	key := nameKey(suffix)
+	if key == "" {
+		return false
+	}
+
+	for _, name := range c.SiteNames {
+		if nameKey(name) == key {
+			return true
+		}
+	}
+
+	// "The Verge" matches theverge.com, "Ars Technica" matches arstechnica.com; partial
+	// matches ("Verge Tech" on theverge.com) need a few characters to avoid accidents
+	if host == "" {
+		return false
+	}
+	return key == host ||
+		(len(host) >= 4 && strings.Contains(key, host)) ||
+		(len(key) >= 4 && strings.Contains(host, key))
+}
+
+// hostKey returns the registrable part of a URL's host without punctuation, e.g. "theverge"
+func hostKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	labels := strings.Split(strings.TrimPrefix(strings.ToLower(u.Hostname()), "www."), ".")
+	if len(labels) >= 2 {
+		labels = labels[:len(labels)-1]
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+
+	// Use the label left of the TLD, so blog.example.com matches "Example"
+	return nameKey(labels[len(labels)-1])
+}
+
+// nameKey lowercases a name and keeps only letters and digits
+func nameKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// smallWords stay lowercase in title case unless they start or end the title
+var smallWords = map[string]bool{
+	"a": true, "an": true, "and": true, "as": true, "at": true, "but": true, "by": true,
+	"for": true, "in": true, "nor": true, "of": true, "on": true, "or": true, "the": true,
+	"to": true, "vs": true, "via": true, "with": true,
+}
+
+// toTitleCase capitalizes each word, keeping words that already contain capitals (iOS, GitHub, API)
+func toTitleCase(title string) string {
+	words := strings.Fields(title)
+	for i, word := range words {
+		if hasUpperAfterFirst(word) {
+			continue
+		}
+
+		lower := strings.ToLower(word)
+		if i > 0 && i < len(words)-1 && smallWords[lower] {
+			words[i] = lower
+			continue
+		}
+
+		runes := []rune(lower)
+		for j, r := range runes {
+			if unicode.IsLetter(r) {
+				runes[j] = unicode.ToUpper(r)
+				break
+			}
+		}
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
+
+func hasUpperAfterFirst(word string) bool {
+	for i, r := range []rune(word) {
+		if i > 0 && unicode.IsUpper(r) {
+			return true
+		}
+	}
+	return false
+}
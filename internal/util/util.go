@@ -7,10 +7,32 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"instapaper-cli/internal/model"
 
 	"github.com/gosimple/slug"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/unicode/norm"
 )
 
+// TrackingParams lists query parameters removed by CanonicalizeURL; entries ending in "*" match by prefix
+var TrackingParams = []string{
+	"utm_*", "fbclid", "gclid", "dclid", "gclsrc", "msclkid", "yclid", "igshid",
+	"mc_cid", "mc_eid", "_hsenc", "_hsmi", "mkt_tok", "ref", "ref_src", "ref_url",
+}
+
+// AddTrackingParams extends TrackingParams with additional parameter names or prefixes
+func AddTrackingParams(params ...string) {
+	for _, param := range params {
+		param = strings.ToLower(strings.TrimSpace(param))
+		if param != "" {
+			TrackingParams = append(TrackingParams, param)
+		}
+	}
+}
+
 func CanonicalizeURL(rawURL string) (string, error) {
 	u, err := url.Parse(rawURL)
 	if err != nil {
@@ -25,9 +47,52 @@ func CanonicalizeURL(rawURL string) (string, error) {
 
 	u.Path = strings.TrimSuffix(u.Path, "/")
 
+	u.RawQuery = stripTrackingParams(u.RawQuery)
+
 	return u.String(), nil
 }
 
+// stripTrackingParams removes tracking parameters from a raw query, keeping the order of the rest
+func stripTrackingParams(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	var kept []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+
+		key := pair
+		if i := strings.Index(pair, "="); i >= 0 {
+			key = pair[:i]
+		}
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+
+		if !isTrackingParam(strings.ToLower(key)) {
+			kept = append(kept, pair)
+		}
+	}
+
+	return strings.Join(kept, "&")
+}
+
+func isTrackingParam(key string) bool {
+	for _, param := range TrackingParams {
+		if prefix, ok := strings.CutSuffix(param, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == param {
+			return true
+		}
+	}
+	return false
+}
+
 func UnixToISO8601(unixTime int64) string {
 	return time.Unix(unixTime, 0).UTC().Format(time.RFC3339)
 }
@@ -72,6 +137,176 @@ func ParseTags(tagsStr string) []string {
 	return tags
 }
 
+var (
+	// highlightSeparator matches lines that separate highlights in a multi-highlight selection
+	highlightSeparator = regexp.MustCompile(`(?m)^\s*(?:---+|\* \* \*|…|\.\.\.)\s*$`)
+
+	// blankLines matches one or more blank lines between highlights
+	blankLines = regexp.MustCompile(`\n\s*\n`)
+)
+
+// ParseHighlights splits a CSV selection into individual highlights.
+// Highlights are separated by blank lines or separator lines (---, * * *, …).
+func ParseHighlights(selection string) []string {
+	selection = strings.ReplaceAll(selection, "\r\n", "\n")
+	selection = highlightSeparator.ReplaceAllString(selection, "\n")
+
+	var highlights []string
+	for _, part := range blankLines.Split(selection, -1) {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			highlights = append(highlights, part)
+		}
+	}
+
+	return highlights
+}
+
+// foldedLetters maps letters that do not decompose into a base letter and a diacritic
+var foldedLetters = strings.NewReplacer(
+	"ø", "o", "æ", "ae", "œ", "oe", "ß", "ss", "đ", "d", "ð", "d", "þ", "th", "ł", "l", "ı", "i",
+)
+
+// NormalizeText lowercases text and strips its diacritics, so "Café" and "Ærø" compare equal
+// to "cafe" and "aero"
+func NormalizeText(text string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(text)) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return foldedLetters.Replace(b.String())
+}
+
+// CountGarbled counts the characters of text that were decoded with the wrong charset: replacement
+// characters left by invalid UTF-8, and UTF-8 read as Windows-1252 ("cafÃ©" for "café"), which is
+// found by encoding runs of non-ASCII characters back to Windows-1252 and decoding them as UTF-8
+func CountGarbled(text string) int {
+	garbled := 0
+	var run []rune
+	flush := func() {
+		if len(run) > 1 {
+			if encoded, err := charmap.Windows1252.NewEncoder().String(string(run)); err == nil {
+				for s := encoded; s != ""; {
+					r, size := utf8.DecodeRuneInString(s)
+					if r != utf8.RuneError && size > 1 {
+						garbled++
+					}
+					s = s[size:]
+				}
+			}
+		}
+		run = run[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case r == utf8.RuneError:
+			garbled++
+			flush()
+		case r >= utf8.RuneSelf:
+			run = append(run, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return garbled
+}
+
+// Paragraphs splits markdown at blank lines into its non-empty paragraphs. Paragraph numbers in
+// outlines and passages count these, from 1.
+func Paragraphs(markdown string) []string {
+	var paragraphs []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n\n") {
+		if paragraph = strings.Trim(paragraph, "\n"); strings.TrimSpace(paragraph) != "" {
+			paragraphs = append(paragraphs, paragraph)
+		}
+	}
+	return paragraphs
+}
+
+var (
+	// atxHeading matches a markdown heading line such as "## Setup ##"
+	atxHeading = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.*?)(?:[ \t]+#+)?[ \t]*$`)
+
+	// inlineLink matches a markdown link or image, keeping its text
+	inlineLink = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+)
+
+// ParseOutline lists the headings of markdown in order, skipping lines in fenced code blocks, with
+// links and emphasis reduced to their text
+func ParseOutline(markdown string) []model.Heading {
+	headings := []model.Heading{}
+	anchors := make(map[string]int)
+	inFence := false
+
+	for i, paragraph := range Paragraphs(markdown) {
+		for _, line := range strings.Split(paragraph, "\n") {
+			if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+				inFence = !inFence
+				continue
+			}
+			if inFence {
+				continue
+			}
+
+			match := atxHeading.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			title := inlineLink.ReplaceAllString(match[2], "$1")
+			title = strings.TrimSpace(strings.NewReplacer("**", "", "__", "", "`", "").Replace(title))
+			if title == "" {
+				continue
+			}
+
+			headings = append(headings, model.Heading{
+				Level:     len(match[1]),
+				Title:     title,
+				Anchor:    headingAnchor(title, anchors),
+				Paragraph: i + 1,
+			})
+		}
+	}
+	return headings
+}
+
+// headingAnchor makes a GitHub-style anchor for a heading: lowercase, punctuation dropped, spaces
+// as hyphens, and a numeric suffix for repeated headings
+func headingAnchor(title string, seen map[string]int) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+	anchor := b.String()
+
+	n := seen[anchor]
+	seen[anchor] = n + 1
+	if n > 0 {
+		return fmt.Sprintf("%s-%d", anchor, n)
+	}
+	return anchor
+}
+
+// TruncateUTF8 cuts s to at most maxBytes bytes, backing off to a rune boundary so no character
+// is split
+func TruncateUTF8(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	for maxBytes > 0 && !utf8.RuneStart(s[maxBytes]) {
+		maxBytes--
+	}
+	return s[:maxBytes]
+}
+
 func SlugifyTitle(title string, maxLength int) string {
 	s := slug.Make(title)
 	if len(s) > maxLength {
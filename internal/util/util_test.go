@@ -0,0 +1,29 @@
+package util
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateUTF8(t *testing.T) {
+	tests := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly", 7, "exactly"},
+		{"ascii text", 5, "ascii"},
+		{"añb", 2, "a"},  // ñ is two bytes; cutting after its first leaves it out
+		{"añb", 3, "añ"}, // a whole ñ fits
+		{"日本語", 4, "日"},
+		{"日本語", 2, ""},
+	}
+
+	for _, tt := range tests {
+		got := TruncateUTF8(tt.in, tt.max)
+		if got != tt.want || !utf8.ValidString(got) {
+			t.Errorf("TruncateUTF8(%q, %d) = %q, want %q", tt.in, tt.max, got, tt.want)
+		}
+	}
+}
@@ -44,15 +44,20 @@ func getVersionFromGit() string {
 		latestTag = "v0.0.0"
 	}
 
-	// Check if we're exactly on a tag
+	// Outside a git checkout, or without git, there is no revision to describe
 	currentCommit := getCurrentCommit()
+	if currentCommit == "" {
+		return "dev"
+	}
+
+	// Check if we're exactly on a tag
 	tagCommit := getTagCommit(latestTag)
 
 	version := latestTag
 
 	// If not on exact tag commit, add revision info
 	if currentCommit != tagCommit {
-		version += "-rev-" + currentCommit[:8]
+		version += "-rev-" + shortCommit(currentCommit)
 	}
 
 	// Check for uncommitted changes
@@ -73,16 +78,24 @@ func getLatestTag() string {
 	return strings.TrimSpace(string(output))
 }
 
-// getCurrentCommit gets the current commit hash
+// getCurrentCommit gets the current commit hash, or "" outside a git checkout
 func getCurrentCommit() string {
 	cmd := exec.Command("git", "rev-parse", "HEAD")
 	output, err := cmd.Output()
 	if err != nil {
-		return "unknown"
+		return ""
 	}
 	return strings.TrimSpace(string(output))
 }
 
+// shortCommit abbreviates a commit hash to its first 8 characters
+func shortCommit(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
+
 // getTagCommit gets the commit hash for a specific tag
 func getTagCommit(tag string) string {
 	cmd := exec.Command("git", "rev-list", "-n", "1", tag)
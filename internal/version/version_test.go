@@ -0,0 +1,40 @@
+package version
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetVersionOutsideGitCheckout(t *testing.T) {
+	dir := t.TempDir()
+	// Keep git from finding a repository above the temporary directory
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if got := GetVersion(); got != "dev" {
+		t.Errorf("GetVersion() outside a git checkout = %q, want %q", got, "dev")
+	}
+}
+
+func TestShortCommit(t *testing.T) {
+	tests := map[string]string{
+		"0123456789abcdef": "01234567",
+		"01234567":         "01234567",
+		"abc":              "abc",
+		"":                 "",
+	}
+	for commit, want := range tests {
+		if got := shortCommit(commit); got != want {
+			t.Errorf("shortCommit(%q) = %q, want %q", commit, got, want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+-- Recreate the FTS table with contentless_delete so rows can be removed
+-- when articles become obsolete and replaced when content changes
+
+DROP TABLE IF EXISTS articles_fts;
+
+CREATE VIRTUAL TABLE articles_fts USING fts5(
+  url, title, content, folder, tags, content='', contentless_delete=1
+);
+
+INSERT INTO articles_fts (rowid, url, title, content, folder, tags)
+SELECT
+  a.id,
+  a.url,
+  COALESCE(a.title, ''),
+  COALESCE(a.content_md, ''),
+  COALESCE(f.path_cache, ''),
+  COALESCE((
+    SELECT GROUP_CONCAT(t.title, ', ')
+    FROM article_tags at
+    JOIN tags t ON at.tag_id = t.id
+    WHERE at.article_id = a.id
+  ), '')
+FROM articles a
+LEFT JOIN folders f ON a.folder_id = f.id
+WHERE a.obsolete = FALSE;
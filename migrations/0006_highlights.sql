@@ -0,0 +1,18 @@
+-- Store highlights separately so an article can have several, in order
+
+CREATE TABLE highlights (
+  id INTEGER PRIMARY KEY,
+  article_id INTEGER NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+  position INTEGER NOT NULL,
+  text TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (datetime('now')),
+  UNIQUE (article_id, position)
+);
+
+CREATE INDEX idx_highlights_article ON highlights(article_id);
+
+-- Backfill existing single selections as the first highlight
+INSERT INTO highlights (article_id, position, text)
+SELECT id, 0, selection
+FROM articles
+WHERE selection IS NOT NULL AND TRIM(selection) != '';
@@ -0,0 +1,3 @@
+-- Canonical URL declared by the page (rel=canonical or og:url), recorded on fetch
+
+ALTER TABLE articles ADD COLUMN canonical_url TEXT;
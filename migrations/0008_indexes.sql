@@ -0,0 +1,15 @@
+-- Indexes for the common filters: date ranges and fetch state on active articles, tag lookups, status codes.
+-- IF NOT EXISTS also restores indexes from earlier migrations on databases that lost them.
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_articles_url ON articles(url);
+CREATE INDEX IF NOT EXISTS idx_articles_folder ON articles(folder_id);
+CREATE INDEX IF NOT EXISTS idx_articles_instapapered_at ON articles(instapapered_at);
+CREATE INDEX IF NOT EXISTS idx_articles_synced_at ON articles(synced_at);
+CREATE INDEX IF NOT EXISTS idx_articles_obsolete ON articles(obsolete);
+
+CREATE INDEX IF NOT EXISTS idx_articles_obsolete_instapapered_at ON articles(obsolete, instapapered_at);
+CREATE INDEX IF NOT EXISTS idx_articles_obsolete_synced_at ON articles(obsolete, synced_at);
+CREATE INDEX IF NOT EXISTS idx_articles_status_code ON articles(status_code);
+CREATE INDEX IF NOT EXISTS idx_article_tags_tag ON article_tags(tag_id, article_id);
+
+ANALYZE;
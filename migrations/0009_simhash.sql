@@ -0,0 +1,3 @@
+-- 64-bit SimHash signature of content_md, used to find similar and near-duplicate articles
+
+ALTER TABLE articles ADD COLUMN simhash INTEGER;
@@ -0,0 +1,29 @@
+-- Folder titles only need to be unique among siblings so nested paths like
+-- Tech/Go and Work/Go can both have a "Go" folder. SQLite cannot drop the old
+-- UNIQUE(title) constraint, so the table is rebuilt. Dropping it fires the
+-- ON DELETE SET NULL actions, so article and parent links are saved and restored.
+
+CREATE TABLE folders_backup AS SELECT id, title, parent_id, path_cache FROM folders;
+CREATE TABLE article_folders_backup AS SELECT id, folder_id FROM articles WHERE folder_id IS NOT NULL;
+
+DROP TABLE folders;
+
+CREATE TABLE folders (
+  id INTEGER PRIMARY KEY,
+  title TEXT NOT NULL,
+  parent_id INTEGER REFERENCES folders(id) ON DELETE SET NULL,
+  path_cache TEXT
+);
+
+CREATE UNIQUE INDEX idx_folders_parent_title ON folders(COALESCE(parent_id, 0), title);
+CREATE INDEX idx_folders_path_cache ON folders(path_cache);
+
+INSERT INTO folders (id, title, parent_id, path_cache)
+SELECT id, title, parent_id, path_cache FROM folders_backup;
+
+UPDATE articles
+SET folder_id = (SELECT b.folder_id FROM article_folders_backup b WHERE b.id = articles.id)
+WHERE id IN (SELECT id FROM article_folders_backup);
+
+DROP TABLE folders_backup;
+DROP TABLE article_folders_backup;
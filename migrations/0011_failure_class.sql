@@ -0,0 +1,26 @@
+-- Fetch failures are classified (dns, tls, timeout, network, http_4xx, http_5xx, read, readability, markdown)
+-- and next_retry_at holds when the article may be retried. A failure with no next_retry_at has used up its retries.
+
+ALTER TABLE articles ADD COLUMN failure_class TEXT;
+ALTER TABLE articles ADD COLUMN next_retry_at TEXT;
+
+UPDATE articles
+SET failure_class = CASE
+    WHEN status_code >= 500 THEN 'http_5xx'
+    WHEN status_code >= 400 THEN 'http_4xx'
+    WHEN status_text LIKE 'ReadabilityError:%' THEN 'readability'
+    WHEN status_text LIKE 'MarkdownError:%' THEN 'markdown'
+    WHEN status_text LIKE 'ReadError:%' THEN 'read'
+    WHEN status_text LIKE '%no such host%' THEN 'dns'
+    WHEN status_text LIKE '%tls:%' OR status_text LIKE '%x509:%' THEN 'tls'
+    WHEN status_text LIKE '%timeout%' OR status_text LIKE '%timed out%' OR status_text LIKE '%deadline exceeded%' THEN 'timeout'
+    ELSE 'network'
+  END
+WHERE synced_at IS NULL AND sync_failed_at IS NOT NULL;
+
+-- Keep the previous rule for existing failures: retry an hour after the last failure, up to 5 attempts
+UPDATE articles
+SET next_retry_at = strftime('%Y-%m-%dT%H:%M:%SZ', sync_failed_at, '+1 hour')
+WHERE synced_at IS NULL AND sync_failed_at IS NOT NULL AND failed_count < 5;
+
+CREATE INDEX IF NOT EXISTS idx_articles_next_retry_at ON articles(next_retry_at);
@@ -0,0 +1,27 @@
+-- Recreate the FTS table with prefix indexes so prefix queries like gener* stay fast,
+-- and expose its term list through fts5vocab for typo-tolerant fuzzy search
+
+DROP TABLE IF EXISTS articles_fts;
+
+CREATE VIRTUAL TABLE articles_fts USING fts5(
+  url, title, content, folder, tags, content='', contentless_delete=1, prefix='2 3 4'
+);
+
+INSERT INTO articles_fts (rowid, url, title, content, folder, tags)
+SELECT
+  a.id,
+  a.url,
+  COALESCE(a.title, ''),
+  COALESCE(a.content_md, ''),
+  COALESCE(f.path_cache, ''),
+  COALESCE((
+    SELECT GROUP_CONCAT(t.title, ', ')
+    FROM article_tags at
+    JOIN tags t ON at.tag_id = t.id
+    WHERE at.article_id = a.id
+  ), '')
+FROM articles a
+LEFT JOIN folders f ON a.folder_id = f.id
+WHERE a.obsolete = FALSE;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS articles_fts_vocab USING fts5vocab(articles_fts, 'row');
@@ -0,0 +1,5 @@
+-- Publication date read from the article page's metadata when it is fetched, for latest --by published.
+
+ALTER TABLE articles ADD COLUMN published_at TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_articles_obsolete_published_at ON articles(obsolete, published_at);
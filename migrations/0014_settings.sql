@@ -0,0 +1,6 @@
+-- Settings stored with the database, such as whether new article content is compressed.
+
+CREATE TABLE IF NOT EXISTS settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
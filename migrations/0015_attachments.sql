@@ -0,0 +1,17 @@
+-- Original files (PDFs, slide decks, images) of articles that are not web pages.
+-- The file itself lives in the attachments directory, named by its SHA-256 hash, so identical files are stored once.
+
+CREATE TABLE attachments (
+  id INTEGER PRIMARY KEY,
+  article_id INTEGER NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+  hash TEXT NOT NULL,
+  filename TEXT NOT NULL,
+  content_type TEXT NOT NULL,
+  size INTEGER NOT NULL,
+  source_url TEXT,
+  created_at TEXT NOT NULL DEFAULT (datetime('now')),
+  UNIQUE (article_id, hash)
+);
+
+CREATE INDEX idx_attachments_article ON attachments(article_id);
+CREATE INDEX idx_attachments_hash ON attachments(hash);
@@ -0,0 +1,12 @@
+-- Latest check-links result per article, a cheap HEAD request that finds dead, redirected, and moved links
+
+CREATE TABLE link_checks (
+  article_id INTEGER PRIMARY KEY REFERENCES articles(id) ON DELETE CASCADE,
+  checked_at TEXT NOT NULL,
+  status TEXT NOT NULL,
+  status_code INTEGER,
+  final_url TEXT,
+  error TEXT
+);
+
+CREATE INDEX idx_link_checks_status ON link_checks(status);
@@ -0,0 +1,12 @@
+-- Audit trail of obsolete and restore actions, with the reason given and the criteria that selected each article
+
+CREATE TABLE obsolete_log (
+  id INTEGER PRIMARY KEY,
+  article_id INTEGER NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+  action TEXT NOT NULL,
+  reason TEXT,
+  criteria TEXT,
+  created_at TEXT NOT NULL
+);
+
+CREATE INDEX idx_obsolete_log_article ON obsolete_log(article_id, id);
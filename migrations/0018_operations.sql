@@ -0,0 +1,21 @@
+-- Journal of bulk operations (obsolete, restore, bulk retag and folder moves, tag renames and merges)
+-- with each article's previous value, so undo --operation-id can revert them
+
+CREATE TABLE operations (
+  id INTEGER PRIMARY KEY,
+  kind TEXT NOT NULL,
+  description TEXT,
+  created_at TEXT NOT NULL,
+  undone_at TEXT
+);
+
+CREATE TABLE operation_changes (
+  id INTEGER PRIMARY KEY,
+  operation_id INTEGER NOT NULL REFERENCES operations(id) ON DELETE CASCADE,
+  article_id INTEGER REFERENCES articles(id) ON DELETE CASCADE,
+  field TEXT NOT NULL,
+  before_value TEXT,
+  after_value TEXT
+);
+
+CREATE INDEX idx_operation_changes_operation ON operation_changes(operation_id, id);
@@ -0,0 +1,10 @@
+-- Alternative names for tags and folders, resolved to their target in search, import, and MCP filters
+
+CREATE TABLE aliases (
+  id INTEGER PRIMARY KEY,
+  kind TEXT NOT NULL,
+  alias TEXT NOT NULL COLLATE NOCASE,
+  target TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  UNIQUE(kind, alias)
+);
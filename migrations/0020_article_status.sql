@@ -0,0 +1,10 @@
+-- Reading status: inbox, reading, someday, read, or archived, with when it last changed
+-- Articles in Instapaper's top-level Archive folder start out archived
+
+ALTER TABLE articles ADD COLUMN status TEXT NOT NULL DEFAULT 'inbox';
+ALTER TABLE articles ADD COLUMN status_changed_at TEXT;
+
+UPDATE articles SET status = 'archived'
+WHERE folder_id IN (SELECT id FROM folders WHERE title = 'Archive' AND parent_id IS NULL);
+
+CREATE INDEX idx_articles_status ON articles(status);
@@ -0,0 +1,19 @@
+-- When an article was marked read, which reading goals count. Archiving keeps it and moving the
+-- article back to inbox, reading, or someday clears it
+
+ALTER TABLE articles ADD COLUMN read_at TEXT;
+
+UPDATE articles SET read_at = status_changed_at WHERE status = 'read';
+
+CREATE INDEX idx_articles_read_at ON articles(read_at);
+
+-- Reading goals: a target number of articles or words read per week or month
+
+CREATE TABLE goals (
+  id INTEGER PRIMARY KEY,
+  metric TEXT NOT NULL,
+  period TEXT NOT NULL,
+  target INTEGER NOT NULL,
+  created_at TEXT NOT NULL,
+  UNIQUE(metric, period)
+);
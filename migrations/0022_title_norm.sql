@@ -0,0 +1,6 @@
+-- Lowercased titles with diacritics stripped, matched by title searches so "cafe" finds "Café".
+-- Kept up to date wherever a title is written
+
+ALTER TABLE articles ADD COLUMN title_norm TEXT;
+
+UPDATE articles SET title_norm = normalize_text(title);
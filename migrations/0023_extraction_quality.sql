@@ -0,0 +1,6 @@
+-- How much fetched content looks like the article, from 0 (junk) to 1. NULL for articles fetched
+-- before it was recorded and for attachments
+
+ALTER TABLE articles ADD COLUMN extraction_quality REAL;
+
+CREATE INDEX idx_articles_extraction_quality ON articles(extraction_quality);
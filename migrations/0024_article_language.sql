@@ -0,0 +1,29 @@
+-- The language of each article, declared by the page or detected from its text. NULL when unsure
+
+ALTER TABLE articles ADD COLUMN language TEXT;
+
+UPDATE articles SET language = detect_language(COALESCE(title, '') || ' ' || COALESCE(content_text(content_md), ''));
+
+CREATE INDEX idx_articles_language ON articles(language);
+
+-- Reindex Chinese, Japanese, Korean, and Thai articles with their text split into character pairs.
+-- Other articles pick up segmentation of the odd CJK word on their next fetch or doctor run
+
+DELETE FROM articles_fts WHERE rowid IN (SELECT id FROM articles WHERE language IN ('zh', 'ja', 'ko', 'th'));
+
+INSERT INTO articles_fts (rowid, url, title, content, folder, tags)
+SELECT
+  a.id,
+  a.url,
+  segment_text(COALESCE(a.title, '')),
+  segment_text(COALESCE(content_text(a.content_md), '')),
+  segment_text(COALESCE(f.path_cache, '')),
+  segment_text(COALESCE((
+    SELECT GROUP_CONCAT(t.title, ', ')
+    FROM article_tags at
+    JOIN tags t ON at.tag_id = t.id
+    WHERE at.article_id = a.id
+  ), ''))
+FROM articles a
+LEFT JOIN folders f ON a.folder_id = f.id
+WHERE a.obsolete = FALSE AND a.language IN ('zh', 'ja', 'ko', 'th');
@@ -0,0 +1,6 @@
+-- The headings of each article's content as a JSON array of level, title, anchor, and paragraph.
+-- NULL for articles not fetched yet
+
+ALTER TABLE articles ADD COLUMN outline TEXT;
+
+UPDATE articles SET outline = markdown_outline(content_text(content_md)) WHERE content_md IS NOT NULL;
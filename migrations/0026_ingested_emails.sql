@@ -0,0 +1,10 @@
+-- Email messages turned into articles by ingest:email, keyed by Message-ID, so a message is
+-- ingested once even when it is left unread or copied to another mailbox
+
+CREATE TABLE ingested_emails (
+  message_id TEXT PRIMARY KEY,
+  article_id INTEGER REFERENCES articles(id) ON DELETE SET NULL,
+  mailbox TEXT NOT NULL,
+  sender TEXT,
+  ingested_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
@@ -0,0 +1,24 @@
+-- Other URLs of an article: redirect hops, its declared canonical URL, and URLs it was saved
+-- under before. Imports and feeds resolve them to the article instead of adding a duplicate.
+
+CREATE TABLE url_aliases (
+  url TEXT PRIMARY KEY,
+  article_id INTEGER NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE INDEX idx_url_aliases_article ON url_aliases(article_id);
+
+-- Articles fetched before this table keep their last final and canonical URLs, apart from
+-- Wayback Machine snapshots
+INSERT OR IGNORE INTO url_aliases (url, article_id, kind)
+SELECT final_url, id, 'redirect' FROM articles
+WHERE final_url IS NOT NULL AND final_url != '' AND final_url != url
+  AND final_url NOT LIKE 'https://web.archive.org/%'
+  AND final_url NOT IN (SELECT url FROM articles);
+
+INSERT OR IGNORE INTO url_aliases (url, article_id, kind)
+SELECT canonical_url, id, 'canonical' FROM articles
+WHERE canonical_url IS NOT NULL AND canonical_url != '' AND canonical_url != url
+  AND canonical_url NOT IN (SELECT url FROM articles);
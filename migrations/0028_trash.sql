@@ -0,0 +1,11 @@
+-- Trashed articles, deleted for good by trash empty. Trashing also marks an article obsolete, which
+-- hides it from search, export, and fetch, and restoring it puts back the obsolete flag it had before
+
+CREATE TABLE trash (
+  article_id INTEGER PRIMARY KEY REFERENCES articles(id) ON DELETE CASCADE,
+  was_obsolete BOOLEAN NOT NULL DEFAULT FALSE,
+  reason TEXT,
+  trashed_at TEXT NOT NULL
+);
+
+CREATE INDEX idx_trash_trashed_at ON trash(trashed_at);
@@ -0,0 +1,3 @@
+-- Feeds whose items carry the whole article in content:encoded can store it at sync time
+-- instead of queuing a fetch of each item
+ALTER TABLE rss_feeds ADD COLUMN use_content INTEGER NOT NULL DEFAULT 0;
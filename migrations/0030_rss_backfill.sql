@@ -0,0 +1,9 @@
+-- How much of a feed's history its first sync ingests: all, none, or items newer than an age
+-- like 30d. Items left out are remembered, so later syncs do not ingest them either.
+ALTER TABLE rss_feeds ADD COLUMN backfill TEXT NOT NULL DEFAULT 'all';
+
+CREATE TABLE rss_skipped_items (
+  feed_id INTEGER NOT NULL REFERENCES rss_feeds(id) ON DELETE CASCADE,
+  url TEXT NOT NULL,
+  PRIMARY KEY (feed_id, url)
+);
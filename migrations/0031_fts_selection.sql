@@ -0,0 +1,26 @@
+-- Recreate the FTS table with a selection column, so an article can be found by the passage
+-- highlighted when saving it. Highlights are split from the selection, so it covers them too
+
+DROP TABLE IF EXISTS articles_fts;
+
+CREATE VIRTUAL TABLE articles_fts USING fts5(
+  url, title, content, folder, tags, selection, content='', contentless_delete=1, prefix='2 3 4'
+);
+
+INSERT INTO articles_fts (rowid, url, title, content, folder, tags, selection)
+SELECT
+  a.id,
+  a.url,
+  segment_text(COALESCE(a.title, '')),
+  segment_text(COALESCE(content_text(a.content_md), '')),
+  segment_text(COALESCE(f.path_cache, '')),
+  segment_text(COALESCE((
+    SELECT GROUP_CONCAT(t.title, ', ')
+    FROM article_tags at
+    JOIN tags t ON at.tag_id = t.id
+    WHERE at.article_id = a.id
+  ), '')),
+  segment_text(COALESCE(a.selection, ''))
+FROM articles a
+LEFT JOIN folders f ON a.folder_id = f.id
+WHERE a.obsolete = FALSE;
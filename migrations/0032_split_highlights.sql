@@ -0,0 +1,12 @@
+-- Split the selections 0006 backfilled whole into separate highlights, as imports do. This drops
+-- each backfilled highlight (the only one of its article, holding the whole selection), and the
+-- migration's Go step in db.migrationHooks inserts the highlights util.ParseHighlights finds
+
+DELETE FROM highlights
+WHERE article_id IN (
+  SELECT h.article_id
+  FROM highlights h
+  JOIN articles a ON a.id = h.article_id
+  GROUP BY h.article_id
+  HAVING COUNT(*) = 1 AND MAX(h.text) = MAX(a.selection)
+);
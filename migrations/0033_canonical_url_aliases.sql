@@ -0,0 +1,7 @@
+-- 0027 backfilled the final and canonical URLs of fetched articles as they were stored, but URLs
+-- are canonicalized before they are looked up, so those aliases never matched. The migration's Go
+-- step in db.migrationHooks canonicalizes every alias. Dates the column default wrote in SQL form
+-- move to RFC 3339, as everywhere else.
+
+UPDATE url_aliases SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at)
+WHERE created_at NOT LIKE '%T%';
@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL schema migrations so programs using the
+// library do not need the migrations directory on disk.
+package migrations
+
+import "embed"
+
+// FS holds the numbered *.sql migration files
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,28 @@
+package instapaper
+
+import (
+	"context"
+
+	"instapaper-cli/internal/export"
+)
+
+// Exporter renders articles as Markdown with YAML frontmatter
+type Exporter struct {
+	export *export.Export
+}
+
+// NewExporter creates an Exporter for store
+func NewExporter(store *Store) *Exporter {
+	return &Exporter{export: export.New(store.db)}
+}
+
+// Markdown returns a single article as Markdown
+func (e *Exporter) Markdown(ctx context.Context, id int64) (string, error) {
+	return e.export.ArticleMarkdown(ctx, id)
+}
+
+// ExportAll writes matching articles to opts.Directory without printing progress
+func (e *Exporter) ExportAll(ctx context.Context, opts ExportOptions) (*ExportResult, error) {
+	opts.Quiet = true
+	return e.export.ExportAll(ctx, opts)
+}
@@ -0,0 +1,22 @@
+package instapaper
+
+import (
+	"context"
+
+	"instapaper-cli/internal/fetcher"
+)
+
+// Fetcher downloads article content into a Store
+type Fetcher struct {
+	fetcher *fetcher.Fetcher
+}
+
+// NewFetcher creates a Fetcher for store
+func NewFetcher(store *Store) *Fetcher {
+	return &Fetcher{fetcher: fetcher.New(store.db)}
+}
+
+// Fetch downloads content for unfetched articles selected by opts
+func (f *Fetcher) Fetch(ctx context.Context, opts FetchOptions) (*FetchResult, error) {
+	return f.fetcher.FetchArticles(ctx, opts)
+}
@@ -0,0 +1,46 @@
+// Package instapaper exposes the archive store, fetcher, exporter and search
+// behind instapaper-cli so other Go programs can embed them without shelling
+// out to the CLI.
+//
+//	store, err := instapaper.Open("instapaper.sqlite")
+//	if err != nil {
+//		return err
+//	}
+//	defer store.Close()
+//
+//	results, err := instapaper.NewSearch(store).Find(ctx, instapaper.SearchOptions{Query: "golang", UseFTS: true})
+package instapaper
+
+import (
+	"instapaper-cli/internal/export"
+	"instapaper-cli/internal/fetcher"
+	"instapaper-cli/internal/importer"
+	"instapaper-cli/internal/model"
+	"instapaper-cli/internal/search"
+)
+
+// Types shared with the CLI, re-exported so callers can name them
+type (
+	Article            = model.Article
+	ArticleWithDetails = model.ArticleWithDetails
+	SearchResult       = model.SearchResult
+	ItemError          = model.ItemError
+
+	ImportOptions = importer.ImportOptions
+	ImportResult  = importer.ImportResult
+
+	FetchOptions    = fetcher.FetchOptions
+	FetchResult     = fetcher.FetchResult
+	PriorityWeights = fetcher.PriorityWeights
+
+	SearchOptions = search.SearchOptions
+	Selector      = search.Selector
+
+	ExportOptions = export.ExportAllOptions
+	ExportResult  = export.ExportResult
+)
+
+// DefaultPriorityWeights returns the scoring weights used by FetchOptions{Order: "priority"}
+func DefaultPriorityWeights() PriorityWeights {
+	return fetcher.DefaultPriorityWeights()
+}
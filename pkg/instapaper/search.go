@@ -0,0 +1,23 @@
+package instapaper
+
+import (
+	"context"
+
+	"instapaper-cli/internal/search"
+)
+
+// Search queries articles in a Store
+type Search struct {
+	search *search.Search
+}
+
+// NewSearch creates a Search for store
+func NewSearch(store *Store) *Search {
+	return &Search{search: search.New(store.db)}
+}
+
+// Find returns articles matching opts; JSONOutput and Explain are ignored
+func (s *Search) Find(ctx context.Context, opts SearchOptions) ([]SearchResult, error) {
+	opts.Explain = false
+	return s.search.Find(ctx, opts)
+}
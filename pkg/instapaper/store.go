@@ -0,0 +1,39 @@
+package instapaper
+
+import (
+	"context"
+
+	"instapaper-cli/internal/db"
+	"instapaper-cli/internal/importer"
+	"instapaper-cli/migrations"
+)
+
+// Store is an open archive database
+type Store struct {
+	db *db.DB
+}
+
+// Open opens (or creates) the SQLite archive at path and applies the embedded migrations
+func Open(path string) (*Store, error) {
+	database, err := db.New(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.RunMigrationsFS(migrations.FS); err != nil {
+		database.Close()
+		return nil, err
+	}
+
+	return &Store{db: database}, nil
+}
+
+// Close closes the underlying database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Import imports an Instapaper CSV export
+func (s *Store) Import(ctx context.Context, csvPath string, opts ImportOptions) (*ImportResult, error) {
+	return importer.New(s.db).ImportCSV(ctx, csvPath, opts)
+}